@@ -80,4 +80,146 @@ const (
 	ErrInvitationAlreadyAccepted = "err_invitation_already_accepted"
 	ErrNotOwner                  = "err_not_owner"
 	ErrMaxUsersReached           = "err_max_users_reached"
+
+	// Bank statement
+	ErrBankRequired           = "err_bank_required"
+	ErrBankNotSupported       = "err_bank_not_supported"
+	ErrStatementFileRequired  = "err_statement_file_required"
+	ErrStatementFileTooLarge  = "err_statement_file_too_large"
+	ErrStatementInvalid       = "err_statement_invalid"
+	ErrPaymentMatchesRequired = "err_payment_matches_required"
+	ErrPaymentAmountInvalid   = "err_payment_amount_invalid"
+	ErrStatementLineRequired  = "err_statement_line_required"
+	ErrStatementLineMatched   = "err_statement_line_matched"
+	ErrOrderNotOutstanding    = "err_order_not_outstanding"
+	ErrPaymentAboveBalance    = "err_payment_above_balance"
+
+	// Customer credit
+	ErrCreditTypeInvalid   = "err_credit_type_invalid"
+	ErrCreditAmountInvalid = "err_credit_amount_invalid"
+	ErrInsufficientCredit  = "err_insufficient_credit"
+	ErrNoOverpayment       = "err_no_overpayment"
+	ErrPaymentHasCredit    = "err_payment_has_credit"
+
+	// DP rule
+	ErrDPRuleNotFound     = "err_dp_rule_not_found"
+	ErrDPRuleIDRequired   = "err_dp_rule_id_required"
+	ErrDPRuleNameRequired = "err_dp_rule_name_required"
+	ErrDPPercentInvalid   = "err_dp_percent_invalid"
+	ErrDPDeadlineInvalid  = "err_dp_deadline_invalid"
+	// Refund
+	ErrOrderAlreadyCancelled = "err_order_already_cancelled"
+	ErrCancelReasonInvalid   = "err_cancel_reason_invalid"
+	ErrRefundAmountInvalid   = "err_refund_amount_invalid"
+	ErrRefundMethodInvalid   = "err_refund_method_invalid"
+
+	// Purchase list
+	ErrPurchaseStatusInvalid = "err_purchase_status_invalid"
+	ErrPurchaseQtyInvalid    = "err_purchase_qty_invalid"
+	ErrPurchaseNotRecorded   = "err_purchase_not_recorded"
+	ErrShortagePolicyInvalid = "err_shortage_policy_invalid"
+	ErrNoShortage            = "err_no_shortage"
+
+	// Shop
+	ErrInvoiceNumberPatternInvalid = "err_invoice_number_pattern_invalid"
+	ErrShopTimezoneInvalid         = "err_shop_timezone_invalid"
+	ErrShopLocaleInvalid           = "err_shop_locale_invalid"
+	ErrShopWhatsAppInvalid         = "err_shop_whatsapp_invalid"
+	ErrShopInstagramInvalid        = "err_shop_instagram_invalid"
+	ErrShopCurrencyInvalid         = "err_shop_currency_invalid"
+
+	// Share link
+	ErrShareLinkNotFound      = "err_share_link_not_found"
+	ErrShareLinkIDRequired    = "err_share_link_id_required"
+	ErrShareLinkExpired       = "err_share_link_expired"
+	ErrShareLinkExpiryInvalid = "err_share_link_expiry_invalid"
+	ErrShareLinkLabelTooLong  = "err_share_link_label_too_long"
+
+	// Public temp order
+	ErrTooManyRequests      = "err_too_many_requests"
+	ErrChallengeInvalid     = "err_challenge_invalid"
+	ErrSubmissionRejected   = "err_submission_rejected"
+	ErrTooManyOrderItems    = "err_too_many_order_items"
+	ErrQtyTooLarge          = "err_qty_too_large"
+	ErrCustomerPhoneInvalid = "err_customer_phone_invalid"
+	ErrCustomerNameTooLong  = "err_customer_name_too_long"
+	ErrProductInactive      = "err_product_inactive"
+	ErrDuplicateTempOrder   = "err_duplicate_temp_order"
+
+	// Customer profile
+	ErrCustomerTagInvalid  = "err_customer_tag_invalid"
+	ErrTooManyCustomerTags = "err_too_many_customer_tags"
+	ErrCustomerBlocked     = "err_customer_blocked"
+
+	// Customer merge
+	ErrDuplicateIDsRequired  = "err_duplicate_ids_required"
+	ErrCustomerMergeIntoSelf = "err_customer_merge_into_self"
+	ErrCustomerMergeOrders   = "err_customer_merge_orders"
+
+	// Customer address
+	ErrCustomerAddressNotFound = "err_customer_address_not_found"
+	ErrAddressIDRequired       = "err_address_id_required"
+	ErrRecipientRequired       = "err_recipient_required"
+	ErrPostalCodeInvalid       = "err_postal_code_invalid"
+
+	// Order documents
+	ErrDocumentTypeInvalid   = "err_document_type_invalid"
+	ErrDocumentFormatInvalid = "err_document_format_invalid"
+	ErrNoOrdersSelected      = "err_no_orders_selected"
+	ErrTooManyOrders         = "err_too_many_orders"
+	ErrDateInvalid           = "err_date_invalid"
+
+	// Reports
+	ErrReportInvalid       = "err_report_invalid"
+	ErrReportFormatInvalid = "err_report_format_invalid"
+
+	// Stats
+	ErrStatsBucketInvalid = "err_stats_bucket_invalid"
+	ErrStatsRangeInvalid  = "err_stats_range_invalid"
+	ErrStatsRangeTooLong  = "err_stats_range_too_long"
+	ErrStatsTopInvalid    = "err_stats_top_invalid"
+
+	// Bulk import
+	ErrImportFileRequired   = "err_import_file_required"
+	ErrImportFileTooLarge   = "err_import_file_too_large"
+	ErrImportFormatInvalid  = "err_import_format_invalid"
+	ErrImportFileInvalid    = "err_import_file_invalid"
+	ErrImportFileEmpty      = "err_import_file_empty"
+	ErrImportTooManyRows    = "err_import_too_many_rows"
+	ErrImportMappingInvalid = "err_import_mapping_invalid"
+	ErrImportDryRunInvalid  = "err_import_dry_run_invalid"
+	ErrImportColumnMissing  = "err_import_column_missing"
+	ErrImportDuplicateRow   = "err_import_duplicate_row"
+
+	// Shop archive
+	ErrArchiveFileRequired       = "err_archive_file_required"
+	ErrArchiveFileTooLarge       = "err_archive_file_too_large"
+	ErrArchiveInvalid            = "err_archive_invalid"
+	ErrArchiveVersionUnsupported = "err_archive_version_unsupported"
+	ErrArchiveShopNotEmpty       = "err_archive_shop_not_empty"
+	ErrArchiveShopIDInvalid      = "err_archive_shop_id_invalid"
+
+	// Shop closure
+	ErrShopClosed          = "err_shop_closed"
+	ErrShopNotClosed       = "err_shop_not_closed"
+	ErrShopClosurePassword = "err_shop_closure_password_required"
+	ErrShopIDInvalid       = "err_shop_id_invalid"
+
+	// Product category
+	ErrProductCategoryNotFound      = "err_product_category_not_found"
+	ErrProductCategoryIDRequired    = "err_product_category_id_required"
+	ErrProductCategoryIDInvalid     = "err_product_category_id_invalid"
+	ErrProductCategoryNameRequired  = "err_product_category_name_required"
+	ErrProductCategoryNameTooLong   = "err_product_category_name_too_long"
+	ErrProductCategoryNameExists    = "err_product_category_name_exists"
+	ErrProductCategoryParentInvalid = "err_product_category_parent_invalid"
+	ErrProductTagInvalid            = "err_product_tag_invalid"
+	ErrTooManyProductTags           = "err_too_many_product_tags"
+	ErrReorderIDsRequired           = "err_reorder_ids_required"
+
+	// Product image
+	ErrProductImageNotFound       = "err_product_image_not_found"
+	ErrProductImageIDInvalid      = "err_product_image_id_invalid"
+	ErrProductImagePrimaryInvalid = "err_product_image_primary_invalid"
+	ErrTooManyProductImages       = "err_too_many_product_images"
 )
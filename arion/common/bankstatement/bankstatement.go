@@ -0,0 +1,83 @@
+// Package bankstatement parses internet-banking mutation exports (mutasi
+// rekening) into a bank-agnostic list of mutations. Each bank registers its
+// own Parser so new export formats can be added without touching callers.
+package bankstatement
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BankBCA     = "bca"
+	BankMandiri = "mandiri"
+	BankBRI     = "bri"
+)
+
+var (
+	ErrUnknownFormat = errors.New("bankstatement: header row not found")
+	ErrNoMutations   = errors.New("bankstatement: no mutations found")
+)
+
+type (
+	// Mutation is a single line of a bank statement. Amount is in whole
+	// rupiah and always positive; IsCredit tells incoming from outgoing money.
+	Mutation struct {
+		Date        time.Time
+		Description string
+		Amount      int
+		IsCredit    bool
+	}
+
+	Parser interface {
+		Parse(r io.Reader) ([]Mutation, error)
+	}
+)
+
+var (
+	mu      sync.RWMutex
+	parsers = map[string]Parser{}
+)
+
+// Register makes a parser available under the given bank code. Registering
+// the same code twice replaces the previous parser.
+func Register(bank string, p Parser) {
+	mu.Lock()
+	defer mu.Unlock()
+	parsers[strings.ToLower(bank)] = p
+}
+
+// Get returns the parser registered for bank, if any.
+func Get(bank string) (Parser, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := parsers[strings.ToLower(strings.TrimSpace(bank))]
+	return p, ok
+}
+
+// Banks returns the registered bank codes in alphabetical order.
+func Banks() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	banks := make([]string, 0, len(parsers))
+	for b := range parsers {
+		banks = append(banks, b)
+	}
+	sort.Strings(banks)
+	return banks
+}
+
+// Credits filters mutations down to incoming transfers.
+func Credits(mutations []Mutation) []Mutation {
+	credits := []Mutation{}
+	for _, m := range mutations {
+		if m.IsCredit {
+			credits = append(credits, m)
+		}
+	}
+	return credits
+}
@@ -0,0 +1,152 @@
+package bankstatement
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseAmount(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantAmount int
+		wantMarker string
+		wantErr    bool
+	}{
+		{name: "plain integer", input: "150000", wantAmount: 150000},
+		{name: "decimal point", input: "150000.00", wantAmount: 150000},
+		{name: "english thousands", input: "1,234,567.00", wantAmount: 1234567},
+		{name: "indonesian thousands", input: "1.234.567,00", wantAmount: 1234567},
+		{name: "indonesian thousands without decimals", input: "150.000", wantAmount: 150000},
+		{name: "rounds half up", input: "10.50", wantAmount: 11},
+		{name: "credit marker", input: "150,000.00 CR", wantAmount: 150000, wantMarker: "CR"},
+		{name: "debit marker", input: "25,000.00 DB", wantAmount: 25000, wantMarker: "DB"},
+		{name: "negative is debit", input: "-25000", wantAmount: 25000, wantMarker: "DB"},
+		{name: "rupiah prefix", input: "Rp 50.000", wantAmount: 50000},
+		{name: "empty", input: "", wantAmount: 0},
+		{name: "dash", input: "-", wantAmount: 0},
+		{name: "garbage", input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, marker, err := parseAmount(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if amount != tt.wantAmount || marker != tt.wantMarker {
+				t.Errorf("parseAmount() = (%d, %q), want (%d, %q)", amount, marker, tt.wantAmount, tt.wantMarker)
+			}
+		})
+	}
+}
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		bank    string
+		input   string
+		want    []Mutation
+		wantErr error
+	}{
+		{
+			name: "bca with period header and separate marker column",
+			bank: BankBCA,
+			input: `No. rekening : 1234567890
+Nama : TOKO JASTIP
+Periode : 01/03/2024 - 31/03/2024
+Kode Mata Uang : Rp
+
+Tanggal Transaksi,Keterangan,Cabang,Jumlah,,Saldo
+'01/03,TRSF E-BANKING CR 0103/FTSCY/WS95051   BUDI SANTOSO,'0000,"150,000.00",CR,"1,150,000.00"
+'02/03,BIAYA ADM,'0000,"10,000.00",DB,"1,140,000.00"
+'PEND,TRSF E-BANKING CR SITI,'0000,"75,000.00",CR,"1,215,000.00"
+Saldo Awal,"1,000,000.00"
+`,
+			want: []Mutation{
+				{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Description: "TRSF E-BANKING CR 0103/FTSCY/WS95051 BUDI SANTOSO", Amount: 150000, IsCredit: true},
+				{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Description: "BIAYA ADM", Amount: 10000, IsCredit: false},
+			},
+		},
+		{
+			name: "bca with inline marker",
+			bank: BankBCA,
+			input: `Tanggal Transaksi,Keterangan,Cabang,Jumlah,Saldo
+05/03/2024,SETORAN TUNAI ANI,0000,"200,000.00 CR","2,000,000.00"
+`,
+			want: []Mutation{
+				{Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Description: "SETORAN TUNAI ANI", Amount: 200000, IsCredit: true},
+			},
+		},
+		{
+			name: "mandiri semicolon separated",
+			bank: BankMandiri,
+			input: `Account No;Date;Val. Date;Transaction Code;Description;Reference No.;Debit;Credit
+1234567890;01/03/2024;01/03/2024;8888;TRANSFER DARI SITI AMINAH;REF1;0,00;275.000,00
+1234567890;02/03/2024;02/03/2024;8889;BIAYA TRANSFER;REF2;6.500,00;0,00
+`,
+			want: []Mutation{
+				{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Description: "TRANSFER DARI SITI AMINAH", Amount: 275000, IsCredit: true},
+				{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Description: "BIAYA TRANSFER", Amount: 6500, IsCredit: false},
+			},
+		},
+		{
+			name: "bri raw headers",
+			bank: BankBRI,
+			input: `TGL_TRAN,DESK_TRAN,MUTASI_DEBET,MUTASI_KREDIT,SALDO_AKHIR_MUTASI
+2024-03-03 10:15:00,NBMB RINA WIJAYA TO TOKO,0,99500,1099500
+`,
+			want: []Mutation{
+				{Date: time.Date(2024, 3, 3, 10, 15, 0, 0, time.UTC), Description: "NBMB RINA WIJAYA TO TOKO", Amount: 99500, IsCredit: true},
+			},
+		},
+		{
+			name:    "unknown format",
+			bank:    BankBRI,
+			input:   "foo,bar\n1,2\n",
+			wantErr: ErrUnknownFormat,
+		},
+		{
+			name:    "header without rows",
+			bank:    BankMandiri,
+			input:   "Date,Description,Debit,Credit\n",
+			wantErr: ErrNoMutations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := Get(tt.bank)
+			if !ok {
+				t.Fatalf("parser for %q not registered", tt.bank)
+			}
+			got, err := p.Parse(strings.NewReader(tt.input))
+			if err != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErr == nil {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBanks(t *testing.T) {
+	want := []string{BankBCA, BankBRI, BankMandiri}
+	if got := Banks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Banks() = %v, want %v", got, want)
+	}
+}
+
+func TestCredits(t *testing.T) {
+	in := []Mutation{{Amount: 1, IsCredit: true}, {Amount: 2}, {Amount: 3, IsCredit: true}}
+	want := []Mutation{{Amount: 1, IsCredit: true}, {Amount: 3, IsCredit: true}}
+	if got := Credits(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("Credits() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,12 @@
+package bankstatement
+
+// BCA (KlikBCA / myBCA) exports a single "Jumlah" column with a CR/DB
+// marker and dd/mm dates without a year.
+func init() {
+	Register(BankBCA, columnParser{
+		dateCols:   []string{"tanggal transaksi", "tanggal", "tgl", "date"},
+		descCols:   []string{"keterangan", "description"},
+		amountCols: []string{"jumlah", "mutasi", "amount"},
+		shortDates: true,
+	})
+}
@@ -0,0 +1,12 @@
+package bankstatement
+
+// BRI (Internet Banking / BRImo) exports separate debit and credit columns,
+// using either the raw TGL_TRAN style headers or their display labels.
+func init() {
+	Register(BankBRI, columnParser{
+		dateCols:   []string{"tgl_tran", "tanggal", "tanggal transaksi", "tgl transaksi"},
+		descCols:   []string{"desk_tran", "uraian transaksi", "keterangan", "transaksi"},
+		debitCols:  []string{"mutasi_debet", "debet", "debit", "mutasi debet"},
+		creditCols: []string{"mutasi_kredit", "kredit", "credit", "mutasi kredit"},
+	})
+}
@@ -0,0 +1,271 @@
+package bankstatement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	dateLayouts = []string{
+		"02/01/2006",
+		"02/01/06",
+		"2006-01-02",
+		"02-01-2006",
+		"02 Jan 2006",
+		"02-Jan-2006",
+		"02/01/2006 15:04:05",
+		"02/01/06 15:04:05",
+		"2006-01-02 15:04:05",
+	}
+
+	yearPattern  = regexp.MustCompile(`\b\d{2}/\d{2}/(\d{4})\b`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// columnParser reads CSV exports whose columns are identified by header
+// names. Banks either export one signed amount column with a CR/DB marker
+// (amountCols) or separate debit and credit columns (debitCols/creditCols).
+type columnParser struct {
+	dateCols   []string
+	descCols   []string
+	amountCols []string
+	debitCols  []string
+	creditCols []string
+
+	// shortDates accepts dd/mm dates without a year. The year is taken from
+	// the statement period printed above the header row.
+	shortDates bool
+}
+
+type columnIndex struct {
+	date, desc, amount, debit, credit int
+}
+
+func (p columnParser) Parse(r io.Reader) ([]Mutation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	lastErr := ErrUnknownFormat
+	for _, comma := range []rune{',', ';', '\t'} {
+		mutations, err := p.parse(data, comma)
+		if err == nil {
+			return mutations, nil
+		}
+		if !errors.Is(err, ErrUnknownFormat) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+func (p columnParser) parse(data []byte, comma rune) ([]Mutation, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	header := -1
+	year := 0
+	var cols columnIndex
+	for i, rec := range records {
+		if c, ok := p.matchHeader(rec); ok {
+			header, cols = i, c
+			break
+		}
+		if year == 0 {
+			year = yearFromRecord(rec)
+		}
+	}
+	if header < 0 {
+		return nil, ErrUnknownFormat
+	}
+	if year == 0 {
+		year = time.Now().Year()
+	}
+
+	mutations := []Mutation{}
+	for _, rec := range records[header+1:] {
+		// footer rows (opening/closing balance, totals) and pending lines
+		// carry no parseable date and are skipped
+		date, ok := p.parseDate(cell(rec, cols.date), year)
+		if !ok {
+			continue
+		}
+
+		m := Mutation{
+			Date:        date,
+			Description: strings.TrimSpace(spacePattern.ReplaceAllString(cell(rec, cols.desc), " ")),
+		}
+		if cols.amount >= 0 {
+			amount, marker, err := parseAmount(cell(rec, cols.amount))
+			if err != nil {
+				continue
+			}
+			if marker == "" {
+				// some exports put the CR/DB marker in its own column
+				marker = strings.ToUpper(cell(rec, cols.amount+1))
+			}
+			m.Amount = amount
+			m.IsCredit = marker == "CR"
+		} else {
+			credit, _, _ := parseAmount(cell(rec, cols.credit))
+			debit, _, _ := parseAmount(cell(rec, cols.debit))
+			if credit > 0 {
+				m.Amount, m.IsCredit = credit, true
+			} else {
+				m.Amount = debit
+			}
+		}
+		if m.Amount <= 0 {
+			continue
+		}
+		mutations = append(mutations, m)
+	}
+
+	if len(mutations) == 0 {
+		return nil, ErrNoMutations
+	}
+	return mutations, nil
+}
+
+func (p columnParser) matchHeader(rec []string) (columnIndex, bool) {
+	cols := columnIndex{date: -1, desc: -1, amount: -1, debit: -1, credit: -1}
+	for i, c := range rec {
+		name := normalizeHeader(c)
+		switch {
+		case cols.date < 0 && contains(p.dateCols, name):
+			cols.date = i
+		case cols.desc < 0 && contains(p.descCols, name):
+			cols.desc = i
+		case cols.amount < 0 && contains(p.amountCols, name):
+			cols.amount = i
+		case cols.debit < 0 && contains(p.debitCols, name):
+			cols.debit = i
+		case cols.credit < 0 && contains(p.creditCols, name):
+			cols.credit = i
+		}
+	}
+	if cols.date < 0 || cols.desc < 0 {
+		return cols, false
+	}
+	if cols.amount < 0 && cols.credit < 0 {
+		return cols, false
+	}
+	return cols, true
+}
+
+func (p columnParser) parseDate(s string, year int) (time.Time, bool) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "'"))
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	if p.shortDates {
+		if t, err := time.Parse("02/01", s); err == nil {
+			return time.Date(year, t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseAmount converts a statement amount into whole rupiah. Both "1,234.00"
+// and "1.234,00" styles are accepted: the last separator is treated as the
+// decimal point only when it is followed by one or two digits. A trailing
+// CR/DB marker, or a leading minus sign (reported as DB), is returned
+// separately.
+func parseAmount(s string) (int, string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	marker := ""
+	for _, m := range []string{"CR", "DB"} {
+		if strings.HasSuffix(s, m) {
+			marker = m
+			s = strings.TrimSpace(strings.TrimSuffix(s, m))
+			break
+		}
+	}
+	s = strings.TrimPrefix(s, "RP")
+	s = strings.ReplaceAll(s, " ", "")
+	if s == "" || s == "-" {
+		return 0, marker, nil
+	}
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+		if marker == "" {
+			marker = "DB"
+		}
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i-1 <= 2 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	intPart = strings.NewReplacer(".", "", ",", "").Replace(intPart)
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	amount, err := strconv.Atoi(intPart)
+	if err != nil {
+		return 0, marker, err
+	}
+	if fracPart != "" {
+		if _, err := strconv.Atoi(fracPart); err != nil {
+			return 0, marker, err
+		}
+		if fracPart[0] >= '5' {
+			amount++
+		}
+	}
+	return amount, marker, nil
+}
+
+func yearFromRecord(rec []string) int {
+	for _, c := range rec {
+		if m := yearPattern.FindStringSubmatch(c); m != nil {
+			y, _ := strconv.Atoi(m[1])
+			return y
+		}
+	}
+	return 0
+}
+
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.Trim(s, "'\".:")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+}
+
+func cell(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package bankstatement
+
+// Mandiri (Livin' / MCM) exports separate debit and credit columns.
+func init() {
+	Register(BankMandiri, columnParser{
+		dateCols:   []string{"tanggal", "tanggal transaksi", "date", "posting date", "transaction date"},
+		descCols:   []string{"keterangan", "description", "remarks", "deskripsi"},
+		debitCols:  []string{"debit", "debet", "nominal debit"},
+		creditCols: []string{"kredit", "credit", "nominal kredit"},
+	})
+}
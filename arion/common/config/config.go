@@ -27,6 +27,18 @@ type Config struct {
 
 	UploadDir string `env:"UPLOAD_DIR" envDefault:"./uploads"`
 
+	// Public storefront checkout. The difficulty is the number of leading zero
+	// bits the proof-of-work hash needs; 0 turns the challenge off.
+	PublicOrderPoWDifficulty int `env:"PUBLIC_ORDER_POW_DIFFICULTY" envDefault:"16"`
+	// Use X-Forwarded-For for the client IP. Only set behind a proxy that overwrites it.
+	TrustProxyHeaders bool `env:"TRUST_PROXY_HEADERS"`
+
+	// TrueType font for generated PDFs (leave empty to use the bundled DejaVu Sans)
+	PDFFontFile string `env:"PDF_FONT_FILE"`
+	// TrueType font with CJK glyphs, for documents DejaVu Sans can't print
+	// (leave empty to look for one installed by the system's font packages)
+	PDFFallbackFontFile string `env:"PDF_FALLBACK_FONT_FILE"`
+
 	// Midtrans payment gateway
 	MidtransServerKey string `env:"MIDTRANS_SERVER_KEY"`
 	MidtransBaseURL   string `env:"MIDTRANS_BASE_URL" envDefault:"https://app.sandbox.midtrans.com"`
@@ -1,5 +1,7 @@
 package constant
 
+import "time"
+
 const (
 	// User role constants
 	RoleSystem = "system"
@@ -42,6 +44,167 @@ const (
 
 	OrderPaymentStatusOutstanding = "outstanding"
 	OrderPaymentStatusPaid        = "paid"
+	OrderPaymentStatusAwaitingDP  = "awaiting_dp"
+	OrderPaymentStatusDPPaid      = "dp_paid"
+	OrderPaymentStatusRefunded    = "refunded"
+
+	// Order cancellation and refund reason codes
+	OrderReasonOutOfStock        = "out_of_stock"
+	OrderReasonCustomerCancelled = "customer_cancelled"
+	OrderReasonPriceChanged      = "price_changed"
+	OrderReasonDuplicate         = "duplicate"
+	OrderReasonDamaged           = "damaged"
+	OrderReasonOther             = "other"
+	OrderReasonDPOverdue         = "dp_overdue" // set by the overdue DP cron only
+
+	// Refund methods
+	RefundMethodTransfer = "transfer"
+	RefundMethodCash     = "cash"
+	RefundMethodCredit   = "credit"
+
+	// Purchase list item status
+	PurchaseStatusPending     = "pending"
+	PurchaseStatusBought      = "bought"
+	PurchaseStatusUnavailable = "unavailable"
+
+	// Shortage allocation policies
+	ShortagePolicyFirstCome = "first_come" // oldest orders are filled first
+	ShortagePolicyProRata   = "pro_rata"   // every order loses the same share
+	ShortagePolicyPriority  = "priority"   // higher priority customers are filled first
+	ShortagePolicyPaidFirst = "paid_first" // paid orders, then down-paid ones, then the rest
+
+	// Order document types. Invoices also have a per-shop number sequence.
+	DocumentTypeInvoice     = "invoice"
+	DocumentTypePackingSlip = "packing_slip"
+	DocumentTypeLabel       = "label" // A6 shipping label
+
+	// Batch document output formats
+	DocumentFormatPDF = "pdf" // one PDF, a page (or more) per order
+	DocumentFormatZIP = "zip" // one PDF per order
+	// MaxDocumentOrders caps how many orders one batch document request prints
+	MaxDocumentOrders = 200
+
+	// Reports
+	ReportSalesByProduct  = "sales_by_product"
+	ReportSalesByCustomer = "sales_by_customer"
+	ReportDailyRevenue    = "daily_revenue"
+	ReportReceivables     = "receivables" // orders with an amount left to collect
+	ReportPayments        = "payments"    // payment ledger, refunds as negative amounts
+
+	// Report download formats
+	ReportFormatCSV  = "csv"
+	ReportFormatXLSX = "xlsx"
+
+	// Customer and product bulk import file formats
+	ImportFormatCSV  = "csv"
+	ImportFormatXLSX = "xlsx"
+	// ImportMaxRows caps how many data rows one import file can hold
+	ImportMaxRows = 5000
+	// ImportBatchSize is how many rows one insert or update statement writes
+	ImportBatchSize = 500
+
+	// Shop archive. ArchiveVersion goes up whenever the layout of the archive
+	// changes in a way an older import can't read.
+	ArchiveFormat      = "recapo-shop-archive"
+	ArchiveVersion     = 1
+	ArchiveMaxFileSize = 512 << 20
+
+	// Shop archive tables, in the order they are exported and restored:
+	// every table comes after the tables its rows point to.
+	ArchiveTableSettings          = "settings"
+	ArchiveTableDocumentSequences = "document_sequences"
+	ArchiveTableDPRules           = "dp_rules"
+	ArchiveTableCustomers         = "customers"
+	ArchiveTableCustomerAddresses = "customer_addresses"
+	ArchiveTableProductCategories = "product_categories"
+	ArchiveTableProducts          = "products"
+	ArchiveTableProductImages     = "product_images"
+	ArchiveTableOrders            = "orders"
+	ArchiveTableOrderItems        = "order_items"
+	ArchiveTableOrderPayments     = "order_payments"
+	ArchiveTableOrderRefunds      = "order_refunds"
+	ArchiveTableCustomerCredits   = "customer_credits"
+	ArchiveTableTempOrders        = "temp_orders"
+	ArchiveTableTempOrderItems    = "temp_order_items"
+
+	// ShopClosureGracePeriod is how long a closed shop keeps its data before
+	// the purge cron deletes it; it can be reopened until then.
+	ShopClosureGracePeriod = 30 * 24 * time.Hour
+	// AnonymizedCustomerName replaces the name of an anonymized customer
+	AnonymizedCustomerName = "Deleted customer"
+
+	// UploadGCMinAge is how old an uploaded file must be before the cleanup
+	// cron deletes it for not being referenced, which leaves time to save
+	// the form it was uploaded from.
+	UploadGCMinAge = 24 * time.Hour
+	// UploadGCBatchSize is how many unreferenced uploads are looked up at once
+	UploadGCBatchSize = 500
+
+	// Order stats timeseries buckets. Weeks start on Monday.
+	StatsBucketDay   = "day"
+	StatsBucketWeek  = "week"
+	StatsBucketMonth = "month"
+	// StatsDefaultDays is the range, ending today, used when no dates are given
+	StatsDefaultDays = 30
+	// StatsMaxBuckets caps how many points one timeseries request returns
+	StatsMaxBuckets = 400
+	StatsDefaultTop = 5
+	StatsMaxTop     = 50
+
+	// Defaults for shops that have not set their own timezone (IANA name), locale and currency
+	DefaultShopTimezone = "Asia/Jakarta"
+	DefaultShopLocale   = "id"
+	DefaultShopCurrency = "IDR"
+
+	// DefaultInvoiceNumberPattern is used for shops that have not set their own
+	DefaultInvoiceNumberPattern = "INV/{YYYY}/{MM}/{seq}"
+	// InvoiceNumberPatternMaxLen is the longest invoice number pattern a shop can set
+	InvoiceNumberPatternMaxLen = 40
+
+	// UniqueCodeMax is the largest unique transfer code (kode unik) added to an order total
+	UniqueCodeMax = 999
+
+	// Customer credit ledger entry types
+	CustomerCreditTypeDeposit     = "deposit"
+	CustomerCreditTypeOverpayment = "overpayment"
+	CustomerCreditTypeRefund      = "refund"
+	CustomerCreditTypeAdjustment  = "adjustment"
+	CustomerCreditTypePayment     = "payment"
+
+	// Customer tag limits
+	CustomerMaxTags      = 20
+	CustomerTagMaxLength = 30
+
+	// Product tag and category limits
+	ProductMaxTags               = 20
+	ProductTagMaxLength          = 30
+	ProductCategoryNameMaxLength = 50
+
+	// Product image variants, as the longest side in pixels. Uploads are
+	// never scaled up.
+	ProductImageThumbnailSize = 200
+	ProductImageMediumSize    = 640
+	ProductImageFullSize      = 1280
+	// ProductImageWebPQuality is the lossy WebP quality the variants are stored at
+	ProductImageWebPQuality = 82
+	// ProductMaxImages caps how many images one product can have
+	ProductMaxImages = 10
+
+	// Bank statement payment match reasons
+	PaymentMatchExactAmount  = "exact_amount"
+	PaymentMatchUniqueCode   = "unique_code"
+	PaymentMatchCustomerName = "customer_name"
+
+	// Public temp order limits. Requests over a rate limit get 429.
+	PublicOrderMaxItems     = 50  // order lines per temp order
+	PublicOrderMaxQty       = 100 // qty per order line
+	PublicOrderIPLimit      = 10  // temp orders per client IP per PublicOrderIPWindow
+	PublicOrderIPWindow     = 10 * time.Minute
+	PublicOrderTokenLimit   = 100 // temp orders per share token per PublicOrderTokenWindow
+	PublicOrderTokenWindow  = time.Hour
+	PublicOrderChallengeTTL = 10 * time.Minute
+	// TempOrderDuplicateWindow is how long the same phone can't send the same items to a shop again
+	TempOrderDuplicateWindow = 10 * time.Minute
 
 	// Invitation status constants
 	InvitationStatusPending  = "pending"
@@ -64,6 +64,14 @@ func GetLangFromRequest(r *http.Request) string {
 	return defaultLang
 }
 
+// IsSupported reports whether lang has messages.
+func IsSupported(lang string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := messages[lang]
+	return ok
+}
+
 // T returns the translated message for the given language and code.
 // Falls back to English if the key is missing in the requested language.
 // Returns empty string if not found in any language.
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/ratelimit"
+	"github.com/zeirash/recapo/arion/handler"
+)
+
+// RateLimit answers 429 with a Retry-After header once key(r) is over the
+// limiter's limit. Requests with an empty key are not counted.
+func RateLimit(limiter *ratelimit.Limiter, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if ok, retryAfter := limiter.Allow(k); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				handler.WriteErrorJson(w, r, http.StatusTooManyRequests, errors.New(apierr.ErrTooManyRequests), "rate_limited")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the IP the request came from. The first X-Forwarded-For
+// address is only used with TRUST_PROXY_HEADERS, since clients can set it.
+func ClientIP(r *http.Request) string {
+	if config.GetConfig().TrustProxyHeaders {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ShareToken returns the {share_token} route variable.
+func ShareToken(r *http.Request) string {
+	return mux.Vars(r)["share_token"]
+}
@@ -0,0 +1,47 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/middleware"
+	"github.com/zeirash/recapo/arion/common/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := middleware.RateLimit(ratelimit.New(2, time.Minute), middleware.ClientIP)(next)
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		wantStatus     int
+		wantRetryAfter bool
+	}{
+		{name: "first request passes", remoteAddr: "1.2.3.4:5000", wantStatus: http.StatusOK},
+		{name: "second request from another port passes", remoteAddr: "1.2.3.4:5001", wantStatus: http.StatusOK},
+		{name: "third request returns 429", remoteAddr: "1.2.3.4:5002", wantStatus: http.StatusTooManyRequests, wantRetryAfter: true},
+		{name: "other IP passes", remoteAddr: "5.6.7.8:5000", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/public/shops/abc/order", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RateLimit() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After") != ""; got != tt.wantRetryAfter {
+				t.Errorf("RateLimit() Retry-After set = %v, want %v", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
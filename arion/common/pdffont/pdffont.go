@@ -0,0 +1,154 @@
+// Package pdffont provides the TrueType fonts used for generated PDFs. The
+// core PDF fonts only cover Latin-1, so documents embed DejaVu Sans instead,
+// which covers Latin (including Vietnamese and Indonesian diacritics), Greek
+// and Cyrillic. DejaVu has no CJK glyphs, so a document with Japanese, Korean
+// or Chinese text is set in a fallback font that has them: the configured
+// PDF_FALLBACK_FONT_FILE, or one of the CJK fonts Linux font packages install.
+package pdffont
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"unicode"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Family is the font family name to pass to SetFont after Register.
+const Family = "unicode"
+
+//go:embed fonts/*.ttf
+var fonts embed.FS
+
+var styles = map[string]string{
+	"":  "fonts/DejaVuSansCondensed.ttf",
+	"B": "fonts/DejaVuSansCondensed-Bold.ttf",
+	"I": "fonts/DejaVuSansCondensed-Oblique.ttf",
+}
+
+// fallbackFiles are where Debian's fonts-droid-fallback, fonts-ipafont-gothic
+// and fonts-nanum packages install their TrueType fonts. They are looked for
+// when no fallback font is configured.
+var fallbackFiles = []string{
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/opentype/ipafont-gothic/ipag.ttf",
+	"/usr/share/fonts/truetype/nanum/NanumGothic.ttf",
+}
+
+// Register adds Family with regular, bold and italic styles to pdf. When
+// customFile is set, that TrueType font is used for every style. Otherwise
+// DejaVu Sans is, unless a fallback font has glyphs for more of the
+// characters in text, the document's names, addresses and notes: then that
+// font is used for every style. fallbackFile is the fallback font to try;
+// when it is empty, those of fallbackFiles that are installed are. Font
+// errors are reported through pdf.Err.
+func Register(pdf *fpdf.Fpdf, customFile, fallbackFile string, text ...string) error {
+	if customFile != "" {
+		data, err := os.ReadFile(customFile)
+		if err != nil {
+			return err
+		}
+		registerAllStyles(pdf, data)
+		return pdf.Error()
+	}
+
+	fallback, err := fallbackFor(fallbackFile, text)
+	if err != nil {
+		return err
+	}
+	if fallback != nil {
+		registerAllStyles(pdf, fallback)
+		return pdf.Error()
+	}
+
+	for style, file := range styles {
+		data, err := fonts.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		pdf.AddUTF8FontFromBytes(Family, style, data)
+	}
+	return pdf.Error()
+}
+
+func registerAllStyles(pdf *fpdf.Fpdf, data []byte) {
+	for style := range styles {
+		pdf.AddUTF8FontFromBytes(Family, style, data)
+	}
+}
+
+// fallbackFor returns the fallback font to set text in, or nil when DejaVu
+// Sans has a glyph for every character of it or no fallback font has more.
+func fallbackFor(fallbackFile string, text []string) ([]byte, error) {
+	runes := documentRunes(text)
+	regular, err := fonts.ReadFile(styles[""])
+	if err != nil {
+		return nil, err
+	}
+	fewest := missingGlyphs(regular, runes)
+	if fewest == 0 {
+		return nil, nil
+	}
+
+	files := fallbackFiles
+	if fallbackFile != "" {
+		files = []string{fallbackFile}
+	}
+	var best []byte
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) && fallbackFile == "" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if missing := missingGlyphs(data, runes); missing < fewest {
+			best, fewest = data, missing
+		}
+	}
+	return best, nil
+}
+
+// documentRunes returns the distinct printable characters of text along with
+// printable ASCII, which every document's labels and amounts are written in.
+func documentRunes(text []string) []rune {
+	seen := map[rune]bool{}
+	var runes []rune
+	add := func(r rune) {
+		if !seen[r] && unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			seen[r] = true
+			runes = append(runes, r)
+		}
+	}
+	for r := rune('!'); r <= '~'; r++ {
+		add(r)
+	}
+	for _, s := range text {
+		for _, r := range s {
+			add(r)
+		}
+	}
+	return runes
+}
+
+// missingGlyphs counts the runes the TrueType font in data has no glyph for.
+// A font that can't be parsed has none of them.
+func missingGlyphs(data []byte, runes []rune) int {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return len(runes)
+	}
+	var buf sfnt.Buffer
+	missing := 0
+	for _, r := range runes {
+		// fpdf only reads the font's Basic Multilingual Plane cmap.
+		if i, err := f.GlyphIndex(&buf, r); err != nil || i == 0 || r > 0xffff {
+			missing++
+		}
+	}
+	return missing
+}
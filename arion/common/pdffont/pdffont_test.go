@@ -0,0 +1,246 @@
+package pdffont
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		customFile string
+		wantErr    bool
+	}{
+		{
+			name: "registers bundled DejaVu Sans",
+		},
+		{
+			name:       "registers custom font for every style",
+			customFile: "fonts/DejaVuSansCondensed.ttf",
+		},
+		{
+			name:       "returns error when custom font is missing",
+			customFile: filepath.Join(t.TempDir(), "missing.ttf"),
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf := fpdf.New("P", "mm", "A4", "")
+			pdf.SetCompression(false)
+
+			err := Register(pdf, tt.customFile, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			pdf.AddPage()
+			for _, style := range []string{"", "B", "I"} {
+				pdf.SetFont(Family, style, 12)
+				pdf.Cell(40, 10, "Nguyễn Матрёшка")
+			}
+			var buf bytes.Buffer
+			if err := pdf.Output(&buf); err != nil {
+				t.Fatalf("Output() error = %v", err)
+			}
+			for _, font := range []string{"/utf8unicode\n", "/utf8unicodeB\n", "/utf8unicodeI\n"} {
+				if !strings.Contains(buf.String(), "/BaseFont "+font) {
+					t.Errorf("Register() output does not embed font %q", strings.TrimSpace(font))
+				}
+			}
+		})
+	}
+}
+
+func TestRegister_fallback(t *testing.T) {
+	const name = "山田太郎"
+	fallbackFile := filepath.Join(t.TempDir(), "cjk.ttf")
+	if err := os.WriteFile(fallbackFile, cjkFont(t, name), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		fallbackFile string
+		text         string
+		wantGlyphs   bool
+		wantErr      bool
+	}{
+		{
+			name:         "sets CJK text in the fallback font",
+			fallbackFile: fallbackFile,
+			text:         name,
+			wantGlyphs:   true,
+		},
+		{
+			name:         "keeps DejaVu Sans when it has every glyph",
+			fallbackFile: fallbackFile,
+			text:         "Nguyễn Матрёшка",
+		},
+		{
+			name:         "returns error when fallback font is missing",
+			fallbackFile: filepath.Join(t.TempDir(), "missing.ttf"),
+			text:         name,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf := fpdf.New("P", "mm", "A4", "")
+			pdf.SetCompression(false)
+
+			err := Register(pdf, "", tt.fallbackFile, "Jl. Sudirman 1", tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			pdf.AddPage()
+			pdf.SetFont(Family, "", 12)
+			pdf.Cell(40, 10, name)
+			var buf bytes.Buffer
+			if err := pdf.Output(&buf); err != nil {
+				t.Fatalf("Output() error = %v", err)
+			}
+			cidToGID := cidToGIDMap(t, buf.Bytes())
+			for _, r := range name {
+				gid := binary.BigEndian.Uint16(cidToGID[r*2:])
+				if (gid != 0) != tt.wantGlyphs {
+					t.Errorf("glyph for %q = %d, want glyph %v", r, gid, tt.wantGlyphs)
+				}
+			}
+		})
+	}
+}
+
+// cjkFont returns a copy of DejaVu Sans whose cmap maps printable ASCII to
+// its own glyphs and each rune of cjk to a Cyrillic glyph, standing in for a
+// CJK font.
+func cjkFont(t *testing.T, cjk string) []byte {
+	t.Helper()
+	data, err := fonts.ReadFile(styles[""])
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb sfnt.Buffer
+	glyph := func(r rune) uint16 {
+		i, err := f.GlyphIndex(&sb, r)
+		if err != nil || i == 0 {
+			t.Fatalf("DejaVu Sans has no glyph for %q", r)
+		}
+		return uint16(i)
+	}
+	glyphs := map[rune]uint16{}
+	for r := rune('!'); r <= '~'; r++ {
+		glyphs[r] = glyph(r)
+	}
+	for i, r := range []rune(cjk) {
+		glyphs[r] = glyph([]rune("ЖЩЮЯ")[i%4])
+	}
+
+	// A format 4 subtable with a segment per rune, then the closing 0xFFFF one.
+	runes := make([]rune, 0, len(glyphs))
+	for r := range glyphs {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	segs := len(runes) + 1
+	ends, starts, deltas := make([]uint16, segs), make([]uint16, segs), make([]uint16, segs)
+	for i, r := range runes {
+		ends[i], starts[i], deltas[i] = uint16(r), uint16(r), glyphs[r]-uint16(r)
+	}
+	ends[segs-1], starts[segs-1], deltas[segs-1] = 0xffff, 0xffff, 1
+	searchRange, entrySelector := 2, 0
+	for searchRange*2 <= segs*2 {
+		searchRange *= 2
+		entrySelector++
+	}
+	var sub bytes.Buffer
+	put := func(v ...uint16) { binary.Write(&sub, binary.BigEndian, v) }
+	put(4, uint16(16+8*segs), 0, uint16(segs*2), uint16(searchRange), uint16(entrySelector), uint16(segs*2-searchRange))
+	put(ends...)
+	put(0)
+	put(starts...)
+	put(deltas...)
+	put(make([]uint16, segs)...)
+	var cmap bytes.Buffer
+	binary.Write(&cmap, binary.BigEndian, []uint16{0, 1, 3, 1, 0, 12})
+	cmap.Write(sub.Bytes())
+
+	// Rebuild the font with the new cmap, each table 4-byte aligned.
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	dir := make([]byte, 12+16*numTables)
+	copy(dir, data[:12])
+	var body bytes.Buffer
+	for i := 0; i < numTables; i++ {
+		rec := data[12+16*i : 28+16*i]
+		table := data[binary.BigEndian.Uint32(rec[8:]):][:binary.BigEndian.Uint32(rec[12:])]
+		if string(rec[:4]) == "cmap" {
+			table = cmap.Bytes()
+		}
+		out := dir[12+16*i:]
+		copy(out, rec[:4])
+		binary.BigEndian.PutUint32(out[4:], checksum(table))
+		binary.BigEndian.PutUint32(out[8:], uint32(len(dir)+body.Len()))
+		binary.BigEndian.PutUint32(out[12:], uint32(len(table)))
+		body.Write(table)
+		body.Write(make([]byte, -len(table)&3))
+	}
+	return append(dir, body.Bytes()...)
+}
+
+func checksum(table []byte) uint32 {
+	var sum uint32
+	padded := append(append([]byte(nil), table...), 0, 0, 0)
+	for i := 0; i+4 <= len(padded); i += 4 {
+		sum += binary.BigEndian.Uint32(padded[i:])
+	}
+	return sum
+}
+
+// cidToGIDMap returns the decompressed CIDToGIDMap of the PDF's font, two
+// big-endian bytes of glyph index per BMP character.
+func cidToGIDMap(t *testing.T, pdf []byte) []byte {
+	t.Helper()
+	ref := regexp.MustCompile(`/CIDToGIDMap (\d+) 0 R`).FindSubmatch(pdf)
+	if ref == nil {
+		t.Fatal("Output() has no CIDToGIDMap")
+	}
+	obj := regexp.MustCompile(`(?m)^` + string(ref[1]) + ` 0 obj\n<</Length (\d+)/Filter /FlateDecode>>\nstream\n`).FindSubmatchIndex(pdf)
+	if obj == nil {
+		t.Fatalf("Output() has no CIDToGIDMap object %s", ref[1])
+	}
+	n, _ := strconv.Atoi(string(pdf[obj[2]:obj[3]]))
+	r, err := zlib.NewReader(bytes.NewReader(pdf[obj[1] : obj[1]+n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
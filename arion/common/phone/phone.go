@@ -0,0 +1,87 @@
+// Package phone normalizes phone numbers to E.164. Numbers written without a
+// country code are read as Indonesian, the way customers type them: 0812…,
+// 812… or 62 812….
+//
+// migrations/015_normalize_customer_phones.sql applies the same rules in SQL;
+// keep the two in step.
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// DefaultCountryCode is assumed for numbers with a trunk 0 or none at all.
+const DefaultCountryCode = "62"
+
+var ErrInvalid = errors.New("invalid phone number")
+
+// Normalize returns raw as +<country code><number>. Spaces, dashes, dots,
+// slashes and parentheses are ignored. A leading + or 00 means the country
+// code is given; a leading 0 or 8 means an Indonesian number. A 0 written
+// after +62 is dropped.
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	hasPlus := strings.HasPrefix(raw, "+")
+	if hasPlus {
+		raw = raw[1:]
+	}
+
+	var b strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ', r == '-', r == '.', r == '/', r == '(', r == ')':
+		default:
+			return "", ErrInvalid
+		}
+	}
+	digits := b.String()
+
+	switch {
+	case hasPlus:
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0"):
+		digits = DefaultCountryCode + digits[1:]
+	case strings.HasPrefix(digits, "8"):
+		digits = DefaultCountryCode + digits
+	}
+
+	if strings.HasPrefix(digits, DefaultCountryCode+"0") {
+		digits = DefaultCountryCode + digits[len(DefaultCountryCode)+1:]
+	}
+
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalid
+	}
+
+	return "+" + digits, nil
+}
+
+// SearchFragment rewrites a search for part of a phone number to match stored
+// numbers: separators are dropped and a trunk 0 is cut, so "0812-34" finds
+// +6281234…. Queries that aren't phone-like are returned unchanged.
+func SearchFragment(q string) string {
+	trimmed := strings.TrimSpace(q)
+	var b strings.Builder
+	for i, r := range trimmed {
+		switch {
+		case r >= '0' && r <= '9', r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ', r == '-', r == '.', r == '/', r == '(', r == ')':
+		default:
+			return q
+		}
+	}
+
+	fragment := b.String()
+	if strings.HasPrefix(fragment, "0") && !strings.HasPrefix(fragment, "00") {
+		fragment = fragment[1:]
+	}
+	if fragment == "" {
+		return q
+	}
+	return fragment
+}
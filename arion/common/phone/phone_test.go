@@ -0,0 +1,63 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "081234567890", want: "+6281234567890"},
+		{raw: "+6281234567890", want: "+6281234567890"},
+		{raw: "6281234567890", want: "+6281234567890"},
+		{raw: "62 812-3456-7890", want: "+6281234567890"},
+		{raw: "(0812) 3456.7890", want: "+6281234567890"},
+		{raw: "81234567890", want: "+6281234567890"},
+		{raw: "+62 0812 3456 7890", want: "+6281234567890"},
+		{raw: "006281234567890", want: "+6281234567890"},
+		{raw: " 0812-3456-7890 ", want: "+6281234567890"},
+		{raw: "+65 9123 4567", want: "+6591234567"},
+		{raw: "021 5551234", want: "+62215551234"},
+		{raw: "", wantErr: true},
+		{raw: "12345", wantErr: true},
+		{raw: "0812-CALL-ME", wantErr: true},
+		{raw: "+0812345678", wantErr: true},
+		{raw: "0812345678901234567", wantErr: true},
+		{raw: "0812+34567890", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := Normalize(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFragment(t *testing.T) {
+	tests := []struct {
+		q    string
+		want string
+	}{
+		{q: "0812-34", want: "81234"},
+		{q: "+62 812", want: "+62812"},
+		{q: "62812", want: "62812"},
+		{q: "Jane", want: "Jane"},
+		{q: "Jane 0812", want: "Jane 0812"},
+		{q: "0", want: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			if got := SearchFragment(tt.q); got != tt.want {
+				t.Errorf("SearchFragment(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package pow
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "secret"
+
+	c, err := Issue(secret, "share-abc", 8, time.Minute)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	solution := Solve(c.Token, c.Difficulty)
+
+	expired, _ := Issue(secret, "share-abc", 0, -time.Minute)
+	tampered := strings.Replace(c.Token, ".", "x.", 1)
+
+	tests := []struct {
+		name     string
+		secret   string
+		scope    string
+		token    string
+		solution string
+		want     bool
+	}{
+		{name: "wrong solution", secret: secret, scope: "share-abc", token: c.Token, solution: solution + "x", want: false},
+		{name: "other scope", secret: secret, scope: "share-xyz", token: c.Token, solution: solution, want: false},
+		{name: "other secret", secret: "other", scope: "share-abc", token: c.Token, solution: solution, want: false},
+		{name: "tampered token", secret: secret, scope: "share-abc", token: tampered, solution: solution, want: false},
+		{name: "malformed token", secret: secret, scope: "share-abc", token: "abc", solution: solution, want: false},
+		{name: "expired token", secret: secret, scope: "share-abc", token: expired.Token, solution: "0", want: false},
+		{name: "valid solution", secret: secret, scope: "share-abc", token: c.Token, solution: solution, want: true},
+		{name: "token can't be reused", secret: secret, scope: "share-abc", token: c.Token, solution: solution, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.scope, tt.token, tt.solution); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolves(t *testing.T) {
+	token := "token"
+	solution := Solve(token, 12)
+	if !Solves(token, solution, 12) {
+		t.Errorf("Solves(%q, 12) = false for a solution from Solve", solution)
+	}
+	if !Solves(token, "anything", 0) {
+		t.Error("Solves() with difficulty 0 = false, want true")
+	}
+}
@@ -0,0 +1,71 @@
+// Package ratelimit counts requests per key in fixed time windows, in memory.
+// Counts are per process, so each instance behind a load balancer enforces
+// its own limit.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	hits      map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+// New returns a limiter that allows limit requests per key in each window.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:  limit,
+		window: window,
+		hits:   map[string]*bucket{},
+		now:    time.Now,
+	}
+}
+
+// Allow counts a request for key. When the key is over its limit it returns
+// false and how long until its window resets.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.hits[key]
+	if !ok || now.Sub(b.start) >= l.window {
+		l.hits[key] = &bucket{start: now, count: 1}
+		return true, 0
+	}
+
+	if b.count >= l.limit {
+		return false, b.start.Add(l.window).Sub(now)
+	}
+
+	b.count++
+	return true, 0
+}
+
+// sweep drops finished windows, at most once per window, so keys that stop
+// sending don't stay in memory.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, b := range l.hits {
+		if now.Sub(b.start) >= l.window {
+			delete(l.hits, key)
+		}
+	}
+	l.lastSweep = now
+}
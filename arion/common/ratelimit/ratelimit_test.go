@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	now := start
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("1.2.3.4"); !ok {
+			t.Fatalf("Allow() request %d = false, want true", i+1)
+		}
+	}
+
+	now = start.Add(20 * time.Second)
+	ok, retryAfter := l.Allow("1.2.3.4")
+	if ok {
+		t.Fatal("Allow() over the limit = true, want false")
+	}
+	if retryAfter != 40*time.Second {
+		t.Errorf("Allow() retryAfter = %v, want 40s", retryAfter)
+	}
+
+	if ok, _ := l.Allow("5.6.7.8"); !ok {
+		t.Error("Allow() for another key = false, want true")
+	}
+
+	now = start.Add(time.Minute)
+	if ok, _ := l.Allow("1.2.3.4"); !ok {
+		t.Error("Allow() after the window = false, want true")
+	}
+}
+
+func TestLimiter_sweep(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	now := start
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	now = start.Add(2 * time.Minute)
+	l.Allow("b")
+
+	if _, ok := l.hits["a"]; ok {
+		t.Error("sweep kept a finished window")
+	}
+	if len(l.hits) != 1 {
+		t.Errorf("len(hits) = %d, want 1", len(l.hits))
+	}
+}
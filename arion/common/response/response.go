@@ -22,13 +22,116 @@ type (
 		UpdatedAt          *time.Time `json:"updated_at"`
 	}
 
+	ShopData struct {
+		ID                   int        `json:"id"`
+		Name                 string     `json:"name"`
+		UniqueCodeEnabled    bool       `json:"unique_code_enabled"`
+		UniqueCodeAsFee      bool       `json:"unique_code_as_fee"`
+		Address              string     `json:"address"`
+		LogoURL              string     `json:"logo_url"`
+		BankAccounts         string     `json:"bank_accounts"`
+		InvoiceFooter        string     `json:"invoice_footer"`
+		InvoiceNumberPattern string     `json:"invoice_number_pattern"`
+		Timezone             string     `json:"timezone"`
+		Locale               string     `json:"locale"`
+		Description          string     `json:"description"`
+		WhatsApp             string     `json:"whatsapp"`
+		Instagram            string     `json:"instagram"`
+		InvoiceMessage       string     `json:"invoice_message"`
+		Currency             string     `json:"currency"`
+		CreatedAt            time.Time  `json:"created_at"`
+		UpdatedAt            *time.Time `json:"updated_at"`
+	}
+
+	// PublicShopData is what the storefront shows about the seller.
+	PublicShopData struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		LogoURL     string `json:"logo_url"`
+		WhatsApp    string `json:"whatsapp"`
+		Instagram   string `json:"instagram"`
+		Currency    string `json:"currency"`
+	}
+
+	PublicShopProductsData struct {
+		Shop       PublicShopData        `json:"shop"`
+		Categories []ProductCategoryData `json:"categories"`
+		Products   []ProductData         `json:"products"`
+	}
+
+	OrderChallengeData struct {
+		Challenge  string    `json:"challenge"`
+		Difficulty int       `json:"difficulty"`
+		ExpiresAt  time.Time `json:"expires_at"`
+	}
+
 	CustomerData struct {
-		ID        int        `json:"id"`
-		Name      string     `json:"name"`
-		Phone     string     `json:"phone"`
-		Address   string     `json:"address"`
-		CreatedAt time.Time  `json:"created_at"`
-		UpdatedAt *time.Time `json:"updated_at"`
+		ID            int                `json:"id"`
+		Name          string             `json:"name"`
+		Phone         string             `json:"phone"`
+		Address       string             `json:"address"`
+		Priority      int                `json:"priority"`
+		Tags          []string           `json:"tags"`
+		Blocked       bool               `json:"blocked"`
+		CreditBalance *int               `json:"credit_balance,omitempty"`
+		Stats         *CustomerStatsData `json:"stats,omitempty"`
+		CreatedAt     time.Time          `json:"created_at"`
+		UpdatedAt     *time.Time         `json:"updated_at"`
+	}
+
+	// CustomerStatsData is a customer's lifetime order stats. Cancelled
+	// orders are left out; outstanding_balance is what is still due on the
+	// rest.
+	CustomerStatsData struct {
+		OrderCount         int        `json:"order_count"`
+		TotalSpent         int        `json:"total_spent"`
+		OutstandingBalance int        `json:"outstanding_balance"`
+		FirstOrderAt       *time.Time `json:"first_order_at"`
+		LastOrderAt        *time.Time `json:"last_order_at"`
+	}
+
+	CustomerCreditData struct {
+		ID        int       `json:"id"`
+		OrderID   *int      `json:"order_id,omitempty"`
+		Type      string    `json:"type"`
+		Amount    int       `json:"amount"`
+		Notes     string    `json:"notes"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// CustomerCreditsData is the customer's credit ledger, newest entry first, with its running balance.
+	CustomerCreditsData struct {
+		Balance int                  `json:"balance"`
+		Entries []CustomerCreditData `json:"entries"`
+	}
+
+	CustomerAddressData struct {
+		ID         int        `json:"id"`
+		Label      string     `json:"label"`
+		Recipient  string     `json:"recipient"`
+		Phone      string     `json:"phone"`
+		Address    string     `json:"address"`
+		Province   string     `json:"province"`
+		City       string     `json:"city"`
+		District   string     `json:"district"`
+		PostalCode string     `json:"postal_code"`
+		IsDefault  bool       `json:"is_default"`
+		CreatedAt  time.Time  `json:"created_at"`
+		UpdatedAt  *time.Time `json:"updated_at"`
+	}
+
+	// ShippingAddressData is the address copied onto an order, so later edits to the
+	// customer's address book don't change where an existing order ships.
+	ShippingAddressData struct {
+		AddressID  *int   `json:"address_id,omitempty"`
+		Label      string `json:"label,omitempty"`
+		Recipient  string `json:"recipient"`
+		Phone      string `json:"phone"`
+		Address    string `json:"address"`
+		Province   string `json:"province"`
+		City       string `json:"city"`
+		District   string `json:"district"`
+		PostalCode string `json:"postal_code"`
 	}
 
 	// CustomerCheckActiveOrderByPhone is the response when checking active order by phone (get-or-create customer).
@@ -38,15 +141,38 @@ type (
 	}
 
 	ProductData struct {
-		ID            int        `json:"id"`
-		Name          string     `json:"name"`
-		Description   string     `json:"description"`
-		Price         int        `json:"price"`
-		OriginalPrice int        `json:"original_price"`
-		ImageURL      string     `json:"image_url"`
-		IsActive      bool       `json:"is_active"`
-		CreatedAt     time.Time  `json:"created_at"`
-		UpdatedAt     *time.Time `json:"updated_at"`
+		ID            int                `json:"id"`
+		Name          string             `json:"name"`
+		Description   string             `json:"description"`
+		Price         int                `json:"price"`
+		OriginalPrice int                `json:"original_price"`
+		ImageURL      string             `json:"image_url"`
+		IsActive      bool               `json:"is_active"`
+		CategoryID    *int               `json:"category_id"`
+		Position      int                `json:"position"`
+		Tags          []string           `json:"tags"`
+		Images        []ProductImageData `json:"images"`
+		CreatedAt     time.Time          `json:"created_at"`
+		UpdatedAt     *time.Time         `json:"updated_at"`
+	}
+
+	ProductImageData struct {
+		ID           int       `json:"id"`
+		ThumbnailURL string    `json:"thumbnail_url"`
+		MediumURL    string    `json:"medium_url"`
+		FullURL      string    `json:"full_url"`
+		Position     int       `json:"position"`
+		IsPrimary    bool      `json:"is_primary"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+
+	ProductCategoryData struct {
+		ID        int        `json:"id"`
+		ParentID  *int       `json:"parent_id"`
+		Name      string     `json:"name"`
+		Position  int        `json:"position"`
+		CreatedAt time.Time  `json:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at"`
 	}
 
 	UploadImageData struct {
@@ -54,27 +180,66 @@ type (
 	}
 
 	OrderData struct {
-		ID                int                `json:"id"`
-		CustomerName      string             `json:"customer_name"`
-		IsCustomerDeleted bool               `json:"is_customer_deleted"`
-		TotalPrice        int                `json:"total_price"`
-		Status            string             `json:"status"`
-		PaymentStatus     string             `json:"payment_status"`
-		Notes             string             `json:"notes"`
-		OrderItems        []OrderItemData    `json:"order_items,omitempty"`
-		OrderPayments     []OrderPaymentData `json:"order_payments,omitempty"`
-		CreatedAt         time.Time          `json:"created_at"`
-		UpdatedAt         *time.Time         `json:"updated_at"`
+		ID                int                  `json:"id"`
+		CustomerName      string               `json:"customer_name"`
+		IsCustomerDeleted bool                 `json:"is_customer_deleted"`
+		TotalPrice        int                  `json:"total_price"`
+		UniqueCode        int                  `json:"unique_code,omitempty"`
+		AmountDue         int                  `json:"amount_due,omitempty"` // total_price plus unique_code; set only when a code is assigned
+		InvoiceNumber     string               `json:"invoice_number,omitempty"`
+		DPAmount          int                  `json:"dp_amount,omitempty"`
+		DPDueAt           *time.Time           `json:"dp_due_at,omitempty"`
+		DPOverdue         bool                 `json:"dp_overdue,omitempty"`
+		Status            string               `json:"status"`
+		CancelReason      string               `json:"cancel_reason,omitempty"`
+		CancelNotes       string               `json:"cancel_notes,omitempty"`
+		CancelledAt       *time.Time           `json:"cancelled_at,omitempty"`
+		ShortageNotify    bool                 `json:"shortage_notify,omitempty"`
+		PaymentStatus     string               `json:"payment_status"`
+		Notes             string               `json:"notes"`
+		ShippingAddress   *ShippingAddressData `json:"shipping_address,omitempty"`
+		OrderItems        []OrderItemData      `json:"order_items,omitempty"`
+		OrderPayments     []OrderPaymentData   `json:"order_payments,omitempty"`
+		CreatedAt         time.Time            `json:"created_at"`
+		UpdatedAt         *time.Time           `json:"updated_at"`
+	}
+
+	DPRuleData struct {
+		ID         int        `json:"id"`
+		Name       string     `json:"name"`
+		Percent    int        `json:"percent"`
+		DueDays    *int       `json:"due_days,omitempty"`
+		DueDate    *time.Time `json:"due_date,omitempty"`
+		AutoCancel bool       `json:"auto_cancel"`
+		IsDefault  bool       `json:"is_default"`
+		CreatedAt  time.Time  `json:"created_at"`
+		UpdatedAt  *time.Time `json:"updated_at"`
+	}
+
+	ShareLinkData struct {
+		ID         int        `json:"id"`
+		Token      string     `json:"token"`
+		Label      string     `json:"label"`
+		ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+		RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+		Active     bool       `json:"active"`
+		ProductIDs []int      `json:"product_ids"`
+		TempOrders int        `json:"temp_orders"`
+		Accepted   int        `json:"accepted"`
+		CreatedAt  time.Time  `json:"created_at"`
+		UpdatedAt  *time.Time `json:"updated_at"`
 	}
 
 	OrderItemData struct {
-		ID          int        `json:"id"`
-		OrderID     int        `json:"order_id,omitempty"`
-		ProductName string     `json:"product_name"`
-		Price       int        `json:"price"`
-		Qty         int        `json:"qty"`
-		CreatedAt   time.Time  `json:"created_at"`
-		UpdatedAt   *time.Time `json:"updated_at"`
+		ID           int        `json:"id"`
+		OrderID      int        `json:"order_id,omitempty"`
+		ProductName  string     `json:"product_name"`
+		Price        int        `json:"price"`
+		Qty          int        `json:"qty"`
+		ShortageQty  int        `json:"shortage_qty,omitempty"`
+		AllocatedQty *int       `json:"allocated_qty,omitempty"`
+		CreatedAt    time.Time  `json:"created_at"`
+		UpdatedAt    *time.Time `json:"updated_at"`
 	}
 
 	OrderPaymentData struct {
@@ -85,31 +250,140 @@ type (
 		UpdatedAt *time.Time `json:"updated_at"`
 	}
 
+	OrderRefundData struct {
+		ID        int       `json:"id"`
+		OrderID   int       `json:"order_id"`
+		PaymentID int       `json:"payment_id"`
+		Amount    int       `json:"amount"`
+		Method    string    `json:"method"`
+		Reason    string    `json:"reason"`
+		ProofURL  string    `json:"proof_url"`
+		Notes     string    `json:"notes"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	BankMutationData struct {
+		Date        time.Time `json:"date"`
+		Description string    `json:"description"`
+		Amount      int       `json:"amount"`
+	}
+
+	// PaymentMatchData is a proposed order payment for a bank statement credit.
+	// Score is 0-100; MatchedBy lists the signals that produced the match.
+	PaymentMatchData struct {
+		Mutation     BankMutationData `json:"mutation"`
+		OrderID      int              `json:"order_id"`
+		CustomerName string           `json:"customer_name"`
+		Outstanding  int              `json:"outstanding"`
+		Amount       int              `json:"amount"`
+		Score        int              `json:"score"`
+		MatchedBy    []string         `json:"matched_by"`
+	}
+
+	BankStatementMatchData struct {
+		Bank      string             `json:"bank"`
+		Matches   []PaymentMatchData `json:"matches"`
+		Unmatched []BankMutationData `json:"unmatched"`
+	}
+
+	// ImportResultData is the outcome of a customer or product import. Created
+	// and Updated count the rows that are (or, on a dry run, would be) inserted
+	// and upserted. When Errors is not empty nothing was written.
+	ImportResultData struct {
+		DryRun    bool                 `json:"dry_run"`
+		TotalRows int                  `json:"total_rows"`
+		Created   int                  `json:"created"`
+		Updated   int                  `json:"updated"`
+		Errors    []ImportRowErrorData `json:"errors"`
+	}
+
+	// ImportRowErrorData is a validation error of one cell. Row is the
+	// spreadsheet row number, counting the header row.
+	ImportRowErrorData struct {
+		Row     int    `json:"row"`
+		Field   string `json:"field"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	// ShopImportData is the outcome of restoring a shop archive: the rows
+	// restored per archive table and the number of images uploaded again.
+	ShopImportData struct {
+		ShopID int            `json:"shop_id"`
+		Tables map[string]int `json:"tables"`
+		Images int            `json:"images"`
+	}
+
 	TempOrderData struct {
-		ID             int                 `json:"id"`
-		CustomerName   string              `json:"customer_name"`
-		CustomerPhone  string              `json:"customer_phone"`
-		TotalPrice     int                 `json:"total_price"`
-		Status         string              `json:"status"`
-		TempOrderItems []TempOrderItemData `json:"order_items,omitempty"`
-		CreatedAt      time.Time           `json:"created_at"`
-		UpdatedAt      *time.Time          `json:"updated_at"`
+		ID              int                  `json:"id"`
+		ShareLinkID     *int                 `json:"share_link_id,omitempty"`
+		CustomerName    string               `json:"customer_name"`
+		CustomerPhone   string               `json:"customer_phone"`
+		TotalPrice      int                  `json:"total_price"`
+		Status          string               `json:"status"`
+		ShippingAddress *ShippingAddressData `json:"shipping_address,omitempty"`
+		TempOrderItems  []TempOrderItemData  `json:"order_items,omitempty"`
+		CreatedAt       time.Time            `json:"created_at"`
+		UpdatedAt       *time.Time           `json:"updated_at"`
 	}
 
 	TempOrderItemData struct {
-		ID          int        `json:"id"`
-		TempOrderID int        `json:"temp_order_id,omitempty"`
-		ProductID   int        `json:"product_id,omitempty"`
-		ProductName string     `json:"product_name"`
-		Price       int        `json:"price"`
-		Qty         int        `json:"qty"`
-		CreatedAt   time.Time  `json:"created_at"`
+		ID          int       `json:"id"`
+		TempOrderID int       `json:"temp_order_id,omitempty"`
+		ProductID   int       `json:"product_id,omitempty"`
+		ProductName string    `json:"product_name"`
+		Price       int       `json:"price"`
+		Qty         int       `json:"qty"`
+		CreatedAt   time.Time `json:"created_at"`
 	}
 
 	PurchaseListProductData struct {
+		ProductID   int    `json:"product_id"`
 		ProductName string `json:"product_name"`
 		Price       int    `json:"price"`
 		Qty         int    `json:"qty"`
+		Status      string `json:"status"`
+		BoughtQty   int    `json:"bought_qty"`
+		ActualCost  int    `json:"actual_cost"`
+		Notes       string `json:"notes,omitempty"`
+	}
+
+	PurchaseListItemData struct {
+		ProductID  int        `json:"product_id"`
+		Status     string     `json:"status"`
+		BoughtQty  int        `json:"bought_qty"`
+		ActualCost int        `json:"actual_cost"`
+		Notes      string     `json:"notes,omitempty"`
+		CreatedAt  time.Time  `json:"created_at"`
+		UpdatedAt  *time.Time `json:"updated_at"`
+	}
+
+	ShortageAllocationData struct {
+		ProductID int                 `json:"product_id"`
+		Policy    string              `json:"policy"`
+		Preview   bool                `json:"preview"` // nothing was written
+		Ordered   int                 `json:"ordered"`
+		Available int                 `json:"available"`
+		Shortage  int                 `json:"shortage"`
+		Orders    []ShortageOrderData `json:"orders"`
+	}
+
+	// StockAllocationData is a run of the allocation over the whole purchase list.
+	StockAllocationData struct {
+		Policy   string                   `json:"policy"`
+		Preview  bool                     `json:"preview"`
+		Products []ShortageAllocationData `json:"products"`
+	}
+
+	// ShortageOrderData is what one order lost to a shortage.
+	ShortageOrderData struct {
+		OrderID      int    `json:"order_id"`
+		CustomerName string `json:"customer_name"`
+		OrderItemID  int    `json:"order_item_id"`
+		Qty          int    `json:"qty"` // quantity left on the order item
+		Cut          int    `json:"cut"`
+		TotalPrice   int    `json:"total_price"`
+		CreditAmount int    `json:"credit_amount,omitempty"` // overpayment moved to customer credit
 	}
 
 	PlanData struct {
@@ -139,9 +413,43 @@ type (
 
 	OrderStatsData struct {
 		TotalRevenue int `json:"total_revenue"`
+		TotalRefunds int `json:"total_refunds"`
 		NetSales     int `json:"net_sales"`
 	}
 
+	OrderTimeseriesData struct {
+		Bucket       string                 `json:"bucket"`
+		Timezone     string                 `json:"timezone"`
+		DateFrom     string                 `json:"date_from"`
+		DateTo       string                 `json:"date_to"`
+		Points       []OrderTimeseriesPoint `json:"points"`
+		TopProducts  []TopProductData       `json:"top_products"`
+		TopCustomers []TopCustomerData      `json:"top_customers"`
+	}
+
+	OrderTimeseriesPoint struct {
+		Date              string `json:"date"`
+		Orders            int    `json:"orders"`
+		Revenue           int    `json:"revenue"`
+		NetSales          int    `json:"net_sales"`
+		NewCustomers      int    `json:"new_customers"`
+		AverageOrderValue int    `json:"average_order_value"`
+	}
+
+	TopProductData struct {
+		ProductID   int    `json:"product_id"`
+		ProductName string `json:"product_name"`
+		Qty         int    `json:"qty"`
+		Sales       int    `json:"sales"`
+	}
+
+	TopCustomerData struct {
+		CustomerID   int    `json:"customer_id"`
+		CustomerName string `json:"customer_name"`
+		Orders       int    `json:"orders"`
+		Sales        int    `json:"sales"`
+	}
+
 	SystemStatsData struct {
 		TotalShops    int `json:"total_shops"`
 		SubsTrialing  int `json:"subs_trialing"`
@@ -177,4 +485,11 @@ type (
 		PaidAt          *time.Time `json:"paid_at,omitempty"`
 		CreatedAt       time.Time  `json:"created_at"`
 	}
+
+	SystemUploadGCData struct {
+		DeletedCount int      `json:"deleted_count"`
+		FreedBytes   int64    `json:"freed_bytes"`
+		FailedCount  int      `json:"failed_count"`
+		Keys         []string `json:"keys"`
+	}
 )
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// LocalURLPrefix is the path local uploads are served from.
+const LocalURLPrefix = "/uploads"
+
+// Local stores objects as files under a directory. It is also the
+// http.Handler that serves them, once stripped of the URL prefix.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal returns a backend storing files under dir, served from baseURL.
+func NewLocal(dir, baseURL string) *Local {
+	return &Local{dir: dir, baseURL: baseURL}
+}
+
+func (l *Local) Put(ctx context.Context, key, contentType string, data []byte) error {
+	filePath := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	return nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (l *Local) URL(key string) string {
+	return l.baseURL + "/" + key
+}
+
+func (l *Local) Key(url string) (string, bool) {
+	return keyFromURL(l.baseURL, url)
+}
+
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	http.FileServer(http.Dir(l.dir)).ServeHTTP(w, r)
+}
+
+// path returns the file of key. Cleaning against a rooted path keeps ".."
+// from leaving the directory.
+func (l *Local) path(key string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Memory keeps objects in a map. It is meant for tests.
+type Memory struct {
+	baseURL string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// NewMemory returns an empty backend whose objects are served from baseURL.
+func NewMemory(baseURL string) *Memory {
+	return &Memory{baseURL: baseURL, objects: map[string][]byte{}}
+}
+
+func (m *Memory) Put(ctx context.Context, key, contentType string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *Memory) URL(key string) string {
+	return m.baseURL + "/" + key
+}
+
+func (m *Memory) Key(url string) (string, bool) {
+	return keyFromURL(m.baseURL, url)
+}
+
+// Keys returns the keys of the stored objects, sorted.
+func (m *Memory) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Config points at a bucket of an S3-compatible service.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PublicURL is where the bucket's objects are served from
+	PublicURL string
+}
+
+// S3 stores objects in an S3-compatible bucket, such as Cloudflare R2.
+type S3 struct {
+	client    *s3.Client
+	bucket    string
+	publicURL string
+}
+
+// NewS3 returns a backend storing objects in the bucket of c.
+func NewS3(c S3Config) *S3 {
+	client := s3.New(s3.Options{
+		Region:       c.Region,
+		BaseEndpoint: aws.String(c.Endpoint),
+		Credentials:  credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, ""),
+		UsePathStyle: true,
+	})
+	return &S3{client: client, bucket: c.Bucket, publicURL: c.PublicURL}
+}
+
+func (s *S3) Put(ctx context.Context, key, contentType string, data []byte) error {
+	// The AWS SDK v2 reads the body once to compute a CRC32 checksum, then
+	// seeks back to the start before sending, so the body must be seekable;
+	// a non-seekable reader produces a BadDigest error from Cloudflare R2.
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+// Delete removes the object under key. S3 does not say whether there was
+// one, so a missing object is not an error.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *S3) URL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+func (s *S3) Key(url string) (string, bool) {
+	return keyFromURL(s.publicURL, url)
+}
@@ -0,0 +1,62 @@
+// Package storage keeps uploaded files behind one interface, whether they
+// live on the local filesystem, in an S3-compatible bucket such as Cloudflare
+// R2, or in memory for tests.
+package storage
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/zeirash/recapo/arion/common/config"
+)
+
+// ErrNotFound is returned for an object that is not stored.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Backend stores objects under slash-separated keys such as
+// "products/abc.webp". Every object is served publicly from URL(key).
+type Backend interface {
+	// Put stores data under key, replacing any object already there.
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Get returns the object under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object under key. Backends that can tell without
+	// another request return ErrNotFound when there is none.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the object under key.
+	URL(key string) string
+	// Key returns the key of the object served from url, or false when url
+	// does not point into the backend.
+	Key(url string) (string, bool)
+}
+
+// New returns the backend the config points to: the R2 bucket when one is
+// set, the local upload directory otherwise.
+func New(c config.Config) Backend {
+	if c.R2BucketName != "" {
+		return NewS3(S3Config{
+			Endpoint:        "https://" + c.R2AccountID + ".r2.cloudflarestorage.com",
+			Region:          "auto",
+			Bucket:          c.R2BucketName,
+			AccessKeyID:     c.R2AccessKeyID,
+			SecretAccessKey: c.R2SecretAccessKey,
+			PublicURL:       c.R2PublicURL,
+		})
+	}
+	return NewLocal(c.UploadDir, LocalURLPrefix)
+}
+
+// keyFromURL returns the key of url under baseURL. The key is cleaned so it
+// cannot climb out of the backend with "..".
+func keyFromURL(baseURL, url string) (string, bool) {
+	if !strings.HasPrefix(url, baseURL+"/") {
+		return "", false
+	}
+	key := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(url, baseURL+"/")), "/")
+	if key == "" {
+		return "", false
+	}
+	return key, true
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zeirash/recapo/arion/common/config"
+)
+
+// fakeS3 serves path-style S3 object requests from a map.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// backends returns one of each backend, all serving from their own base URL.
+func backends(t *testing.T) map[string]Backend {
+	t.Helper()
+
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	t.Cleanup(server.Close)
+
+	return map[string]Backend{
+		"local":  NewLocal(t.TempDir(), "/uploads"),
+		"memory": NewMemory("/uploads"),
+		"s3": NewS3(S3Config{
+			Endpoint:        server.URL,
+			Region:          "auto",
+			Bucket:          "test-bucket",
+			AccessKeyID:     "key",
+			SecretAccessKey: "secret",
+			PublicURL:       "https://pub-test.r2.dev",
+		}),
+	}
+}
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := b.Put(ctx, "products/a.webp", "image/webp", []byte("image")); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			got, err := b.Get(ctx, "products/a.webp")
+			if err != nil || string(got) != "image" {
+				t.Fatalf("Get() = %q, %v, want image", got, err)
+			}
+
+			if err := b.Put(ctx, "products/a.webp", "image/webp", []byte("replaced")); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if got, _ := b.Get(ctx, "products/a.webp"); string(got) != "replaced" {
+				t.Errorf("Get() after replace = %q, want replaced", got)
+			}
+
+			key, ok := b.Key(b.URL("products/a.webp"))
+			if !ok || key != "products/a.webp" {
+				t.Errorf("Key(URL()) = %q, %v, want products/a.webp", key, ok)
+			}
+
+			if err := b.Delete(ctx, "products/a.webp"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := b.Get(ctx, "products/a.webp"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+			}
+			if err := b.Delete(ctx, "products/a.webp"); err != nil && !errors.Is(err, ErrNotFound) {
+				t.Errorf("Delete() of missing object error = %v", err)
+			}
+		})
+	}
+}
+
+func TestBackend_Key(t *testing.T) {
+	b := NewMemory("https://pub-test.r2.dev")
+
+	tests := []struct {
+		name   string
+		url    string
+		want   string
+		wantOK bool
+	}{
+		{name: "object URL", url: "https://pub-test.r2.dev/logos/a.png", want: "logos/a.png", wantOK: true},
+		{name: "foreign URL", url: "https://example.com/logos/a.png"},
+		{name: "look-alike host", url: "https://pub-test.r2.dev.example.com/a.png"},
+		{name: "base URL only", url: "https://pub-test.r2.dev/"},
+		{name: "climbs out with ..", url: "https://pub-test.r2.dev/../../etc/passwd", want: "etc/passwd", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := b.Key(tt.url)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Key(%q) = %q, %v, want %q, %v", tt.url, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLocal_staysInDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	b := NewLocal(dir, "/uploads")
+
+	if err := b.Put(context.Background(), "../outside.png", "image/png", []byte("x")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "outside.png")); err == nil {
+		t.Error("Put() wrote outside the upload directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.png")); err != nil {
+		t.Errorf("Put() did not write inside the upload directory: %v", err)
+	}
+}
+
+func TestLocal_ServeHTTP(t *testing.T) {
+	b := NewLocal(t.TempDir(), "/uploads")
+	if err := b.Put(context.Background(), "logos/a.png", "image/png", []byte("logo")); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	http.StripPrefix("/uploads/", b).ServeHTTP(rec, httptest.NewRequest("GET", "/uploads/logos/a.png", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "logo" {
+		t.Errorf("ServeHTTP() = %d %q, want 200 logo", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, ok := New(config.Config{UploadDir: t.TempDir()}).(*Local); !ok {
+		t.Error("New() without a bucket is not the local backend")
+	}
+
+	b := New(config.Config{R2BucketName: "bucket", R2AccountID: "acct", R2PublicURL: "https://pub-test.r2.dev"})
+	if _, ok := b.(*S3); !ok {
+		t.Fatal("New() with a bucket is not the S3 backend")
+	}
+	if url := b.URL("logos/a.png"); !strings.HasPrefix(url, "https://pub-test.r2.dev/") {
+		t.Errorf("URL() = %q, want the public R2 URL", url)
+	}
+}
@@ -0,0 +1,874 @@
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// ErrInvalidQuality is returned for a lossy quality outside 0 to 100.
+var ErrInvalidQuality = errors.New("webp: quality must be 0 to 100")
+
+// The prediction modes the lossy encoder picks from. Luma is always
+// predicted as one 16x16 block, so the 4x4 modes are never written.
+const (
+	predDC = iota
+	predTM
+	predVE
+	predHE
+)
+
+// EncodeLossy writes m to w as a lossy WebP file (VP8). quality runs from 0,
+// the smallest file, to 100, the closest copy. Colours are stored as 4:2:0
+// YCbCr; an image that is not fully opaque keeps its alpha exactly, coded
+// losslessly in an ALPH chunk.
+func EncodeLossy(w io.Writer, m image.Image, quality int) error {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	// A VP8 frame header has one bit less for each side than a VP8L one.
+	if width < 1 || height < 1 || width >= maxDimension || height >= maxDimension {
+		return ErrInvalidSize
+	}
+	if quality < 0 || quality > 100 {
+		return ErrInvalidQuality
+	}
+
+	argb, hasAlpha := toARGB(m)
+	e := newLossyEncoder(argb, width, height, (100-quality)*127/100)
+	frame := e.encode()
+
+	var chunks []byte
+	if hasAlpha {
+		vp8x := make([]byte, 10)
+		vp8x[0] = 0x10 // alpha
+		putUint24(vp8x[4:], width-1)
+		putUint24(vp8x[7:], height-1)
+		chunks = appendChunk(chunks, "VP8X", vp8x)
+
+		alpha := make([]uint32, len(argb))
+		for i, p := range argb {
+			alpha[i] = 0xff000000 | (p>>24)<<8
+		}
+		bw := &bitWriter{}
+		writeLossless(bw, alpha, width, height)
+		// No pre-processing or filtering; the plane is coded as VP8L.
+		chunks = appendChunk(chunks, "ALPH", append([]byte{1}, bw.bytes()...))
+	}
+	chunks = appendChunk(chunks, "VP8 ", frame)
+
+	header := make([]byte, 12)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+len(chunks)))
+	copy(header[8:], "WEBP")
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(chunks)
+	return err
+}
+
+// appendChunk appends a RIFF chunk holding data to buf, padded to an even
+// length.
+func appendChunk(buf []byte, fourCC string, data []byte) []byte {
+	buf = append(buf, fourCC...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(data)))
+	buf = append(buf, data...)
+	if len(data)&1 == 1 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+func putUint24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// lossyEncoder codes one VP8 key frame. Every macroblock predicts its luma
+// as a 16x16 block and its chroma as 8x8 blocks from the macroblocks already
+// reconstructed above and to the left of it, so it has to reconstruct each
+// one exactly as a decoder will.
+type lossyEncoder struct {
+	width, height int
+	mbw, mbh      int
+	qIndex        int
+	quant         quantizer
+
+	// y, u and v are the source planes, padded out to whole macroblocks by
+	// repeating the last row and column. ry, ru and rv are the same planes
+	// as a decoder reconstructs them.
+	y, u, v    []uint8
+	ry, ru, rv []uint8
+	yStride    int
+	uvStride   int
+
+	mbs []macroblock
+}
+
+// quantizer holds the DC and AC steps of each kind of block.
+type quantizer struct {
+	y1, y2, uv [2]int32
+}
+
+// macroblock is what the encoder decided for one macroblock. levels holds
+// the quantized coefficients of its 16 luma blocks, 4 U and 4 V blocks and
+// the block of luma DCs, each in raster order.
+type macroblock struct {
+	yMode, uvMode uint8
+	skip          bool
+	levels        [25][16]int16
+}
+
+// The quantization bias rounds a coefficient up to the next level once its
+// remainder is that many 256ths of the step. AC coefficients are rounded
+// down a little harder than DC ones, since they cost more bits to code.
+var quantBias = [3][2]int32{
+	planeY1WithY2: {96, 110},
+	planeY2:       {96, 108},
+	planeUV:       {110, 115},
+}
+
+func newLossyEncoder(argb []uint32, width, height, qIndex int) *lossyEncoder {
+	e := &lossyEncoder{
+		width:  width,
+		height: height,
+		mbw:    (width + 15) / 16,
+		mbh:    (height + 15) / 16,
+		qIndex: qIndex,
+	}
+	e.yStride, e.uvStride = e.mbw*16, e.mbw*8
+	e.y = make([]uint8, e.yStride*e.mbh*16)
+	e.u = make([]uint8, e.uvStride*e.mbh*8)
+	e.v = make([]uint8, e.uvStride*e.mbh*8)
+	e.ry = make([]uint8, len(e.y))
+	e.ru = make([]uint8, len(e.u))
+	e.rv = make([]uint8, len(e.v))
+	e.mbs = make([]macroblock, e.mbw*e.mbh)
+
+	e.quant.y1 = [2]int32{int32(dequantTableDC[qIndex]), int32(dequantTableAC[qIndex])}
+	e.quant.y2 = [2]int32{int32(dequantTableDC[qIndex]) * 2, max(int32(dequantTableAC[qIndex])*155/100, 8)}
+	e.quant.uv = [2]int32{int32(dequantTableDC[min(qIndex, 117)]), int32(dequantTableAC[qIndex])}
+
+	e.toYUV(argb)
+	return e
+}
+
+// toYUV converts the pixels to limited range BT.601 YCbCr, the colour space
+// VP8 is decoded in, averaging each 2x2 block of pixels for the chroma.
+func (e *lossyEncoder) toYUV(argb []uint32) {
+	pixel := func(x, y int) (r, g, b int32) {
+		p := argb[min(y, e.height-1)*e.width+min(x, e.width-1)]
+		return int32(p>>16) & 0xff, int32(p>>8) & 0xff, int32(p) & 0xff
+	}
+	for y := 0; y < e.mbh*16; y++ {
+		for x := 0; x < e.yStride; x++ {
+			r, g, b := pixel(x, y)
+			e.y[y*e.yStride+x] = uint8((16839*r + 33059*g + 6420*b + 1<<15 + 16<<16) >> 16)
+		}
+	}
+	for y := 0; y < e.mbh*8; y++ {
+		for x := 0; x < e.uvStride; x++ {
+			var r, g, b int32
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb := pixel(2*x+d[0], 2*y+d[1])
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			e.u[y*e.uvStride+x] = clipUV(-9719*r - 19081*g + 28800*b)
+			e.v[y*e.uvStride+x] = clipUV(28800*r - 24116*g - 4684*b)
+		}
+	}
+}
+
+// clipUV scales a chroma value computed from the sum of four pixels.
+func clipUV(v int32) uint8 {
+	return uint8(clamp255(int((v + 1<<17 + 128<<18) >> 18)))
+}
+
+// encode returns the VP8 frame: its header, the first partition with the
+// frame settings and the macroblock modes, and the partition of tokens.
+func (e *lossyEncoder) encode() []byte {
+	for mby := 0; mby < e.mbh; mby++ {
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+
+	// The first pass over the tokens only counts them, so the probabilities
+	// can be fitted to this image before they are written.
+	stats := new([nPlane][nBand][nContext][nProb][2]uint32)
+	e.writeTokens(&tokenWriter{probs: &defaultTokenProb, stats: stats})
+	probs, updated := fitTokenProbs(stats)
+	tokens := &boolEncoder{}
+	e.writeTokens(&tokenWriter{enc: tokens, probs: &probs})
+
+	fp := &boolEncoder{}
+	fp.putLiteral(0, 1) // colour space
+	fp.putLiteral(0, 1) // clamping type
+	fp.putLiteral(0, 1) // no segmentation
+	fp.putLiteral(0, 1) // normal loop filter
+	fp.putLiteral(0, 6) // but a level of 0 turns it off
+	fp.putLiteral(0, 3) // sharpness
+	fp.putLiteral(0, 1) // no loop filter deltas
+	fp.putLiteral(0, 2) // a single token partition
+	fp.putLiteral(uint32(e.qIndex), 7)
+	for i := 0; i < 5; i++ {
+		fp.putLiteral(0, 1) // no quantizer deltas
+	}
+	fp.putLiteral(0, 1) // refresh entropy probabilities
+	for i := range probs {
+		for j := range probs[i] {
+			for k := range probs[i][j] {
+				for l := range probs[i][j][k] {
+					update := updated[i][j][k][l]
+					fp.putBit(update, tokenProbUpdateProb[i][j][k][l])
+					if update {
+						fp.putLiteral(uint32(probs[i][j][k][l]), 8)
+					}
+				}
+			}
+		}
+	}
+
+	skipped := 0
+	for i := range e.mbs {
+		if e.mbs[i].skip {
+			skipped++
+		}
+	}
+	skipProb := uint8(0)
+	fp.putLiteral(uint32(min(skipped, 1)), 1)
+	if skipped > 0 {
+		skipProb = fitProb(uint32(len(e.mbs)-skipped), uint32(skipped))
+		fp.putLiteral(uint32(skipProb), 8)
+	}
+	for i := range e.mbs {
+		mb := &e.mbs[i]
+		if skipped > 0 {
+			fp.putBit(mb.skip, skipProb)
+		}
+		fp.putBit(true, 145) // 16x16 luma prediction
+		switch mb.yMode {
+		case predDC:
+			fp.putBit(false, 156)
+			fp.putBit(false, 163)
+		case predVE:
+			fp.putBit(false, 156)
+			fp.putBit(true, 163)
+		case predHE:
+			fp.putBit(true, 156)
+			fp.putBit(false, 128)
+		case predTM:
+			fp.putBit(true, 156)
+			fp.putBit(true, 128)
+		}
+		fp.putBit(mb.uvMode != predDC, 142)
+		if mb.uvMode != predDC {
+			fp.putBit(mb.uvMode != predVE, 114)
+			if mb.uvMode != predVE {
+				fp.putBit(mb.uvMode == predTM, 183)
+			}
+		}
+	}
+
+	first := fp.finish()
+	tag := uint32(len(first))<<5 | 1<<4 // a key frame, version 0, shown
+	frame := []byte{
+		byte(tag), byte(tag >> 8), byte(tag >> 16),
+		0x9d, 0x01, 0x2a,
+		byte(e.width), byte(e.width >> 8),
+		byte(e.height), byte(e.height >> 8),
+	}
+	frame = append(frame, first...)
+	return append(frame, tokens.finish()...)
+}
+
+// encodeMacroblock picks the prediction modes of a macroblock, quantizes
+// what is left over and reconstructs it.
+func (e *lossyEncoder) encodeMacroblock(mbx, mby int) {
+	mb := &e.mbs[mby*e.mbw+mbx]
+
+	var yPred [256]uint8
+	yTop, yLeft, yTopLeft := e.edges(e.ry, e.yStride, 16, mbx, mby)
+	mb.yMode = bestMode(mbx, mby, func(mode uint8) int {
+		predictBlock(yPred[:], 16, mode, yTop, yLeft, yTopLeft, mbx, mby)
+		return sse(e.y, e.yStride, 16, mbx, mby, yPred[:])
+	})
+	predictBlock(yPred[:], 16, mb.yMode, yTop, yLeft, yTopLeft, mbx, mby)
+
+	var uPred, vPred [64]uint8
+	uTop, uLeft, uTopLeft := e.edges(e.ru, e.uvStride, 8, mbx, mby)
+	vTop, vLeft, vTopLeft := e.edges(e.rv, e.uvStride, 8, mbx, mby)
+	mb.uvMode = bestMode(mbx, mby, func(mode uint8) int {
+		predictBlock(uPred[:], 8, mode, uTop, uLeft, uTopLeft, mbx, mby)
+		predictBlock(vPred[:], 8, mode, vTop, vLeft, vTopLeft, mbx, mby)
+		return sse(e.u, e.uvStride, 8, mbx, mby, uPred[:]) + sse(e.v, e.uvStride, 8, mbx, mby, vPred[:])
+	})
+	predictBlock(uPred[:], 8, mb.uvMode, uTop, uLeft, uTopLeft, mbx, mby)
+	predictBlock(vPred[:], 8, mb.uvMode, vTop, vLeft, vTopLeft, mbx, mby)
+
+	// Luma: the DCs of the 16 blocks are taken out and coded together
+	// through the Walsh-Hadamard transform.
+	var dcs, coeffs [16]int32
+	for n := 0; n < 16; n++ {
+		forwardBlock(e.y, e.yStride, mbx*16+n%4*4, mby*16+n/4*4, yPred[:], 16, n%4*4, n/4*4, &coeffs)
+		dcs[n] = coeffs[0]
+		coeffs[0] = 0
+		quantize(&coeffs, &mb.levels[n], e.quant.y1, quantBias[planeY1WithY2])
+	}
+	fwht(&dcs, &coeffs)
+	quantize(&coeffs, &mb.levels[24], e.quant.y2, quantBias[planeY2])
+
+	for n := 0; n < 4; n++ {
+		x, y := n%2*4, n/2*4
+		forwardBlock(e.u, e.uvStride, mbx*8+x, mby*8+y, uPred[:], 8, x, y, &coeffs)
+		quantize(&coeffs, &mb.levels[16+n], e.quant.uv, quantBias[planeUV])
+		forwardBlock(e.v, e.uvStride, mbx*8+x, mby*8+y, vPred[:], 8, x, y, &coeffs)
+		quantize(&coeffs, &mb.levels[20+n], e.quant.uv, quantBias[planeUV])
+	}
+
+	mb.skip = true
+	for i := range mb.levels {
+		if mb.levels[i] != [16]int16{} {
+			mb.skip = false
+			break
+		}
+	}
+
+	// Reconstruct the macroblock the way a decoder does.
+	var dequant [16]int32
+	dequantize(&mb.levels[24], &dequant, e.quant.y2)
+	iwht(&dequant, &dcs)
+	for n := 0; n < 16; n++ {
+		dequantize(&mb.levels[n], &dequant, e.quant.y1)
+		dequant[0] = dcs[n]
+		inverseBlock(e.ry, e.yStride, mbx*16+n%4*4, mby*16+n/4*4, yPred[:], 16, n%4*4, n/4*4, &dequant)
+	}
+	for n := 0; n < 4; n++ {
+		x, y := n%2*4, n/2*4
+		dequantize(&mb.levels[16+n], &dequant, e.quant.uv)
+		inverseBlock(e.ru, e.uvStride, mbx*8+x, mby*8+y, uPred[:], 8, x, y, &dequant)
+		dequantize(&mb.levels[20+n], &dequant, e.quant.uv)
+		inverseBlock(e.rv, e.uvStride, mbx*8+x, mby*8+y, vPred[:], 8, x, y, &dequant)
+	}
+}
+
+// edges returns the reconstructed row above and column left of a block of
+// size pixels, and the pixel above and left of it. Outside the frame the
+// row above is 127 and the column left is 129, as decoders assume.
+func (e *lossyEncoder) edges(plane []uint8, stride, size, mbx, mby int) (top, left []uint8, topLeft uint8) {
+	top, left = make([]uint8, size), make([]uint8, size)
+	x0, y0 := mbx*size, mby*size
+	for i := 0; i < size; i++ {
+		top[i], left[i] = 127, 129
+		if mby > 0 {
+			top[i] = plane[(y0-1)*stride+x0+i]
+		}
+		if mbx > 0 {
+			left[i] = plane[(y0+i)*stride+x0-1]
+		}
+	}
+	topLeft = 127
+	if mby > 0 && mbx > 0 {
+		topLeft = plane[(y0-1)*stride+x0-1]
+	} else if mby > 0 {
+		topLeft = 129
+	}
+	return top, left, topLeft
+}
+
+// bestMode returns the prediction mode with the smallest cost. Modes that
+// need pixels outside the frame are left out, so the corner values they
+// would be predicted from can't differ between decoders.
+func bestMode(mbx, mby int, cost func(mode uint8) int) uint8 {
+	best, bestCost := uint8(predDC), cost(predDC)
+	for _, mode := range []uint8{predVE, predHE, predTM} {
+		if (mode != predHE && mby == 0) || (mode != predVE && mbx == 0) {
+			continue
+		}
+		if c := cost(mode); c < bestCost {
+			best, bestCost = mode, c
+		}
+	}
+	return best
+}
+
+// predictBlock fills dst, a size x size block, with the prediction of mode.
+func predictBlock(dst []uint8, size int, mode uint8, top, left []uint8, topLeft uint8, mbx, mby int) {
+	switch mode {
+	case predDC:
+		shift := bits.TrailingZeros(uint(size))
+		sum, dc := 0, 128
+		switch {
+		case mbx > 0 && mby > 0:
+			for i := 0; i < size; i++ {
+				sum += int(top[i]) + int(left[i])
+			}
+			dc = (sum + size) >> (shift + 1)
+		case mby > 0:
+			for i := 0; i < size; i++ {
+				sum += int(top[i])
+			}
+			dc = (sum + size/2) >> shift
+		case mbx > 0:
+			for i := 0; i < size; i++ {
+				sum += int(left[i])
+			}
+			dc = (sum + size/2) >> shift
+		}
+		for i := range dst[:size*size] {
+			dst[i] = uint8(dc)
+		}
+	case predTM:
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				dst[y*size+x] = uint8(clamp255(int(left[y]) + int(top[x]) - int(topLeft)))
+			}
+		}
+	case predVE:
+		for y := 0; y < size; y++ {
+			copy(dst[y*size:(y+1)*size], top)
+		}
+	case predHE:
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				dst[y*size+x] = left[y]
+			}
+		}
+	}
+}
+
+// sse returns the sum of squared differences between a macroblock's source
+// pixels and their prediction.
+func sse(plane []uint8, stride, size, mbx, mby int, pred []uint8) int {
+	sum := 0
+	for y := 0; y < size; y++ {
+		row := plane[(mby*size+y)*stride+mbx*size:]
+		for x := 0; x < size; x++ {
+			d := int(row[x]) - int(pred[y*size+x])
+			sum += d * d
+		}
+	}
+	return sum
+}
+
+// forwardBlock transforms the difference between the 4x4 block of plane at
+// (px, py) and its prediction at (bx, by) in pred.
+func forwardBlock(plane []uint8, stride, px, py int, pred []uint8, predStride, bx, by int, out *[16]int32) {
+	var residual [16]int32
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			residual[y*4+x] = int32(plane[(py+y)*stride+px+x]) - int32(pred[(by+y)*predStride+bx+x])
+		}
+	}
+	fdct(&residual, out)
+}
+
+// inverseBlock adds the inverse transform of coeffs to the prediction at (bx, by)
+// in pred and stores the result in the 4x4 block of plane at (px, py).
+func inverseBlock(plane []uint8, stride, px, py int, pred []uint8, predStride, bx, by int, coeffs *[16]int32) {
+	var residual [16]int32
+	if *coeffs != [16]int32{} {
+		idct(coeffs, &residual)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			v := int(pred[(by+y)*predStride+bx+x]) + int(residual[y*4+x])
+			plane[(py+y)*stride+px+x] = uint8(clamp255(v))
+		}
+	}
+}
+
+// quantize divides coeffs by the DC and AC steps in q, rounding a remainder
+// of at least bias 256ths of the step up.
+func quantize(coeffs *[16]int32, levels *[16]int16, q, bias [2]int32) {
+	for i, c := range coeffs {
+		k := min(i, 1)
+		level := (abs32(c) + q[k]*bias[k]>>8) / q[k]
+		// The largest level a token can code is 2048 plus the extra bits.
+		level = min(level, 2048)
+		if c < 0 {
+			level = -level
+		}
+		levels[i] = int16(level)
+	}
+}
+
+func dequantize(levels *[16]int16, coeffs *[16]int32, q [2]int32) {
+	for i, l := range levels {
+		coeffs[i] = int32(l) * q[min(i, 1)]
+	}
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// fdct is the forward DCT of the VP8 reference encoder.
+func fdct(in, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		a := (in[i*4+0] + in[i*4+3]) * 8
+		b := (in[i*4+1] + in[i*4+2]) * 8
+		c := (in[i*4+1] - in[i*4+2]) * 8
+		d := (in[i*4+0] - in[i*4+3]) * 8
+		tmp[i*4+0] = a + b
+		tmp[i*4+2] = a - b
+		tmp[i*4+1] = (c*2217 + d*5352 + 14500) >> 12
+		tmp[i*4+3] = (d*2217 - c*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[12+i]
+		b := tmp[4+i] + tmp[8+i]
+		c := tmp[4+i] - tmp[8+i]
+		d := tmp[i] - tmp[12+i]
+		out[i] = (a + b + 7) >> 4
+		out[8+i] = (a - b + 7) >> 4
+		out[4+i] = (c*2217 + d*5352 + 12000) >> 16
+		if d != 0 {
+			out[4+i]++
+		}
+		out[12+i] = (d*2217 - c*5352 + 51000) >> 16
+	}
+}
+
+// idct is the inverse DCT of section 14.3, which decoders have to match
+// exactly.
+func idct(in, out *[16]int32) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := in[i] + in[8+i]
+		b := in[i] - in[8+i]
+		c := (in[4+i]*c2)>>16 - (in[12+i]*c1)>>16
+		d := (in[4+i]*c1)>>16 + (in[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		out[j*4+0] = (a + d) >> 3
+		out[j*4+1] = (b + c) >> 3
+		out[j*4+2] = (b - c) >> 3
+		out[j*4+3] = (a - d) >> 3
+	}
+}
+
+// fwht is the forward Walsh-Hadamard transform of the VP8 reference
+// encoder, applied to the DCs of a macroblock's luma blocks.
+func fwht(in, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		a := (in[i*4+0] + in[i*4+2]) * 4
+		d := (in[i*4+1] + in[i*4+3]) * 4
+		c := (in[i*4+1] - in[i*4+3]) * 4
+		b := (in[i*4+0] - in[i*4+2]) * 4
+		tmp[i*4+0] = a + d
+		if a != 0 {
+			tmp[i*4+0]++
+		}
+		tmp[i*4+1] = b + c
+		tmp[i*4+2] = b - c
+		tmp[i*4+3] = a - d
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[8+i]
+		d := tmp[4+i] + tmp[12+i]
+		c := tmp[4+i] - tmp[12+i]
+		b := tmp[i] - tmp[8+i]
+		for k, v := range [4]int32{a + d, b + c, b - c, a - d} {
+			if v < 0 {
+				v++
+			}
+			out[k*4+i] = (v + 3) >> 3
+		}
+	}
+}
+
+// iwht is the inverse Walsh-Hadamard transform of section 14.3. It returns
+// the DC of each luma block in raster order.
+func iwht(in, out *[16]int32) {
+	var m [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := in[i] + in[12+i]
+		a1 := in[4+i] + in[8+i]
+		a2 := in[4+i] - in[8+i]
+		a3 := in[i] - in[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0 := dc + m[i*4+3]
+		a1 := m[i*4+1] + m[i*4+2]
+		a2 := m[i*4+1] - m[i*4+2]
+		a3 := dc - m[i*4+3]
+		out[i*4+0] = (a0 + a1) >> 3
+		out[i*4+1] = (a3 + a2) >> 3
+		out[i*4+2] = (a0 - a1) >> 3
+		out[i*4+3] = (a3 - a2) >> 3
+	}
+}
+
+// writeTokens writes the coefficients of every macroblock that isn't
+// skipped. Whether a neighbouring block had any coefficients selects the
+// probabilities of the next one, so the contexts are tracked per column
+// above and per row to the left: four luma, two U and two V blocks, then
+// the luma DCs.
+func (e *lossyEncoder) writeTokens(t *tokenWriter) {
+	top := make([][9]uint8, e.mbw)
+	for mby := 0; mby < e.mbh; mby++ {
+		var left [9]uint8
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &e.mbs[mby*e.mbw+mbx]
+			above := &top[mbx]
+			if mb.skip {
+				left, *above = [9]uint8{}, [9]uint8{}
+				continue
+			}
+
+			nz := t.writeBlock(&mb.levels[24], planeY2, left[8]+above[8], 0)
+			left[8], above[8] = nz, nz
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					nz := t.writeBlock(&mb.levels[y*4+x], planeY1WithY2, left[y]+above[x], 1)
+					left[y], above[x] = nz, nz
+				}
+			}
+			for c := 0; c < 2; c++ {
+				for y := 0; y < 2; y++ {
+					for x := 0; x < 2; x++ {
+						nz := t.writeBlock(&mb.levels[16+c*4+y*2+x], planeUV, left[4+c*2+y]+above[4+c*2+x], 0)
+						left[4+c*2+y], above[4+c*2+x] = nz, nz
+					}
+				}
+			}
+		}
+	}
+}
+
+// tokenWriter codes blocks of coefficients as tokens. Without an encoder it
+// only counts how often each probability sees a zero and a one.
+type tokenWriter struct {
+	enc   *boolEncoder
+	probs *[nPlane][nBand][nContext][nProb]uint8
+	stats *[nPlane][nBand][nContext][nProb][2]uint32
+}
+
+func (t *tokenWriter) put(bit bool, plane, band, ctx, i int) {
+	if t.stats != nil {
+		t.stats[plane][band][ctx][i][btoi(bit)]++
+	}
+	if t.enc != nil {
+		t.enc.putBit(bit, t.probs[plane][band][ctx][i])
+	}
+}
+
+func (t *tokenWriter) putFixed(bit bool, prob uint8) {
+	if t.enc != nil {
+		t.enc.putBit(bit, prob)
+	}
+}
+
+// writeBlock writes the levels of one block from position first in zigzag
+// order on, as section 13 specifies, and returns 1 if any were non-zero.
+func (t *tokenWriter) writeBlock(levels *[16]int16, plane int, ctx uint8, first int) uint8 {
+	last := -1
+	for n := 15; n >= first; n-- {
+		if levels[zigzag[n]] != 0 {
+			last = n
+			break
+		}
+	}
+
+	n, band, c := first, int(bands[first]), int(ctx)
+	if last < 0 {
+		t.put(false, plane, band, c, 0)
+		return 0
+	}
+	t.put(true, plane, band, c, 0)
+	for n < 16 {
+		v := int(levels[zigzag[n]])
+		n++
+		if v == 0 {
+			t.put(false, plane, band, c, 1)
+			band, c = int(bands[n]), 0
+			continue
+		}
+		t.put(true, plane, band, c, 1)
+
+		a, next := abs(v), 2
+		switch {
+		case a == 1:
+			t.put(false, plane, band, c, 2)
+			next = 1
+		case a <= 4:
+			t.put(true, plane, band, c, 2)
+			t.put(false, plane, band, c, 3)
+			t.put(a != 2, plane, band, c, 4)
+			if a != 2 {
+				t.put(a == 4, plane, band, c, 5)
+			}
+		case a <= 10:
+			t.put(true, plane, band, c, 2)
+			t.put(true, plane, band, c, 3)
+			t.put(false, plane, band, c, 6)
+			t.put(a > 6, plane, band, c, 7)
+			if a <= 6 {
+				t.putFixed(a == 6, 159)
+			} else {
+				t.putFixed((a-7)&2 != 0, 165)
+				t.putFixed((a-7)&1 != 0, 145)
+			}
+		default:
+			t.put(true, plane, band, c, 2)
+			t.put(true, plane, band, c, 3)
+			t.put(true, plane, band, c, 6)
+			cat := 0
+			for cat < 3 && a >= 3+(8<<(cat+1)) {
+				cat++
+			}
+			t.put(cat >= 2, plane, band, c, 8)
+			t.put(cat&1 == 1, plane, band, c, 9+cat/2)
+			extra, tab := a-(3+8<<cat), &cat3456[cat]
+			nBits := 0
+			for tab[nBits] != 0 {
+				nBits++
+			}
+			for i := 0; i < nBits; i++ {
+				t.putFixed(extra>>(nBits-1-i)&1 == 1, tab[i])
+			}
+		}
+		t.putFixed(v < 0, 128)
+
+		band, c = int(bands[n]), next
+		if n == 16 {
+			break
+		}
+		t.put(n <= last, plane, band, c, 0)
+		if n > last {
+			break
+		}
+	}
+	return 1
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// fitTokenProbs returns the token probabilities that code the counted
+// tokens in the fewest bits, and which of them differ from the defaults by
+// enough to be worth the cost of sending.
+func fitTokenProbs(stats *[nPlane][nBand][nContext][nProb][2]uint32) (probs [nPlane][nBand][nContext][nProb]uint8, updated [nPlane][nBand][nContext][nProb]bool) {
+	probs = defaultTokenProb
+	for i := range probs {
+		for j := range probs[i] {
+			for k := range probs[i][j] {
+				for l := range probs[i][j][k] {
+					zeros, ones := stats[i][j][k][l][0], stats[i][j][k][l][1]
+					if zeros+ones == 0 {
+						continue
+					}
+					old, fitted := probs[i][j][k][l], fitProb(zeros, ones)
+					u := tokenProbUpdateProb[i][j][k][l]
+					keep := bitCost(zeros, ones, old) + bitCost(1, 0, u)
+					send := bitCost(zeros, ones, fitted) + bitCost(0, 1, u) + 8
+					if send < keep {
+						probs[i][j][k][l] = fitted
+						updated[i][j][k][l] = true
+					}
+				}
+			}
+		}
+	}
+	return probs, updated
+}
+
+// fitProb returns the probability, in 256ths, of a bit being zero when it
+// was zero zeros times and one ones times.
+func fitProb(zeros, ones uint32) uint8 {
+	p := (uint64(zeros)*256 + uint64(zeros+ones)/2) / uint64(zeros+ones)
+	return uint8(min(max(p, 1), 255))
+}
+
+// bitCost estimates how many bits coding zeros zeros and ones ones takes
+// with prob.
+func bitCost(zeros, ones uint32, prob uint8) float64 {
+	p := float64(prob) / 256
+	return -float64(zeros)*math.Log2(p) - float64(ones)*math.Log2(1-p)
+}
+
+// boolEncoder is the boolean entropy encoder of section 7, the counterpart
+// of the decoder every partition is read with.
+type boolEncoder struct {
+	buf   []byte
+	rng   uint32
+	low   uint32
+	count int
+}
+
+func (e *boolEncoder) putBit(bit bool, prob uint8) {
+	if e.rng == 0 {
+		e.rng, e.count = 255, -24
+	}
+	split := 1 + (e.rng-1)*uint32(prob)>>8
+	if bit {
+		e.low += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	shift := bits.LeadingZeros8(uint8(e.rng))
+	e.rng <<= shift
+	e.count += shift
+	if e.count >= 0 {
+		offset := shift - e.count
+		if (e.low<<(offset-1))&0x80000000 != 0 {
+			// Carry into the bytes already written.
+			i := len(e.buf) - 1
+			for i >= 0 && e.buf[i] == 0xff {
+				e.buf[i] = 0
+				i--
+			}
+			e.buf[i]++
+		}
+		e.buf = append(e.buf, byte(e.low>>(24-offset)))
+		e.low <<= offset
+		shift = e.count
+		e.low &= 0xffffff
+		e.count -= 8
+	}
+	e.low <<= shift
+}
+
+// putLiteral writes the low n bits of v, most significant first, each with
+// even odds.
+func (e *boolEncoder) putLiteral(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.putBit(v>>i&1 == 1, 128)
+	}
+}
+
+// finish flushes the bits still held and returns the coded bytes.
+func (e *boolEncoder) finish() []byte {
+	for i := 0; i < 32; i++ {
+		e.putBit(false, 128)
+	}
+	return e.buf
+}
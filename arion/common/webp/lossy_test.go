@@ -0,0 +1,201 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+func TestEncodeLossy_roundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name    string
+		width   int
+		height  int
+		quality int
+		pixel   func(x, y int) color.NRGBA
+		minPSNR float64
+	}{
+		{
+			name:    "single pixel",
+			width:   1,
+			height:  1,
+			quality: 80,
+			pixel:   func(x, y int) color.NRGBA { return color.NRGBA{R: 200, G: 10, B: 30, A: 255} },
+			minPSNR: 40,
+		},
+		{
+			name:    "flat white",
+			width:   300,
+			height:  200,
+			quality: 80,
+			pixel:   func(x, y int) color.NRGBA { return color.NRGBA{R: 255, G: 255, B: 255, A: 255} },
+			minPSNR: 40,
+		},
+		{
+			name:    "gradient",
+			width:   97,
+			height:  61,
+			quality: 80,
+			pixel: func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(x * 2), G: uint8(y * 4), B: uint8(x + y), A: 255}
+			},
+			minPSNR: 40,
+		},
+		{
+			name:    "noise",
+			width:   45,
+			height:  33,
+			quality: 100,
+			pixel: func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255}
+			},
+			minPSNR: 45,
+		},
+		{
+			name:    "noise at lowest quality",
+			width:   45,
+			height:  33,
+			quality: 0,
+			pixel: func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255}
+			},
+			minPSNR: 10,
+		},
+		{
+			name:    "transparency",
+			width:   64,
+			height:  40,
+			quality: 80,
+			pixel: func(x, y int) color.NRGBA {
+				if (x/8+y/8)%2 == 0 {
+					return color.NRGBA{}
+				}
+				return color.NRGBA{R: uint8(x * 4), G: 128, B: uint8(y * 6), A: uint8(100 + x)}
+			},
+			minPSNR: 40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := EncodeLossy(&buf, src, tt.quality); err != nil {
+				t.Fatalf("EncodeLossy() error = %v", err)
+			}
+			got, err := xwebp.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got.Bounds() != src.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), src.Bounds())
+			}
+
+			var ycc *image.YCbCr
+			switch m := got.(type) {
+			case *image.YCbCr:
+				ycc = m
+			case *image.NYCbCrA:
+				ycc = &m.YCbCr
+				for y := 0; y < tt.height; y++ {
+					for x := 0; x < tt.width; x++ {
+						if a, want := m.AOffset(x, y), src.NRGBAAt(x, y).A; m.A[a] != want {
+							t.Fatalf("alpha (%d, %d) = %d, want %d", x, y, m.A[a], want)
+						}
+					}
+				}
+			default:
+				t.Fatalf("Decode() = %T, want a YCbCr image", got)
+			}
+
+			// The decoder has to end up with exactly the planes the encoder
+			// predicted from, or errors would pile up across the image.
+			argb, _ := toARGB(src)
+			e := newLossyEncoder(argb, tt.width, tt.height, (100-tt.quality)*127/100)
+			e.encode()
+			var sum float64
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					i := y*e.yStride + x
+					if v := ycc.Y[ycc.YOffset(x, y)]; v != e.ry[i] {
+						t.Fatalf("Y (%d, %d) = %d, want %d", x, y, v, e.ry[i])
+					}
+					c, j := ycc.COffset(x, y), y/2*e.uvStride+x/2
+					if ycc.Cb[c] != e.ru[j] || ycc.Cr[c] != e.rv[j] {
+						t.Fatalf("CbCr (%d, %d) = (%d, %d), want (%d, %d)", x, y, ycc.Cb[c], ycc.Cr[c], e.ru[j], e.rv[j])
+					}
+					d := float64(e.ry[i]) - float64(e.y[i])
+					sum += d * d
+				}
+			}
+			psnr := math.Inf(1)
+			if sum > 0 {
+				psnr = 10 * math.Log10(255*255*float64(tt.width*tt.height)/sum)
+			}
+			if psnr < tt.minPSNR {
+				t.Errorf("luma PSNR = %.1f dB, want at least %.1f", psnr, tt.minPSNR)
+			}
+		})
+	}
+}
+
+func TestEncodeLossy_smallerAtLowerQuality(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 160, 120))
+	rng := rand.New(rand.NewSource(2))
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 160; x++ {
+			n := uint8(rng.Intn(24))
+			src.Set(x, y, color.RGBA{R: uint8(x) + n, G: uint8(y*2) + n, B: 90 + n, A: 255})
+		}
+	}
+
+	var lossless, high, low bytes.Buffer
+	if err := Encode(&lossless, src); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if err := EncodeLossy(&high, src, 90); err != nil {
+		t.Fatalf("EncodeLossy() error = %v", err)
+	}
+	if err := EncodeLossy(&low, src, 50); err != nil {
+		t.Fatalf("EncodeLossy() error = %v", err)
+	}
+	if high.Len() >= lossless.Len() {
+		t.Errorf("quality 90 is %d bytes, want less than the %d lossless", high.Len(), lossless.Len())
+	}
+	if low.Len() >= high.Len() {
+		t.Errorf("quality 50 is %d bytes, want less than the %d at quality 90", low.Len(), high.Len())
+	}
+}
+
+func TestEncodeLossy_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		img     image.Image
+		quality int
+		wantErr error
+	}{
+		{name: "empty", img: image.NewNRGBA(image.Rect(0, 0, 0, 10)), quality: 80, wantErr: ErrInvalidSize},
+		{name: "too wide", img: image.NewNRGBA(image.Rect(0, 0, 1<<14, 1)), quality: 80, wantErr: ErrInvalidSize},
+		{name: "quality below 0", img: image.NewNRGBA(image.Rect(0, 0, 1, 1)), quality: -1, wantErr: ErrInvalidQuality},
+		{name: "quality above 100", img: image.NewNRGBA(image.Rect(0, 0, 1, 1)), quality: 101, wantErr: ErrInvalidQuality},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := EncodeLossy(&bytes.Buffer{}, tt.img, tt.quality); err != tt.wantErr {
+				t.Errorf("EncodeLossy() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
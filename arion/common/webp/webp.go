@@ -0,0 +1,666 @@
+// Package webp encodes images as WebP. Encode writes lossless files (VP8L):
+// the encoder applies the subtract-green and spatial predictor transforms,
+// replaces runs of repeated pixels with backward references to the left or
+// upper neighbour and writes a single set of canonical Huffman codes for the
+// whole image. That is a small subset of what the format allows, but it keeps
+// the encoder short and still compresses logos and drawings well below the
+// size of the PNG they are decoded from.
+//
+// Photos hold sensor noise that lossless coding has to keep bit for bit, so
+// EncodeLossy writes them as a VP8 key frame instead, with any alpha kept
+// losslessly beside it.
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// maxDimension is the largest width or height a VP8L header can describe.
+const maxDimension = 1 << 14
+
+// ErrInvalidSize is returned for an empty image or one too large to encode.
+var ErrInvalidSize = errors.New("webp: image must be 1 to 16384 pixels on a side")
+
+const (
+	predictorTransform     = 0
+	subtractGreenTransform = 2
+
+	// predictorBits sets the predictor tile size to 16x16 pixels.
+	predictorBits = 4
+
+	numLiteralCodes  = 256
+	numLengthCodes   = 24
+	numDistanceCodes = 40
+	numCodeLengths   = 19
+
+	maxCodeLength     = 15
+	maxCodeLengthCode = 7
+
+	// minMatch is the shortest run worth a backward reference; maxMatch is
+	// the longest the length prefix codes can describe.
+	minMatch = 3
+	maxMatch = 4096
+)
+
+// codeLengthOrder is the order code length code lengths are written in.
+var codeLengthOrder = [numCodeLengths]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// Encode writes m to w as a lossless WebP file.
+func Encode(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > maxDimension || height > maxDimension {
+		return ErrInvalidSize
+	}
+
+	argb, hasAlpha := toARGB(m)
+
+	bw := &bitWriter{}
+	bw.writeBits(0x2f, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3)
+	writeLossless(bw, argb, width, height)
+
+	data := bw.bytes()
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// writeLossless writes the transforms and the coded pixels of a VP8L image,
+// everything that follows its header. Lossy files reuse it for their alpha
+// plane, which is stored the same way without the header.
+func writeLossless(bw *bitWriter, argb []uint32, width, height int) {
+	subtractGreen(argb)
+	bw.writeBits(1, 1)
+	bw.writeBits(subtractGreenTransform, 2)
+
+	residuals, modes, tilesWide := predict(argb, width, height)
+	bw.writeBits(1, 1)
+	bw.writeBits(predictorTransform, 2)
+	bw.writeBits(predictorBits-2, 3)
+	writeImage(bw, modes, tilesWide, false)
+
+	bw.writeBits(0, 1)
+	writeImage(bw, residuals, width, true)
+}
+
+// toARGB returns the pixels of m as non-premultiplied ARGB words in row
+// order, and whether any of them is not fully opaque.
+func toARGB(m image.Image) ([]uint32, bool) {
+	b := m.Bounds()
+	nrgba, ok := m.(*image.NRGBA)
+	if !ok {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(nrgba, nrgba.Bounds(), m, b.Min, draw.Src)
+		b = nrgba.Bounds()
+	}
+
+	argb := make([]uint32, 0, b.Dx()*b.Dy())
+	hasAlpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := nrgba.Pix[nrgba.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := row[x*4 : x*4+4]
+			if p[3] != 0xff {
+				hasAlpha = true
+			}
+			argb = append(argb, uint32(p[3])<<24|uint32(p[0])<<16|uint32(p[1])<<8|uint32(p[2]))
+		}
+	}
+	return argb, hasAlpha
+}
+
+// subtractGreen subtracts the green channel from red and blue in place.
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		g := (p >> 8) & 0xff
+		r := ((p >> 16) - g) & 0xff
+		bl := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | bl
+	}
+}
+
+// predict picks a predictor mode for each tile and returns the residuals of
+// every pixel against its prediction, along with the tile modes packed into
+// the green channel of a sub-image tilesWide pixels wide.
+func predict(argb []uint32, width, height int) (residuals, modes []uint32, tilesWide int) {
+	const tile = 1 << predictorBits
+	tilesWide = (width + tile - 1) / tile
+	tilesHigh := (height + tile - 1) / tile
+
+	residuals = make([]uint32, len(argb))
+	modes = make([]uint32, tilesWide*tilesHigh)
+	for ty := 0; ty < tilesHigh; ty++ {
+		for tx := 0; tx < tilesWide; tx++ {
+			x0, y0 := tx*tile, ty*tile
+			x1, y1 := min(x0+tile, width), min(y0+tile, height)
+
+			best, bestCost := 0, -1
+			for mode := 0; mode < 14; mode++ {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						i := y*width + x
+						cost += residualCost(subPixels(argb[i], predictPixel(argb, i, x, y, width, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			modes[ty*tilesWide+tx] = 0xff000000 | uint32(best)<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					i := y*width + x
+					residuals[i] = subPixels(argb[i], predictPixel(argb, i, x, y, width, best))
+				}
+			}
+		}
+	}
+	return residuals, modes, tilesWide
+}
+
+// residualCost estimates how expensive a residual is to code: small
+// differences in either direction are cheap.
+func residualCost(p uint32) int {
+	cost := 0
+	for s := 0; s < 32; s += 8 {
+		c := int(p>>s) & 0xff
+		cost += min(c, 256-c)
+	}
+	return cost
+}
+
+// predictPixel returns the prediction for pixel i at (x, y). The first pixel,
+// the top row and the left column use fixed predictors as the format
+// requires.
+func predictPixel(argb []uint32, i, x, y, width, mode int) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[i-1]
+	case x == 0:
+		return argb[i-width]
+	}
+
+	left, top := argb[i-1], argb[i-width]
+	topLeft, topRight := argb[i-width-1], argb[i-width+1]
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return left
+	case 2:
+		return top
+	case 3:
+		return topRight
+	case 4:
+		return topLeft
+	case 5:
+		return average2(average2(left, topRight), top)
+	case 6:
+		return average2(left, topLeft)
+	case 7:
+		return average2(left, top)
+	case 8:
+		return average2(topLeft, top)
+	case 9:
+		return average2(top, topRight)
+	case 10:
+		return average2(average2(left, topLeft), average2(top, topRight))
+	case 11:
+		return selectPixel(left, top, topLeft)
+	case 12:
+		return clampAddSubtractFull(left, top, topLeft)
+	default:
+		return clampAddSubtractHalf(average2(left, top), topLeft)
+	}
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+func selectPixel(left, top, topLeft uint32) uint32 {
+	pl, pt := 0, 0
+	for s := 0; s < 32; s += 8 {
+		l, t, tl := int(left>>s)&0xff, int(top>>s)&0xff, int(topLeft>>s)&0xff
+		pl += abs(t - tl)
+		pt += abs(l - tl)
+	}
+	if pl < pt {
+		return left
+	}
+	return top
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var p uint32
+	for s := 0; s < 32; s += 8 {
+		v := int(a>>s)&0xff + int(b>>s)&0xff - int(c>>s)&0xff
+		p |= uint32(clamp255(v)) << s
+	}
+	return p
+}
+
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	var p uint32
+	for s := 0; s < 32; s += 8 {
+		ca, cb := int(a>>s)&0xff, int(b>>s)&0xff
+		p |= uint32(clamp255(ca+(ca-cb)/2)) << s
+	}
+	return p
+}
+
+// subPixels subtracts b from a channel by channel, modulo 256.
+func subPixels(a, b uint32) uint32 {
+	var p uint32
+	for s := 0; s < 32; s += 8 {
+		p |= ((a>>s - b>>s) & 0xff) << s
+	}
+	return p
+}
+
+func clamp255(v int) int {
+	return min(max(v, 0), 255)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// token is either a literal pixel or, when length is non-zero, a copy of
+// length pixels from distCode (a VP8L distance code) pixels back.
+type token struct {
+	argb     uint32
+	length   int
+	distCode int
+}
+
+// backwardRefs splits pixels into literals and copies of runs that repeat
+// the pixel to the left or the pixel above.
+func backwardRefs(pixels []uint32, width int) []token {
+	tokens := make([]token, 0, len(pixels))
+	for i := 0; i < len(pixels); {
+		length, distCode := 0, 0
+		if i >= 1 {
+			// Distance code 2 is the pixel to the left.
+			length, distCode = matchLength(pixels, i, 1), 2
+		}
+		if i >= width {
+			// Distance code 1 is the pixel above.
+			if l := matchLength(pixels, i, width); l > length {
+				length, distCode = l, 1
+			}
+		}
+		if length >= minMatch {
+			tokens = append(tokens, token{length: length, distCode: distCode})
+			i += length
+			continue
+		}
+		tokens = append(tokens, token{argb: pixels[i]})
+		i++
+	}
+	return tokens
+}
+
+func matchLength(pixels []uint32, i, dist int) int {
+	n := 0
+	for i+n < len(pixels) && n < maxMatch && pixels[i+n] == pixels[i+n-dist] {
+		n++
+	}
+	return n
+}
+
+// prefixEncode splits a backward reference length or distance into its
+// prefix code and the extra bits that follow it.
+func prefixEncode(v int) (code int, extraBits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	hb := bits.Len(uint(d)) - 1
+	extraBits = uint(hb - 1)
+	return 2*hb + (d>>extraBits)&1, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+// writeImage entropy-codes pixels, which are width pixels wide. The main
+// image carries an extra bit saying it has no meta prefix codes; the
+// predictor sub-image does not.
+func writeImage(bw *bitWriter, pixels []uint32, width int, main bool) {
+	tokens := backwardRefs(pixels, width)
+
+	green := make([]uint32, numLiteralCodes+numLengthCodes)
+	red := make([]uint32, numLiteralCodes)
+	blue := make([]uint32, numLiteralCodes)
+	alpha := make([]uint32, numLiteralCodes)
+	dist := make([]uint32, numDistanceCodes)
+	for _, t := range tokens {
+		if t.length > 0 {
+			lc, _, _ := prefixEncode(t.length)
+			dc, _, _ := prefixEncode(t.distCode)
+			green[numLiteralCodes+lc]++
+			dist[dc]++
+			continue
+		}
+		green[(t.argb>>8)&0xff]++
+		red[(t.argb>>16)&0xff]++
+		blue[t.argb&0xff]++
+		alpha[t.argb>>24]++
+	}
+
+	// No color cache.
+	bw.writeBits(0, 1)
+	if main {
+		// No meta prefix codes.
+		bw.writeBits(0, 1)
+	}
+
+	codes := make([]huffmanCode, 5)
+	for i, hist := range [][]uint32{green, red, blue, alpha, dist} {
+		codes[i] = newHuffmanCode(hist, maxCodeLength)
+		writeHuffmanCode(bw, codes[i], hist)
+	}
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			lc, lbits, lextra := prefixEncode(t.length)
+			codes[0].write(bw, numLiteralCodes+lc)
+			bw.writeBits(lextra, lbits)
+			dc, dbits, dextra := prefixEncode(t.distCode)
+			codes[4].write(bw, dc)
+			bw.writeBits(dextra, dbits)
+			continue
+		}
+		codes[0].write(bw, int((t.argb>>8)&0xff))
+		codes[1].write(bw, int((t.argb>>16)&0xff))
+		codes[2].write(bw, int(t.argb&0xff))
+		codes[3].write(bw, int(t.argb>>24))
+	}
+}
+
+// huffmanCode holds canonical Huffman codes, bit-reversed so they can be
+// written least significant bit first. A code with a single symbol is
+// written with zero bits.
+type huffmanCode struct {
+	lengths []uint8
+	codes   []uint32
+	single  bool
+}
+
+func (h huffmanCode) write(bw *bitWriter, symbol int) {
+	if h.single {
+		return
+	}
+	bw.writeBits(h.codes[symbol], uint(h.lengths[symbol]))
+}
+
+// newHuffmanCode builds a code for hist with no code longer than limit bits.
+func newHuffmanCode(hist []uint32, limit int) huffmanCode {
+	lengths := codeLengths(hist, limit)
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	return huffmanCode{lengths: lengths, codes: canonicalCodes(lengths), single: used <= 1}
+}
+
+// writeHuffmanCode writes h. Codes for at most one symbol below 256 use the
+// simple form; everything else writes its code lengths, run-length coded
+// with a code length code of its own.
+func writeHuffmanCode(bw *bitWriter, h huffmanCode, hist []uint32) {
+	used, symbol := 0, 0
+	for s, l := range h.lengths {
+		if l > 0 {
+			used++
+			symbol = s
+		}
+	}
+	if used <= 1 && symbol < numLiteralCodes {
+		bw.writeBits(1, 1)
+		bw.writeBits(0, 1)
+		if symbol < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(symbol), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(symbol), 8)
+		}
+		return
+	}
+
+	bw.writeBits(0, 1)
+	tokens := codeLengthTokens(h.lengths)
+	clHist := make([]uint32, numCodeLengths)
+	for _, t := range tokens {
+		clHist[t.symbol]++
+	}
+	clCode := newHuffmanCode(clHist, maxCodeLengthCode)
+
+	count := 4
+	for i, s := range codeLengthOrder {
+		if clCode.lengths[s] > 0 && i+1 > count {
+			count = i + 1
+		}
+	}
+	bw.writeBits(uint32(count-4), 4)
+	for _, s := range codeLengthOrder[:count] {
+		bw.writeBits(uint32(clCode.lengths[s]), 3)
+	}
+
+	// Code lengths run to the end of the alphabet.
+	bw.writeBits(0, 1)
+	for _, t := range tokens {
+		clCode.write(bw, t.symbol)
+		switch t.symbol {
+		case 16:
+			bw.writeBits(uint32(t.extra), 2)
+		case 17:
+			bw.writeBits(uint32(t.extra), 3)
+		case 18:
+			bw.writeBits(uint32(t.extra), 7)
+		}
+	}
+}
+
+type codeLengthToken struct {
+	symbol int
+	extra  uint8
+}
+
+// codeLengthTokens run-length codes lengths: 16 repeats the previous
+// non-zero length 3-6 times, 17 and 18 write 3-10 and 11-138 zeros.
+func codeLengthTokens(lengths []uint8) []codeLengthToken {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		v, run := lengths[i], 1
+		for i+run < len(lengths) && lengths[i+run] == v {
+			run++
+		}
+		i += run
+
+		if v == 0 {
+			for run >= 11 {
+				r := min(run, 138)
+				tokens = append(tokens, codeLengthToken{18, uint8(r - 11)})
+				run -= r
+			}
+			if run >= 3 {
+				tokens = append(tokens, codeLengthToken{17, uint8(run - 3)})
+				run = 0
+			}
+		} else {
+			tokens = append(tokens, codeLengthToken{int(v), 0})
+			run--
+			for run >= 3 {
+				r := min(run, 6)
+				tokens = append(tokens, codeLengthToken{16, uint8(r - 3)})
+				run -= r
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, codeLengthToken{int(v), 0})
+		}
+	}
+	return tokens
+}
+
+// codeLengths returns Huffman code lengths for hist. When the tree is deeper
+// than limit the counts are halved, which flattens it, until it fits.
+func codeLengths(hist []uint32, limit int) []uint8 {
+	counts := make([]uint32, len(hist))
+	copy(counts, hist)
+	for {
+		lengths, depth := buildLengths(counts)
+		if depth <= limit {
+			return lengths
+		}
+		for i, c := range counts {
+			if c > 0 {
+				counts[i] = (c + 1) / 2
+			}
+		}
+	}
+}
+
+// buildLengths builds a Huffman tree over the non-zero counts and returns
+// each symbol's depth in it, along with the deepest.
+func buildLengths(counts []uint32) ([]uint8, int) {
+	type node struct {
+		weight      uint64
+		left, right int
+	}
+
+	lengths := make([]uint8, len(counts))
+	var nodes []node
+	var leaves []int
+	for s, c := range counts {
+		if c > 0 {
+			nodes = append(nodes, node{weight: uint64(c), left: -1, right: s})
+			leaves = append(leaves, len(nodes)-1)
+		}
+	}
+	switch len(leaves) {
+	case 0:
+		return lengths, 0
+	case 1:
+		lengths[nodes[0].right] = 1
+		return lengths, 1
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return nodes[leaves[i]].weight < nodes[leaves[j]].weight })
+
+	// Two-queue construction: merged nodes come out in non-decreasing
+	// weight, so the smallest is always at the front of one queue.
+	var merged []int
+	pop := func() int {
+		if len(merged) == 0 || (len(leaves) > 0 && nodes[leaves[0]].weight <= nodes[merged[0]].weight) {
+			n := leaves[0]
+			leaves = leaves[1:]
+			return n
+		}
+		n := merged[0]
+		merged = merged[1:]
+		return n
+	}
+	for len(leaves)+len(merged) > 1 {
+		a, b := pop(), pop()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, left: a, right: b})
+		merged = append(merged, len(nodes)-1)
+	}
+
+	maxDepth := 0
+	var walk func(n, depth int)
+	walk = func(n, depth int) {
+		if nodes[n].left < 0 {
+			lengths[nodes[n].right] = uint8(depth)
+			maxDepth = max(maxDepth, depth)
+			return
+		}
+		walk(nodes[n].left, depth+1)
+		walk(nodes[n].right, depth+1)
+	}
+	walk(merged[0], 0)
+	return lengths, maxDepth
+}
+
+// canonicalCodes assigns canonical codes to lengths and reverses their bits.
+func canonicalCodes(lengths []uint8) []uint32 {
+	var count [maxCodeLength + 1]uint32
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+
+	codes := make([]uint32, len(lengths))
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		codes[s] = bits.Reverse32(next[l]) >> (32 - l)
+		next[l]++
+	}
+	return codes
+}
+
+// bitWriter packs values least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v) << w.n
+	w.n += n
+	for w.n >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.n -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.n > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.n = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,130 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+func TestEncode_roundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		pixel  func(x, y int) color.NRGBA
+	}{
+		{
+			name:   "single pixel",
+			width:  1,
+			height: 1,
+			pixel:  func(x, y int) color.NRGBA { return color.NRGBA{R: 200, G: 10, B: 30, A: 255} },
+		},
+		{
+			name:   "flat white",
+			width:  300,
+			height: 200,
+			pixel:  func(x, y int) color.NRGBA { return color.NRGBA{R: 255, G: 255, B: 255, A: 255} },
+		},
+		{
+			name:   "gradient",
+			width:  97,
+			height: 61,
+			pixel: func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(x * 2), G: uint8(y * 4), B: uint8(x + y), A: 255}
+			},
+		},
+		{
+			name:   "noise",
+			width:  45,
+			height: 33,
+			pixel: func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255}
+			},
+		},
+		{
+			name:   "transparency",
+			width:  64,
+			height: 40,
+			pixel: func(x, y int) color.NRGBA {
+				if (x/8+y/8)%2 == 0 {
+					return color.NRGBA{}
+				}
+				return color.NRGBA{R: uint8(x * 4), G: 128, B: uint8(y * 6), A: uint8(100 + x)}
+			},
+		},
+		{
+			name:   "single column",
+			width:  1,
+			height: 50,
+			pixel:  func(x, y int) color.NRGBA { return color.NRGBA{R: uint8(y), G: uint8(y * 3), B: 7, A: 255} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := Encode(&buf, src); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := xwebp.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got.Bounds() != src.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), src.Bounds())
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					want := src.NRGBAAt(x, y)
+					if c := color.NRGBAModel.Convert(got.At(x, y)).(color.NRGBA); c != want && want.A != 0 {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, c, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncode_subImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x * 10), G: uint8(y * 10), B: 50, A: 255})
+		}
+	}
+	sub := src.SubImage(image.Rect(5, 5, 15, 12))
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, sub); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := xwebp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Bounds().Dx() != 10 || got.Bounds().Dy() != 7 {
+		t.Fatalf("size = %v, want 10x7", got.Bounds())
+	}
+	r, g, _, _ := got.At(0, 0).RGBA()
+	if r>>8 != 50 || g>>8 != 50 {
+		t.Errorf("first pixel = (%d, %d), want (50, 50)", r>>8, g>>8)
+	}
+}
+
+func TestEncode_invalidSize(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, 0, 10))); err != ErrInvalidSize {
+		t.Errorf("Encode() error = %v, want %v", err, ErrInvalidSize)
+	}
+}
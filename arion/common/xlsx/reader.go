@@ -0,0 +1,268 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrNoSheet is returned when a workbook has no worksheet to read.
+var ErrNoSheet = errors.New("xlsx: workbook has no sheet")
+
+// ReadAll returns the rows of the first sheet of the workbook in r as text.
+// Shared and inline strings are returned as written and numbers as stored,
+// with exponent notation expanded so long phone numbers keep their digits.
+// Rows and cells skipped in the file come back as empty rows and cells, so
+// rows[i] is spreadsheet row i+1.
+func ReadAll(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	sheet, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := files[sheet]
+	if !ok {
+		return nil, ErrNoSheet
+	}
+
+	var shared []string
+	if sf, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(sf); err != nil {
+			return nil, err
+		}
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return readSheet(rc, shared)
+}
+
+// firstSheetPath resolves the part name of the first sheet listed in the
+// workbook, falling back to the conventional sheet1.xml.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	fallback := "xl/worksheets/sheet1.xml"
+
+	var wb struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(files["xl/workbook.xml"], &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		if _, ok := files[fallback]; ok {
+			return fallback, nil
+		}
+		return "", ErrNoSheet
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// decodePart unmarshals an XML part; a missing part leaves v untouched.
+func decodePart(f *zip.File, v interface{}) error {
+	if f == nil {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// readSharedStrings returns the shared string table. Rich text runs are
+// joined; phonetic hints (rPh) are left out.
+func readSharedStrings(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		shared  []string
+		current strings.Builder
+		inText  bool
+		skip    int
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "rPh":
+				skip++
+			case "t":
+				inText = skip == 0
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				shared = append(shared, current.String())
+			case "rPh":
+				skip--
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+}
+
+func readSheet(r io.Reader, shared []string) ([][]string, error) {
+	var (
+		rows     [][]string
+		row      []string
+		ref      string
+		typ      string
+		value    strings.Builder
+		inValue  bool
+		inCell   bool
+		nextCell int
+	)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				if n, err := strconv.Atoi(attr(t, "r")); err == nil {
+					for len(rows) < n-1 {
+						rows = append(rows, []string{})
+					}
+				}
+				row = []string{}
+				nextCell = 0
+			case "c":
+				inCell = true
+				ref, typ = attr(t, "r"), attr(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = inCell
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "row":
+				rows = append(rows, row)
+			case "c":
+				col := nextCell
+				if i, ok := columnIndex(ref); ok {
+					col = i
+				}
+				for len(row) <= col {
+					row = append(row, "")
+				}
+				row[col] = cellValue(value.String(), typ, shared)
+				nextCell = col + 1
+				inCell = false
+			case "v", "t":
+				inValue = false
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+func cellValue(raw, typ string, shared []string) string {
+	switch typ {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "", "n":
+		if strings.ContainsAny(raw, "eE") {
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				return strconv.FormatFloat(f, 'f', -1, 64)
+			}
+		}
+	}
+	return raw
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// columnIndex returns the zero-based column of a cell reference such as
+// "AB12"; it is the inverse of columnName.
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A') + 1
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
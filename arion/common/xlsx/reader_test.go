@@ -0,0 +1,106 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReadAll_roundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Customers")
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, row := range [][]interface{}{
+		{"Name", "Phone", "Address"},
+		{"Budi & Co", "08123456789", nil},
+		{"Siti", 81234567890, "Jl. Merdeka 1"},
+	} {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := ReadAll(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	want := [][]string{
+		{"Name", "Phone", "Address"},
+		{"Budi & Co", "08123456789"},
+		{"Siti", "81234567890", "Jl. Merdeka 1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAll() = %q, want %q", got, want)
+	}
+}
+
+func TestReadAll_sharedStrings(t *testing.T) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Produk" sheetId="1" r:id="rId3"/><sheet name="Lain" sheetId="2" r:id="rId4"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId4" Target="worksheets/sheet1.xml"/><Relationship Id="rId3" Target="/xl/worksheets/produk.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Nama</t></si><si><t>Harga</t></si>` +
+			`<si><r><t>Kaos </t></r><r><rPr><b/></rPr><t>Polos</t></r><rPh><t>kaosu</t></rPh></si></sst>`,
+		"xl/worksheets/produk.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+			`<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3"><v>8.5E+4</v></c></row>` +
+			`<row r="4"><c r="A4" t="str"><v>Topi</v></c><c r="B4" t="b"><v>1</v></c></row>` +
+			`</sheetData></worksheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="inlineStr"><is><t>wrong sheet</t></is></c></row></sheetData></worksheet>`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadAll(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	want := [][]string{
+		{"Nama", "Harga"},
+		{},
+		{"Kaos Polos", "", "85000"},
+		{"Topi", "1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAll() = %q, want %q", got, want)
+	}
+}
+
+func TestReadAll_notAWorkbook(t *testing.T) {
+	data := []byte("Name,Phone\nBudi,0812\n")
+	if _, err := ReadAll(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("ReadAll() succeeded on a CSV file")
+	}
+}
+
+func Test_columnIndex(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB2": 27, "ZZ1": 701, "AAA1": 702}
+	for ref, want := range tests {
+		if got, ok := columnIndex(ref); !ok || got != want {
+			t.Errorf("columnIndex(%q) = %d, %v, want %d", ref, got, ok, want)
+		}
+	}
+	if _, ok := columnIndex("12"); ok {
+		t.Error(`columnIndex("12") ok = true, want false`)
+	}
+}
@@ -0,0 +1,81 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Sales <by> product & more than thirty-one chars")
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	rows := [][]interface{}{
+		{"Product", "Qty", "Sales"},
+		{"Kaos <Polos> & Co", 3, int64(45000)},
+		{"Nón lá", nil, 1.5},
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := w.Write([]interface{}{"late"}); err != ErrClosed {
+		t.Errorf("Write() after Close error = %v, want %v", err, ErrClosed)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	if want := `name="Sales &lt;by&gt; product &amp; more than "`; !strings.Contains(files["xl/workbook.xml"], want) {
+		t.Errorf("workbook.xml = %s, want sheet %s", files["xl/workbook.xml"], want)
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<row r="1"><c r="A1" t="inlineStr" s="1"><is><t xml:space="preserve">Product</t></is></c>`,
+		`<c r="B2"><v>3</v></c>`,
+		`<c r="C2"><v>45000</v></c>`,
+		`<t xml:space="preserve">Kaos &lt;Polos&gt; &amp; Co</t>`,
+		`<t xml:space="preserve">Nón lá</t></is></c><c r="C3"><v>1.5</v></c></row>`,
+		`</sheetData></worksheet>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet1.xml missing %s\n%s", want, sheet)
+		}
+	}
+}
+
+func Test_columnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for i, want := range tests {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -10,18 +10,25 @@ import (
 
 func startCron() {
 	go runDailyCron()
+	go runHourlyCron()
 }
 
 func runDailyCron() {
 	svc := service.NewSubscriptionService()
+	shopSvc := service.NewShopService()
+	systemSvc := service.NewSystemService()
 	ticker := time.NewTicker(24 * time.Hour)
 	defer ticker.Stop()
 
 	// run once on startup
 	runExpireSubscriptions(svc)
+	runPurgeClosedShops(shopSvc)
+	runCollectUnreferencedUploads(systemSvc)
 
 	for range ticker.C {
 		runExpireSubscriptions(svc)
+		runPurgeClosedShops(shopSvc)
+		runCollectUnreferencedUploads(systemSvc)
 	}
 }
 
@@ -30,3 +37,35 @@ func runExpireSubscriptions(svc service.SubscriptionService) {
 		logger.WithError(err).Error("expire_subscriptions_cron_error")
 	}
 }
+
+func runPurgeClosedShops(svc service.ShopService) {
+	if err := svc.PurgeClosedShops(context.Background()); err != nil {
+		logger.WithError(err).Error("purge_closed_shops_cron_error")
+	}
+}
+
+func runCollectUnreferencedUploads(svc service.SystemService) {
+	if _, err := svc.CollectUnreferencedUploads(context.Background()); err != nil {
+		logger.WithError(err).Error("collect_unreferenced_uploads_cron_error")
+	}
+}
+
+// runHourlyCron handles jobs tied to deadlines that should not wait a day.
+func runHourlyCron() {
+	svc := service.NewOrderService()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	// run once on startup
+	runFlagOverdueDownPayments(svc)
+
+	for range ticker.C {
+		runFlagOverdueDownPayments(svc)
+	}
+}
+
+func runFlagOverdueDownPayments(svc service.OrderService) {
+	if err := svc.FlagOverdueDownPayments(context.Background()); err != nil {
+		logger.WithError(err).Error("flag_overdue_down_payments_cron_error")
+	}
+}
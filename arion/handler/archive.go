@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+// archiveMemorySize is how much of an uploaded archive is kept in memory;
+// the rest is spooled to a temporary file.
+const archiveMemorySize = 32 << 20
+
+// ExportShopHandler godoc
+//
+//	@Summary		Export shop data
+//	@Description	Download all data of the shop as a zip archive: manifest.json (format, version, row counts, images), one JSON Lines file per table
+//	@Description	(settings, document_sequences, dp_rules, customers, customer_addresses, product_categories, products, product_images, orders, order_items, order_payments, order_refunds, customer_credits, temp_orders, temp_order_items)
+//	@Description	and the uploaded product images, logo and refund proofs under images/. The archive can be restored into an empty shop with the system import.
+//	@Description	Works without an active subscription, so a shop can always take its data out.
+//	@Tags			shop
+//	@Produce		application/zip
+//	@Security		BearerAuth
+//	@Success		200	{file}		binary
+//	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/shop/export [get]
+func ExportShopHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	filename := fmt.Sprintf("shop_%d_%s.zip", shopID, time.Now().Format("2006-01-02"))
+	dw := &downloadWriter{w: w, contentType: "application/zip", filename: filename}
+	buf := bufio.NewWriterSize(dw, reportBufferSize)
+	err := archiveService.ExportShop(ctx, shopID, buf)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		if dw.started {
+			// part of the archive is already on its way; the download is cut short
+			logger.WithError(err).WithField("shop_id", shopID).Error("stream_shop_export_error")
+			return
+		}
+		logger.WithError(err).Error("export_shop_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "export_shop")
+	}
+}
+
+// ImportShopArchiveHandler godoc
+//
+//	@Summary		Import shop archive
+//	@Description	Restore an archive made by GET /shop/export into a shop that has no customers, products, orders or temp orders yet.
+//	@Description	Settings are copied onto the shop, every other row is inserted with a new id and references between rows follow.
+//	@Description	Images are uploaded again. Share links are not part of the archive; restored temp orders lose their link.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			system
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			shop_id	path		int		true	"Shop to restore into"
+//	@Param			file	formData	file	true	"Shop archive (zip, max 512MB)"
+//	@Success		200		{object}	response.ShopImportData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (missing, oversized or invalid archive, unsupported version)"
+//	@Failure		404		{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		409		{object}	ErrorApiResponse	"Shop already has data"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/system/shops/{shop_id}/import [post]
+func ImportShopArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	shopID, err := strconv.Atoi(mux.Vars(r)["shop_id"])
+	if err != nil || shopID <= 0 {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrArchiveShopIDInvalid), "validation")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, constant.ArchiveMaxFileSize+archiveMemorySize)
+	if err := r.ParseMultipartForm(archiveMemorySize); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrArchiveFileTooLarge), "validation")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrArchiveFileRequired), "validation")
+		return
+	}
+	defer file.Close()
+	if header.Size > constant.ArchiveMaxFileSize {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrArchiveFileTooLarge), "validation")
+		return
+	}
+
+	res, err := archiveService.ImportShop(ctx, service.ImportShopInput{
+		ShopID: shopID,
+		File:   file,
+		Size:   header.Size,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrArchiveInvalid, apierr.ErrArchiveVersionUnsupported,
+			apierr.ErrUnsupportedImageType, apierr.ErrImageTooLarge:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		case apierr.ErrShopNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		case apierr.ErrArchiveShopNotEmpty:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+		default:
+			logger.WithError(err).Error("import_shop_archive_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "import_shop_archive")
+		}
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
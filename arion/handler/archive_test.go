@@ -0,0 +1,197 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+func TestExportShopHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetArchiveService()
+	defer handler.SetArchiveService(oldService)
+
+	mockService := mock_service.NewMockArchiveService(ctrl)
+	handler.SetArchiveService(mockService)
+
+	t.Run("downloads the archive", func(t *testing.T) {
+		mockService.EXPECT().ExportShop(gomock.Any(), 1, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ int, w io.Writer) error {
+				io.WriteString(w, "PK")
+				return nil
+			})
+
+		rec := httptest.NewRecorder()
+		handler.ExportShopHandler(rec, newRequestWithShopID("GET", "/shop/export", nil, 1))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("ExportShopHandler() status = %v, want %v", rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/zip" {
+			t.Errorf("ExportShopHandler() content type = %q, want application/zip", got)
+		}
+		if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment; filename=shop_1_") || !strings.HasSuffix(got, ".zip") {
+			t.Errorf("ExportShopHandler() disposition = %q", got)
+		}
+		if rec.Body.String() != "PK" {
+			t.Errorf("ExportShopHandler() body = %q, want PK", rec.Body.String())
+		}
+	})
+
+	t.Run("returns 500 when nothing was written", func(t *testing.T) {
+		mockService.EXPECT().ExportShop(gomock.Any(), 1, gomock.Any()).Return(errors.New("db error"))
+
+		rec := httptest.NewRecorder()
+		handler.ExportShopHandler(rec, newRequestWithShopID("GET", "/shop/export", nil, 1))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("ExportShopHandler() status = %v, want %v", rec.Code, http.StatusInternalServerError)
+		}
+	})
+}
+
+func TestImportShopArchiveHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetArchiveService()
+	defer handler.SetArchiveService(oldService)
+
+	mockService := mock_service.NewMockArchiveService(ctrl)
+	handler.SetArchiveService(mockService)
+
+	archive := []byte("PK archive")
+
+	tests := []struct {
+		name        string
+		shopID      string
+		withFile    bool
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+		wantData    response.ShopImportData
+	}{
+		{
+			name:     "restores the archive into the shop",
+			shopID:   "8",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().ImportShop(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, input service.ImportShopInput) (response.ShopImportData, error) {
+						if input.ShopID != 8 || input.Size != int64(len(archive)) {
+							t.Errorf("ImportShop() input = %+v", input)
+						}
+						return response.ShopImportData{ShopID: 8, Tables: map[string]int{"customers": 2}, Images: 1}, nil
+					})
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+			wantData:    response.ShopImportData{ShopID: 8, Tables: map[string]int{"customers": 2}, Images: 1},
+		},
+		{
+			name:       "returns 400 for a shop id that is not a number",
+			shopID:     "abc",
+			withFile:   true,
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "returns 400 when file is missing",
+			shopID:     "8",
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "returns 400 for an invalid archive",
+			shopID:   "8",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().ImportShop(gomock.Any(), gomock.Any()).
+					Return(response.ShopImportData{}, errors.New(apierr.ErrArchiveInvalid))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "returns 404 when the shop does not exist",
+			shopID:   "8",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().ImportShop(gomock.Any(), gomock.Any()).
+					Return(response.ShopImportData{}, errors.New(apierr.ErrShopNotFound))
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:     "returns 409 when the shop already has data",
+			shopID:   "8",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().ImportShop(gomock.Any(), gomock.Any()).
+					Return(response.ShopImportData{}, errors.New(apierr.ErrArchiveShopNotEmpty))
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:     "returns 500 on service error",
+			shopID:   "8",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().ImportShop(gomock.Any(), gomock.Any()).
+					Return(response.ShopImportData{}, errors.New("db error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			if tt.withFile {
+				part, _ := writer.CreateFormFile("file", "shop.zip")
+				part.Write(archive)
+			}
+			writer.Close()
+
+			req := newRequestWithPathVars(newRequestWithShopID("POST", "/system/shops/"+tt.shopID+"/import", body.Bytes(), 1), map[string]string{"shop_id": tt.shopID})
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rec := httptest.NewRecorder()
+
+			handler.ImportShopArchiveHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ImportShopArchiveHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp struct {
+				Success bool                    `json:"success"`
+				Data    response.ShopImportData `json:"data"`
+			}
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ImportShopArchiveHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+			if tt.wantSuccess && (resp.Data.ShopID != tt.wantData.ShopID || resp.Data.Images != tt.wantData.Images || resp.Data.Tables["customers"] != tt.wantData.Tables["customers"]) {
+				t.Errorf("ImportShopArchiveHandler() data = %+v, want %+v", resp.Data, tt.wantData)
+			}
+		})
+	}
+}
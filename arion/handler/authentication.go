@@ -56,6 +56,7 @@ type (
 //	@Success		200		{object}	response.TokenResponse
 //	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or validation)"
 //	@Failure		401		{object}	ErrorApiResponse	"Invalid credentials"
+//	@Failure		403		{object}	ErrorApiResponse	"Shop closed"
 //	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/login [post]
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -75,8 +76,11 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	res, err := userService.UserLogin(ctx, inp.Email, inp.Password)
 	if err != nil {
 		status := http.StatusInternalServerError
-		if err.Error() == apierr.ErrPasswordIncorrect {
+		switch err.Error() {
+		case apierr.ErrPasswordIncorrect:
 			status = http.StatusUnauthorized
+		case apierr.ErrShopClosed:
+			status = http.StatusForbidden
 		}
 
 		WriteErrorJson(w, r, status, err, "user_login")
@@ -68,6 +68,21 @@ func TestLoginHandler(t *testing.T) {
 			wantSuccess:    false,
 			wantErrMessage: "Password is incorrect",
 		},
+		{
+			name: "login returns 403 when the shop is closed",
+			body: map[string]interface{}{
+				"email":    "user@example.com",
+				"password": "password123",
+			},
+			mockSetup: func() {
+				mockUserService.EXPECT().
+					UserLogin(gomock.Any(), "user@example.com", "password123").
+					Return(response.TokenResponse{}, errors.New(apierr.ErrShopClosed))
+			},
+			wantStatus:     http.StatusForbidden,
+			wantSuccess:    false,
+			wantErrMessage: "This shop has been closed",
+		},
 		{
 			name: "login returns 500 on service error",
 			body: map[string]interface{}{
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/bankstatement"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+type (
+	ConfirmPaymentMatchesRequest struct {
+		Matches []ConfirmPaymentMatchRequest `json:"matches"`
+	}
+
+	ConfirmPaymentMatchRequest struct {
+		OrderID  int                    `json:"order_id"`
+		Amount   int                    `json:"amount"`
+		Mutation ConfirmMutationRequest `json:"mutation"`
+	}
+
+	// ConfirmMutationRequest is the statement credit as POST
+	// /bank_statements/match returned it.
+	ConfirmMutationRequest struct {
+		Date        time.Time `json:"date"`
+		Description string    `json:"description"`
+		Amount      int       `json:"amount"`
+	}
+)
+
+// MatchBankStatementHandler godoc
+//
+//	@Summary		Match bank statement
+//	@Description	Upload a bank mutation export (CSV from BCA, Mandiri or BRI internet banking, max 2MB) and match its credits to orders with an outstanding balance.
+//	@Description	Credits are matched by exact amount, by a unique-code suffix on top of the outstanding amount, and by customer name in the transfer description.
+//	@Description	Nothing is written; confirm the proposed matches with POST /bank_statements/confirm.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			payment
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			bank	formData	string	true	"Bank code (bca, mandiri, bri)"
+//	@Param			file	formData	file	true	"Statement CSV (max 2MB)"
+//	@Success		200		{object}	response.BankStatementMatchData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (missing file, unsupported bank or unreadable statement)"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/bank_statements/match [post]
+func MatchBankStatementHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	if err := r.ParseMultipartForm(2 << 20); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrStatementFileTooLarge), "validation")
+		return
+	}
+
+	bank := strings.TrimSpace(r.FormValue("bank"))
+	if bank == "" {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrBankRequired), "validation")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrStatementFileRequired), "validation")
+		return
+	}
+	defer file.Close()
+
+	res, err := bankStatementService.MatchBankStatement(ctx, shopID, bank, file)
+	if err != nil {
+		if err.Error() == apierr.ErrBankNotSupported || err.Error() == apierr.ErrStatementInvalid {
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("match_bank_statement_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "match_bank_statement")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// ConfirmPaymentMatchesHandler godoc
+//
+//	@Summary		Confirm payment matches
+//	@Description	Record the selected bank statement matches as order payments in a single transaction.
+//	@Description	Each match carries the statement credit it came from; a credit can only be recorded once.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			payment
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		ConfirmPaymentMatchesRequest	true	"Matches to record"
+//	@Success		200		{array}		response.OrderPaymentData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or validation)"
+//	@Failure		404		{object}	ErrorApiResponse	"Order not found"
+//	@Failure		409		{object}	ErrorApiResponse	"Statement credit already recorded, order no longer outstanding or payment above its balance"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/bank_statements/confirm [post]
+func ConfirmPaymentMatchesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := ConfirmPaymentMatchesRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateConfirmPaymentMatches(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	matches := make([]service.ConfirmPaymentMatchInput, 0, len(inp.Matches))
+	for _, m := range inp.Matches {
+		matches = append(matches, service.ConfirmPaymentMatchInput{
+			OrderID: m.OrderID,
+			Amount:  m.Amount,
+			Mutation: bankstatement.Mutation{
+				Date:        m.Mutation.Date,
+				Description: m.Mutation.Description,
+				Amount:      m.Mutation.Amount,
+				IsCredit:    true,
+			},
+		})
+	}
+
+	res, err := bankStatementService.ConfirmPaymentMatches(ctx, shopID, matches)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrOrderNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrStatementLineMatched, apierr.ErrOrderNotOutstanding, apierr.ErrPaymentAboveBalance:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+			return
+		}
+		logger.WithError(err).Error("confirm_payment_matches_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "confirm_payment_matches")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+func validateConfirmPaymentMatches(inp ConfirmPaymentMatchesRequest) (bool, error) {
+	if len(inp.Matches) == 0 {
+		return false, errors.New(apierr.ErrPaymentMatchesRequired)
+	}
+
+	for _, m := range inp.Matches {
+		if m.OrderID <= 0 {
+			return false, errors.New(apierr.ErrOrderIDRequired)
+		}
+		if m.Amount <= 0 {
+			return false, errors.New(apierr.ErrPaymentAmountInvalid)
+		}
+		if m.Mutation.Date.IsZero() || m.Mutation.Amount <= 0 {
+			return false, errors.New(apierr.ErrStatementLineRequired)
+		}
+		if m.Amount > m.Mutation.Amount {
+			return false, errors.New(apierr.ErrPaymentAmountInvalid)
+		}
+	}
+
+	return true, nil
+}
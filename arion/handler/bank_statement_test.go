@@ -0,0 +1,268 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/bankstatement"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+func TestMatchBankStatementHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetBankStatementService()
+	defer handler.SetBankStatementService(oldService)
+
+	mockService := mock_service.NewMockBankStatementService(ctrl)
+	handler.SetBankStatementService(mockService)
+
+	buildRequest := func(bank string, withFile bool) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		if bank != "" {
+			writer.WriteField("bank", bank)
+		}
+		if withFile {
+			part, _ := writer.CreateFormFile("file", "mutasi.csv")
+			part.Write([]byte("Date,Description,Debit,Credit\n01/03/2024,TRANSFER DARI BUDI,0,150000\n"))
+		}
+		writer.Close()
+
+		req := newRequestWithShopID("POST", "/bank_statements/match", body.Bytes(), 1)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		bank        string
+		withFile    bool
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully match statement",
+			bank:     "mandiri",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().
+					MatchBankStatement(gomock.Any(), 1, "mandiri", gomock.Any()).
+					Return(response.BankStatementMatchData{
+						Bank:      "mandiri",
+						Matches:   []response.PaymentMatchData{{OrderID: 7, Amount: 150000, Score: 100}},
+						Unmatched: []response.BankMutationData{},
+					}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when bank is missing",
+			withFile:    true,
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when file is missing",
+			bank:        "bca",
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 400 for unsupported bank",
+			bank:     "bni",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().
+					MatchBankStatement(gomock.Any(), 1, "bni", gomock.Any()).
+					Return(response.BankStatementMatchData{}, errors.New(apierr.ErrBankNotSupported))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service error",
+			bank:     "bca",
+			withFile: true,
+			mockSetup: func() {
+				mockService.EXPECT().
+					MatchBankStatement(gomock.Any(), 1, "bca", gomock.Any()).
+					Return(response.BankStatementMatchData{}, errors.New("db error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			req := buildRequest(tt.bank, tt.withFile)
+			rec := httptest.NewRecorder()
+
+			handler.MatchBankStatementHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("MatchBankStatementHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("MatchBankStatementHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestConfirmPaymentMatchesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetBankStatementService()
+	defer handler.SetBankStatementService(oldService)
+
+	mockService := mock_service.NewMockBankStatementService(ctrl)
+	handler.SetBankStatementService(mockService)
+
+	fixedTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mutation := func(amount int) map[string]interface{} {
+		return map[string]interface{}{"date": fixedTime, "description": "TRSF E-BANKING CR SITI", "amount": amount}
+	}
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully confirm matches",
+			body: map[string]interface{}{
+				"matches": []map[string]interface{}{{"order_id": 1, "amount": 150000, "mutation": mutation(150000)}},
+			},
+			mockSetup: func() {
+				mockService.EXPECT().
+					ConfirmPaymentMatches(gomock.Any(), 1, []service.ConfirmPaymentMatchInput{{
+						OrderID:  1,
+						Amount:   150000,
+						Mutation: bankstatement.Mutation{Date: fixedTime, Description: "TRSF E-BANKING CR SITI", Amount: 150000, IsCredit: true},
+					}}).
+					Return([]response.OrderPaymentData{{ID: 11, OrderID: 1, Amount: 150000, CreatedAt: fixedTime}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when matches are empty",
+			body:        map[string]interface{}{"matches": []map[string]int{}},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when amount is not positive",
+			body: map[string]interface{}{
+				"matches": []map[string]interface{}{{"order_id": 1, "amount": 0, "mutation": mutation(1000)}},
+			},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when the statement credit is missing",
+			body: map[string]interface{}{
+				"matches": []map[string]interface{}{{"order_id": 1, "amount": 1000}},
+			},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when amount is above the statement credit",
+			body: map[string]interface{}{
+				"matches": []map[string]interface{}{{"order_id": 1, "amount": 2000, "mutation": mutation(1000)}},
+			},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when an order is not found",
+			body: map[string]interface{}{
+				"matches": []map[string]interface{}{{"order_id": 99, "amount": 1000, "mutation": mutation(1000)}},
+			},
+			mockSetup: func() {
+				mockService.EXPECT().
+					ConfirmPaymentMatches(gomock.Any(), 1, gomock.Any()).
+					Return(nil, errors.New(apierr.ErrOrderNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 409 when a statement credit was already recorded",
+			body: map[string]interface{}{
+				"matches": []map[string]interface{}{{"order_id": 1, "amount": 1000, "mutation": mutation(1000)}},
+			},
+			mockSetup: func() {
+				mockService.EXPECT().
+					ConfirmPaymentMatches(gomock.Any(), 1, gomock.Any()).
+					Return(nil, errors.New(apierr.ErrStatementLineMatched))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			body: map[string]interface{}{
+				"matches": []map[string]interface{}{{"order_id": 1, "amount": 1000, "mutation": mutation(1000)}},
+			},
+			mockSetup: func() {
+				mockService.EXPECT().
+					ConfirmPaymentMatches(gomock.Any(), 1, gomock.Any()).
+					Return(nil, errors.New("db error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			body, _ := json.Marshal(tt.body)
+			req := newRequestWithShopID("POST", "/bank_statements/confirm", body, 1)
+			rec := httptest.NewRecorder()
+
+			handler.ConfirmPaymentMatchesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ConfirmPaymentMatchesHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ConfirmPaymentMatchesHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
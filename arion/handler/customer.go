@@ -3,16 +3,22 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/zeirash/recapo/arion/common"
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/logger"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/service"
 )
 
+// postalCodePattern matches an Indonesian kode pos.
+var postalCodePattern = regexp.MustCompile(`^\d{5}$`)
+
 type (
 	CreateCustomerRequest struct {
 		Name    string `json:"name"`
@@ -21,9 +27,50 @@ type (
 	}
 
 	UpdateCustomerRequest struct {
-		Name    *string `json:"name"`
-		Phone   *string `json:"phone"`
-		Address *string `json:"address"`
+		Name     *string   `json:"name"`
+		Phone    *string   `json:"phone"`
+		Address  *string   `json:"address"`
+		Priority *int      `json:"priority"`
+		Tags     *[]string `json:"tags"`
+		Blocked  *bool     `json:"blocked"`
+	}
+
+	// CreateCustomerCreditRequest is the body for POST /customers/{customer_id}/credit.
+	CreateCustomerCreditRequest struct {
+		Type   string `json:"type"`
+		Amount int    `json:"amount"`
+		Notes  string `json:"notes"`
+	}
+
+	// CreateCustomerAddressRequest is the body for POST /customers/{customer_id}/address.
+	CreateCustomerAddressRequest struct {
+		Label      string `json:"label"`
+		Recipient  string `json:"recipient"`
+		Phone      string `json:"phone"`
+		Address    string `json:"address"`
+		Province   string `json:"province"`
+		City       string `json:"city"`
+		District   string `json:"district"`
+		PostalCode string `json:"postal_code"`
+		IsDefault  bool   `json:"is_default"`
+	}
+
+	UpdateCustomerAddressRequest struct {
+		Label      *string `json:"label"`
+		Recipient  *string `json:"recipient"`
+		Phone      *string `json:"phone"`
+		Address    *string `json:"address"`
+		Province   *string `json:"province"`
+		City       *string `json:"city"`
+		District   *string `json:"district"`
+		PostalCode *string `json:"postal_code"`
+		IsDefault  *bool   `json:"is_default"`
+	}
+
+	// MergeCustomersRequest is the body for POST /customers/merge.
+	MergeCustomersRequest struct {
+		CustomerID   int   `json:"customer_id"`
+		DuplicateIDs []int `json:"duplicate_ids"`
 	}
 
 	// CheckActiveOrderRequest is the body for POST /customers/check_active_order. Phone required; name and address optional (used when creating customer).
@@ -36,7 +83,7 @@ type (
 // CreateCustomerHandler godoc
 //
 //	@Summary		Create customer
-//	@Description	Create a new customer for the shop.
+//	@Description	Create a new customer for the shop. The phone is stored in E.164 form; numbers without a country code are read as Indonesian (0812… becomes +62812…).
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			customer
 //	@Accept			json
@@ -44,7 +91,8 @@ type (
 //	@Security		BearerAuth
 //	@Param			body	body		CreateCustomerRequest	true	"Customer data"
 //	@Success		200		{object}	response.CustomerData
-//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or validation)"
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON, validation or invalid phone)"
+//	@Failure		409		{object}	ErrorApiResponse	"A customer with this phone already exists"
 //	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/customer [post]
 func CreateCustomerHandler(w http.ResponseWriter, r *http.Request) {
@@ -64,6 +112,14 @@ func CreateCustomerHandler(w http.ResponseWriter, r *http.Request) {
 
 	res, err := customerService.CreateCustomer(ctx, inp.Name, inp.Phone, inp.Address, shopID)
 	if err != nil {
+		switch err.Error() {
+		case apierr.ErrCustomerPhoneInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		case apierr.ErrCustomerPhoneExists:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+			return
+		}
 		logger.WithError(err).Error("create_customer_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_customer")
 		return
@@ -75,7 +131,7 @@ func CreateCustomerHandler(w http.ResponseWriter, r *http.Request) {
 // GetCustomerHandler godoc
 //
 //	@Summary		Get customer by ID
-//	@Description	Get a single customer by ID.
+//	@Description	Get a single customer by ID, with the credit balance and lifetime stats: order count, total spent, outstanding balance and first and last order dates. Cancelled orders are not counted.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			customer
 //	@Accept			json
@@ -116,14 +172,21 @@ func GetCustomerHandler(w http.ResponseWriter, r *http.Request) {
 // GetCustomersHandler godoc
 //
 //	@Summary		List customers
-//	@Description	Get all customers for the shop. Optional search query to filter by name, phone, or address.
+//	@Description	Get all customers for the shop with their lifetime stats. Optional search query to filter by name, phone, or address.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			customer
 //	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			search	query		string	false	"Search query"
-//	@Param			sort  	query		string	false	"Sort by column and order (e.g. name,desc)"
+//	@Param			search			query		string	false	"Search query"
+//	@Param			tags			query		string	false	"Customers with any of the tags, comma separated (e.g. VIP,reseller)"
+//	@Param			blocked			query		bool	false	"Only blocked (true) or unblocked (false) customers"
+//	@Param			min_orders		query		int		false	"At least this many orders"
+//	@Param			min_spent		query		int		false	"At least this total spent"
+//	@Param			has_outstanding	query		bool	false	"Only customers with (true) or without (false) an outstanding balance"
+//	@Param			last_order_from	query		string	false	"Last order on or after date (YYYY-MM-DD)"
+//	@Param			last_order_to	query		string	false	"Last order on or before date (YYYY-MM-DD)"
+//	@Param			sort  	query		string	false	"Sort by column and order (e.g. name,desc). Stats columns: order_count, total_spent, outstanding, first_order_at, last_order_at"
 //	@Success		200		{array}		response.CustomerData
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/customers [get]
@@ -131,11 +194,49 @@ func GetCustomersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	shopID := ctx.Value(common.ShopIDKey).(int)
 
-	filter := model.FilterOptions{}
-	if q := r.URL.Query().Get("search"); q != "" {
+	query := r.URL.Query()
+	filter := model.CustomerFilterOptions{}
+	if q := query.Get("search"); q != "" {
 		filter.SearchQuery = &q
 	}
-	if sort := r.URL.Query().Get("sort"); sort != "" {
+	if tags := query.Get("tags"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	if b := query.Get("blocked"); b != "" {
+		if blocked, err := strconv.ParseBool(b); err == nil {
+			filter.Blocked = &blocked
+		}
+	}
+	if mo := query.Get("min_orders"); mo != "" {
+		if n, err := strconv.Atoi(mo); err == nil {
+			filter.MinOrders = &n
+		}
+	}
+	if ms := query.Get("min_spent"); ms != "" {
+		if n, err := strconv.Atoi(ms); err == nil {
+			filter.MinSpent = &n
+		}
+	}
+	if ho := query.Get("has_outstanding"); ho != "" {
+		if b, err := strconv.ParseBool(ho); err == nil {
+			filter.HasOutstanding = &b
+		}
+	}
+	if df := query.Get("last_order_from"); df != "" {
+		if t, err := parseDate(df); err == nil {
+			filter.LastOrderFrom = &t
+		}
+	}
+	if dt := query.Get("last_order_to"); dt != "" {
+		if t, err := parseDate(dt); err == nil {
+			filter.LastOrderTo = &t
+		}
+	}
+	if sort := query.Get("sort"); sort != "" {
 		filter.Sort = &sort
 	}
 
@@ -152,7 +253,7 @@ func GetCustomersHandler(w http.ResponseWriter, r *http.Request) {
 // UpdateCustomerHandler godoc
 //
 //	@Summary		Update customer
-//	@Description	Update an existing customer. Only provided fields are updated.
+//	@Description	Update an existing customer. Only provided fields are updated. tags replaces the customer's tags; blocked refuses public temp orders from the customer's phone.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			customer
 //	@Accept			json
@@ -161,7 +262,8 @@ func GetCustomersHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			customer_id	path		int						true	"Customer ID"
 //	@Param			body		body		UpdateCustomerRequest	true	"Fields to update"
 //	@Success		200			{object}	response.CustomerData
-//	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid JSON or customer_id)"
+//	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid JSON, customer_id, phone or tags)"
+//	@Failure		409	{object}	ErrorApiResponse	"A customer with this phone already exists"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/customers/{customer_id} [patch]
 func UpdateCustomerHandler(w http.ResponseWriter, r *http.Request) {
@@ -181,12 +283,23 @@ func UpdateCustomerHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res, err := customerService.UpdateCustomer(ctx, service.UpdateCustomerInput{
-		ID:      customerIDInt,
-		Name:    inp.Name,
-		Phone:   inp.Phone,
-		Address: inp.Address,
+		ID:       customerIDInt,
+		Name:     inp.Name,
+		Phone:    inp.Phone,
+		Address:  inp.Address,
+		Priority: inp.Priority,
+		Tags:     inp.Tags,
+		Blocked:  inp.Blocked,
 	})
 	if err != nil {
+		switch err.Error() {
+		case apierr.ErrCustomerPhoneInvalid, apierr.ErrCustomerTagInvalid, apierr.ErrTooManyCustomerTags:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		case apierr.ErrCustomerPhoneExists:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+			return
+		}
 		logger.WithError(err).Error("update_customer_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_customer")
 		return
@@ -232,14 +345,14 @@ func DeleteCustomerHandler(w http.ResponseWriter, r *http.Request) {
 // CustomerCheckActiveOrderHandler godoc
 //
 //	@Summary		Check active order by phone (get-or-create customer)
-//	@Description	Looks up customer by phone for the shop. If not found, creates a customer with the given phone (and optional name/address). Returns customer_id and whether that customer has an active order. Use when the client has a phone number but may not have selected a customer yet (e.g. before creating an order).
+//	@Description	Looks up customer by phone for the shop, comparing normalized numbers so 0812…, +62812… and 62 812-… match. If not found, creates a customer with the given phone (and optional name/address). Returns customer_id and whether that customer has an active order. Use when the client has a phone number but may not have selected a customer yet (e.g. before creating an order).
 //	@Tags			customer
 //	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			body		body		CheckActiveOrderRequest	true	"Phone required; name and address optional (used only when creating)"
 //	@Success		200			{object}	response.CustomerCheckActiveOrderByPhone
-//	@Failure		400	{object}	ErrorApiResponse	"Bad request (phone required or invalid JSON)"
+//	@Failure		400	{object}	ErrorApiResponse	"Bad request (phone required or invalid, or invalid JSON)"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/customers/check_active_order [post]
 func CustomerCheckActiveOrderHandler(w http.ResponseWriter, r *http.Request) {
@@ -259,6 +372,10 @@ func CustomerCheckActiveOrderHandler(w http.ResponseWriter, r *http.Request) {
 
 	res, err := customerService.CheckActiveOrderByPhone(ctx, inp.Phone, inp.Name, shopID)
 	if err != nil {
+		if err.Error() == apierr.ErrCustomerPhoneInvalid {
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
 		logger.WithError(err).Error("check_active_order_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "check_active_order")
 		return
@@ -267,6 +384,430 @@ func CustomerCheckActiveOrderHandler(w http.ResponseWriter, r *http.Request) {
 	WriteJson(w, http.StatusOK, res)
 }
 
+// GetCustomerCreditsHandler godoc
+//
+//	@Summary		Get customer credit ledger
+//	@Description	Get the customer's credit balance and ledger entries (deposits, overpayments, refunds, adjustments and payments), newest first.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			customer_id	path		int	true	"Customer ID"
+//	@Success		200			{object}	response.CustomerCreditsData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid customer_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Customer not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/{customer_id}/credits [get]
+func GetCustomerCreditsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateCustomerID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	customerIDInt, _ := strconv.Atoi(params["customer_id"])
+
+	res, err := customerService.GetCustomerCredits(ctx, customerIDInt, shopID)
+	if err != nil {
+		if err.Error() == apierr.ErrCustomerNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("get_customer_credits_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_customer_credits")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// CreateCustomerCreditHandler godoc
+//
+//	@Summary		Record customer credit
+//	@Description	Add an entry to the customer's credit ledger. deposit and refund take a positive amount; adjustment may be negative but cannot take the balance below zero.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			customer_id	path		int							true	"Customer ID"
+//	@Param			body		body		CreateCustomerCreditRequest	true	"Credit entry"
+//	@Success		200			{object}	response.CustomerCreditData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON, type or amount, or insufficient credit)"
+//	@Failure		404			{object}	ErrorApiResponse	"Customer not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/{customer_id}/credit [post]
+func CreateCustomerCreditHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateCustomerID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := CreateCustomerCreditRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateCreateCustomerCredit(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	customerIDInt, _ := strconv.Atoi(params["customer_id"])
+
+	res, err := customerService.CreateCustomerCredit(ctx, service.CreateCustomerCreditInput{
+		CustomerID: customerIDInt,
+		ShopID:     shopID,
+		Type:       inp.Type,
+		Amount:     inp.Amount,
+		Notes:      inp.Notes,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrCustomerNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrInsufficientCredit:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("create_customer_credit_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_customer_credit")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// GetCustomerAddressesHandler godoc
+//
+//	@Summary		Get customer addresses
+//	@Description	Get the customer's address book, default address first.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			customer_id	path		int	true	"Customer ID"
+//	@Success		200			{array}		response.CustomerAddressData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid customer_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Customer not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/{customer_id}/addresses [get]
+func GetCustomerAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateCustomerID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	customerIDInt, _ := strconv.Atoi(params["customer_id"])
+
+	res, err := customerService.GetCustomerAddresses(ctx, customerIDInt, shopID)
+	if err != nil {
+		if err.Error() == apierr.ErrCustomerNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("get_customer_addresses_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_customer_addresses")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// CreateCustomerAddressHandler godoc
+//
+//	@Summary		Add customer address
+//	@Description	Add an address to the customer's address book. The customer's first address becomes the default; is_default replaces the current default.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			customer_id	path		int								true	"Customer ID"
+//	@Param			body		body		CreateCustomerAddressRequest	true	"Address"
+//	@Success		200			{object}	response.CustomerAddressData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON, missing recipient or address, invalid phone or postal code)"
+//	@Failure		404			{object}	ErrorApiResponse	"Customer not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/{customer_id}/address [post]
+func CreateCustomerAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateCustomerID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := CreateCustomerAddressRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateShippingAddress(inp.Recipient, inp.Address, inp.PostalCode); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	customerIDInt, _ := strconv.Atoi(params["customer_id"])
+
+	res, err := customerService.CreateCustomerAddress(ctx, service.CreateCustomerAddressInput{
+		CustomerID: customerIDInt,
+		ShopID:     shopID,
+		Label:      strings.TrimSpace(inp.Label),
+		Recipient:  strings.TrimSpace(inp.Recipient),
+		Phone:      inp.Phone,
+		Address:    strings.TrimSpace(inp.Address),
+		Province:   strings.TrimSpace(inp.Province),
+		City:       strings.TrimSpace(inp.City),
+		District:   strings.TrimSpace(inp.District),
+		PostalCode: strings.TrimSpace(inp.PostalCode),
+		IsDefault:  inp.IsDefault,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrCustomerNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrCustomerPhoneInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("create_customer_address_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_customer_address")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// UpdateCustomerAddressHandler godoc
+//
+//	@Summary		Update customer address
+//	@Description	Update an address in the customer's address book. Setting is_default replaces the current default. Orders keep the address they were created with.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			customer_id	path		int								true	"Customer ID"
+//	@Param			address_id	path		int								true	"Address ID"
+//	@Param			body		body		UpdateCustomerAddressRequest	true	"Fields to update"
+//	@Success		200			{object}	response.CustomerAddressData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON or validation)"
+//	@Failure		404			{object}	ErrorApiResponse	"Customer or address not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/{customer_id}/addresses/{address_id} [patch]
+func UpdateCustomerAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateCustomerAddressID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := UpdateCustomerAddressRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateUpdateCustomerAddress(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	customerIDInt, _ := strconv.Atoi(params["customer_id"])
+	addressIDInt, _ := strconv.Atoi(params["address_id"])
+
+	res, err := customerService.UpdateCustomerAddress(ctx, service.UpdateCustomerAddressInput{
+		ID:         addressIDInt,
+		CustomerID: customerIDInt,
+		ShopID:     shopID,
+		Label:      inp.Label,
+		Recipient:  inp.Recipient,
+		Phone:      inp.Phone,
+		Address:    inp.Address,
+		Province:   inp.Province,
+		City:       inp.City,
+		District:   inp.District,
+		PostalCode: inp.PostalCode,
+		IsDefault:  inp.IsDefault,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrCustomerNotFound, apierr.ErrCustomerAddressNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrCustomerPhoneInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("update_customer_address_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_customer_address")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// DeleteCustomerAddressHandler godoc
+//
+//	@Summary		Delete customer address
+//	@Description	Remove an address from the customer's address book. Orders keep the address they were created with. Deleting the default leaves the customer without one.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			customer
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			customer_id	path		int	true	"Customer ID"
+//	@Param			address_id	path		int	true	"Address ID"
+//	@Success		200			{string}	string	"Success. data contains \"OK\""
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid customer_id or address_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Customer or address not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/{customer_id}/addresses/{address_id} [delete]
+func DeleteCustomerAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateCustomerAddressID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	customerIDInt, _ := strconv.Atoi(params["customer_id"])
+	addressIDInt, _ := strconv.Atoi(params["address_id"])
+
+	if err := customerService.DeleteCustomerAddress(ctx, addressIDInt, customerIDInt, shopID); err != nil {
+		switch err.Error() {
+		case apierr.ErrCustomerNotFound, apierr.ErrCustomerAddressNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("delete_customer_address_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "delete_customer_address")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// MergeCustomersHandler godoc
+//
+//	@Summary		Merge customers
+//	@Description	Merge duplicate customers into the one given by customer_id. Orders (with their payments) and credit entries of the duplicates move to that customer, and the duplicates are deleted.
+//	@Description	A customer has at most one open order, so the merge is refused while more than one of them has one.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		MergeCustomersRequest	true	"Surviving customer_id and the duplicate_ids to fold into it"
+//	@Success		200		{object}	response.CustomerData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON, customer_id or duplicate_ids missing, or merging a customer into itself)"
+//	@Failure		404		{object}	ErrorApiResponse	"Customer not found"
+//	@Failure		409		{object}	ErrorApiResponse	"More than one of the customers has an open order"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/merge [post]
+func MergeCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := MergeCustomersRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateMergeCustomers(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	res, err := customerService.MergeCustomers(ctx, shopID, inp.CustomerID, inp.DuplicateIDs)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrCustomerNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrCustomerMergeIntoSelf, apierr.ErrDuplicateIDsRequired:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		case apierr.ErrCustomerMergeOrders:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+			return
+		}
+		logger.WithError(err).Error("merge_customers_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "merge_customers")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// AnonymizeCustomerHandler godoc
+//
+//	@Summary		Erase customer personal data
+//	@Description	Erase the personal data of a customer on their request: the name becomes "Deleted customer" and phone, address, tags, saved addresses and the shipping addresses of their orders are removed.
+//	@Description	Temp orders sent from their phone lose name, phone and address too. Orders, payments and totals stay, so reports don't change. This can't be undone.
+//	@Description	Works without an active subscription.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			customer_id	path		int	true	"Customer ID"
+//	@Success		200			{object}	response.CustomerData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid customer_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Customer not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/{customer_id}/anonymize [post]
+func AnonymizeCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	params := mux.Vars(r)
+	if valid, err := validateCustomerID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	customerIDInt, _ := strconv.Atoi(params["customer_id"])
+
+	res, err := customerService.AnonymizeCustomer(ctx, customerIDInt, shopID)
+	if err != nil {
+		if err.Error() == apierr.ErrCustomerNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("anonymize_customer_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "anonymize_customer")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
 func validateCreateCustomer(inp CreateCustomerRequest) (bool, error) {
 	if inp.Name == "" {
 		return false, errors.New(apierr.ErrNameRequired)
@@ -279,6 +820,24 @@ func validateCreateCustomer(inp CreateCustomerRequest) (bool, error) {
 	return true, nil
 }
 
+func validateMergeCustomers(inp MergeCustomersRequest) (bool, error) {
+	if inp.CustomerID <= 0 {
+		return false, errors.New(apierr.ErrCustomerIDRequired)
+	}
+
+	if len(inp.DuplicateIDs) == 0 {
+		return false, errors.New(apierr.ErrDuplicateIDsRequired)
+	}
+
+	for _, id := range inp.DuplicateIDs {
+		if id <= 0 {
+			return false, errors.New(apierr.ErrCustomerIDRequired)
+		}
+	}
+
+	return true, nil
+}
+
 func validateCustomerID(params map[string]string) (bool, error) {
 	if params["customer_id"] == "" {
 		return false, errors.New(apierr.ErrCustomerIDRequired)
@@ -298,3 +857,66 @@ func validateCheckActiveOrder(inp CheckActiveOrderRequest) (bool, error) {
 
 	return true, nil
 }
+
+func validateCreateCustomerCredit(inp CreateCustomerCreditRequest) (bool, error) {
+	switch inp.Type {
+	case constant.CustomerCreditTypeDeposit, constant.CustomerCreditTypeRefund:
+		if inp.Amount <= 0 {
+			return false, errors.New(apierr.ErrCreditAmountInvalid)
+		}
+	case constant.CustomerCreditTypeAdjustment:
+		if inp.Amount == 0 {
+			return false, errors.New(apierr.ErrCreditAmountInvalid)
+		}
+	default:
+		return false, errors.New(apierr.ErrCreditTypeInvalid)
+	}
+
+	return true, nil
+}
+
+func validateCustomerAddressID(params map[string]string) (bool, error) {
+	if params["customer_id"] == "" {
+		return false, errors.New(apierr.ErrCustomerIDRequired)
+	}
+
+	if params["address_id"] == "" {
+		return false, errors.New(apierr.ErrAddressIDRequired)
+	}
+
+	return true, nil
+}
+
+// validateShippingAddress checks the fields a courier can't do without. The
+// rest of the address is free text.
+func validateShippingAddress(recipient, address, postalCode string) (bool, error) {
+	if strings.TrimSpace(recipient) == "" {
+		return false, errors.New(apierr.ErrRecipientRequired)
+	}
+
+	if strings.TrimSpace(address) == "" {
+		return false, errors.New(apierr.ErrAddressRequired)
+	}
+
+	if postalCode = strings.TrimSpace(postalCode); postalCode != "" && !postalCodePattern.MatchString(postalCode) {
+		return false, errors.New(apierr.ErrPostalCodeInvalid)
+	}
+
+	return true, nil
+}
+
+func validateUpdateCustomerAddress(inp UpdateCustomerAddressRequest) (bool, error) {
+	if inp.Recipient != nil && strings.TrimSpace(*inp.Recipient) == "" {
+		return false, errors.New(apierr.ErrRecipientRequired)
+	}
+
+	if inp.Address != nil && strings.TrimSpace(*inp.Address) == "" {
+		return false, errors.New(apierr.ErrAddressRequired)
+	}
+
+	if inp.PostalCode != nil && *inp.PostalCode != "" && !postalCodePattern.MatchString(*inp.PostalCode) {
+		return false, errors.New(apierr.ErrPostalCodeInvalid)
+	}
+
+	return true, nil
+}
@@ -78,6 +78,36 @@ func TestCreateCustomerHandler(t *testing.T) {
 			wantSuccess:    false,
 			wantErrMessage: "database error",
 		},
+		{
+			name: "create customer returns 400 on invalid phone",
+			body: map[string]interface{}{
+				"name":  "John",
+				"phone": "12345",
+			},
+			shopID: 1,
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomer(gomock.Any(), "John", "12345", "", 1).
+					Return(response.CustomerData{}, errors.New(apierr.ErrCustomerPhoneInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "create customer returns 409 when phone exists",
+			body: map[string]interface{}{
+				"name":  "John",
+				"phone": "0812-3456-789",
+			},
+			shopID: 1,
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomer(gomock.Any(), "John", "0812-3456-789", "", 1).
+					Return(response.CustomerData{}, errors.New(apierr.ErrCustomerPhoneExists))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
 		{
 			name:        "create customer returns 400 on invalid json",
 			body:        "invalid json",
@@ -294,7 +324,7 @@ func TestGetCustomersHandler(t *testing.T) {
 			shopID: 1,
 			mockSetup: func() {
 				mockCustomerService.EXPECT().
-					GetCustomersByShopID(gomock.Any(), 1, model.FilterOptions{}).
+					GetCustomersByShopID(gomock.Any(), 1, model.CustomerFilterOptions{}).
 					Return([]response.CustomerData{
 						{ID: 1, Name: "John Doe", Phone: "08123456789", Address: "123 Main St", CreatedAt: fixedTime},
 						{ID: 2, Name: "Jane Doe", Phone: "08987654321", Address: "456 Oak Ave", CreatedAt: fixedTime},
@@ -311,7 +341,7 @@ func TestGetCustomersHandler(t *testing.T) {
 			mockSetup: func() {
 				q := "john"
 				mockCustomerService.EXPECT().
-					GetCustomersByShopID(gomock.Any(), 1, model.FilterOptions{SearchQuery: &q}).
+					GetCustomersByShopID(gomock.Any(), 1, model.CustomerFilterOptions{SearchQuery: &q}).
 					Return([]response.CustomerData{
 						{ID: 1, Name: "John Doe", Phone: "08123456789", Address: "123 Main St", CreatedAt: fixedTime},
 					}, nil)
@@ -327,7 +357,7 @@ func TestGetCustomersHandler(t *testing.T) {
 			mockSetup: func() {
 				s := "name,asc"
 				mockCustomerService.EXPECT().
-					GetCustomersByShopID(gomock.Any(), 1, model.FilterOptions{Sort: &s}).
+					GetCustomersByShopID(gomock.Any(), 1, model.CustomerFilterOptions{Sort: &s}).
 					Return([]response.CustomerData{
 						{ID: 2, Name: "Jane Doe", Phone: "08987654321", Address: "456 Oak Ave", CreatedAt: fixedTime},
 						{ID: 1, Name: "John Doe", Phone: "08123456789", Address: "123 Main St", CreatedAt: fixedTime},
@@ -337,13 +367,40 @@ func TestGetCustomersHandler(t *testing.T) {
 			wantSuccess: true,
 			wantCount:   2,
 		},
+		{
+			name:   "successfully get customers with profile filters",
+			url:    "/customers?tags=VIP,%20late%20payer&blocked=false&min_orders=2&min_spent=100000&has_outstanding=true&last_order_from=2024-03-01&last_order_to=2024-03-31",
+			shopID: 1,
+			mockSetup: func() {
+				blocked, hasOutstanding := false, true
+				minOrders, minSpent := 2, 100000
+				from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+				to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+				mockCustomerService.EXPECT().
+					GetCustomersByShopID(gomock.Any(), 1, model.CustomerFilterOptions{
+						Tags:           []string{"VIP", "late payer"},
+						Blocked:        &blocked,
+						MinOrders:      &minOrders,
+						MinSpent:       &minSpent,
+						HasOutstanding: &hasOutstanding,
+						LastOrderFrom:  &from,
+						LastOrderTo:    &to,
+					}).
+					Return([]response.CustomerData{
+						{ID: 1, Name: "John Doe", Phone: "08123456789", Tags: []string{"VIP"}, CreatedAt: fixedTime},
+					}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+			wantCount:   1,
+		},
 		{
 			name:   "get customers returns 500 on service error",
 			url:    "/customers",
 			shopID: 1,
 			mockSetup: func() {
 				mockCustomerService.EXPECT().
-					GetCustomersByShopID(gomock.Any(), 1, model.FilterOptions{}).
+					GetCustomersByShopID(gomock.Any(), 1, model.CustomerFilterOptions{}).
 					Return(nil, errors.New("database error"))
 			},
 			wantStatus:  http.StatusInternalServerError,
@@ -430,6 +487,42 @@ func TestUpdateCustomerHandler(t *testing.T) {
 			wantStatus:  http.StatusOK,
 			wantSuccess: true,
 		},
+		{
+			name:       "successfully update customer tags and block flag",
+			customerID: "1",
+			body: map[string]interface{}{
+				"tags":    []string{"VIP", "reseller"},
+				"blocked": true,
+			},
+			mockSetup: func() {
+				tags := []string{"VIP", "reseller"}
+				blocked := true
+				mockCustomerService.EXPECT().
+					UpdateCustomer(gomock.Any(), service.UpdateCustomerInput{
+						ID:      1,
+						Tags:    &tags,
+						Blocked: &blocked,
+					}).
+					Return(response.CustomerData{ID: 1, Name: "John Doe", Tags: tags, Blocked: true, CreatedAt: fixedTime}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:       "update customer returns 400 on too many tags",
+			customerID: "1",
+			body: map[string]interface{}{
+				"tags": []string{"VIP"},
+			},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					UpdateCustomer(gomock.Any(), gomock.Any()).
+					Return(response.CustomerData{}, errors.New(apierr.ErrTooManyCustomerTags))
+			},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "A customer can have at most 20 tags",
+		},
 		{
 			name:        "update customer returns 400 when customer_id missing",
 			customerID:  "",
@@ -728,3 +821,645 @@ func intPtr(n int) *int {
 func ptrBool(b bool) *bool {
 	return &b
 }
+
+func TestGetCustomerCreditsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetCustomerService()
+	defer handler.SetCustomerService(oldService)
+
+	mockCustomerService := mock_service.NewMockCustomerService(ctrl)
+	handler.SetCustomerService(mockCustomerService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully get credit ledger",
+			pathVars: map[string]string{"customer_id": "1"},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					GetCustomerCredits(gomock.Any(), 1, 1).
+					Return(response.CustomerCreditsData{
+						Balance: 500000,
+						Entries: []response.CustomerCreditData{{ID: 1, Type: "deposit", Amount: 500000}},
+					}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on missing customer_id",
+			pathVars:    map[string]string{},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when customer not found",
+			pathVars: map[string]string{"customer_id": "1"},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					GetCustomerCredits(gomock.Any(), 1, 1).
+					Return(response.CustomerCreditsData{}, errors.New(apierr.ErrCustomerNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("GET", "/customers/1/credits", nil, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.GetCustomerCreditsHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetCustomerCreditsHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetCustomerCreditsHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestCreateCustomerCreditHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetCustomerService()
+	defer handler.SetCustomerService(oldService)
+
+	mockCustomerService := mock_service.NewMockCustomerService(ctrl)
+	handler.SetCustomerService(mockCustomerService)
+
+	tests := []struct {
+		name        string
+		body        map[string]interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully record deposit",
+			body: map[string]interface{}{"type": "deposit", "amount": 500000, "notes": "DP"},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomerCredit(gomock.Any(), service.CreateCustomerCreditInput{
+						CustomerID: 1, ShopID: 1, Type: "deposit", Amount: 500000, Notes: "DP",
+					}).
+					Return(response.CustomerCreditData{ID: 1, Type: "deposit", Amount: 500000, Notes: "DP"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on unknown type",
+			body:        map[string]interface{}{"type": "payment", "amount": 1000},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 on negative deposit",
+			body:        map[string]interface{}{"type": "deposit", "amount": -1000},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when adjustment overdraws balance",
+			body: map[string]interface{}{"type": "adjustment", "amount": -1000},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomerCredit(gomock.Any(), gomock.Any()).
+					Return(response.CustomerCreditData{}, errors.New(apierr.ErrInsufficientCredit))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{"type": "refund", "amount": 1000},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomerCredit(gomock.Any(), gomock.Any()).
+					Return(response.CustomerCreditData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			body, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/customers/1/credit", body, 1),
+				map[string]string{"customer_id": "1"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.CreateCustomerCreditHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CreateCustomerCreditHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CreateCustomerCreditHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestMergeCustomersHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetCustomerService()
+	defer handler.SetCustomerService(oldService)
+
+	mockCustomerService := mock_service.NewMockCustomerService(ctrl)
+	handler.SetCustomerService(mockCustomerService)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully merge customers",
+			body: map[string]interface{}{"customer_id": 1, "duplicate_ids": []int{2, 3}},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					MergeCustomers(gomock.Any(), 1, 1, []int{2, 3}).
+					Return(response.CustomerData{ID: 1, Name: "Jane", Phone: "+628123456789"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on invalid json",
+			body:        "invalid json",
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when customer_id is missing",
+			body:        map[string]interface{}{"duplicate_ids": []int{2}},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when duplicate_ids is empty",
+			body:        map[string]interface{}{"customer_id": 1, "duplicate_ids": []int{}},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when merging a customer into itself",
+			body: map[string]interface{}{"customer_id": 1, "duplicate_ids": []int{1}},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					MergeCustomers(gomock.Any(), 1, 1, []int{1}).
+					Return(response.CustomerData{}, errors.New(apierr.ErrCustomerMergeIntoSelf))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when a customer is not found",
+			body: map[string]interface{}{"customer_id": 1, "duplicate_ids": []int{99}},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					MergeCustomers(gomock.Any(), 1, 1, []int{99}).
+					Return(response.CustomerData{}, errors.New(apierr.ErrCustomerNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 409 when more than one customer has an open order",
+			body: map[string]interface{}{"customer_id": 1, "duplicate_ids": []int{2}},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					MergeCustomers(gomock.Any(), 1, 1, []int{2}).
+					Return(response.CustomerData{}, errors.New(apierr.ErrCustomerMergeOrders))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{"customer_id": 1, "duplicate_ids": []int{2}},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					MergeCustomers(gomock.Any(), 1, 1, []int{2}).
+					Return(response.CustomerData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			var bodyBytes []byte
+			switch b := tt.body.(type) {
+			case string:
+				bodyBytes = []byte(b)
+			default:
+				bodyBytes, _ = json.Marshal(b)
+			}
+
+			req := newRequestWithShopID("POST", "/customers/merge", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.MergeCustomersHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("MergeCustomersHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("MergeCustomersHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestCreateCustomerAddressHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetCustomerService()
+	defer handler.SetCustomerService(oldService)
+
+	mockCustomerService := mock_service.NewMockCustomerService(ctrl)
+	handler.SetCustomerService(mockCustomerService)
+
+	tests := []struct {
+		name        string
+		body        map[string]interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully add address",
+			body: map[string]interface{}{"label": "Rumah", "recipient": " Jane ", "address": "Jl. Merdeka 1", "city": "Bandung", "postal_code": "40111", "is_default": true},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomerAddress(gomock.Any(), service.CreateCustomerAddressInput{
+						CustomerID: 1, ShopID: 1, Label: "Rumah", Recipient: "Jane", Address: "Jl. Merdeka 1", City: "Bandung", PostalCode: "40111", IsDefault: true,
+					}).
+					Return(response.CustomerAddressData{ID: 1, Label: "Rumah", Recipient: "Jane", IsDefault: true}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when recipient is missing",
+			body:        map[string]interface{}{"address": "Jl. Merdeka 1"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when address is missing",
+			body:        map[string]interface{}{"recipient": "Jane", "address": "  "},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 on invalid postal code",
+			body:        map[string]interface{}{"recipient": "Jane", "address": "Jl. Merdeka 1", "postal_code": "4O111"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 on invalid phone",
+			body: map[string]interface{}{"recipient": "Jane", "address": "Jl. Merdeka 1", "phone": "12"},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomerAddress(gomock.Any(), gomock.Any()).
+					Return(response.CustomerAddressData{}, errors.New(apierr.ErrCustomerPhoneInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when customer not found",
+			body: map[string]interface{}{"recipient": "Jane", "address": "Jl. Merdeka 1"},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					CreateCustomerAddress(gomock.Any(), gomock.Any()).
+					Return(response.CustomerAddressData{}, errors.New(apierr.ErrCustomerNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			body, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/customers/1/address", body, 1),
+				map[string]string{"customer_id": "1"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.CreateCustomerAddressHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CreateCustomerAddressHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CreateCustomerAddressHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestUpdateCustomerAddressHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetCustomerService()
+	defer handler.SetCustomerService(oldService)
+
+	mockCustomerService := mock_service.NewMockCustomerService(ctrl)
+	handler.SetCustomerService(mockCustomerService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		body        map[string]interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully make address default",
+			pathVars: map[string]string{"customer_id": "1", "address_id": "2"},
+			body:     map[string]interface{}{"is_default": true},
+			mockSetup: func() {
+				isDefault := true
+				mockCustomerService.EXPECT().
+					UpdateCustomerAddress(gomock.Any(), service.UpdateCustomerAddressInput{
+						ID: 2, CustomerID: 1, ShopID: 1, IsDefault: &isDefault,
+					}).
+					Return(response.CustomerAddressData{ID: 2, IsDefault: true}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when address_id is missing",
+			pathVars:    map[string]string{"customer_id": "1"},
+			body:        map[string]interface{}{"label": "Kantor"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when recipient is cleared",
+			pathVars:    map[string]string{"customer_id": "1", "address_id": "2"},
+			body:        map[string]interface{}{"recipient": ""},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when address not found",
+			pathVars: map[string]string{"customer_id": "1", "address_id": "99"},
+			body:     map[string]interface{}{"label": "Kantor"},
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					UpdateCustomerAddress(gomock.Any(), gomock.Any()).
+					Return(response.CustomerAddressData{}, errors.New(apierr.ErrCustomerAddressNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			body, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("PATCH", "/customers/1/addresses/2", body, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.UpdateCustomerAddressHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UpdateCustomerAddressHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("UpdateCustomerAddressHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestDeleteCustomerAddressHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetCustomerService()
+	defer handler.SetCustomerService(oldService)
+
+	mockCustomerService := mock_service.NewMockCustomerService(ctrl)
+	handler.SetCustomerService(mockCustomerService)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully delete address",
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					DeleteCustomerAddress(gomock.Any(), 2, 1, 1).
+					Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 404 when address not found",
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					DeleteCustomerAddress(gomock.Any(), 2, 1, 1).
+					Return(errors.New(apierr.ErrCustomerAddressNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service failure",
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					DeleteCustomerAddress(gomock.Any(), 2, 1, 1).
+					Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("DELETE", "/customers/1/addresses/2", nil, 1),
+				map[string]string{"customer_id": "1", "address_id": "2"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.DeleteCustomerAddressHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("DeleteCustomerAddressHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("DeleteCustomerAddressHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestAnonymizeCustomerHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetCustomerService()
+	defer handler.SetCustomerService(oldService)
+
+	mockCustomerService := mock_service.NewMockCustomerService(ctrl)
+	handler.SetCustomerService(mockCustomerService)
+
+	tests := []struct {
+		name        string
+		customerID  string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:       "erases the customer's personal data",
+			customerID: "5",
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					AnonymizeCustomer(gomock.Any(), 5, 1).
+					Return(response.CustomerData{ID: 5, Name: "Deleted customer", Phone: "anon-5"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when customer_id is missing",
+			customerID:  "",
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 404 when the customer is not found",
+			customerID: "5",
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					AnonymizeCustomer(gomock.Any(), 5, 1).
+					Return(response.CustomerData{}, errors.New(apierr.ErrCustomerNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 500 on service failure",
+			customerID: "5",
+			mockSetup: func() {
+				mockCustomerService.EXPECT().
+					AnonymizeCustomer(gomock.Any(), 5, 1).
+					Return(response.CustomerData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/customers/"+tt.customerID+"/anonymize", nil, 1),
+				map[string]string{"customer_id": tt.customerID},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.AnonymizeCustomerHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("AnonymizeCustomerHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("AnonymizeCustomerHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
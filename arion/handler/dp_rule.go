@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+type (
+	CreateDPRuleRequest struct {
+		Name       string     `json:"name"`
+		Percent    int        `json:"percent"`
+		DueDays    *int       `json:"due_days"`
+		DueDate    *time.Time `json:"due_date"`
+		AutoCancel bool       `json:"auto_cancel"`
+		IsDefault  bool       `json:"is_default"`
+	}
+
+	UpdateDPRuleRequest struct {
+		Name       *string    `json:"name"`
+		Percent    *int       `json:"percent"`
+		DueDays    *int       `json:"due_days"`
+		DueDate    *time.Time `json:"due_date"`
+		AutoCancel *bool      `json:"auto_cancel"`
+		IsDefault  *bool      `json:"is_default"`
+	}
+)
+
+// CreateDPRuleHandler godoc
+//
+//	@Summary		Create DP rule
+//	@Description	Create a down-payment rule: the percent of the order total to pay up front and its deadline, either a number of days after the order is created (due_days) or a fixed date such as a trip's departure (due_date).
+//	@Description	The shop's default rule is applied to new orders. With auto_cancel, orders that miss the deadline are cancelled instead of only flagged.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			dp_rule
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		CreateDPRuleRequest	true	"DP rule data"
+//	@Success		200		{object}	response.DPRuleData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or validation)"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/dp_rule [post]
+func CreateDPRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := CreateDPRuleRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateCreateDPRule(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	res, err := dpRuleService.CreateDPRule(ctx, service.CreateDPRuleInput{
+		ShopID:     shopID,
+		Name:       strings.TrimSpace(inp.Name),
+		Percent:    inp.Percent,
+		DueDays:    inp.DueDays,
+		DueDate:    inp.DueDate,
+		AutoCancel: inp.AutoCancel,
+		IsDefault:  inp.IsDefault,
+	})
+	if err != nil {
+		logger.WithError(err).Error("create_dp_rule_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_dp_rule")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// GetDPRulesHandler godoc
+//
+//	@Summary		List DP rules
+//	@Description	Get all down-payment rules of the shop, default rule first.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			dp_rule
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{array}		response.DPRuleData
+//	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/dp_rules [get]
+func GetDPRulesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	res, err := dpRuleService.GetDPRulesByShopID(ctx, shopID)
+	if err != nil {
+		logger.WithError(err).Error("get_dp_rules_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_dp_rules")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// UpdateDPRuleHandler godoc
+//
+//	@Summary		Update DP rule
+//	@Description	Update a down-payment rule. Only provided fields are updated; setting due_days clears due_date and the other way around.
+//	@Description	Orders that already have the rule keep the DP amount and deadline computed when it was applied.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			dp_rule
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			dp_rule_id	path		int					true	"DP rule ID"
+//	@Param			body		body		UpdateDPRuleRequest	true	"Fields to update"
+//	@Success		200			{object}	response.DPRuleData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON or validation)"
+//	@Failure		404			{object}	ErrorApiResponse	"DP rule not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/dp_rules/{dp_rule_id} [patch]
+func UpdateDPRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateDPRuleID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	ruleIDInt, _ := strconv.Atoi(params["dp_rule_id"])
+
+	inp := UpdateDPRuleRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateUpdateDPRule(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	if inp.Name != nil {
+		name := strings.TrimSpace(*inp.Name)
+		inp.Name = &name
+	}
+
+	res, err := dpRuleService.UpdateDPRule(ctx, service.UpdateDPRuleInput{
+		ID:         ruleIDInt,
+		ShopID:     shopID,
+		Name:       inp.Name,
+		Percent:    inp.Percent,
+		DueDays:    inp.DueDays,
+		DueDate:    inp.DueDate,
+		AutoCancel: inp.AutoCancel,
+		IsDefault:  inp.IsDefault,
+	})
+	if err != nil {
+		if err.Error() == apierr.ErrDPRuleNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("update_dp_rule_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_dp_rule")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// DeleteDPRuleHandler godoc
+//
+//	@Summary		Delete DP rule
+//	@Description	Delete a down-payment rule. Orders that used it keep their DP amount and deadline.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			dp_rule
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			dp_rule_id	path		int	true	"DP rule ID"
+//	@Success		200			{string}	string	"Success. data contains \"OK\""
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid dp_rule_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"DP rule not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/dp_rules/{dp_rule_id} [delete]
+func DeleteDPRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateDPRuleID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	ruleIDInt, _ := strconv.Atoi(params["dp_rule_id"])
+
+	if err := dpRuleService.DeleteDPRuleByID(ctx, ruleIDInt, shopID); err != nil {
+		if err.Error() == apierr.ErrDPRuleNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("delete_dp_rule_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "delete_dp_rule")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+func validateDPRuleID(params map[string]string) (bool, error) {
+	if params["dp_rule_id"] == "" {
+		return false, errors.New(apierr.ErrDPRuleIDRequired)
+	}
+
+	return true, nil
+}
+
+func validateCreateDPRule(inp CreateDPRuleRequest) (bool, error) {
+	if strings.TrimSpace(inp.Name) == "" {
+		return false, errors.New(apierr.ErrDPRuleNameRequired)
+	}
+
+	if inp.Percent < 1 || inp.Percent > 100 {
+		return false, errors.New(apierr.ErrDPPercentInvalid)
+	}
+
+	if (inp.DueDays == nil) == (inp.DueDate == nil) || (inp.DueDays != nil && *inp.DueDays < 0) {
+		return false, errors.New(apierr.ErrDPDeadlineInvalid)
+	}
+
+	return true, nil
+}
+
+func validateUpdateDPRule(inp UpdateDPRuleRequest) (bool, error) {
+	if inp.Name != nil && strings.TrimSpace(*inp.Name) == "" {
+		return false, errors.New(apierr.ErrDPRuleNameRequired)
+	}
+
+	if inp.Percent != nil && (*inp.Percent < 1 || *inp.Percent > 100) {
+		return false, errors.New(apierr.ErrDPPercentInvalid)
+	}
+
+	if (inp.DueDays != nil && inp.DueDate != nil) || (inp.DueDays != nil && *inp.DueDays < 0) {
+		return false, errors.New(apierr.ErrDPDeadlineInvalid)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,319 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+func TestCreateDPRuleHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDPRuleService := mock_service.NewMockDPRuleService(ctrl)
+	handler.SetDPRuleService(mockDPRuleService)
+
+	dueDays := 3
+
+	tests := []struct {
+		name           string
+		body           interface{}
+		mockSetup      func()
+		wantStatus     int
+		wantSuccess    bool
+		wantErrMessage string
+	}{
+		{
+			name: "successfully create rule",
+			body: map[string]interface{}{"name": " DP 50% ", "percent": 50, "due_days": 3, "is_default": true},
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().
+					CreateDPRule(gomock.Any(), service.CreateDPRuleInput{ShopID: 1, Name: "DP 50%", Percent: 50, DueDays: &dueDays, IsDefault: true}).
+					Return(response.DPRuleData{ID: 1, Name: "DP 50%", Percent: 50, DueDays: &dueDays, IsDefault: true}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:           "returns 400 when name is missing",
+			body:           map[string]interface{}{"percent": 50, "due_days": 3},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "DP rule name is required",
+		},
+		{
+			name:           "returns 400 when percent is out of range",
+			body:           map[string]interface{}{"name": "DP", "percent": 120, "due_days": 3},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "DP percent must be between 1 and 100",
+		},
+		{
+			name:           "returns 400 when no deadline is given",
+			body:           map[string]interface{}{"name": "DP", "percent": 50},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Set either due_days (0 or more) or due_date, not both",
+		},
+		{
+			name:           "returns 400 when both deadlines are given",
+			body:           map[string]interface{}{"name": "DP", "percent": 50, "due_days": 3, "due_date": "2024-02-01T00:00:00Z"},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Set either due_days (0 or more) or due_date, not both",
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{"name": "DP", "percent": 50, "due_date": "2024-02-01T00:00:00Z"},
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().
+					CreateDPRule(gomock.Any(), gomock.Any()).
+					Return(response.DPRuleData{}, errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithShopID("POST", "/dp_rule", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.CreateDPRuleHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CreateDPRuleHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CreateDPRuleHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+			if tt.wantErrMessage != "" && resp.Message != tt.wantErrMessage {
+				t.Errorf("CreateDPRuleHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
+			}
+		})
+	}
+}
+
+func TestGetDPRulesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDPRuleService := mock_service.NewMockDPRuleService(ctrl)
+	handler.SetDPRuleService(mockDPRuleService)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully get rules",
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().
+					GetDPRulesByShopID(gomock.Any(), 1).
+					Return([]response.DPRuleData{{ID: 1, Name: "DP 50%", Percent: 50}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 500 on service failure",
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().
+					GetDPRulesByShopID(gomock.Any(), 1).
+					Return(nil, errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("GET", "/dp_rules", nil, 1)
+			rec := httptest.NewRecorder()
+
+			handler.GetDPRulesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetDPRulesHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetDPRulesHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestUpdateDPRuleHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDPRuleService := mock_service.NewMockDPRuleService(ctrl)
+	handler.SetDPRuleService(mockDPRuleService)
+
+	percent := 40
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully update rule",
+			pathVars: map[string]string{"dp_rule_id": "2"},
+			body:     map[string]interface{}{"percent": 40},
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().
+					UpdateDPRule(gomock.Any(), service.UpdateDPRuleInput{ID: 2, ShopID: 1, Percent: &percent}).
+					Return(response.DPRuleData{ID: 2, Percent: 40}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on missing dp_rule_id",
+			pathVars:    map[string]string{},
+			body:        map[string]interface{}{"percent": 40},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when both deadlines are given",
+			pathVars:    map[string]string{"dp_rule_id": "2"},
+			body:        map[string]interface{}{"due_days": 3, "due_date": "2024-02-01T00:00:00Z"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when rule not found",
+			pathVars: map[string]string{"dp_rule_id": "2"},
+			body:     map[string]interface{}{"percent": 40},
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().
+					UpdateDPRule(gomock.Any(), gomock.Any()).
+					Return(response.DPRuleData{}, errors.New(apierr.ErrDPRuleNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("PATCH", "/dp_rules/2", bodyBytes, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.UpdateDPRuleHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UpdateDPRuleHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("UpdateDPRuleHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestDeleteDPRuleHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDPRuleService := mock_service.NewMockDPRuleService(ctrl)
+	handler.SetDPRuleService(mockDPRuleService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully delete rule",
+			pathVars: map[string]string{"dp_rule_id": "2"},
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().DeleteDPRuleByID(gomock.Any(), 2, 1).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:     "returns 404 when rule not found",
+			pathVars: map[string]string{"dp_rule_id": "2"},
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().DeleteDPRuleByID(gomock.Any(), 2, 1).Return(errors.New(apierr.ErrDPRuleNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service failure",
+			pathVars: map[string]string{"dp_rule_id": "2"},
+			mockSetup: func() {
+				mockDPRuleService.EXPECT().DeleteDPRuleByID(gomock.Any(), 2, 1).Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("DELETE", "/dp_rules/2", nil, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.DeleteDPRuleHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("DeleteDPRuleHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("DeleteDPRuleHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -38,6 +38,15 @@ var (
 	feedbackService     service.FeedbackService
 	systemService       service.SystemService
 	invitationService   service.InvitationService
+
+	bankStatementService   service.BankStatementService
+	dpRuleService          service.DPRuleService
+	purchaseListService    service.PurchaseListService
+	reportService          service.ReportService
+	shareLinkService       service.ShareLinkService
+	importService          service.ImportService
+	archiveService         service.ArchiveService
+	productCategoryService service.ProductCategoryService
 )
 
 func Init() {
@@ -76,6 +85,38 @@ func Init() {
 	if invitationService == nil {
 		invitationService = service.NewInvitationService()
 	}
+
+	if bankStatementService == nil {
+		bankStatementService = service.NewBankStatementService()
+	}
+
+	if dpRuleService == nil {
+		dpRuleService = service.NewDPRuleService()
+	}
+
+	if purchaseListService == nil {
+		purchaseListService = service.NewPurchaseListService()
+	}
+
+	if reportService == nil {
+		reportService = service.NewReportService()
+	}
+
+	if shareLinkService == nil {
+		shareLinkService = service.NewShareLinkService()
+	}
+
+	if importService == nil {
+		importService = service.NewImportService()
+	}
+
+	if archiveService == nil {
+		archiveService = service.NewArchiveService()
+	}
+
+	if productCategoryService == nil {
+		productCategoryService = service.NewProductCategoryService()
+	}
 }
 
 // SetFeedbackService sets the feedback service (for testing)
@@ -168,6 +209,86 @@ func GetInvitationService() service.InvitationService {
 	return invitationService
 }
 
+// SetBankStatementService sets the bank statement service (for testing).
+func SetBankStatementService(s service.BankStatementService) {
+	bankStatementService = s
+}
+
+// GetBankStatementService returns the current bank statement service (for testing).
+func GetBankStatementService() service.BankStatementService {
+	return bankStatementService
+}
+
+// SetDPRuleService sets the DP rule service (for testing).
+func SetDPRuleService(s service.DPRuleService) {
+	dpRuleService = s
+}
+
+// GetDPRuleService returns the current DP rule service (for testing).
+func GetDPRuleService() service.DPRuleService {
+	return dpRuleService
+}
+
+// SetPurchaseListService sets the purchase list service (for testing).
+func SetPurchaseListService(s service.PurchaseListService) {
+	purchaseListService = s
+}
+
+// GetPurchaseListService returns the current purchase list service (for testing).
+func GetPurchaseListService() service.PurchaseListService {
+	return purchaseListService
+}
+
+// SetReportService sets the report service (for testing).
+func SetReportService(s service.ReportService) {
+	reportService = s
+}
+
+// GetReportService returns the current report service (for testing).
+func GetReportService() service.ReportService {
+	return reportService
+}
+
+// SetShareLinkService sets the share link service (for testing).
+func SetShareLinkService(s service.ShareLinkService) {
+	shareLinkService = s
+}
+
+// GetShareLinkService returns the current share link service (for testing).
+func GetShareLinkService() service.ShareLinkService {
+	return shareLinkService
+}
+
+// SetImportService sets the import service (for testing).
+func SetImportService(s service.ImportService) {
+	importService = s
+}
+
+// GetImportService returns the current import service (for testing).
+func GetImportService() service.ImportService {
+	return importService
+}
+
+// SetArchiveService sets the archive service (for testing).
+func SetArchiveService(s service.ArchiveService) {
+	archiveService = s
+}
+
+// GetArchiveService returns the current archive service (for testing).
+func GetArchiveService() service.ArchiveService {
+	return archiveService
+}
+
+// SetProductCategoryService sets the product category service (for testing).
+func SetProductCategoryService(s service.ProductCategoryService) {
+	productCategoryService = s
+}
+
+// GetProductCategoryService returns the current product category service (for testing).
+func GetProductCategoryService() service.ProductCategoryService {
+	return productCategoryService
+}
+
 func WriteJson(w http.ResponseWriter, status int, body interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
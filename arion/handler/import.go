@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/i18n"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+// importMaxFileSize is the largest spreadsheet an import accepts.
+const importMaxFileSize = 5 << 20
+
+// ImportCustomersHandler godoc
+//
+//	@Summary		Import customers
+//	@Description	Upload a CSV or XLSX file (max 5MB, 5000 rows) of customers. The first non-empty row is the header.
+//	@Description	Columns are found by header name (name/nama, phone/no hp, address/alamat) unless mapping says otherwise, e.g. {"phone":"Nomor WA"}.
+//	@Description	Customers are upserted by normalized phone. With dry_run=true nothing is written and the result shows what would be created and updated.
+//	@Description	Rows that fail validation are listed in errors with their row number and an error code; when there are any, nothing is written.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			customer
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			file	formData	file	true	"CSV or XLSX file (max 5MB)"
+//	@Param			format	formData	string	false	"File format (csv, xlsx); taken from the file name when empty"
+//	@Param			mapping	formData	string	false	"JSON object mapping a field (name, phone, address) to a column header"
+//	@Param			dry_run	formData	bool	false	"Validate only, write nothing"
+//	@Success		200		{object}	response.ImportResultData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (missing, unreadable or oversized file, invalid mapping or missing column)"
+//	@Failure		409		{object}	ErrorApiResponse	"Phone already taken by another customer"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/customers/import [post]
+func ImportCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	input, file, err := parseImportRequest(r)
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+	defer file.Close()
+
+	res, err := importService.ImportCustomers(ctx, input)
+	if err != nil {
+		writeImportError(w, r, err, "import_customers")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, translateImportErrors(r, res))
+}
+
+// ImportProductsHandler godoc
+//
+//	@Summary		Import products
+//	@Description	Upload a CSV or XLSX file (max 5MB, 5000 rows) of products. The first non-empty row is the header.
+//	@Description	Columns are found by header name (name/nama produk, price/harga, original_price/harga modal, description/deskripsi) unless mapping says otherwise, e.g. {"price":"Harga Jual"}.
+//	@Description	Products are upserted by name. With dry_run=true nothing is written and the result shows what would be created and updated.
+//	@Description	Rows that fail validation are listed in errors with their row number and an error code; when there are any, nothing is written.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			file	formData	file	true	"CSV or XLSX file (max 5MB)"
+//	@Param			format	formData	string	false	"File format (csv, xlsx); taken from the file name when empty"
+//	@Param			mapping	formData	string	false	"JSON object mapping a field (name, price, original_price, description) to a column header"
+//	@Param			dry_run	formData	bool	false	"Validate only, write nothing"
+//	@Success		200		{object}	response.ImportResultData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (missing, unreadable or oversized file, invalid mapping or missing column)"
+//	@Failure		409		{object}	ErrorApiResponse	"Product name already taken"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/import [post]
+func ImportProductsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	input, file, err := parseImportRequest(r)
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+	defer file.Close()
+
+	res, err := importService.ImportProducts(ctx, input)
+	if err != nil {
+		writeImportError(w, r, err, "import_products")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, translateImportErrors(r, res))
+}
+
+// parseImportRequest reads the multipart form of an import request. The
+// caller closes the returned file.
+func parseImportRequest(r *http.Request) (service.ImportInput, multipart.File, error) {
+	shopID := r.Context().Value(common.ShopIDKey).(int)
+
+	if err := r.ParseMultipartForm(importMaxFileSize); err != nil {
+		return service.ImportInput{}, nil, errors.New(apierr.ErrImportFileTooLarge)
+	}
+
+	input := service.ImportInput{
+		ShopID: shopID,
+		Format: strings.ToLower(strings.TrimSpace(r.FormValue("format"))),
+	}
+
+	if raw := strings.TrimSpace(r.FormValue("mapping")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &input.Mapping); err != nil {
+			return service.ImportInput{}, nil, errors.New(apierr.ErrImportMappingInvalid)
+		}
+	}
+
+	if raw := strings.TrimSpace(r.FormValue("dry_run")); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			return service.ImportInput{}, nil, errors.New(apierr.ErrImportDryRunInvalid)
+		}
+		input.DryRun = dryRun
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return service.ImportInput{}, nil, errors.New(apierr.ErrImportFileRequired)
+	}
+	if header.Size > importMaxFileSize {
+		file.Close()
+		return service.ImportInput{}, nil, errors.New(apierr.ErrImportFileTooLarge)
+	}
+	if input.Format == "" {
+		input.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+	input.File = file
+
+	return input, file, nil
+}
+
+func writeImportError(w http.ResponseWriter, r *http.Request, err error, code string) {
+	switch err.Error() {
+	case apierr.ErrImportFormatInvalid, apierr.ErrImportFileInvalid, apierr.ErrImportFileEmpty,
+		apierr.ErrImportTooManyRows, apierr.ErrImportMappingInvalid, apierr.ErrImportColumnMissing:
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	case apierr.ErrCustomerPhoneExists, apierr.ErrProductNameExists:
+		WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+		return
+	}
+	logger.WithError(err).Error(code + "_error")
+	WriteErrorJson(w, r, http.StatusInternalServerError, err, code)
+}
+
+// translateImportErrors fills in the message of every row error in the
+// language of the request.
+func translateImportErrors(r *http.Request, res response.ImportResultData) response.ImportResultData {
+	for i, e := range res.Errors {
+		res.Errors[i].Message = i18n.Message(r, e.Code, e.Code)
+	}
+	return res
+}
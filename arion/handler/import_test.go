@@ -0,0 +1,283 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+// buildImportRequest builds a multipart import request. An empty fileName
+// leaves the file out.
+func buildImportRequest(path, fileName string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for k, v := range fields {
+		writer.WriteField(k, v)
+	}
+	if fileName != "" {
+		part, _ := writer.CreateFormFile("file", fileName)
+		part.Write([]byte("name,phone\nBudi,08123456789\n"))
+	}
+	writer.Close()
+
+	req := newRequestWithShopID("POST", path, body.Bytes(), 1)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// importInputMatcher matches an ImportInput of shop 1 by everything but the
+// uploaded file, which only has to be present.
+type importInputMatcher struct {
+	format  string
+	dryRun  bool
+	mapping map[string]string
+}
+
+func (m importInputMatcher) Matches(x interface{}) bool {
+	input, ok := x.(service.ImportInput)
+	if !ok || input.ShopID != 1 || input.Format != m.format || input.DryRun != m.dryRun || input.File == nil {
+		return false
+	}
+	if len(input.Mapping) != len(m.mapping) {
+		return false
+	}
+	for k, v := range m.mapping {
+		if input.Mapping[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (m importInputMatcher) String() string {
+	return fmt.Sprintf("import input format=%s dry_run=%v mapping=%v", m.format, m.dryRun, m.mapping)
+}
+
+func TestImportCustomersHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetImportService()
+	defer handler.SetImportService(oldService)
+
+	mockService := mock_service.NewMockImportService(ctrl)
+	handler.SetImportService(mockService)
+
+	tests := []struct {
+		name         string
+		fileName     string
+		fields       map[string]string
+		mockSetup    func()
+		wantStatus   int
+		wantSuccess  bool
+		wantMessages []string
+	}{
+		{
+			name:     "successfully imports with format from the file name",
+			fileName: "pelanggan.CSV",
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportCustomers(gomock.Any(), importInputMatcher{format: "csv"}).
+					Return(response.ImportResultData{TotalRows: 1, Created: 1, Errors: []response.ImportRowErrorData{}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:     "dry run with mapping returns translated row errors",
+			fileName: "pelanggan.xlsx",
+			fields:   map[string]string{"dry_run": "true", "mapping": `{"phone":"Nomor WA"}`},
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportCustomers(gomock.Any(), importInputMatcher{format: "xlsx", dryRun: true, mapping: map[string]string{"phone": "Nomor WA"}}).
+					Return(response.ImportResultData{DryRun: true, TotalRows: 1, Errors: []response.ImportRowErrorData{
+						{Row: 2, Field: "phone", Code: apierr.ErrPhoneRequired},
+					}}, nil)
+			},
+			wantStatus:   http.StatusOK,
+			wantSuccess:  true,
+			wantMessages: []string{"Phone is required"},
+		},
+		{
+			name:        "returns 400 when file is missing",
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 for invalid mapping",
+			fileName:    "pelanggan.csv",
+			fields:      map[string]string{"mapping": `["phone"]`},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 for invalid dry_run",
+			fileName:    "pelanggan.csv",
+			fields:      map[string]string{"dry_run": "maybe"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 400 when a column is missing",
+			fileName: "pelanggan.csv",
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportCustomers(gomock.Any(), gomock.Any()).
+					Return(response.ImportResultData{}, errors.New(apierr.ErrImportColumnMissing))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 409 when a phone is taken",
+			fileName: "pelanggan.csv",
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportCustomers(gomock.Any(), gomock.Any()).
+					Return(response.ImportResultData{}, errors.New(apierr.ErrCustomerPhoneExists))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service error",
+			fileName: "pelanggan.csv",
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportCustomers(gomock.Any(), gomock.Any()).
+					Return(response.ImportResultData{}, errors.New("db error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			req := buildImportRequest("/customers/import", tt.fileName, tt.fields)
+			rec := httptest.NewRecorder()
+
+			handler.ImportCustomersHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ImportCustomersHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp struct {
+				Success bool                      `json:"success"`
+				Data    response.ImportResultData `json:"data"`
+			}
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ImportCustomersHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+			for i, want := range tt.wantMessages {
+				if i >= len(resp.Data.Errors) || resp.Data.Errors[i].Message != want {
+					t.Errorf("ImportCustomersHandler() errors = %+v, want message %q", resp.Data.Errors, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportProductsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetImportService()
+	defer handler.SetImportService(oldService)
+
+	mockService := mock_service.NewMockImportService(ctrl)
+	handler.SetImportService(mockService)
+
+	tests := []struct {
+		name        string
+		fileName    string
+		fields      map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully imports with explicit format",
+			fileName: "produk",
+			fields:   map[string]string{"format": "CSV"},
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportProducts(gomock.Any(), importInputMatcher{format: "csv"}).
+					Return(response.ImportResultData{TotalRows: 1, Updated: 1, Errors: []response.ImportRowErrorData{}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:     "returns 400 for unsupported format",
+			fileName: "produk.ods",
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportProducts(gomock.Any(), gomock.Any()).
+					Return(response.ImportResultData{}, errors.New(apierr.ErrImportFormatInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 409 when a name is taken",
+			fileName: "produk.csv",
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportProducts(gomock.Any(), gomock.Any()).
+					Return(response.ImportResultData{}, errors.New(apierr.ErrProductNameExists))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service error",
+			fileName: "produk.csv",
+			mockSetup: func() {
+				mockService.EXPECT().
+					ImportProducts(gomock.Any(), gomock.Any()).
+					Return(response.ImportResultData{}, errors.New("db error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			req := buildImportRequest("/products/import", tt.fileName, tt.fields)
+			rec := httptest.NewRecorder()
+
+			handler.ImportProductsHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ImportProductsHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ImportProductsHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -108,7 +108,7 @@ func AcceptInviteHandler(w http.ResponseWriter, r *http.Request) {
 		switch err.Error() {
 		case apierr.ErrInvitationNotFound:
 			WriteErrorJson(w, r, http.StatusBadRequest, err, err.Error())
-		case apierr.ErrMaxUsersReached:
+		case apierr.ErrMaxUsersReached, apierr.ErrShopClosed:
 			WriteErrorJson(w, r, http.StatusForbidden, err, err.Error())
 		case apierr.ErrInvitationAlreadyAccepted:
 			WriteErrorJson(w, r, http.StatusConflict, err, err.Error())
@@ -12,7 +12,9 @@ import (
 	"github.com/zeirash/recapo/arion/common"
 	"github.com/zeirash/recapo/arion/common/apierr"
 	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/i18n"
 	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/response"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/service"
 )
@@ -21,6 +23,7 @@ type (
 	CreateOrderRequest struct {
 		CustomerID int     `json:"customer_id"`
 		Notes      *string `json:"notes"`
+		AddressID  *int    `json:"address_id"` // defaults to the customer's default address
 	}
 
 	UpdateOrderRequest struct {
@@ -29,6 +32,7 @@ type (
 		Status        *string `json:"status"`
 		PaymentStatus *string `json:"payment_status"`
 		Notes         *string `json:"notes"`
+		AddressID     *int    `json:"address_id"`
 	}
 
 	CreateOrderItemRequest struct {
@@ -50,12 +54,31 @@ type (
 	OrderPaymentRequest struct {
 		Amount int `json:"amount"`
 	}
+
+	ApplyDPRuleRequest struct {
+		DPRuleID *int `json:"dp_rule_id"`
+	}
+
+	CancelOrderRequest struct {
+		Reason string              `json:"reason"`
+		Notes  string              `json:"notes"`
+		Refund *RefundOrderRequest `json:"refund"` // reason is taken from the cancellation
+	}
+
+	RefundOrderRequest struct {
+		Amount   int    `json:"amount"`
+		Method   string `json:"method"`
+		Reason   string `json:"reason"`
+		ProofURL string `json:"proof_url"`
+		Notes    string `json:"notes"`
+	}
 )
 
 // GetOrderStatsHandler godoc
 //
 //	@Summary		Get order stats
-//	@Description	Get aggregated order stats for the shop. Returns total revenue (sum of all order payments).
+//	@Description	Get aggregated order stats for the shop. Returns total revenue (sum of all order payments), which is net of refunds since each refund is booked as a negative payment, and total refunds on their own.
+//	@Description	Net sales leaves out cancelled orders.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			order
 //	@Accept			json
@@ -92,10 +115,78 @@ func GetOrderStatsHandler(w http.ResponseWriter, r *http.Request) {
 	WriteJson(w, http.StatusOK, res)
 }
 
+// GetOrderTimeseriesHandler godoc
+//
+//	@Summary		Get order stats timeseries
+//	@Description	Get order count, revenue, net sales, new customers and average order value per day, week or month, plus the top products and customers of the range.
+//	@Description	Dates are read in the shop's timezone and every bucket of the range has a point. Weeks start on Monday. Without dates the range is the last 30 days.
+//	@Description	Revenue sums payments (net of refunds) by payment date; the other numbers leave out cancelled orders. A new customer is one whose first order falls in the bucket.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			date_from	query		string	false	"From date (YYYY-MM-DD)"
+//	@Param			date_to		query		string	false	"To date (YYYY-MM-DD)"
+//	@Param			bucket		query		string	false	"day (default), week or month"
+//	@Param			top			query		int		false	"Number of top products and customers (default 5, max 50)"
+//	@Success		200			{object}	response.OrderTimeseriesData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid date, bucket, range or top)"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/stats/timeseries [get]
+func GetOrderTimeseriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	query := r.URL.Query()
+
+	input := service.GetOrdersTimeseriesInput{
+		ShopID: shopID,
+		Bucket: query.Get("bucket"),
+	}
+	if df := query.Get("date_from"); df != "" {
+		t, err := parseDate(df)
+		if err != nil {
+			WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrDateInvalid), "validation")
+			return
+		}
+		input.DateFrom = &t
+	}
+	if dt := query.Get("date_to"); dt != "" {
+		t, err := parseDate(dt)
+		if err != nil {
+			WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrDateInvalid), "validation")
+			return
+		}
+		input.DateTo = &t
+	}
+	if tp := query.Get("top"); tp != "" {
+		top, err := strconv.Atoi(tp)
+		if err != nil || top < 1 {
+			WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrStatsTopInvalid), "validation")
+			return
+		}
+		input.Top = top
+	}
+
+	res, err := orderService.GetOrdersTimeseries(ctx, input)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrStatsBucketInvalid, apierr.ErrStatsRangeInvalid, apierr.ErrStatsRangeTooLong, apierr.ErrStatsTopInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		default:
+			logger.WithError(err).Error("get_order_timeseries_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_order_timeseries")
+		}
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
 // CreateOrderHandler godoc
 //
 //	@Summary		Create order
-//	@Description	Create a new order for the shop.
+//	@Description	Create a new order for the shop. The order ships to address_id from the customer's address book, or to the customer's default address when it is omitted; the address is copied onto the order.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			order
 //	@Accept			json
@@ -104,6 +195,7 @@ func GetOrderStatsHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			body	body		CreateOrderRequest	true	"Order data"
 //	@Success		200		{object}	response.OrderData
 //	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or validation)"
+//	@Failure		404		{object}	ErrorApiResponse	"Address not found"
 //	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/order [post]
 func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
@@ -121,11 +213,15 @@ func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := orderService.CreateOrder(ctx, inp.CustomerID, shopID, inp.Notes)
+	res, err := orderService.CreateOrder(ctx, inp.CustomerID, shopID, inp.Notes, inp.AddressID)
 	if err != nil {
-		if err.Error() == apierr.ErrActiveOrderExists {
+		switch err.Error() {
+		case apierr.ErrActiveOrderExists:
 			WriteErrorJson(w, r, http.StatusConflict, err, "duplicate_customer_order")
 			return
+		case apierr.ErrCustomerAddressNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
 		}
 		logger.WithError(err).Error("create_order_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_order")
@@ -184,7 +280,8 @@ type ExportOrderRequest struct {
 // ExportOrderHandler godoc
 //
 //	@Summary		Export order as PDF invoice
-//	@Description	Generate and download a PDF invoice for a given order. Optional message in body appended as footer; falls back to order notes.
+//	@Description	Generate and download a PDF invoice for a given order. Optional message in body appended as footer; falls back to order notes, then to the shop's invoice_message.
+//	@Description	Labels follow the shop's locale, or the request language (Accept-Language) for a shop without one. The shop's next invoice number is assigned on first export and reused afterwards.
 //	@Tags			order
 //	@Accept			json
 //	@Produce		application/pdf
@@ -216,7 +313,7 @@ func ExportOrderHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	pdfBytes, err := orderService.GenerateOrderInvoice(ctx, orderID, shopID, inp.Message)
+	pdfBytes, err := orderService.GenerateOrderInvoice(ctx, orderID, shopID, inp.Message, i18n.GetLangFromRequest(r))
 	if err != nil {
 		if err.Error() == apierr.ErrOrderNotFound {
 			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
@@ -235,6 +332,106 @@ func ExportOrderHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(pdfBytes)
 }
 
+// OrderDocumentsFilter selects orders the same way the GET /orders query does.
+type OrderDocumentsFilter struct {
+	Search        string   `json:"search"`
+	Status        []string `json:"status"`
+	PaymentStatus string   `json:"payment_status"`
+	DateFrom      string   `json:"date_from"` // YYYY-MM-DD
+	DateTo        string   `json:"date_to"`   // YYYY-MM-DD
+}
+
+// GenerateOrderDocumentsRequest is the request body for GenerateOrderDocumentsHandler.
+// OrderIDs takes precedence over Filter.
+type GenerateOrderDocumentsRequest struct {
+	OrderIDs []int                 `json:"order_ids"`
+	Filter   *OrderDocumentsFilter `json:"filter"`
+	Type     string                `json:"type"`   // invoice, packing_slip or label
+	Format   string                `json:"format"` // pdf (default) or zip
+	Message  string                `json:"message"`
+}
+
+// GenerateOrderDocumentsHandler godoc
+//
+//	@Summary		Print documents for several orders
+//	@Description	Generate invoices, packing slips or A6 shipping labels for the given order IDs, or for the orders matching filter (max 200).
+//	@Description	format pdf returns one multi-page PDF; zip returns one PDF per order. Invoices get their invoice numbers assigned as in the single export.
+//	@Tags			order
+//	@Accept			json
+//	@Produce		application/pdf,application/zip
+//	@Security		BearerAuth
+//	@Param			body	body		GenerateOrderDocumentsRequest	true	"Orders and document type"
+//	@Success		200		{file}		binary
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid type, format or date, no orders, too many orders)"
+//	@Failure		404		{object}	ErrorApiResponse	"Order not found"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/documents [post]
+func GenerateOrderDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := GenerateOrderDocumentsRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	input := service.GenerateOrderDocumentsInput{
+		ShopID:   shopID,
+		OrderIDs: inp.OrderIDs,
+		Type:     inp.Type,
+		Format:   inp.Format,
+		Message:  inp.Message,
+		Lang:     i18n.GetLangFromRequest(r),
+	}
+	if inp.Filter != nil {
+		opts := model.OrderFilterOptions{Status: inp.Filter.Status}
+		if inp.Filter.Search != "" {
+			opts.SearchQuery = &inp.Filter.Search
+		}
+		if inp.Filter.PaymentStatus != "" {
+			opts.PaymentStatus = &inp.Filter.PaymentStatus
+		}
+		if inp.Filter.DateFrom != "" {
+			t, err := parseDate(inp.Filter.DateFrom)
+			if err != nil {
+				WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrDateInvalid), "validation")
+				return
+			}
+			opts.DateFrom = &t
+		}
+		if inp.Filter.DateTo != "" {
+			t, err := parseDate(inp.Filter.DateTo)
+			if err != nil {
+				WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrDateInvalid), "validation")
+				return
+			}
+			opts.DateTo = &t
+		}
+		input.Filter = &opts
+	}
+
+	res, err := orderService.GenerateOrderDocuments(ctx, input)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrDocumentTypeInvalid, apierr.ErrDocumentFormatInvalid, apierr.ErrNoOrdersSelected, apierr.ErrTooManyOrders:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		case apierr.ErrOrderNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		default:
+			logger.WithError(err).Error("generate_order_documents_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "generate_order_documents")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", res.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+res.Filename)
+	w.Header().Set("Content-Length", strconv.Itoa(len(res.Content)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(res.Content)
+}
+
 // GetOrdersHandler godoc
 //
 //	@Summary		List orders
@@ -244,11 +441,13 @@ func ExportOrderHandler(w http.ResponseWriter, r *http.Request) {
 //	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			search		query		string	false	"Search query"
+//	@Param			search		query		string	false	"Search by customer name, phone or invoice number"
 //	@Param			date_from	query		string	false	"Filter orders from date (YYYY-MM-DD)"
 //	@Param			date_to		query		string	false	"Filter orders to date (YYYY-MM-DD)"
 //	@Param			status		query		string	false	"Filter by status (e.g. created,in_progress,in_delivery,done,cancelled)"
-//	@Param			payment_status	query		string	false	"Filter by payment status (e.g. outstanding,paid)"
+//	@Param			payment_status	query		string	false	"Filter by payment status (e.g. outstanding,awaiting_dp,dp_paid,paid)"
+//	@Param			dp_overdue	query		bool	false	"Only orders whose DP deadline passed unpaid (true) or not (false)"
+//	@Param			shortage_notify	query		bool	false	"Only orders whose customer still has to be told about a purchase list shortage (true) or not (false)"
 //	@Param			sort		  query		string	false	"Sort by column and order (e.g. created_at,desc)"
 //	@Success		200		{array}		response.OrderData
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
@@ -265,6 +464,16 @@ func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	if ps := r.URL.Query().Get("payment_status"); ps != "" {
 		opts.PaymentStatus = &ps
 	}
+	if do := r.URL.Query().Get("dp_overdue"); do != "" {
+		if b, err := strconv.ParseBool(do); err == nil {
+			opts.DPOverdue = &b
+		}
+	}
+	if sn := r.URL.Query().Get("shortage_notify"); sn != "" {
+		if b, err := strconv.ParseBool(sn); err == nil {
+			opts.ShortageNotify = &b
+		}
+	}
 	if q := r.URL.Query().Get("search"); q != "" {
 		opts.SearchQuery = &q
 	}
@@ -295,7 +504,7 @@ func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 // UpdateOrderHandler godoc
 //
 //	@Summary		Update order
-//	@Description	Update an existing order. Only provided fields are updated.
+//	@Description	Update an existing order. Only provided fields are updated. address_id copies that entry of the customer's address book onto the order.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			order
 //	@Accept			json
@@ -305,6 +514,7 @@ func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			body		body		UpdateOrderRequest	true	"Fields to update"
 //	@Success		200			{object}	response.OrderData
 //	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid JSON or order_id)"
+//	@Failure		404	{object}	ErrorApiResponse	"Address not found"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/orders/{order_id} [patch]
 func UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
@@ -329,8 +539,13 @@ func UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		Status:        inp.Status,
 		PaymentStatus: inp.PaymentStatus,
 		Notes:         inp.Notes,
+		AddressID:     inp.AddressID,
 	})
 	if err != nil {
+		if err.Error() == apierr.ErrCustomerAddressNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
 		logger.WithError(err).Error("update_order_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_order")
 		return
@@ -351,6 +566,7 @@ func UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			order_id	path	int	true	"Order ID"
 //	@Success		200		{string}	string	"Success. data contains \"OK\""
 //	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid order_id)"
+//	@Failure		409	{object}	ErrorApiResponse	"A payment is tied to a customer credit entry"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/orders/{order_id} [delete]
 func DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
@@ -365,6 +581,10 @@ func DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 
 	err := orderService.DeleteOrderByID(ctx, orderIDInt)
 	if err != nil {
+		if err.Error() == apierr.ErrPaymentHasCredit {
+			WriteErrorJson(w, r, http.StatusConflict, err, "payment_has_credit")
+			return
+		}
 		logger.WithError(err).Error("delete_order_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "delete_order")
 		return
@@ -863,6 +1083,7 @@ func UpdateOrderPaymentAmountHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			order_id	path	int	true	"Order ID"
 //	@Success		200		{string}	string	"Success. data contains \"OK\""
 //	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid order_id)"
+//	@Failure		409	{object}	ErrorApiResponse	"A payment is tied to a customer credit entry"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/orders/{order_id}/payments [delete]
 func DeleteOrderPaymentsHandler(w http.ResponseWriter, r *http.Request) {
@@ -877,6 +1098,10 @@ func DeleteOrderPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 
 	err := orderService.DeleteOrderPaymentsByOrderID(ctx, orderIDInt)
 	if err != nil {
+		if err.Error() == apierr.ErrPaymentHasCredit {
+			WriteErrorJson(w, r, http.StatusConflict, err, "payment_has_credit")
+			return
+		}
 		logger.WithError(err).Error("delete_order_payments_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "delete_order_payments")
 		return
@@ -932,6 +1157,7 @@ func GetOrderPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			payment_id	path		int	true	"Payment ID"
 //	@Success		200		{string}	string	"Success. data contains \"OK\""
 //	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid JSON or order_id)"
+//	@Failure		409	{object}	ErrorApiResponse	"A payment is tied to a customer credit entry"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/orders/{order_id}/payments/{payment_id} [delete]
 func DeleteOrderPaymentHandler(w http.ResponseWriter, r *http.Request) {
@@ -952,6 +1178,10 @@ func DeleteOrderPaymentHandler(w http.ResponseWriter, r *http.Request) {
 
 	err := orderService.DeleteOrderPaymentByID(ctx, paymentIDInt, orderIDInt)
 	if err != nil {
+		if err.Error() == apierr.ErrPaymentHasCredit {
+			WriteErrorJson(w, r, http.StatusConflict, err, "payment_has_credit")
+			return
+		}
 		logger.WithError(err).Error("delete_order_payment_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "delete_order_payment")
 		return
@@ -960,6 +1190,421 @@ func DeleteOrderPaymentHandler(w http.ResponseWriter, r *http.Request) {
 	WriteJson(w, http.StatusOK, "OK")
 }
 
+// ApplyCustomerCreditHandler godoc
+//
+//	@Summary		Apply customer credit to order
+//	@Description	Pay part of the order from the customer's credit balance. The amount is recorded as an order payment and debited from the credit ledger.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			order_id	path		int					true	"Order ID"
+//	@Param			body		body		OrderPaymentRequest	true	"Amount of credit to apply"
+//	@Success		200			{object}	response.OrderPaymentData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid amount, insufficient credit or amount above what is outstanding)"
+//	@Failure		404			{object}	ErrorApiResponse	"Order not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/{order_id}/apply_credit [post]
+func ApplyCustomerCreditHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateOrderID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := OrderPaymentRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if inp.Amount <= 0 {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrCreditAmountInvalid), "validation")
+		return
+	}
+
+	orderIDInt, _ := strconv.Atoi(params["order_id"])
+
+	res, err := orderService.ApplyCustomerCredit(ctx, orderIDInt, shopID, inp.Amount)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrOrderNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrInsufficientCredit, apierr.ErrCreditAmountInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("apply_customer_credit_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "apply_customer_credit")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// CarryOverOverpaymentHandler godoc
+//
+//	@Summary		Carry over order overpayment
+//	@Description	Move the amount paid beyond the order's amount due into the customer's credit balance. A negative payment of the same amount is recorded on the order.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			order_id	path		int	true	"Order ID"
+//	@Success		200			{object}	response.CustomerCreditData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid order_id or no overpayment)"
+//	@Failure		404			{object}	ErrorApiResponse	"Order not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/{order_id}/carry_over [post]
+func CarryOverOverpaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateOrderID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	orderIDInt, _ := strconv.Atoi(params["order_id"])
+
+	res, err := orderService.CarryOverOverpayment(ctx, orderIDInt, shopID)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrOrderNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrNoOverpayment:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("carry_over_overpayment_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "carry_over_overpayment")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// ApplyDPRuleHandler godoc
+//
+//	@Summary		Apply DP rule to order
+//	@Description	Set the order's down-payment requirement from a DP rule, e.g. the rule of the trip the order belongs to. The DP amount and deadline are computed from the rule; dp_rule_id 0 removes the requirement.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			order_id	path		int					true	"Order ID"
+//	@Param			body		body		ApplyDPRuleRequest	true	"DP rule to apply"
+//	@Success		200			{object}	response.OrderData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON or missing dp_rule_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Order or DP rule not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/{order_id}/dp_rule [post]
+func ApplyDPRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateOrderID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := ApplyDPRuleRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if inp.DPRuleID == nil || *inp.DPRuleID < 0 {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrDPRuleIDRequired), "validation")
+		return
+	}
+
+	orderIDInt, _ := strconv.Atoi(params["order_id"])
+
+	res, err := orderService.ApplyDPRule(ctx, orderIDInt, shopID, *inp.DPRuleID)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrOrderNotFound, apierr.ErrDPRuleNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("apply_dp_rule_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "apply_dp_rule")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// CancelOrderHandler godoc
+//
+//	@Summary		Cancel order
+//	@Description	Cancel an order with a reason code: out_of_stock, customer_cancelled, price_changed, duplicate, damaged or other. The order's items no longer count in the purchase list.
+//	@Description	Pass refund to pay back part or all of what the customer paid in the same step. Refund method is transfer, cash or credit; credit adds the amount to the customer's credit balance.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			order_id	path		int					true	"Order ID"
+//	@Param			body		body		CancelOrderRequest	true	"Cancellation reason and optional refund"
+//	@Success		200			{object}	response.OrderData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON, reason, method or amount)"
+//	@Failure		404			{object}	ErrorApiResponse	"Order not found"
+//	@Failure		409			{object}	ErrorApiResponse	"Order already cancelled"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/{order_id}/cancel [post]
+func CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateOrderID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := CancelOrderRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateCancelOrder(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	orderIDInt, _ := strconv.Atoi(params["order_id"])
+
+	input := service.CancelOrderInput{
+		OrderID: orderIDInt,
+		ShopID:  shopID,
+		Reason:  inp.Reason,
+		Notes:   strings.TrimSpace(inp.Notes),
+	}
+	if inp.Refund != nil {
+		input.Refund = &service.RefundOrderInput{
+			Amount:   inp.Refund.Amount,
+			Method:   inp.Refund.Method,
+			ProofURL: inp.Refund.ProofURL,
+			Notes:    strings.TrimSpace(inp.Refund.Notes),
+		}
+	}
+
+	res, err := orderService.CancelOrder(ctx, input)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrOrderNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrOrderAlreadyCancelled:
+			WriteErrorJson(w, r, http.StatusConflict, err, "already_cancelled")
+			return
+		case apierr.ErrRefundAmountInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("cancel_order_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "cancel_order")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// RefundOrderHandler godoc
+//
+//	@Summary		Refund order
+//	@Description	Pay money back to the customer for an order, cancelled or not (e.g. a damaged item). The refund is booked as a negative payment, so it lowers the order's paid amount and the shop's revenue.
+//	@Description	The amount may not exceed what is paid on the order. Once everything is refunded the order's payment status becomes refunded.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			order_id	path		int					true	"Order ID"
+//	@Param			body		body		RefundOrderRequest	true	"Refund data"
+//	@Success		200			{object}	response.OrderRefundData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON, reason, method or amount)"
+//	@Failure		404			{object}	ErrorApiResponse	"Order not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/{order_id}/refund [post]
+func RefundOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateOrderID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := RefundOrderRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateRefundOrder(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	orderIDInt, _ := strconv.Atoi(params["order_id"])
+
+	res, err := orderService.RefundOrder(ctx, service.RefundOrderInput{
+		OrderID:  orderIDInt,
+		ShopID:   shopID,
+		Amount:   inp.Amount,
+		Method:   inp.Method,
+		Reason:   inp.Reason,
+		ProofURL: inp.ProofURL,
+		Notes:    strings.TrimSpace(inp.Notes),
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrOrderNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrRefundAmountInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("refund_order_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "refund_order")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// GetOrderRefundsHandler godoc
+//
+//	@Summary		List order refunds
+//	@Description	Get the refunds of an order, oldest first.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			order_id	path		int	true	"Order ID"
+//	@Success		200			{array}		response.OrderRefundData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid order_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Order not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/{order_id}/refunds [get]
+func GetOrderRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateOrderID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	orderIDInt, _ := strconv.Atoi(params["order_id"])
+
+	res, err := orderService.GetOrderRefundsByOrderID(ctx, orderIDInt, shopID)
+	if err != nil {
+		if err.Error() == apierr.ErrOrderNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("get_order_refunds_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_order_refunds")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// ClearShortageNotifyHandler godoc
+//
+//	@Summary		Mark shortage notified
+//	@Description	Clear the order's shortage_notify flag once the customer has been told that part of the order could not be bought.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			order_id	path		int		true	"Order ID"
+//	@Success		200			{string}	string	"Success. data contains \"OK\""
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid order_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Order not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/{order_id}/shortage_notified [post]
+func ClearShortageNotifyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateOrderID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	orderIDInt, _ := strconv.Atoi(params["order_id"])
+
+	if err := orderService.ClearShortageNotify(ctx, orderIDInt, shopID); err != nil {
+		if err.Error() == apierr.ErrOrderNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("clear_shortage_notify_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "clear_shortage_notify")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// UploadRefundProofHandler godoc
+//
+//	@Summary		Upload refund proof
+//	@Description	Upload a photo of the refund transfer receipt (jpeg, png, webp, max 5MB). Returns the image_url to send as proof_url when refunding.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			order
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			image	formData	file	true	"Image file (jpeg/png/webp, max 5MB)"
+//	@Success		200		{object}	response.UploadImageData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (missing file, invalid type)"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/orders/refund_proof [post]
+func UploadRefundProofHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrImageTooLarge), "validation")
+		return
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrImageFieldRequired), "validation")
+		return
+	}
+	defer file.Close()
+
+	imageURL, err := orderService.UploadRefundProof(ctx, file)
+	if err != nil {
+		if err.Error() == apierr.ErrUnsupportedImageType {
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("upload_refund_proof_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "upload_refund_proof")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, response.UploadImageData{ImageURL: imageURL})
+}
+
 func validateCreateOrderItem(inp CreateOrderItemRequest) (bool, error) {
 	if inp.ProductID <= 0 {
 		return false, errors.New(apierr.ErrProductIDRequired)
@@ -1024,6 +1669,47 @@ func validateMergeTempOrder(inp MergeOrderRequest) (bool, error) {
 	return true, nil
 }
 
+func validateCancelOrder(inp CancelOrderRequest) (bool, error) {
+	if !isOrderReason(inp.Reason) {
+		return false, errors.New(apierr.ErrCancelReasonInvalid)
+	}
+
+	if inp.Refund != nil {
+		refund := *inp.Refund
+		refund.Reason = inp.Reason
+		return validateRefundOrder(refund)
+	}
+
+	return true, nil
+}
+
+func validateRefundOrder(inp RefundOrderRequest) (bool, error) {
+	if !isOrderReason(inp.Reason) {
+		return false, errors.New(apierr.ErrCancelReasonInvalid)
+	}
+
+	if inp.Amount <= 0 {
+		return false, errors.New(apierr.ErrRefundAmountInvalid)
+	}
+
+	switch inp.Method {
+	case constant.RefundMethodTransfer, constant.RefundMethodCash, constant.RefundMethodCredit:
+	default:
+		return false, errors.New(apierr.ErrRefundMethodInvalid)
+	}
+
+	return true, nil
+}
+
+func isOrderReason(reason string) bool {
+	switch reason {
+	case constant.OrderReasonOutOfStock, constant.OrderReasonCustomerCancelled, constant.OrderReasonPriceChanged,
+		constant.OrderReasonDuplicate, constant.OrderReasonDamaged, constant.OrderReasonOther:
+		return true
+	}
+	return false
+}
+
 // parseDate parses a YYYY-MM-DD date string (UTC).
 func parseDate(s string) (time.Time, error) {
 	return time.ParseInLocation("2006-01-02", s, time.UTC)
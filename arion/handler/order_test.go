@@ -44,7 +44,7 @@ func TestCreateOrderHandler(t *testing.T) {
 			shopID: 1,
 			mockSetup: func() {
 				mockOrderService.EXPECT().
-					CreateOrder(gomock.Any(), 1, 1, nil).
+					CreateOrder(gomock.Any(), 1, 1, nil, nil).
 					Return(response.OrderData{
 						ID:           1,
 						CustomerName: "John Doe",
@@ -66,7 +66,7 @@ func TestCreateOrderHandler(t *testing.T) {
 			mockSetup: func() {
 				notes := "Rush delivery"
 				mockOrderService.EXPECT().
-					CreateOrder(gomock.Any(), 2, 1, &notes).
+					CreateOrder(gomock.Any(), 2, 1, &notes, nil).
 					Return(response.OrderData{
 						ID:           2,
 						CustomerName: "Jane Doe",
@@ -87,7 +87,7 @@ func TestCreateOrderHandler(t *testing.T) {
 			shopID: 1,
 			mockSetup: func() {
 				mockOrderService.EXPECT().
-					CreateOrder(gomock.Any(), 1, 1, nil).
+					CreateOrder(gomock.Any(), 1, 1, nil, nil).
 					Return(response.OrderData{}, errors.New("database error"))
 			},
 			wantStatus:     http.StatusInternalServerError,
@@ -120,13 +120,56 @@ func TestCreateOrderHandler(t *testing.T) {
 			shopID: 1,
 			mockSetup: func() {
 				mockOrderService.EXPECT().
-					CreateOrder(gomock.Any(), 1, 1, nil).
+					CreateOrder(gomock.Any(), 1, 1, nil, nil).
 					Return(response.OrderData{}, errors.New(apierr.ErrActiveOrderExists))
 			},
 			wantStatus:     http.StatusConflict,
 			wantSuccess:    false,
 			wantErrMessage: "Customer already has an active order",
 		},
+		{
+			name: "successfully create order shipping to an address",
+			body: map[string]interface{}{
+				"customer_id": 1,
+				"address_id":  7,
+			},
+			shopID: 1,
+			mockSetup: func() {
+				addressID := 7
+				mockOrderService.EXPECT().
+					CreateOrder(gomock.Any(), 1, 1, nil, &addressID).
+					Return(response.OrderData{
+						ID:           3,
+						CustomerName: "John Doe",
+						Status:       "created",
+						ShippingAddress: &response.ShippingAddressData{
+							AddressID: &addressID,
+							Recipient: "John Doe",
+							Address:   "Jl. Merdeka 1",
+						},
+						CreatedAt: time.Now(),
+					}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "create order returns 404 when address not found",
+			body: map[string]interface{}{
+				"customer_id": 1,
+				"address_id":  99,
+			},
+			shopID: 1,
+			mockSetup: func() {
+				addressID := 99
+				mockOrderService.EXPECT().
+					CreateOrder(gomock.Any(), 1, 1, nil, &addressID).
+					Return(response.OrderData{}, errors.New(apierr.ErrCustomerAddressNotFound))
+			},
+			wantStatus:     http.StatusNotFound,
+			wantSuccess:    false,
+			wantErrMessage: "Address not found",
+		},
 	}
 
 	for _, tt := range tests {
@@ -273,6 +316,7 @@ func TestGetOrdersHandler(t *testing.T) {
 		status        string
 		sort          string
 		paymentStatus string
+		dpOverdue     string
 	}
 
 	tests := []struct {
@@ -444,6 +488,19 @@ func TestGetOrdersHandler(t *testing.T) {
 			wantStatus:  http.StatusOK,
 			wantSuccess: true,
 		},
+		{
+			name:   "get orders with dp_overdue filter passes to service",
+			shopID: 1,
+			opts:   queryOpts{dpOverdue: "true"},
+			mockSetup: func() {
+				overdue := true
+				mockOrderService.EXPECT().
+					GetOrdersByShopID(gomock.Any(), 1, model.OrderFilterOptions{DPOverdue: &overdue}).
+					Return([]response.OrderData{}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -470,6 +527,9 @@ func TestGetOrdersHandler(t *testing.T) {
 			if tt.opts.paymentStatus != "" {
 				params = append(params, "payment_status="+tt.opts.paymentStatus)
 			}
+			if tt.opts.dpOverdue != "" {
+				params = append(params, "dp_overdue="+tt.opts.dpOverdue)
+			}
 			if len(params) > 0 {
 				path += "?" + strings.Join(params, "&")
 			}
@@ -1646,6 +1706,7 @@ func TestExportOrderHandler(t *testing.T) {
 		shopID          int
 		pathVars        map[string]string
 		body            interface{}
+		acceptLanguage  string
 		mockSetup       func()
 		wantStatus      int
 		wantContentType string
@@ -1658,7 +1719,7 @@ func TestExportOrderHandler(t *testing.T) {
 			body:     nil,
 			mockSetup: func() {
 				mockOrderService.EXPECT().
-					GenerateOrderInvoice(gomock.Any(), 1, 1, "").
+					GenerateOrderInvoice(gomock.Any(), 1, 1, "", "en").
 					Return(fakePDF, nil)
 			},
 			wantStatus:      http.StatusOK,
@@ -1671,7 +1732,21 @@ func TestExportOrderHandler(t *testing.T) {
 			body:     map[string]interface{}{"message": "Thank you!\nSee you again."},
 			mockSetup: func() {
 				mockOrderService.EXPECT().
-					GenerateOrderInvoice(gomock.Any(), 1, 1, "Thank you!\nSee you again.").
+					GenerateOrderInvoice(gomock.Any(), 1, 1, "Thank you!\nSee you again.", "en").
+					Return(fakePDF, nil)
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/pdf",
+		},
+		{
+			name:           "export order passes the request language",
+			shopID:         1,
+			pathVars:       map[string]string{"order_id": "1"},
+			body:           nil,
+			acceptLanguage: "id-ID,id;q=0.9",
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GenerateOrderInvoice(gomock.Any(), 1, 1, "", "id").
 					Return(fakePDF, nil)
 			},
 			wantStatus:      http.StatusOK,
@@ -1684,7 +1759,7 @@ func TestExportOrderHandler(t *testing.T) {
 			body:     nil,
 			mockSetup: func() {
 				mockOrderService.EXPECT().
-					GenerateOrderInvoice(gomock.Any(), 999, 1, "").
+					GenerateOrderInvoice(gomock.Any(), 999, 1, "", "en").
 					Return(nil, errors.New(apierr.ErrOrderNotFound))
 			},
 			wantStatus:     http.StatusNotFound,
@@ -1697,7 +1772,7 @@ func TestExportOrderHandler(t *testing.T) {
 			body:     nil,
 			mockSetup: func() {
 				mockOrderService.EXPECT().
-					GenerateOrderInvoice(gomock.Any(), 1, 1, "").
+					GenerateOrderInvoice(gomock.Any(), 1, 1, "", "en").
 					Return(nil, errors.New("pdf generation error"))
 			},
 			wantStatus:     http.StatusInternalServerError,
@@ -1738,6 +1813,9 @@ func TestExportOrderHandler(t *testing.T) {
 
 			req := newRequestWithShopID("POST", "/orders/1/export", bodyBytes, tt.shopID)
 			req = newRequestWithPathVars(req, tt.pathVars)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
 			rec := httptest.NewRecorder()
 
 			handler.ExportOrderHandler(rec, req)
@@ -1764,6 +1842,164 @@ func TestExportOrderHandler(t *testing.T) {
 	}
 }
 
+func TestGenerateOrderDocumentsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	search := "john"
+
+	tests := []struct {
+		name            string
+		body            interface{}
+		mockSetup       func()
+		wantStatus      int
+		wantContentType string
+		wantDisposition string
+		wantErrMessage  string
+	}{
+		{
+			name: "successfully print packing slips for order ids",
+			body: map[string]interface{}{"order_ids": []int{1, 2}, "type": "packing_slip"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GenerateOrderDocuments(gomock.Any(), service.GenerateOrderDocumentsInput{
+						ShopID:   1,
+						OrderIDs: []int{1, 2},
+						Type:     "packing_slip",
+						Lang:     "en",
+					}).
+					Return(service.OrderDocuments{Filename: "packing-slips.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4")}, nil)
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/pdf",
+			wantDisposition: "attachment; filename=packing-slips.pdf",
+		},
+		{
+			name: "successfully print labels for a filter as zip",
+			body: map[string]interface{}{
+				"filter": map[string]interface{}{"search": "john", "status": []string{"created"}, "date_from": "2024-01-01"},
+				"type":   "label",
+				"format": "zip",
+			},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GenerateOrderDocuments(gomock.Any(), service.GenerateOrderDocumentsInput{
+						ShopID: 1,
+						Filter: &model.OrderFilterOptions{SearchQuery: &search, Status: []string{"created"}, DateFrom: &dateFrom},
+						Type:   "label",
+						Format: "zip",
+						Lang:   "en",
+					}).
+					Return(service.OrderDocuments{Filename: "labels.zip", ContentType: "application/zip", Content: []byte("PK")}, nil)
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/zip",
+			wantDisposition: "attachment; filename=labels.zip",
+		},
+		{
+			name:           "returns 400 on invalid filter date",
+			body:           map[string]interface{}{"filter": map[string]interface{}{"date_to": "01/02/2024"}, "type": "label"},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Date must use the YYYY-MM-DD format",
+		},
+		{
+			name: "returns 400 on invalid document type",
+			body: map[string]interface{}{"order_ids": []int{1}, "type": "receipt"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GenerateOrderDocuments(gomock.Any(), gomock.Any()).
+					Return(service.OrderDocuments{}, errors.New(apierr.ErrDocumentTypeInvalid))
+			},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Document type must be invoice, packing_slip or label",
+		},
+		{
+			name: "returns 400 on too many orders",
+			body: map[string]interface{}{"filter": map[string]interface{}{}, "type": "label"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GenerateOrderDocuments(gomock.Any(), gomock.Any()).
+					Return(service.OrderDocuments{}, errors.New(apierr.ErrTooManyOrders))
+			},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Too many orders in one request (max 200)",
+		},
+		{
+			name: "returns 404 when an order is not found",
+			body: map[string]interface{}{"order_ids": []int{999}, "type": "label"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GenerateOrderDocuments(gomock.Any(), gomock.Any()).
+					Return(service.OrderDocuments{}, errors.New(apierr.ErrOrderNotFound))
+			},
+			wantStatus:     http.StatusNotFound,
+			wantErrMessage: "Order not found",
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{"order_ids": []int{1}, "type": "label"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GenerateOrderDocuments(gomock.Any(), gomock.Any()).
+					Return(service.OrderDocuments{}, errors.New("pdf generation error"))
+			},
+			wantStatus:     http.StatusInternalServerError,
+			wantErrMessage: "pdf generation error",
+		},
+		{
+			name:       "returns 400 on invalid json body",
+			body:       "invalid json",
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			var bodyBytes []byte
+			switch b := tt.body.(type) {
+			case string:
+				bodyBytes = []byte(b)
+			default:
+				bodyBytes, _ = json.Marshal(b)
+			}
+
+			req := newRequestWithShopID("POST", "/orders/documents", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.GenerateOrderDocumentsHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GenerateOrderDocumentsHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantContentType != "" {
+				if ct := rec.Header().Get("Content-Type"); ct != tt.wantContentType {
+					t.Errorf("GenerateOrderDocumentsHandler() Content-Type = %v, want %v", ct, tt.wantContentType)
+				}
+			}
+			if tt.wantDisposition != "" {
+				if cd := rec.Header().Get("Content-Disposition"); cd != tt.wantDisposition {
+					t.Errorf("GenerateOrderDocumentsHandler() Content-Disposition = %v, want %v", cd, tt.wantDisposition)
+				}
+			}
+			if tt.wantErrMessage != "" {
+				var resp handler.ApiResponse
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if resp.Message != tt.wantErrMessage {
+					t.Errorf("GenerateOrderDocumentsHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
+				}
+			}
+		})
+	}
+}
+
 func TestGetTempOrdersHandler(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -2331,6 +2567,17 @@ func TestDeleteOrderPaymentHandler(t *testing.T) {
 			wantStatus:  http.StatusBadRequest,
 			wantSuccess: false,
 		},
+		{
+			name:     "returns 409 when the payment is tied to a credit entry",
+			pathVars: map[string]string{"order_id": "10", "payment_id": "1"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					DeleteOrderPaymentByID(gomock.Any(), 1, 10).
+					Return(errors.New(apierr.ErrPaymentHasCredit))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
 		{
 			name:     "returns 500 on service failure",
 			pathVars: map[string]string{"order_id": "10", "payment_id": "1"},
@@ -2593,3 +2840,726 @@ func TestGetOrderStatsHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOrderTimeseriesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	df := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dt := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func()
+		wantStatus     int
+		wantPoints     int
+		wantErrMessage string
+	}{
+		{
+			name:  "passes dates, bucket and top to service",
+			query: "?date_from=2024-01-01&date_to=2024-01-31&bucket=week&top=10",
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GetOrdersTimeseries(gomock.Any(), service.GetOrdersTimeseriesInput{ShopID: 1, DateFrom: &df, DateTo: &dt, Bucket: "week", Top: 10}).
+					Return(response.OrderTimeseriesData{
+						Bucket: "week",
+						Points: []response.OrderTimeseriesPoint{{Date: "2024-01-01", Orders: 2}, {Date: "2024-01-08"}},
+					}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantPoints: 2,
+		},
+		{
+			name:  "uses service defaults without query",
+			query: "",
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GetOrdersTimeseries(gomock.Any(), service.GetOrdersTimeseriesInput{ShopID: 1}).
+					Return(response.OrderTimeseriesData{Bucket: "day", Points: []response.OrderTimeseriesPoint{}}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "returns 400 on invalid date",
+			query:          "?date_to=31-01-2024",
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Date must use the YYYY-MM-DD format",
+		},
+		{
+			name:           "returns 400 on non-numeric top",
+			query:          "?top=all",
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "top must be between 1 and 50",
+		},
+		{
+			name:  "returns 400 on validation error from service",
+			query: "?bucket=hour",
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GetOrdersTimeseries(gomock.Any(), gomock.Any()).
+					Return(response.OrderTimeseriesData{}, errors.New(apierr.ErrStatsBucketInvalid))
+			},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Bucket must be day, week or month",
+		},
+		{
+			name:  "returns 500 on service error",
+			query: "",
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GetOrdersTimeseries(gomock.Any(), gomock.Any()).
+					Return(response.OrderTimeseriesData{}, errors.New("database error"))
+			},
+			wantStatus:     http.StatusInternalServerError,
+			wantErrMessage: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("GET", "/orders/stats/timeseries"+tt.query, nil, 1)
+			rec := httptest.NewRecorder()
+
+			handler.GetOrderTimeseriesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetOrderTimeseriesHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if tt.wantErrMessage != "" {
+				if resp.Message != tt.wantErrMessage {
+					t.Errorf("GetOrderTimeseriesHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
+				}
+				return
+			}
+			data, ok := resp.Data.(map[string]interface{})
+			if !ok {
+				t.Fatalf("GetOrderTimeseriesHandler() data is not a map: %v", resp.Data)
+			}
+			points, _ := data["points"].([]interface{})
+			if len(points) != tt.wantPoints {
+				t.Errorf("GetOrderTimeseriesHandler() points = %d, want %d", len(points), tt.wantPoints)
+			}
+		})
+	}
+}
+
+func TestApplyCustomerCreditHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully apply credit",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"amount": 50000},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyCustomerCredit(gomock.Any(), 1, 1, 50000).
+					Return(response.OrderPaymentData{ID: 1, OrderID: 1, Amount: 50000}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when amount is not positive",
+			pathVars:    map[string]string{"order_id": "1"},
+			body:        map[string]interface{}{"amount": 0},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 400 on insufficient credit",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"amount": 50000},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyCustomerCredit(gomock.Any(), 1, 1, 50000).
+					Return(response.OrderPaymentData{}, errors.New(apierr.ErrInsufficientCredit))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when order not found",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"amount": 50000},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyCustomerCredit(gomock.Any(), 1, 1, 50000).
+					Return(response.OrderPaymentData{}, errors.New(apierr.ErrOrderNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service failure",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"amount": 50000},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyCustomerCredit(gomock.Any(), 1, 1, 50000).
+					Return(response.OrderPaymentData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/orders/1/apply_credit", bodyBytes, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.ApplyCustomerCreditHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ApplyCustomerCreditHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ApplyCustomerCreditHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestCarryOverOverpaymentHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully carry over overpayment",
+			pathVars: map[string]string{"order_id": "1"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CarryOverOverpayment(gomock.Any(), 1, 1).
+					Return(response.CustomerCreditData{ID: 4, Type: "overpayment", Amount: 50000}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on missing order_id",
+			pathVars:    map[string]string{},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 400 when nothing was overpaid",
+			pathVars: map[string]string{"order_id": "1"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CarryOverOverpayment(gomock.Any(), 1, 1).
+					Return(response.CustomerCreditData{}, errors.New(apierr.ErrNoOverpayment))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service failure",
+			pathVars: map[string]string{"order_id": "1"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CarryOverOverpayment(gomock.Any(), 1, 1).
+					Return(response.CustomerCreditData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/orders/1/carry_over", nil, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.CarryOverOverpaymentHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CarryOverOverpaymentHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CarryOverOverpaymentHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestApplyDPRuleHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully apply DP rule",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"dp_rule_id": 3},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyDPRule(gomock.Any(), 1, 1, 3).
+					Return(response.OrderData{ID: 1, PaymentStatus: "awaiting_dp", DPAmount: 150000}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:     "dp_rule_id 0 clears the requirement",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"dp_rule_id": 0},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyDPRule(gomock.Any(), 1, 1, 0).
+					Return(response.OrderData{ID: 1, PaymentStatus: "outstanding"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when dp_rule_id is missing",
+			pathVars:    map[string]string{"order_id": "1"},
+			body:        map[string]interface{}{},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when rule not found",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"dp_rule_id": 3},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyDPRule(gomock.Any(), 1, 1, 3).
+					Return(response.OrderData{}, errors.New(apierr.ErrDPRuleNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service failure",
+			pathVars: map[string]string{"order_id": "1"},
+			body:     map[string]interface{}{"dp_rule_id": 3},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					ApplyDPRule(gomock.Any(), 1, 1, 3).
+					Return(response.OrderData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/orders/1/dp_rule", bodyBytes, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.ApplyDPRuleHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ApplyDPRuleHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ApplyDPRuleHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestCancelOrderHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	tests := []struct {
+		name           string
+		body           interface{}
+		mockSetup      func()
+		wantStatus     int
+		wantSuccess    bool
+		wantErrMessage string
+	}{
+		{
+			name: "successfully cancel order",
+			body: map[string]interface{}{"reason": "out_of_stock", "notes": " supplier sold out "},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CancelOrder(gomock.Any(), service.CancelOrderInput{OrderID: 1, ShopID: 1, Reason: "out_of_stock", Notes: "supplier sold out"}).
+					Return(response.OrderData{ID: 1, Status: "cancelled", CancelReason: "out_of_stock"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "successfully cancel order with refund",
+			body: map[string]interface{}{"reason": "customer_cancelled", "refund": map[string]interface{}{"amount": 50000, "method": "transfer", "proof_url": "https://cdn.example.com/refunds/a.jpg"}},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CancelOrder(gomock.Any(), service.CancelOrderInput{OrderID: 1, ShopID: 1, Reason: "customer_cancelled",
+						Refund: &service.RefundOrderInput{Amount: 50000, Method: "transfer", ProofURL: "https://cdn.example.com/refunds/a.jpg"}}).
+					Return(response.OrderData{ID: 1, Status: "cancelled"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:           "returns 400 when reason is unknown",
+			body:           map[string]interface{}{"reason": "changed_mind"},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Reason must be out_of_stock, customer_cancelled, price_changed, duplicate, damaged or other",
+		},
+		{
+			name:           "returns 400 when refund method is invalid",
+			body:           map[string]interface{}{"reason": "duplicate", "refund": map[string]interface{}{"amount": 50000, "method": "cheque"}},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Refund method must be transfer, cash or credit",
+		},
+		{
+			name: "returns 409 when order is already cancelled",
+			body: map[string]interface{}{"reason": "other"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CancelOrder(gomock.Any(), gomock.Any()).
+					Return(response.OrderData{}, errors.New(apierr.ErrOrderAlreadyCancelled))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when order not found",
+			body: map[string]interface{}{"reason": "other"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CancelOrder(gomock.Any(), gomock.Any()).
+					Return(response.OrderData{}, errors.New(apierr.ErrOrderNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{"reason": "other"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					CancelOrder(gomock.Any(), gomock.Any()).
+					Return(response.OrderData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/orders/1/cancel", bodyBytes, 1),
+				map[string]string{"order_id": "1"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.CancelOrderHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CancelOrderHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CancelOrderHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+			if tt.wantErrMessage != "" && resp.Message != tt.wantErrMessage {
+				t.Errorf("CancelOrderHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
+			}
+		})
+	}
+}
+
+func TestRefundOrderHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully refund order",
+			body: map[string]interface{}{"amount": 50000, "method": "cash", "reason": "damaged", "notes": "cracked lid"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					RefundOrder(gomock.Any(), service.RefundOrderInput{OrderID: 1, ShopID: 1, Amount: 50000, Method: "cash", Reason: "damaged", Notes: "cracked lid"}).
+					Return(response.OrderRefundData{ID: 1, OrderID: 1, Amount: 50000}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when amount is not positive",
+			body:        map[string]interface{}{"amount": 0, "method": "cash", "reason": "damaged"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when reason is missing",
+			body:        map[string]interface{}{"amount": 50000, "method": "cash"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when amount exceeds what was paid",
+			body: map[string]interface{}{"amount": 500000, "method": "transfer", "reason": "price_changed"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					RefundOrder(gomock.Any(), gomock.Any()).
+					Return(response.OrderRefundData{}, errors.New(apierr.ErrRefundAmountInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when order not found",
+			body: map[string]interface{}{"amount": 50000, "method": "transfer", "reason": "other"},
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					RefundOrder(gomock.Any(), gomock.Any()).
+					Return(response.OrderRefundData{}, errors.New(apierr.ErrOrderNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/orders/1/refund", bodyBytes, 1),
+				map[string]string{"order_id": "1"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.RefundOrderHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RefundOrderHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("RefundOrderHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestGetOrderRefundsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully get refunds",
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GetOrderRefundsByOrderID(gomock.Any(), 1, 1).
+					Return([]response.OrderRefundData{{ID: 1, OrderID: 1, Amount: 50000}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 404 when order not found",
+			mockSetup: func() {
+				mockOrderService.EXPECT().
+					GetOrderRefundsByOrderID(gomock.Any(), 1, 1).
+					Return(nil, errors.New(apierr.ErrOrderNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("GET", "/orders/1/refunds", nil, 1),
+				map[string]string{"order_id": "1"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.GetOrderRefundsHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetOrderRefundsHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetOrderRefundsHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestClearShortageNotifyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrderService := mock_service.NewMockOrderService(ctrl)
+	handler.SetOrderService(mockOrderService)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully clear shortage flag",
+			mockSetup: func() {
+				mockOrderService.EXPECT().ClearShortageNotify(gomock.Any(), 1, 1).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 404 when order not found",
+			mockSetup: func() {
+				mockOrderService.EXPECT().ClearShortageNotify(gomock.Any(), 1, 1).Return(errors.New(apierr.ErrOrderNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			mockSetup: func() {
+				mockOrderService.EXPECT().ClearShortageNotify(gomock.Any(), 1, 1).Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/orders/1/shortage_notified", nil, 1),
+				map[string]string{"order_id": "1"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.ClearShortageNotifyHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ClearShortageNotifyHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ClearShortageNotifyHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/zeirash/recapo/arion/common"
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/logger"
 	"github.com/zeirash/recapo/arion/common/response"
 	"github.com/zeirash/recapo/arion/model"
@@ -25,17 +26,35 @@ type (
 	}
 
 	UpdateProductRequest struct {
-		Name          *string `json:"name"`
-		Price         *int    `json:"price"`
-		Description   *string `json:"description"`
-		OriginalPrice *int    `json:"original_price"`
-		ImageURL      *string `json:"image_url"`
-		IsActive      *bool   `json:"is_active"`
+		Name          *string   `json:"name"`
+		Price         *int      `json:"price"`
+		Description   *string   `json:"description"`
+		OriginalPrice *int      `json:"original_price"`
+		ImageURL      *string   `json:"image_url"`
+		IsActive      *bool     `json:"is_active"`
+		CategoryID    *int      `json:"category_id"`
+		Tags          *[]string `json:"tags"`
 	}
 
 	DeleteProductImageRequest struct {
 		ImageURL string `json:"image_url"`
 	}
+
+	UpdatePurchaseListItemRequest struct {
+		Status     string `json:"status"`
+		BoughtQty  int    `json:"bought_qty"`
+		ActualCost int    `json:"actual_cost"`
+		Notes      string `json:"notes"`
+	}
+
+	AllocateShortageRequest struct {
+		Policy  string `json:"policy"`
+		Preview bool   `json:"preview"`
+	}
+
+	ReorderProductsRequest struct {
+		ProductIDs []int `json:"product_ids"`
+	}
 )
 
 // CreateProductHandler godoc
@@ -129,21 +148,22 @@ func GetProductHandler(w http.ResponseWriter, r *http.Request) {
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			search	query	  	string	false	"Search query"
-//	@Param			sort  	query		  string	false	"Sort by column and order (e.g. name,desc)"
+//	@Param			sort  	query		  string	false	"Sort by column and order (e.g. name,desc). position lists products by category and their position in it"
 //	@Param			is_active	query		string	false	"Filter by active status (true/false)"
+//	@Param			category_id	query		int		false	"Products in the category or its subcategories"
+//	@Param			tags		query		string	false	"Products with any of the tags, comma separated (e.g. sale,new)"
 //	@Success		200		{array}		response.ProductData
+//	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid category_id)"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/products [get]
 func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	shopID := ctx.Value(common.ShopIDKey).(int)
 
-	filter := model.FilterOptions{}
-	if q := r.URL.Query().Get("search"); q != "" {
-		filter.SearchQuery = &q
-	}
-	if sort := r.URL.Query().Get("sort"); sort != "" {
-		filter.Sort = &sort
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
 	}
 	if isActive := r.URL.Query().Get("is_active"); isActive != "" {
 		v := isActive == "true"
@@ -164,6 +184,7 @@ func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
 //
 //	@Summary		Update product
 //	@Description	Update a product by ID. Only provided fields are updated (partial update).
+//	@Description	category_id moves the product to the end of that category, 0 removes it from its category; tags replaces the product's tags.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			product
 //	@Accept			json
@@ -172,11 +193,13 @@ func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			product_id	path		int						true	"Product ID"
 //	@Param			body		body		UpdateProductRequest	true	"Fields to update (name, description, price)"
 //	@Success		200			{object}	response.ProductData
-//	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid product_id or JSON)"
+//	@Failure		400	{object}	ErrorApiResponse	"Bad request (invalid product_id, JSON or tags)"
+//	@Failure		404	{object}	ErrorApiResponse	"Product category not found"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/products/{product_id} [patch]
 func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
 	params := mux.Vars(r)
 	if valid, err := validateProductID(params); !valid {
 		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
@@ -194,14 +217,25 @@ func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
 
 	res, err := productService.UpdateProduct(ctx, service.UpdateProductInput{
 		ID:            productID,
+		ShopID:        shopID,
 		Name:          inp.Name,
 		Description:   inp.Description,
 		Price:         inp.Price,
 		OriginalPrice: inp.OriginalPrice,
 		ImageURL:      inp.ImageURL,
 		IsActive:      inp.IsActive,
+		CategoryID:    inp.CategoryID,
+		Tags:          inp.Tags,
 	})
 	if err != nil {
+		switch err.Error() {
+		case apierr.ErrProductCategoryNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrProductTagInvalid, apierr.ErrTooManyProductTags:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
 		logger.WithError(err).Error("update_product_error")
 		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_product")
 		return
@@ -271,10 +305,194 @@ func PurchaseListProductHandler(w http.ResponseWriter, r *http.Request) {
 	WriteJson(w, http.StatusOK, res)
 }
 
+// UpdatePurchaseListItemHandler godoc
+//
+//	@Summary		Update purchase list item
+//	@Description	Tick a product off the purchase list: bought with the quantity actually bought and what it cost in total, unavailable, or back to pending.
+//	@Description	An unavailable product has its quantity and cost cleared. When less was bought than ordered, allocate the shortage to the orders with the shortage endpoint.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			product_id	path		int								true	"Product ID"
+//	@Param			body		body		UpdatePurchaseListItemRequest	true	"Shopping progress"
+//	@Success		200			{object}	response.PurchaseListItemData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON, status or quantity)"
+//	@Failure		404			{object}	ErrorApiResponse	"Product not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/purchase_list/{product_id} [patch]
+func UpdatePurchaseListItemHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateProductID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := UpdatePurchaseListItemRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if valid, err := validateUpdatePurchaseListItem(inp); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	productID, _ := strconv.Atoi(params["product_id"])
+
+	res, err := purchaseListService.UpdatePurchaseListItem(ctx, service.UpdatePurchaseListItemInput{
+		ShopID:     shopID,
+		ProductID:  productID,
+		Status:     inp.Status,
+		BoughtQty:  inp.BoughtQty,
+		ActualCost: inp.ActualCost,
+		Notes:      strings.TrimSpace(inp.Notes),
+	})
+	if err != nil {
+		if err.Error() == apierr.ErrProductNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("update_purchase_list_item_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_purchase_list_item")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// ResetPurchaseListHandler godoc
+//
+//	@Summary		Reset purchase list
+//	@Description	Clear the shopping progress of every product, e.g. before the next shopping trip. The products to buy still come from the open orders.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{string}	string	"Success. data contains \"OK\""
+//	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/purchase_list [delete]
+func ResetPurchaseListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	if err := purchaseListService.ResetPurchaseList(ctx, shopID); err != nil {
+		logger.WithError(err).Error("reset_purchase_list_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "reset_purchase_list")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// AllocateShortageHandler godoc
+//
+//	@Summary		Allocate purchase list shortage
+//	@Description	Spread what could not be bought of a product over the open orders holding it. first_come fills the oldest orders first; priority fills customers with the highest priority first; paid_first fills paid orders, then down-paid ones; pro_rata cuts every order by the same share.
+//	@Description	Cut units move from the order item's qty to its shortage_qty, allocated_qty records what the item kept, and the order total drops by the cut units' price. Anything already paid beyond the new total moves to the customer's credit.
+//	@Description	With preview set nothing is saved; the response shows what the allocation would do.
+//	@Description	Affected orders get shortage_notify set until the customer is told (POST /orders/{order_id}/shortage_notified).
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			product_id	path		int						true	"Product ID"
+//	@Param			body		body		AllocateShortageRequest	true	"Allocation policy (first_come, priority, paid_first or pro_rata)"
+//	@Success		200			{object}	response.ShortageAllocationData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid policy, product not ticked off, or no shortage)"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/purchase_list/{product_id}/shortage [post]
+func AllocateShortageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateProductID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := AllocateShortageRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	productID, _ := strconv.Atoi(params["product_id"])
+
+	res, err := purchaseListService.AllocateShortage(ctx, service.AllocateShortageInput{
+		ShopID:    shopID,
+		ProductID: productID,
+		Policy:    inp.Policy,
+		Preview:   inp.Preview,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrShortagePolicyInvalid, apierr.ErrPurchaseNotRecorded, apierr.ErrNoShortage:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("allocate_shortage_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "allocate_shortage")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// AllocatePurchaseListHandler godoc
+//
+//	@Summary		Allocate bought stock
+//	@Description	Run the allocation for every product ticked off the purchase list. Products bought in full are allocated as ordered; short products are spread over their orders as for POST /products/purchase_list/{product_id}/shortage.
+//	@Description	With preview set nothing is saved; the response shows what the allocation would do.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		AllocateShortageRequest	true	"Allocation policy (first_come, priority, paid_first or pro_rata)"
+//	@Success		200		{object}	response.StockAllocationData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid policy or nothing ticked off)"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/purchase_list/allocate [post]
+func AllocatePurchaseListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := AllocateShortageRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	res, err := purchaseListService.AllocatePurchaseList(ctx, service.AllocatePurchaseListInput{
+		ShopID:  shopID,
+		Policy:  inp.Policy,
+		Preview: inp.Preview,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrShortagePolicyInvalid, apierr.ErrPurchaseNotRecorded:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("allocate_purchase_list_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "allocate_purchase_list")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
 // UploadProductImageHandler godoc
 //
 //	@Summary		Upload product image
-//	@Description	Upload an image file (jpeg, png, webp, max 5MB). Returns the image_url to include in create/update product requests.
+//	@Description	Upload an image file (jpeg, png, webp, max 5MB). It is stored as WebP thumbnail, medium and full-size variants with its metadata removed. Returns the full-size image_url to include in create/update product requests, where it becomes the primary image with all its variants.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			product
 //	@Accept			multipart/form-data
@@ -301,7 +519,7 @@ func UploadProductImageHandler(w http.ResponseWriter, r *http.Request) {
 
 	imageURL, err := productService.UploadProductImage(ctx, file)
 	if err != nil {
-		if err.Error() == apierr.ErrUnsupportedImageType {
+		if err.Error() == apierr.ErrUnsupportedImageType || err.Error() == apierr.ErrImageTooLarge {
 			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
 			return
 		}
@@ -316,7 +534,7 @@ func UploadProductImageHandler(w http.ResponseWriter, r *http.Request) {
 // DeleteProductImageHandler godoc
 //
 //	@Summary		Delete product image
-//	@Description	Delete an uploaded product image by its URL. Call this if product creation fails after a successful image upload.
+//	@Description	Delete an uploaded product image, with all its variants, by its URL. Call this if product creation fails after a successful image upload.
 //	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
 //	@Tags			product
 //	@Accept			json
@@ -404,6 +622,72 @@ func DeactivateAllProductsHandler(w http.ResponseWriter, r *http.Request) {
 	WriteJson(w, http.StatusOK, "OK")
 }
 
+// ReorderProductsHandler godoc
+//
+//	@Summary		Reorder products
+//	@Description	Set the order of products within their category: each listed product gets its index in product_ids as its position. Send the products of one category in their new order.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		ReorderProductsRequest	true	"Product IDs in their new order"
+//	@Success		200		{string}	string	"Success. data contains \"OK\""
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or no product_ids)"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/reorder [post]
+func ReorderProductsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := ReorderProductsRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if len(inp.ProductIDs) == 0 {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrReorderIDsRequired), "validation")
+		return
+	}
+
+	if err := productService.ReorderProducts(ctx, shopID, inp.ProductIDs); err != nil {
+		logger.WithError(err).Error("reorder_products_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "reorder_products")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// parseProductFilter reads the product list filters shared by the shop's
+// product list and the public storefront from the query string.
+func parseProductFilter(r *http.Request) (model.FilterOptions, error) {
+	query := r.URL.Query()
+	filter := model.FilterOptions{}
+	if q := query.Get("search"); q != "" {
+		filter.SearchQuery = &q
+	}
+	if sort := query.Get("sort"); sort != "" {
+		filter.Sort = &sort
+	}
+	if categoryID := query.Get("category_id"); categoryID != "" {
+		id, err := strconv.Atoi(categoryID)
+		if err != nil || id <= 0 {
+			return model.FilterOptions{}, errors.New(apierr.ErrProductCategoryIDInvalid)
+		}
+		filter.CategoryID = &id
+	}
+	if tags := query.Get("tags"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	return filter, nil
+}
+
 func validateCreateProduct(inp CreateProductRequest) (bool, error) {
 	if inp.Name == "" {
 		return false, errors.New(apierr.ErrNameRequired)
@@ -416,6 +700,20 @@ func validateCreateProduct(inp CreateProductRequest) (bool, error) {
 	return true, nil
 }
 
+func validateUpdatePurchaseListItem(inp UpdatePurchaseListItemRequest) (bool, error) {
+	switch inp.Status {
+	case constant.PurchaseStatusPending, constant.PurchaseStatusBought, constant.PurchaseStatusUnavailable:
+	default:
+		return false, errors.New(apierr.ErrPurchaseStatusInvalid)
+	}
+
+	if inp.BoughtQty < 0 || inp.ActualCost < 0 {
+		return false, errors.New(apierr.ErrPurchaseQtyInvalid)
+	}
+
+	return true, nil
+}
+
 func validateProductID(params map[string]string) (bool, error) {
 	if params["product_id"] == "" {
 		return false, errors.New(apierr.ErrProductIDRequired)
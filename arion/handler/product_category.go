@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+type (
+	CreateProductCategoryRequest struct {
+		Name     string `json:"name"`
+		ParentID *int   `json:"parent_id"`
+	}
+
+	UpdateProductCategoryRequest struct {
+		Name     *string `json:"name"`
+		ParentID *int    `json:"parent_id"`
+	}
+
+	ReorderProductCategoriesRequest struct {
+		CategoryIDs []int `json:"category_ids"`
+	}
+)
+
+// CreateProductCategoryHandler godoc
+//
+//	@Summary		Create product category
+//	@Description	Create a product category, placed after its siblings. parent_id makes it a subcategory of a top-level category; categories nest one level deep.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product_category
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		CreateProductCategoryRequest	true	"Category data"
+//	@Success		200		{object}	response.ProductCategoryData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON, name or parent_id)"
+//	@Failure		409		{object}	ErrorApiResponse	"A sibling category has the same name"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/product_category [post]
+func CreateProductCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := CreateProductCategoryRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	name := strings.TrimSpace(inp.Name)
+	if valid, err := validateProductCategoryName(name); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	if inp.ParentID != nil && *inp.ParentID == 0 {
+		inp.ParentID = nil
+	}
+
+	res, err := productCategoryService.CreateProductCategory(ctx, service.CreateProductCategoryInput{
+		ShopID:   shopID,
+		ParentID: inp.ParentID,
+		Name:     name,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrProductCategoryParentInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		case apierr.ErrProductCategoryNameExists:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+			return
+		}
+		logger.WithError(err).Error("create_product_category_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_product_category")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// GetProductCategoriesHandler godoc
+//
+//	@Summary		List product categories
+//	@Description	Get the shop's product categories in display order: each top-level category followed by its subcategories.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product_category
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{array}		response.ProductCategoryData
+//	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/product_categories [get]
+func GetProductCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	res, err := productCategoryService.GetProductCategoriesByShopID(ctx, shopID)
+	if err != nil {
+		logger.WithError(err).Error("get_product_categories_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_product_categories")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// UpdateProductCategoryHandler godoc
+//
+//	@Summary		Update product category
+//	@Description	Rename a category or move it. parent_id moves it under a top-level category, 0 makes it top-level; a moved category goes after its new siblings.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product_category
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			category_id	path		int								true	"Category ID"
+//	@Param			body		body		UpdateProductCategoryRequest	true	"Fields to update"
+//	@Success		200			{object}	response.ProductCategoryData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid JSON, name or parent_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Product category not found"
+//	@Failure		409			{object}	ErrorApiResponse	"A sibling category has the same name"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/product_categories/{category_id} [patch]
+func UpdateProductCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateProductCategoryID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	categoryIDInt, _ := strconv.Atoi(params["category_id"])
+
+	inp := UpdateProductCategoryRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if inp.Name != nil {
+		name := strings.TrimSpace(*inp.Name)
+		if valid, err := validateProductCategoryName(name); !valid {
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		inp.Name = &name
+	}
+
+	res, err := productCategoryService.UpdateProductCategory(ctx, service.UpdateProductCategoryInput{
+		ID:       categoryIDInt,
+		ShopID:   shopID,
+		Name:     inp.Name,
+		ParentID: inp.ParentID,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrProductCategoryNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrProductCategoryParentInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		case apierr.ErrProductCategoryNameExists:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+			return
+		}
+		logger.WithError(err).Error("update_product_category_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_product_category")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// DeleteProductCategoryHandler godoc
+//
+//	@Summary		Delete product category
+//	@Description	Delete a category. Its subcategories become top-level and its products uncategorized; the products themselves are kept.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			product_category
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			category_id	path		int	true	"Category ID"
+//	@Success		200			{string}	string	"Success. data contains \"OK\""
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid category_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Product category not found"
+//	@Failure		409			{object}	ErrorApiResponse	"A subcategory has the same name as a top-level category"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/product_categories/{category_id} [delete]
+func DeleteProductCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateProductCategoryID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	categoryIDInt, _ := strconv.Atoi(params["category_id"])
+
+	if err := productCategoryService.DeleteProductCategoryByID(ctx, categoryIDInt, shopID); err != nil {
+		switch err.Error() {
+		case apierr.ErrProductCategoryNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrProductCategoryNameExists:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+			return
+		}
+		logger.WithError(err).Error("delete_product_category_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "delete_product_category")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// ReorderProductCategoriesHandler godoc
+//
+//	@Summary		Reorder product categories
+//	@Description	Set the order of categories among their siblings: each listed category gets its index in category_ids as its position. Send the top-level categories, or the subcategories of one category, in their new order.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			product_category
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		ReorderProductCategoriesRequest	true	"Category IDs in their new order"
+//	@Success		200		{string}	string	"Success. data contains \"OK\""
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or no category_ids)"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/product_categories/reorder [post]
+func ReorderProductCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := ReorderProductCategoriesRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if len(inp.CategoryIDs) == 0 {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrReorderIDsRequired), "validation")
+		return
+	}
+
+	if err := productCategoryService.ReorderProductCategories(ctx, shopID, inp.CategoryIDs); err != nil {
+		logger.WithError(err).Error("reorder_product_categories_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "reorder_product_categories")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+func validateProductCategoryID(params map[string]string) (bool, error) {
+	if params["category_id"] == "" {
+		return false, errors.New(apierr.ErrProductCategoryIDRequired)
+	}
+
+	return true, nil
+}
+
+func validateProductCategoryName(name string) (bool, error) {
+	if name == "" {
+		return false, errors.New(apierr.ErrProductCategoryNameRequired)
+	}
+
+	if utf8.RuneCountInString(name) > constant.ProductCategoryNameMaxLength {
+		return false, errors.New(apierr.ErrProductCategoryNameTooLong)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,454 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+func TestCreateProductCategoryHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProductCategoryService := mock_service.NewMockProductCategoryService(ctrl)
+	handler.SetProductCategoryService(mockProductCategoryService)
+
+	parentID := 2
+
+	tests := []struct {
+		name           string
+		body           interface{}
+		mockSetup      func()
+		wantStatus     int
+		wantSuccess    bool
+		wantErrMessage string
+	}{
+		{
+			name: "successfully create category",
+			body: map[string]interface{}{"name": " Snacks "},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					CreateProductCategory(gomock.Any(), service.CreateProductCategoryInput{ShopID: 1, Name: "Snacks"}).
+					Return(response.ProductCategoryData{ID: 1, Name: "Snacks"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "successfully create subcategory",
+			body: map[string]interface{}{"name": "Chips", "parent_id": 2},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					CreateProductCategory(gomock.Any(), service.CreateProductCategoryInput{ShopID: 1, ParentID: &parentID, Name: "Chips"}).
+					Return(response.ProductCategoryData{ID: 3, ParentID: &parentID, Name: "Chips"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "parent_id 0 creates a top-level category",
+			body: map[string]interface{}{"name": "Drinks", "parent_id": 0},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					CreateProductCategory(gomock.Any(), service.CreateProductCategoryInput{ShopID: 1, Name: "Drinks"}).
+					Return(response.ProductCategoryData{ID: 4, Name: "Drinks"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:           "returns 400 when name is missing",
+			body:           map[string]interface{}{"name": "  "},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Category name is required",
+		},
+		{
+			name:           "returns 400 when name is too long",
+			body:           map[string]interface{}{"name": strings.Repeat("a", 51)},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Category name must be at most 50 characters",
+		},
+		{
+			name: "returns 400 when parent is invalid",
+			body: map[string]interface{}{"name": "Chips", "parent_id": 3},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					CreateProductCategory(gomock.Any(), gomock.Any()).
+					Return(response.ProductCategoryData{}, errors.New(apierr.ErrProductCategoryParentInvalid))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "returns 409 when the name is taken",
+			body: map[string]interface{}{"name": "Snacks"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					CreateProductCategory(gomock.Any(), gomock.Any()).
+					Return(response.ProductCategoryData{}, errors.New(apierr.ErrProductCategoryNameExists))
+			},
+			wantStatus:     http.StatusConflict,
+			wantErrMessage: "A category with this name already exists here",
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{"name": "Snacks"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					CreateProductCategory(gomock.Any(), gomock.Any()).
+					Return(response.ProductCategoryData{}, errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithShopID("POST", "/product_category", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.CreateProductCategoryHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CreateProductCategoryHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CreateProductCategoryHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+			if tt.wantErrMessage != "" && resp.Message != tt.wantErrMessage {
+				t.Errorf("CreateProductCategoryHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
+			}
+		})
+	}
+}
+
+func TestGetProductCategoriesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProductCategoryService := mock_service.NewMockProductCategoryService(ctrl)
+	handler.SetProductCategoryService(mockProductCategoryService)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully get categories",
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					GetProductCategoriesByShopID(gomock.Any(), 1).
+					Return([]response.ProductCategoryData{{ID: 1, Name: "Snacks"}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 500 on service failure",
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					GetProductCategoriesByShopID(gomock.Any(), 1).
+					Return(nil, errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("GET", "/product_categories", nil, 1)
+			rec := httptest.NewRecorder()
+
+			handler.GetProductCategoriesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetProductCategoriesHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetProductCategoriesHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestUpdateProductCategoryHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProductCategoryService := mock_service.NewMockProductCategoryService(ctrl)
+	handler.SetProductCategoryService(mockProductCategoryService)
+
+	name := "Sweets"
+	topLevel := 0
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully rename category",
+			pathVars: map[string]string{"category_id": "2"},
+			body:     map[string]interface{}{"name": " Sweets "},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					UpdateProductCategory(gomock.Any(), service.UpdateProductCategoryInput{ID: 2, ShopID: 1, Name: &name}).
+					Return(response.ProductCategoryData{ID: 2, Name: "Sweets"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:     "successfully move category to top level",
+			pathVars: map[string]string{"category_id": "2"},
+			body:     map[string]interface{}{"parent_id": 0},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					UpdateProductCategory(gomock.Any(), service.UpdateProductCategoryInput{ID: 2, ShopID: 1, ParentID: &topLevel}).
+					Return(response.ProductCategoryData{ID: 2, Name: "Sweets"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on missing category_id",
+			pathVars:    map[string]string{},
+			body:        map[string]interface{}{"name": "Sweets"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 on empty name",
+			pathVars:    map[string]string{"category_id": "2"},
+			body:        map[string]interface{}{"name": ""},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when category not found",
+			pathVars: map[string]string{"category_id": "2"},
+			body:     map[string]interface{}{"name": "Sweets"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					UpdateProductCategory(gomock.Any(), gomock.Any()).
+					Return(response.ProductCategoryData{}, errors.New(apierr.ErrProductCategoryNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 409 when the name is taken",
+			pathVars: map[string]string{"category_id": "2"},
+			body:     map[string]interface{}{"name": "Sweets"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().
+					UpdateProductCategory(gomock.Any(), gomock.Any()).
+					Return(response.ProductCategoryData{}, errors.New(apierr.ErrProductCategoryNameExists))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("PATCH", "/product_categories/2", bodyBytes, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.UpdateProductCategoryHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UpdateProductCategoryHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("UpdateProductCategoryHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestDeleteProductCategoryHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProductCategoryService := mock_service.NewMockProductCategoryService(ctrl)
+	handler.SetProductCategoryService(mockProductCategoryService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully delete category",
+			pathVars: map[string]string{"category_id": "2"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().DeleteProductCategoryByID(gomock.Any(), 2, 1).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on missing category_id",
+			pathVars:    map[string]string{},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when category not found",
+			pathVars: map[string]string{"category_id": "2"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().DeleteProductCategoryByID(gomock.Any(), 2, 1).Return(errors.New(apierr.ErrProductCategoryNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 409 when a subcategory name clashes",
+			pathVars: map[string]string{"category_id": "2"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().DeleteProductCategoryByID(gomock.Any(), 2, 1).Return(errors.New(apierr.ErrProductCategoryNameExists))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service failure",
+			pathVars: map[string]string{"category_id": "2"},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().DeleteProductCategoryByID(gomock.Any(), 2, 1).Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("DELETE", "/product_categories/2", nil, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.DeleteProductCategoryHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("DeleteProductCategoryHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("DeleteProductCategoryHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestReorderProductCategoriesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProductCategoryService := mock_service.NewMockProductCategoryService(ctrl)
+	handler.SetProductCategoryService(mockProductCategoryService)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully reorder categories",
+			body: map[string]interface{}{"category_ids": []int{2, 1}},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().ReorderProductCategories(gomock.Any(), 1, []int{2, 1}).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when category_ids is empty",
+			body:        map[string]interface{}{},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{"category_ids": []int{2, 1}},
+			mockSetup: func() {
+				mockProductCategoryService.EXPECT().ReorderProductCategories(gomock.Any(), 1, []int{2, 1}).Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithShopID("POST", "/product_categories/reorder", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.ReorderProductCategoriesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ReorderProductCategoriesHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ReorderProductCategoriesHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/logger"
+)
+
+type (
+	UpdateProductImageRequest struct {
+		IsPrimary *bool `json:"is_primary"`
+	}
+
+	ReorderProductImagesRequest struct {
+		ImageIDs []int `json:"image_ids"`
+	}
+)
+
+// AddProductImageHandler godoc
+//
+//	@Summary		Add product image
+//	@Description	Upload an image (jpeg, png, webp, max 5MB) to the product's images. It is stored as WebP thumbnail, medium and full-size variants with its metadata (EXIF, GPS) removed, and added after the other images. The product's first image is its primary image.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			product_id	path		int		true	"Product ID"
+//	@Param			image		formData	file	true	"Image file (jpeg/png/webp, max 5MB)"
+//	@Success		201			{object}	response.ProductImageData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (missing file, invalid type, too large, or too many images)"
+//	@Failure		404			{object}	ErrorApiResponse	"Product not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/{product_id}/images [post]
+func AddProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateProductID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+	productID, _ := strconv.Atoi(params["product_id"])
+
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrImageTooLarge), "validation")
+		return
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrImageFieldRequired), "validation")
+		return
+	}
+	defer file.Close()
+
+	res, err := productService.AddProductImage(ctx, productID, shopID, file)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrProductNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		case apierr.ErrUnsupportedImageType, apierr.ErrImageTooLarge, apierr.ErrTooManyProductImages:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("add_product_image_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "add_product_image")
+		return
+	}
+
+	WriteJson(w, http.StatusCreated, res)
+}
+
+// GetProductImagesHandler godoc
+//
+//	@Summary		List product images
+//	@Description	Get the product's images in display order.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			product_id	path		int	true	"Product ID"
+//	@Success		200			{array}		response.ProductImageData
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (missing product_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Product not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/{product_id}/images [get]
+func GetProductImagesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateProductID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+	productID, _ := strconv.Atoi(params["product_id"])
+
+	res, err := productService.GetProductImages(ctx, productID, shopID)
+	if err != nil {
+		if err.Error() == apierr.ErrProductNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("get_product_images_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_product_images")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// UpdateProductImageHandler godoc
+//
+//	@Summary		Set primary product image
+//	@Description	Make the image the product's primary image by sending is_primary true. Its full-size URL becomes the product's image_url.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			product_id	path		int							true	"Product ID"
+//	@Param			image_id	path		int							true	"Image ID"
+//	@Param			body		body		UpdateProductImageRequest	true	"Primary flag"
+//	@Success		200			{string}	string						"Success. data contains \"OK\""
+//	@Failure		400			{object}	ErrorApiResponse			"Bad request (invalid JSON, image_id or is_primary)"
+//	@Failure		404			{object}	ErrorApiResponse			"Product or image not found"
+//	@Failure		500			{object}	ErrorApiResponse			"Internal server error"
+//	@Router			/products/{product_id}/images/{image_id} [patch]
+func UpdateProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	productID, imageID, err := parseProductImageParams(params)
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	inp := UpdateProductImageRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if inp.IsPrimary == nil || !*inp.IsPrimary {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrProductImagePrimaryInvalid), "validation")
+		return
+	}
+
+	if err := productService.SetPrimaryProductImage(ctx, imageID, productID, shopID); err != nil {
+		switch err.Error() {
+		case apierr.ErrProductNotFound, apierr.ErrProductImageNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("update_product_image_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_product_image")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// ReorderProductImagesHandler godoc
+//
+//	@Summary		Reorder product images
+//	@Description	Set the order of the product's images: each listed image gets its index in image_ids as its position.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			product_id	path		int							true	"Product ID"
+//	@Param			body		body		ReorderProductImagesRequest	true	"Image IDs in their new order"
+//	@Success		200			{string}	string						"Success. data contains \"OK\""
+//	@Failure		400			{object}	ErrorApiResponse			"Bad request (invalid JSON or no image_ids)"
+//	@Failure		404			{object}	ErrorApiResponse			"Product not found"
+//	@Failure		500			{object}	ErrorApiResponse			"Internal server error"
+//	@Router			/products/{product_id}/images/reorder [post]
+func ReorderProductImagesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	if valid, err := validateProductID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+	productID, _ := strconv.Atoi(params["product_id"])
+
+	inp := ReorderProductImagesRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if len(inp.ImageIDs) == 0 {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrReorderIDsRequired), "validation")
+		return
+	}
+
+	if err := productService.ReorderProductImages(ctx, productID, shopID, inp.ImageIDs); err != nil {
+		if err.Error() == apierr.ErrProductNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("reorder_product_images_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "reorder_product_images")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// DeleteProductImageByIDHandler godoc
+//
+//	@Summary		Delete product image
+//	@Description	Remove an image from the product and delete its files. When it was the primary image, the next image in order becomes primary.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			product_id	path		int		true	"Product ID"
+//	@Param			image_id	path		int		true	"Image ID"
+//	@Success		200			{string}	string	"Success. data contains \"OK\""
+//	@Failure		400			{object}	ErrorApiResponse	"Bad request (invalid image_id)"
+//	@Failure		404			{object}	ErrorApiResponse	"Product or image not found"
+//	@Failure		500			{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/products/{product_id}/images/{image_id} [delete]
+func DeleteProductImageByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+
+	productID, imageID, err := parseProductImageParams(params)
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	if err := productService.DeleteProductImageByID(ctx, imageID, productID, shopID); err != nil {
+		switch err.Error() {
+		case apierr.ErrProductNotFound, apierr.ErrProductImageNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("delete_product_image_by_id_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "delete_product_image_by_id")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// parseProductImageParams reads the product and image IDs from the path.
+func parseProductImageParams(params map[string]string) (productID, imageID int, err error) {
+	if valid, err := validateProductID(params); !valid {
+		return 0, 0, err
+	}
+	productID, _ = strconv.Atoi(params["product_id"])
+
+	imageID, err = strconv.Atoi(params["image_id"])
+	if err != nil || imageID <= 0 {
+		return 0, 0, errors.New(apierr.ErrProductImageIDInvalid)
+	}
+
+	return productID, imageID, nil
+}
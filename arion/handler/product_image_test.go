@@ -0,0 +1,461 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+)
+
+func TestAddProductImageHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetProductService()
+	defer handler.SetProductService(oldService)
+
+	mockProductService := mock_service.NewMockProductService(ctrl)
+	handler.SetProductService(mockProductService)
+
+	buildMultipartRequest := func(fieldName string, vars map[string]string) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile(fieldName, "test.png")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write([]byte("\x89PNG\r\n\x1a\n"))
+		writer.Close()
+
+		req := httptest.NewRequest("POST", "/products/1/images", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req = req.WithContext(context.WithValue(req.Context(), common.ShopIDKey, 1))
+		return newRequestWithPathVars(req, vars)
+	}
+
+	tests := []struct {
+		name        string
+		buildReq    func() *http.Request
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully add image",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", map[string]string{"product_id": "1"})
+			},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					AddProductImage(gomock.Any(), 1, 1, gomock.Any()).
+					Return(response.ProductImageData{ID: 2, FullURL: "/uploads/products/abc_full.webp", IsPrimary: true}, nil)
+			},
+			wantStatus:  http.StatusCreated,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 400 on missing product_id",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", map[string]string{})
+			},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when image field is missing",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("other_field", map[string]string{"product_id": "1"})
+			},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when the product has too many images",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", map[string]string{"product_id": "1"})
+			},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					AddProductImage(gomock.Any(), 1, 1, gomock.Any()).
+					Return(response.ProductImageData{}, errors.New(apierr.ErrTooManyProductImages))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when product not found",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", map[string]string{"product_id": "1"})
+			},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					AddProductImage(gomock.Any(), 1, 1, gomock.Any()).
+					Return(response.ProductImageData{}, errors.New(apierr.ErrProductNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", map[string]string{"product_id": "1"})
+			},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					AddProductImage(gomock.Any(), 1, 1, gomock.Any()).
+					Return(response.ProductImageData{}, errors.New("storage error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			rec := httptest.NewRecorder()
+			handler.AddProductImageHandler(rec, tt.buildReq())
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("AddProductImageHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("AddProductImageHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestGetProductImagesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetProductService()
+	defer handler.SetProductService(oldService)
+
+	mockProductService := mock_service.NewMockProductService(ctrl)
+	handler.SetProductService(mockProductService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully get images",
+			pathVars: map[string]string{"product_id": "1"},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					GetProductImages(gomock.Any(), 1, 1).
+					Return([]response.ProductImageData{{ID: 2, IsPrimary: true}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on missing product_id",
+			pathVars:    map[string]string{},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when product not found",
+			pathVars: map[string]string{"product_id": "1"},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					GetProductImages(gomock.Any(), 1, 1).
+					Return(nil, errors.New(apierr.ErrProductNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(newRequestWithShopID("GET", "/products/1/images", nil, 1), tt.pathVars)
+			rec := httptest.NewRecorder()
+
+			handler.GetProductImagesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetProductImagesHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetProductImagesHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestUpdateProductImageHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetProductService()
+	defer handler.SetProductService(oldService)
+
+	mockProductService := mock_service.NewMockProductService(ctrl)
+	handler.SetProductService(mockProductService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully set primary image",
+			pathVars: map[string]string{"product_id": "1", "image_id": "3"},
+			body:     map[string]interface{}{"is_primary": true},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					SetPrimaryProductImage(gomock.Any(), 3, 1, 1).
+					Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on invalid image_id",
+			pathVars:    map[string]string{"product_id": "1", "image_id": "abc"},
+			body:        map[string]interface{}{"is_primary": true},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when is_primary is false",
+			pathVars:    map[string]string{"product_id": "1", "image_id": "3"},
+			body:        map[string]interface{}{"is_primary": false},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when is_primary is missing",
+			pathVars:    map[string]string{"product_id": "1", "image_id": "3"},
+			body:        map[string]interface{}{},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when image not found",
+			pathVars: map[string]string{"product_id": "1", "image_id": "3"},
+			body:     map[string]interface{}{"is_primary": true},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					SetPrimaryProductImage(gomock.Any(), 3, 1, 1).
+					Return(errors.New(apierr.ErrProductImageNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(newRequestWithShopID("PATCH", "/products/1/images/3", bodyBytes, 1), tt.pathVars)
+			rec := httptest.NewRecorder()
+
+			handler.UpdateProductImageHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UpdateProductImageHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("UpdateProductImageHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestReorderProductImagesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetProductService()
+	defer handler.SetProductService(oldService)
+
+	mockProductService := mock_service.NewMockProductService(ctrl)
+	handler.SetProductService(mockProductService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully reorder images",
+			pathVars: map[string]string{"product_id": "1"},
+			body:     map[string]interface{}{"image_ids": []int{3, 2}},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					ReorderProductImages(gomock.Any(), 1, 1, []int{3, 2}).
+					Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when image_ids is empty",
+			pathVars:    map[string]string{"product_id": "1"},
+			body:        map[string]interface{}{"image_ids": []int{}},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when product not found",
+			pathVars: map[string]string{"product_id": "1"},
+			body:     map[string]interface{}{"image_ids": []int{3, 2}},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					ReorderProductImages(gomock.Any(), 1, 1, []int{3, 2}).
+					Return(errors.New(apierr.ErrProductNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(newRequestWithShopID("POST", "/products/1/images/reorder", bodyBytes, 1), tt.pathVars)
+			rec := httptest.NewRecorder()
+
+			handler.ReorderProductImagesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ReorderProductImagesHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ReorderProductImagesHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestDeleteProductImageByIDHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetProductService()
+	defer handler.SetProductService(oldService)
+
+	mockProductService := mock_service.NewMockProductService(ctrl)
+	handler.SetProductService(mockProductService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully delete image",
+			pathVars: map[string]string{"product_id": "1", "image_id": "3"},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					DeleteProductImageByID(gomock.Any(), 3, 1, 1).
+					Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on missing image_id",
+			pathVars:    map[string]string{"product_id": "1"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 404 when image not found",
+			pathVars: map[string]string{"product_id": "1", "image_id": "3"},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					DeleteProductImageByID(gomock.Any(), 3, 1, 1).
+					Return(errors.New(apierr.ErrProductImageNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:     "returns 500 on service error",
+			pathVars: map[string]string{"product_id": "1", "image_id": "3"},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					DeleteProductImageByID(gomock.Any(), 3, 1, 1).
+					Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(newRequestWithShopID("DELETE", "/products/1/images/3", nil, 1), tt.pathVars)
+			rec := httptest.NewRecorder()
+
+			handler.DeleteProductImageByIDHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("DeleteProductImageByIDHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("DeleteProductImageByIDHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -333,6 +333,30 @@ func TestGetProductsHandler(t *testing.T) {
 			wantSuccess: true,
 			wantCount:   1,
 		},
+		{
+			name:   "successfully get products with category and tags",
+			url:    "/products?category_id=3&tags=sale,new",
+			shopID: 1,
+			mockSetup: func() {
+				categoryID := 3
+				mockProductService.EXPECT().
+					GetProductsByShopID(gomock.Any(), 1, model.FilterOptions{CategoryID: &categoryID, Tags: []string{"sale", "new"}}).
+					Return([]response.ProductData{
+						{ID: 1, Name: "Widget A", Price: 1000, CreatedAt: fixedTime},
+					}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+			wantCount:   1,
+		},
+		{
+			name:        "get products returns 400 on invalid category_id",
+			url:         "/products?category_id=0",
+			shopID:      1,
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
 		{
 			name:   "get products returns 500 on service error",
 			url:    "/products",
@@ -410,6 +434,7 @@ func TestUpdateProductHandler(t *testing.T) {
 				mockProductService.EXPECT().
 					UpdateProduct(gomock.Any(), service.UpdateProductInput{
 						ID:            1,
+						ShopID:        10,
 						Name:          &name,
 						Price:         &price,
 						OriginalPrice: &price,
@@ -425,6 +450,54 @@ func TestUpdateProductHandler(t *testing.T) {
 			wantStatus:  http.StatusOK,
 			wantSuccess: true,
 		},
+		{
+			name:      "successfully move product to a category and set tags",
+			productID: "1",
+			body: map[string]interface{}{
+				"category_id": 3,
+				"tags":        []string{"sale", "new"},
+			},
+			mockSetup: func() {
+				categoryID := 3
+				tags := []string{"sale", "new"}
+				mockProductService.EXPECT().
+					UpdateProduct(gomock.Any(), service.UpdateProductInput{
+						ID:         1,
+						ShopID:     10,
+						CategoryID: &categoryID,
+						Tags:       &tags,
+					}).
+					Return(response.ProductData{ID: 1, CategoryID: &categoryID, Tags: tags, CreatedAt: fixedTime}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:      "update product returns 404 when category not found",
+			productID: "1",
+			body:      map[string]interface{}{"category_id": 99},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					UpdateProduct(gomock.Any(), gomock.Any()).
+					Return(response.ProductData{}, errors.New(apierr.ErrProductCategoryNotFound))
+			},
+			wantStatus:     http.StatusNotFound,
+			wantSuccess:    false,
+			wantErrMessage: "Product category not found",
+		},
+		{
+			name:      "update product returns 400 on too many tags",
+			productID: "1",
+			body:      map[string]interface{}{"tags": []string{"a"}},
+			mockSetup: func() {
+				mockProductService.EXPECT().
+					UpdateProduct(gomock.Any(), gomock.Any()).
+					Return(response.ProductData{}, errors.New(apierr.ErrTooManyProductTags))
+			},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "A product can have at most 20 tags",
+		},
 		{
 			name:      "update product returns 400 when product_id missing",
 			productID: "",
@@ -463,7 +536,7 @@ func TestUpdateProductHandler(t *testing.T) {
 			tt.mockSetup()
 
 			bodyBytes, _ := json.Marshal(tt.body)
-			req := httptest.NewRequest("PATCH", "/products/"+tt.productID, bytes.NewReader(bodyBytes))
+			req := newRequestWithShopID("PATCH", "/products/"+tt.productID, bodyBytes, 10)
 			if tt.productID != "" {
 				req = newRequestWithPathVars(req, map[string]string{"product_id": tt.productID})
 			}
@@ -891,3 +964,388 @@ func TestDeleteProductImageHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdatePurchaseListItemHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPurchaseListService := mock_service.NewMockPurchaseListService(ctrl)
+	handler.SetPurchaseListService(mockPurchaseListService)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully mark product bought",
+			body: map[string]interface{}{"status": "bought", "bought_qty": 8, "actual_cost": 76000, "notes": " last 8 "},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					UpdatePurchaseListItem(gomock.Any(), service.UpdatePurchaseListItemInput{ShopID: 1, ProductID: 5, Status: "bought", BoughtQty: 8, ActualCost: 76000, Notes: "last 8"}).
+					Return(response.PurchaseListItemData{ProductID: 5, Status: "bought", BoughtQty: 8, ActualCost: 76000}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when status is unknown",
+			body:        map[string]interface{}{"status": "done"},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 when bought quantity is negative",
+			body:        map[string]interface{}{"status": "bought", "bought_qty": -1},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when product not found",
+			body: map[string]interface{}{"status": "unavailable"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					UpdatePurchaseListItem(gomock.Any(), gomock.Any()).
+					Return(response.PurchaseListItemData{}, errors.New(apierr.ErrProductNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("PATCH", "/products/purchase_list/5", bodyBytes, 1),
+				map[string]string{"product_id": "5"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.UpdatePurchaseListItemHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UpdatePurchaseListItemHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("UpdatePurchaseListItemHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestReorderProductsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetProductService()
+	defer handler.SetProductService(oldService)
+
+	mockProductService := mock_service.NewMockProductService(ctrl)
+	handler.SetProductService(mockProductService)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully reorder products",
+			body: map[string]interface{}{"product_ids": []int{3, 1, 2}},
+			mockSetup: func() {
+				mockProductService.EXPECT().ReorderProducts(gomock.Any(), 1, []int{3, 1, 2}).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when product_ids is empty",
+			body:        map[string]interface{}{"product_ids": []int{}},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:        "returns 400 on invalid json",
+			body:        "invalid json",
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			body: map[string]interface{}{"product_ids": []int{1}},
+			mockSetup: func() {
+				mockProductService.EXPECT().ReorderProducts(gomock.Any(), 1, []int{1}).Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithShopID("POST", "/products/reorder", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.ReorderProductsHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ReorderProductsHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ReorderProductsHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestResetPurchaseListHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPurchaseListService := mock_service.NewMockPurchaseListService(ctrl)
+	handler.SetPurchaseListService(mockPurchaseListService)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully reset purchase list",
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().ResetPurchaseList(gomock.Any(), 1).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 500 on service error",
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().ResetPurchaseList(gomock.Any(), 1).Return(errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("DELETE", "/products/purchase_list", nil, 1)
+			rec := httptest.NewRecorder()
+
+			handler.ResetPurchaseListHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ResetPurchaseListHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ResetPurchaseListHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestAllocateShortageHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPurchaseListService := mock_service.NewMockPurchaseListService(ctrl)
+	handler.SetPurchaseListService(mockPurchaseListService)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully allocate shortage",
+			body: map[string]interface{}{"policy": "pro_rata"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocateShortage(gomock.Any(), service.AllocateShortageInput{ShopID: 1, ProductID: 5, Policy: "pro_rata"}).
+					Return(response.ShortageAllocationData{ProductID: 5, Policy: "pro_rata", Ordered: 10, Available: 7, Shortage: 3}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "successfully preview shortage allocation",
+			body: map[string]interface{}{"policy": "priority", "preview": true},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocateShortage(gomock.Any(), service.AllocateShortageInput{ShopID: 1, ProductID: 5, Policy: "priority", Preview: true}).
+					Return(response.ShortageAllocationData{ProductID: 5, Policy: "priority", Preview: true, Ordered: 10, Available: 7, Shortage: 3}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 400 when policy is invalid",
+			body: map[string]interface{}{"policy": "random"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocateShortage(gomock.Any(), service.AllocateShortageInput{ShopID: 1, ProductID: 5, Policy: "random"}).
+					Return(response.ShortageAllocationData{}, errors.New(apierr.ErrShortagePolicyInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 when there is no shortage",
+			body: map[string]interface{}{"policy": "first_come"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocateShortage(gomock.Any(), service.AllocateShortageInput{ShopID: 1, ProductID: 5, Policy: "first_come"}).
+					Return(response.ShortageAllocationData{}, errors.New(apierr.ErrNoShortage))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			body: map[string]interface{}{"policy": "first_come"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocateShortage(gomock.Any(), service.AllocateShortageInput{ShopID: 1, ProductID: 5, Policy: "first_come"}).
+					Return(response.ShortageAllocationData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/products/purchase_list/5/shortage", bodyBytes, 1),
+				map[string]string{"product_id": "5"},
+			)
+			rec := httptest.NewRecorder()
+
+			handler.AllocateShortageHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("AllocateShortageHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("AllocateShortageHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestAllocatePurchaseListHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPurchaseListService := mock_service.NewMockPurchaseListService(ctrl)
+	handler.SetPurchaseListService(mockPurchaseListService)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully allocate purchase list",
+			body: map[string]interface{}{"policy": "paid_first"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocatePurchaseList(gomock.Any(), service.AllocatePurchaseListInput{ShopID: 1, Policy: "paid_first"}).
+					Return(response.StockAllocationData{Policy: "paid_first", Products: []response.ShortageAllocationData{{ProductID: 5}}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "successfully preview purchase list allocation",
+			body: map[string]interface{}{"policy": "first_come", "preview": true},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocatePurchaseList(gomock.Any(), service.AllocatePurchaseListInput{ShopID: 1, Policy: "first_come", Preview: true}).
+					Return(response.StockAllocationData{Policy: "first_come", Preview: true}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 400 when nothing was ticked off",
+			body: map[string]interface{}{"policy": "first_come"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocatePurchaseList(gomock.Any(), service.AllocatePurchaseListInput{ShopID: 1, Policy: "first_come"}).
+					Return(response.StockAllocationData{}, errors.New(apierr.ErrPurchaseNotRecorded))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			body: map[string]interface{}{"policy": "first_come"},
+			mockSetup: func() {
+				mockPurchaseListService.EXPECT().
+					AllocatePurchaseList(gomock.Any(), service.AllocatePurchaseListInput{ShopID: 1, Policy: "first_come"}).
+					Return(response.StockAllocationData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithShopID("POST", "/products/purchase_list/allocate", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.AllocatePurchaseListHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("AllocatePurchaseListHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("AllocatePurchaseListHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
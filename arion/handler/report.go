@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/i18n"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+// reportBufferSize is how much of a report is held back before the response
+// starts. Errors within it still get a JSON error response; small reports
+// never stream at all.
+const reportBufferSize = 64 * 1024
+
+var reportContentTypes = map[string]string{
+	constant.ReportFormatCSV:  "text/csv; charset=utf-8",
+	constant.ReportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// GetReportHandler godoc
+//
+//	@Summary		Download report
+//	@Description	Download a report as CSV or XLSX. report is one of sales_by_product, sales_by_customer, daily_revenue, receivables or payments.
+//	@Description	Dates filter on the order date, except for daily_revenue and payments, which filter on the payment date. Without a status filter, cancelled orders are left out of the sales and receivables reports.
+//	@Description	Column headers follow the request language (Accept-Language). Large reports are streamed.
+//	@Tags			report
+//	@Produce		text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Security		BearerAuth
+//	@Param			report			path		string	true	"Report name"
+//	@Param			format			query		string	false	"csv (default) or xlsx"
+//	@Param			date_from		query		string	false	"From date (YYYY-MM-DD)"
+//	@Param			date_to			query		string	false	"To date (YYYY-MM-DD)"
+//	@Param			status			query		string	false	"Filter by order status (e.g. created,in_progress,done)"
+//	@Param			payment_status	query		string	false	"Filter by payment status (e.g. outstanding,paid)"
+//	@Success		200				{file}		binary
+//	@Failure		400				{object}	ErrorApiResponse	"Bad request (unknown report or format, invalid date)"
+//	@Failure		500				{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/reports/{report} [get]
+func GetReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	report := mux.Vars(r)["report"]
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = constant.ReportFormatCSV
+	}
+
+	opts := model.OrderFilterOptions{}
+	filename := report
+	if df := query.Get("date_from"); df != "" {
+		t, err := parseDate(df)
+		if err != nil {
+			WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrDateInvalid), "validation")
+			return
+		}
+		opts.DateFrom = &t
+		filename += "_" + df
+	}
+	if dt := query.Get("date_to"); dt != "" {
+		t, err := parseDate(dt)
+		if err != nil {
+			WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrDateInvalid), "validation")
+			return
+		}
+		opts.DateTo = &t
+		filename += "_" + dt
+	}
+	if s := query.Get("status"); s != "" && s != constant.FilterStatusAll {
+		opts.Status = strings.Split(s, ",")
+	}
+	if ps := query.Get("payment_status"); ps != "" {
+		opts.PaymentStatus = &ps
+	}
+
+	dw := &downloadWriter{w: w, contentType: reportContentTypes[format], filename: filename + "." + format}
+	buf := bufio.NewWriterSize(dw, reportBufferSize)
+	err := reportService.ExportReport(ctx, service.ExportReportInput{
+		ShopID: shopID,
+		Report: report,
+		Format: format,
+		Filter: opts,
+		Lang:   i18n.GetLangFromRequest(r),
+	}, buf)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		if dw.started {
+			// part of the file is already on its way; the download is cut short
+			logger.WithError(err).WithField("report", report).Error("stream_report_error")
+			return
+		}
+		switch err.Error() {
+		case apierr.ErrReportInvalid, apierr.ErrReportFormatInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		default:
+			logger.WithError(err).Error("get_report_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_report")
+		}
+	}
+}
+
+// downloadWriter sends the file download headers along with the first bytes
+// written.
+type downloadWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	if !d.started {
+		d.started = true
+		d.w.Header().Set("Content-Type", d.contentType)
+		d.w.Header().Set("Content-Disposition", "attachment; filename="+d.filename)
+		d.w.WriteHeader(http.StatusOK)
+	}
+	return d.w.Write(p)
+}
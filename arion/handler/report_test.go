@@ -0,0 +1,165 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+func TestGetReportHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReportService := mock_service.NewMockReportService(ctrl)
+	handler.SetReportService(mockReportService)
+
+	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	paid := "paid"
+
+	// writeReport returns a mock ExportReport that writes body and then
+	// returns err.
+	writeReport := func(body string, err error) func(context.Context, service.ExportReportInput, io.Writer) error {
+		return func(_ context.Context, _ service.ExportReportInput, w io.Writer) error {
+			io.WriteString(w, body)
+			return err
+		}
+	}
+
+	tests := []struct {
+		name            string
+		report          string
+		query           string
+		mockSetup       func()
+		wantStatus      int
+		wantContentType string
+		wantDisposition string
+		wantBody        string
+		wantErrMessage  string
+	}{
+		{
+			name:   "downloads csv by default with filters",
+			report: "sales_by_product",
+			query:  "?date_from=2024-01-01&date_to=2024-01-31&status=done,in_delivery&payment_status=paid",
+			mockSetup: func() {
+				mockReportService.EXPECT().
+					ExportReport(gomock.Any(), service.ExportReportInput{
+						ShopID: 1,
+						Report: "sales_by_product",
+						Format: "csv",
+						Filter: model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo, Status: []string{"done", "in_delivery"}, PaymentStatus: &paid},
+						Lang:   "en",
+					}, gomock.Any()).
+					DoAndReturn(writeReport("Product ID,Product\n1,Product A\n", nil))
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantDisposition: "attachment; filename=sales_by_product_2024-01-01_2024-01-31.csv",
+			wantBody:        "Product ID,Product\n1,Product A\n",
+		},
+		{
+			name:   "downloads xlsx",
+			report: "receivables",
+			query:  "?format=xlsx&status=all",
+			mockSetup: func() {
+				mockReportService.EXPECT().
+					ExportReport(gomock.Any(), service.ExportReportInput{ShopID: 1, Report: "receivables", Format: "xlsx", Lang: "en"}, gomock.Any()).
+					DoAndReturn(writeReport("PK", nil))
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			wantDisposition: "attachment; filename=receivables.xlsx",
+			wantBody:        "PK",
+		},
+		{
+			name:           "returns 400 on invalid date",
+			report:         "payments",
+			query:          "?date_from=15-01-2024",
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Date must use the YYYY-MM-DD format",
+		},
+		{
+			name:   "returns 400 on unknown report",
+			report: "profit",
+			mockSetup: func() {
+				mockReportService.EXPECT().
+					ExportReport(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New(apierr.ErrReportInvalid))
+			},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Report must be sales_by_product, sales_by_customer, daily_revenue, receivables or payments",
+		},
+		{
+			name:   "returns 500 when the report fails before streaming",
+			report: "payments",
+			mockSetup: func() {
+				mockReportService.EXPECT().
+					ExportReport(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(writeReport("Payment ID\n", errors.New("database error")))
+			},
+			wantStatus:     http.StatusInternalServerError,
+			wantErrMessage: "database error",
+		},
+		{
+			name:   "cuts the download short when the report fails mid-stream",
+			report: "payments",
+			mockSetup: func() {
+				mockReportService.EXPECT().
+					ExportReport(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(writeReport(strings.Repeat("x", 100*1024), errors.New("database error")))
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("GET", "/reports/"+tt.report+tt.query, nil, 1)
+			req = newRequestWithPathVars(req, map[string]string{"report": tt.report})
+			rec := httptest.NewRecorder()
+
+			handler.GetReportHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetReportHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantContentType != "" {
+				if ct := rec.Header().Get("Content-Type"); ct != tt.wantContentType {
+					t.Errorf("GetReportHandler() Content-Type = %v, want %v", ct, tt.wantContentType)
+				}
+			}
+			if tt.wantDisposition != "" {
+				if cd := rec.Header().Get("Content-Disposition"); cd != tt.wantDisposition {
+					t.Errorf("GetReportHandler() Content-Disposition = %v, want %v", cd, tt.wantDisposition)
+				}
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("GetReportHandler() body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantErrMessage != "" {
+				var resp handler.ApiResponse
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if resp.Message != tt.wantErrMessage {
+					t.Errorf("GetReportHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/zeirash/recapo/arion/common"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+const maxShareLinkLabelLength = 100
+
+type CreateShareLinkRequest struct {
+	Label      string     `json:"label"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	ProductIDs []int      `json:"product_ids"`
+}
+
+// CreateShareLinkHandler godoc
+//
+//	@Summary		Create share link
+//	@Description	Create an extra storefront link, e.g. one per Instagram post or per trip. label is for the seller only.
+//	@Description	With expires_at the link stops working at that time. With product_ids the storefront only shows, and only takes orders for, those products; every ID must be one of the shop's products.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			share_link
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		CreateShareLinkRequest	true	"Share link data"
+//	@Success		200		{object}	response.ShareLinkData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON, validation or a product_id that is not the shop's)"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/share_link [post]
+func CreateShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := CreateShareLinkRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	inp.Label = strings.TrimSpace(inp.Label)
+	if valid, err := validateCreateShareLink(inp, time.Now()); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	res, err := shareLinkService.CreateShareLink(ctx, service.CreateShareLinkInput{
+		ShopID:     shopID,
+		Label:      inp.Label,
+		ExpiresAt:  inp.ExpiresAt,
+		ProductIDs: inp.ProductIDs,
+	})
+	if err != nil {
+		if err.Error() == apierr.ErrProductNotFound {
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+			return
+		}
+		logger.WithError(err).Error("create_share_link_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_share_link")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// GetShareLinksHandler godoc
+//
+//	@Summary		List share links
+//	@Description	Get all storefront links of the shop, newest first, including revoked and expired ones.
+//	@Description	temp_orders counts the temp orders placed through each link and accepted how many of them were accepted.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			share_link
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{array}		response.ShareLinkData
+//	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/share_links [get]
+func GetShareLinksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	res, err := shareLinkService.GetShareLinksByShopID(ctx, shopID)
+	if err != nil {
+		logger.WithError(err).Error("get_share_links_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_share_links")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// RevokeShareLinkHandler godoc
+//
+//	@Summary		Revoke share link
+//	@Description	Turn a storefront link off. Buyers opening it get 410 Gone. The link stays in the list so its temp orders keep their attribution.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			share_link
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			share_link_id	path		int	true	"Share link ID"
+//	@Success		200				{string}	string	"Success. data contains \"OK\""
+//	@Failure		400				{object}	ErrorApiResponse	"Bad request (invalid share_link_id)"
+//	@Failure		404				{object}	ErrorApiResponse	"Share link not found"
+//	@Failure		500				{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/share_links/{share_link_id} [delete]
+func RevokeShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateShareLinkID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	linkIDInt, _ := strconv.Atoi(params["share_link_id"])
+
+	if err := shareLinkService.RevokeShareLink(ctx, linkIDInt, shopID); err != nil {
+		if err.Error() == apierr.ErrShareLinkNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("revoke_share_link_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "revoke_share_link")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// RegenerateShareLinkHandler godoc
+//
+//	@Summary		Regenerate share link token
+//	@Description	Give a storefront link a new token, e.g. after the old URL leaked. The old URL stops working at once; a revoked link becomes active again. Label, expiry and products are kept.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			share_link
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			share_link_id	path		int	true	"Share link ID"
+//	@Success		200				{object}	response.ShareLinkData
+//	@Failure		400				{object}	ErrorApiResponse	"Bad request (invalid share_link_id)"
+//	@Failure		404				{object}	ErrorApiResponse	"Share link not found"
+//	@Failure		500				{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/share_links/{share_link_id}/regenerate [post]
+func RegenerateShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	params := mux.Vars(r)
+	if valid, err := validateShareLinkID(params); !valid {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	linkIDInt, _ := strconv.Atoi(params["share_link_id"])
+
+	res, err := shareLinkService.RegenerateShareLink(ctx, linkIDInt, shopID)
+	if err != nil {
+		if err.Error() == apierr.ErrShareLinkNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("regenerate_share_link_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "regenerate_share_link")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+func validateShareLinkID(params map[string]string) (bool, error) {
+	if params["share_link_id"] == "" {
+		return false, errors.New(apierr.ErrShareLinkIDRequired)
+	}
+
+	return true, nil
+}
+
+func validateCreateShareLink(inp CreateShareLinkRequest, now time.Time) (bool, error) {
+	if utf8.RuneCountInString(inp.Label) > maxShareLinkLabelLength {
+		return false, errors.New(apierr.ErrShareLinkLabelTooLong)
+	}
+
+	if inp.ExpiresAt != nil && !inp.ExpiresAt.After(now) {
+		return false, errors.New(apierr.ErrShareLinkExpiryInvalid)
+	}
+
+	for _, id := range inp.ProductIDs {
+		if id <= 0 {
+			return false, errors.New(apierr.ErrProductIDRequired)
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,296 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/handler"
+	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/service"
+)
+
+func TestCreateShareLinkHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShareLinkService := mock_service.NewMockShareLinkService(ctrl)
+	handler.SetShareLinkService(mockShareLinkService)
+
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name           string
+		body           interface{}
+		mockSetup      func()
+		wantStatus     int
+		wantSuccess    bool
+		wantErrMessage string
+	}{
+		{
+			name: "successfully create link",
+			body: map[string]interface{}{"label": " Trip Jepang ", "expires_at": expiresAt, "product_ids": []int{3, 4}},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().
+					CreateShareLink(gomock.Any(), service.CreateShareLinkInput{ShopID: 1, Label: "Trip Jepang", ExpiresAt: &expiresAt, ProductIDs: []int{3, 4}}).
+					Return(response.ShareLinkData{ID: 2, Token: "abc123", Label: "Trip Jepang", Active: true, ProductIDs: []int{3, 4}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:           "returns 400 when expiry is in the past",
+			body:           map[string]interface{}{"expires_at": "2024-01-01T00:00:00Z"},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Expiry must be in the future",
+		},
+		{
+			name:           "returns 400 when product_id is invalid",
+			body:           map[string]interface{}{"product_ids": []int{0}},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantErrMessage: "Product ID is required",
+		},
+		{
+			name: "returns 400 when a product is not the shop's",
+			body: map[string]interface{}{"product_ids": []int{99}},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().
+					CreateShareLink(gomock.Any(), gomock.Any()).
+					Return(response.ShareLinkData{}, errors.New(apierr.ErrProductNotFound))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "returns 500 on service failure",
+			body: map[string]interface{}{},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().
+					CreateShareLink(gomock.Any(), gomock.Any()).
+					Return(response.ShareLinkData{}, errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := newRequestWithShopID("POST", "/share_link", bodyBytes, 1)
+			rec := httptest.NewRecorder()
+
+			handler.CreateShareLinkHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CreateShareLinkHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CreateShareLinkHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+			if tt.wantErrMessage != "" && resp.Message != tt.wantErrMessage {
+				t.Errorf("CreateShareLinkHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
+			}
+		})
+	}
+}
+
+func TestGetShareLinksHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShareLinkService := mock_service.NewMockShareLinkService(ctrl)
+	handler.SetShareLinkService(mockShareLinkService)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully get links",
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().
+					GetShareLinksByShopID(gomock.Any(), 1).
+					Return([]response.ShareLinkData{{ID: 1, Token: "abc123", Active: true, ProductIDs: []int{}}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 500 on service failure",
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().
+					GetShareLinksByShopID(gomock.Any(), 1).
+					Return(nil, errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("GET", "/share_links", nil, 1)
+			rec := httptest.NewRecorder()
+
+			handler.GetShareLinksHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetShareLinksHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetShareLinksHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestRevokeShareLinkHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShareLinkService := mock_service.NewMockShareLinkService(ctrl)
+	handler.SetShareLinkService(mockShareLinkService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully revoke link",
+			pathVars: map[string]string{"share_link_id": "2"},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().RevokeShareLink(gomock.Any(), 2, 1).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:       "returns 400 on missing share_link_id",
+			pathVars:   map[string]string{},
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "returns 404 when link not found",
+			pathVars: map[string]string{"share_link_id": "2"},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().RevokeShareLink(gomock.Any(), 2, 1).Return(errors.New(apierr.ErrShareLinkNotFound))
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("DELETE", "/share_links/2", nil, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.RevokeShareLinkHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RevokeShareLinkHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("RevokeShareLinkHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestRegenerateShareLinkHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShareLinkService := mock_service.NewMockShareLinkService(ctrl)
+	handler.SetShareLinkService(mockShareLinkService)
+
+	tests := []struct {
+		name        string
+		pathVars    map[string]string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:     "successfully regenerate token",
+			pathVars: map[string]string{"share_link_id": "2"},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().RegenerateShareLink(gomock.Any(), 2, 1).
+					Return(response.ShareLinkData{ID: 2, Token: "newtoken", Active: true, ProductIDs: []int{}}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:     "returns 404 when link not found",
+			pathVars: map[string]string{"share_link_id": "2"},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().RegenerateShareLink(gomock.Any(), 2, 1).
+					Return(response.ShareLinkData{}, errors.New(apierr.ErrShareLinkNotFound))
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:     "returns 500 on service failure",
+			pathVars: map[string]string{"share_link_id": "2"},
+			mockSetup: func() {
+				mockShareLinkService.EXPECT().RegenerateShareLink(gomock.Any(), 2, 1).
+					Return(response.ShareLinkData{}, errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(
+				newRequestWithShopID("POST", "/share_links/2/regenerate", nil, 1),
+				tt.pathVars,
+			)
+			rec := httptest.NewRecorder()
+
+			handler.RegenerateShareLinkHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RegenerateShareLinkHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("RegenerateShareLinkHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -3,31 +3,314 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/zeirash/recapo/arion/common"
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/phone"
+	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/service"
 )
 
+const maxCustomerNameLength = 100
+
 type (
 	CreateShopTempOrderRequest struct {
 		CustomerName  string                           `json:"customer_name"`
 		CustomerPhone string                           `json:"customer_phone"`
 		Items         []CreateShopTempOrderItemRequest `json:"order_items"`
+		Address       *ShopTempOrderAddressRequest     `json:"address"` // optional
+		PowChallenge  string                           `json:"pow_challenge"`
+		PowSolution   string                           `json:"pow_solution"`
+		// Website is a honeypot: the share page hides the field, so only
+		// bots filling in every input send it.
+		Website string `json:"website"`
 	}
 
 	CreateShopTempOrderItemRequest struct {
 		ProductID int `json:"product_id"`
 		Qty       int `json:"qty"`
 	}
+
+	// ShopTempOrderAddressRequest is where the buyer wants the order shipped.
+	ShopTempOrderAddressRequest struct {
+		Recipient  string `json:"recipient"`
+		Phone      string `json:"phone"`
+		Address    string `json:"address"`
+		Province   string `json:"province"`
+		City       string `json:"city"`
+		District   string `json:"district"`
+		PostalCode string `json:"postal_code"`
+	}
+
+	UpdateShopRequest struct {
+		Name                 *string `json:"name,omitempty"`
+		UniqueCodeEnabled    *bool   `json:"unique_code_enabled,omitempty"`
+		UniqueCodeAsFee      *bool   `json:"unique_code_as_fee,omitempty"`
+		Address              *string `json:"address,omitempty"`
+		BankAccounts         *string `json:"bank_accounts,omitempty"`
+		InvoiceFooter        *string `json:"invoice_footer,omitempty"`
+		InvoiceNumberPattern *string `json:"invoice_number_pattern,omitempty"`
+		Timezone             *string `json:"timezone,omitempty"`
+		Locale               *string `json:"locale,omitempty"`
+		Description          *string `json:"description,omitempty"`
+		WhatsApp             *string `json:"whatsapp,omitempty"`
+		Instagram            *string `json:"instagram,omitempty"`
+		InvoiceMessage       *string `json:"invoice_message,omitempty"`
+		Currency             *string `json:"currency,omitempty"`
+	}
+
+	CloseShopRequest struct {
+		Password string `json:"password"`
+	}
 )
 
+// GetShopHandler godoc
+//
+//	@Summary		Get shop
+//	@Description	Get the authenticated shop and its settings.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			shop
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.ShopData
+//	@Failure		404	{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/shop [get]
+func GetShopHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	res, err := shopService.GetShopByID(ctx, shopID)
+	if err != nil {
+		if err.Error() == apierr.ErrShopNotFound {
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+			return
+		}
+		logger.WithError(err).Error("get_shop_error")
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_shop")
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// UpdateShopHandler godoc
+//
+//	@Summary		Update shop settings
+//	@Description	Partially update the authenticated shop's profile and settings. Only provided fields are updated.
+//	@Description	name, description, logo (see /shop/logo), whatsapp, instagram and currency are shown on the public storefront. whatsapp is read like customer phones (a number without a country code is Indonesian) and stored as international digits; instagram is the handle without "@"; currency is an ISO 4217 code (default IDR).
+//	@Description	unique_code_enabled adds a unique transfer code (1-999) to the amount due of new orders; unique_code_as_fee keeps the code on the order as a fee once paid instead of releasing it.
+//	@Description	address, bank_accounts (one account per line) and invoice_footer are printed on generated invoices. invoice_message is printed when an export does not bring its own message.
+//	@Description	invoice_number_pattern formats invoice numbers from {YYYY}, {YY}, {MM}, {DD} and the shop sequence {seq} or {seq:N} (zero-padded to N digits); it must contain the sequence. Numbers already issued keep their format.
+//	@Description	timezone is an IANA name (default Asia/Jakarta). Date filters, stats, invoice dates and numbers and DP deadlines counted in days use it. locale (en or id, default id) is the language of invoices, packing slips and labels.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			shop
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		UpdateShopRequest	true	"Fields to update"
+//	@Success		200		{object}	response.ShopData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON, empty name, invalid whatsapp, instagram, currency, invoice number pattern, timezone or locale)"
+//	@Failure		404		{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/shop [patch]
+func UpdateShopHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	inp := UpdateShopRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	res, err := shopService.UpdateShopByID(ctx, service.UpdateShopInput{
+		ID:                   shopID,
+		Name:                 inp.Name,
+		UniqueCodeEnabled:    inp.UniqueCodeEnabled,
+		UniqueCodeAsFee:      inp.UniqueCodeAsFee,
+		Address:              inp.Address,
+		BankAccounts:         inp.BankAccounts,
+		InvoiceFooter:        inp.InvoiceFooter,
+		InvoiceNumberPattern: inp.InvoiceNumberPattern,
+		Timezone:             inp.Timezone,
+		Locale:               inp.Locale,
+		Description:          inp.Description,
+		WhatsApp:             inp.WhatsApp,
+		Instagram:            inp.Instagram,
+		InvoiceMessage:       inp.InvoiceMessage,
+		Currency:             inp.Currency,
+	})
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrNameRequired, apierr.ErrShopWhatsAppInvalid, apierr.ErrShopInstagramInvalid, apierr.ErrShopCurrencyInvalid,
+			apierr.ErrInvoiceNumberPatternInvalid, apierr.ErrShopTimezoneInvalid, apierr.ErrShopLocaleInvalid:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		case apierr.ErrShopNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		default:
+			logger.WithError(err).Error("update_shop_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "update_shop")
+		}
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// UploadShopLogoHandler godoc
+//
+//	@Summary		Upload shop logo
+//	@Description	Upload the logo printed on invoices (jpeg, png, webp, max 5MB). Invoices only render jpeg and png logos.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			shop
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			image	formData	file	true	"Image file (jpeg/png/webp, max 5MB)"
+//	@Success		200		{object}	response.ShopData
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (missing file, invalid type)"
+//	@Failure		404		{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/shop/logo [post]
+func UploadShopLogoHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrImageTooLarge), "validation")
+		return
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrImageFieldRequired), "validation")
+		return
+	}
+	defer file.Close()
+
+	res, err := shopService.UploadShopLogo(ctx, shopID, file)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrUnsupportedImageType:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		case apierr.ErrShopNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		default:
+			logger.WithError(err).Error("upload_shop_logo_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "upload_shop_logo")
+		}
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
+
+// CloseShopHandler godoc
+//
+//	@Summary		Close shop
+//	@Description	Close the shop for good. Only the owner can, confirming with their password. Every user of the shop is signed out and can't log in again, the storefront goes offline and the subscription is cancelled.
+//	@Description	The data stays for 30 days, during which support can reopen the shop; export it first with GET /shop/export to keep a copy. After that the shop's data, users and images are deleted.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			shop
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			body	body		CloseShopRequest	true	"Password of the owner"
+//	@Success		200		{string}	string				"Success. data contains \"OK\""
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid JSON or password missing)"
+//	@Failure		401		{object}	ErrorApiResponse	"Wrong password"
+//	@Failure		403		{object}	ErrorApiResponse	"Not the owner"
+//	@Failure		404		{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		409		{object}	ErrorApiResponse	"Shop already closed"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/shop/close [post]
+func CloseShopHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shopID := ctx.Value(common.ShopIDKey).(int)
+	userID := ctx.Value(common.UserIDKey).(int)
+
+	inp := CloseShopRequest{}
+	if err := ParseJson(r.Body, &inp); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "parse_json")
+		return
+	}
+
+	if inp.Password == "" {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrShopClosurePassword), "validation")
+		return
+	}
+
+	if err := shopService.CloseShop(ctx, shopID, userID, inp.Password); err != nil {
+		switch err.Error() {
+		case apierr.ErrPasswordIncorrect:
+			WriteErrorJson(w, r, http.StatusUnauthorized, err, err.Error())
+		case apierr.ErrNotOwner:
+			WriteErrorJson(w, r, http.StatusForbidden, err, err.Error())
+		case apierr.ErrShopNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		case apierr.ErrShopClosed:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+		default:
+			logger.WithError(err).Error("close_shop_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "close_shop")
+		}
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
+// ReopenShopHandler godoc
+//
+//	@Summary		Reopen shop
+//	@Description	Undo the closure of a shop within its 30 day grace period, before its data is deleted. Its users can log in again; the subscription stays cancelled.
+//	@Description	Success Response envelope: { success, data, code, message }. data contains "OK" on success.
+//	@Tags			system
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			shop_id	path		int		true	"Shop ID"
+//	@Success		200		{string}	string				"Success. data contains \"OK\""
+//	@Failure		400		{object}	ErrorApiResponse	"Bad request (invalid shop_id)"
+//	@Failure		404		{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		409		{object}	ErrorApiResponse	"Shop not closed or already purged"
+//	@Failure		500		{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/system/shops/{shop_id}/reopen [post]
+func ReopenShopHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	shopID, err := strconv.Atoi(mux.Vars(r)["shop_id"])
+	if err != nil || shopID <= 0 {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrShopIDInvalid), "validation")
+		return
+	}
+
+	if err := shopService.ReopenShop(ctx, shopID); err != nil {
+		switch err.Error() {
+		case apierr.ErrShopNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		case apierr.ErrShopNotClosed:
+			WriteErrorJson(w, r, http.StatusConflict, err, "conflict")
+		default:
+			logger.WithError(err).Error("reopen_shop_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "reopen_shop")
+		}
+		return
+	}
+
+	WriteJson(w, http.StatusOK, "OK")
+}
+
 // GetShopShareTokenHandler godoc
 //
 //	@Summary		Get shop share token
-//	@Description	Get the authenticated shop's share_token. Used for generating share links.
+//	@Description	Get the token of the authenticated shop's default share link: the one that shows every product and never expires. A new default link is created if the shop has none. Other links are managed under /share_links.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			shop
 //	@Produce		json
@@ -56,14 +339,22 @@ func GetShopShareTokenHandler(w http.ResponseWriter, r *http.Request) {
 // GetShopProductsHandler godoc
 //
 //	@Summary		List shop products (public)
-//	@Description	Get all products for a shop by its share token. No authentication required. Used for public product catalog share links.
+//	@Description	Get the shop's public profile and its active products by a share link token. No authentication required. Used for public product catalog share links.
+//	@Description	A link with a product subset only lists those products.
+//	@Description	shop holds the storefront header: name, description, logo_url, whatsapp, instagram and currency. categories lists the shop's categories in display order.
+//	@Description	Products are listed the way the shop arranged them unless sort is given.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			shop
 //	@Produce		json
 //	@Param			share_token	path		string	true	"Shop share token"
-//	@Success		200			{array}		response.ProductData
-//	@Failure		400	{object}	ErrorApiResponse	"Bad request (share_token required)"
+//	@Param			search		query		string	false	"Search query"
+//	@Param			sort		query		string	false	"Sort by column and order (e.g. price,asc)"
+//	@Param			category_id	query		int		false	"Products in the category or its subcategories"
+//	@Param			tags		query		string	false	"Products with any of the tags, comma separated (e.g. sale,new)"
+//	@Success		200			{object}	response.PublicShopProductsData
+//	@Failure		400	{object}	ErrorApiResponse	"Bad request (share_token required or invalid category_id)"
 //	@Failure		404	{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		410	{object}	ErrorApiResponse	"Share link expired or revoked"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/public/shops/{share_token}/products [get]
 func GetShopProductsHandler(w http.ResponseWriter, r *http.Request) {
@@ -76,24 +367,37 @@ func GetShopProductsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	products, err := shopService.GetPublicProducts(ctx, shareToken)
+	filter, err := parseProductFilter(r)
 	if err != nil {
-		if err.Error() == apierr.ErrShopNotFound {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
+	res, err := shopService.GetPublicProducts(ctx, shareToken, filter)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrShopNotFound:
 			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
-			return
+		case apierr.ErrShareLinkExpired:
+			WriteErrorJson(w, r, http.StatusGone, err, "gone")
+		default:
+			logger.WithError(err).Error("get_shop_products_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_shop_products")
 		}
-		logger.WithError(err).Error("get_shop_products_error")
-		WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_shop_products")
 		return
 	}
 
-	WriteJson(w, http.StatusOK, products)
+	WriteJson(w, http.StatusOK, res)
 }
 
 // CreateShopOrderTempHandler godoc
 //
 //	@Summary		Create order temp (public)
-//	@Description	Create a temporary order for a shop by share token. No authentication required. Used for public share-page checkout.
+//	@Description	Create a temporary order for a shop by share link token. No authentication required. Used for public share-page checkout.
+//	@Description	The order records the link it came from. Products outside the link's product subset, from another shop or inactive are rejected.
+//	@Description	Send a solved challenge from GET /public/shops/{share_token}/challenge as pow_challenge and pow_solution. The hidden website field must stay empty.
+//	@Description	An optional shipping address may be sent; it is copied onto the order when the seller accepts it.
+//	@Description	The same phone and items within 10 minutes are rejected as a duplicate. Orders from the phone of a customer the shop blocked are refused. Requests are rate limited per IP and per share token.
 //	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
 //	@Tags			shop
 //	@Accept			json
@@ -101,7 +405,12 @@ func GetShopProductsHandler(w http.ResponseWriter, r *http.Request) {
 //	@Param			share_token	path		string						true	"Shop share token"
 //	@Param			body		body		CreateShopOrderTempRequest	true	"Customer name, phone, and order items (product_id, qty)"
 //	@Success		200			{object}	response.OrderTempData
-//	@Failure		400	{object}	ErrorApiResponse	"Bad request (missing share_token, invalid JSON, or validation: customer_name/customer_phone required)"
+//	@Failure		400	{object}	ErrorApiResponse	"Bad request (missing share_token, invalid JSON, invalid challenge, inactive product, or validation: customer_name/customer_phone required, too many items, qty too large)"
+//	@Failure		403	{object}	ErrorApiResponse	"Phone blocked by the shop"
+//	@Failure		404	{object}	ErrorApiResponse	"Shop or product not found"
+//	@Failure		409	{object}	ErrorApiResponse	"Duplicate submission"
+//	@Failure		410	{object}	ErrorApiResponse	"Share link expired or revoked"
+//	@Failure		429	{object}	ErrorApiResponse	"Too many requests"
 //	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
 //	@Router			/public/shops/{share_token}/orders [post]
 func CreateShopTempOrderHandler(w http.ResponseWriter, r *http.Request) {
@@ -125,6 +434,11 @@ func CreateShopTempOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := shopService.VerifyOrderChallenge(ctx, shareToken, inp.PowChallenge, inp.PowSolution); err != nil {
+		WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		return
+	}
+
 	items := []service.CreateTempOrderItemInput{}
 	for _, item := range inp.Items {
 		items = append(items, service.CreateTempOrderItemInput{
@@ -132,10 +446,35 @@ func CreateShopTempOrderHandler(w http.ResponseWriter, r *http.Request) {
 			Qty:       item.Qty,
 		})
 	}
-	res, err := orderService.CreateTempOrder(ctx, inp.CustomerName, inp.CustomerPhone, shareToken, items)
+	var address *model.ShippingAddress
+	if inp.Address != nil {
+		address = &model.ShippingAddress{
+			Recipient:  strings.TrimSpace(inp.Address.Recipient),
+			Phone:      inp.Address.Phone,
+			Address:    strings.TrimSpace(inp.Address.Address),
+			Province:   strings.TrimSpace(inp.Address.Province),
+			City:       strings.TrimSpace(inp.Address.City),
+			District:   strings.TrimSpace(inp.Address.District),
+			PostalCode: strings.TrimSpace(inp.Address.PostalCode),
+		}
+	}
+	res, err := orderService.CreateTempOrder(ctx, inp.CustomerName, inp.CustomerPhone, shareToken, address, items)
 	if err != nil {
-		logger.WithError(err).Error("create_shop_temp_order_error")
-		WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_shop_temp_order")
+		switch err.Error() {
+		case apierr.ErrShopNotFound, apierr.ErrProductNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		case apierr.ErrProductInactive:
+			WriteErrorJson(w, r, http.StatusBadRequest, err, "validation")
+		case apierr.ErrDuplicateTempOrder:
+			WriteErrorJson(w, r, http.StatusConflict, err, "duplicate")
+		case apierr.ErrCustomerBlocked:
+			WriteErrorJson(w, r, http.StatusForbidden, err, "forbidden")
+		case apierr.ErrShareLinkExpired:
+			WriteErrorJson(w, r, http.StatusGone, err, "gone")
+		default:
+			logger.WithError(err).Error("create_shop_temp_order_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "create_shop_temp_order")
+		}
 		return
 	}
 
@@ -143,18 +482,34 @@ func CreateShopTempOrderHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func validateCreateShopTempOrder(inp CreateShopTempOrderRequest) (bool, error) {
+	if inp.Website != "" {
+		return false, errors.New(apierr.ErrSubmissionRejected)
+	}
+
 	if inp.CustomerName == "" {
 		return false, errors.New(apierr.ErrCustomerNameRequired)
 	}
 
+	if utf8.RuneCountInString(inp.CustomerName) > maxCustomerNameLength {
+		return false, errors.New(apierr.ErrCustomerNameTooLong)
+	}
+
 	if inp.CustomerPhone == "" {
 		return false, errors.New(apierr.ErrCustomerPhoneRequired)
 	}
 
+	if _, err := phone.Normalize(inp.CustomerPhone); err != nil {
+		return false, errors.New(apierr.ErrCustomerPhoneInvalid)
+	}
+
 	if len(inp.Items) == 0 {
 		return false, errors.New(apierr.ErrOrderItemsRequired)
 	}
 
+	if len(inp.Items) > constant.PublicOrderMaxItems {
+		return false, errors.New(apierr.ErrTooManyOrderItems)
+	}
+
 	for _, item := range inp.Items {
 		if item.ProductID <= 0 {
 			return false, errors.New(apierr.ErrProductIDRequired)
@@ -165,7 +520,64 @@ func validateCreateShopTempOrder(inp CreateShopTempOrderRequest) (bool, error) {
 		if item.Qty <= 0 {
 			return false, errors.New(apierr.ErrQtyRequired)
 		}
+		if item.Qty > constant.PublicOrderMaxQty {
+			return false, errors.New(apierr.ErrQtyTooLarge)
+		}
+	}
+
+	if inp.Address != nil {
+		if valid, err := validateShippingAddress(inp.Address.Recipient, inp.Address.Address, inp.Address.PostalCode); !valid {
+			return false, err
+		}
+		if inp.Address.Phone != "" {
+			if _, err := phone.Normalize(inp.Address.Phone); err != nil {
+				return false, errors.New(apierr.ErrCustomerPhoneInvalid)
+			}
+		}
 	}
 
 	return true, nil
 }
+
+// GetShopOrderChallengeHandler godoc
+//
+//	@Summary		Get order challenge (public)
+//	@Description	Get a proof-of-work challenge for placing an order through a share link. No authentication required.
+//	@Description	Find a solution, counting up from 0, for which sha256(challenge + ":" + solution) starts with difficulty zero bits, and send both with the order.
+//	@Description	A challenge is valid for one order until expires_at.
+//	@Description	Success Response envelope: { success, data, code, message }. Schema below shows the data field (inner payload).
+//	@Tags			shop
+//	@Produce		json
+//	@Param			share_token	path		string	true	"Shop share token"
+//	@Success		200			{object}	response.OrderChallengeData
+//	@Failure		400	{object}	ErrorApiResponse	"Bad request (share_token required)"
+//	@Failure		404	{object}	ErrorApiResponse	"Shop not found"
+//	@Failure		410	{object}	ErrorApiResponse	"Share link expired or revoked"
+//	@Failure		500	{object}	ErrorApiResponse	"Internal server error"
+//	@Router			/public/shops/{share_token}/challenge [get]
+func GetShopOrderChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	shareToken := params["share_token"]
+
+	if shareToken == "" {
+		WriteErrorJson(w, r, http.StatusBadRequest, errors.New(apierr.ErrShareTokenRequired), "validation")
+		return
+	}
+
+	res, err := shopService.GetOrderChallenge(ctx, shareToken)
+	if err != nil {
+		switch err.Error() {
+		case apierr.ErrShopNotFound:
+			WriteErrorJson(w, r, http.StatusNotFound, err, "not_found")
+		case apierr.ErrShareLinkExpired:
+			WriteErrorJson(w, r, http.StatusGone, err, "gone")
+		default:
+			logger.WithError(err).Error("get_shop_order_challenge_error")
+			WriteErrorJson(w, r, http.StatusInternalServerError, err, "get_shop_order_challenge")
+		}
+		return
+	}
+
+	WriteJson(w, http.StatusOK, res)
+}
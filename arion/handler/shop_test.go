@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +17,8 @@ import (
 	"github.com/zeirash/recapo/arion/common/response"
 	"github.com/zeirash/recapo/arion/handler"
 	mock_service "github.com/zeirash/recapo/arion/mock/service"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/service"
 )
 
 func newRequestWithShareToken(method, path string, shareToken string) *http.Request {
@@ -36,14 +40,17 @@ func TestGetShopProductsHandler(t *testing.T) {
 	handler.SetShopService(mockShopService)
 
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	categoryID := 3
 
 	tests := []struct {
 		name           string
 		shareToken     string
+		query          string
 		mockSetup      func()
 		wantStatus     int
 		wantSuccess    bool
 		wantCount      int
+		wantShopName   string
 		wantErrMessage string
 	}{
 		{
@@ -51,28 +58,58 @@ func TestGetShopProductsHandler(t *testing.T) {
 			shareToken: "abc123xyz",
 			mockSetup: func() {
 				mockShopService.EXPECT().
-					GetPublicProducts(gomock.Any(), "abc123xyz").
-					Return([]response.ProductData{
-						{ID: 1, Name: "Product A", Price: 1000, CreatedAt: fixedTime},
-						{ID: 2, Name: "Product B", Price: 500, CreatedAt: fixedTime},
+					GetPublicProducts(gomock.Any(), "abc123xyz", model.FilterOptions{}).
+					Return(response.PublicShopProductsData{
+						Shop: response.PublicShopData{Name: "Toko Jastip", WhatsApp: "6281234567890"},
+						Products: []response.ProductData{
+							{ID: 1, Name: "Product A", Price: 1000, CreatedAt: fixedTime},
+							{ID: 2, Name: "Product B", Price: 500, CreatedAt: fixedTime},
+						},
 					}, nil)
 			},
-			wantStatus:  http.StatusOK,
-			wantSuccess: true,
-			wantCount:   2,
+			wantStatus:   http.StatusOK,
+			wantSuccess:  true,
+			wantCount:    2,
+			wantShopName: "Toko Jastip",
 		},
 		{
 			name:       "successfully get shop products - empty list",
 			shareToken: "empty123",
 			mockSetup: func() {
 				mockShopService.EXPECT().
-					GetPublicProducts(gomock.Any(), "empty123").
-					Return([]response.ProductData{}, nil)
+					GetPublicProducts(gomock.Any(), "empty123", model.FilterOptions{}).
+					Return(response.PublicShopProductsData{Products: []response.ProductData{}}, nil)
 			},
 			wantStatus:  http.StatusOK,
 			wantSuccess: true,
 			wantCount:   0,
 		},
+		{
+			name:       "passes category and tag filters",
+			shareToken: "abc123xyz",
+			query:      "?category_id=3&tags=sale,%20new,",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetPublicProducts(gomock.Any(), "abc123xyz", model.FilterOptions{CategoryID: &categoryID, Tags: []string{"sale", "new"}}).
+					Return(response.PublicShopProductsData{
+						Shop:     response.PublicShopData{Name: "Toko Jastip"},
+						Products: []response.ProductData{{ID: 1, Name: "Product A", Price: 1000, CreatedAt: fixedTime}},
+					}, nil)
+			},
+			wantStatus:   http.StatusOK,
+			wantSuccess:  true,
+			wantCount:    1,
+			wantShopName: "Toko Jastip",
+		},
+		{
+			name:           "returns 400 when category_id is invalid",
+			shareToken:     "abc123xyz",
+			query:          "?category_id=abc",
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Category ID must be a positive number",
+		},
 		{
 			name:       "returns 400 when share_token is missing",
 			shareToken: "",
@@ -85,20 +122,32 @@ func TestGetShopProductsHandler(t *testing.T) {
 			shareToken: "invalid",
 			mockSetup: func() {
 				mockShopService.EXPECT().
-					GetPublicProducts(gomock.Any(), "invalid").
-					Return(nil, errors.New(apierr.ErrShopNotFound))
+					GetPublicProducts(gomock.Any(), "invalid", model.FilterOptions{}).
+					Return(response.PublicShopProductsData{}, errors.New(apierr.ErrShopNotFound))
 			},
 			wantStatus:     http.StatusNotFound,
 			wantSuccess:    false,
 			wantErrMessage: "Shop not found",
 		},
+		{
+			name:       "returns 410 when share link expired",
+			shareToken: "expired",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetPublicProducts(gomock.Any(), "expired", model.FilterOptions{}).
+					Return(response.PublicShopProductsData{}, errors.New(apierr.ErrShareLinkExpired))
+			},
+			wantStatus:     http.StatusGone,
+			wantSuccess:    false,
+			wantErrMessage: "This link has expired or was turned off by the seller",
+		},
 		{
 			name:       "returns 500 on service error",
 			shareToken: "token",
 			mockSetup: func() {
 				mockShopService.EXPECT().
-					GetPublicProducts(gomock.Any(), "token").
-					Return(nil, errors.New("database error"))
+					GetPublicProducts(gomock.Any(), "token", model.FilterOptions{}).
+					Return(response.PublicShopProductsData{}, errors.New("database error"))
 			},
 			wantStatus:     http.StatusInternalServerError,
 			wantSuccess:    false,
@@ -110,7 +159,7 @@ func TestGetShopProductsHandler(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
 
-			req := newRequestWithShareToken("GET", "/public/shops/"+tt.shareToken+"/products", tt.shareToken)
+			req := newRequestWithShareToken("GET", "/public/shops/"+tt.shareToken+"/products"+tt.query, tt.shareToken)
 			rec := httptest.NewRecorder()
 
 			handler.GetShopProductsHandler(rec, req)
@@ -128,10 +177,15 @@ func TestGetShopProductsHandler(t *testing.T) {
 				t.Errorf("GetShopProductsHandler() message = %v, want %v", resp.Message, tt.wantErrMessage)
 			}
 			if tt.wantCount > 0 {
-				products, ok := resp.Data.([]interface{})
+				data, _ := resp.Data.(map[string]interface{})
+				products, ok := data["products"].([]interface{})
 				if !ok || len(products) != tt.wantCount {
 					t.Errorf("GetShopProductsHandler() data count = %v, want %v", len(products), tt.wantCount)
 				}
+				shop, _ := data["shop"].(map[string]interface{})
+				if shop["name"] != tt.wantShopName {
+					t.Errorf("GetShopProductsHandler() shop name = %v, want %v", shop["name"], tt.wantShopName)
+				}
 			}
 		})
 	}
@@ -234,11 +288,21 @@ func TestCreateShopTempOrderHandler(t *testing.T) {
 	oldOrderService := handler.GetOrderService()
 	defer handler.SetOrderService(oldOrderService)
 
+	oldShopService := handler.GetShopService()
+	defer handler.SetShopService(oldShopService)
+
 	mockOrderService := mock_service.NewMockOrderService(ctrl)
 	handler.SetOrderService(mockOrderService)
+	mockShopService := mock_service.NewMockShopService(ctrl)
+	handler.SetShopService(mockShopService)
 
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 
+	manyItems := make([]interface{}, 51)
+	for i := range manyItems {
+		manyItems[i] = map[string]interface{}{"product_id": i + 1, "qty": 1}
+	}
+
 	tests := []struct {
 		name           string
 		shareToken     string
@@ -260,8 +324,11 @@ func TestCreateShopTempOrderHandler(t *testing.T) {
 				},
 			},
 			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
 				mockOrderService.EXPECT().
-					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", gomock.Any()).
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", nil, gomock.Any()).
 					Return(response.TempOrderData{
 						ID:            1,
 						CustomerName:  "Jane Doe",
@@ -278,6 +345,58 @@ func TestCreateShopTempOrderHandler(t *testing.T) {
 			wantStatus:  http.StatusOK,
 			wantSuccess: true,
 		},
+		{
+			name:       "successfully create shop temp order with shipping address",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items": []interface{}{
+					map[string]interface{}{"product_id": 10, "qty": 2},
+				},
+				"address": map[string]interface{}{
+					"recipient":   " Jane Doe ",
+					"address":     "Jl. Merdeka 1",
+					"city":        "Bandung",
+					"postal_code": "40111",
+				},
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
+				mockOrderService.EXPECT().
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", &model.ShippingAddress{
+						Recipient:  "Jane Doe",
+						Address:    "Jl. Merdeka 1",
+						City:       "Bandung",
+						PostalCode: "40111",
+					}, gomock.Any()).
+					Return(response.TempOrderData{ID: 1, Status: "pending", CreatedAt: fixedTime}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:       "returns 400 when shipping address postal code is invalid",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items": []interface{}{
+					map[string]interface{}{"product_id": 10, "qty": 2},
+				},
+				"address": map[string]interface{}{
+					"recipient":   "Jane Doe",
+					"address":     "Jl. Merdeka 1",
+					"postal_code": "4011",
+				},
+			},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Postal code must be 5 digits",
+		},
 		{
 			name:       "returns 400 when share_token is missing",
 			shareToken: "",
@@ -400,6 +519,199 @@ func TestCreateShopTempOrderHandler(t *testing.T) {
 			wantSuccess:    false,
 			wantErrMessage: "Quantity is required",
 		},
+		{
+			name:       "returns 404 when product is not offered by the link",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
+				mockOrderService.EXPECT().
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", nil, gomock.Any()).
+					Return(response.TempOrderData{}, errors.New(apierr.ErrProductNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 410 when share link is revoked",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
+				mockOrderService.EXPECT().
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", nil, gomock.Any()).
+					Return(response.TempOrderData{}, errors.New(apierr.ErrShareLinkExpired))
+			},
+			wantStatus:  http.StatusGone,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 400 when the honeypot field is filled",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+				"website":        "http://spam.example",
+			},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Submission rejected",
+		},
+		{
+			name:       "returns 400 when customer_name is too long",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  strings.Repeat("a", 101),
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Name must be at most 100 characters",
+		},
+		{
+			name:       "returns 400 when customer_phone has letters",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "0812-CALL-ME",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Phone number must be 8 to 15 digits",
+		},
+		{
+			name:       "returns 400 when customer_phone is too short",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "12345",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Phone number must be 8 to 15 digits",
+		},
+		{
+			name:       "returns 400 when there are too many items",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    manyItems,
+			},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "An order can have at most 50 items",
+		},
+		{
+			name:       "returns 400 when qty is too large",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 101}},
+			},
+			mockSetup:      func() {},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Quantity per item can be at most 100",
+		},
+		{
+			name:       "returns 400 when the challenge is not solved",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+				"pow_challenge":  "bad",
+				"pow_solution":   "1",
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "bad", "1").
+					Return(errors.New(apierr.ErrChallengeInvalid))
+			},
+			wantStatus:     http.StatusBadRequest,
+			wantSuccess:    false,
+			wantErrMessage: "Anti-spam check failed, please try again",
+		},
+		{
+			name:       "returns 400 when a product is inactive",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
+				mockOrderService.EXPECT().
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", nil, gomock.Any()).
+					Return(response.TempOrderData{}, errors.New(apierr.ErrProductInactive))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 409 when the order was already sent",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
+				mockOrderService.EXPECT().
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", nil, gomock.Any()).
+					Return(response.TempOrderData{}, errors.New(apierr.ErrDuplicateTempOrder))
+			},
+			wantStatus:  http.StatusConflict,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 403 when the phone is blocked",
+			shareToken: "share-abc123",
+			body: map[string]interface{}{
+				"customer_name":  "Jane Doe",
+				"customer_phone": "+62812345678",
+				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
+				mockOrderService.EXPECT().
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", nil, gomock.Any()).
+					Return(response.TempOrderData{}, errors.New(apierr.ErrCustomerBlocked))
+			},
+			wantStatus:  http.StatusForbidden,
+			wantSuccess: false,
+		},
 		{
 			name:       "returns 500 when order service fails",
 			shareToken: "share-abc123",
@@ -409,8 +721,11 @@ func TestCreateShopTempOrderHandler(t *testing.T) {
 				"order_items":    []interface{}{map[string]interface{}{"product_id": 10, "qty": 1}},
 			},
 			mockSetup: func() {
+				mockShopService.EXPECT().
+					VerifyOrderChallenge(gomock.Any(), "share-abc123", "", "").
+					Return(nil)
 				mockOrderService.EXPECT().
-					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", gomock.Any()).
+					CreateTempOrder(gomock.Any(), "Jane Doe", "+62812345678", "share-abc123", nil, gomock.Any()).
 					Return(response.TempOrderData{}, errors.New("database error"))
 			},
 			wantStatus:     http.StatusInternalServerError,
@@ -457,3 +772,591 @@ func TestCreateShopTempOrderHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestGetShopOrderChallengeHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetShopService()
+	defer handler.SetShopService(oldService)
+
+	mockShopService := mock_service.NewMockShopService(ctrl)
+	handler.SetShopService(mockShopService)
+
+	fixedTime := time.Date(2024, 1, 15, 10, 40, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		shareToken  string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:       "successfully get challenge",
+			shareToken: "share-abc123",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetOrderChallenge(gomock.Any(), "share-abc123").
+					Return(response.OrderChallengeData{Challenge: "abc.def", Difficulty: 16, ExpiresAt: fixedTime}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 when share_token is missing",
+			shareToken:  "",
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 404 when share token is unknown",
+			shareToken: "share-abc123",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetOrderChallenge(gomock.Any(), "share-abc123").
+					Return(response.OrderChallengeData{}, errors.New(apierr.ErrShopNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name:       "returns 410 when share link is revoked",
+			shareToken: "share-abc123",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetOrderChallenge(gomock.Any(), "share-abc123").
+					Return(response.OrderChallengeData{}, errors.New(apierr.ErrShareLinkExpired))
+			},
+			wantStatus:  http.StatusGone,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := httptest.NewRequest("GET", "/public/shops/"+tt.shareToken+"/challenge", nil)
+			if tt.shareToken != "" {
+				req = mux.SetURLVars(req, map[string]string{"share_token": tt.shareToken})
+			}
+
+			rec := httptest.NewRecorder()
+			handler.GetShopOrderChallengeHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetShopOrderChallengeHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetShopOrderChallengeHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestGetShopHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetShopService()
+	defer handler.SetShopService(oldService)
+
+	mockShopService := mock_service.NewMockShopService(ctrl)
+	handler.SetShopService(mockShopService)
+
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully get shop",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetShopByID(gomock.Any(), 1).
+					Return(response.ShopData{ID: 1, Name: "My Shop", CreatedAt: fixedTime}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 404 when shop not found",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetShopByID(gomock.Any(), 1).
+					Return(response.ShopData{}, errors.New(apierr.ErrShopNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					GetShopByID(gomock.Any(), 1).
+					Return(response.ShopData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("GET", "/shop", nil, 1)
+			rec := httptest.NewRecorder()
+
+			handler.GetShopHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetShopHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("GetShopHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestUpdateShopHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetShopService()
+	defer handler.SetShopService(oldService)
+
+	mockShopService := mock_service.NewMockShopService(ctrl)
+	handler.SetShopService(mockShopService)
+
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		body        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully enable unique code",
+			body: `{"unique_code_enabled": true}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UpdateShopByID(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, input service.UpdateShopInput) (response.ShopData, error) {
+						if input.ID != 1 || input.UniqueCodeEnabled == nil || !*input.UniqueCodeEnabled || input.UniqueCodeAsFee != nil {
+							t.Errorf("UpdateShopByID() input = %+v", input)
+						}
+						return response.ShopData{ID: 1, UniqueCodeEnabled: true, CreatedAt: fixedTime}, nil
+					})
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "successfully update invoice branding",
+			body: `{"address": "Jl. Melati 5", "bank_accounts": "BCA 123\nMandiri 456", "invoice_footer": "Thanks"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UpdateShopByID(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, input service.UpdateShopInput) (response.ShopData, error) {
+						if input.Address == nil || *input.Address != "Jl. Melati 5" ||
+							input.BankAccounts == nil || *input.BankAccounts != "BCA 123\nMandiri 456" ||
+							input.InvoiceFooter == nil || *input.InvoiceFooter != "Thanks" {
+							t.Errorf("UpdateShopByID() input = %+v", input)
+						}
+						return response.ShopData{ID: 1, Address: *input.Address, CreatedAt: fixedTime}, nil
+					})
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:        "returns 400 on invalid JSON",
+			body:        `{invalid`,
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 on invalid invoice number pattern",
+			body: `{"invoice_number_pattern": "INV/{YYYY}"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UpdateShopByID(gomock.Any(), gomock.Any()).
+					Return(response.ShopData{}, errors.New(apierr.ErrInvoiceNumberPatternInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 on invalid whatsapp number",
+			body: `{"whatsapp": "call me"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UpdateShopByID(gomock.Any(), gomock.Any()).
+					Return(response.ShopData{}, errors.New(apierr.ErrShopWhatsAppInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 on invalid timezone",
+			body: `{"timezone": "Mars/Olympus"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UpdateShopByID(gomock.Any(), gomock.Any()).
+					Return(response.ShopData{}, errors.New(apierr.ErrShopTimezoneInvalid))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when shop not found",
+			body: `{"unique_code_as_fee": true}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UpdateShopByID(gomock.Any(), gomock.Any()).
+					Return(response.ShopData{}, errors.New(apierr.ErrShopNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			body: `{"unique_code_enabled": false}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UpdateShopByID(gomock.Any(), gomock.Any()).
+					Return(response.ShopData{}, errors.New("database error"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithShopID("PATCH", "/shop", []byte(tt.body), 1)
+			rec := httptest.NewRecorder()
+
+			handler.UpdateShopHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UpdateShopHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("UpdateShopHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestUploadShopLogoHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetShopService()
+	defer handler.SetShopService(oldService)
+
+	mockShopService := mock_service.NewMockShopService(ctrl)
+	handler.SetShopService(mockShopService)
+
+	buildMultipartRequest := func(fieldName, filename string, content []byte) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile(fieldName, filename)
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write(content)
+		writer.Close()
+
+		req := newRequestWithShopID("POST", "/shop/logo", body.Bytes(), 1)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	pngBytes := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+
+	tests := []struct {
+		name        string
+		buildReq    func() *http.Request
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "successfully upload logo",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", "logo.png", pngBytes)
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UploadShopLogo(gomock.Any(), 1, gomock.Any()).
+					Return(response.ShopData{ID: 1, LogoURL: "/uploads/logos/abc.png"}, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name: "returns 400 when image field is missing",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("file", "logo.png", pngBytes)
+			},
+			mockSetup:   func() {},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 400 on unsupported image type",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", "logo.txt", []byte("plain text"))
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UploadShopLogo(gomock.Any(), 1, gomock.Any()).
+					Return(response.ShopData{}, errors.New(apierr.ErrUnsupportedImageType))
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 404 when shop not found",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", "logo.png", pngBytes)
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UploadShopLogo(gomock.Any(), 1, gomock.Any()).
+					Return(response.ShopData{}, errors.New(apierr.ErrShopNotFound))
+			},
+			wantStatus:  http.StatusNotFound,
+			wantSuccess: false,
+		},
+		{
+			name: "returns 500 on service error",
+			buildReq: func() *http.Request {
+				return buildMultipartRequest("image", "logo.png", pngBytes)
+			},
+			mockSetup: func() {
+				mockShopService.EXPECT().
+					UploadShopLogo(gomock.Any(), 1, gomock.Any()).
+					Return(response.ShopData{}, errors.New("failed to save file"))
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			rec := httptest.NewRecorder()
+
+			handler.UploadShopLogoHandler(rec, tt.buildReq())
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UploadShopLogoHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("UploadShopLogoHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestCloseShopHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetShopService()
+	defer handler.SetShopService(oldService)
+
+	mockShopService := mock_service.NewMockShopService(ctrl)
+	handler.SetShopService(mockShopService)
+
+	tests := []struct {
+		name        string
+		body        string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name: "closes the shop",
+			body: `{"password":"secret123"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().CloseShop(gomock.Any(), 1, 3, "secret123").Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:       "returns 400 on invalid json",
+			body:       "invalid json",
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "returns 400 when password is missing",
+			body:       `{}`,
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "returns 401 on wrong password",
+			body: `{"password":"wrong"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().CloseShop(gomock.Any(), 1, 3, "wrong").Return(errors.New(apierr.ErrPasswordIncorrect))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "returns 403 when the caller is not the owner",
+			body: `{"password":"secret123"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().CloseShop(gomock.Any(), 1, 3, "secret123").Return(errors.New(apierr.ErrNotOwner))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "returns 409 when the shop is already closed",
+			body: `{"password":"secret123"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().CloseShop(gomock.Any(), 1, 3, "secret123").Return(errors.New(apierr.ErrShopClosed))
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "returns 500 on service failure",
+			body: `{"password":"secret123"}`,
+			mockSetup: func() {
+				mockShopService.EXPECT().CloseShop(gomock.Any(), 1, 3, "secret123").Return(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithUserAndShopID("POST", "/shop/close", []byte(tt.body), 3, 1)
+			rec := httptest.NewRecorder()
+
+			handler.CloseShopHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("CloseShopHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("CloseShopHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestReopenShopHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldService := handler.GetShopService()
+	defer handler.SetShopService(oldService)
+
+	mockShopService := mock_service.NewMockShopService(ctrl)
+	handler.SetShopService(mockShopService)
+
+	tests := []struct {
+		name        string
+		shopID      string
+		mockSetup   func()
+		wantStatus  int
+		wantSuccess bool
+	}{
+		{
+			name:   "reopens the shop",
+			shopID: "8",
+			mockSetup: func() {
+				mockShopService.EXPECT().ReopenShop(gomock.Any(), 8).Return(nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantSuccess: true,
+		},
+		{
+			name:       "returns 400 for a shop id that is not a number",
+			shopID:     "abc",
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "returns 404 when the shop does not exist",
+			shopID: "8",
+			mockSetup: func() {
+				mockShopService.EXPECT().ReopenShop(gomock.Any(), 8).Return(errors.New(apierr.ErrShopNotFound))
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:   "returns 409 when the shop is not closed",
+			shopID: "8",
+			mockSetup: func() {
+				mockShopService.EXPECT().ReopenShop(gomock.Any(), 8).Return(errors.New(apierr.ErrShopNotClosed))
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:   "returns 500 on service failure",
+			shopID: "8",
+			mockSetup: func() {
+				mockShopService.EXPECT().ReopenShop(gomock.Any(), 8).Return(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := newRequestWithPathVars(newRequestWithShopID("POST", "/system/shops/"+tt.shopID+"/reopen", nil, 1), map[string]string{"shop_id": tt.shopID})
+			rec := httptest.NewRecorder()
+
+			handler.ReopenShopHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ReopenShopHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			var resp handler.ApiResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Success != tt.wantSuccess {
+				t.Errorf("ReopenShopHandler() success = %v, want %v", resp.Success, tt.wantSuccess)
+			}
+		})
+	}
+}
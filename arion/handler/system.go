@@ -87,3 +87,23 @@ func GetSystemPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	WriteJson(w, http.StatusOK, payments)
 }
+
+// CollectUnreferencedUploadsHandler godoc
+//
+//	@Summary		Collect unreferenced uploads
+//	@Description	Deletes uploaded files older than a day that nothing points to, as the daily cron does, and reports what was freed.
+//	@Tags			system
+//	@Produce		json
+//	@Success		200	{object}	ApiResponse
+//	@Failure		401	{object}	ErrorApiResponse
+//	@Router			/system/uploads/gc [post]
+//	@Security		BearerAuth
+func CollectUnreferencedUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	result, err := systemService.CollectUnreferencedUploads(ctx)
+	if err != nil {
+		WriteErrorJson(w, r, http.StatusInternalServerError, err, "collect_unreferenced_uploads")
+		return
+	}
+	WriteJson(w, http.StatusOK, result)
+}
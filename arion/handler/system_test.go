@@ -212,3 +212,52 @@ func TestGetSystemPaymentsHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectUnreferencedUploadsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockSetup  func(m *mock_service.MockSystemService)
+		wantStatus int
+	}{
+		{
+			name: "returns what was freed",
+			mockSetup: func(m *mock_service.MockSystemService) {
+				m.EXPECT().CollectUnreferencedUploads(gomock.Any()).Return(response.SystemUploadGCData{
+					DeletedCount: 2,
+					FreedBytes:   2560,
+					Keys:         []string{"products/a_full.webp", "refunds/b.jpg"},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "returns 500 on service error",
+			mockSetup: func(m *mock_service.MockSystemService) {
+				m.EXPECT().CollectUnreferencedUploads(gomock.Any()).Return(response.SystemUploadGCData{}, errors.New("db error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldSvc := handler.GetSystemService()
+			defer handler.SetSystemService(oldSvc)
+
+			mockSvc := mock_service.NewMockSystemService(ctrl)
+			handler.SetSystemService(mockSvc)
+			tt.mockSetup(mockSvc)
+
+			req := httptest.NewRequest(http.MethodPost, "/system/uploads/gc", nil)
+			w := httptest.NewRecorder()
+			handler.CollectUnreferencedUploadsHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
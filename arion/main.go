@@ -22,6 +22,7 @@ import (
 	"os"
 	"strings"
 	"time"
+	_ "time/tzdata" // shop timezones must load on hosts without a zoneinfo database
 
 
 	sentry "github.com/getsentry/sentry-go"
@@ -31,9 +32,12 @@ import (
 	promhttp "github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/database"
 	"github.com/zeirash/recapo/arion/common/logger"
 	"github.com/zeirash/recapo/arion/common/middleware"
+	"github.com/zeirash/recapo/arion/common/ratelimit"
+	"github.com/zeirash/recapo/arion/common/storage"
 	"github.com/zeirash/recapo/arion/handler"
 
 	_ "github.com/zeirash/recapo/arion/docs" // swagger docs
@@ -75,7 +79,11 @@ func NewRouter() *mux.Router {
 	r.HandleFunc("/health", handler.HealthHandler)
 	r.HandleFunc("/plans", handler.GetPlansHandler).Methods("GET")
 	r.HandleFunc("/public/shops/{share_token}/products", handler.GetShopProductsHandler).Methods("GET")
-	r.HandleFunc("/public/shops/{share_token}/order", handler.CreateShopTempOrderHandler).Methods("POST")
+	r.HandleFunc("/public/shops/{share_token}/challenge", handler.GetShopOrderChallengeHandler).Methods("GET")
+	r.Handle("/public/shops/{share_token}/order", middleware.ChainMiddleware(
+		middleware.RateLimit(ratelimit.New(constant.PublicOrderIPLimit, constant.PublicOrderIPWindow), middleware.ClientIP),
+		middleware.RateLimit(ratelimit.New(constant.PublicOrderTokenLimit, constant.PublicOrderTokenWindow), middleware.ShareToken),
+	)(http.HandlerFunc(handler.CreateShopTempOrderHandler))).Methods("POST")
 
 	r.HandleFunc("/login", handler.LoginHandler).Methods("POST")
 	r.HandleFunc("/send_otp", handler.SendOTPHandler).Methods("POST")
@@ -108,9 +116,29 @@ func NewRouter() *mux.Router {
 	r.Handle("/customers/{customer_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteCustomerHandler))).Methods("DELETE")
 	r.Handle("/customers/{customer_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetCustomerHandler))).Methods("GET")
 	r.Handle("/customers/check_active_order", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CustomerCheckActiveOrderHandler))).Methods("POST")
+	r.Handle("/customers/merge", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.MergeCustomersHandler))).Methods("POST")
+	r.Handle("/customers/{customer_id}/anonymize", middleware.ChainMiddleware(middleware.Authentication)(http.HandlerFunc(handler.AnonymizeCustomerHandler))).Methods("POST")
+	r.Handle("/customers/import", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ImportCustomersHandler))).Methods("POST")
+	r.Handle("/customers/{customer_id}/credits", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetCustomerCreditsHandler))).Methods("GET")
+	r.Handle("/customers/{customer_id}/credit", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CreateCustomerCreditHandler))).Methods("POST")
+	r.Handle("/customers/{customer_id}/addresses", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetCustomerAddressesHandler))).Methods("GET")
+	r.Handle("/customers/{customer_id}/address", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CreateCustomerAddressHandler))).Methods("POST")
+	r.Handle("/customers/{customer_id}/addresses/{address_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateCustomerAddressHandler))).Methods("PATCH")
+	r.Handle("/customers/{customer_id}/addresses/{address_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteCustomerAddressHandler))).Methods("DELETE")
 
 	// Shop
+	r.Handle("/shop", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetShopHandler))).Methods("GET")
+	r.Handle("/shop", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateShopHandler))).Methods("PATCH")
+	r.Handle("/shop/logo", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UploadShopLogoHandler))).Methods("POST")
 	r.Handle("/shop/share_token", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetShopShareTokenHandler))).Methods("GET")
+	r.Handle("/shop/export", middleware.ChainMiddleware(middleware.Authentication)(http.HandlerFunc(handler.ExportShopHandler))).Methods("GET")
+	r.Handle("/shop/close", middleware.ChainMiddleware(middleware.Authentication)(http.HandlerFunc(handler.CloseShopHandler))).Methods("POST")
+
+	// Share Link
+	r.Handle("/share_link", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CreateShareLinkHandler))).Methods("POST")
+	r.Handle("/share_links", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetShareLinksHandler))).Methods("GET")
+	r.Handle("/share_links/{share_link_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.RevokeShareLinkHandler))).Methods("DELETE")
+	r.Handle("/share_links/{share_link_id}/regenerate", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.RegenerateShareLinkHandler))).Methods("POST")
 
 	// For Product (register literal paths before /products/{product_id} so they match first)
 	r.Handle("/product", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CreateProductHandler))).Methods("POST")
@@ -118,16 +146,37 @@ func NewRouter() *mux.Router {
 	r.Handle("/products/activate_all", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ActivateAllProductsHandler))).Methods("PATCH")
 	r.Handle("/products/deactivate_all", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeactivateAllProductsHandler))).Methods("PATCH")
 	r.Handle("/products/purchase_list", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.PurchaseListProductHandler))).Methods("GET")
+	r.Handle("/products/purchase_list", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ResetPurchaseListHandler))).Methods("DELETE")
+	r.Handle("/products/purchase_list/allocate", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.AllocatePurchaseListHandler))).Methods("POST")
+	r.Handle("/products/purchase_list/{product_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdatePurchaseListItemHandler))).Methods("PATCH")
+	r.Handle("/products/purchase_list/{product_id}/shortage", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.AllocateShortageHandler))).Methods("POST")
+	r.Handle("/products/reorder", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ReorderProductsHandler))).Methods("POST")
+	r.Handle("/products/import", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ImportProductsHandler))).Methods("POST")
 	r.Handle("/products/image", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UploadProductImageHandler))).Methods("POST")
 	r.Handle("/products/image", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteProductImageHandler))).Methods("DELETE")
 	r.Handle("/products/{product_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateProductHandler))).Methods("PATCH")
 	r.Handle("/products/{product_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteProductHandler))).Methods("DELETE")
 	r.Handle("/products/{product_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetProductHandler))).Methods("GET")
+	r.Handle("/products/{product_id}/images", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.AddProductImageHandler))).Methods("POST")
+	r.Handle("/products/{product_id}/images", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetProductImagesHandler))).Methods("GET")
+	r.Handle("/products/{product_id}/images/reorder", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ReorderProductImagesHandler))).Methods("POST")
+	r.Handle("/products/{product_id}/images/{image_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateProductImageHandler))).Methods("PATCH")
+	r.Handle("/products/{product_id}/images/{image_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteProductImageByIDHandler))).Methods("DELETE")
+
+	// Product category
+	r.Handle("/product_category", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CreateProductCategoryHandler))).Methods("POST")
+	r.Handle("/product_categories", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetProductCategoriesHandler))).Methods("GET")
+	r.Handle("/product_categories/reorder", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ReorderProductCategoriesHandler))).Methods("POST")
+	r.Handle("/product_categories/{category_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateProductCategoryHandler))).Methods("PATCH")
+	r.Handle("/product_categories/{category_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteProductCategoryHandler))).Methods("DELETE")
 
 	// Order
 	r.Handle("/order", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CreateOrderHandler))).Methods("POST")
 	r.Handle("/orders", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetOrdersHandler))).Methods("GET")
 	r.Handle("/orders/stats", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetOrderStatsHandler))).Methods("GET")
+	r.Handle("/orders/stats/timeseries", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetOrderTimeseriesHandler))).Methods("GET")
+	r.Handle("/orders/documents", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GenerateOrderDocumentsHandler))).Methods("POST")
+	r.Handle("/orders/refund_proof", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UploadRefundProofHandler))).Methods("POST")
 	r.Handle("/orders/{order_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateOrderHandler))).Methods("PATCH")
 	r.Handle("/orders/{order_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteOrderHandler))).Methods("DELETE")
 	r.Handle("/orders/{order_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetOrderHandler))).Methods("GET")
@@ -142,6 +191,26 @@ func NewRouter() *mux.Router {
 	r.Handle("/orders/{order_id}/payments", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteOrderPaymentsHandler))).Methods("DELETE")
 	r.Handle("/orders/{order_id}/payments/{payment_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateOrderPaymentAmountHandler))).Methods("PATCH")
 	r.Handle("/orders/{order_id}/payments/{payment_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteOrderPaymentHandler))).Methods("DELETE")
+	r.Handle("/orders/{order_id}/apply_credit", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ApplyCustomerCreditHandler))).Methods("POST")
+	r.Handle("/orders/{order_id}/carry_over", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CarryOverOverpaymentHandler))).Methods("POST")
+	r.Handle("/orders/{order_id}/dp_rule", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ApplyDPRuleHandler))).Methods("POST")
+	r.Handle("/orders/{order_id}/cancel", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CancelOrderHandler))).Methods("POST")
+	r.Handle("/orders/{order_id}/refund", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.RefundOrderHandler))).Methods("POST")
+	r.Handle("/orders/{order_id}/refunds", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetOrderRefundsHandler))).Methods("GET")
+	r.Handle("/orders/{order_id}/shortage_notified", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ClearShortageNotifyHandler))).Methods("POST")
+
+	// DP Rule
+	r.Handle("/dp_rule", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.CreateDPRuleHandler))).Methods("POST")
+	r.Handle("/dp_rules", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetDPRulesHandler))).Methods("GET")
+	r.Handle("/dp_rules/{dp_rule_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.UpdateDPRuleHandler))).Methods("PATCH")
+	r.Handle("/dp_rules/{dp_rule_id}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.DeleteDPRuleHandler))).Methods("DELETE")
+
+	// Bank Statement
+	r.Handle("/bank_statements/match", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.MatchBankStatementHandler))).Methods("POST")
+	r.Handle("/bank_statements/confirm", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.ConfirmPaymentMatchesHandler))).Methods("POST")
+
+	// Report
+	r.Handle("/reports/{report}", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetReportHandler))).Methods("GET")
 
 	// Temp Order
 	r.Handle("/temp_orders", middleware.ChainMiddleware(middleware.Authentication, middleware.SubscriptionCheck)(http.HandlerFunc(handler.GetTempOrdersHandler))).Methods("GET")
@@ -157,12 +226,16 @@ func NewRouter() *mux.Router {
 	// r.Handle("/system/users", middleware.ChainMiddleware(middleware.Authentication, middleware.CheckSystemMode)(http.HandlerFunc(handler.GetUsersHandler))).Methods("GET")
 	r.Handle("/system/stats", middleware.ChainMiddleware(middleware.Authentication, middleware.CheckSystemMode)(http.HandlerFunc(handler.GetSystemStatsHandler))).Methods("GET")
 	r.Handle("/system/shops", middleware.ChainMiddleware(middleware.Authentication, middleware.CheckSystemMode)(http.HandlerFunc(handler.GetSystemShopsHandler))).Methods("GET")
+	r.Handle("/system/shops/{shop_id}/import", middleware.ChainMiddleware(middleware.Authentication, middleware.CheckSystemMode)(http.HandlerFunc(handler.ImportShopArchiveHandler))).Methods("POST")
+	r.Handle("/system/shops/{shop_id}/reopen", middleware.ChainMiddleware(middleware.Authentication, middleware.CheckSystemMode)(http.HandlerFunc(handler.ReopenShopHandler))).Methods("POST")
 	r.Handle("/system/payments", middleware.ChainMiddleware(middleware.Authentication, middleware.CheckSystemMode)(http.HandlerFunc(handler.GetSystemPaymentsHandler))).Methods("GET")
+	r.Handle("/system/uploads/gc", middleware.ChainMiddleware(middleware.Authentication, middleware.CheckSystemMode)(http.HandlerFunc(handler.CollectUnreferencedUploadsHandler))).Methods("POST")
 
 
-	// Static file serving for uploaded product images
-	uploadDir := config.GetConfig().UploadDir
-	r.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir(uploadDir))))
+	// Static file serving for uploads kept on the local filesystem
+	if local, ok := storage.New(config.GetConfig()).(*storage.Local); ok {
+		r.PathPrefix(storage.LocalURLPrefix + "/").Handler(http.StripPrefix(storage.LocalURLPrefix+"/", local))
+	}
 
 	return r
 }
@@ -0,0 +1,12 @@
+-- Opt-in unique transfer code (kode unik) added on top of order totals.
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS unique_code_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS unique_code_as_fee BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS unique_code INT NOT NULL DEFAULT 0;
+
+-- A code may only be held by one open (unpaid, not cancelled) order per shop.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_shop_open_unique_code
+    ON orders (shop_id, unique_code)
+    WHERE unique_code > 0 AND payment_status <> 'paid' AND status <> 'cancelled';
@@ -0,0 +1,14 @@
+-- Per-customer credit ledger. Positive amounts add to the customer's balance
+-- (deposits, overpayment carry-over, refunds), negative amounts spend it.
+CREATE TABLE IF NOT EXISTS customer_credits (
+    id          SERIAL PRIMARY KEY,
+    shop_id     INT NOT NULL REFERENCES shops(id),
+    customer_id INT NOT NULL REFERENCES customers(id),
+    order_id    INT REFERENCES orders(id) ON DELETE SET NULL,
+    type        VARCHAR(20) NOT NULL,
+    amount      INT NOT NULL,
+    notes       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_credits_customer_id ON customer_credits (customer_id);
@@ -0,0 +1,33 @@
+-- Down-payment (DP) rules. A rule either gives a number of days after the
+-- order is created or a fixed deadline (e.g. the buying date of a trip).
+CREATE TABLE IF NOT EXISTS dp_rules (
+    id          SERIAL PRIMARY KEY,
+    shop_id     INT NOT NULL REFERENCES shops(id),
+    name        VARCHAR(100) NOT NULL,
+    percent     INT NOT NULL CHECK (percent BETWEEN 1 AND 100),
+    due_days    INT,
+    due_date    TIMESTAMP,
+    auto_cancel BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP,
+    CHECK ((due_days IS NULL) <> (due_date IS NULL))
+);
+
+-- At most one default rule per shop; it is applied to new orders.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dp_rules_shop_default
+    ON dp_rules (shop_id)
+    WHERE is_default;
+
+-- Orders keep a snapshot of the rule they were given, so editing a rule
+-- does not move deadlines of existing orders.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS dp_rule_id INT REFERENCES dp_rules(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS dp_percent INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dp_due_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS dp_auto_cancel BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS dp_overdue_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_orders_dp_due_at
+    ON orders (dp_due_at)
+    WHERE dp_percent > 0 AND dp_overdue_at IS NULL;
@@ -0,0 +1,23 @@
+-- Cancellation reason on orders. Cancelled orders drop out of the purchase
+-- list, which only counts created and in_progress orders.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS cancel_reason VARCHAR(30),
+    ADD COLUMN IF NOT EXISTS cancel_notes TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+
+-- Refunds paid back to the customer. Each refund is booked as a negative
+-- order payment, so the payment ledger stays the source of the paid amount;
+-- this table keeps the reason, method and proof.
+CREATE TABLE IF NOT EXISTS order_refunds (
+    id         SERIAL PRIMARY KEY,
+    order_id   INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    payment_id INT NOT NULL REFERENCES order_payments(id) ON DELETE CASCADE,
+    amount     INT NOT NULL CHECK (amount > 0),
+    method     VARCHAR(20) NOT NULL,
+    reason     VARCHAR(30) NOT NULL,
+    proof_url  TEXT NOT NULL DEFAULT '',
+    notes      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_refunds_order_id ON order_refunds (order_id);
@@ -0,0 +1,25 @@
+-- Shopping progress on the purchase list, one row per product. The list itself
+-- is still derived from open orders; a product without a row is pending.
+-- actual_cost is the total paid for bought_qty units.
+CREATE TABLE IF NOT EXISTS purchase_list_items (
+    id          SERIAL PRIMARY KEY,
+    shop_id     INT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    product_id  INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    status      VARCHAR(20) NOT NULL DEFAULT 'pending',
+    bought_qty  INT NOT NULL DEFAULT 0 CHECK (bought_qty >= 0),
+    actual_cost INT NOT NULL DEFAULT 0 CHECK (actual_cost >= 0),
+    notes       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP,
+    UNIQUE (shop_id, product_id)
+);
+
+-- Units cut from an order item because fewer could be bought than were ordered.
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS shortage_qty INT NOT NULL DEFAULT 0;
+
+-- Set when a shortage changed the order, cleared once the customer was told.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS shortage_notify BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_orders_shortage_notify ON orders (shop_id) WHERE shortage_notify;
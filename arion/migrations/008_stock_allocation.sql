@@ -0,0 +1,8 @@
+-- Units handed to an order item by the last stock allocation. NULL until the
+-- item has been allocated; qty is lowered to the same value at that point.
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS allocated_qty INT CHECK (allocated_qty >= 0);
+
+-- Customers with a higher priority are served first by the priority policy.
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0;
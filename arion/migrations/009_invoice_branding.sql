@@ -0,0 +1,22 @@
+-- Shop branding printed on invoices. bank_accounts is free text, one account
+-- per line (e.g. "BCA 1234567890 a.n. Toko Recapo").
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS logo_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS bank_accounts TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS invoice_footer TEXT NOT NULL DEFAULT '';
+
+-- Per-shop document counters. A number is taken in the same transaction that
+-- stores it on the document, so a failed export leaves no gap.
+CREATE TABLE IF NOT EXISTS document_sequences (
+    shop_id    INT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    doc_type   VARCHAR(20) NOT NULL,
+    last_value INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (shop_id, doc_type)
+);
+
+-- Invoice number within the shop, assigned on the first export.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS invoice_seq INT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_shop_invoice_seq ON orders (shop_id, invoice_seq) WHERE invoice_seq IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Formatted per-shop invoice numbers.
+-- invoice_number_pattern tokens: {YYYY}, {YY}, {MM}, {DD}, {seq} and {seq:N}
+-- (sequence zero-padded to N digits). The sequence itself never resets.
+
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS invoice_number_pattern TEXT NOT NULL DEFAULT 'INV/{YYYY}/{MM}/{seq}';
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS invoice_number TEXT;
+
+-- Invoices exported before this migration printed the bare sequence value.
+UPDATE orders SET invoice_number = invoice_seq::text WHERE invoice_seq IS NOT NULL AND invoice_number IS NULL;
@@ -0,0 +1,23 @@
+-- Shop timezone (IANA name) and locale. Date filters, stats buckets, invoice
+-- dates and DP deadlines are evaluated in the shop's timezone; invoices and
+-- other customer-facing documents are printed in the shop's locale.
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'Asia/Jakarta',
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'id';
+
+-- Store every timestamp with its offset so the API can return explicit
+-- offsets whatever the server's timezone. Existing values were written by
+-- servers running in UTC; change 'UTC' below if yours ran elsewhere.
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = 'public' AND data_type = 'timestamp without time zone'
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE ''UTC''',
+            col.table_name, col.column_name, col.column_name);
+    END LOOP;
+END $$;
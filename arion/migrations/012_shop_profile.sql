@@ -0,0 +1,11 @@
+-- Shop profile shown on the public storefront, and the defaults printed on
+-- invoices. whatsapp is stored as international digits (e.g. 6281234567890)
+-- so the storefront can link to wa.me; instagram is the handle without "@".
+-- invoice_message is printed when an export does not bring its own message.
+
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS whatsapp TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS instagram TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS invoice_message TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'IDR';
@@ -0,0 +1,34 @@
+-- Storefront share links. A shop can have several links, each with its own
+-- token, an optional label (e.g. the Instagram post it was shared on), an
+-- optional expiry and an optional product subset. Revoked links are kept so
+-- the temp orders that came through them stay attributed.
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id          SERIAL PRIMARY KEY,
+    shop_id     INT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    token       TEXT NOT NULL UNIQUE,
+    label       TEXT NOT NULL DEFAULT '',
+    expires_at  TIMESTAMPTZ,
+    revoked_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_shop_id ON share_links (shop_id);
+
+-- A link without rows here shows all of the shop's active products.
+CREATE TABLE IF NOT EXISTS share_link_products (
+    share_link_id INT NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+    product_id    INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    PRIMARY KEY (share_link_id, product_id)
+);
+
+ALTER TABLE temp_orders
+    ADD COLUMN IF NOT EXISTS share_link_id INT REFERENCES share_links(id);
+
+-- Every shop keeps its current token as its first link.
+INSERT INTO share_links (shop_id, token, created_at)
+SELECT id, share_token, created_at
+FROM shops
+WHERE share_token IS NOT NULL
+ON CONFLICT (token) DO NOTHING;
@@ -0,0 +1,7 @@
+-- Fingerprint of a public temp order: the buyer's phone digits and the
+-- ordered items. The same fingerprint for a shop within a few minutes is a
+-- double submission and is rejected.
+
+ALTER TABLE temp_orders ADD COLUMN IF NOT EXISTS fingerprint TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_temp_orders_shop_fingerprint ON temp_orders (shop_id, fingerprint, created_at);
@@ -0,0 +1,99 @@
+-- Rewrite customer and temp order phones to E.164 (+62… for Indonesian
+-- numbers), the form the app now stores. Live customers of a shop whose phones
+-- normalize to the same number are merged into the oldest one: their orders
+-- and credit entries move over and the duplicates are soft-deleted. Phones
+-- that can't be parsed are left as they are.
+--
+-- A customer has at most one open order, so customers of which more than one
+-- has an open order are not merged and keep their phones as they are, for the
+-- seller to merge once only one of the orders is still open.
+--
+-- pg_temp.normalize_phone follows the rules of common/phone.Normalize.
+
+BEGIN;
+
+CREATE FUNCTION pg_temp.normalize_phone(raw TEXT) RETURNS TEXT AS $$
+DECLARE
+    trimmed TEXT := btrim(raw);
+    has_plus BOOLEAN := left(btrim(raw), 1) = '+';
+    digits TEXT;
+BEGIN
+    IF has_plus THEN
+        trimmed := substr(trimmed, 2);
+    END IF;
+    IF trimmed !~ '^[0-9 ./()-]*$' THEN
+        RETURN raw;
+    END IF;
+    digits := regexp_replace(trimmed, '[^0-9]', '', 'g');
+
+    IF has_plus THEN
+        NULL;
+    ELSIF digits LIKE '00%' THEN
+        digits := substr(digits, 3);
+    ELSIF digits LIKE '0%' THEN
+        digits := '62' || substr(digits, 2);
+    ELSIF digits LIKE '8%' THEN
+        digits := '62' || digits;
+    END IF;
+
+    IF digits LIKE '620%' THEN
+        digits := '62' || substr(digits, 4);
+    END IF;
+
+    IF length(digits) NOT BETWEEN 8 AND 15 OR digits LIKE '0%' THEN
+        RETURN raw;
+    END IF;
+
+    RETURN '+' || digits;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE TEMP TABLE customer_phone_merges ON COMMIT DROP AS
+SELECT id AS duplicate_id,
+       first_value(id) OVER (PARTITION BY shop_id, pg_temp.normalize_phone(phone) ORDER BY created_at, id) AS survivor_id
+FROM customers
+WHERE deleted_at IS NULL;
+
+DELETE FROM customer_phone_merges m
+WHERE m.duplicate_id = m.survivor_id
+   OR (
+       SELECT count(DISTINCT o.customer_id)
+       FROM customer_phone_merges g
+       JOIN orders o ON o.customer_id = g.duplicate_id
+       WHERE g.survivor_id = m.survivor_id AND o.status IN ('created', 'in_progress')
+   ) > 1;
+
+UPDATE orders o
+SET customer_id = m.survivor_id
+FROM customer_phone_merges m
+WHERE o.customer_id = m.duplicate_id;
+
+UPDATE customer_credits cc
+SET customer_id = m.survivor_id
+FROM customer_phone_merges m
+WHERE cc.customer_id = m.duplicate_id;
+
+UPDATE customers c
+SET deleted_at = now()
+FROM customer_phone_merges m
+WHERE c.id = m.duplicate_id;
+
+-- Skip a phone another row of the shop already holds verbatim, so a
+-- soft-deleted customer can't make the update trip the unique constraint, or
+-- that another live customer left unmerged above normalizes to as well.
+UPDATE customers c
+SET phone = pg_temp.normalize_phone(c.phone), updated_at = now()
+WHERE c.deleted_at IS NULL
+  AND c.phone <> pg_temp.normalize_phone(c.phone)
+  AND NOT EXISTS (
+      SELECT 1 FROM customers d
+      WHERE d.shop_id = c.shop_id AND d.id <> c.id
+        AND (d.phone = pg_temp.normalize_phone(c.phone)
+             OR (d.deleted_at IS NULL AND pg_temp.normalize_phone(d.phone) = pg_temp.normalize_phone(c.phone)))
+  );
+
+UPDATE temp_orders
+SET customer_phone = pg_temp.normalize_phone(customer_phone)
+WHERE customer_phone <> pg_temp.normalize_phone(customer_phone);
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Free-form customer tags ("VIP", "reseller", "late payer") and a block
+-- flag. Public temp orders from a blocked customer's phone are refused.
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS blocked BOOLEAN NOT NULL DEFAULT false;
+
+-- Lifetime stats aggregate a customer's orders.
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders (customer_id);
@@ -0,0 +1,30 @@
+-- Customer address book. A customer can keep several labelled shipping
+-- addresses, one of which is the default for new orders.
+CREATE TABLE IF NOT EXISTS customer_addresses (
+    id           SERIAL PRIMARY KEY,
+    shop_id      INT NOT NULL REFERENCES shops(id),
+    customer_id  INT NOT NULL REFERENCES customers(id),
+    label        VARCHAR(50) NOT NULL DEFAULT '',
+    recipient    VARCHAR(100) NOT NULL,
+    phone        VARCHAR(20) NOT NULL DEFAULT '',
+    address      TEXT NOT NULL,
+    province     VARCHAR(100) NOT NULL DEFAULT '',
+    city         VARCHAR(100) NOT NULL DEFAULT '',
+    district     VARCHAR(100) NOT NULL DEFAULT '',
+    postal_code  VARCHAR(10) NOT NULL DEFAULT '',
+    is_default   BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_addresses_customer_id ON customer_addresses (customer_id);
+
+-- At most one default address per customer.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_addresses_default
+    ON customer_addresses (customer_id)
+    WHERE is_default;
+
+-- Orders and temp orders keep a copy of the address they ship to, so editing
+-- or deleting an address book entry does not rewrite shipping history.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_address JSONB;
+ALTER TABLE temp_orders ADD COLUMN IF NOT EXISTS shipping_address JSONB;
@@ -0,0 +1,12 @@
+-- Shop closure. A closed shop is soft-deleted: its users can't log in and
+-- its storefront is gone. Once the grace period is over its data and users
+-- are purged; the emptied shop row stays, with purged_at set, so the
+-- subscription payments that point at it are kept.
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS purged_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_shops_closed_at ON shops (closed_at) WHERE closed_at IS NOT NULL AND purged_at IS NULL;
+
+-- Customers whose personal data was erased. Their orders and totals stay.
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ;
@@ -0,0 +1,26 @@
+-- Shop-defined product categories. A category can sit under a top-level
+-- category; position orders categories among their siblings.
+CREATE TABLE IF NOT EXISTS product_categories (
+    id          SERIAL PRIMARY KEY,
+    shop_id     INT NOT NULL REFERENCES shops(id),
+    parent_id   INT REFERENCES product_categories(id),
+    name        VARCHAR(50) NOT NULL,
+    position    INT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_categories_shop_id ON product_categories (shop_id);
+
+-- Category names are unique among siblings, case-insensitively.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_categories_name
+    ON product_categories (shop_id, COALESCE(parent_id, 0), LOWER(name));
+
+-- Products get a category, free-form tags and a position that orders them
+-- within their category.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS category_id INT REFERENCES product_categories(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products (category_id);
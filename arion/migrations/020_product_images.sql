@@ -0,0 +1,29 @@
+-- Product image collections. Each image is stored as resized WebP variants;
+-- position orders a product's images and one of them is primary.
+-- products.image_url keeps the full-size URL of the primary image.
+CREATE TABLE IF NOT EXISTS product_images (
+    id             SERIAL PRIMARY KEY,
+    product_id     INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    shop_id        INT NOT NULL REFERENCES shops(id),
+    thumbnail_url  TEXT NOT NULL,
+    medium_url     TEXT NOT NULL,
+    full_url       TEXT NOT NULL,
+    position       INT NOT NULL DEFAULT 0,
+    is_primary     BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images (product_id);
+CREATE INDEX IF NOT EXISTS idx_product_images_shop_id ON product_images (shop_id);
+
+-- At most one primary image per product.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_images_primary
+    ON product_images (product_id) WHERE is_primary;
+
+-- Existing single images become the primary image of their product. They
+-- were stored unprocessed, so every variant points at the original file.
+INSERT INTO product_images (product_id, shop_id, thumbnail_url, medium_url, full_url, position, is_primary)
+SELECT p.id, p.shop_id, p.image_url, p.image_url, p.image_url, 0, TRUE
+FROM products p
+WHERE p.image_url <> '' AND p.deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM product_images i WHERE i.product_id = p.id);
@@ -0,0 +1,13 @@
+-- Every file stored through the storage backend, by key. A file is in use
+-- while a row elsewhere holds its URL (shop logos, product images, refund
+-- proofs); the upload GC deletes the files that have been unused for a while.
+-- Files stored before this table existed are not tracked and are never
+-- collected.
+CREATE TABLE IF NOT EXISTS uploaded_objects (
+    key           TEXT PRIMARY KEY,
+    size          BIGINT NOT NULL,
+    content_type  TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploaded_objects_created_at ON uploaded_objects (created_at);
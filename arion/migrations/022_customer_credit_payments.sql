@@ -0,0 +1,8 @@
+-- The order payment booked together with a credit entry (credit spent on an
+-- order, an overpayment carried over, a refund to credit). Such a payment
+-- can't be deleted while its credit entry stands. Entries from before this
+-- column only know their order, so they hold every payment of that order.
+ALTER TABLE customer_credits
+    ADD COLUMN IF NOT EXISTS payment_id INT REFERENCES order_payments(id);
+
+CREATE INDEX IF NOT EXISTS idx_customer_credits_order_id ON customer_credits (order_id);
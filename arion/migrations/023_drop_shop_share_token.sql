@@ -0,0 +1,10 @@
+-- A shop's storefront tokens live in share_links. Shops created after 013
+-- got a token in shops.share_token that was never added there, so it led
+-- nowhere; it becomes the shop's default link before the column goes.
+INSERT INTO share_links (shop_id, token, created_at)
+SELECT id, share_token, created_at
+FROM shops
+WHERE share_token IS NOT NULL
+ON CONFLICT (token) DO NOTHING;
+
+ALTER TABLE shops DROP COLUMN IF EXISTS share_token;
@@ -0,0 +1,14 @@
+-- Bank statement credits confirmed as order payments, so the same line of a
+-- statement can't be recorded twice. Bank exports carry no transaction id; a
+-- line is known by its date, description and amount. Deleting the payment
+-- frees the line to be matched again.
+CREATE TABLE IF NOT EXISTS bank_statement_lines (
+    id          SERIAL PRIMARY KEY,
+    shop_id     INT NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    payment_id  INT NOT NULL REFERENCES order_payments(id) ON DELETE CASCADE,
+    date        DATE NOT NULL,
+    description TEXT NOT NULL,
+    amount      INT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (shop_id, date, description, amount)
+);
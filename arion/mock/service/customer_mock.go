@@ -37,6 +37,21 @@ func (m *MockCustomerService) EXPECT() *MockCustomerServiceMockRecorder {
 	return m.recorder
 }
 
+// AnonymizeCustomer mocks base method.
+func (m *MockCustomerService) AnonymizeCustomer(ctx context.Context, customerID, shopID int) (response.CustomerData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeCustomer", ctx, customerID, shopID)
+	ret0, _ := ret[0].(response.CustomerData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnonymizeCustomer indicates an expected call of AnonymizeCustomer.
+func (mr *MockCustomerServiceMockRecorder) AnonymizeCustomer(ctx, customerID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeCustomer", reflect.TypeOf((*MockCustomerService)(nil).AnonymizeCustomer), ctx, customerID, shopID)
+}
+
 // CheckActiveOrderByPhone mocks base method.
 func (m *MockCustomerService) CheckActiveOrderByPhone(ctx context.Context, phone, name string, shopID int) (response.CustomerCheckActiveOrderByPhone, error) {
 	m.ctrl.T.Helper()
@@ -67,6 +82,50 @@ func (mr *MockCustomerServiceMockRecorder) CreateCustomer(ctx, name, phone, addr
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCustomer", reflect.TypeOf((*MockCustomerService)(nil).CreateCustomer), ctx, name, phone, address, shopID)
 }
 
+// CreateCustomerAddress mocks base method.
+func (m *MockCustomerService) CreateCustomerAddress(ctx context.Context, input service.CreateCustomerAddressInput) (response.CustomerAddressData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCustomerAddress", ctx, input)
+	ret0, _ := ret[0].(response.CustomerAddressData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCustomerAddress indicates an expected call of CreateCustomerAddress.
+func (mr *MockCustomerServiceMockRecorder) CreateCustomerAddress(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCustomerAddress", reflect.TypeOf((*MockCustomerService)(nil).CreateCustomerAddress), ctx, input)
+}
+
+// CreateCustomerCredit mocks base method.
+func (m *MockCustomerService) CreateCustomerCredit(ctx context.Context, input service.CreateCustomerCreditInput) (response.CustomerCreditData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCustomerCredit", ctx, input)
+	ret0, _ := ret[0].(response.CustomerCreditData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCustomerCredit indicates an expected call of CreateCustomerCredit.
+func (mr *MockCustomerServiceMockRecorder) CreateCustomerCredit(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCustomerCredit", reflect.TypeOf((*MockCustomerService)(nil).CreateCustomerCredit), ctx, input)
+}
+
+// DeleteCustomerAddress mocks base method.
+func (m *MockCustomerService) DeleteCustomerAddress(ctx context.Context, id, customerID, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCustomerAddress", ctx, id, customerID, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCustomerAddress indicates an expected call of DeleteCustomerAddress.
+func (mr *MockCustomerServiceMockRecorder) DeleteCustomerAddress(ctx, id, customerID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCustomerAddress", reflect.TypeOf((*MockCustomerService)(nil).DeleteCustomerAddress), ctx, id, customerID, shopID)
+}
+
 // DeleteCustomerByID mocks base method.
 func (m *MockCustomerService) DeleteCustomerByID(ctx context.Context, id int) error {
 	m.ctrl.T.Helper()
@@ -81,6 +140,21 @@ func (mr *MockCustomerServiceMockRecorder) DeleteCustomerByID(ctx, id interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCustomerByID", reflect.TypeOf((*MockCustomerService)(nil).DeleteCustomerByID), ctx, id)
 }
 
+// GetCustomerAddresses mocks base method.
+func (m *MockCustomerService) GetCustomerAddresses(ctx context.Context, customerID, shopID int) ([]response.CustomerAddressData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCustomerAddresses", ctx, customerID, shopID)
+	ret0, _ := ret[0].([]response.CustomerAddressData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCustomerAddresses indicates an expected call of GetCustomerAddresses.
+func (mr *MockCustomerServiceMockRecorder) GetCustomerAddresses(ctx, customerID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerAddresses", reflect.TypeOf((*MockCustomerService)(nil).GetCustomerAddresses), ctx, customerID, shopID)
+}
+
 // GetCustomerByID mocks base method.
 func (m *MockCustomerService) GetCustomerByID(ctx context.Context, customerID int, shopID ...int) (*response.CustomerData, error) {
 	m.ctrl.T.Helper()
@@ -101,8 +175,23 @@ func (mr *MockCustomerServiceMockRecorder) GetCustomerByID(ctx, customerID inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerByID", reflect.TypeOf((*MockCustomerService)(nil).GetCustomerByID), varargs...)
 }
 
+// GetCustomerCredits mocks base method.
+func (m *MockCustomerService) GetCustomerCredits(ctx context.Context, customerID, shopID int) (response.CustomerCreditsData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCustomerCredits", ctx, customerID, shopID)
+	ret0, _ := ret[0].(response.CustomerCreditsData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCustomerCredits indicates an expected call of GetCustomerCredits.
+func (mr *MockCustomerServiceMockRecorder) GetCustomerCredits(ctx, customerID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerCredits", reflect.TypeOf((*MockCustomerService)(nil).GetCustomerCredits), ctx, customerID, shopID)
+}
+
 // GetCustomersByShopID mocks base method.
-func (m *MockCustomerService) GetCustomersByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]response.CustomerData, error) {
+func (m *MockCustomerService) GetCustomersByShopID(ctx context.Context, shopID int, filter model.CustomerFilterOptions) ([]response.CustomerData, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetCustomersByShopID", ctx, shopID, filter)
 	ret0, _ := ret[0].([]response.CustomerData)
@@ -116,6 +205,21 @@ func (mr *MockCustomerServiceMockRecorder) GetCustomersByShopID(ctx, shopID, fil
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomersByShopID", reflect.TypeOf((*MockCustomerService)(nil).GetCustomersByShopID), ctx, shopID, filter)
 }
 
+// MergeCustomers mocks base method.
+func (m *MockCustomerService) MergeCustomers(ctx context.Context, shopID, survivorID int, duplicateIDs []int) (response.CustomerData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeCustomers", ctx, shopID, survivorID, duplicateIDs)
+	ret0, _ := ret[0].(response.CustomerData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeCustomers indicates an expected call of MergeCustomers.
+func (mr *MockCustomerServiceMockRecorder) MergeCustomers(ctx, shopID, survivorID, duplicateIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeCustomers", reflect.TypeOf((*MockCustomerService)(nil).MergeCustomers), ctx, shopID, survivorID, duplicateIDs)
+}
+
 // UpdateCustomer mocks base method.
 func (m *MockCustomerService) UpdateCustomer(ctx context.Context, input service.UpdateCustomerInput) (response.CustomerData, error) {
 	m.ctrl.T.Helper()
@@ -130,3 +234,18 @@ func (mr *MockCustomerServiceMockRecorder) UpdateCustomer(ctx, input interface{}
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCustomer", reflect.TypeOf((*MockCustomerService)(nil).UpdateCustomer), ctx, input)
 }
+
+// UpdateCustomerAddress mocks base method.
+func (m *MockCustomerService) UpdateCustomerAddress(ctx context.Context, input service.UpdateCustomerAddressInput) (response.CustomerAddressData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCustomerAddress", ctx, input)
+	ret0, _ := ret[0].(response.CustomerAddressData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCustomerAddress indicates an expected call of UpdateCustomerAddress.
+func (mr *MockCustomerServiceMockRecorder) UpdateCustomerAddress(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCustomerAddress", reflect.TypeOf((*MockCustomerService)(nil).UpdateCustomerAddress), ctx, input)
+}
@@ -6,6 +6,7 @@ package mock_service
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -37,19 +38,93 @@ func (m *MockOrderService) EXPECT() *MockOrderServiceMockRecorder {
 	return m.recorder
 }
 
+// ApplyCustomerCredit mocks base method.
+func (m *MockOrderService) ApplyCustomerCredit(ctx context.Context, orderID, shopID, amount int) (response.OrderPaymentData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyCustomerCredit", ctx, orderID, shopID, amount)
+	ret0, _ := ret[0].(response.OrderPaymentData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyCustomerCredit indicates an expected call of ApplyCustomerCredit.
+func (mr *MockOrderServiceMockRecorder) ApplyCustomerCredit(ctx, orderID, shopID, amount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyCustomerCredit", reflect.TypeOf((*MockOrderService)(nil).ApplyCustomerCredit), ctx, orderID, shopID, amount)
+}
+
+// ApplyDPRule mocks base method.
+func (m *MockOrderService) ApplyDPRule(ctx context.Context, orderID, shopID, ruleID int) (response.OrderData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyDPRule", ctx, orderID, shopID, ruleID)
+	ret0, _ := ret[0].(response.OrderData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyDPRule indicates an expected call of ApplyDPRule.
+func (mr *MockOrderServiceMockRecorder) ApplyDPRule(ctx, orderID, shopID, ruleID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyDPRule", reflect.TypeOf((*MockOrderService)(nil).ApplyDPRule), ctx, orderID, shopID, ruleID)
+}
+
+// CancelOrder mocks base method.
+func (m *MockOrderService) CancelOrder(ctx context.Context, input service.CancelOrderInput) (response.OrderData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelOrder", ctx, input)
+	ret0, _ := ret[0].(response.OrderData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelOrder indicates an expected call of CancelOrder.
+func (mr *MockOrderServiceMockRecorder) CancelOrder(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOrder", reflect.TypeOf((*MockOrderService)(nil).CancelOrder), ctx, input)
+}
+
+// CarryOverOverpayment mocks base method.
+func (m *MockOrderService) CarryOverOverpayment(ctx context.Context, orderID, shopID int) (response.CustomerCreditData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CarryOverOverpayment", ctx, orderID, shopID)
+	ret0, _ := ret[0].(response.CustomerCreditData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CarryOverOverpayment indicates an expected call of CarryOverOverpayment.
+func (mr *MockOrderServiceMockRecorder) CarryOverOverpayment(ctx, orderID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CarryOverOverpayment", reflect.TypeOf((*MockOrderService)(nil).CarryOverOverpayment), ctx, orderID, shopID)
+}
+
+// ClearShortageNotify mocks base method.
+func (m *MockOrderService) ClearShortageNotify(ctx context.Context, orderID, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearShortageNotify", ctx, orderID, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearShortageNotify indicates an expected call of ClearShortageNotify.
+func (mr *MockOrderServiceMockRecorder) ClearShortageNotify(ctx, orderID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearShortageNotify", reflect.TypeOf((*MockOrderService)(nil).ClearShortageNotify), ctx, orderID, shopID)
+}
+
 // CreateOrder mocks base method.
-func (m *MockOrderService) CreateOrder(ctx context.Context, customerID, shopID int, notes *string) (response.OrderData, error) {
+func (m *MockOrderService) CreateOrder(ctx context.Context, customerID, shopID int, notes *string, addressID *int) (response.OrderData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrder", ctx, customerID, shopID, notes)
+	ret := m.ctrl.Call(m, "CreateOrder", ctx, customerID, shopID, notes, addressID)
 	ret0, _ := ret[0].(response.OrderData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateOrder indicates an expected call of CreateOrder.
-func (mr *MockOrderServiceMockRecorder) CreateOrder(ctx, customerID, shopID, notes interface{}) *gomock.Call {
+func (mr *MockOrderServiceMockRecorder) CreateOrder(ctx, customerID, shopID, notes, addressID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockOrderService)(nil).CreateOrder), ctx, customerID, shopID, notes)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockOrderService)(nil).CreateOrder), ctx, customerID, shopID, notes, addressID)
 }
 
 // CreateOrderItem mocks base method.
@@ -83,18 +158,18 @@ func (mr *MockOrderServiceMockRecorder) CreateOrderPayment(ctx, orderID, amount
 }
 
 // CreateTempOrder mocks base method.
-func (m *MockOrderService) CreateTempOrder(ctx context.Context, customerName, customerPhone, shareToken string, items []service.CreateTempOrderItemInput) (response.TempOrderData, error) {
+func (m *MockOrderService) CreateTempOrder(ctx context.Context, customerName, customerPhone, shareToken string, address *model.ShippingAddress, items []service.CreateTempOrderItemInput) (response.TempOrderData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTempOrder", ctx, customerName, customerPhone, shareToken, items)
+	ret := m.ctrl.Call(m, "CreateTempOrder", ctx, customerName, customerPhone, shareToken, address, items)
 	ret0, _ := ret[0].(response.TempOrderData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateTempOrder indicates an expected call of CreateTempOrder.
-func (mr *MockOrderServiceMockRecorder) CreateTempOrder(ctx, customerName, customerPhone, shareToken, items interface{}) *gomock.Call {
+func (mr *MockOrderServiceMockRecorder) CreateTempOrder(ctx, customerName, customerPhone, shareToken, address, items interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTempOrder", reflect.TypeOf((*MockOrderService)(nil).CreateTempOrder), ctx, customerName, customerPhone, shareToken, items)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTempOrder", reflect.TypeOf((*MockOrderService)(nil).CreateTempOrder), ctx, customerName, customerPhone, shareToken, address, items)
 }
 
 // DeleteOrderByID mocks base method.
@@ -153,19 +228,48 @@ func (mr *MockOrderServiceMockRecorder) DeleteOrderPaymentsByOrderID(ctx, orderI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrderPaymentsByOrderID", reflect.TypeOf((*MockOrderService)(nil).DeleteOrderPaymentsByOrderID), ctx, orderID)
 }
 
+// FlagOverdueDownPayments mocks base method.
+func (m *MockOrderService) FlagOverdueDownPayments(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlagOverdueDownPayments", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FlagOverdueDownPayments indicates an expected call of FlagOverdueDownPayments.
+func (mr *MockOrderServiceMockRecorder) FlagOverdueDownPayments(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlagOverdueDownPayments", reflect.TypeOf((*MockOrderService)(nil).FlagOverdueDownPayments), ctx)
+}
+
+// GenerateOrderDocuments mocks base method.
+func (m *MockOrderService) GenerateOrderDocuments(ctx context.Context, input service.GenerateOrderDocumentsInput) (service.OrderDocuments, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateOrderDocuments", ctx, input)
+	ret0, _ := ret[0].(service.OrderDocuments)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateOrderDocuments indicates an expected call of GenerateOrderDocuments.
+func (mr *MockOrderServiceMockRecorder) GenerateOrderDocuments(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateOrderDocuments", reflect.TypeOf((*MockOrderService)(nil).GenerateOrderDocuments), ctx, input)
+}
+
 // GenerateOrderInvoice mocks base method.
-func (m *MockOrderService) GenerateOrderInvoice(ctx context.Context, orderID, shopID int, message string) ([]byte, error) {
+func (m *MockOrderService) GenerateOrderInvoice(ctx context.Context, orderID, shopID int, message, lang string) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateOrderInvoice", ctx, orderID, shopID, message)
+	ret := m.ctrl.Call(m, "GenerateOrderInvoice", ctx, orderID, shopID, message, lang)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateOrderInvoice indicates an expected call of GenerateOrderInvoice.
-func (mr *MockOrderServiceMockRecorder) GenerateOrderInvoice(ctx, orderID, shopID, message interface{}) *gomock.Call {
+func (mr *MockOrderServiceMockRecorder) GenerateOrderInvoice(ctx, orderID, shopID, message, lang interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateOrderInvoice", reflect.TypeOf((*MockOrderService)(nil).GenerateOrderInvoice), ctx, orderID, shopID, message)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateOrderInvoice", reflect.TypeOf((*MockOrderService)(nil).GenerateOrderInvoice), ctx, orderID, shopID, message, lang)
 }
 
 // GetOrderByID mocks base method.
@@ -233,19 +337,19 @@ func (mr *MockOrderServiceMockRecorder) GetOrderPaymentsByOrderID(ctx, orderID i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderPaymentsByOrderID", reflect.TypeOf((*MockOrderService)(nil).GetOrderPaymentsByOrderID), ctx, orderID)
 }
 
-// GetOrdersStats mocks base method.
-func (m *MockOrderService) GetOrdersStats(ctx context.Context, shopID int, opts model.OrderFilterOptions) (response.OrderStatsData, error) {
+// GetOrderRefundsByOrderID mocks base method.
+func (m *MockOrderService) GetOrderRefundsByOrderID(ctx context.Context, orderID, shopID int) ([]response.OrderRefundData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrdersStats", ctx, shopID, opts)
-	ret0, _ := ret[0].(response.OrderStatsData)
+	ret := m.ctrl.Call(m, "GetOrderRefundsByOrderID", ctx, orderID, shopID)
+	ret0, _ := ret[0].([]response.OrderRefundData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOrdersStats indicates an expected call of GetOrdersStats.
-func (mr *MockOrderServiceMockRecorder) GetOrdersStats(ctx, shopID, opts interface{}) *gomock.Call {
+// GetOrderRefundsByOrderID indicates an expected call of GetOrderRefundsByOrderID.
+func (mr *MockOrderServiceMockRecorder) GetOrderRefundsByOrderID(ctx, orderID, shopID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersStats", reflect.TypeOf((*MockOrderService)(nil).GetOrdersStats), ctx, shopID, opts)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderRefundsByOrderID", reflect.TypeOf((*MockOrderService)(nil).GetOrderRefundsByOrderID), ctx, orderID, shopID)
 }
 
 // GetOrdersByShopID mocks base method.
@@ -263,6 +367,36 @@ func (mr *MockOrderServiceMockRecorder) GetOrdersByShopID(ctx, shopID, opts inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersByShopID", reflect.TypeOf((*MockOrderService)(nil).GetOrdersByShopID), ctx, shopID, opts)
 }
 
+// GetOrdersStats mocks base method.
+func (m *MockOrderService) GetOrdersStats(ctx context.Context, shopID int, opts model.OrderFilterOptions) (response.OrderStatsData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersStats", ctx, shopID, opts)
+	ret0, _ := ret[0].(response.OrderStatsData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrdersStats indicates an expected call of GetOrdersStats.
+func (mr *MockOrderServiceMockRecorder) GetOrdersStats(ctx, shopID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersStats", reflect.TypeOf((*MockOrderService)(nil).GetOrdersStats), ctx, shopID, opts)
+}
+
+// GetOrdersTimeseries mocks base method.
+func (m *MockOrderService) GetOrdersTimeseries(ctx context.Context, input service.GetOrdersTimeseriesInput) (response.OrderTimeseriesData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersTimeseries", ctx, input)
+	ret0, _ := ret[0].(response.OrderTimeseriesData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrdersTimeseries indicates an expected call of GetOrdersTimeseries.
+func (mr *MockOrderServiceMockRecorder) GetOrdersTimeseries(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersTimeseries", reflect.TypeOf((*MockOrderService)(nil).GetOrdersTimeseries), ctx, input)
+}
+
 // GetTempOrderByID mocks base method.
 func (m *MockOrderService) GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*response.TempOrderData, error) {
 	m.ctrl.T.Helper()
@@ -313,6 +447,21 @@ func (mr *MockOrderServiceMockRecorder) MergeTempOrder(ctx, tempOrderID, custome
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeTempOrder", reflect.TypeOf((*MockOrderService)(nil).MergeTempOrder), ctx, tempOrderID, customerID, shopID, activeOrderID)
 }
 
+// RefundOrder mocks base method.
+func (m *MockOrderService) RefundOrder(ctx context.Context, input service.RefundOrderInput) (response.OrderRefundData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundOrder", ctx, input)
+	ret0, _ := ret[0].(response.OrderRefundData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundOrder indicates an expected call of RefundOrder.
+func (mr *MockOrderServiceMockRecorder) RefundOrder(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundOrder", reflect.TypeOf((*MockOrderService)(nil).RefundOrder), ctx, input)
+}
+
 // RejectTempOrderByID mocks base method.
 func (m *MockOrderService) RejectTempOrderByID(ctx context.Context, id int) (response.TempOrderData, error) {
 	m.ctrl.T.Helper()
@@ -372,3 +521,18 @@ func (mr *MockOrderServiceMockRecorder) UpdateOrderPaymentAmountByID(ctx, id, or
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrderPaymentAmountByID", reflect.TypeOf((*MockOrderService)(nil).UpdateOrderPaymentAmountByID), ctx, id, orderID, amount)
 }
+
+// UploadRefundProof mocks base method.
+func (m *MockOrderService) UploadRefundProof(ctx context.Context, file io.Reader) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadRefundProof", ctx, file)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadRefundProof indicates an expected call of UploadRefundProof.
+func (mr *MockOrderServiceMockRecorder) UploadRefundProof(ctx, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadRefundProof", reflect.TypeOf((*MockOrderService)(nil).UploadRefundProof), ctx, file)
+}
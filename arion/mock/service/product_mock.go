@@ -52,6 +52,21 @@ func (mr *MockProductServiceMockRecorder) ActivateAllProductsByShopID(ctx, shopI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActivateAllProductsByShopID", reflect.TypeOf((*MockProductService)(nil).ActivateAllProductsByShopID), ctx, shopID)
 }
 
+// AddProductImage mocks base method.
+func (m *MockProductService) AddProductImage(ctx context.Context, productID, shopID int, file io.Reader) (response.ProductImageData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddProductImage", ctx, productID, shopID, file)
+	ret0, _ := ret[0].(response.ProductImageData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddProductImage indicates an expected call of AddProductImage.
+func (mr *MockProductServiceMockRecorder) AddProductImage(ctx, productID, shopID, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProductImage", reflect.TypeOf((*MockProductService)(nil).AddProductImage), ctx, productID, shopID, file)
+}
+
 // CreateProduct mocks base method.
 func (m *MockProductService) CreateProduct(ctx context.Context, shopID int, name string, description *string, price int, originalPrice *int, imageURL *string) (response.ProductData, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +124,20 @@ func (mr *MockProductServiceMockRecorder) DeleteProductImage(ctx, imageURL inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProductImage", reflect.TypeOf((*MockProductService)(nil).DeleteProductImage), ctx, imageURL)
 }
 
+// DeleteProductImageByID mocks base method.
+func (m *MockProductService) DeleteProductImageByID(ctx context.Context, imageID, productID, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProductImageByID", ctx, imageID, productID, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProductImageByID indicates an expected call of DeleteProductImageByID.
+func (mr *MockProductServiceMockRecorder) DeleteProductImageByID(ctx, imageID, productID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProductImageByID", reflect.TypeOf((*MockProductService)(nil).DeleteProductImageByID), ctx, imageID, productID, shopID)
+}
+
 // GetProductByID mocks base method.
 func (m *MockProductService) GetProductByID(ctx context.Context, productID int, shopID ...int) (*response.ProductData, error) {
 	m.ctrl.T.Helper()
@@ -129,6 +158,21 @@ func (mr *MockProductServiceMockRecorder) GetProductByID(ctx, productID interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductByID", reflect.TypeOf((*MockProductService)(nil).GetProductByID), varargs...)
 }
 
+// GetProductImages mocks base method.
+func (m *MockProductService) GetProductImages(ctx context.Context, productID, shopID int) ([]response.ProductImageData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProductImages", ctx, productID, shopID)
+	ret0, _ := ret[0].([]response.ProductImageData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProductImages indicates an expected call of GetProductImages.
+func (mr *MockProductServiceMockRecorder) GetProductImages(ctx, productID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductImages", reflect.TypeOf((*MockProductService)(nil).GetProductImages), ctx, productID, shopID)
+}
+
 // GetProductsByShopID mocks base method.
 func (m *MockProductService) GetProductsByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]response.ProductData, error) {
 	m.ctrl.T.Helper()
@@ -159,6 +203,48 @@ func (mr *MockProductServiceMockRecorder) GetPurchaseListProducts(ctx, shopID in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPurchaseListProducts", reflect.TypeOf((*MockProductService)(nil).GetPurchaseListProducts), ctx, shopID)
 }
 
+// ReorderProductImages mocks base method.
+func (m *MockProductService) ReorderProductImages(ctx context.Context, productID, shopID int, imageIDs []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReorderProductImages", ctx, productID, shopID, imageIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReorderProductImages indicates an expected call of ReorderProductImages.
+func (mr *MockProductServiceMockRecorder) ReorderProductImages(ctx, productID, shopID, imageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderProductImages", reflect.TypeOf((*MockProductService)(nil).ReorderProductImages), ctx, productID, shopID, imageIDs)
+}
+
+// ReorderProducts mocks base method.
+func (m *MockProductService) ReorderProducts(ctx context.Context, shopID int, productIDs []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReorderProducts", ctx, shopID, productIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReorderProducts indicates an expected call of ReorderProducts.
+func (mr *MockProductServiceMockRecorder) ReorderProducts(ctx, shopID, productIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderProducts", reflect.TypeOf((*MockProductService)(nil).ReorderProducts), ctx, shopID, productIDs)
+}
+
+// SetPrimaryProductImage mocks base method.
+func (m *MockProductService) SetPrimaryProductImage(ctx context.Context, imageID, productID, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPrimaryProductImage", ctx, imageID, productID, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPrimaryProductImage indicates an expected call of SetPrimaryProductImage.
+func (mr *MockProductServiceMockRecorder) SetPrimaryProductImage(ctx, imageID, productID, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPrimaryProductImage", reflect.TypeOf((*MockProductService)(nil).SetPrimaryProductImage), ctx, imageID, productID, shopID)
+}
+
 // UpdateProduct mocks base method.
 func (m *MockProductService) UpdateProduct(ctx context.Context, input service.UpdateProductInput) (response.ProductData, error) {
 	m.ctrl.T.Helper()
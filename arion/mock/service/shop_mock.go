@@ -6,10 +6,13 @@ package mock_service
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
 	response "github.com/zeirash/recapo/arion/common/response"
+	model "github.com/zeirash/recapo/arion/model"
+	service "github.com/zeirash/recapo/arion/service"
 )
 
 // MockShopService is a mock of ShopService interface.
@@ -35,19 +38,48 @@ func (m *MockShopService) EXPECT() *MockShopServiceMockRecorder {
 	return m.recorder
 }
 
+// CloseShop mocks base method.
+func (m *MockShopService) CloseShop(ctx context.Context, shopID, userID int, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseShop", ctx, shopID, userID, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseShop indicates an expected call of CloseShop.
+func (mr *MockShopServiceMockRecorder) CloseShop(ctx, shopID, userID, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseShop", reflect.TypeOf((*MockShopService)(nil).CloseShop), ctx, shopID, userID, password)
+}
+
+// GetOrderChallenge mocks base method.
+func (m *MockShopService) GetOrderChallenge(ctx context.Context, shareToken string) (response.OrderChallengeData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderChallenge", ctx, shareToken)
+	ret0, _ := ret[0].(response.OrderChallengeData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderChallenge indicates an expected call of GetOrderChallenge.
+func (mr *MockShopServiceMockRecorder) GetOrderChallenge(ctx, shareToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderChallenge", reflect.TypeOf((*MockShopService)(nil).GetOrderChallenge), ctx, shareToken)
+}
+
 // GetPublicProducts mocks base method.
-func (m *MockShopService) GetPublicProducts(ctx context.Context, shareToken string) ([]response.ProductData, error) {
+func (m *MockShopService) GetPublicProducts(ctx context.Context, shareToken string, filter model.FilterOptions) (response.PublicShopProductsData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPublicProducts", ctx, shareToken)
-	ret0, _ := ret[0].([]response.ProductData)
+	ret := m.ctrl.Call(m, "GetPublicProducts", ctx, shareToken, filter)
+	ret0, _ := ret[0].(response.PublicShopProductsData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPublicProducts indicates an expected call of GetPublicProducts.
-func (mr *MockShopServiceMockRecorder) GetPublicProducts(ctx, shareToken interface{}) *gomock.Call {
+func (mr *MockShopServiceMockRecorder) GetPublicProducts(ctx, shareToken, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPublicProducts", reflect.TypeOf((*MockShopService)(nil).GetPublicProducts), ctx, shareToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPublicProducts", reflect.TypeOf((*MockShopService)(nil).GetPublicProducts), ctx, shareToken, filter)
 }
 
 // GetShareTokenByID mocks base method.
@@ -64,3 +96,90 @@ func (mr *MockShopServiceMockRecorder) GetShareTokenByID(ctx, shopID interface{}
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShareTokenByID", reflect.TypeOf((*MockShopService)(nil).GetShareTokenByID), ctx, shopID)
 }
+
+// GetShopByID mocks base method.
+func (m *MockShopService) GetShopByID(ctx context.Context, shopID int) (response.ShopData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetShopByID", ctx, shopID)
+	ret0, _ := ret[0].(response.ShopData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetShopByID indicates an expected call of GetShopByID.
+func (mr *MockShopServiceMockRecorder) GetShopByID(ctx, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShopByID", reflect.TypeOf((*MockShopService)(nil).GetShopByID), ctx, shopID)
+}
+
+// PurgeClosedShops mocks base method.
+func (m *MockShopService) PurgeClosedShops(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeClosedShops", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeClosedShops indicates an expected call of PurgeClosedShops.
+func (mr *MockShopServiceMockRecorder) PurgeClosedShops(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeClosedShops", reflect.TypeOf((*MockShopService)(nil).PurgeClosedShops), ctx)
+}
+
+// ReopenShop mocks base method.
+func (m *MockShopService) ReopenShop(ctx context.Context, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReopenShop", ctx, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReopenShop indicates an expected call of ReopenShop.
+func (mr *MockShopServiceMockRecorder) ReopenShop(ctx, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReopenShop", reflect.TypeOf((*MockShopService)(nil).ReopenShop), ctx, shopID)
+}
+
+// UpdateShopByID mocks base method.
+func (m *MockShopService) UpdateShopByID(ctx context.Context, input service.UpdateShopInput) (response.ShopData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateShopByID", ctx, input)
+	ret0, _ := ret[0].(response.ShopData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateShopByID indicates an expected call of UpdateShopByID.
+func (mr *MockShopServiceMockRecorder) UpdateShopByID(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateShopByID", reflect.TypeOf((*MockShopService)(nil).UpdateShopByID), ctx, input)
+}
+
+// UploadShopLogo mocks base method.
+func (m *MockShopService) UploadShopLogo(ctx context.Context, shopID int, file io.Reader) (response.ShopData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadShopLogo", ctx, shopID, file)
+	ret0, _ := ret[0].(response.ShopData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadShopLogo indicates an expected call of UploadShopLogo.
+func (mr *MockShopServiceMockRecorder) UploadShopLogo(ctx, shopID, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadShopLogo", reflect.TypeOf((*MockShopService)(nil).UploadShopLogo), ctx, shopID, file)
+}
+
+// VerifyOrderChallenge mocks base method.
+func (m *MockShopService) VerifyOrderChallenge(ctx context.Context, shareToken, challenge, solution string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyOrderChallenge", ctx, shareToken, challenge, solution)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyOrderChallenge indicates an expected call of VerifyOrderChallenge.
+func (mr *MockShopServiceMockRecorder) VerifyOrderChallenge(ctx, shareToken, challenge, solution interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyOrderChallenge", reflect.TypeOf((*MockShopService)(nil).VerifyOrderChallenge), ctx, shareToken, challenge, solution)
+}
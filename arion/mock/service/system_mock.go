@@ -36,19 +36,34 @@ func (m *MockSystemService) EXPECT() *MockSystemServiceMockRecorder {
 	return m.recorder
 }
 
-// GetSystemStats mocks base method.
-func (m *MockSystemService) GetSystemStats(ctx context.Context) (*response.SystemStatsData, error) {
+// CollectUnreferencedUploads mocks base method.
+func (m *MockSystemService) CollectUnreferencedUploads(ctx context.Context) (response.SystemUploadGCData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSystemStats", ctx)
-	ret0, _ := ret[0].(*response.SystemStatsData)
+	ret := m.ctrl.Call(m, "CollectUnreferencedUploads", ctx)
+	ret0, _ := ret[0].(response.SystemUploadGCData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSystemStats indicates an expected call of GetSystemStats.
-func (mr *MockSystemServiceMockRecorder) GetSystemStats(ctx interface{}) *gomock.Call {
+// CollectUnreferencedUploads indicates an expected call of CollectUnreferencedUploads.
+func (mr *MockSystemServiceMockRecorder) CollectUnreferencedUploads(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemStats", reflect.TypeOf((*MockSystemService)(nil).GetSystemStats), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectUnreferencedUploads", reflect.TypeOf((*MockSystemService)(nil).CollectUnreferencedUploads), ctx)
+}
+
+// GetSystemPayments mocks base method.
+func (m *MockSystemService) GetSystemPayments(ctx context.Context, opts model.SystemPaymentFilterOptions) ([]response.SystemPaymentData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSystemPayments", ctx, opts)
+	ret0, _ := ret[0].([]response.SystemPaymentData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSystemPayments indicates an expected call of GetSystemPayments.
+func (mr *MockSystemServiceMockRecorder) GetSystemPayments(ctx, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemPayments", reflect.TypeOf((*MockSystemService)(nil).GetSystemPayments), ctx, opts)
 }
 
 // GetSystemShops mocks base method.
@@ -66,17 +81,17 @@ func (mr *MockSystemServiceMockRecorder) GetSystemShops(ctx interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemShops", reflect.TypeOf((*MockSystemService)(nil).GetSystemShops), ctx)
 }
 
-// GetSystemPayments mocks base method.
-func (m *MockSystemService) GetSystemPayments(ctx context.Context, opts model.SystemPaymentFilterOptions) ([]response.SystemPaymentData, error) {
+// GetSystemStats mocks base method.
+func (m *MockSystemService) GetSystemStats(ctx context.Context) (*response.SystemStatsData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSystemPayments", ctx, opts)
-	ret0, _ := ret[0].([]response.SystemPaymentData)
+	ret := m.ctrl.Call(m, "GetSystemStats", ctx)
+	ret0, _ := ret[0].(*response.SystemStatsData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSystemPayments indicates an expected call of GetSystemPayments.
-func (mr *MockSystemServiceMockRecorder) GetSystemPayments(ctx, opts interface{}) *gomock.Call {
+// GetSystemStats indicates an expected call of GetSystemStats.
+func (mr *MockSystemServiceMockRecorder) GetSystemStats(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemPayments", reflect.TypeOf((*MockSystemService)(nil).GetSystemPayments), ctx, opts)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemStats", reflect.TypeOf((*MockSystemService)(nil).GetSystemStats), ctx)
 }
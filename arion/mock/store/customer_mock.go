@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	database "github.com/zeirash/recapo/arion/common/database"
 	model "github.com/zeirash/recapo/arion/model"
 	store "github.com/zeirash/recapo/arion/store"
 )
@@ -36,6 +37,48 @@ func (m *MockCustomerStore) EXPECT() *MockCustomerStoreMockRecorder {
 	return m.recorder
 }
 
+// AnonymizeCustomer mocks base method.
+func (m *MockCustomerStore) AnonymizeCustomer(ctx context.Context, tx database.Tx, shopID, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeCustomer", ctx, tx, shopID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnonymizeCustomer indicates an expected call of AnonymizeCustomer.
+func (mr *MockCustomerStoreMockRecorder) AnonymizeCustomer(ctx, tx, shopID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeCustomer", reflect.TypeOf((*MockCustomerStore)(nil).AnonymizeCustomer), ctx, tx, shopID, id)
+}
+
+// BulkCreateCustomers mocks base method.
+func (m *MockCustomerStore) BulkCreateCustomers(ctx context.Context, tx database.Tx, inputs []store.CreateCustomerInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkCreateCustomers", ctx, tx, inputs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BulkCreateCustomers indicates an expected call of BulkCreateCustomers.
+func (mr *MockCustomerStoreMockRecorder) BulkCreateCustomers(ctx, tx, inputs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkCreateCustomers", reflect.TypeOf((*MockCustomerStore)(nil).BulkCreateCustomers), ctx, tx, inputs)
+}
+
+// BulkUpdateCustomers mocks base method.
+func (m *MockCustomerStore) BulkUpdateCustomers(ctx context.Context, tx database.Tx, inputs []store.BulkUpdateCustomerInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkUpdateCustomers", ctx, tx, inputs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BulkUpdateCustomers indicates an expected call of BulkUpdateCustomers.
+func (mr *MockCustomerStoreMockRecorder) BulkUpdateCustomers(ctx, tx, inputs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkUpdateCustomers", reflect.TypeOf((*MockCustomerStore)(nil).BulkUpdateCustomers), ctx, tx, inputs)
+}
+
 // CreateCustomer mocks base method.
 func (m *MockCustomerStore) CreateCustomer(ctx context.Context, input store.CreateCustomerInput) (*model.Customer, error) {
 	m.ctrl.T.Helper()
@@ -100,8 +143,38 @@ func (mr *MockCustomerStoreMockRecorder) GetCustomerByPhone(ctx, phone, shopID i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerByPhone", reflect.TypeOf((*MockCustomerStore)(nil).GetCustomerByPhone), ctx, phone, shopID)
 }
 
+// GetCustomerStats mocks base method.
+func (m *MockCustomerStore) GetCustomerStats(ctx context.Context, id int) (*model.CustomerStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCustomerStats", ctx, id)
+	ret0, _ := ret[0].(*model.CustomerStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCustomerStats indicates an expected call of GetCustomerStats.
+func (mr *MockCustomerStoreMockRecorder) GetCustomerStats(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerStats", reflect.TypeOf((*MockCustomerStore)(nil).GetCustomerStats), ctx, id)
+}
+
+// GetCustomersByPhones mocks base method.
+func (m *MockCustomerStore) GetCustomersByPhones(ctx context.Context, shopID int, phones []string) ([]model.Customer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCustomersByPhones", ctx, shopID, phones)
+	ret0, _ := ret[0].([]model.Customer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCustomersByPhones indicates an expected call of GetCustomersByPhones.
+func (mr *MockCustomerStoreMockRecorder) GetCustomersByPhones(ctx, shopID, phones interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomersByPhones", reflect.TypeOf((*MockCustomerStore)(nil).GetCustomersByPhones), ctx, shopID, phones)
+}
+
 // GetCustomersByShopID mocks base method.
-func (m *MockCustomerStore) GetCustomersByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]model.Customer, error) {
+func (m *MockCustomerStore) GetCustomersByShopID(ctx context.Context, shopID int, filter model.CustomerFilterOptions) ([]model.Customer, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetCustomersByShopID", ctx, shopID, filter)
 	ret0, _ := ret[0].([]model.Customer)
@@ -115,6 +188,20 @@ func (mr *MockCustomerStoreMockRecorder) GetCustomersByShopID(ctx, shopID, filte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomersByShopID", reflect.TypeOf((*MockCustomerStore)(nil).GetCustomersByShopID), ctx, shopID, filter)
 }
 
+// MergeCustomers mocks base method.
+func (m *MockCustomerStore) MergeCustomers(ctx context.Context, tx database.Tx, shopID, survivorID int, duplicateIDs []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeCustomers", ctx, tx, shopID, survivorID, duplicateIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MergeCustomers indicates an expected call of MergeCustomers.
+func (mr *MockCustomerStoreMockRecorder) MergeCustomers(ctx, tx, shopID, survivorID, duplicateIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeCustomers", reflect.TypeOf((*MockCustomerStore)(nil).MergeCustomers), ctx, tx, shopID, survivorID, duplicateIDs)
+}
+
 // UpdateCustomer mocks base method.
 func (m *MockCustomerStore) UpdateCustomer(ctx context.Context, id int, input store.UpdateCustomerInput) (*model.Customer, error) {
 	m.ctrl.T.Helper()
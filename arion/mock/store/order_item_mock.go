@@ -37,6 +37,20 @@ func (m *MockOrderItemStore) EXPECT() *MockOrderItemStoreMockRecorder {
 	return m.recorder
 }
 
+// AllocateOrderItem mocks base method.
+func (m *MockOrderItemStore) AllocateOrderItem(ctx context.Context, tx database.Tx, id, cut int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllocateOrderItem", ctx, tx, id, cut)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AllocateOrderItem indicates an expected call of AllocateOrderItem.
+func (mr *MockOrderItemStoreMockRecorder) AllocateOrderItem(ctx, tx, id, cut interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocateOrderItem", reflect.TypeOf((*MockOrderItemStore)(nil).AllocateOrderItem), ctx, tx, id, cut)
+}
+
 // CreateOrderItem mocks base method.
 func (m *MockOrderItemStore) CreateOrderItem(ctx context.Context, tx database.Tx, orderID, productID, qty int) (*model.OrderItem, error) {
 	m.ctrl.T.Helper()
@@ -124,19 +138,19 @@ func (mr *MockOrderItemStoreMockRecorder) GetNetSalesByShopID(ctx, shopID, opts
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetSalesByShopID", reflect.TypeOf((*MockOrderItemStore)(nil).GetNetSalesByShopID), ctx, shopID, opts)
 }
 
-// GetOrderTotalsExcludingProduct mocks base method.
-func (m *MockOrderItemStore) GetOrderTotalsExcludingProduct(ctx context.Context, productID int) (map[int]int, error) {
+// GetOpenOrderItemsByProductID mocks base method.
+func (m *MockOrderItemStore) GetOpenOrderItemsByProductID(ctx context.Context, tx database.Tx, shopID, productID int) ([]model.ProductOrderItem, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrderTotalsExcludingProduct", ctx, productID)
-	ret0, _ := ret[0].(map[int]int)
+	ret := m.ctrl.Call(m, "GetOpenOrderItemsByProductID", ctx, tx, shopID, productID)
+	ret0, _ := ret[0].([]model.ProductOrderItem)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOrderTotalsExcludingProduct indicates an expected call of GetOrderTotalsExcludingProduct.
-func (mr *MockOrderItemStoreMockRecorder) GetOrderTotalsExcludingProduct(ctx, productID interface{}) *gomock.Call {
+// GetOpenOrderItemsByProductID indicates an expected call of GetOpenOrderItemsByProductID.
+func (mr *MockOrderItemStoreMockRecorder) GetOpenOrderItemsByProductID(ctx, tx, shopID, productID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderTotalsExcludingProduct", reflect.TypeOf((*MockOrderItemStore)(nil).GetOrderTotalsExcludingProduct), ctx, productID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpenOrderItemsByProductID", reflect.TypeOf((*MockOrderItemStore)(nil).GetOpenOrderItemsByProductID), ctx, tx, shopID, productID)
 }
 
 // GetOrderItemByID mocks base method.
@@ -184,6 +198,21 @@ func (mr *MockOrderItemStoreMockRecorder) GetOrderItemsByOrderID(ctx, orderID in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderItemsByOrderID", reflect.TypeOf((*MockOrderItemStore)(nil).GetOrderItemsByOrderID), ctx, orderID)
 }
 
+// GetOrderTotalsExcludingProduct mocks base method.
+func (m *MockOrderItemStore) GetOrderTotalsExcludingProduct(ctx context.Context, productID int) (map[int]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderTotalsExcludingProduct", ctx, productID)
+	ret0, _ := ret[0].(map[int]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderTotalsExcludingProduct indicates an expected call of GetOrderTotalsExcludingProduct.
+func (mr *MockOrderItemStoreMockRecorder) GetOrderTotalsExcludingProduct(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderTotalsExcludingProduct", reflect.TypeOf((*MockOrderItemStore)(nil).GetOrderTotalsExcludingProduct), ctx, productID)
+}
+
 // GetTempOrderItemsByTempOrderID mocks base method.
 func (m *MockOrderItemStore) GetTempOrderItemsByTempOrderID(ctx context.Context, tempOrderID int) ([]model.TempOrderItem, error) {
 	m.ctrl.T.Helper()
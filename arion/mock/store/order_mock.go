@@ -7,6 +7,7 @@ package mock_store
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	database "github.com/zeirash/recapo/arion/common/database"
@@ -37,6 +38,35 @@ func (m *MockOrderStore) EXPECT() *MockOrderStoreMockRecorder {
 	return m.recorder
 }
 
+// AssignUniqueCode mocks base method.
+func (m *MockOrderStore) AssignUniqueCode(ctx context.Context, tx database.Tx, id, shopID int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignUniqueCode", ctx, tx, id, shopID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignUniqueCode indicates an expected call of AssignUniqueCode.
+func (mr *MockOrderStoreMockRecorder) AssignUniqueCode(ctx, tx, id, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignUniqueCode", reflect.TypeOf((*MockOrderStore)(nil).AssignUniqueCode), ctx, tx, id, shopID)
+}
+
+// CancelOrder mocks base method.
+func (m *MockOrderStore) CancelOrder(ctx context.Context, tx database.Tx, id int, reason, notes string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelOrder", ctx, tx, id, reason, notes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelOrder indicates an expected call of CancelOrder.
+func (mr *MockOrderStoreMockRecorder) CancelOrder(ctx, tx, id, reason, notes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOrder", reflect.TypeOf((*MockOrderStore)(nil).CancelOrder), ctx, tx, id, reason, notes)
+}
+
 // CreateOrder mocks base method.
 func (m *MockOrderStore) CreateOrder(ctx context.Context, tx database.Tx, customerID, shopID int, notes *string, totalPrice *int) (*model.Order, error) {
 	m.ctrl.T.Helper()
@@ -53,18 +83,18 @@ func (mr *MockOrderStoreMockRecorder) CreateOrder(ctx, tx, customerID, shopID, n
 }
 
 // CreateTempOrder mocks base method.
-func (m *MockOrderStore) CreateTempOrder(ctx context.Context, tx database.Tx, customerName, customerPhone string, shopID int) (*model.TempOrder, error) {
+func (m *MockOrderStore) CreateTempOrder(ctx context.Context, tx database.Tx, customerName, customerPhone string, shopID int, shareLinkID *int, fingerprint string, shippingAddress *model.ShippingAddress) (*model.TempOrder, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTempOrder", ctx, tx, customerName, customerPhone, shopID)
+	ret := m.ctrl.Call(m, "CreateTempOrder", ctx, tx, customerName, customerPhone, shopID, shareLinkID, fingerprint, shippingAddress)
 	ret0, _ := ret[0].(*model.TempOrder)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateTempOrder indicates an expected call of CreateTempOrder.
-func (mr *MockOrderStoreMockRecorder) CreateTempOrder(ctx, tx, customerName, customerPhone, shopID interface{}) *gomock.Call {
+func (mr *MockOrderStoreMockRecorder) CreateTempOrder(ctx, tx, customerName, customerPhone, shopID, shareLinkID, fingerprint, shippingAddress interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTempOrder", reflect.TypeOf((*MockOrderStore)(nil).CreateTempOrder), ctx, tx, customerName, customerPhone, shopID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTempOrder", reflect.TypeOf((*MockOrderStore)(nil).CreateTempOrder), ctx, tx, customerName, customerPhone, shopID, shareLinkID, fingerprint, shippingAddress)
 }
 
 // DeleteOrderByID mocks base method.
@@ -96,6 +126,36 @@ func (mr *MockOrderStoreMockRecorder) GetActiveOrderByCustomerID(ctx, customerID
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveOrderByCustomerID", reflect.TypeOf((*MockOrderStore)(nil).GetActiveOrderByCustomerID), ctx, customerID, shopID)
 }
 
+// GetDPOverdueOrders mocks base method.
+func (m *MockOrderStore) GetDPOverdueOrders(ctx context.Context, now time.Time) ([]model.DPOverdueOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDPOverdueOrders", ctx, now)
+	ret0, _ := ret[0].([]model.DPOverdueOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDPOverdueOrders indicates an expected call of GetDPOverdueOrders.
+func (mr *MockOrderStoreMockRecorder) GetDPOverdueOrders(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDPOverdueOrders", reflect.TypeOf((*MockOrderStore)(nil).GetDPOverdueOrders), ctx, now)
+}
+
+// GetInvoiceNumberForUpdate mocks base method.
+func (m *MockOrderStore) GetInvoiceNumberForUpdate(ctx context.Context, tx database.Tx, id int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvoiceNumberForUpdate", ctx, tx, id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvoiceNumberForUpdate indicates an expected call of GetInvoiceNumberForUpdate.
+func (mr *MockOrderStoreMockRecorder) GetInvoiceNumberForUpdate(ctx, tx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvoiceNumberForUpdate", reflect.TypeOf((*MockOrderStore)(nil).GetInvoiceNumberForUpdate), ctx, tx, id)
+}
+
 // GetOrderByID mocks base method.
 func (m *MockOrderStore) GetOrderByID(ctx context.Context, id int, shopID ...int) (*model.Order, error) {
 	m.ctrl.T.Helper()
@@ -116,6 +176,21 @@ func (mr *MockOrderStoreMockRecorder) GetOrderByID(ctx, id interface{}, shopID .
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByID", reflect.TypeOf((*MockOrderStore)(nil).GetOrderByID), varargs...)
 }
 
+// GetOrderByIDForUpdate mocks base method.
+func (m *MockOrderStore) GetOrderByIDForUpdate(ctx context.Context, tx database.Tx, id int) (*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderByIDForUpdate", ctx, tx, id)
+	ret0, _ := ret[0].(*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderByIDForUpdate indicates an expected call of GetOrderByIDForUpdate.
+func (mr *MockOrderStoreMockRecorder) GetOrderByIDForUpdate(ctx, tx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByIDForUpdate", reflect.TypeOf((*MockOrderStore)(nil).GetOrderByIDForUpdate), ctx, tx, id)
+}
+
 // GetOrdersByShopID mocks base method.
 func (m *MockOrderStore) GetOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]model.Order, error) {
 	m.ctrl.T.Helper()
@@ -131,6 +206,21 @@ func (mr *MockOrderStoreMockRecorder) GetOrdersByShopID(ctx, shopID, opts interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersByShopID", reflect.TypeOf((*MockOrderStore)(nil).GetOrdersByShopID), ctx, shopID, opts)
 }
 
+// GetOutstandingOrdersByShopID mocks base method.
+func (m *MockOrderStore) GetOutstandingOrdersByShopID(ctx context.Context, shopID int) ([]model.OutstandingOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutstandingOrdersByShopID", ctx, shopID)
+	ret0, _ := ret[0].([]model.OutstandingOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutstandingOrdersByShopID indicates an expected call of GetOutstandingOrdersByShopID.
+func (mr *MockOrderStoreMockRecorder) GetOutstandingOrdersByShopID(ctx, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutstandingOrdersByShopID", reflect.TypeOf((*MockOrderStore)(nil).GetOutstandingOrdersByShopID), ctx, shopID)
+}
+
 // GetTempOrderByID mocks base method.
 func (m *MockOrderStore) GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*model.TempOrder, error) {
 	m.ctrl.T.Helper()
@@ -166,6 +256,92 @@ func (mr *MockOrderStoreMockRecorder) GetTempOrdersByShopID(ctx, shopID, opts in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTempOrdersByShopID", reflect.TypeOf((*MockOrderStore)(nil).GetTempOrdersByShopID), ctx, shopID, opts)
 }
 
+// HasRecentTempOrder mocks base method.
+func (m *MockOrderStore) HasRecentTempOrder(ctx context.Context, tx database.Tx, shopID int, fingerprint string, since time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasRecentTempOrder", ctx, tx, shopID, fingerprint, since)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasRecentTempOrder indicates an expected call of HasRecentTempOrder.
+func (mr *MockOrderStoreMockRecorder) HasRecentTempOrder(ctx, tx, shopID, fingerprint, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasRecentTempOrder", reflect.TypeOf((*MockOrderStore)(nil).HasRecentTempOrder), ctx, tx, shopID, fingerprint, since)
+}
+
+// MarkDPOverdue mocks base method.
+func (m *MockOrderStore) MarkDPOverdue(ctx context.Context, tx database.Tx, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDPOverdue", ctx, tx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkDPOverdue indicates an expected call of MarkDPOverdue.
+func (mr *MockOrderStoreMockRecorder) MarkDPOverdue(ctx, tx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDPOverdue", reflect.TypeOf((*MockOrderStore)(nil).MarkDPOverdue), ctx, tx, id)
+}
+
+// ReclaimUniqueCode mocks base method.
+func (m *MockOrderStore) ReclaimUniqueCode(ctx context.Context, tx database.Tx, id, shopID int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReclaimUniqueCode", ctx, tx, id, shopID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReclaimUniqueCode indicates an expected call of ReclaimUniqueCode.
+func (mr *MockOrderStoreMockRecorder) ReclaimUniqueCode(ctx, tx, id, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReclaimUniqueCode", reflect.TypeOf((*MockOrderStore)(nil).ReclaimUniqueCode), ctx, tx, id, shopID)
+}
+
+// SetDownPayment mocks base method.
+func (m *MockOrderStore) SetDownPayment(ctx context.Context, tx database.Tx, id int, input store.SetDownPaymentInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDownPayment", ctx, tx, id, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDownPayment indicates an expected call of SetDownPayment.
+func (mr *MockOrderStoreMockRecorder) SetDownPayment(ctx, tx, id, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownPayment", reflect.TypeOf((*MockOrderStore)(nil).SetDownPayment), ctx, tx, id, input)
+}
+
+// SetInvoiceNumber mocks base method.
+func (m *MockOrderStore) SetInvoiceNumber(ctx context.Context, tx database.Tx, id, seq int, number string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetInvoiceNumber", ctx, tx, id, seq, number)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetInvoiceNumber indicates an expected call of SetInvoiceNumber.
+func (mr *MockOrderStoreMockRecorder) SetInvoiceNumber(ctx, tx, id, seq, number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetInvoiceNumber", reflect.TypeOf((*MockOrderStore)(nil).SetInvoiceNumber), ctx, tx, id, seq, number)
+}
+
+// SetShippingAddress mocks base method.
+func (m *MockOrderStore) SetShippingAddress(ctx context.Context, tx database.Tx, id int, address *model.ShippingAddress) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetShippingAddress", ctx, tx, id, address)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetShippingAddress indicates an expected call of SetShippingAddress.
+func (mr *MockOrderStoreMockRecorder) SetShippingAddress(ctx, tx, id, address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetShippingAddress", reflect.TypeOf((*MockOrderStore)(nil).SetShippingAddress), ctx, tx, id, address)
+}
+
 // UpdateOrder mocks base method.
 func (m *MockOrderStore) UpdateOrder(ctx context.Context, tx database.Tx, id int, input store.UpdateOrderInput) (*model.Order, error) {
 	m.ctrl.T.Helper()
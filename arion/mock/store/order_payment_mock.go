@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	database "github.com/zeirash/recapo/arion/common/database"
 	model "github.com/zeirash/recapo/arion/model"
+	store "github.com/zeirash/recapo/arion/store"
 )
 
 // MockOrderPaymentStore is a mock of OrderPaymentStore interface.
@@ -51,18 +52,33 @@ func (mr *MockOrderPaymentStoreMockRecorder) CreateOrderPayment(ctx, tx, orderID
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrderPayment", reflect.TypeOf((*MockOrderPaymentStore)(nil).CreateOrderPayment), ctx, tx, orderID, amount)
 }
 
+// CreateStatementLine mocks base method.
+func (m *MockOrderPaymentStore) CreateStatementLine(ctx context.Context, tx database.Tx, input store.CreateStatementLineInput) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStatementLine", ctx, tx, input)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStatementLine indicates an expected call of CreateStatementLine.
+func (mr *MockOrderPaymentStoreMockRecorder) CreateStatementLine(ctx, tx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStatementLine", reflect.TypeOf((*MockOrderPaymentStore)(nil).CreateStatementLine), ctx, tx, input)
+}
+
 // DeleteOrderPaymentByID mocks base method.
-func (m *MockOrderPaymentStore) DeleteOrderPaymentByID(ctx context.Context, id, orderID int) error {
+func (m *MockOrderPaymentStore) DeleteOrderPaymentByID(ctx context.Context, tx database.Tx, id, orderID int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteOrderPaymentByID", ctx, id, orderID)
+	ret := m.ctrl.Call(m, "DeleteOrderPaymentByID", ctx, tx, id, orderID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteOrderPaymentByID indicates an expected call of DeleteOrderPaymentByID.
-func (mr *MockOrderPaymentStoreMockRecorder) DeleteOrderPaymentByID(ctx, id, orderID interface{}) *gomock.Call {
+func (mr *MockOrderPaymentStoreMockRecorder) DeleteOrderPaymentByID(ctx, tx, id, orderID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrderPaymentByID", reflect.TypeOf((*MockOrderPaymentStore)(nil).DeleteOrderPaymentByID), ctx, id, orderID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrderPaymentByID", reflect.TypeOf((*MockOrderPaymentStore)(nil).DeleteOrderPaymentByID), ctx, tx, id, orderID)
 }
 
 // DeleteOrderPaymentsByOrderID mocks base method.
@@ -80,18 +96,18 @@ func (mr *MockOrderPaymentStoreMockRecorder) DeleteOrderPaymentsByOrderID(ctx, t
 }
 
 // GetOrderPaymentsByOrderID mocks base method.
-func (m *MockOrderPaymentStore) GetOrderPaymentsByOrderID(ctx context.Context, orderID int) ([]model.OrderPayment, error) {
+func (m *MockOrderPaymentStore) GetOrderPaymentsByOrderID(ctx context.Context, tx database.Tx, orderID int) ([]model.OrderPayment, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrderPaymentsByOrderID", ctx, orderID)
+	ret := m.ctrl.Call(m, "GetOrderPaymentsByOrderID", ctx, tx, orderID)
 	ret0, _ := ret[0].([]model.OrderPayment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrderPaymentsByOrderID indicates an expected call of GetOrderPaymentsByOrderID.
-func (mr *MockOrderPaymentStoreMockRecorder) GetOrderPaymentsByOrderID(ctx, orderID interface{}) *gomock.Call {
+func (mr *MockOrderPaymentStoreMockRecorder) GetOrderPaymentsByOrderID(ctx, tx, orderID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderPaymentsByOrderID", reflect.TypeOf((*MockOrderPaymentStore)(nil).GetOrderPaymentsByOrderID), ctx, orderID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderPaymentsByOrderID", reflect.TypeOf((*MockOrderPaymentStore)(nil).GetOrderPaymentsByOrderID), ctx, tx, orderID)
 }
 
 // GetPaymentsSumByShopID mocks base method.
@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	database "github.com/zeirash/recapo/arion/common/database"
 	model "github.com/zeirash/recapo/arion/model"
 	store "github.com/zeirash/recapo/arion/store"
 )
@@ -36,6 +37,34 @@ func (m *MockProductStore) EXPECT() *MockProductStoreMockRecorder {
 	return m.recorder
 }
 
+// BulkCreateProducts mocks base method.
+func (m *MockProductStore) BulkCreateProducts(ctx context.Context, tx database.Tx, inputs []store.BulkCreateProductInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkCreateProducts", ctx, tx, inputs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BulkCreateProducts indicates an expected call of BulkCreateProducts.
+func (mr *MockProductStoreMockRecorder) BulkCreateProducts(ctx, tx, inputs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkCreateProducts", reflect.TypeOf((*MockProductStore)(nil).BulkCreateProducts), ctx, tx, inputs)
+}
+
+// BulkUpdateProducts mocks base method.
+func (m *MockProductStore) BulkUpdateProducts(ctx context.Context, tx database.Tx, inputs []store.BulkUpdateProductInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkUpdateProducts", ctx, tx, inputs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BulkUpdateProducts indicates an expected call of BulkUpdateProducts.
+func (mr *MockProductStoreMockRecorder) BulkUpdateProducts(ctx, tx, inputs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkUpdateProducts", reflect.TypeOf((*MockProductStore)(nil).BulkUpdateProducts), ctx, tx, inputs)
+}
+
 // CreateProduct mocks base method.
 func (m *MockProductStore) CreateProduct(ctx context.Context, name string, description *string, price, shopID int, originalPrice *int, imageURL *string) (*model.Product, error) {
 	m.ctrl.T.Helper()
@@ -85,6 +114,21 @@ func (mr *MockProductStoreMockRecorder) GetProductByID(ctx, productID interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductByID", reflect.TypeOf((*MockProductStore)(nil).GetProductByID), varargs...)
 }
 
+// GetProductsByNames mocks base method.
+func (m *MockProductStore) GetProductsByNames(ctx context.Context, shopID int, names []string) ([]model.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProductsByNames", ctx, shopID, names)
+	ret0, _ := ret[0].([]model.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProductsByNames indicates an expected call of GetProductsByNames.
+func (mr *MockProductStoreMockRecorder) GetProductsByNames(ctx, shopID, names interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductsByNames", reflect.TypeOf((*MockProductStore)(nil).GetProductsByNames), ctx, shopID, names)
+}
+
 // GetProductsByShopID mocks base method.
 func (m *MockProductStore) GetProductsByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]model.Product, error) {
 	m.ctrl.T.Helper()
@@ -115,6 +159,20 @@ func (mr *MockProductStoreMockRecorder) GetProductsListByActiveOrders(ctx, shopI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductsListByActiveOrders", reflect.TypeOf((*MockProductStore)(nil).GetProductsListByActiveOrders), ctx, shopID)
 }
 
+// ReorderProducts mocks base method.
+func (m *MockProductStore) ReorderProducts(ctx context.Context, shopID int, productIDs []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReorderProducts", ctx, shopID, productIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReorderProducts indicates an expected call of ReorderProducts.
+func (mr *MockProductStoreMockRecorder) ReorderProducts(ctx, shopID, productIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderProducts", reflect.TypeOf((*MockProductStore)(nil).ReorderProducts), ctx, shopID, productIDs)
+}
+
 // SetAllProductsStatusByShopID mocks base method.
 func (m *MockProductStore) SetAllProductsStatusByShopID(ctx context.Context, shopID int, isActive bool) error {
 	m.ctrl.T.Helper()
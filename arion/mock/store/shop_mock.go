@@ -7,10 +7,12 @@ package mock_store
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	database "github.com/zeirash/recapo/arion/common/database"
 	model "github.com/zeirash/recapo/arion/model"
+	store "github.com/zeirash/recapo/arion/store"
 )
 
 // MockShopStore is a mock of ShopStore interface.
@@ -36,6 +38,20 @@ func (m *MockShopStore) EXPECT() *MockShopStoreMockRecorder {
 	return m.recorder
 }
 
+// CloseShop mocks base method.
+func (m *MockShopStore) CloseShop(ctx context.Context, tx database.Tx, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseShop", ctx, tx, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseShop indicates an expected call of CloseShop.
+func (mr *MockShopStoreMockRecorder) CloseShop(ctx, tx, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseShop", reflect.TypeOf((*MockShopStore)(nil).CloseShop), ctx, tx, shopID)
+}
+
 // CreateShop mocks base method.
 func (m *MockShopStore) CreateShop(ctx context.Context, tx database.Tx, name string) (*model.Shop, error) {
 	m.ctrl.T.Helper()
@@ -51,47 +67,91 @@ func (mr *MockShopStoreMockRecorder) CreateShop(ctx, tx, name interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShop", reflect.TypeOf((*MockShopStore)(nil).CreateShop), ctx, tx, name)
 }
 
-// GetShareTokenByID mocks base method.
-func (m *MockShopStore) GetShareTokenByID(ctx context.Context, shopID int) (string, error) {
+// GetShopByID mocks base method.
+func (m *MockShopStore) GetShopByID(ctx context.Context, shopID int) (*model.Shop, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetShareTokenByID", ctx, shopID)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GetShopByID", ctx, shopID)
+	ret0, _ := ret[0].(*model.Shop)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetShareTokenByID indicates an expected call of GetShareTokenByID.
-func (mr *MockShopStoreMockRecorder) GetShareTokenByID(ctx, shopID interface{}) *gomock.Call {
+// GetShopByID indicates an expected call of GetShopByID.
+func (mr *MockShopStoreMockRecorder) GetShopByID(ctx, shopID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShareTokenByID", reflect.TypeOf((*MockShopStore)(nil).GetShareTokenByID), ctx, shopID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShopByID", reflect.TypeOf((*MockShopStore)(nil).GetShopByID), ctx, shopID)
 }
 
-// GetShopByID mocks base method.
-func (m *MockShopStore) GetShopByID(ctx context.Context, shopID int) (*model.Shop, error) {
+// GetShopImageURLs mocks base method.
+func (m *MockShopStore) GetShopImageURLs(ctx context.Context, shopID int) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetShopByID", ctx, shopID)
-	ret0, _ := ret[0].(*model.Shop)
+	ret := m.ctrl.Call(m, "GetShopImageURLs", ctx, shopID)
+	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetShopByID indicates an expected call of GetShopByID.
-func (mr *MockShopStoreMockRecorder) GetShopByID(ctx, shopID interface{}) *gomock.Call {
+// GetShopImageURLs indicates an expected call of GetShopImageURLs.
+func (mr *MockShopStoreMockRecorder) GetShopImageURLs(ctx, shopID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShopByID", reflect.TypeOf((*MockShopStore)(nil).GetShopByID), ctx, shopID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShopImageURLs", reflect.TypeOf((*MockShopStore)(nil).GetShopImageURLs), ctx, shopID)
+}
+
+// GetShopsToPurge mocks base method.
+func (m *MockShopStore) GetShopsToPurge(ctx context.Context, closedBefore time.Time) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetShopsToPurge", ctx, closedBefore)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetShopsToPurge indicates an expected call of GetShopsToPurge.
+func (mr *MockShopStoreMockRecorder) GetShopsToPurge(ctx, closedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShopsToPurge", reflect.TypeOf((*MockShopStore)(nil).GetShopsToPurge), ctx, closedBefore)
+}
+
+// PurgeShop mocks base method.
+func (m *MockShopStore) PurgeShop(ctx context.Context, tx database.Tx, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeShop", ctx, tx, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeShop indicates an expected call of PurgeShop.
+func (mr *MockShopStoreMockRecorder) PurgeShop(ctx, tx, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeShop", reflect.TypeOf((*MockShopStore)(nil).PurgeShop), ctx, tx, shopID)
+}
+
+// ReopenShop mocks base method.
+func (m *MockShopStore) ReopenShop(ctx context.Context, shopID int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReopenShop", ctx, shopID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReopenShop indicates an expected call of ReopenShop.
+func (mr *MockShopStoreMockRecorder) ReopenShop(ctx, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReopenShop", reflect.TypeOf((*MockShopStore)(nil).ReopenShop), ctx, shopID)
 }
 
-// GetShopByShareToken mocks base method.
-func (m *MockShopStore) GetShopByShareToken(ctx context.Context, shareToken string) (*model.Shop, error) {
+// UpdateShop mocks base method.
+func (m *MockShopStore) UpdateShop(ctx context.Context, shopID int, input store.UpdateShopInput) (*model.Shop, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetShopByShareToken", ctx, shareToken)
+	ret := m.ctrl.Call(m, "UpdateShop", ctx, shopID, input)
 	ret0, _ := ret[0].(*model.Shop)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetShopByShareToken indicates an expected call of GetShopByShareToken.
-func (mr *MockShopStoreMockRecorder) GetShopByShareToken(ctx, shareToken interface{}) *gomock.Call {
+// UpdateShop indicates an expected call of UpdateShop.
+func (mr *MockShopStoreMockRecorder) UpdateShop(ctx, shopID, input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShopByShareToken", reflect.TypeOf((*MockShopStore)(nil).GetShopByShareToken), ctx, shareToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateShop", reflect.TypeOf((*MockShopStore)(nil).UpdateShop), ctx, shopID, input)
 }
@@ -170,6 +170,20 @@ func (mr *MockUserStoreMockRecorder) IsValidRole(role interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsValidRole", reflect.TypeOf((*MockUserStore)(nil).IsValidRole), role)
 }
 
+// ResetSessionTokensByShopID mocks base method.
+func (m *MockUserStore) ResetSessionTokensByShopID(ctx context.Context, tx database.Tx, shopID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetSessionTokensByShopID", ctx, tx, shopID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetSessionTokensByShopID indicates an expected call of ResetSessionTokensByShopID.
+func (mr *MockUserStoreMockRecorder) ResetSessionTokensByShopID(ctx, tx, shopID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetSessionTokensByShopID", reflect.TypeOf((*MockUserStore)(nil).ResetSessionTokensByShopID), ctx, tx, shopID)
+}
+
 // Roles mocks base method.
 func (m *MockUserStore) Roles() []string {
 	m.ctrl.T.Helper()
@@ -40,17 +40,39 @@ type (
 		SearchQuery *string
 		Sort        *string // value: column,order. E.g. created_at,desc
 		IsActive    *bool
+		CategoryID  *int     // products in the category or its subcategories
+		Tags        []string // products with any of the tags, case-insensitive
 	}
 
-	// OrderFilterOptions holds optional filters for listing orders.
-	// Used by handler and service; store consumes it. Add fields as needed (e.g. Status, CustomerID).
+	// CustomerFilterOptions holds optional filters for listing customers. As
+	// with OrderFilterOptions, stores read LastOrderFrom and LastOrderTo as
+	// the instants [LastOrderFrom, LastOrderTo).
+	CustomerFilterOptions struct {
+		SearchQuery    *string
+		Sort           *string  // value: column,order. E.g. total_spent,desc
+		Tags           []string // customers with any of the tags, case-insensitive
+		Blocked        *bool
+		MinOrders      *int
+		MinSpent       *int
+		HasOutstanding *bool
+		LastOrderFrom  *time.Time
+		LastOrderTo    *time.Time
+	}
+
+	// OrderFilterOptions holds optional filters for listing orders and their
+	// items, payments and refunds. Used by handler and service; store consumes
+	// it. Stores read DateFrom and DateTo as the instants [DateFrom, DateTo);
+	// services turn the requested calendar dates into them in the shop's
+	// timezone.
 	OrderFilterOptions struct {
-		SearchQuery   *string
-		DateFrom      *time.Time
-		DateTo        *time.Time
-		Status        []string
-		PaymentStatus *string
-		Sort          *string // value: column,order. E.g. created_at,desc
+		SearchQuery    *string
+		DateFrom       *time.Time
+		DateTo         *time.Time
+		Status         []string
+		PaymentStatus  *string
+		DPOverdue      *bool
+		ShortageNotify *bool   // orders flagged for a shortage notification
+		Sort           *string // value: column,order. E.g. created_at,desc
 	}
 
 	// SystemPaymentFilterOptions holds optional filters for listing payments in system mode.
@@ -76,80 +98,360 @@ type (
 
 	/********************* Shop ************************/
 	Shop struct {
+		ID                   int          `db:"id"`
+		Name                 string       `db:"name"`
+		UniqueCodeEnabled    bool         `db:"unique_code_enabled"`
+		UniqueCodeAsFee      bool         `db:"unique_code_as_fee"`
+		Address              string       `db:"address"`
+		LogoURL              string       `db:"logo_url"`
+		BankAccounts         string       `db:"bank_accounts"` // one account per line
+		InvoiceFooter        string       `db:"invoice_footer"`
+		InvoiceNumberPattern string       `db:"invoice_number_pattern"`
+		Timezone             string       `db:"timezone"` // IANA name, e.g. Asia/Jakarta
+		Locale               string       `db:"locale"`   // language of customer-facing documents
+		Description          string       `db:"description"`
+		WhatsApp             string       `db:"whatsapp"`  // international digits, e.g. 6281234567890
+		Instagram            string       `db:"instagram"` // handle without "@"
+		InvoiceMessage       string       `db:"invoice_message"`
+		Currency             string       `db:"currency"`  // ISO 4217 code
+		ClosedAt             sql.NullTime `db:"closed_at"` // set when the owner closed the shop
+		CreatedAt            time.Time    `db:"created_at"`
+		UpdatedAt            sql.NullTime `db:"updated_at"`
+	}
+
+	// ShareLink is a storefront link of a shop. A link with ProductIDs only
+	// shows those products.
+	ShareLink struct {
 		ID         int          `db:"id"`
-		Name       string       `db:"name"`
-		ShareToken string       `db:"share_token"`
+		ShopID     int          `db:"shop_id"`
+		Token      string       `db:"token"`
+		Label      string       `db:"label"`
+		ExpiresAt  sql.NullTime `db:"expires_at"`
+		RevokedAt  sql.NullTime `db:"revoked_at"`
+		ProductIDs []int
+		TempOrders int          // temp orders placed through the link
+		Accepted   int          // of which accepted by the shop
 		CreatedAt  time.Time    `db:"created_at"`
 		UpdatedAt  sql.NullTime `db:"updated_at"`
 	}
 
 	/******************* Customer *********************/
 	Customer struct {
-		ID        int          `db:"id"`
-		ShopID    int          `db:"shop_id"`
-		Name      string       `db:"name"`
-		Phone     string       `db:"phone"`
-		Address   string       `db:"address"`
-		CreatedAt time.Time    `db:"created_at"`
-		UpdatedAt sql.NullTime `db:"updated_at"`
-		DeletedAt sql.NullTime `db:"deleted_at"`
+		ID        int           `db:"id"`
+		ShopID    int           `db:"shop_id"`
+		Name      string        `db:"name"`
+		Phone     string        `db:"phone"`
+		Address   string        `db:"address"`
+		Priority  int           `db:"priority"` // higher goes first when bought stock is allocated
+		Tags      []string      `db:"tags"`
+		Blocked   bool          `db:"blocked"` // public temp orders from this phone are refused
+		Stats     CustomerStats // filled when listing customers
+		CreatedAt time.Time     `db:"created_at"`
+		UpdatedAt sql.NullTime  `db:"updated_at"`
+		DeletedAt sql.NullTime  `db:"deleted_at"`
+	}
+
+	// CustomerStats sums up a customer's orders over their lifetime.
+	// Cancelled orders are left out.
+	CustomerStats struct {
+		OrderCount   int
+		TotalSpent   int // order totals
+		Outstanding  int // amount due on the orders not paid in full yet
+		FirstOrderAt sql.NullTime
+		LastOrderAt  sql.NullTime
+	}
+
+	CustomerCredit struct {
+		ID         int           `db:"id"`
+		ShopID     int           `db:"shop_id"`
+		CustomerID int           `db:"customer_id"`
+		OrderID    sql.NullInt64 `db:"order_id"`
+		Type       string        `db:"type"`
+		Amount     int           `db:"amount"`
+		Notes      string        `db:"notes"`
+		CreatedAt  time.Time     `db:"created_at"`
+	}
+
+	// CustomerAddress is an entry of a customer's address book.
+	CustomerAddress struct {
+		ID         int          `db:"id"`
+		ShopID     int          `db:"shop_id"`
+		CustomerID int          `db:"customer_id"`
+		Label      string       `db:"label"` // e.g. Rumah, Kantor
+		Recipient  string       `db:"recipient"`
+		Phone      string       `db:"phone"`
+		Address    string       `db:"address"` // street, number, RT/RW
+		Province   string       `db:"province"`
+		City       string       `db:"city"`
+		District   string       `db:"district"` // kecamatan
+		PostalCode string       `db:"postal_code"`
+		IsDefault  bool         `db:"is_default"`
+		CreatedAt  time.Time    `db:"created_at"`
+		UpdatedAt  sql.NullTime `db:"updated_at"`
+	}
+
+	// ShippingAddress is the copy of an address kept on an order or temp
+	// order. AddressID is the address book entry it was taken from, if any;
+	// the entry may have changed or been deleted since.
+	ShippingAddress struct {
+		AddressID  *int   `json:"address_id,omitempty"`
+		Label      string `json:"label"`
+		Recipient  string `json:"recipient"`
+		Phone      string `json:"phone"`
+		Address    string `json:"address"`
+		Province   string `json:"province"`
+		City       string `json:"city"`
+		District   string `json:"district"`
+		PostalCode string `json:"postal_code"`
+	}
+
+	/******************* DP Rule *********************/
+	DPRule struct {
+		ID         int           `db:"id"`
+		ShopID     int           `db:"shop_id"`
+		Name       string        `db:"name"`
+		Percent    int           `db:"percent"`
+		DueDays    sql.NullInt64 `db:"due_days"`
+		DueDate    sql.NullTime  `db:"due_date"`
+		AutoCancel bool          `db:"auto_cancel"`
+		IsDefault  bool          `db:"is_default"`
+		CreatedAt  time.Time     `db:"created_at"`
+		UpdatedAt  sql.NullTime  `db:"updated_at"`
 	}
 
 	/******************* Product *********************/
 	Product struct {
-		ID            int          `db:"id"`
-		ShopID        int          `db:"shop_id"`
-		Name          string       `db:"name"`
-		Description   string       `db:"description"`
-		Price         int          `db:"price"`
-		OriginalPrice int          `db:"original_price"`
-		ImageURL      string       `db:"image_url"`
-		IsActive      bool         `db:"is_active"`
-		CreatedAt     time.Time    `db:"created_at"`
-		UpdatedAt     sql.NullTime `db:"updated_at"`
-		DeletedAt     sql.NullTime `db:"deleted_at"`
+		ID            int           `db:"id"`
+		ShopID        int           `db:"shop_id"`
+		Name          string        `db:"name"`
+		Description   string        `db:"description"`
+		Price         int           `db:"price"`
+		OriginalPrice int           `db:"original_price"`
+		ImageURL      string        `db:"image_url"`
+		IsActive      bool          `db:"is_active"`
+		CategoryID    sql.NullInt64 `db:"category_id"`
+		Position      int           `db:"position"` // order within the category
+		Tags          []string      `db:"tags"`
+		CreatedAt     time.Time     `db:"created_at"`
+		UpdatedAt     sql.NullTime  `db:"updated_at"`
+		DeletedAt     sql.NullTime  `db:"deleted_at"`
+	}
+
+	// ProductCategory groups a shop's products. Subcategories have a
+	// ParentID; Position orders a category among its siblings.
+	ProductCategory struct {
+		ID        int           `db:"id"`
+		ShopID    int           `db:"shop_id"`
+		ParentID  sql.NullInt64 `db:"parent_id"`
+		Name      string        `db:"name"`
+		Position  int           `db:"position"`
+		CreatedAt time.Time     `db:"created_at"`
+		UpdatedAt sql.NullTime  `db:"updated_at"`
+	}
+
+	ProductImage struct {
+		ID           int       `db:"id"`
+		ProductID    int       `db:"product_id"`
+		ShopID       int       `db:"shop_id"`
+		ThumbnailURL string    `db:"thumbnail_url"`
+		MediumURL    string    `db:"medium_url"`
+		FullURL      string    `db:"full_url"`
+		Position     int       `db:"position"`
+		IsPrimary    bool      `db:"is_primary"`
+		CreatedAt    time.Time `db:"created_at"`
 	}
 
 	PurchaseProduct struct {
+		ProductID   int    `db:"product_id"`
 		ProductName string `db:"name"`
 		Price       int    `db:"price"`
 		Qty         int    `db:"qty"`
+		Status      string `db:"status"`
+		BoughtQty   int    `db:"bought_qty"`
+		ActualCost  int    `db:"actual_cost"`
+		Notes       string `db:"notes"`
+	}
+
+	// PurchaseListItem is the shopping progress of one product on the purchase list.
+	PurchaseListItem struct {
+		ID         int          `db:"id"`
+		ShopID     int          `db:"shop_id"`
+		ProductID  int          `db:"product_id"`
+		Status     string       `db:"status"`
+		BoughtQty  int          `db:"bought_qty"`
+		ActualCost int          `db:"actual_cost"`
+		Notes      string       `db:"notes"`
+		CreatedAt  time.Time    `db:"created_at"`
+		UpdatedAt  sql.NullTime `db:"updated_at"`
 	}
 
 	/******************** Order **********************/
 	Order struct {
-		ID                int          `db:"id"`
-		ShopID            int          `db:"shop_id"`
-		CustomerName      string       `db:"customer_name"`
-		IsCustomerDeleted bool         `db:"is_customer_deleted"`
-		TotalPrice        int          `db:"total_price"`
-		Status            string       `db:"status"`
-		PaymentStatus     string       `db:"payment_status"`
-		Notes             string       `db:"notes"`
-		CreatedAt         time.Time    `db:"created_at"`
-		UpdatedAt         sql.NullTime `db:"updated_at"`
+		ID                int              `db:"id"`
+		ShopID            int              `db:"shop_id"`
+		CustomerID        int              `db:"customer_id"`
+		CustomerName      string           `db:"customer_name"`
+		IsCustomerDeleted bool             `db:"is_customer_deleted"`
+		TotalPrice        int              `db:"total_price"`
+		UniqueCode        int              `db:"unique_code"`
+		InvoiceNumber     sql.NullString   `db:"invoice_number"`
+		DPPercent         int              `db:"dp_percent"`
+		DPDueAt           sql.NullTime     `db:"dp_due_at"`
+		DPOverdueAt       sql.NullTime     `db:"dp_overdue_at"`
+		CancelReason      sql.NullString   `db:"cancel_reason"`
+		CancelNotes       string           `db:"cancel_notes"`
+		CancelledAt       sql.NullTime     `db:"cancelled_at"`
+		ShortageNotify    bool             `db:"shortage_notify"`
+		Status            string           `db:"status"`
+		PaymentStatus     string           `db:"payment_status"`
+		Notes             string           `db:"notes"`
+		ShippingAddress   *ShippingAddress `db:"shipping_address"`
+		CreatedAt         time.Time        `db:"created_at"`
+		UpdatedAt         sql.NullTime     `db:"updated_at"`
 	}
 
 	OrderItem struct {
-		ID            int          `db:"id"`
-		OrderID       int          `db:"order_id"`
-		ProductName   string       `db:"product_name"`
-		Price         int          `db:"price"`
-		OriginalPrice int          `db:"original_price"`
-		Qty           int          `db:"qty"`
-		CreatedAt     time.Time    `db:"created_at"`
-		UpdatedAt     sql.NullTime `db:"updated_at"`
+		ID            int           `db:"id"`
+		OrderID       int           `db:"order_id"`
+		ProductName   string        `db:"product_name"`
+		Price         int           `db:"price"`
+		OriginalPrice int           `db:"original_price"`
+		Qty           int           `db:"qty"`
+		ShortageQty   int           `db:"shortage_qty"`
+		AllocatedQty  sql.NullInt64 `db:"allocated_qty"`
+		CreatedAt     time.Time     `db:"created_at"`
+		UpdatedAt     sql.NullTime  `db:"updated_at"`
+	}
+
+	// ProductOrderItem is an order item of a product in an open order, as
+	// needed to allocate bought stock of that product over the orders.
+	ProductOrderItem struct {
+		ID                 int       `db:"id"`
+		OrderID            int       `db:"order_id"`
+		CustomerName       string    `db:"customer_name"`
+		CustomerPriority   int       `db:"customer_priority"`
+		OrderPaymentStatus string    `db:"order_payment_status"`
+		Price              int       `db:"price"`
+		Qty                int       `db:"qty"`
+		OrderCreatedAt     time.Time `db:"order_created_at"`
+	}
+
+	// OutstandingOrder is an order whose payments do not yet cover its total.
+	OutstandingOrder struct {
+		ID           int       `db:"id"`
+		CustomerName string    `db:"customer_name"`
+		TotalPrice   int       `db:"total_price"`
+		UniqueCode   int       `db:"unique_code"`
+		PaidAmount   int       `db:"paid_amount"`
+		CreatedAt    time.Time `db:"created_at"`
+	}
+
+	// DPOverdueOrder is an order whose down payment was not met by its deadline.
+	DPOverdueOrder struct {
+		ID         int  `db:"id"`
+		ShopID     int  `db:"shop_id"`
+		AutoCancel bool `db:"dp_auto_cancel"`
+	}
+
+	// ProductSalesRow is one line of the sales by product report. Sales and
+	// cost use the product's current prices, like net sales in the order stats.
+	ProductSalesRow struct {
+		ProductID   int    `db:"product_id"`
+		ProductName string `db:"product_name"`
+		Orders      int    `db:"orders"`
+		Qty         int    `db:"qty"`
+		Sales       int    `db:"sales"`
+		Cost        int    `db:"cost"`
+	}
+
+	// CustomerSalesRow is one line of the sales by customer report.
+	CustomerSalesRow struct {
+		CustomerID   int    `db:"customer_id"`
+		CustomerName string `db:"customer_name"`
+		Phone        string `db:"phone"`
+		Orders       int    `db:"orders"`
+		Total        int    `db:"total"`
+		Paid         int    `db:"paid"`
+	}
+
+	// DailyRevenueRow sums the payments booked on one day. Refunds are booked
+	// as negative payments and reported on their own.
+	DailyRevenueRow struct {
+		Date     time.Time `db:"date"`
+		Payments int       `db:"payments"`
+		Received int       `db:"received"`
+		Refunded int       `db:"refunded"`
+	}
+
+	// ReceivableRow is an order that still has an amount to collect.
+	ReceivableRow struct {
+		OrderID       int            `db:"order_id"`
+		InvoiceNumber sql.NullString `db:"invoice_number"`
+		CustomerName  string         `db:"customer_name"`
+		Phone         string         `db:"phone"`
+		PaymentStatus string         `db:"payment_status"`
+		AmountDue     int            `db:"amount_due"`
+		Paid          int            `db:"paid"`
+		DPDueAt       sql.NullTime   `db:"dp_due_at"`
+		CreatedAt     time.Time      `db:"created_at"`
+	}
+
+	// PaymentReportRow is one entry of the payment ledger; refunds have a
+	// negative amount.
+	PaymentReportRow struct {
+		ID            int            `db:"id"`
+		OrderID       int            `db:"order_id"`
+		InvoiceNumber sql.NullString `db:"invoice_number"`
+		CustomerName  string         `db:"customer_name"`
+		Amount        int            `db:"amount"`
+		CreatedAt     time.Time      `db:"created_at"`
+	}
+
+	// StatsPeriod is the UTC range [From, To) of a stats request and how it
+	// is bucketed. Timezone is the IANA name buckets are cut in.
+	StatsPeriod struct {
+		From     time.Time
+		To       time.Time
+		Bucket   string
+		Timezone string
+	}
+
+	// StatsBucket holds the activity of one day, week or month. Start is the
+	// bucket's local start as a wall clock, without a zone.
+	StatsBucket struct {
+		Start        time.Time `db:"bucket"`
+		Orders       int       `db:"orders"`
+		Sales        int       `db:"sales"`
+		Revenue      int       `db:"revenue"`
+		NetSales     int       `db:"net_sales"`
+		NewCustomers int       `db:"new_customers"`
+	}
+
+	TopProduct struct {
+		ProductID   int    `db:"product_id"`
+		ProductName string `db:"product_name"`
+		Qty         int    `db:"qty"`
+		Sales       int    `db:"sales"`
+	}
+
+	TopCustomer struct {
+		CustomerID   int    `db:"customer_id"`
+		CustomerName string `db:"customer_name"`
+		Orders       int    `db:"orders"`
+		Sales        int    `db:"sales"`
 	}
 
 	TempOrder struct {
-		ID            int          `db:"id"`
-		ShopID        int          `db:"shop_id"`
-		CustomerName  string       `db:"customer_name"`
-		CustomerPhone string       `db:"customer_phone"`
-		TotalPrice    int          `db:"total_price"`
-		Status        string       `db:"status"`
-		CreatedAt     time.Time    `db:"created_at"`
-		UpdatedAt     sql.NullTime `db:"updated_at"`
+		ID              int              `db:"id"`
+		ShopID          int              `db:"shop_id"`
+		ShareLinkID     sql.NullInt64    `db:"share_link_id"` // link the customer ordered through
+		CustomerName    string           `db:"customer_name"`
+		CustomerPhone   string           `db:"customer_phone"`
+		TotalPrice      int              `db:"total_price"`
+		Status          string           `db:"status"`
+		ShippingAddress *ShippingAddress `db:"shipping_address"` // submitted with the public order form
+		CreatedAt       time.Time        `db:"created_at"`
+		UpdatedAt       sql.NullTime     `db:"updated_at"`
 	}
 
 	TempOrderItem struct {
@@ -171,6 +473,20 @@ type (
 		UpdatedAt sql.NullTime `db:"updated_at"`
 	}
 
+	// OrderRefund is money paid back to the customer. It is booked as the
+	// negative order payment PaymentID.
+	OrderRefund struct {
+		ID        int       `db:"id"`
+		OrderID   int       `db:"order_id"`
+		PaymentID int       `db:"payment_id"`
+		Amount    int       `db:"amount"`
+		Method    string    `db:"method"`
+		Reason    string    `db:"reason"`
+		ProofURL  string    `db:"proof_url"`
+		Notes     string    `db:"notes"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
 	/******************* Invitation *********************/
 	Invitation struct {
 		ID        int          `db:"id"`
@@ -225,4 +541,13 @@ type (
 		CreatedAt        time.Time    `db:"created_at"`
 		UpdatedAt        sql.NullTime `db:"updated_at"`
 	}
+
+	/******************* Uploaded Object *******************/
+
+	UploadedObject struct {
+		Key         string    `db:"key"`
+		Size        int64     `db:"size"`
+		ContentType string    `db:"content_type"`
+		CreatedAt   time.Time `db:"created_at"`
+	}
 )
@@ -0,0 +1,454 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/storage"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+type (
+	// ArchiveService exports a shop to a zip archive and restores such an
+	// archive into another shop. The archive holds manifest.json, one JSON
+	// Lines file per table (<table>.jsonl, one row per line) and the
+	// uploaded images under images/.
+	ArchiveService interface {
+		ExportShop(ctx context.Context, shopID int, w io.Writer) error
+		ImportShop(ctx context.Context, input ImportShopInput) (response.ShopImportData, error)
+	}
+
+	arservice struct{}
+
+	// ImportShopInput is an uploaded archive to restore into ShopID.
+	ImportShopInput struct {
+		ShopID int
+		File   io.ReaderAt
+		Size   int64
+	}
+
+	// archiveManifest describes an archive. Tables counts the rows of each
+	// table file; Images lists the images the archive carries.
+	archiveManifest struct {
+		Format     string         `json:"format"`
+		Version    int            `json:"version"`
+		ExportedAt time.Time      `json:"exported_at"`
+		ShopID     int            `json:"shop_id"`
+		Tables     map[string]int `json:"tables"`
+		Images     []archiveImage `json:"images"`
+	}
+
+	// archiveImage is an uploaded image: the URL rows refer to it by, its
+	// file in the archive and the upload directory it is restored into.
+	archiveImage struct {
+		URL  string `json:"url"`
+		Path string `json:"path"`
+		Dir  string `json:"dir"`
+	}
+)
+
+const archiveManifestFile = "manifest.json"
+
+// archiveTableOrder is the order tables are written and restored in; a row
+// is only restored after the rows it points to.
+var archiveTableOrder = []string{
+	constant.ArchiveTableSettings,
+	constant.ArchiveTableDocumentSequences,
+	constant.ArchiveTableDPRules,
+	constant.ArchiveTableCustomers,
+	constant.ArchiveTableCustomerAddresses,
+	constant.ArchiveTableProductCategories,
+	constant.ArchiveTableProducts,
+	constant.ArchiveTableProductImages,
+	constant.ArchiveTableOrders,
+	constant.ArchiveTableOrderItems,
+	constant.ArchiveTableOrderPayments,
+	constant.ArchiveTableOrderRefunds,
+	constant.ArchiveTableCustomerCredits,
+	constant.ArchiveTableTempOrders,
+	constant.ArchiveTableTempOrderItems,
+}
+
+// archiveImageColumns are the columns holding uploaded image URLs, with the
+// upload directory of each.
+var archiveImageColumns = map[string]map[string]string{
+	constant.ArchiveTableSettings:      {"logo_url": "logos"},
+	constant.ArchiveTableProducts:      {"image_url": "products"},
+	constant.ArchiveTableProductImages: {"thumbnail_url": "products", "medium_url": "products", "full_url": "products"},
+	constant.ArchiveTableOrderRefunds:  {"proof_url": "refunds"},
+}
+
+// archiveRefs maps a column pointing at another archived row to the table
+// of that row; restored rows point at the new id.
+var archiveRefs = map[string]string{
+	"customer_id":   constant.ArchiveTableCustomers,
+	"product_id":    constant.ArchiveTableProducts,
+	"order_id":      constant.ArchiveTableOrders,
+	"payment_id":    constant.ArchiveTableOrderPayments,
+	"temp_order_id": constant.ArchiveTableTempOrders,
+	"dp_rule_id":    constant.ArchiveTableDPRules,
+	"category_id":   constant.ArchiveTableProductCategories,
+	"parent_id":     constant.ArchiveTableProductCategories,
+}
+
+// archiveClearedColumns point at rows the archive leaves out; they are
+// restored as NULL. Share links are not archived, so restored temp orders
+// lose the link they came through.
+var archiveClearedColumns = map[string]bool{
+	"share_link_id": true,
+}
+
+// archiveSkippedSettings are the shop columns that belong to the shop
+// rather than its data and are kept as they are on import. A restored shop
+// never inherits the closure of the shop it was exported from.
+var archiveSkippedSettings = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"closed_at":  true,
+	"purged_at":  true,
+}
+
+func NewArchiveService() ArchiveService {
+	cfg = config.GetConfig()
+
+	if archiveStore == nil {
+		archiveStore = store.NewArchiveStore()
+	}
+	if shopStore == nil {
+		shopStore = store.NewShopStore()
+	}
+	if uploadedObjectStore == nil {
+		uploadedObjectStore = store.NewUploadedObjectStore()
+	}
+	if storageBackend == nil {
+		storageBackend = storage.New(cfg)
+	}
+
+	return &arservice{}
+}
+
+// ExportShop writes the shop's archive to w. Images that can no longer be
+// read are left out of the archive; rows keep their URL.
+func (s *arservice) ExportShop(ctx context.Context, shopID int, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	manifest := archiveManifest{
+		Format:     constant.ArchiveFormat,
+		Version:    constant.ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		ShopID:     shopID,
+		Tables:     map[string]int{},
+		Images:     []archiveImage{},
+	}
+
+	var images []archiveImage
+	seen := map[string]bool{}
+	for _, table := range archiveTableOrder {
+		f, err := zw.Create(table + ".jsonl")
+		if err != nil {
+			return err
+		}
+		count := 0
+		err = archiveStore.StreamRows(ctx, shopID, table, func(row []byte) error {
+			if cols, ok := archiveImageColumns[table]; ok {
+				var values map[string]interface{}
+				if err := json.Unmarshal(row, &values); err != nil {
+					return err
+				}
+				for col, dir := range cols {
+					url, _ := values[col].(string)
+					if url == "" || seen[url] {
+						continue
+					}
+					seen[url] = true
+					images = append(images, archiveImage{URL: url, Dir: dir})
+				}
+			}
+			count++
+			if _, err := f.Write(row); err != nil {
+				return err
+			}
+			_, err := f.Write([]byte{'\n'})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		manifest.Tables[table] = count
+	}
+
+	for i, img := range images {
+		data, err := readUploadedImage(ctx, img.URL)
+		if err != nil {
+			logger.WithError(err).WithField("url", img.URL).Warn("archive_image_read_error")
+			continue
+		}
+		img.Path = fmt.Sprintf("images/%d%s", i+1, path.Ext(img.URL))
+		f, err := zw.Create(img.Path)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+		manifest.Images = append(manifest.Images, img)
+	}
+
+	f, err := zw.Create(archiveManifestFile)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ImportShop restores an archive into a shop that has no customers,
+// products or orders yet. Rows get new ids and references between them are
+// rewritten to match; images are uploaded again. The rows are restored in
+// one transaction, so a failed import leaves the shop empty.
+func (s *arservice) ImportShop(ctx context.Context, input ImportShopInput) (response.ShopImportData, error) {
+	zr, err := zip.NewReader(input.File, input.Size)
+	if err != nil {
+		return response.ShopImportData{}, errors.New(apierr.ErrArchiveInvalid)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifest, err := readArchiveManifest(files[archiveManifestFile])
+	if err != nil {
+		return response.ShopImportData{}, err
+	}
+
+	shop, err := shopStore.GetShopByID(ctx, input.ShopID)
+	if err != nil {
+		return response.ShopImportData{}, err
+	}
+	if shop == nil {
+		return response.ShopImportData{}, errors.New(apierr.ErrShopNotFound)
+	}
+	empty, err := archiveStore.IsShopEmpty(ctx, input.ShopID)
+	if err != nil {
+		return response.ShopImportData{}, err
+	}
+	if !empty {
+		return response.ShopImportData{}, errors.New(apierr.ErrArchiveShopNotEmpty)
+	}
+
+	// Images go up first so restored rows can point at their new URLs.
+	imageURLs := map[string]string{}
+	for _, img := range manifest.Images {
+		url, err := restoreArchiveImage(ctx, files[img.Path], img.Dir)
+		if err != nil {
+			return response.ShopImportData{}, err
+		}
+		imageURLs[img.URL] = url
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.ShopImportData{}, err
+	}
+	defer tx.Rollback()
+
+	result := response.ShopImportData{
+		ShopID: input.ShopID,
+		Tables: map[string]int{},
+		Images: len(imageURLs),
+	}
+	ids := map[string]map[int]int{}
+	for _, table := range archiveTableOrder {
+		f, ok := files[table+".jsonl"]
+		if !ok {
+			continue
+		}
+		count, err := restoreArchiveTable(ctx, tx, input.ShopID, table, f, ids, imageURLs)
+		if err != nil {
+			return response.ShopImportData{}, err
+		}
+		result.Tables[table] = count
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.ShopImportData{}, err
+	}
+
+	return result, nil
+}
+
+func readArchiveManifest(f *zip.File) (archiveManifest, error) {
+	var manifest archiveManifest
+	if f == nil {
+		return manifest, errors.New(apierr.ErrArchiveInvalid)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return manifest, errors.New(apierr.ErrArchiveInvalid)
+	}
+	defer rc.Close()
+
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil || manifest.Format != constant.ArchiveFormat || manifest.Version < 1 {
+		return manifest, errors.New(apierr.ErrArchiveInvalid)
+	}
+	if manifest.Version > constant.ArchiveVersion {
+		return manifest, errors.New(apierr.ErrArchiveVersionUnsupported)
+	}
+	return manifest, nil
+}
+
+// restoreArchiveImage uploads an archived image into dir and returns its
+// new URL.
+func restoreArchiveImage(ctx context.Context, f *zip.File, dir string) (string, error) {
+	if f == nil || (dir != "products" && dir != "logos" && dir != "refunds") {
+		return "", errors.New(apierr.ErrArchiveInvalid)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", errors.New(apierr.ErrArchiveInvalid)
+	}
+	defer rc.Close()
+	return uploadImage(ctx, rc, dir)
+}
+
+// restoreArchiveTable restores the rows of one table file and records the
+// new id of every row in ids.
+func restoreArchiveTable(ctx context.Context, tx database.Tx, shopID int, table string, f *zip.File, ids map[string]map[int]int, imageURLs map[string]string) (int, error) {
+	columns, err := archiveStore.GetColumns(ctx, tx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return 0, errors.New(apierr.ErrArchiveInvalid)
+	}
+	defer rc.Close()
+
+	ids[table] = map[int]int{}
+	dec := json.NewDecoder(rc)
+	dec.UseNumber()
+	count := 0
+	for {
+		var row map[string]interface{}
+		if err := dec.Decode(&row); err == io.EOF {
+			return count, nil
+		} else if err != nil || row == nil {
+			return 0, errors.New(apierr.ErrArchiveInvalid)
+		}
+
+		oldID, err := rewriteArchiveRow(table, row, shopID, ids, imageURLs)
+		if err != nil {
+			return 0, err
+		}
+		cols := archiveRowColumns(table, columns, row)
+		data, err := json.Marshal(row)
+		if err != nil {
+			return 0, err
+		}
+
+		if table == constant.ArchiveTableSettings {
+			if err := archiveStore.UpdateSettings(ctx, tx, shopID, cols, data); err != nil {
+				return 0, err
+			}
+		} else {
+			id, err := archiveStore.InsertRow(ctx, tx, table, cols, data)
+			if err != nil {
+				return 0, err
+			}
+			if oldID > 0 {
+				ids[table][oldID] = id
+			}
+		}
+		count++
+	}
+}
+
+// rewriteArchiveRow points an archived row at the shop it is restored into:
+// shop_id becomes the new shop, references become the new ids of the rows
+// they point to and image URLs become the re-uploaded images. It returns
+// the row's id in the archive, 0 when it has none.
+func rewriteArchiveRow(table string, row map[string]interface{}, shopID int, ids map[string]map[int]int, imageURLs map[string]string) (int, error) {
+	oldID := 0
+	if v, ok := row["id"]; ok {
+		id, ok := archiveInt(v)
+		if !ok {
+			return 0, errors.New(apierr.ErrArchiveInvalid)
+		}
+		oldID = id
+	}
+
+	for col, v := range row {
+		if v == nil {
+			continue
+		}
+		if col == "shop_id" {
+			row[col] = shopID
+			continue
+		}
+		if archiveClearedColumns[col] {
+			row[col] = nil
+			continue
+		}
+		if ref, ok := archiveRefs[col]; ok {
+			old, ok := archiveInt(v)
+			if !ok {
+				return 0, errors.New(apierr.ErrArchiveInvalid)
+			}
+			id, ok := ids[ref][old]
+			if !ok {
+				return 0, errors.New(apierr.ErrArchiveInvalid)
+			}
+			row[col] = id
+			continue
+		}
+		if _, ok := archiveImageColumns[table][col]; ok {
+			if url, ok := imageURLs[fmt.Sprint(v)]; ok {
+				row[col] = url
+			}
+		}
+	}
+	return oldID, nil
+}
+
+// archiveRowColumns returns the columns of the table, in table order, that
+// the row has a value for and that are restored. The id is always left to
+// the database.
+func archiveRowColumns(table string, columns []string, row map[string]interface{}) []string {
+	cols := []string{}
+	for _, c := range columns {
+		if c == "id" || (table == constant.ArchiveTableSettings && archiveSkippedSettings[c]) {
+			continue
+		}
+		if _, ok := row[c]; ok {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+func archiveInt(v interface{}) (int, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return 0, false
+	}
+	return int(i), true
+}
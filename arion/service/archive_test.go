@@ -0,0 +1,471 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+var archivePNG = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x01, 0x02}
+
+// buildTestArchive zips the given files, keyed by name.
+func buildTestArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readTestArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("archive is not a zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+func Test_arservice_ExportShop(t *testing.T) {
+	backend := useMemoryStorage(t)
+	if err := backend.Put(context.Background(), "products/a.png", "image/png", archivePNG); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := map[string][]string{
+		constant.ArchiveTableSettings:  {`{"id":3,"name":"Toko","logo_url":""}`},
+		constant.ArchiveTableCustomers: {`{"id":11,"shop_id":3,"name":"Budi"}`, `{"id":12,"shop_id":3,"name":"Siti"}`},
+		constant.ArchiveTableProducts: {
+			`{"id":5,"shop_id":3,"name":"Kopi","image_url":"/uploads/products/a.png"}`,
+			`{"id":6,"shop_id":3,"name":"Teh","image_url":"/uploads/products/a.png"}`,
+			`{"id":7,"shop_id":3,"name":"Susu","image_url":"/uploads/products/missing.png"}`,
+		},
+	}
+
+	t.Run("writes tables, images and manifest", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldArchiveStore := archiveStore
+		defer func() { archiveStore = oldArchiveStore }()
+
+		mockArchive := mock_store.NewMockArchiveStore(ctrl)
+		var tables []string
+		mockArchive.EXPECT().StreamRows(gomock.Any(), 3, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, shopID int, table string, fn func([]byte) error) error {
+				tables = append(tables, table)
+				for _, row := range rows[table] {
+					if err := fn([]byte(row)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Times(len(archiveTableOrder))
+		archiveStore = mockArchive
+
+		var buf bytes.Buffer
+		if err := (&arservice{}).ExportShop(context.Background(), 3, &buf); err != nil {
+			t.Fatalf("ExportShop() error = %v", err)
+		}
+		if !reflect.DeepEqual(tables, archiveTableOrder) {
+			t.Errorf("tables streamed = %v, want %v", tables, archiveTableOrder)
+		}
+
+		files := readTestArchive(t, buf.Bytes())
+		if got, want := files["customers.jsonl"], rows[constant.ArchiveTableCustomers][0]+"\n"+rows[constant.ArchiveTableCustomers][1]+"\n"; got != want {
+			t.Errorf("customers.jsonl = %q, want %q", got, want)
+		}
+		if got := files["orders.jsonl"]; got != "" {
+			t.Errorf("orders.jsonl = %q, want empty", got)
+		}
+		if got := files["images/1.png"]; got != string(archivePNG) {
+			t.Errorf("images/1.png = %q, want the uploaded image", got)
+		}
+		if _, ok := files["images/2.png"]; ok {
+			t.Error("unreadable image should be left out")
+		}
+
+		var manifest archiveManifest
+		if err := json.Unmarshal([]byte(files[archiveManifestFile]), &manifest); err != nil {
+			t.Fatalf("manifest: %v", err)
+		}
+		if manifest.Format != constant.ArchiveFormat || manifest.Version != constant.ArchiveVersion || manifest.ShopID != 3 {
+			t.Errorf("manifest = %+v", manifest)
+		}
+		if manifest.Tables[constant.ArchiveTableProducts] != 3 || manifest.Tables[constant.ArchiveTableOrders] != 0 {
+			t.Errorf("manifest.Tables = %v", manifest.Tables)
+		}
+		wantImages := []archiveImage{{URL: "/uploads/products/a.png", Path: "images/1.png", Dir: "products"}}
+		if !reflect.DeepEqual(manifest.Images, wantImages) {
+			t.Errorf("manifest.Images = %+v, want %+v", manifest.Images, wantImages)
+		}
+	})
+
+	t.Run("returns store error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldArchiveStore := archiveStore
+		defer func() { archiveStore = oldArchiveStore }()
+
+		mockArchive := mock_store.NewMockArchiveStore(ctrl)
+		mockArchive.EXPECT().StreamRows(gomock.Any(), 3, constant.ArchiveTableSettings, gomock.Any()).Return(errors.New("db error"))
+		archiveStore = mockArchive
+
+		err := (&arservice{}).ExportShop(context.Background(), 3, io.Discard)
+		if err == nil || err.Error() != "db error" {
+			t.Errorf("ExportShop() error = %v, want db error", err)
+		}
+	})
+}
+
+func Test_arservice_ImportShop_validation(t *testing.T) {
+	validManifest := `{"format":"recapo-shop-archive","version":1,"tables":{},"images":[]}`
+
+	tests := []struct {
+		name       string
+		archive    []byte
+		mockSetup  func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockArchiveStore)
+		wantErrMsg string
+	}{
+		{
+			name:       "not a zip",
+			archive:    []byte("not a zip"),
+			wantErrMsg: apierr.ErrArchiveInvalid,
+		},
+		{
+			name:       "missing manifest",
+			archive:    buildTestArchive(t, map[string]string{"customers.jsonl": ""}),
+			wantErrMsg: apierr.ErrArchiveInvalid,
+		},
+		{
+			name:       "foreign format",
+			archive:    buildTestArchive(t, map[string]string{archiveManifestFile: `{"format":"other","version":1}`}),
+			wantErrMsg: apierr.ErrArchiveInvalid,
+		},
+		{
+			name:       "newer version",
+			archive:    buildTestArchive(t, map[string]string{archiveManifestFile: `{"format":"recapo-shop-archive","version":2}`}),
+			wantErrMsg: apierr.ErrArchiveVersionUnsupported,
+		},
+		{
+			name:    "shop not found",
+			archive: buildTestArchive(t, map[string]string{archiveManifestFile: validManifest}),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockArchiveStore) {
+				mockShop := mock_store.NewMockShopStore(ctrl)
+				mockShop.EXPECT().GetShopByID(gomock.Any(), 8).Return(nil, nil)
+				return mockShop, mock_store.NewMockArchiveStore(ctrl)
+			},
+			wantErrMsg: apierr.ErrShopNotFound,
+		},
+		{
+			name:    "shop already has data",
+			archive: buildTestArchive(t, map[string]string{archiveManifestFile: validManifest}),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockArchiveStore) {
+				mockShop := mock_store.NewMockShopStore(ctrl)
+				mockShop.EXPECT().GetShopByID(gomock.Any(), 8).Return(&model.Shop{ID: 8}, nil)
+				mockArchive := mock_store.NewMockArchiveStore(ctrl)
+				mockArchive.EXPECT().IsShopEmpty(gomock.Any(), 8).Return(false, nil)
+				return mockShop, mockArchive
+			},
+			wantErrMsg: apierr.ErrArchiveShopNotEmpty,
+		},
+		{
+			name: "image missing from archive",
+			archive: buildTestArchive(t, map[string]string{
+				archiveManifestFile: `{"format":"recapo-shop-archive","version":1,"images":[{"url":"/uploads/products/a.png","path":"images/1.png","dir":"products"}]}`,
+			}),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockArchiveStore) {
+				mockShop := mock_store.NewMockShopStore(ctrl)
+				mockShop.EXPECT().GetShopByID(gomock.Any(), 8).Return(&model.Shop{ID: 8}, nil)
+				mockArchive := mock_store.NewMockArchiveStore(ctrl)
+				mockArchive.EXPECT().IsShopEmpty(gomock.Any(), 8).Return(true, nil)
+				return mockShop, mockArchive
+			},
+			wantErrMsg: apierr.ErrArchiveInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldShopStore, oldArchiveStore := shopStore, archiveStore
+			defer func() {
+				shopStore, archiveStore = oldShopStore, oldArchiveStore
+			}()
+			if tt.mockSetup != nil {
+				shopStore, archiveStore = tt.mockSetup(ctrl)
+			}
+
+			_, err := (&arservice{}).ImportShop(context.Background(), ImportShopInput{
+				ShopID: 8,
+				File:   bytes.NewReader(tt.archive),
+				Size:   int64(len(tt.archive)),
+			})
+			if err == nil || err.Error() != tt.wantErrMsg {
+				t.Errorf("ImportShop() error = %v, want %v", err, tt.wantErrMsg)
+			}
+		})
+	}
+}
+
+func Test_arservice_ImportShop(t *testing.T) {
+	useMemoryStorage(t)
+
+	archive := buildTestArchive(t, map[string]string{
+		archiveManifestFile:      `{"format":"recapo-shop-archive","version":1,"shop_id":3,"images":[{"url":"/uploads/products/a.png","path":"images/1.png","dir":"products"},{"url":"/uploads/refunds/b.png","path":"images/2.png","dir":"refunds"}]}`,
+		"images/1.png":           string(archivePNG),
+		"images/2.png":           string(archivePNG),
+		"settings.jsonl":         `{"id":3,"name":"Toko","share_token":"abc","created_at":"2024-01-15T10:30:00","currency":"IDR","closed_at":"2024-02-01T00:00:00+00:00","purged_at":null,"dropped":1}` + "\n",
+		"customers.jsonl":        `{"id":11,"shop_id":3,"name":"Budi"}` + "\n" + `{"id":12,"shop_id":3,"name":"Siti"}` + "\n",
+		"products.jsonl":         `{"id":5,"shop_id":3,"name":"Kopi","image_url":"/uploads/products/a.png"}` + "\n",
+		"orders.jsonl":           `{"id":20,"shop_id":3,"customer_id":12,"dp_rule_id":null,"shipping_address":{"city":"Bandung"}}` + "\n",
+		"order_items.jsonl":      `{"id":30,"order_id":20,"product_id":5,"qty":2}` + "\n",
+		"order_payments.jsonl":   `{"id":50,"order_id":20,"amount":-5000}` + "\n",
+		"order_refunds.jsonl":    `{"id":60,"order_id":20,"payment_id":50,"amount":5000,"proof_url":"/uploads/refunds/b.png"}` + "\n",
+		"customer_credits.jsonl": `{"id":70,"shop_id":3,"customer_id":12,"order_id":20,"amount":2500}` + "\n",
+		"temp_orders.jsonl":      `{"id":40,"shop_id":3,"share_link_id":9,"customer_name":"Andi"}` + "\n",
+	})
+
+	columns := map[string][]string{
+		constant.ArchiveTableSettings:        {"id", "name", "currency", "created_at", "updated_at", "closed_at", "purged_at"},
+		constant.ArchiveTableCustomers:       {"id", "shop_id", "name", "phone"},
+		constant.ArchiveTableProducts:        {"id", "shop_id", "name", "image_url"},
+		constant.ArchiveTableOrders:          {"id", "shop_id", "customer_id", "dp_rule_id", "shipping_address"},
+		constant.ArchiveTableOrderItems:      {"id", "order_id", "product_id", "qty"},
+		constant.ArchiveTableOrderPayments:   {"id", "order_id", "amount"},
+		constant.ArchiveTableOrderRefunds:    {"id", "order_id", "payment_id", "amount", "proof_url"},
+		constant.ArchiveTableCustomerCredits: {"id", "shop_id", "customer_id", "order_id", "amount"},
+		constant.ArchiveTableTempOrders:      {"id", "shop_id", "share_link_id", "customer_name"},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldShopStore, oldArchiveStore, oldDBGetter := shopStore, archiveStore, dbGetter
+	defer func() {
+		shopStore, archiveStore, dbGetter = oldShopStore, oldArchiveStore, oldDBGetter
+	}()
+
+	mockShop := mock_store.NewMockShopStore(ctrl)
+	mockShop.EXPECT().GetShopByID(gomock.Any(), 8).Return(&model.Shop{ID: 8}, nil)
+
+	mockTx := mock_database.NewMockTx(ctrl)
+	mockTx.EXPECT().Commit().Return(nil)
+	mockTx.EXPECT().Rollback().Return(nil)
+	mockDB := mock_database.NewMockDB(ctrl)
+	mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+	type insert struct {
+		table   string
+		columns []string
+		row     map[string]interface{}
+	}
+	var inserts []insert
+	nextID := 100
+
+	mockArchive := mock_store.NewMockArchiveStore(ctrl)
+	mockArchive.EXPECT().IsShopEmpty(gomock.Any(), 8).Return(true, nil)
+	mockArchive.EXPECT().GetColumns(gomock.Any(), mockTx, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, tx database.Tx, table string) ([]string, error) {
+			return columns[table], nil
+		}).Times(len(columns))
+	mockArchive.EXPECT().UpdateSettings(gomock.Any(), mockTx, 8, []string{"name", "currency"}, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, tx database.Tx, shopID int, cols []string, row []byte) error {
+			if !strings.Contains(string(row), `"name":"Toko"`) {
+				t.Errorf("settings row = %s", row)
+			}
+			return nil
+		})
+	mockArchive.EXPECT().InsertRow(gomock.Any(), mockTx, gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, tx database.Tx, table string, cols []string, row []byte) (int, error) {
+			var values map[string]interface{}
+			if err := json.Unmarshal(row, &values); err != nil {
+				t.Fatalf("row %s: %v", row, err)
+			}
+			inserts = append(inserts, insert{table: table, columns: cols, row: values})
+			nextID++
+			return nextID, nil
+		}).Times(9)
+
+	shopStore, archiveStore = mockShop, mockArchive
+	dbGetter = func() database.DB { return mockDB }
+
+	got, err := (&arservice{}).ImportShop(context.Background(), ImportShopInput{
+		ShopID: 8,
+		File:   bytes.NewReader(archive),
+		Size:   int64(len(archive)),
+	})
+	if err != nil {
+		t.Fatalf("ImportShop() error = %v", err)
+	}
+
+	want := response.ShopImportData{
+		ShopID: 8,
+		Tables: map[string]int{
+			constant.ArchiveTableSettings:        1,
+			constant.ArchiveTableCustomers:       2,
+			constant.ArchiveTableProducts:        1,
+			constant.ArchiveTableOrders:          1,
+			constant.ArchiveTableOrderItems:      1,
+			constant.ArchiveTableOrderPayments:   1,
+			constant.ArchiveTableOrderRefunds:    1,
+			constant.ArchiveTableCustomerCredits: 1,
+			constant.ArchiveTableTempOrders:      1,
+		},
+		Images: 2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportShop() = %+v, want %+v", got, want)
+	}
+
+	if len(inserts) != 9 {
+		t.Fatalf("inserted %d rows, want 9", len(inserts))
+	}
+	// customers 11 and 12 became 101 and 102, product 5 became 103,
+	// order 20 became 104 and payment 50 became 106.
+	product := inserts[2]
+	imageURL, _ := product.row["image_url"].(string)
+	if !strings.HasPrefix(imageURL, "/uploads/products/") || imageURL == "/uploads/products/a.png" {
+		t.Errorf("product image_url = %q, want the re-uploaded image", imageURL)
+	}
+	if _, err := readUploadedImage(context.Background(), imageURL); err != nil {
+		t.Errorf("re-uploaded image not found: %v", err)
+	}
+
+	order := inserts[3]
+	if !reflect.DeepEqual(order.columns, []string{"shop_id", "customer_id", "dp_rule_id", "shipping_address"}) {
+		t.Errorf("order columns = %v", order.columns)
+	}
+	if order.row["shop_id"] != float64(8) || order.row["customer_id"] != float64(102) || order.row["dp_rule_id"] != nil {
+		t.Errorf("order row = %v", order.row)
+	}
+	if !reflect.DeepEqual(order.row["shipping_address"], map[string]interface{}{"city": "Bandung"}) {
+		t.Errorf("order shipping_address = %v", order.row["shipping_address"])
+	}
+
+	item := inserts[4]
+	if item.table != constant.ArchiveTableOrderItems || item.row["order_id"] != float64(104) || item.row["product_id"] != float64(103) {
+		t.Errorf("order item = %+v", item)
+	}
+
+	refund := inserts[6]
+	proofURL, _ := refund.row["proof_url"].(string)
+	if refund.table != constant.ArchiveTableOrderRefunds || refund.row["order_id"] != float64(104) || refund.row["payment_id"] != float64(106) {
+		t.Errorf("order refund = %+v", refund)
+	}
+	if !strings.HasPrefix(proofURL, "/uploads/refunds/") || proofURL == "/uploads/refunds/b.png" {
+		t.Errorf("refund proof_url = %q, want the re-uploaded image", proofURL)
+	}
+
+	credit := inserts[7]
+	if credit.table != constant.ArchiveTableCustomerCredits || credit.row["shop_id"] != float64(8) || credit.row["customer_id"] != float64(102) || credit.row["order_id"] != float64(104) {
+		t.Errorf("customer credit = %+v", credit)
+	}
+
+	tempOrder := inserts[8]
+	if tempOrder.row["share_link_id"] != nil || tempOrder.row["shop_id"] != float64(8) {
+		t.Errorf("temp order row = %v", tempOrder.row)
+	}
+}
+
+func Test_rewriteArchiveRow(t *testing.T) {
+	ids := map[string]map[int]int{
+		constant.ArchiveTableCustomers: {11: 101},
+	}
+
+	tests := []struct {
+		name    string
+		table   string
+		row     map[string]interface{}
+		wantRow map[string]interface{}
+		wantID  int
+		wantErr bool
+	}{
+		{
+			name:    "rewrites shop and references",
+			table:   constant.ArchiveTableCustomerAddresses,
+			row:     map[string]interface{}{"id": json.Number("7"), "shop_id": json.Number("3"), "customer_id": json.Number("11"), "label": "Home"},
+			wantRow: map[string]interface{}{"id": json.Number("7"), "shop_id": 8, "customer_id": 101, "label": "Home"},
+			wantID:  7,
+		},
+		{
+			name:    "keeps null references",
+			table:   constant.ArchiveTableOrders,
+			row:     map[string]interface{}{"id": json.Number("20"), "dp_rule_id": nil},
+			wantRow: map[string]interface{}{"id": json.Number("20"), "dp_rule_id": nil},
+			wantID:  20,
+		},
+		{
+			name:    "reference to a row not in the archive",
+			table:   constant.ArchiveTableOrders,
+			row:     map[string]interface{}{"id": json.Number("20"), "customer_id": json.Number("99")},
+			wantErr: true,
+		},
+		{
+			name:    "id that is not a number",
+			table:   constant.ArchiveTableCustomers,
+			row:     map[string]interface{}{"id": "x"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := rewriteArchiveRow(tt.table, tt.row, 8, ids, nil)
+			if tt.wantErr {
+				if err == nil || err.Error() != apierr.ErrArchiveInvalid {
+					t.Errorf("rewriteArchiveRow() error = %v, want %v", err, apierr.ErrArchiveInvalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rewriteArchiveRow() error = %v", err)
+			}
+			if id != tt.wantID {
+				t.Errorf("rewriteArchiveRow() id = %d, want %d", id, tt.wantID)
+			}
+			if !reflect.DeepEqual(tt.row, tt.wantRow) {
+				t.Errorf("rewriteArchiveRow() row = %v, want %v", tt.row, tt.wantRow)
+			}
+		})
+	}
+}
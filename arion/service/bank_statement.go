@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/bankstatement"
+	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+// matchMinScore is the lowest score a credit/order pair needs before it is
+// proposed as a payment.
+const matchMinScore = 50
+
+type (
+	BankStatementService interface {
+		MatchBankStatement(ctx context.Context, shopID int, bank string, file io.Reader) (response.BankStatementMatchData, error)
+		ConfirmPaymentMatches(ctx context.Context, shopID int, matches []ConfirmPaymentMatchInput) ([]response.OrderPaymentData, error)
+	}
+
+	bsservice struct{}
+
+	// ConfirmPaymentMatchInput pays Amount of the statement credit Mutation
+	// to the order.
+	ConfirmPaymentMatchInput struct {
+		OrderID  int
+		Amount   int
+		Mutation bankstatement.Mutation
+	}
+
+	matchCandidate struct {
+		mutation  int
+		order     int
+		amount    int
+		score     int
+		matchedBy []string
+	}
+)
+
+func NewBankStatementService() BankStatementService {
+	cfg = config.GetConfig()
+
+	if orderStore == nil {
+		orderStore = store.NewOrderStore()
+	}
+
+	if orderPaymentStore == nil {
+		orderPaymentStore = store.NewOrderPaymentStore()
+	}
+
+	if shopStore == nil {
+		shopStore = store.NewShopStore()
+	}
+
+	return &bsservice{}
+}
+
+func (b *bsservice) MatchBankStatement(ctx context.Context, shopID int, bank string, file io.Reader) (response.BankStatementMatchData, error) {
+	parser, ok := bankstatement.Get(bank)
+	if !ok {
+		return response.BankStatementMatchData{}, errors.New(apierr.ErrBankNotSupported)
+	}
+
+	mutations, err := parser.Parse(file)
+	if err != nil {
+		if errors.Is(err, bankstatement.ErrUnknownFormat) || errors.Is(err, bankstatement.ErrNoMutations) {
+			return response.BankStatementMatchData{}, errors.New(apierr.ErrStatementInvalid)
+		}
+		return response.BankStatementMatchData{}, err
+	}
+
+	orders, err := orderStore.GetOutstandingOrdersByShopID(ctx, shopID)
+	if err != nil {
+		return response.BankStatementMatchData{}, err
+	}
+
+	matches, unmatched := matchPayments(bankstatement.Credits(mutations), orders)
+
+	return response.BankStatementMatchData{
+		Bank:      strings.ToLower(strings.TrimSpace(bank)),
+		Matches:   matches,
+		Unmatched: unmatched,
+	}, nil
+}
+
+// ConfirmPaymentMatches records the matches as payments. The orders are
+// locked and checked again, as payments may have come in since the statement
+// was matched, and a statement line already recorded is rejected.
+func (b *bsservice) ConfirmPaymentMatches(ctx context.Context, shopID int, matches []ConfirmPaymentMatchInput) ([]response.OrderPaymentData, error) {
+	matched := map[int]int{}
+	for _, m := range matches {
+		matched[m.OrderID] += m.Amount
+	}
+	// Locked in id order so two confirmations can't wait on each other.
+	orderIDs := make([]int, 0, len(matched))
+	for id := range matched {
+		orderIDs = append(orderIDs, id)
+	}
+	sort.Ints(orderIDs)
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	orders := map[int]*model.Order{}
+	paid := map[int]int{}
+	for _, id := range orderIDs {
+		order, err := orderStore.GetOrderByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if order == nil || order.ShopID != shopID {
+			return nil, errors.New(apierr.ErrOrderNotFound)
+		}
+		if order.Status == constant.OrderStatusCancelled || order.PaymentStatus == constant.OrderPaymentStatusPaid {
+			return nil, errors.New(apierr.ErrOrderNotOutstanding)
+		}
+
+		paid[id], err = sumOrderPayments(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if paid[id]+matched[id] > order.TotalPrice+order.UniqueCode {
+			return nil, errors.New(apierr.ErrPaymentAboveBalance)
+		}
+		orders[id] = order
+	}
+
+	res := make([]response.OrderPaymentData, 0, len(matches))
+	for _, m := range matches {
+		payment, err := orderPaymentStore.CreateOrderPayment(ctx, tx, m.OrderID, m.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		recorded, err := orderPaymentStore.CreateStatementLine(ctx, tx, store.CreateStatementLineInput{
+			ShopID:      shopID,
+			PaymentID:   payment.ID,
+			Date:        m.Mutation.Date,
+			Description: m.Mutation.Description,
+			Amount:      m.Mutation.Amount,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !recorded {
+			return nil, errors.New(apierr.ErrStatementLineMatched)
+		}
+
+		paid[m.OrderID] += payment.Amount
+		res = append(res, response.OrderPaymentData{
+			ID:        payment.ID,
+			OrderID:   payment.OrderID,
+			Amount:    payment.Amount,
+			CreatedAt: payment.CreatedAt,
+		})
+	}
+
+	for _, id := range orderIDs {
+		if err := settleUniqueCode(ctx, tx, orders[id], paid[id]); err != nil {
+			return nil, err
+		}
+		if err := settleDownPayment(ctx, tx, orders[id], paid[id]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// matchPayments pairs statement credits with outstanding orders. Every
+// credit/order pair is scored, then pairs are taken greedily from the highest
+// score so each credit and each order is proposed at most once. Ties go to
+// the oldest order.
+func matchPayments(credits []bankstatement.Mutation, orders []model.OutstandingOrder) ([]response.PaymentMatchData, []response.BankMutationData) {
+	candidates := []matchCandidate{}
+	for i, m := range credits {
+		for j, o := range orders {
+			if c, ok := scoreMatch(m, o); ok {
+				c.mutation, c.order = i, j
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].score != candidates[b].score {
+			return candidates[a].score > candidates[b].score
+		}
+		if candidates[a].order != candidates[b].order {
+			return orders[candidates[a].order].CreatedAt.Before(orders[candidates[b].order].CreatedAt)
+		}
+		return candidates[a].mutation < candidates[b].mutation
+	})
+
+	usedMutations := map[int]bool{}
+	usedOrders := map[int]bool{}
+	matches := []response.PaymentMatchData{}
+	for _, c := range candidates {
+		if usedMutations[c.mutation] || usedOrders[c.order] {
+			continue
+		}
+		usedMutations[c.mutation] = true
+		usedOrders[c.order] = true
+
+		o := orders[c.order]
+		matches = append(matches, response.PaymentMatchData{
+			Mutation:     toBankMutationData(credits[c.mutation]),
+			OrderID:      o.ID,
+			CustomerName: o.CustomerName,
+			Outstanding:  o.TotalPrice + o.UniqueCode - o.PaidAmount,
+			Amount:       c.amount,
+			Score:        c.score,
+			MatchedBy:    c.matchedBy,
+		})
+	}
+
+	unmatched := []response.BankMutationData{}
+	for i, m := range credits {
+		if !usedMutations[i] {
+			unmatched = append(unmatched, toBankMutationData(m))
+		}
+	}
+
+	return matches, unmatched
+}
+
+// scoreMatch rates how likely a credit pays an order:
+//   - the amount equals the balance including the order's unique code (80)
+//   - the amount equals the outstanding balance (60)
+//   - the order has no code and the amount exceeds its balance by a
+//     customer-chosen suffix of 1-999 (45)
+//   - the customer's name appears in the transfer description (up to 40)
+//   - a strong name match for less than the balance is a partial payment (20)
+func scoreMatch(m bankstatement.Mutation, o model.OutstandingOrder) (matchCandidate, bool) {
+	outstanding := o.TotalPrice + o.UniqueCode - o.PaidAmount
+	c := matchCandidate{amount: m.Amount, matchedBy: []string{}}
+
+	amountMatched := true
+	switch diff := m.Amount - outstanding; {
+	case diff == 0 && o.UniqueCode > 0:
+		c.score += 80
+		c.matchedBy = append(c.matchedBy, constant.PaymentMatchExactAmount, constant.PaymentMatchUniqueCode)
+	case diff == 0 || diff == -o.UniqueCode:
+		c.score += 60
+		c.matchedBy = append(c.matchedBy, constant.PaymentMatchExactAmount)
+	case o.UniqueCode == 0 && diff > 0 && diff <= constant.UniqueCodeMax:
+		c.score += 45
+		c.amount = outstanding
+		c.matchedBy = append(c.matchedBy, constant.PaymentMatchUniqueCode)
+	default:
+		amountMatched = false
+	}
+
+	sim := nameSimilarity(o.CustomerName, m.Description)
+	if sim >= 0.5 {
+		c.score += int(math.Round(40 * sim))
+		c.matchedBy = append(c.matchedBy, constant.PaymentMatchCustomerName)
+		if !amountMatched {
+			if m.Amount > outstanding || sim < 0.75 {
+				return c, false
+			}
+			c.score += 20
+		}
+	}
+
+	return c, c.score >= matchMinScore
+}
+
+// nameSimilarity returns the share of the customer's name words found in
+// the transfer description. Banks truncate sender names, so a word also
+// counts when one is a prefix of the other (at least three letters).
+func nameSimilarity(name, description string) float64 {
+	nameWords := words(name)
+	if len(nameWords) == 0 {
+		return 0
+	}
+	descWords := words(description)
+
+	found := 0
+	for _, n := range nameWords {
+		for _, d := range descWords {
+			if n == d || (len(n) >= 3 && len(d) >= 3 && (strings.HasPrefix(n, d) || strings.HasPrefix(d, n))) {
+				found++
+				break
+			}
+		}
+	}
+
+	return float64(found) / float64(len(nameWords))
+}
+
+func words(s string) []string {
+	fields := strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	res := []string{}
+	for _, f := range fields {
+		if len(f) >= 2 {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func toBankMutationData(m bankstatement.Mutation) response.BankMutationData {
+	return response.BankMutationData{
+		Date:        m.Date,
+		Description: m.Description,
+		Amount:      m.Amount,
+	}
+}
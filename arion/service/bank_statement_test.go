@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/bankstatement"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+func Test_nameSimilarity(t *testing.T) {
+	tests := []struct {
+		name        string
+		customer    string
+		description string
+		want        float64
+	}{
+		{name: "full name present", customer: "Budi Santoso", description: "TRSF E-BANKING CR 0103/FTSCY BUDI SANTOSO", want: 1},
+		{name: "truncated surname", customer: "Budi Santoso", description: "TRSF E-BANKING CR BUDI SANT", want: 1},
+		{name: "first name only", customer: "Siti Aminah", description: "TRANSFER DARI SITI", want: 0.5},
+		{name: "no overlap", customer: "Rina Wijaya", description: "BIAYA ADM", want: 0},
+		{name: "empty customer name", customer: "", description: "BUDI", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nameSimilarity(tt.customer, tt.description); got != tt.want {
+				t.Errorf("nameSimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchPayments(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	orders := []model.OutstandingOrder{
+		{ID: 1, CustomerName: "Budi Santoso", TotalPrice: 150000, CreatedAt: day},
+		{ID: 2, CustomerName: "Siti Aminah", TotalPrice: 275000, PaidAmount: 75000, CreatedAt: day.Add(time.Hour)},
+		{ID: 3, CustomerName: "Rina Wijaya", TotalPrice: 300000, CreatedAt: day.Add(2 * time.Hour)},
+		{ID: 4, CustomerName: "Andi", TotalPrice: 150000, CreatedAt: day.Add(3 * time.Hour)},
+	}
+	credits := []bankstatement.Mutation{
+		{Date: day, Description: "TRSF CR ANDI", Amount: 150000, IsCredit: true},
+		{Date: day, Description: "TRANSFER DARI SITI AMINAH", Amount: 200123, IsCredit: true},
+		{Date: day, Description: "NBMB RINA WIJAYA", Amount: 100000, IsCredit: true},
+		{Date: day, Description: "SETORAN TUNAI", Amount: 150000, IsCredit: true},
+		{Date: day, Description: "SETORAN TUNAI", Amount: 42000, IsCredit: true},
+	}
+
+	matches, unmatched := matchPayments(credits, orders)
+
+	wantMatches := []response.PaymentMatchData{
+		{
+			Mutation:     toBankMutationData(credits[0]),
+			OrderID:      4,
+			CustomerName: "Andi",
+			Outstanding:  150000,
+			Amount:       150000,
+			Score:        100,
+			MatchedBy:    []string{constant.PaymentMatchExactAmount, constant.PaymentMatchCustomerName},
+		},
+		{
+			Mutation:     toBankMutationData(credits[1]),
+			OrderID:      2,
+			CustomerName: "Siti Aminah",
+			Outstanding:  200000,
+			Amount:       200000,
+			Score:        85,
+			MatchedBy:    []string{constant.PaymentMatchUniqueCode, constant.PaymentMatchCustomerName},
+		},
+		{
+			Mutation:     toBankMutationData(credits[3]),
+			OrderID:      1,
+			CustomerName: "Budi Santoso",
+			Outstanding:  150000,
+			Amount:       150000,
+			Score:        60,
+			MatchedBy:    []string{constant.PaymentMatchExactAmount},
+		},
+		{
+			Mutation:     toBankMutationData(credits[2]),
+			OrderID:      3,
+			CustomerName: "Rina Wijaya",
+			Outstanding:  300000,
+			Amount:       100000,
+			Score:        60,
+			MatchedBy:    []string{constant.PaymentMatchCustomerName},
+		},
+	}
+	wantUnmatched := []response.BankMutationData{toBankMutationData(credits[4])}
+
+	if !reflect.DeepEqual(matches, wantMatches) {
+		t.Errorf("matchPayments() matches = %+v, want %+v", matches, wantMatches)
+	}
+	if !reflect.DeepEqual(unmatched, wantUnmatched) {
+		t.Errorf("matchPayments() unmatched = %+v, want %+v", unmatched, wantUnmatched)
+	}
+}
+
+func Test_scoreMatch_assignedUniqueCode(t *testing.T) {
+	order := model.OutstandingOrder{ID: 1, CustomerName: "Budi Santoso", TotalPrice: 150000, UniqueCode: 417}
+
+	tests := []struct {
+		name          string
+		mutation      bankstatement.Mutation
+		wantOK        bool
+		wantScore     int
+		wantMatchedBy []string
+	}{
+		{
+			name:          "amount due including code",
+			mutation:      bankstatement.Mutation{Description: "SETORAN", Amount: 150417},
+			wantOK:        true,
+			wantScore:     80,
+			wantMatchedBy: []string{constant.PaymentMatchExactAmount, constant.PaymentMatchUniqueCode},
+		},
+		{
+			name:          "total without the code",
+			mutation:      bankstatement.Mutation{Description: "SETORAN", Amount: 150000},
+			wantOK:        true,
+			wantScore:     60,
+			wantMatchedBy: []string{constant.PaymentMatchExactAmount},
+		},
+		{
+			name:     "another suffix is not guessed as a code",
+			mutation: bankstatement.Mutation{Description: "SETORAN", Amount: 150123},
+			wantOK:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := scoreMatch(tt.mutation, order)
+			if ok != tt.wantOK {
+				t.Fatalf("scoreMatch() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.score != tt.wantScore {
+				t.Errorf("scoreMatch() score = %v, want %v", got.score, tt.wantScore)
+			}
+			if !reflect.DeepEqual(got.matchedBy, tt.wantMatchedBy) {
+				t.Errorf("scoreMatch() matchedBy = %v, want %v", got.matchedBy, tt.wantMatchedBy)
+			}
+		})
+	}
+}
+
+func Test_bsservice_MatchBankStatement(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	statement := "Date,Description,Debit,Credit\n01/03/2024,TRANSFER DARI BUDI,0,150000\n02/03/2024,BIAYA ADM,6500,0\n"
+
+	tests := []struct {
+		name      string
+		bank      string
+		file      string
+		mockSetup func(ctrl *gomock.Controller) *mock_store.MockOrderStore
+		want      response.BankStatementMatchData
+		wantErr   string
+	}{
+		{
+			name: "matches credits and ignores debits",
+			bank: "Mandiri",
+			file: statement,
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				m := mock_store.NewMockOrderStore(ctrl)
+				m.EXPECT().GetOutstandingOrdersByShopID(gomock.Any(), 1).
+					Return([]model.OutstandingOrder{{ID: 7, CustomerName: "Budi", TotalPrice: 150000, CreatedAt: fixedTime}}, nil)
+				return m
+			},
+			want: response.BankStatementMatchData{
+				Bank: bankstatement.BankMandiri,
+				Matches: []response.PaymentMatchData{
+					{
+						Mutation:     response.BankMutationData{Date: fixedTime, Description: "TRANSFER DARI BUDI", Amount: 150000},
+						OrderID:      7,
+						CustomerName: "Budi",
+						Outstanding:  150000,
+						Amount:       150000,
+						Score:        100,
+						MatchedBy:    []string{constant.PaymentMatchExactAmount, constant.PaymentMatchCustomerName},
+					},
+				},
+				Unmatched: []response.BankMutationData{},
+			},
+		},
+		{
+			name: "unsupported bank",
+			bank: "bni",
+			file: statement,
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				return mock_store.NewMockOrderStore(ctrl)
+			},
+			wantErr: apierr.ErrBankNotSupported,
+		},
+		{
+			name: "unreadable statement",
+			bank: bankstatement.BankBRI,
+			file: "hello,world\n",
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				return mock_store.NewMockOrderStore(ctrl)
+			},
+			wantErr: apierr.ErrStatementInvalid,
+		},
+		{
+			name: "store error",
+			bank: bankstatement.BankMandiri,
+			file: statement,
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				m := mock_store.NewMockOrderStore(ctrl)
+				m.EXPECT().GetOutstandingOrdersByShopID(gomock.Any(), 1).Return(nil, errors.New("db error"))
+				return m
+			},
+			wantErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore := orderStore
+			defer func() { orderStore = oldOrderStore }()
+			orderStore = tt.mockSetup(ctrl)
+
+			var b bsservice
+			got, gotErr := b.MatchBankStatement(context.Background(), 1, tt.bank, strings.NewReader(tt.file))
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("MatchBankStatement() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("MatchBankStatement() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchBankStatement() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_bsservice_ConfirmPaymentMatches(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	credit := func(description string, amount int) bankstatement.Mutation {
+		return bankstatement.Mutation{Date: fixedTime, Description: description, Amount: amount, IsCredit: true}
+	}
+	matches := []ConfirmPaymentMatchInput{
+		{OrderID: 2, Amount: 50000, Mutation: credit("TRSF E-BANKING CR BUDI", 50000)},
+		{OrderID: 1, Amount: 150000, Mutation: credit("TRSF E-BANKING CR SITI", 150000)},
+	}
+	line := func(paymentID int, m bankstatement.Mutation) store.CreateStatementLineInput {
+		return store.CreateStatementLineInput{ShopID: 10, PaymentID: paymentID, Date: m.Date, Description: m.Description, Amount: m.Amount}
+	}
+	openOrder := func(id, total int) *model.Order {
+		return &model.Order{ID: id, ShopID: 10, TotalPrice: total, Status: constant.OrderStatusCreated, PaymentStatus: constant.OrderPaymentStatusOutstanding}
+	}
+
+	type mocks struct {
+		order   *mock_store.MockOrderStore
+		payment *mock_store.MockOrderPaymentStore
+		tx      *mock_database.MockTx
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(m mocks)
+		want      []response.OrderPaymentData
+		wantErr   string
+	}{
+		{
+			name: "locks the orders and creates all payments in one transaction",
+			mockSetup: func(m mocks) {
+				gomock.InOrder(
+					m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 1).Return(openOrder(1, 150000), nil),
+					m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 2).Return(openOrder(2, 100000), nil),
+				)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), m.tx, 1).Return(nil, nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), m.tx, 2).Return([]model.OrderPayment{{ID: 5, OrderID: 2, Amount: 50000}}, nil)
+
+				m.payment.EXPECT().CreateOrderPayment(gomock.Any(), m.tx, 2, 50000).
+					Return(&model.OrderPayment{ID: 12, OrderID: 2, Amount: 50000, CreatedAt: fixedTime}, nil)
+				m.payment.EXPECT().CreateStatementLine(gomock.Any(), m.tx, line(12, matches[0].Mutation)).Return(true, nil)
+				m.payment.EXPECT().CreateOrderPayment(gomock.Any(), m.tx, 1, 150000).
+					Return(&model.OrderPayment{ID: 11, OrderID: 1, Amount: 150000, CreatedAt: fixedTime}, nil)
+				m.payment.EXPECT().CreateStatementLine(gomock.Any(), m.tx, line(11, matches[1].Mutation)).Return(true, nil)
+				m.tx.EXPECT().Commit().Return(nil)
+			},
+			want: []response.OrderPaymentData{
+				{ID: 12, OrderID: 2, Amount: 50000, CreatedAt: fixedTime},
+				{ID: 11, OrderID: 1, Amount: 150000, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "order of another shop is rejected before writing",
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 1).Return(&model.Order{ID: 1, ShopID: 11}, nil)
+			},
+			wantErr: apierr.ErrOrderNotFound,
+		},
+		{
+			name: "order cancelled since the statement was matched is rejected",
+			mockSetup: func(m mocks) {
+				cancelled := openOrder(1, 150000)
+				cancelled.Status = constant.OrderStatusCancelled
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 1).Return(cancelled, nil)
+			},
+			wantErr: apierr.ErrOrderNotOutstanding,
+		},
+		{
+			name: "payment recorded since the statement was matched leaves no room for the match",
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 1).Return(openOrder(1, 150000), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), m.tx, 1).Return([]model.OrderPayment{{ID: 5, OrderID: 1, Amount: 150000}}, nil)
+			},
+			wantErr: apierr.ErrPaymentAboveBalance,
+		},
+		{
+			name: "statement line already recorded is rejected",
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 1).Return(openOrder(1, 150000), nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 2).Return(openOrder(2, 100000), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), m.tx, gomock.Any()).Return(nil, nil).Times(2)
+				m.payment.EXPECT().CreateOrderPayment(gomock.Any(), m.tx, 2, 50000).
+					Return(&model.OrderPayment{ID: 12, OrderID: 2, Amount: 50000, CreatedAt: fixedTime}, nil)
+				m.payment.EXPECT().CreateStatementLine(gomock.Any(), m.tx, line(12, matches[0].Mutation)).Return(false, nil)
+			},
+			wantErr: apierr.ErrStatementLineMatched,
+		},
+		{
+			name: "payment insert failure rolls back",
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 1).Return(openOrder(1, 150000), nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), m.tx, 2).Return(openOrder(2, 100000), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), m.tx, gomock.Any()).Return(nil, nil).Times(2)
+				m.payment.EXPECT().CreateOrderPayment(gomock.Any(), m.tx, 2, 50000).Return(nil, errors.New("insert error"))
+			},
+			wantErr: "insert error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldOrderPaymentStore, oldDBGetter := orderStore, orderPaymentStore, dbGetter
+			defer func() {
+				orderStore, orderPaymentStore, dbGetter = oldOrderStore, oldOrderPaymentStore, oldDBGetter
+			}()
+
+			m := mocks{
+				order:   mock_store.NewMockOrderStore(ctrl),
+				payment: mock_store.NewMockOrderPaymentStore(ctrl),
+				tx:      mock_database.NewMockTx(ctrl),
+			}
+			m.tx.EXPECT().Rollback().Return(nil)
+			mockDB := mock_database.NewMockDB(ctrl)
+			mockDB.EXPECT().Begin().Return(m.tx, nil)
+			tt.mockSetup(m)
+			orderStore = m.order
+			orderPaymentStore = m.payment
+			dbGetter = func() database.DB { return mockDB }
+
+			var b bsservice
+			got, gotErr := b.ConfirmPaymentMatches(context.Background(), 10, matches)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("ConfirmPaymentMatches() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("ConfirmPaymentMatches() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfirmPaymentMatches() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
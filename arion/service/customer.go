@@ -3,9 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/zeirash/recapo/arion/common/apierr"
 	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/phone"
 	"github.com/zeirash/recapo/arion/common/response"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/store"
@@ -15,19 +20,69 @@ type (
 	CustomerService interface {
 		CreateCustomer(ctx context.Context, name, phone, address string, shopID int) (response.CustomerData, error)
 		GetCustomerByID(ctx context.Context, customerID int, shopID ...int) (*response.CustomerData, error)
-		GetCustomersByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]response.CustomerData, error)
+		GetCustomersByShopID(ctx context.Context, shopID int, filter model.CustomerFilterOptions) ([]response.CustomerData, error)
 		UpdateCustomer(ctx context.Context, input UpdateCustomerInput) (response.CustomerData, error)
 		DeleteCustomerByID(ctx context.Context, id int) error
 		CheckActiveOrderByPhone(ctx context.Context, phone, name string, shopID int) (response.CustomerCheckActiveOrderByPhone, error)
+		MergeCustomers(ctx context.Context, shopID, survivorID int, duplicateIDs []int) (response.CustomerData, error)
+		AnonymizeCustomer(ctx context.Context, customerID, shopID int) (response.CustomerData, error)
+
+		GetCustomerCredits(ctx context.Context, customerID, shopID int) (response.CustomerCreditsData, error)
+		CreateCustomerCredit(ctx context.Context, input CreateCustomerCreditInput) (response.CustomerCreditData, error)
+
+		GetCustomerAddresses(ctx context.Context, customerID, shopID int) ([]response.CustomerAddressData, error)
+		CreateCustomerAddress(ctx context.Context, input CreateCustomerAddressInput) (response.CustomerAddressData, error)
+		UpdateCustomerAddress(ctx context.Context, input UpdateCustomerAddressInput) (response.CustomerAddressData, error)
+		DeleteCustomerAddress(ctx context.Context, id, customerID, shopID int) error
 	}
 
 	cservice struct{}
 
 	UpdateCustomerInput struct {
-		ID      int
-		Name    *string
-		Phone   *string
-		Address *string
+		ID       int
+		Name     *string
+		Phone    *string
+		Address  *string
+		Priority *int
+		Tags     *[]string
+		Blocked  *bool
+	}
+
+	CreateCustomerCreditInput struct {
+		CustomerID int
+		ShopID     int
+		Type       string
+		Amount     int
+		Notes      string
+	}
+
+	CreateCustomerAddressInput struct {
+		CustomerID int
+		ShopID     int
+		Label      string
+		Recipient  string
+		Phone      string
+		Address    string
+		Province   string
+		City       string
+		District   string
+		PostalCode string
+		IsDefault  bool
+	}
+
+	UpdateCustomerAddressInput struct {
+		ID         int
+		CustomerID int
+		ShopID     int
+		Label      *string
+		Recipient  *string
+		Phone      *string
+		Address    *string
+		Province   *string
+		City       *string
+		District   *string
+		PostalCode *string
+		IsDefault  *bool
 	}
 )
 
@@ -38,13 +93,26 @@ func NewCustomerService() CustomerService {
 		customerStore = store.NewCustomerStore()
 	}
 
+	if customerCreditStore == nil {
+		customerCreditStore = store.NewCustomerCreditStore()
+	}
+
+	if customerAddressStore == nil {
+		customerAddressStore = store.NewCustomerAddressStore()
+	}
+
 	return &cservice{}
 }
 
-func (c *cservice) CreateCustomer(ctx context.Context, name, phone, address string, shopID int) (response.CustomerData, error) {
+func (c *cservice) CreateCustomer(ctx context.Context, name, phoneNumber, address string, shopID int) (response.CustomerData, error) {
+	phoneNumber, err := normalizePhone(phoneNumber)
+	if err != nil {
+		return response.CustomerData{}, err
+	}
+
 	customer, err := customerStore.CreateCustomer(ctx, store.CreateCustomerInput{
 		Name:    name,
-		Phone:   phone,
+		Phone:   phoneNumber,
 		Address: &address,
 		ShopID:  shopID,
 	})
@@ -52,15 +120,7 @@ func (c *cservice) CreateCustomer(ctx context.Context, name, phone, address stri
 		return response.CustomerData{}, err
 	}
 
-	res := response.CustomerData{
-		ID:        customer.ID,
-		Name:      customer.Name,
-		Phone:     customer.Phone,
-		Address:   customer.Address,
-		CreatedAt: customer.CreatedAt,
-	}
-
-	return res, nil
+	return toCustomerData(*customer), nil
 }
 
 func (c *cservice) GetCustomerByID(ctx context.Context, customerID int, shopID ...int) (*response.CustomerData, error) {
@@ -73,22 +133,39 @@ func (c *cservice) GetCustomerByID(ctx context.Context, customerID int, shopID .
 		return nil, errors.New(apierr.ErrCustomerNotFound)
 	}
 
-	res := response.CustomerData{
-		ID:        customer.ID,
-		Name:      customer.Name,
-		Phone:     customer.Phone,
-		Address:   customer.Address,
-		CreatedAt: customer.CreatedAt,
+	balance, err := customerCreditStore.GetCustomerCreditBalance(ctx, nil, customer.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	if customer.UpdatedAt.Valid {
-		res.UpdatedAt = &customer.UpdatedAt.Time
+	stats, err := customerStore.GetCustomerStats(ctx, customer.ID)
+	if err != nil {
+		return nil, err
 	}
 
+	res := toCustomerData(*customer)
+	res.CreditBalance = &balance
+	res.Stats = toCustomerStatsData(*stats)
+
 	return &res, nil
 }
 
-func (c *cservice) GetCustomersByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]response.CustomerData, error) {
+// GetCustomersByShopID lists the shop's customers with their lifetime stats.
+// The last order dates of the filter are calendar dates in the shop's
+// timezone.
+func (c *cservice) GetCustomersByShopID(ctx context.Context, shopID int, filter model.CustomerFilterOptions) ([]response.CustomerData, error) {
+	if filter.SearchQuery != nil {
+		q := phone.SearchFragment(*filter.SearchQuery)
+		filter.SearchQuery = &q
+	}
+	if filter.LastOrderFrom != nil || filter.LastOrderTo != nil {
+		loc, err := shopLocation(ctx, shopID)
+		if err != nil {
+			return []response.CustomerData{}, err
+		}
+		filter.LastOrderFrom, filter.LastOrderTo = localDateBounds(filter.LastOrderFrom, filter.LastOrderTo, loc)
+	}
+
 	customers, err := customerStore.GetCustomersByShopID(ctx, shopID, filter)
 	if err != nil {
 		return []response.CustomerData{}, err
@@ -96,19 +173,8 @@ func (c *cservice) GetCustomersByShopID(ctx context.Context, shopID int, filter
 
 	customersData := make([]response.CustomerData, 0, len(customers))
 	for _, customer := range customers {
-		res := response.CustomerData{
-			ID:        customer.ID,
-			Name:      customer.Name,
-			Phone:     customer.Phone,
-			Address:   customer.Address,
-			CreatedAt: customer.CreatedAt,
-		}
-
-		if customer.UpdatedAt.Valid {
-			t := customer.UpdatedAt.Time
-			res.UpdatedAt = &t
-		}
-
+		res := toCustomerData(customer)
+		res.Stats = toCustomerStatsData(customer.Stats)
 		customersData = append(customersData, res)
 	}
 
@@ -125,30 +191,37 @@ func (c *cservice) UpdateCustomer(ctx context.Context, input UpdateCustomerInput
 		return response.CustomerData{}, errors.New(apierr.ErrCustomerNotFound)
 	}
 
-	updateData := store.UpdateCustomerInput{
-		Name:    input.Name,
-		Phone:   input.Phone,
-		Address: input.Address,
+	if input.Phone != nil {
+		normalized, err := normalizePhone(*input.Phone)
+		if err != nil {
+			return response.CustomerData{}, err
+		}
+		input.Phone = &normalized
 	}
 
-	customerData, err := customerStore.UpdateCustomer(ctx, input.ID, updateData)
-	if err != nil {
-		return response.CustomerData{}, err
+	if input.Tags != nil {
+		tags, err := normalizeTags(*input.Tags)
+		if err != nil {
+			return response.CustomerData{}, err
+		}
+		input.Tags = &tags
 	}
 
-	res := response.CustomerData{
-		ID:        customerData.ID,
-		Name:      customerData.Name,
-		Phone:     customerData.Phone,
-		Address:   customerData.Address,
-		CreatedAt: customerData.CreatedAt,
+	updateData := store.UpdateCustomerInput{
+		Name:     input.Name,
+		Phone:    input.Phone,
+		Address:  input.Address,
+		Priority: input.Priority,
+		Tags:     input.Tags,
+		Blocked:  input.Blocked,
 	}
 
-	if customerData.UpdatedAt.Valid {
-		res.UpdatedAt = &customerData.UpdatedAt.Time
+	customerData, err := customerStore.UpdateCustomer(ctx, input.ID, updateData)
+	if err != nil {
+		return response.CustomerData{}, err
 	}
 
-	return res, nil
+	return toCustomerData(*customerData), nil
 }
 
 func (c *cservice) DeleteCustomerByID(ctx context.Context, id int) error {
@@ -160,8 +233,13 @@ func (c *cservice) DeleteCustomerByID(ctx context.Context, id int) error {
 	return nil
 }
 
-func (c *cservice) CheckActiveOrderByPhone(ctx context.Context, phone, name string, shopID int) (response.CustomerCheckActiveOrderByPhone, error) {
-	customer, err := customerStore.GetCustomerByPhone(ctx, phone, shopID)
+func (c *cservice) CheckActiveOrderByPhone(ctx context.Context, phoneNumber, name string, shopID int) (response.CustomerCheckActiveOrderByPhone, error) {
+	phoneNumber, err := normalizePhone(phoneNumber)
+	if err != nil {
+		return response.CustomerCheckActiveOrderByPhone{}, err
+	}
+
+	customer, err := customerStore.GetCustomerByPhone(ctx, phoneNumber, shopID)
 	if err != nil {
 		return response.CustomerCheckActiveOrderByPhone{}, err
 	}
@@ -169,7 +247,7 @@ func (c *cservice) CheckActiveOrderByPhone(ctx context.Context, phone, name stri
 	if customer == nil {
 		customer, err = customerStore.CreateCustomer(ctx, store.CreateCustomerInput{
 			Name:   name,
-			Phone:  phone,
+			Phone:  phoneNumber,
 			ShopID: shopID,
 		})
 		if err != nil {
@@ -192,3 +270,472 @@ func (c *cservice) CheckActiveOrderByPhone(ctx context.Context, phone, name stri
 		ActiveOrderID: activeOrderID,
 	}, nil
 }
+
+// MergeCustomers folds duplicate customers into the survivor: their orders,
+// with the payments on them, and their credit entries move over, and the
+// duplicates are deleted. It is refused while more than one of them has an
+// open order.
+func (c *cservice) MergeCustomers(ctx context.Context, shopID, survivorID int, duplicateIDs []int) (response.CustomerData, error) {
+	ids := make([]int, 0, len(duplicateIDs))
+	seen := map[int]bool{}
+	for _, id := range duplicateIDs {
+		if id == survivorID {
+			return response.CustomerData{}, errors.New(apierr.ErrCustomerMergeIntoSelf)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return response.CustomerData{}, errors.New(apierr.ErrDuplicateIDsRequired)
+	}
+
+	for _, id := range append([]int{survivorID}, ids...) {
+		customer, err := customerStore.GetCustomerByID(ctx, id, shopID)
+		if err != nil {
+			return response.CustomerData{}, err
+		}
+		if customer == nil {
+			return response.CustomerData{}, errors.New(apierr.ErrCustomerNotFound)
+		}
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.CustomerData{}, err
+	}
+	defer tx.Rollback()
+
+	// Take the credit ledger lock of every customer involved, in ID order so
+	// two merges can't deadlock, before their entries move to the survivor.
+	locked := append([]int{survivorID}, ids...)
+	sort.Ints(locked)
+	for _, id := range locked {
+		if _, err := customerCreditStore.GetCustomerCreditBalance(ctx, tx, id); err != nil {
+			return response.CustomerData{}, err
+		}
+	}
+
+	if err := customerStore.MergeCustomers(ctx, tx, shopID, survivorID, ids); err != nil {
+		return response.CustomerData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.CustomerData{}, err
+	}
+
+	res, err := c.GetCustomerByID(ctx, survivorID, shopID)
+	if err != nil {
+		return response.CustomerData{}, err
+	}
+
+	return *res, nil
+}
+
+// AnonymizeCustomer erases the customer's personal data on request: name,
+// phone, address, tags, saved addresses and the shipping addresses of their
+// orders. The customer and their orders stay, so totals and reports don't
+// change.
+func (c *cservice) AnonymizeCustomer(ctx context.Context, customerID, shopID int) (response.CustomerData, error) {
+	customer, err := customerStore.GetCustomerByID(ctx, customerID, shopID)
+	if err != nil {
+		return response.CustomerData{}, err
+	}
+	if customer == nil {
+		return response.CustomerData{}, errors.New(apierr.ErrCustomerNotFound)
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.CustomerData{}, err
+	}
+	defer tx.Rollback()
+
+	if err := customerStore.AnonymizeCustomer(ctx, tx, shopID, customerID); err != nil {
+		return response.CustomerData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.CustomerData{}, err
+	}
+
+	res, err := c.GetCustomerByID(ctx, customerID, shopID)
+	if err != nil {
+		return response.CustomerData{}, err
+	}
+
+	return *res, nil
+}
+
+// normalizePhone stores and looks up phones in one form, so 0812…, +62812…
+// and 62 812-… are the same customer.
+func normalizePhone(raw string) (string, error) {
+	normalized, err := phone.Normalize(raw)
+	if err != nil {
+		return "", errors.New(apierr.ErrCustomerPhoneInvalid)
+	}
+	return normalized, nil
+}
+
+// normalizeTags cleans customer tags with uniqueTags and checks them
+// against the customer tag limits.
+func normalizeTags(raw []string) ([]string, error) {
+	tags := uniqueTags(raw)
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > constant.CustomerTagMaxLength {
+			return nil, errors.New(apierr.ErrCustomerTagInvalid)
+		}
+	}
+	if len(tags) > constant.CustomerMaxTags {
+		return nil, errors.New(apierr.ErrTooManyCustomerTags)
+	}
+	return tags, nil
+}
+
+// uniqueTags trims the tags and drops empty ones and repeats, compared
+// case-insensitively; the first spelling of a tag is kept.
+func uniqueTags(raw []string) []string {
+	tags := make([]string, 0, len(raw))
+	seen := map[string]bool{}
+	for _, tag := range raw {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func toCustomerData(customer model.Customer) response.CustomerData {
+	res := response.CustomerData{
+		ID:        customer.ID,
+		Name:      customer.Name,
+		Phone:     customer.Phone,
+		Address:   customer.Address,
+		Priority:  customer.Priority,
+		Tags:      customer.Tags,
+		Blocked:   customer.Blocked,
+		CreatedAt: customer.CreatedAt,
+	}
+
+	if res.Tags == nil {
+		res.Tags = []string{}
+	}
+	if customer.UpdatedAt.Valid {
+		t := customer.UpdatedAt.Time
+		res.UpdatedAt = &t
+	}
+
+	return res
+}
+
+func toCustomerStatsData(stats model.CustomerStats) *response.CustomerStatsData {
+	res := response.CustomerStatsData{
+		OrderCount:         stats.OrderCount,
+		TotalSpent:         stats.TotalSpent,
+		OutstandingBalance: stats.Outstanding,
+	}
+
+	if stats.FirstOrderAt.Valid {
+		t := stats.FirstOrderAt.Time
+		res.FirstOrderAt = &t
+	}
+	if stats.LastOrderAt.Valid {
+		t := stats.LastOrderAt.Time
+		res.LastOrderAt = &t
+	}
+
+	return &res
+}
+
+func (c *cservice) GetCustomerCredits(ctx context.Context, customerID, shopID int) (response.CustomerCreditsData, error) {
+	customer, err := customerStore.GetCustomerByID(ctx, customerID, shopID)
+	if err != nil {
+		return response.CustomerCreditsData{}, err
+	}
+
+	if customer == nil {
+		return response.CustomerCreditsData{}, errors.New(apierr.ErrCustomerNotFound)
+	}
+
+	credits, err := customerCreditStore.GetCustomerCreditsByCustomerID(ctx, customerID)
+	if err != nil {
+		return response.CustomerCreditsData{}, err
+	}
+
+	res := response.CustomerCreditsData{
+		Entries: make([]response.CustomerCreditData, 0, len(credits)),
+	}
+	for _, credit := range credits {
+		res.Balance += credit.Amount
+		res.Entries = append(res.Entries, toCustomerCreditData(credit))
+	}
+
+	return res, nil
+}
+
+// CreateCustomerCredit records a manual ledger entry. Deposits and refunds add
+// to the balance; adjustments may go either way but never below zero.
+func (c *cservice) CreateCustomerCredit(ctx context.Context, input CreateCustomerCreditInput) (response.CustomerCreditData, error) {
+	customer, err := customerStore.GetCustomerByID(ctx, input.CustomerID, input.ShopID)
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	if customer == nil {
+		return response.CustomerCreditData{}, errors.New(apierr.ErrCustomerNotFound)
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+	defer tx.Rollback()
+
+	balance, err := customerCreditStore.GetCustomerCreditBalance(ctx, tx, input.CustomerID)
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	if balance+input.Amount < 0 {
+		return response.CustomerCreditData{}, errors.New(apierr.ErrInsufficientCredit)
+	}
+
+	credit, err := customerCreditStore.CreateCustomerCredit(ctx, tx, store.CreateCustomerCreditInput{
+		ShopID:     input.ShopID,
+		CustomerID: input.CustomerID,
+		Type:       input.Type,
+		Amount:     input.Amount,
+		Notes:      input.Notes,
+	})
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	return toCustomerCreditData(*credit), nil
+}
+
+func toCustomerCreditData(credit model.CustomerCredit) response.CustomerCreditData {
+	res := response.CustomerCreditData{
+		ID:        credit.ID,
+		Type:      credit.Type,
+		Amount:    credit.Amount,
+		Notes:     credit.Notes,
+		CreatedAt: credit.CreatedAt,
+	}
+
+	if credit.OrderID.Valid {
+		orderID := int(credit.OrderID.Int64)
+		res.OrderID = &orderID
+	}
+
+	return res
+}
+
+func (c *cservice) GetCustomerAddresses(ctx context.Context, customerID, shopID int) ([]response.CustomerAddressData, error) {
+	customer, err := customerStore.GetCustomerByID(ctx, customerID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		return nil, errors.New(apierr.ErrCustomerNotFound)
+	}
+
+	addresses, err := customerAddressStore.GetCustomerAddressesByCustomerID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []response.CustomerAddressData{}
+	for _, address := range addresses {
+		res = append(res, toCustomerAddressData(address))
+	}
+
+	return res, nil
+}
+
+// CreateCustomerAddress adds an address to the customer's address book. The
+// first address becomes the default; a new default replaces the current one.
+func (c *cservice) CreateCustomerAddress(ctx context.Context, input CreateCustomerAddressInput) (response.CustomerAddressData, error) {
+	customer, err := customerStore.GetCustomerByID(ctx, input.CustomerID, input.ShopID)
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+
+	if customer == nil {
+		return response.CustomerAddressData{}, errors.New(apierr.ErrCustomerNotFound)
+	}
+
+	if input.Phone != "" {
+		input.Phone, err = normalizePhone(input.Phone)
+		if err != nil {
+			return response.CustomerAddressData{}, err
+		}
+	}
+
+	current, err := customerAddressStore.GetDefaultCustomerAddress(ctx, input.CustomerID)
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+	if current == nil {
+		input.IsDefault = true
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+	defer tx.Rollback()
+
+	if input.IsDefault && current != nil {
+		if err := customerAddressStore.ClearDefaultCustomerAddress(ctx, tx, input.CustomerID); err != nil {
+			return response.CustomerAddressData{}, err
+		}
+	}
+
+	address, err := customerAddressStore.CreateCustomerAddress(ctx, tx, store.CreateCustomerAddressInput{
+		ShopID:     input.ShopID,
+		CustomerID: input.CustomerID,
+		Label:      input.Label,
+		Recipient:  input.Recipient,
+		Phone:      input.Phone,
+		Address:    input.Address,
+		Province:   input.Province,
+		City:       input.City,
+		District:   input.District,
+		PostalCode: input.PostalCode,
+		IsDefault:  input.IsDefault,
+	})
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.CustomerAddressData{}, err
+	}
+
+	return toCustomerAddressData(*address), nil
+}
+
+// UpdateCustomerAddress edits an address in the book. Orders keep the copy
+// they were created with.
+func (c *cservice) UpdateCustomerAddress(ctx context.Context, input UpdateCustomerAddressInput) (response.CustomerAddressData, error) {
+	customer, err := customerStore.GetCustomerByID(ctx, input.CustomerID, input.ShopID)
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+
+	if customer == nil {
+		return response.CustomerAddressData{}, errors.New(apierr.ErrCustomerNotFound)
+	}
+
+	address, err := customerAddressStore.GetCustomerAddressByID(ctx, input.ID, input.CustomerID)
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+
+	if address == nil {
+		return response.CustomerAddressData{}, errors.New(apierr.ErrCustomerAddressNotFound)
+	}
+
+	if input.Phone != nil && *input.Phone != "" {
+		normalized, err := normalizePhone(*input.Phone)
+		if err != nil {
+			return response.CustomerAddressData{}, err
+		}
+		input.Phone = &normalized
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+	defer tx.Rollback()
+
+	if input.IsDefault != nil && *input.IsDefault && !address.IsDefault {
+		if err := customerAddressStore.ClearDefaultCustomerAddress(ctx, tx, input.CustomerID); err != nil {
+			return response.CustomerAddressData{}, err
+		}
+	}
+
+	address, err = customerAddressStore.UpdateCustomerAddress(ctx, tx, input.ID, store.UpdateCustomerAddressInput{
+		Label:      input.Label,
+		Recipient:  input.Recipient,
+		Phone:      input.Phone,
+		Address:    input.Address,
+		Province:   input.Province,
+		City:       input.City,
+		District:   input.District,
+		PostalCode: input.PostalCode,
+		IsDefault:  input.IsDefault,
+	})
+	if err != nil {
+		return response.CustomerAddressData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.CustomerAddressData{}, err
+	}
+
+	return toCustomerAddressData(*address), nil
+}
+
+// DeleteCustomerAddress removes an address from the book. Deleting the default
+// leaves the customer without one until another address is marked default.
+func (c *cservice) DeleteCustomerAddress(ctx context.Context, id, customerID, shopID int) error {
+	customer, err := customerStore.GetCustomerByID(ctx, customerID, shopID)
+	if err != nil {
+		return err
+	}
+
+	if customer == nil {
+		return errors.New(apierr.ErrCustomerNotFound)
+	}
+
+	address, err := customerAddressStore.GetCustomerAddressByID(ctx, id, customerID)
+	if err != nil {
+		return err
+	}
+
+	if address == nil {
+		return errors.New(apierr.ErrCustomerAddressNotFound)
+	}
+
+	return customerAddressStore.DeleteCustomerAddressByID(ctx, id, customerID)
+}
+
+func toCustomerAddressData(address model.CustomerAddress) response.CustomerAddressData {
+	res := response.CustomerAddressData{
+		ID:         address.ID,
+		Label:      address.Label,
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Address:    address.Address,
+		Province:   address.Province,
+		City:       address.City,
+		District:   address.District,
+		PostalCode: address.PostalCode,
+		IsDefault:  address.IsDefault,
+		CreatedAt:  address.CreatedAt,
+	}
+
+	if address.UpdatedAt.Valid {
+		t := address.UpdatedAt.Time
+		res.UpdatedAt = &t
+	}
+
+	return res
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
 	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
 	mock_store "github.com/zeirash/recapo/arion/mock/store"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/store"
@@ -19,6 +25,10 @@ func strPtr(s string) *string {
 	return &s
 }
 
+func intPtr(n int) *int {
+	return &n
+}
+
 func Test_cservice_CreateCustomer(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 
@@ -40,7 +50,7 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 			name: "successfully create customer",
 			input: input{
 				name:    "John Doe",
-				phone:   "1234567890",
+				phone:   "0812-3456-7890",
 				address: "123 Main St",
 				shopID:  10,
 			},
@@ -49,14 +59,14 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 				mock.EXPECT().
 					CreateCustomer(gomock.Any(), gomock.Eq(store.CreateCustomerInput{
 						Name:    "John Doe",
-						Phone:   "1234567890",
+						Phone:   "+6281234567890",
 						Address: strPtr("123 Main St"),
 						ShopID:  10,
 					})).
 					Return(&model.Customer{
 						ID:        1,
 						Name:      "John Doe",
-						Phone:     "1234567890",
+						Phone:     "+6281234567890",
 						Address:   "123 Main St",
 						CreatedAt: fixedTime,
 					}, nil)
@@ -65,8 +75,9 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 			wantResult: response.CustomerData{
 				ID:        1,
 				Name:      "John Doe",
-				Phone:     "1234567890",
+				Phone:     "+6281234567890",
 				Address:   "123 Main St",
+				Tags:      []string{},
 				CreatedAt: fixedTime,
 			},
 			wantErr: false,
@@ -75,7 +86,7 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 			name: "successfully create customer without address",
 			input: input{
 				name:    "Jane Doe",
-				phone:   "0987654321",
+				phone:   "0898 7654 321",
 				address: "",
 				shopID:  10,
 			},
@@ -84,14 +95,14 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 				mock.EXPECT().
 					CreateCustomer(gomock.Any(), gomock.Eq(store.CreateCustomerInput{
 						Name:    "Jane Doe",
-						Phone:   "0987654321",
+						Phone:   "+628987654321",
 						Address: strPtr(""),
 						ShopID:  10,
 					})).
 					Return(&model.Customer{
 						ID:        2,
 						Name:      "Jane Doe",
-						Phone:     "0987654321",
+						Phone:     "+628987654321",
 						Address:   "",
 						CreatedAt: fixedTime,
 					}, nil)
@@ -100,8 +111,9 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 			wantResult: response.CustomerData{
 				ID:        2,
 				Name:      "Jane Doe",
-				Phone:     "0987654321",
+				Phone:     "+628987654321",
 				Address:   "",
+				Tags:      []string{},
 				CreatedAt: fixedTime,
 			},
 			wantErr: false,
@@ -110,7 +122,7 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 			name: "create customer with duplicate phone returns error",
 			input: input{
 				name:    "John Doe",
-				phone:   "1234567890",
+				phone:   "0812-3456-7890",
 				address: "123 Main St",
 				shopID:  10,
 			},
@@ -119,7 +131,7 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 				mock.EXPECT().
 					CreateCustomer(gomock.Any(), gomock.Eq(store.CreateCustomerInput{
 						Name:    "John Doe",
-						Phone:   "1234567890",
+						Phone:   "+6281234567890",
 						Address: strPtr("123 Main St"),
 						ShopID:  10,
 					})).
@@ -133,7 +145,7 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 			name: "create customer returns error on database failure",
 			input: input{
 				name:    "John Doe",
-				phone:   "1234567890",
+				phone:   "0812-3456-7890",
 				address: "123 Main St",
 				shopID:  10,
 			},
@@ -142,7 +154,7 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 				mock.EXPECT().
 					CreateCustomer(gomock.Any(), gomock.Eq(store.CreateCustomerInput{
 						Name:    "John Doe",
-						Phone:   "1234567890",
+						Phone:   "+6281234567890",
 						Address: strPtr("123 Main St"),
 						ShopID:  10,
 					})).
@@ -152,6 +164,20 @@ func Test_cservice_CreateCustomer(t *testing.T) {
 			wantResult: response.CustomerData{},
 			wantErr:    true,
 		},
+		{
+			name: "create customer rejects invalid phone",
+			input: input{
+				name:    "John Doe",
+				phone:   "12345",
+				address: "123 Main St",
+				shopID:  10,
+			},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
+				return mock_store.NewMockCustomerStore(ctrl)
+			},
+			wantResult: response.CustomerData{},
+			wantErr:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -196,6 +222,8 @@ func Test_cservice_GetCustomerByID(t *testing.T) {
 		name       string
 		input      input
 		mockSetup  func(ctrl *gomock.Controller) *mock_store.MockCustomerStore
+		balance    int
+		balanceErr error
 		wantResult *response.CustomerData
 		wantErr    bool
 	}{
@@ -214,15 +242,34 @@ func Test_cservice_GetCustomerByID(t *testing.T) {
 						Name:      "John Doe",
 						Phone:     "1234567890",
 						Address:   "123 Main St",
+						Tags:      []string{"VIP"},
 						CreatedAt: fixedTime,
 					}, nil)
+				mock.EXPECT().
+					GetCustomerStats(gomock.Any(), 1).
+					Return(&model.CustomerStats{
+						OrderCount:   2,
+						TotalSpent:   300000,
+						Outstanding:  50000,
+						FirstOrderAt: sql.NullTime{Time: fixedTime, Valid: true},
+						LastOrderAt:  sql.NullTime{Time: updatedTime, Valid: true},
+					}, nil)
 				return mock
 			},
 			wantResult: &response.CustomerData{
-				ID:        1,
-				Name:      "John Doe",
-				Phone:     "1234567890",
-				Address:   "123 Main St",
+				ID:            1,
+				Name:          "John Doe",
+				Phone:         "1234567890",
+				Address:       "123 Main St",
+				Tags:          []string{"VIP"},
+				CreditBalance: intPtr(0),
+				Stats: &response.CustomerStatsData{
+					OrderCount:         2,
+					TotalSpent:         300000,
+					OutstandingBalance: 50000,
+					FirstOrderAt:       &fixedTime,
+					LastOrderAt:        &updatedTime,
+				},
 				CreatedAt: fixedTime,
 			},
 			wantErr: false,
@@ -245,15 +292,22 @@ func Test_cservice_GetCustomerByID(t *testing.T) {
 						CreatedAt: fixedTime,
 						UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
 					}, nil)
+				mock.EXPECT().
+					GetCustomerStats(gomock.Any(), 1).
+					Return(&model.CustomerStats{}, nil)
 				return mock
 			},
+			balance: 250000,
 			wantResult: &response.CustomerData{
-				ID:        1,
-				Name:      "John Doe",
-				Phone:     "1234567890",
-				Address:   "123 Main St",
-				CreatedAt: fixedTime,
-				UpdatedAt: &updatedTime,
+				ID:            1,
+				Name:          "John Doe",
+				Phone:         "1234567890",
+				Address:       "123 Main St",
+				Tags:          []string{},
+				CreditBalance: intPtr(250000),
+				Stats:         &response.CustomerStatsData{},
+				CreatedAt:     fixedTime,
+				UpdatedAt:     &updatedTime,
 			},
 			wantErr: false,
 		},
@@ -289,6 +343,42 @@ func Test_cservice_GetCustomerByID(t *testing.T) {
 			wantResult: nil,
 			wantErr:    true,
 		},
+		{
+			name: "get customer returns error when credit balance fails",
+			input: input{
+				customerID: 1,
+				shopID:     nil,
+			},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
+				mock := mock_store.NewMockCustomerStore(ctrl)
+				mock.EXPECT().
+					GetCustomerByID(gomock.Any(), 1).
+					Return(&model.Customer{ID: 1, Name: "John Doe", CreatedAt: fixedTime}, nil)
+				return mock
+			},
+			balanceErr: errors.New("database error"),
+			wantResult: nil,
+			wantErr:    true,
+		},
+		{
+			name: "get customer returns error when stats fail",
+			input: input{
+				customerID: 1,
+				shopID:     nil,
+			},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
+				mock := mock_store.NewMockCustomerStore(ctrl)
+				mock.EXPECT().
+					GetCustomerByID(gomock.Any(), 1).
+					Return(&model.Customer{ID: 1, Name: "John Doe", CreatedAt: fixedTime}, nil)
+				mock.EXPECT().
+					GetCustomerStats(gomock.Any(), 1).
+					Return(nil, errors.New("database error"))
+				return mock
+			},
+			wantResult: nil,
+			wantErr:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -300,6 +390,15 @@ func Test_cservice_GetCustomerByID(t *testing.T) {
 			defer func() { customerStore = oldStore }()
 			customerStore = tt.mockSetup(ctrl)
 
+			oldCreditStore := customerCreditStore
+			defer func() { customerCreditStore = oldCreditStore }()
+			creditMock := mock_store.NewMockCustomerCreditStore(ctrl)
+			creditMock.EXPECT().
+				GetCustomerCreditBalance(gomock.Any(), nil, tt.input.customerID).
+				Return(tt.balance, tt.balanceErr).
+				AnyTimes()
+			customerCreditStore = creditMock
+
 			var c cservice
 			got, gotErr := c.GetCustomerByID(context.Background(), tt.input.customerID, tt.input.shopID...)
 
@@ -322,12 +421,13 @@ func Test_cservice_GetCustomerByID(t *testing.T) {
 
 func Test_cservice_GetCustomersByShopID(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	lastOrderTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
 	strPtr := func(s string) *string { return &s }
 
 	tests := []struct {
 		name        string
 		shopID      int
-		filter      model.FilterOptions
+		filter      model.CustomerFilterOptions
 		mockSetup   func(ctrl *gomock.Controller) *mock_store.MockCustomerStore
 		wantResult  []response.CustomerData
 		wantErr     bool
@@ -335,31 +435,34 @@ func Test_cservice_GetCustomersByShopID(t *testing.T) {
 		{
 			name:   "get customers by shop ID returns multiple customers",
 			shopID: 10,
-			filter: model.FilterOptions{},
+			filter: model.CustomerFilterOptions{},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
 				mock := mock_store.NewMockCustomerStore(ctrl)
 				mock.EXPECT().
-					GetCustomersByShopID(gomock.Any(), 10, model.FilterOptions{}).
+					GetCustomersByShopID(gomock.Any(), 10, model.CustomerFilterOptions{}).
 					Return([]model.Customer{
-						{ID: 1, Name: "John Doe", Phone: "1234567890", Address: "123 Main St", CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
-						{ID: 2, Name: "Jane Doe", Phone: "0987654321", Address: "456 Oak Ave", CreatedAt: fixedTime},
+						{ID: 1, Name: "John Doe", Phone: "1234567890", Address: "123 Main St", Tags: []string{"VIP"}, Stats: model.CustomerStats{
+							OrderCount: 2, TotalSpent: 300000, Outstanding: 100000,
+							FirstOrderAt: sql.NullTime{Time: fixedTime, Valid: true}, LastOrderAt: sql.NullTime{Time: lastOrderTime, Valid: true},
+						}, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+						{ID: 2, Name: "Jane Doe", Phone: "0987654321", Address: "456 Oak Ave", Tags: []string{}, Blocked: true, CreatedAt: fixedTime},
 					}, nil)
 				return mock
 			},
 			wantResult: []response.CustomerData{
-				{ID: 1, Name: "John Doe", Phone: "1234567890", Address: "123 Main St", CreatedAt: fixedTime, UpdatedAt: &fixedTime},
-				{ID: 2, Name: "Jane Doe", Phone: "0987654321", Address: "456 Oak Ave", CreatedAt: fixedTime},
+				{ID: 1, Name: "John Doe", Phone: "1234567890", Address: "123 Main St", Tags: []string{"VIP"}, Stats: &response.CustomerStatsData{OrderCount: 2, TotalSpent: 300000, OutstandingBalance: 100000, FirstOrderAt: &fixedTime, LastOrderAt: &lastOrderTime}, CreatedAt: fixedTime, UpdatedAt: &fixedTime},
+				{ID: 2, Name: "Jane Doe", Phone: "0987654321", Address: "456 Oak Ave", Tags: []string{}, Blocked: true, Stats: &response.CustomerStatsData{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
 		{
 			name:   "get customers by shop ID returns empty slice",
 			shopID: 10,
-			filter: model.FilterOptions{},
+			filter: model.CustomerFilterOptions{},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
 				mock := mock_store.NewMockCustomerStore(ctrl)
 				mock.EXPECT().
-					GetCustomersByShopID(gomock.Any(), 10, model.FilterOptions{}).
+					GetCustomersByShopID(gomock.Any(), 10, model.CustomerFilterOptions{}).
 					Return([]model.Customer{}, nil)
 				return mock
 			},
@@ -369,11 +472,11 @@ func Test_cservice_GetCustomersByShopID(t *testing.T) {
 		{
 			name:   "get customers by shop ID returns error on database failure",
 			shopID: 10,
-			filter: model.FilterOptions{},
+			filter: model.CustomerFilterOptions{},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
 				mock := mock_store.NewMockCustomerStore(ctrl)
 				mock.EXPECT().
-					GetCustomersByShopID(gomock.Any(), 10, model.FilterOptions{}).
+					GetCustomersByShopID(gomock.Any(), 10, model.CustomerFilterOptions{}).
 					Return(nil, errors.New("database error"))
 				return mock
 			},
@@ -383,7 +486,7 @@ func Test_cservice_GetCustomersByShopID(t *testing.T) {
 		{
 			name:        "get customers by shop ID with search query returns filtered customers",
 			shopID:      10,
-			filter: model.FilterOptions{SearchQuery: strPtr("john")},
+			filter: model.CustomerFilterOptions{SearchQuery: strPtr("john")},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
 				mock := mock_store.NewMockCustomerStore(ctrl)
 				mock.EXPECT().
@@ -394,7 +497,7 @@ func Test_cservice_GetCustomersByShopID(t *testing.T) {
 				return mock
 			},
 			wantResult: []response.CustomerData{
-				{ID: 1, Name: "John Doe", Phone: "1234567890", Address: "123 Main St", CreatedAt: fixedTime},
+				{ID: 1, Name: "John Doe", Phone: "1234567890", Address: "123 Main St", Tags: []string{}, Stats: &response.CustomerStatsData{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
@@ -476,11 +579,105 @@ func Test_cservice_UpdateCustomer(t *testing.T) {
 				Name:      "Updated Name",
 				Phone:     "1234567890",
 				Address:   "123 Main St",
+				Tags:      []string{},
+				CreatedAt: fixedTime,
+				UpdatedAt: &updatedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfully update customer priority",
+			input: UpdateCustomerInput{
+				ID:       1,
+				Priority: func() *int { p := 5; return &p }(),
+			},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
+				priority := 5
+				mock := mock_store.NewMockCustomerStore(ctrl)
+				mock.EXPECT().
+					GetCustomerByID(gomock.Any(), 1).
+					Return(&model.Customer{
+						ID:        1,
+						Name:      "John Doe",
+						Phone:     "1234567890",
+						CreatedAt: fixedTime,
+					}, nil)
+				mock.EXPECT().
+					UpdateCustomer(gomock.Any(), 1, store.UpdateCustomerInput{Priority: &priority}).
+					Return(&model.Customer{
+						ID:        1,
+						Name:      "John Doe",
+						Phone:     "1234567890",
+						Priority:  5,
+						CreatedAt: fixedTime,
+						UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
+					}, nil)
+				return mock
+			},
+			wantResult: response.CustomerData{
+				ID:        1,
+				Name:      "John Doe",
+				Phone:     "1234567890",
+				Priority:  5,
+				Tags:      []string{},
+				CreatedAt: fixedTime,
+				UpdatedAt: &updatedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfully update customer tags and block flag",
+			input: UpdateCustomerInput{
+				ID:      1,
+				Tags:    &[]string{" VIP ", "reseller", "vip", ""},
+				Blocked: func() *bool { b := true; return &b }(),
+			},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
+				blocked := true
+				mock := mock_store.NewMockCustomerStore(ctrl)
+				mock.EXPECT().
+					GetCustomerByID(gomock.Any(), 1).
+					Return(&model.Customer{ID: 1, Name: "John Doe", Phone: "1234567890", CreatedAt: fixedTime}, nil)
+				mock.EXPECT().
+					UpdateCustomer(gomock.Any(), 1, store.UpdateCustomerInput{Tags: &[]string{"VIP", "reseller"}, Blocked: &blocked}).
+					Return(&model.Customer{
+						ID:        1,
+						Name:      "John Doe",
+						Phone:     "1234567890",
+						Tags:      []string{"VIP", "reseller"},
+						Blocked:   true,
+						CreatedAt: fixedTime,
+						UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
+					}, nil)
+				return mock
+			},
+			wantResult: response.CustomerData{
+				ID:        1,
+				Name:      "John Doe",
+				Phone:     "1234567890",
+				Tags:      []string{"VIP", "reseller"},
+				Blocked:   true,
 				CreatedAt: fixedTime,
 				UpdatedAt: &updatedTime,
 			},
 			wantErr: false,
 		},
+		{
+			name: "update customer with a too long tag returns error",
+			input: UpdateCustomerInput{
+				ID:   1,
+				Tags: &[]string{strings.Repeat("x", constant.CustomerTagMaxLength+1)},
+			},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
+				mock := mock_store.NewMockCustomerStore(ctrl)
+				mock.EXPECT().
+					GetCustomerByID(gomock.Any(), 1).
+					Return(&model.Customer{ID: 1, Name: "John Doe", CreatedAt: fixedTime}, nil)
+				return mock
+			},
+			wantResult: response.CustomerData{},
+			wantErr:    true,
+		},
 		{
 			name: "update customer not found returns error",
 			input: UpdateCustomerInput{
@@ -517,7 +714,7 @@ func Test_cservice_UpdateCustomer(t *testing.T) {
 			name: "update customer returns error on update failure",
 			input: UpdateCustomerInput{
 				ID:    1,
-				Phone: strPtr("9999999999"),
+				Phone: strPtr("0899-9999-9999"),
 			},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerStore {
 				mock := mock_store.NewMockCustomerStore(ctrl)
@@ -531,7 +728,7 @@ func Test_cservice_UpdateCustomer(t *testing.T) {
 						CreatedAt: fixedTime,
 					}, nil)
 				mock.EXPECT().
-					UpdateCustomer(gomock.Any(), 1, store.UpdateCustomerInput{Phone: strPtr("9999999999")}).
+					UpdateCustomer(gomock.Any(), 1, store.UpdateCustomerInput{Phone: strPtr("+6289999999999")}).
 					Return(nil, store.ErrDuplicatePhone)
 				return mock
 			},
@@ -647,8 +844,8 @@ func Test_cservice_CheckActiveOrderByPhone(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
 				cust := mock_store.NewMockCustomerStore(ctrl)
 				ord := mock_store.NewMockOrderStore(ctrl)
-				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "08123456789", 1).
-					Return(&model.Customer{ID: 1, Name: "John Doe", Phone: "08123456789", Address: "", CreatedAt: fixedTime}, nil)
+				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "+628123456789", 1).
+					Return(&model.Customer{ID: 1, Name: "John Doe", Phone: "+628123456789", Address: "", CreatedAt: fixedTime}, nil)
 				ord.EXPECT().GetActiveOrderByCustomerID(gomock.Any(), 1, 1).Return(&model.Order{ID: 1}, nil)
 				return cust, ord
 			},
@@ -663,8 +860,8 @@ func Test_cservice_CheckActiveOrderByPhone(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
 				cust := mock_store.NewMockCustomerStore(ctrl)
 				ord := mock_store.NewMockOrderStore(ctrl)
-				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "08987654321", 1).
-					Return(&model.Customer{ID: 2, Name: "Jane Doe", Phone: "08987654321", Address: "", CreatedAt: fixedTime}, nil)
+				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "+628987654321", 1).
+					Return(&model.Customer{ID: 2, Name: "Jane Doe", Phone: "+628987654321", Address: "", CreatedAt: fixedTime}, nil)
 				ord.EXPECT().GetActiveOrderByCustomerID(gomock.Any(), 2, 1).Return(nil, nil)
 				return cust, ord
 			},
@@ -679,10 +876,10 @@ func Test_cservice_CheckActiveOrderByPhone(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
 				cust := mock_store.NewMockCustomerStore(ctrl)
 				ord := mock_store.NewMockOrderStore(ctrl)
-				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "08000000000", 1).Return(nil, nil)
+				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "+628000000000", 1).Return(nil, nil)
 				cust.EXPECT().CreateCustomer(gomock.Any(), gomock.Eq(store.CreateCustomerInput{
-					Name: "New User", Phone: "08000000000", Address: nil, ShopID: 1,
-				})).Return(&model.Customer{ID: 3, Name: "New User", Phone: "08000000000", Address: "", CreatedAt: fixedTime}, nil)
+					Name: "New User", Phone: "+628000000000", Address: nil, ShopID: 1,
+				})).Return(&model.Customer{ID: 3, Name: "New User", Phone: "+628000000000", Address: "", CreatedAt: fixedTime}, nil)
 				ord.EXPECT().GetActiveOrderByCustomerID(gomock.Any(), 3, 1).Return(nil, nil)
 				return cust, ord
 			},
@@ -697,7 +894,7 @@ func Test_cservice_CheckActiveOrderByPhone(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
 				cust := mock_store.NewMockCustomerStore(ctrl)
 				ord := mock_store.NewMockOrderStore(ctrl)
-				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "08123456789", 1).Return(nil, errors.New("database error"))
+				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "+628123456789", 1).Return(nil, errors.New("database error"))
 				return cust, ord
 			},
 			want:    response.CustomerCheckActiveOrderByPhone{},
@@ -711,9 +908,9 @@ func Test_cservice_CheckActiveOrderByPhone(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
 				cust := mock_store.NewMockCustomerStore(ctrl)
 				ord := mock_store.NewMockOrderStore(ctrl)
-				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "08000000000", 1).Return(nil, nil)
+				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "+628000000000", 1).Return(nil, nil)
 				cust.EXPECT().CreateCustomer(gomock.Any(), gomock.Eq(store.CreateCustomerInput{
-					Name: "New User", Phone: "08000000000", Address: nil, ShopID: 1,
+					Name: "New User", Phone: "+628000000000", Address: nil, ShopID: 1,
 				})).Return(nil, store.ErrDuplicatePhone)
 				return cust, ord
 			},
@@ -728,14 +925,41 @@ func Test_cservice_CheckActiveOrderByPhone(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
 				cust := mock_store.NewMockCustomerStore(ctrl)
 				ord := mock_store.NewMockOrderStore(ctrl)
-				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "08123456789", 1).
-					Return(&model.Customer{ID: 1, Name: "John Doe", Phone: "08123456789", Address: "", CreatedAt: fixedTime}, nil)
+				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "+628123456789", 1).
+					Return(&model.Customer{ID: 1, Name: "John Doe", Phone: "+628123456789", Address: "", CreatedAt: fixedTime}, nil)
 				ord.EXPECT().GetActiveOrderByCustomerID(gomock.Any(), 1, 1).Return(nil, errors.New("database error"))
 				return cust, ord
 			},
 			want:    response.CustomerCheckActiveOrderByPhone{},
 			wantErr: true,
 		},
+		{
+			name:      "formatted phone finds the customer by its normalized number",
+			phone:     "62 812-345-6789",
+			nameParam: "John Doe",
+			shopID:    1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
+				cust := mock_store.NewMockCustomerStore(ctrl)
+				ord := mock_store.NewMockOrderStore(ctrl)
+				cust.EXPECT().GetCustomerByPhone(gomock.Any(), "+628123456789", 1).
+					Return(&model.Customer{ID: 1, Name: "John Doe", Phone: "+628123456789", Address: "", CreatedAt: fixedTime}, nil)
+				ord.EXPECT().GetActiveOrderByCustomerID(gomock.Any(), 1, 1).Return(nil, nil)
+				return cust, ord
+			},
+			want:    response.CustomerCheckActiveOrderByPhone{CustomerID: 1, ActiveOrderID: 0},
+			wantErr: false,
+		},
+		{
+			name:      "invalid phone returns error",
+			phone:     "not a phone",
+			nameParam: "John Doe",
+			shopID:    1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockOrderStore) {
+				return mock_store.NewMockCustomerStore(ctrl), mock_store.NewMockOrderStore(ctrl)
+			},
+			want:    response.CustomerCheckActiveOrderByPhone{},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -767,3 +991,823 @@ func Test_cservice_CheckActiveOrderByPhone(t *testing.T) {
 		})
 	}
 }
+
+func Test_cservice_GetCustomerCredits(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore)
+		want      response.CustomerCreditsData
+		wantErr   bool
+	}{
+		{
+			name: "returns ledger with balance",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockCredit := mock_store.NewMockCustomerCreditStore(ctrl)
+				mockCredit.EXPECT().GetCustomerCreditsByCustomerID(gomock.Any(), 1).Return([]model.CustomerCredit{
+					{ID: 2, ShopID: 10, CustomerID: 1, OrderID: sql.NullInt64{Int64: 7, Valid: true}, Type: "payment", Amount: -150000, CreatedAt: fixedTime},
+					{ID: 1, ShopID: 10, CustomerID: 1, Type: "deposit", Amount: 500000, Notes: "DP", CreatedAt: fixedTime},
+				}, nil)
+				return mockCustomer, mockCredit
+			},
+			want: response.CustomerCreditsData{
+				Balance: 350000,
+				Entries: []response.CustomerCreditData{
+					{ID: 2, OrderID: intPtr(7), Type: "payment", Amount: -150000, CreatedAt: fixedTime},
+					{ID: 1, Type: "deposit", Amount: 500000, Notes: "DP", CreatedAt: fixedTime},
+				},
+			},
+		},
+		{
+			name: "customer of another shop returns not found",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(nil, nil)
+				return mockCustomer, mock_store.NewMockCustomerCreditStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error when ledger lookup fails",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockCredit := mock_store.NewMockCustomerCreditStore(ctrl)
+				mockCredit.EXPECT().GetCustomerCreditsByCustomerID(gomock.Any(), 1).Return(nil, errors.New("database error"))
+				return mockCustomer, mockCredit
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldCustomerStore, oldCreditStore := customerStore, customerCreditStore
+			defer func() { customerStore, customerCreditStore = oldCustomerStore, oldCreditStore }()
+			customerStore, customerCreditStore = tt.mockSetup(ctrl)
+
+			var c cservice
+			got, gotErr := c.GetCustomerCredits(context.Background(), 1, 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomerCredits() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomerCredits() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCustomerCredits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cservice_CreateCustomerCredit(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     CreateCustomerCreditInput
+		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB)
+		want      response.CustomerCreditData
+		wantErr   bool
+	}{
+		{
+			name:  "records deposit",
+			input: CreateCustomerCreditInput{CustomerID: 1, ShopID: 10, Type: "deposit", Amount: 500000, Notes: "DP"},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockCredit := mock_store.NewMockCustomerCreditStore(ctrl)
+				mockCredit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 1).Return(0, nil)
+				mockCredit.EXPECT().CreateCustomerCredit(gomock.Any(), mockTx, store.CreateCustomerCreditInput{
+					ShopID: 10, CustomerID: 1, Type: "deposit", Amount: 500000, Notes: "DP",
+				}).Return(&model.CustomerCredit{ID: 3, ShopID: 10, CustomerID: 1, Type: "deposit", Amount: 500000, Notes: "DP", CreatedAt: fixedTime}, nil)
+				return mockCustomer, mockCredit, mockDB
+			},
+			want: response.CustomerCreditData{ID: 3, Type: "deposit", Amount: 500000, Notes: "DP", CreatedAt: fixedTime},
+		},
+		{
+			name:  "negative adjustment beyond balance is rejected",
+			input: CreateCustomerCreditInput{CustomerID: 1, ShopID: 10, Type: "adjustment", Amount: -200000},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockCredit := mock_store.NewMockCustomerCreditStore(ctrl)
+				mockCredit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 1).Return(150000, nil)
+				return mockCustomer, mockCredit, mockDB
+			},
+			wantErr: true,
+		},
+		{
+			name:  "customer not found",
+			input: CreateCustomerCreditInput{CustomerID: 1, ShopID: 10, Type: "deposit", Amount: 1000},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(nil, nil)
+				return mockCustomer, mock_store.NewMockCustomerCreditStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldCustomerStore, oldCreditStore, oldDBGetter := customerStore, customerCreditStore, dbGetter
+			defer func() {
+				customerStore, customerCreditStore, dbGetter = oldCustomerStore, oldCreditStore, oldDBGetter
+			}()
+
+			mockCustomer, mockCredit, mockDB := tt.mockSetup(ctrl)
+			customerStore = mockCustomer
+			customerCreditStore = mockCredit
+			dbGetter = func() database.DB { return mockDB }
+
+			var c cservice
+			got, gotErr := c.CreateCustomerCredit(context.Background(), tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateCustomerCredit() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateCustomerCredit() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateCustomerCredit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cservice_MergeCustomers(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		survivorID   int
+		duplicateIDs []int
+		mockSetup    func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB)
+		want         response.CustomerData
+		wantErrMsg   string
+	}{
+		{
+			name:         "merges duplicates into the survivor",
+			survivorID:   1,
+			duplicateIDs: []int{3, 2, 3},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				cust := mock_store.NewMockCustomerStore(ctrl)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, Name: "Jane", Phone: "+628123456789", CreatedAt: fixedTime}, nil).Times(2)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 2, 10).Return(&model.Customer{ID: 2}, nil)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 3, 10).Return(&model.Customer{ID: 3}, nil)
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				credit := mock_store.NewMockCustomerCreditStore(ctrl)
+				// Every ledger is locked, lowest customer ID first.
+				gomock.InOrder(
+					credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 1).Return(50000, nil),
+					credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 2).Return(0, nil),
+					credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 3).Return(0, nil),
+					cust.EXPECT().MergeCustomers(gomock.Any(), mockTx, 10, 1, []int{3, 2}).Return(nil),
+				)
+				credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), nil, 1).Return(50000, nil)
+				cust.EXPECT().GetCustomerStats(gomock.Any(), 1).Return(&model.CustomerStats{OrderCount: 4}, nil)
+				return cust, credit, mockDB
+			},
+			want: response.CustomerData{ID: 1, Name: "Jane", Phone: "+628123456789", Tags: []string{}, CreditBalance: intPtr(50000), Stats: &response.CustomerStatsData{OrderCount: 4}, CreatedAt: fixedTime},
+		},
+		{
+			name:         "rejects merging a customer into itself",
+			survivorID:   1,
+			duplicateIDs: []int{2, 1},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				return mock_store.NewMockCustomerStore(ctrl), nil, nil
+			},
+			wantErrMsg: apierr.ErrCustomerMergeIntoSelf,
+		},
+		{
+			name:       "requires at least one duplicate",
+			survivorID: 1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				return mock_store.NewMockCustomerStore(ctrl), nil, nil
+			},
+			wantErrMsg: apierr.ErrDuplicateIDsRequired,
+		},
+		{
+			name:         "returns not found when a duplicate belongs to another shop",
+			survivorID:   1,
+			duplicateIDs: []int{2},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				cust := mock_store.NewMockCustomerStore(ctrl)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1}, nil)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 2, 10).Return(nil, nil)
+				return cust, nil, nil
+			},
+			wantErrMsg: apierr.ErrCustomerNotFound,
+		},
+		{
+			name:         "returns error when the merge fails",
+			survivorID:   1,
+			duplicateIDs: []int{2},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				cust := mock_store.NewMockCustomerStore(ctrl)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1}, nil)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 2, 10).Return(&model.Customer{ID: 2}, nil)
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				credit := mock_store.NewMockCustomerCreditStore(ctrl)
+				credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 1).Return(0, nil)
+				credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 2).Return(0, nil)
+				cust.EXPECT().MergeCustomers(gomock.Any(), mockTx, 10, 1, []int{2}).Return(errors.New("database error"))
+				return cust, credit, mockDB
+			},
+			wantErrMsg: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cust, credit, mockDB := tt.mockSetup(ctrl)
+			oldCust, oldCredit, oldDBGetter := customerStore, customerCreditStore, dbGetter
+			defer func() { customerStore, customerCreditStore, dbGetter = oldCust, oldCredit, oldDBGetter }()
+			customerStore = cust
+			if credit != nil {
+				customerCreditStore = credit
+			}
+			if mockDB != nil {
+				dbGetter = func() database.DB { return mockDB }
+			}
+
+			var c cservice
+			got, gotErr := c.MergeCustomers(context.Background(), 10, tt.survivorID, tt.duplicateIDs)
+
+			if tt.wantErrMsg != "" {
+				if gotErr == nil || gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("MergeCustomers() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if gotErr != nil {
+				t.Fatalf("MergeCustomers() error = %v", gotErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeCustomers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cservice_GetCustomersByShopID_lastOrderDates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldCustomerStore, oldShopStore := customerStore, shopStore
+	defer func() { customerStore, shopStore = oldCustomerStore, oldShopStore }()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	// Asia/Jakarta is UTC+7: the local days start at 17:00 UTC the day before.
+	wantFrom := time.Date(2024, 2, 29, 17, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2024, 3, 31, 17, 0, 0, 0, time.UTC)
+
+	shopMock := mock_store.NewMockShopStore(ctrl)
+	shopMock.EXPECT().GetShopByID(gomock.Any(), 10).Return(&model.Shop{ID: 10, Timezone: "Asia/Jakarta"}, nil)
+	shopStore = shopMock
+
+	customerMock := mock_store.NewMockCustomerStore(ctrl)
+	customerMock.EXPECT().
+		GetCustomersByShopID(gomock.Any(), 10, model.CustomerFilterOptions{LastOrderFrom: &wantFrom, LastOrderTo: &wantTo}).
+		Return([]model.Customer{}, nil)
+	customerStore = customerMock
+
+	var c cservice
+	got, err := c.GetCustomersByShopID(context.Background(), 10, model.CustomerFilterOptions{LastOrderFrom: &from, LastOrderTo: &to})
+	if err != nil {
+		t.Fatalf("GetCustomersByShopID() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("GetCustomersByShopID() = %v, want empty", got)
+	}
+}
+
+func Test_normalizeTags(t *testing.T) {
+	tooMany := make([]string, 0, constant.CustomerMaxTags+1)
+	for i := 0; i <= constant.CustomerMaxTags; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("tag%d", i))
+	}
+
+	tests := []struct {
+		name    string
+		raw     []string
+		want    []string
+		wantErr string
+	}{
+		{name: "trims and drops empty tags", raw: []string{" VIP ", "", "  "}, want: []string{"VIP"}},
+		{name: "keeps the first spelling of a repeated tag", raw: []string{"Late Payer", "late payer", "VIP"}, want: []string{"Late Payer", "VIP"}},
+		{name: "empty list clears the tags", raw: []string{}, want: []string{}},
+		{name: "too long tag", raw: []string{strings.Repeat("é", constant.CustomerTagMaxLength+1)}, wantErr: apierr.ErrCustomerTagInvalid},
+		{name: "too many tags", raw: tooMany, wantErr: apierr.ErrTooManyCustomerTags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("normalizeTags() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeTags() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cservice_GetCustomerAddresses(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore)
+		want      []response.CustomerAddressData
+		wantErr   bool
+	}{
+		{
+			name: "returns addresses",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetCustomerAddressesByCustomerID(gomock.Any(), 1).Return([]model.CustomerAddress{
+					{ID: 2, CustomerID: 1, Label: "Home", Recipient: "Budi", Address: "Jl. Merdeka 1", PostalCode: "12345", IsDefault: true, CreatedAt: fixedTime},
+				}, nil)
+				return mockCustomer, mockAddress
+			},
+			want: []response.CustomerAddressData{
+				{ID: 2, Label: "Home", Recipient: "Budi", Address: "Jl. Merdeka 1", PostalCode: "12345", IsDefault: true, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "no addresses returns empty list",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetCustomerAddressesByCustomerID(gomock.Any(), 1).Return(nil, nil)
+				return mockCustomer, mockAddress
+			},
+			want: []response.CustomerAddressData{},
+		},
+		{
+			name: "customer not found",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(nil, nil)
+				return mockCustomer, mock_store.NewMockCustomerAddressStore(ctrl)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldCustomerStore, oldAddressStore := customerStore, customerAddressStore
+			defer func() {
+				customerStore, customerAddressStore = oldCustomerStore, oldAddressStore
+			}()
+
+			customerStore, customerAddressStore = tt.mockSetup(ctrl)
+
+			var c cservice
+			got, gotErr := c.GetCustomerAddresses(context.Background(), 1, 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomerAddresses() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomerAddresses() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCustomerAddresses() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cservice_CreateCustomerAddress(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     CreateCustomerAddressInput
+		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB)
+		want      response.CustomerAddressData
+		wantErr   bool
+	}{
+		{
+			name:  "first address becomes the default",
+			input: CreateCustomerAddressInput{CustomerID: 1, ShopID: 10, Recipient: "Budi", Phone: "08123456789", Address: "Jl. Merdeka 1", PostalCode: "12345"},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetDefaultCustomerAddress(gomock.Any(), 1).Return(nil, nil)
+				mockAddress.EXPECT().CreateCustomerAddress(gomock.Any(), mockTx, store.CreateCustomerAddressInput{
+					ShopID: 10, CustomerID: 1, Recipient: "Budi", Phone: "+628123456789", Address: "Jl. Merdeka 1", PostalCode: "12345", IsDefault: true,
+				}).Return(&model.CustomerAddress{ID: 2, CustomerID: 1, Recipient: "Budi", Phone: "+628123456789", Address: "Jl. Merdeka 1", PostalCode: "12345", IsDefault: true, CreatedAt: fixedTime}, nil)
+				return mockCustomer, mockAddress, mockDB
+			},
+			want: response.CustomerAddressData{ID: 2, Recipient: "Budi", Phone: "+628123456789", Address: "Jl. Merdeka 1", PostalCode: "12345", IsDefault: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "new default clears the current one",
+			input: CreateCustomerAddressInput{CustomerID: 1, ShopID: 10, Label: "Office", Recipient: "Budi", Address: "Jl. Sudirman 5", PostalCode: "10220", IsDefault: true},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetDefaultCustomerAddress(gomock.Any(), 1).Return(&model.CustomerAddress{ID: 2, CustomerID: 1, IsDefault: true}, nil)
+				mockAddress.EXPECT().ClearDefaultCustomerAddress(gomock.Any(), mockTx, 1).Return(nil)
+				mockAddress.EXPECT().CreateCustomerAddress(gomock.Any(), mockTx, store.CreateCustomerAddressInput{
+					ShopID: 10, CustomerID: 1, Label: "Office", Recipient: "Budi", Address: "Jl. Sudirman 5", PostalCode: "10220", IsDefault: true,
+				}).Return(&model.CustomerAddress{ID: 3, CustomerID: 1, Label: "Office", Recipient: "Budi", Address: "Jl. Sudirman 5", PostalCode: "10220", IsDefault: true, CreatedAt: fixedTime}, nil)
+				return mockCustomer, mockAddress, mockDB
+			},
+			want: response.CustomerAddressData{ID: 3, Label: "Office", Recipient: "Budi", Address: "Jl. Sudirman 5", PostalCode: "10220", IsDefault: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "invalid phone is rejected",
+			input: CreateCustomerAddressInput{CustomerID: 1, ShopID: 10, Recipient: "Budi", Phone: "abc", Address: "Jl. Merdeka 1"},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+				return mockCustomer, mock_store.NewMockCustomerAddressStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "customer not found",
+			input: CreateCustomerAddressInput{CustomerID: 1, ShopID: 10, Recipient: "Budi", Address: "Jl. Merdeka 1"},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(nil, nil)
+				return mockCustomer, mock_store.NewMockCustomerAddressStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldCustomerStore, oldAddressStore, oldDBGetter := customerStore, customerAddressStore, dbGetter
+			defer func() {
+				customerStore, customerAddressStore, dbGetter = oldCustomerStore, oldAddressStore, oldDBGetter
+			}()
+
+			mockCustomer, mockAddress, mockDB := tt.mockSetup(ctrl)
+			customerStore = mockCustomer
+			customerAddressStore = mockAddress
+			dbGetter = func() database.DB { return mockDB }
+
+			var c cservice
+			got, gotErr := c.CreateCustomerAddress(context.Background(), tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateCustomerAddress() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateCustomerAddress() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateCustomerAddress() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cservice_UpdateCustomerAddress(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	isDefault := true
+	recipient := "Siti"
+
+	tests := []struct {
+		name      string
+		input     UpdateCustomerAddressInput
+		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB)
+		want      response.CustomerAddressData
+		wantErr   bool
+	}{
+		{
+			name:  "marking as default clears the current one",
+			input: UpdateCustomerAddressInput{ID: 3, CustomerID: 1, ShopID: 10, IsDefault: &isDefault},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetCustomerAddressByID(gomock.Any(), 3, 1).Return(&model.CustomerAddress{ID: 3, CustomerID: 1}, nil)
+				mockAddress.EXPECT().ClearDefaultCustomerAddress(gomock.Any(), mockTx, 1).Return(nil)
+				mockAddress.EXPECT().UpdateCustomerAddress(gomock.Any(), mockTx, 3, store.UpdateCustomerAddressInput{IsDefault: &isDefault}).
+					Return(&model.CustomerAddress{ID: 3, CustomerID: 1, Recipient: "Budi", IsDefault: true, CreatedAt: fixedTime}, nil)
+				return mockCustomer, mockAddress, mockDB
+			},
+			want: response.CustomerAddressData{ID: 3, Recipient: "Budi", IsDefault: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "updates fields without touching the default",
+			input: UpdateCustomerAddressInput{ID: 3, CustomerID: 1, ShopID: 10, Recipient: &recipient},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetCustomerAddressByID(gomock.Any(), 3, 1).Return(&model.CustomerAddress{ID: 3, CustomerID: 1}, nil)
+				mockAddress.EXPECT().UpdateCustomerAddress(gomock.Any(), mockTx, 3, store.UpdateCustomerAddressInput{Recipient: &recipient}).
+					Return(&model.CustomerAddress{ID: 3, CustomerID: 1, Recipient: "Siti", CreatedAt: fixedTime}, nil)
+				return mockCustomer, mockAddress, mockDB
+			},
+			want: response.CustomerAddressData{ID: 3, Recipient: "Siti", CreatedAt: fixedTime},
+		},
+		{
+			name:  "address not found",
+			input: UpdateCustomerAddressInput{ID: 3, CustomerID: 1, ShopID: 10, Recipient: &recipient},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetCustomerAddressByID(gomock.Any(), 3, 1).Return(nil, nil)
+				return mockCustomer, mockAddress, mock_database.NewMockDB(ctrl)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldCustomerStore, oldAddressStore, oldDBGetter := customerStore, customerAddressStore, dbGetter
+			defer func() {
+				customerStore, customerAddressStore, dbGetter = oldCustomerStore, oldAddressStore, oldDBGetter
+			}()
+
+			mockCustomer, mockAddress, mockDB := tt.mockSetup(ctrl)
+			customerStore = mockCustomer
+			customerAddressStore = mockAddress
+			dbGetter = func() database.DB { return mockDB }
+
+			var c cservice
+			got, gotErr := c.UpdateCustomerAddress(context.Background(), tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("UpdateCustomerAddress() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("UpdateCustomerAddress() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateCustomerAddress() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cservice_DeleteCustomerAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockSetup  func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore)
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name: "deletes address",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetCustomerAddressByID(gomock.Any(), 3, 1).Return(&model.CustomerAddress{ID: 3, CustomerID: 1}, nil)
+				mockAddress.EXPECT().DeleteCustomerAddressByID(gomock.Any(), 3, 1).Return(nil)
+				return mockCustomer, mockAddress
+			},
+		},
+		{
+			name: "address not found",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, ShopID: 10}, nil)
+				mockAddress := mock_store.NewMockCustomerAddressStore(ctrl)
+				mockAddress.EXPECT().GetCustomerAddressByID(gomock.Any(), 3, 1).Return(nil, nil)
+				return mockCustomer, mockAddress
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrCustomerAddressNotFound,
+		},
+		{
+			name: "customer not found",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerAddressStore) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(nil, nil)
+				return mockCustomer, mock_store.NewMockCustomerAddressStore(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrCustomerNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldCustomerStore, oldAddressStore := customerStore, customerAddressStore
+			defer func() {
+				customerStore, customerAddressStore = oldCustomerStore, oldAddressStore
+			}()
+
+			customerStore, customerAddressStore = tt.mockSetup(ctrl)
+
+			var c cservice
+			gotErr := c.DeleteCustomerAddress(context.Background(), 3, 1, 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("DeleteCustomerAddress() error = %v, wantErr %v", gotErr, tt.wantErr)
+				} else if gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("DeleteCustomerAddress() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("DeleteCustomerAddress() succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func Test_cservice_AnonymizeCustomer(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		mockSetup  func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB)
+		want       response.CustomerData
+		wantErrMsg string
+	}{
+		{
+			name: "erases the customer's personal data",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				cust := mock_store.NewMockCustomerStore(ctrl)
+				gomock.InOrder(
+					cust.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, Name: "Jane", Phone: "+628123456789", CreatedAt: fixedTime}, nil),
+					cust.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1, Name: "Deleted customer", Phone: "anon-1", CreatedAt: fixedTime}, nil),
+				)
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				cust.EXPECT().AnonymizeCustomer(gomock.Any(), mockTx, 10, 1).Return(nil)
+				credit := mock_store.NewMockCustomerCreditStore(ctrl)
+				credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), nil, 1).Return(0, nil)
+				cust.EXPECT().GetCustomerStats(gomock.Any(), 1).Return(&model.CustomerStats{OrderCount: 4, TotalSpent: 300000}, nil)
+				return cust, credit, mockDB
+			},
+			want: response.CustomerData{ID: 1, Name: "Deleted customer", Phone: "anon-1", Tags: []string{}, CreditBalance: intPtr(0), Stats: &response.CustomerStatsData{OrderCount: 4, TotalSpent: 300000}, CreatedAt: fixedTime},
+		},
+		{
+			name: "customer not found",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				cust := mock_store.NewMockCustomerStore(ctrl)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(nil, nil)
+				return cust, nil, nil
+			},
+			wantErrMsg: apierr.ErrCustomerNotFound,
+		},
+		{
+			name: "store error rolls back",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_store.MockCustomerCreditStore, *mock_database.MockDB) {
+				cust := mock_store.NewMockCustomerStore(ctrl)
+				cust.EXPECT().GetCustomerByID(gomock.Any(), 1, 10).Return(&model.Customer{ID: 1}, nil)
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				cust.EXPECT().AnonymizeCustomer(gomock.Any(), mockTx, 10, 1).Return(errors.New("db error"))
+				return cust, nil, mockDB
+			},
+			wantErrMsg: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cust, credit, mockDB := tt.mockSetup(ctrl)
+			oldCust, oldCredit, oldDBGetter := customerStore, customerCreditStore, dbGetter
+			defer func() { customerStore, customerCreditStore, dbGetter = oldCust, oldCredit, oldDBGetter }()
+			customerStore = cust
+			if credit != nil {
+				customerCreditStore = credit
+			}
+			if mockDB != nil {
+				dbGetter = func() database.DB { return mockDB }
+			}
+
+			var c cservice
+			got, gotErr := c.AnonymizeCustomer(context.Background(), 1, 10)
+
+			if tt.wantErrMsg != "" {
+				if gotErr == nil || gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("AnonymizeCustomer() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if gotErr != nil {
+				t.Fatalf("AnonymizeCustomer() error = %v", gotErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AnonymizeCustomer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
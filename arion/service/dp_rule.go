@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+type (
+	DPRuleService interface {
+		CreateDPRule(ctx context.Context, input CreateDPRuleInput) (response.DPRuleData, error)
+		GetDPRulesByShopID(ctx context.Context, shopID int) ([]response.DPRuleData, error)
+		UpdateDPRule(ctx context.Context, input UpdateDPRuleInput) (response.DPRuleData, error)
+		DeleteDPRuleByID(ctx context.Context, id, shopID int) error
+	}
+
+	dpservice struct{}
+
+	CreateDPRuleInput struct {
+		ShopID     int
+		Name       string
+		Percent    int
+		DueDays    *int
+		DueDate    *time.Time
+		AutoCancel bool
+		IsDefault  bool
+	}
+
+	UpdateDPRuleInput struct {
+		ID         int
+		ShopID     int
+		Name       *string
+		Percent    *int
+		DueDays    *int
+		DueDate    *time.Time
+		AutoCancel *bool
+		IsDefault  *bool
+	}
+)
+
+func NewDPRuleService() DPRuleService {
+	if dpRuleStore == nil {
+		dpRuleStore = store.NewDPRuleStore()
+	}
+
+	return &dpservice{}
+}
+
+// CreateDPRule stores a new rule. A new default rule replaces the shop's
+// current default; orders that already have a rule keep it.
+func (d *dpservice) CreateDPRule(ctx context.Context, input CreateDPRuleInput) (response.DPRuleData, error) {
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.DPRuleData{}, err
+	}
+	defer tx.Rollback()
+
+	if input.IsDefault {
+		if err := dpRuleStore.ClearDefaultDPRule(ctx, tx, input.ShopID); err != nil {
+			return response.DPRuleData{}, err
+		}
+	}
+
+	rule, err := dpRuleStore.CreateDPRule(ctx, tx, store.CreateDPRuleInput{
+		ShopID:     input.ShopID,
+		Name:       input.Name,
+		Percent:    input.Percent,
+		DueDays:    input.DueDays,
+		DueDate:    input.DueDate,
+		AutoCancel: input.AutoCancel,
+		IsDefault:  input.IsDefault,
+	})
+	if err != nil {
+		return response.DPRuleData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.DPRuleData{}, err
+	}
+
+	return toDPRuleData(*rule), nil
+}
+
+func (d *dpservice) GetDPRulesByShopID(ctx context.Context, shopID int) ([]response.DPRuleData, error) {
+	rules, err := dpRuleStore.GetDPRulesByShopID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []response.DPRuleData{}
+	for _, rule := range rules {
+		res = append(res, toDPRuleData(rule))
+	}
+
+	return res, nil
+}
+
+func (d *dpservice) UpdateDPRule(ctx context.Context, input UpdateDPRuleInput) (response.DPRuleData, error) {
+	rule, err := dpRuleStore.GetDPRuleByID(ctx, input.ID, input.ShopID)
+	if err != nil {
+		return response.DPRuleData{}, err
+	}
+
+	if rule == nil {
+		return response.DPRuleData{}, errors.New(apierr.ErrDPRuleNotFound)
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.DPRuleData{}, err
+	}
+	defer tx.Rollback()
+
+	if input.IsDefault != nil && *input.IsDefault && !rule.IsDefault {
+		if err := dpRuleStore.ClearDefaultDPRule(ctx, tx, input.ShopID); err != nil {
+			return response.DPRuleData{}, err
+		}
+	}
+
+	rule, err = dpRuleStore.UpdateDPRule(ctx, tx, input.ID, store.UpdateDPRuleInput{
+		Name:       input.Name,
+		Percent:    input.Percent,
+		DueDays:    input.DueDays,
+		DueDate:    input.DueDate,
+		AutoCancel: input.AutoCancel,
+		IsDefault:  input.IsDefault,
+	})
+	if err != nil {
+		return response.DPRuleData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.DPRuleData{}, err
+	}
+
+	return toDPRuleData(*rule), nil
+}
+
+func (d *dpservice) DeleteDPRuleByID(ctx context.Context, id, shopID int) error {
+	rule, err := dpRuleStore.GetDPRuleByID(ctx, id, shopID)
+	if err != nil {
+		return err
+	}
+
+	if rule == nil {
+		return errors.New(apierr.ErrDPRuleNotFound)
+	}
+
+	return dpRuleStore.DeleteDPRuleByID(ctx, id, shopID)
+}
+
+func toDPRuleData(rule model.DPRule) response.DPRuleData {
+	res := response.DPRuleData{
+		ID:         rule.ID,
+		Name:       rule.Name,
+		Percent:    rule.Percent,
+		AutoCancel: rule.AutoCancel,
+		IsDefault:  rule.IsDefault,
+		CreatedAt:  rule.CreatedAt,
+	}
+
+	if rule.DueDays.Valid {
+		days := int(rule.DueDays.Int64)
+		res.DueDays = &days
+	}
+
+	if rule.DueDate.Valid {
+		res.DueDate = &rule.DueDate.Time
+	}
+
+	if rule.UpdatedAt.Valid {
+		res.UpdatedAt = &rule.UpdatedAt.Time
+	}
+
+	return res
+}
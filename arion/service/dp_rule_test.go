@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+func Test_dpservice_CreateDPRule(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	dueDays := 3
+
+	tests := []struct {
+		name      string
+		input     CreateDPRuleInput
+		mockSetup func(tx *mock_database.MockTx, rule *mock_store.MockDPRuleStore)
+		want      response.DPRuleData
+		wantErr   bool
+	}{
+		{
+			name:  "new default rule replaces the current default",
+			input: CreateDPRuleInput{ShopID: 10, Name: "DP 50%", Percent: 50, DueDays: &dueDays, IsDefault: true},
+			mockSetup: func(tx *mock_database.MockTx, rule *mock_store.MockDPRuleStore) {
+				tx.EXPECT().Commit().Return(nil)
+				rule.EXPECT().ClearDefaultDPRule(gomock.Any(), tx, 10).Return(nil)
+				rule.EXPECT().CreateDPRule(gomock.Any(), tx, store.CreateDPRuleInput{ShopID: 10, Name: "DP 50%", Percent: 50, DueDays: &dueDays, IsDefault: true}).
+					Return(&model.DPRule{ID: 1, ShopID: 10, Name: "DP 50%", Percent: 50, DueDays: sql.NullInt64{Int64: 3, Valid: true}, IsDefault: true, CreatedAt: fixedTime}, nil)
+			},
+			want: response.DPRuleData{ID: 1, Name: "DP 50%", Percent: 50, DueDays: &dueDays, IsDefault: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "non-default rule keeps the current default",
+			input: CreateDPRuleInput{ShopID: 10, Name: "Trip", Percent: 30, DueDays: &dueDays},
+			mockSetup: func(tx *mock_database.MockTx, rule *mock_store.MockDPRuleStore) {
+				tx.EXPECT().Commit().Return(nil)
+				rule.EXPECT().CreateDPRule(gomock.Any(), tx, gomock.Any()).
+					Return(&model.DPRule{ID: 2, ShopID: 10, Name: "Trip", Percent: 30, DueDays: sql.NullInt64{Int64: 3, Valid: true}, CreatedAt: fixedTime}, nil)
+			},
+			want: response.DPRuleData{ID: 2, Name: "Trip", Percent: 30, DueDays: &dueDays, CreatedAt: fixedTime},
+		},
+		{
+			name:  "returns error when create fails",
+			input: CreateDPRuleInput{ShopID: 10, Name: "Trip", Percent: 30, DueDays: &dueDays},
+			mockSetup: func(tx *mock_database.MockTx, rule *mock_store.MockDPRuleStore) {
+				rule.EXPECT().CreateDPRule(gomock.Any(), tx, gomock.Any()).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldDPRuleStore, oldDBGetter := dpRuleStore, dbGetter
+			defer func() { dpRuleStore, dbGetter = oldDPRuleStore, oldDBGetter }()
+
+			mockTx := mock_database.NewMockTx(ctrl)
+			mockTx.EXPECT().Rollback().Return(nil)
+			mockDB := mock_database.NewMockDB(ctrl)
+			mockDB.EXPECT().Begin().Return(mockTx, nil)
+			mockRule := mock_store.NewMockDPRuleStore(ctrl)
+			tt.mockSetup(mockTx, mockRule)
+
+			dpRuleStore = mockRule
+			dbGetter = func() database.DB { return mockDB }
+
+			var d dpservice
+			got, gotErr := d.CreateDPRule(context.Background(), tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateDPRule() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateDPRule() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateDPRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dpservice_GetDPRulesByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	departure := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(rule *mock_store.MockDPRuleStore)
+		want      []response.DPRuleData
+		wantErr   bool
+	}{
+		{
+			name: "returns rules",
+			mockSetup: func(rule *mock_store.MockDPRuleStore) {
+				rule.EXPECT().GetDPRulesByShopID(gomock.Any(), 10).Return([]model.DPRule{
+					{ID: 2, ShopID: 10, Name: "Trip Jepang", Percent: 30, DueDate: sql.NullTime{Time: departure, Valid: true}, AutoCancel: true, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+				}, nil)
+			},
+			want: []response.DPRuleData{
+				{ID: 2, Name: "Trip Jepang", Percent: 30, DueDate: &departure, AutoCancel: true, CreatedAt: fixedTime, UpdatedAt: &fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when shop has no rules",
+			mockSetup: func(rule *mock_store.MockDPRuleStore) {
+				rule.EXPECT().GetDPRulesByShopID(gomock.Any(), 10).Return([]model.DPRule{}, nil)
+			},
+			want: []response.DPRuleData{},
+		},
+		{
+			name: "returns error on store failure",
+			mockSetup: func(rule *mock_store.MockDPRuleStore) {
+				rule.EXPECT().GetDPRulesByShopID(gomock.Any(), 10).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldDPRuleStore := dpRuleStore
+			defer func() { dpRuleStore = oldDPRuleStore }()
+
+			mockRule := mock_store.NewMockDPRuleStore(ctrl)
+			tt.mockSetup(mockRule)
+			dpRuleStore = mockRule
+
+			var d dpservice
+			got, gotErr := d.GetDPRulesByShopID(context.Background(), 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetDPRulesByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetDPRulesByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDPRulesByShopID() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dpservice_UpdateDPRule(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	percent := 40
+	isDefault := true
+
+	tests := []struct {
+		name      string
+		input     UpdateDPRuleInput
+		mockSetup func(ctrl *gomock.Controller, rule *mock_store.MockDPRuleStore) *mock_database.MockDB
+		want      response.DPRuleData
+		wantErr   string
+	}{
+		{
+			name:  "making a rule default clears the previous default",
+			input: UpdateDPRuleInput{ID: 2, ShopID: 10, Percent: &percent, IsDefault: &isDefault},
+			mockSetup: func(ctrl *gomock.Controller, rule *mock_store.MockDPRuleStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				rule.EXPECT().GetDPRuleByID(gomock.Any(), 2, 10).Return(&model.DPRule{ID: 2, ShopID: 10}, nil)
+				rule.EXPECT().ClearDefaultDPRule(gomock.Any(), mockTx, 10).Return(nil)
+				rule.EXPECT().UpdateDPRule(gomock.Any(), mockTx, 2, store.UpdateDPRuleInput{Percent: &percent, IsDefault: &isDefault}).
+					Return(&model.DPRule{ID: 2, ShopID: 10, Name: "Trip", Percent: 40, IsDefault: true, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}}, nil)
+				return mockDB
+			},
+			want: response.DPRuleData{ID: 2, Name: "Trip", Percent: 40, IsDefault: true, CreatedAt: fixedTime, UpdatedAt: &fixedTime},
+		},
+		{
+			name:  "returns error when rule not found",
+			input: UpdateDPRuleInput{ID: 2, ShopID: 10, Percent: &percent},
+			mockSetup: func(ctrl *gomock.Controller, rule *mock_store.MockDPRuleStore) *mock_database.MockDB {
+				rule.EXPECT().GetDPRuleByID(gomock.Any(), 2, 10).Return(nil, nil)
+				return mock_database.NewMockDB(ctrl)
+			},
+			wantErr: apierr.ErrDPRuleNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldDPRuleStore, oldDBGetter := dpRuleStore, dbGetter
+			defer func() { dpRuleStore, dbGetter = oldDPRuleStore, oldDBGetter }()
+
+			mockRule := mock_store.NewMockDPRuleStore(ctrl)
+			mockDB := tt.mockSetup(ctrl, mockRule)
+			dpRuleStore = mockRule
+			dbGetter = func() database.DB { return mockDB }
+
+			var d dpservice
+			got, gotErr := d.UpdateDPRule(context.Background(), tt.input)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("UpdateDPRule() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("UpdateDPRule() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateDPRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dpservice_DeleteDPRuleByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(rule *mock_store.MockDPRuleStore)
+		wantErr   string
+	}{
+		{
+			name: "deletes rule",
+			mockSetup: func(rule *mock_store.MockDPRuleStore) {
+				rule.EXPECT().GetDPRuleByID(gomock.Any(), 2, 10).Return(&model.DPRule{ID: 2, ShopID: 10}, nil)
+				rule.EXPECT().DeleteDPRuleByID(gomock.Any(), 2, 10).Return(nil)
+			},
+		},
+		{
+			name: "returns error when rule not found",
+			mockSetup: func(rule *mock_store.MockDPRuleStore) {
+				rule.EXPECT().GetDPRuleByID(gomock.Any(), 2, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrDPRuleNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldDPRuleStore := dpRuleStore
+			defer func() { dpRuleStore = oldDPRuleStore }()
+
+			mockRule := mock_store.NewMockDPRuleStore(ctrl)
+			tt.mockSetup(mockRule)
+			dpRuleStore = mockRule
+
+			var d dpservice
+			gotErr := d.DeleteDPRuleByID(context.Background(), 2, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("DeleteDPRuleByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("DeleteDPRuleByID() succeeded unexpectedly")
+			}
+		})
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"time"
 
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/config"
 	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/storage"
 	"github.com/zeirash/recapo/arion/store"
 )
 
@@ -35,14 +37,22 @@ type (
 var githubAPIFunc func(context.Context, githubIssueRequest) error = callGithubAPI
 
 func NewFeedbackService() FeedbackService {
+	cfg = config.GetConfig()
+
 	if userStore == nil {
 		userStore = store.NewUserStore()
 	}
+	if uploadedObjectStore == nil {
+		uploadedObjectStore = store.NewUploadedObjectStore()
+	}
+	if storageBackend == nil {
+		storageBackend = storage.New(cfg)
+	}
 	return &sfeedback{}
 }
 
 func (s *sfeedback) UploadFeedbackImage(ctx context.Context, file io.Reader) (string, error) {
-	return uploadImage(file, "feedback")
+	return uploadImage(ctx, file, "feedback")
 }
 
 func (s *sfeedback) CreateFeedback(ctx context.Context, userID int, feedbackType, title, description, imageURL string) error {
@@ -2,91 +2,323 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/storage"
+	"github.com/zeirash/recapo/arion/common/webp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
-// uploadImage detects the content type, generates a random filename, and uploads
-// the image to R2 (or the local filesystem in dev). pathPrefix is the directory
-// segment used both as the R2 object key prefix and the local upload sub-directory
-// (e.g. "products", "feedback").
-const maxImageSize = 5 * 1024 * 1024 // 5MB
+const (
+	maxImageSize = 5 * 1024 * 1024 // 5MB
+	// maxImagePixels caps the decoded size of a processed upload, so a small
+	// file cannot claim a huge canvas.
+	maxImagePixels = 50_000_000
+)
+
+// imageExtensions maps the image types accepted for upload to the file
+// extension they are stored with.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
 
-func uploadImage(file io.Reader, pathPrefix string) (string, error) {
-	limited := io.LimitReader(file, maxImageSize+1)
+// imageVariant is one resized copy of a processed upload: its file name
+// suffix and the longest side it is scaled down to.
+type imageVariant struct {
+	suffix  string
+	maxSize int
+}
 
-	buf := make([]byte, 512)
-	n, err := limited.Read(buf)
-	if err != nil && err != io.EOF {
+// uploadImage detects the content type, generates a random filename, and
+// stores the image under pathPrefix (e.g. "products", "feedback").
+func uploadImage(ctx context.Context, file io.Reader, pathPrefix string) (string, error) {
+	data, contentType, err := readImageUpload(file)
+	if err != nil {
 		return "", err
 	}
-	contentType := http.DetectContentType(buf[:n])
 
-	var ext string
-	switch contentType {
-	case "image/jpeg":
-		ext = ".jpg"
-	case "image/png":
-		ext = ".png"
-	case "image/webp":
-		ext = ".webp"
-	default:
-		return "", errors.New(apierr.ErrUnsupportedImageType)
+	name, err := randomImageName()
+	if err != nil {
+		return "", err
 	}
 
+	return storeImage(ctx, pathPrefix, name+imageExtensions[contentType], contentType, data)
+}
+
+// uploadProcessedImage decodes the image and stores one WebP copy of it per
+// variant, scaled down to fit the variant's size, under pathPrefix. Decoding
+// and re-encoding drops the EXIF block, GPS position included; a JPEG's
+// orientation is applied to the pixels first so the copies stay upright.
+// The copies are lossy, like the photos they mostly come from, and keep any
+// transparency. The URLs are returned in the order of variants.
+func uploadProcessedImage(ctx context.Context, file io.Reader, pathPrefix string, variants []imageVariant) ([]string, error) {
+	data, _, err := readImageUpload(file)
+	if err != nil {
+		return nil, err
+	}
+
+	img, orientation, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomImageName()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(variants))
+	for _, v := range variants {
+		var buf bytes.Buffer
+		variant := orientImage(resizeImage(img, v.maxSize), orientation)
+		if err := webp.EncodeLossy(&buf, variant, constant.ProductImageWebPQuality); err != nil {
+			return nil, err
+		}
+
+		url, err := storeImage(ctx, pathPrefix, name+"_"+v.suffix+".webp", "image/webp", buf.Bytes())
+		if err != nil {
+			// Don't leave the variants already stored behind.
+			for _, stored := range urls {
+				deleteUploadedImage(ctx, stored)
+			}
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, nil
+}
+
+// readImageUpload reads an upload of at most maxImageSize bytes and checks
+// that it is one of the accepted image types.
+func readImageUpload(file io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(file, maxImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageSize {
+		return nil, "", errors.New(apierr.ErrImageTooLarge)
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		return nil, "", errors.New(apierr.ErrUnsupportedImageType)
+	}
+
+	return data, contentType, nil
+}
+
+func randomImageName() (string, error) {
 	randBytes := make([]byte, 16)
 	if _, err := rand.Read(randBytes); err != nil {
 		return "", err
 	}
-	filename := fmt.Sprintf("%x%s", randBytes, ext)
+	return fmt.Sprintf("%x", randBytes), nil
+}
+
+// untrackedUploadDirs are the upload directories whose files are referenced
+// from outside the database, so they are kept out of uploaded_objects and
+// never garbage collected. Feedback screenshots are linked from GitHub issues.
+var untrackedUploadDirs = map[string]bool{
+	"feedback": true,
+}
 
-	// Buffer the entire file content so the reader is seekable. The AWS SDK v2
-	// reads the body once to compute a CRC32 checksum, then seeks back to the
-	// start before sending. A non-seekable io.MultiReader causes the second read
-	// to return empty data, producing a BadDigest error from Cloudflare R2.
-	var bodyBuf bytes.Buffer
-	bodyBuf.Write(buf[:n])
-	if _, err := io.Copy(&bodyBuf, limited); err != nil {
+// storeImage stores data under pathPrefix/filename, records it for garbage
+// collection and returns the URL it is served from.
+func storeImage(ctx context.Context, pathPrefix, filename, contentType string, data []byte) (string, error) {
+	key := pathPrefix + "/" + filename
+	if err := storageBackend.Put(ctx, key, contentType, data); err != nil {
 		return "", err
 	}
-	if bodyBuf.Len() > maxImageSize {
-		return "", errors.New(apierr.ErrImageTooLarge)
-	}
-	fullReader := bytes.NewReader(bodyBuf.Bytes())
 
-	// Cloud path: upload to Cloudflare R2.
-	if cfg.R2BucketName != "" {
-		objectKey := pathPrefix + "/" + filename
-		if err := r2UploadFunc(objectKey, fullReader, contentType); err != nil {
+	if !untrackedUploadDirs[pathPrefix] {
+		if err := uploadedObjectStore.CreateUploadedObject(ctx, key, int64(len(data)), contentType); err != nil {
+			storageBackend.Delete(ctx, key)
 			return "", err
 		}
-		return cfg.R2PublicURL + "/" + objectKey, nil
 	}
 
-	// Local filesystem path.
-	uploadDir := filepath.Join(cfg.UploadDir, pathPrefix)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return "", err
+	return storageBackend.URL(key), nil
+}
+
+// decodeImage decodes a JPEG, PNG or WebP image and returns it with its EXIF
+// orientation (1 when it has none).
+func decodeImage(data []byte) (image.Image, int, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, errors.New(apierr.ErrUnsupportedImageType)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, 0, errors.New(apierr.ErrImageTooLarge)
 	}
 
-	filePath := filepath.Join(uploadDir, filename)
-	dst, err := os.Create(filePath)
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return "", err
+		return nil, 0, errors.New(apierr.ErrUnsupportedImageType)
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, fullReader); err != nil {
-		os.Remove(filePath)
-		return "", err
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
 	}
+	return img, orientation, nil
+}
 
-	return "/uploads/" + pathPrefix + "/" + filename, nil
+// resizeImage scales img down so its longest side is at most maxSize,
+// keeping its aspect ratio. Smaller images are returned as they are.
+func resizeImage(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+	if w >= h {
+		w, h = maxSize, max(1, h*maxSize/w)
+	} else {
+		w, h = max(1, w*maxSize/h), maxSize
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// jpegOrientation returns the orientation tag of a JPEG's EXIF block, or 1
+// when there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+		marker := data[i+1]
+		// Metadata segments all come before the start of scan.
+		if marker == 0xda || marker == 0xd9 {
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of a TIFF
+// structured EXIF block.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		off := ifd + 2 + e*12
+		if off+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[off:]) != 0x0112 {
+			continue
+		}
+		if v := int(order.Uint16(tiff[off+8:])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 1
+	}
+	return 1
+}
+
+// orientImage returns img flipped and rotated as its EXIF orientation says
+// it should be displayed.
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// readUploadedImage returns the content of an image previously stored by
+// uploadImage.
+func readUploadedImage(ctx context.Context, imageURL string) ([]byte, error) {
+	key, ok := storageBackend.Key(imageURL)
+	if !ok {
+		return nil, errors.New(apierr.ErrInvalidImageURL)
+	}
+	return storageBackend.Get(ctx, key)
+}
+
+// deleteUploadedImage removes an image previously stored by uploadImage
+// along with its record. An image that is already gone is not an error.
+func deleteUploadedImage(ctx context.Context, imageURL string) error {
+	key, ok := storageBackend.Key(imageURL)
+	if !ok {
+		return errors.New(apierr.ErrInvalidImageURL)
+	}
+	if err := storageBackend.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return uploadedObjectStore.DeleteUploadedObject(ctx, key)
 }
@@ -2,107 +2,185 @@ package service
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
 	"io"
+	"math/rand"
 	"strings"
 	"testing"
 
+	"github.com/golang/mock/gomock"
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/storage"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"golang.org/x/image/webp"
 )
 
+// testImage returns a w by h image with a colour gradient.
+func testImage(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(w, h)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeTestJPEG returns a w by h JPEG. A non-zero orientation adds an EXIF
+// block with that orientation and a GPS position, as phone cameras write.
+func encodeTestJPEG(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(w, h), nil); err != nil {
+		t.Fatal(err)
+	}
+	if orientation == 0 {
+		return buf.Bytes()
+	}
+
+	// TIFF header and one IFD holding the orientation and a GPS IFD pointing
+	// at a latitude reference.
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x8825)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 4)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 38)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0001)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 2)
+	tiff = append(tiff, 'S', 0, 0, 0)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+// useMemoryStorage swaps the storage backend for an in-memory one serving
+// from /uploads, and uploadedObjectStore for a mock accepting every call,
+// until the test ends.
+func useMemoryStorage(t *testing.T) *storage.Memory {
+	t.Helper()
+	oldBackend, oldStore := storageBackend, uploadedObjectStore
+	t.Cleanup(func() { storageBackend, uploadedObjectStore = oldBackend, oldStore })
+
+	m := mock_store.NewMockUploadedObjectStore(gomock.NewController(t))
+	m.EXPECT().CreateUploadedObject(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	m.EXPECT().DeleteUploadedObject(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	backend := storage.NewMemory(storage.LocalURLPrefix)
+	storageBackend, uploadedObjectStore = backend, m
+	return backend
+}
+
 func Test_uploadImage(t *testing.T) {
 	// Magic bytes for each supported type.
 	// Note: Go's http.DetectContentType does not recognise webp, so there is no webp success case.
 	jpegBytes := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00, 0x01}
 	pngBytes := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
 
-	tmpDir := t.TempDir()
-	oldCfg := cfg
-	cfg.UploadDir = tmpDir
-	defer func() { cfg = oldCfg }()
-
 	tests := []struct {
-		name         string
-		file         []byte
-		pathPrefix   string
-		r2BucketName string // non-empty enables R2 path
-		r2UploadErr  error
-		wantURLPfx   string
-		wantExt      string
-		wantErr      bool
-		wantErrMsg   string
+		name       string
+		file       []byte
+		pathPrefix string
+		mockSetup  func(m *mock_store.MockUploadedObjectStore)
+		wantURLPfx string
+		wantExt    string
+		wantStored bool
+		wantErr    bool
+		wantErrMsg string
 	}{
 		{
-			name:       "jpeg saved to local filesystem",
+			name:       "jpeg stored and recorded",
 			file:       jpegBytes,
-			pathPrefix: "feedback",
-			wantURLPfx: "/uploads/feedback/",
+			pathPrefix: "refunds",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().CreateUploadedObject(gomock.Any(), gomock.Any(), int64(len(jpegBytes)), "image/jpeg").Return(nil)
+			},
+			wantURLPfx: "/uploads/refunds/",
 			wantExt:    ".jpg",
+			wantStored: true,
 		},
 		{
-			name:       "png saved to local filesystem",
+			name:       "png stored and recorded",
 			file:       pngBytes,
-			pathPrefix: "feedback",
-			wantURLPfx: "/uploads/feedback/",
+			pathPrefix: "logos",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().CreateUploadedObject(gomock.Any(), gomock.Any(), int64(len(pngBytes)), "image/png").Return(nil)
+			},
+			wantURLPfx: "/uploads/logos/",
 			wantExt:    ".png",
+			wantStored: true,
 		},
 		{
-			name:       "uses pathPrefix in local URL",
+			name:       "feedback screenshot is not recorded",
 			file:       jpegBytes,
-			pathPrefix: "products",
-			wantURLPfx: "/uploads/products/",
+			pathPrefix: "feedback",
+			wantURLPfx: "/uploads/feedback/",
 			wantExt:    ".jpg",
+			wantStored: true,
 		},
 		{
 			name:       "returns error for unsupported file type",
 			file:       []byte("hello plain text"),
-			pathPrefix: "feedback",
+			pathPrefix: "refunds",
 			wantErr:    true,
 			wantErrMsg: apierr.ErrUnsupportedImageType,
 		},
 		{
-			name:         "uploads to R2 and returns R2 URL",
-			file:         jpegBytes,
-			pathPrefix:   "feedback",
-			r2BucketName: "test-bucket",
-			r2UploadErr:  nil,
-			wantURLPfx:   "https://pub-test.r2.dev/feedback/",
-			wantExt:      ".jpg",
-		},
-		{
-			name:         "returns error when R2 upload fails",
-			file:         jpegBytes,
-			pathPrefix:   "feedback",
-			r2BucketName: "test-bucket",
-			r2UploadErr:  errors.New("R2 unavailable"),
-			wantErr:      true,
-			wantErrMsg:   "R2 unavailable",
+			name:       "removes the file when it can't be recorded",
+			file:       jpegBytes,
+			pathPrefix: "refunds",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().CreateUploadedObject(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			},
+			wantErr:    true,
+			wantErrMsg: "db error",
 		},
 		{
 			name:       "returns error when file reader fails",
 			file:       nil, // triggers read error via errReader
-			pathPrefix: "feedback",
+			pathPrefix: "refunds",
 			wantErr:    true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.r2BucketName != "" {
-				cfg.R2BucketName = tt.r2BucketName
-				cfg.R2PublicURL = "https://pub-test.r2.dev"
-				defer func() {
-					cfg.R2BucketName = ""
-					cfg.R2PublicURL = ""
-				}()
-
-				uploadErr := tt.r2UploadErr
-				old := r2UploadFunc
-				r2UploadFunc = func(key string, body io.Reader, contentType string) error {
-					return uploadErr
-				}
-				defer func() { r2UploadFunc = old }()
+			backend := useMemoryStorage(t)
+			m := mock_store.NewMockUploadedObjectStore(gomock.NewController(t))
+			if tt.mockSetup != nil {
+				tt.mockSetup(m)
 			}
+			uploadedObjectStore = m
 
 			var reader io.Reader
 			if tt.file != nil {
@@ -111,8 +189,11 @@ func Test_uploadImage(t *testing.T) {
 				reader = &errReader{err: errors.New("read error")}
 			}
 
-			got, gotErr := uploadImage(reader, tt.pathPrefix)
+			got, gotErr := uploadImage(context.Background(), reader, tt.pathPrefix)
 
+			if stored := len(backend.Keys()) == 1; stored != tt.wantStored {
+				t.Errorf("uploadImage() stored %v, want %v stored", backend.Keys(), tt.wantStored)
+			}
 			if gotErr != nil {
 				if !tt.wantErr {
 					t.Errorf("uploadImage() unexpected error: %v", gotErr)
@@ -135,7 +216,309 @@ func Test_uploadImage(t *testing.T) {
 	}
 }
 
+func Test_readUploadedImage(t *testing.T) {
+	backend := useMemoryStorage(t)
+	if err := backend.Put(context.Background(), "logos/a.png", "image/png", []byte("stored")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		imageURL string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "reads stored image",
+			imageURL: "/uploads/logos/a.png",
+			want:     "stored",
+		},
+		{
+			name:     "returns error when the image is missing",
+			imageURL: "/uploads/logos/missing.png",
+			wantErr:  true,
+		},
+		{
+			name:     "returns error for foreign URL",
+			imageURL: "https://example.com/logo.png",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := readUploadedImage(context.Background(), tt.imageURL)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("readUploadedImage() unexpected error: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("readUploadedImage() succeeded unexpectedly")
+			}
+			if string(got) != tt.want {
+				t.Errorf("readUploadedImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_deleteUploadedImage(t *testing.T) {
+	tests := []struct {
+		name      string
+		imageURL  string
+		mockSetup func(m *mock_store.MockUploadedObjectStore)
+		wantLeft  string // keys still stored, comma separated
+		wantErr   bool
+	}{
+		{
+			name:     "deletes the image and its record",
+			imageURL: "/uploads/products/a_full.webp",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "products/a_full.webp").Return(nil)
+			},
+		},
+		{
+			name:     "forgets the record of an image already gone",
+			imageURL: "/uploads/products/gone.webp",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "products/gone.webp").Return(nil)
+			},
+			wantLeft: "products/a_full.webp",
+		},
+		{
+			name:     "returns error for foreign URL",
+			imageURL: "https://example.com/a.webp",
+			wantLeft: "products/a_full.webp",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := useMemoryStorage(t)
+			if err := backend.Put(context.Background(), "products/a_full.webp", "image/webp", []byte("x")); err != nil {
+				t.Fatal(err)
+			}
+			m := mock_store.NewMockUploadedObjectStore(gomock.NewController(t))
+			if tt.mockSetup != nil {
+				tt.mockSetup(m)
+			}
+			uploadedObjectStore = m
+
+			err := deleteUploadedImage(context.Background(), tt.imageURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deleteUploadedImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if left := strings.Join(backend.Keys(), ","); left != tt.wantLeft {
+				t.Errorf("stored after deleteUploadedImage() = %q, want %q", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
 // errReader is an io.Reader that always returns an error.
 type errReader struct{ err error }
 
 func (e *errReader) Read(p []byte) (int, error) { return 0, e.err }
+
+func Test_uploadProcessedImage(t *testing.T) {
+	variants := []imageVariant{{suffix: "thumb", maxSize: 200}, {suffix: "full", maxSize: 1280}}
+
+	tests := []struct {
+		name       string
+		file       []byte
+		wantSizes  [][2]int // width and height of each variant
+		wantErrMsg string
+	}{
+		{
+			name:      "png is scaled down to each variant",
+			file:      encodeTestPNG(t, 1600, 800),
+			wantSizes: [][2]int{{200, 100}, {1280, 640}},
+		},
+		{
+			name:      "small image is not scaled up",
+			file:      encodeTestPNG(t, 120, 90),
+			wantSizes: [][2]int{{120, 90}, {120, 90}},
+		},
+		{
+			name:      "jpeg turned upright by its EXIF orientation",
+			file:      encodeTestJPEG(t, 400, 100, 6),
+			wantSizes: [][2]int{{50, 200}, {100, 400}},
+		},
+		{
+			name:       "returns error for unsupported file type",
+			file:       []byte("hello plain text"),
+			wantErrMsg: apierr.ErrUnsupportedImageType,
+		},
+		{
+			name:       "returns error for corrupt image",
+			file:       append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 64)...),
+			wantErrMsg: apierr.ErrUnsupportedImageType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryStorage(t)
+
+			urls, err := uploadProcessedImage(context.Background(), bytes.NewReader(tt.file), "products", variants)
+			if tt.wantErrMsg != "" {
+				if err == nil || err.Error() != tt.wantErrMsg {
+					t.Fatalf("uploadProcessedImage() error = %v, want %v", err, tt.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("uploadProcessedImage() error = %v", err)
+			}
+			if len(urls) != len(variants) {
+				t.Fatalf("uploadProcessedImage() returned %d urls, want %d", len(urls), len(variants))
+			}
+
+			for i, url := range urls {
+				wantSuffix := "_" + variants[i].suffix + ".webp"
+				if !strings.HasPrefix(url, "/uploads/products/") || !strings.HasSuffix(url, wantSuffix) {
+					t.Errorf("url = %q, want /uploads/products/*%s", url, wantSuffix)
+				}
+
+				data, err := readUploadedImage(context.Background(), url)
+				if err != nil {
+					t.Fatalf("readUploadedImage(%q) error = %v", url, err)
+				}
+				if bytes.Contains(data, []byte("Exif")) {
+					t.Errorf("%s still has an EXIF block", url)
+				}
+				img, err := webp.Decode(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("decode %s: %v", url, err)
+				}
+				if got := [2]int{img.Bounds().Dx(), img.Bounds().Dy()}; got != tt.wantSizes[i] {
+					t.Errorf("%s size = %v, want %v", url, got, tt.wantSizes[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_uploadProcessedImage_cleanup(t *testing.T) {
+	backend := useMemoryStorage(t)
+
+	var recorded, deleted []string
+	m := mock_store.NewMockUploadedObjectStore(gomock.NewController(t))
+	m.EXPECT().CreateUploadedObject(gomock.Any(), gomock.Any(), gomock.Any(), "image/webp").
+		DoAndReturn(func(ctx context.Context, key string, size int64, contentType string) error {
+			if len(recorded) == 1 {
+				return errors.New("db error")
+			}
+			recorded = append(recorded, key)
+			return nil
+		}).Times(2)
+	m.EXPECT().DeleteUploadedObject(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, key string) error {
+			deleted = append(deleted, key)
+			return nil
+		})
+	uploadedObjectStore = m
+
+	_, err := uploadProcessedImage(context.Background(), bytes.NewReader(encodeTestPNG(t, 300, 300)), "products", productImageVariants)
+	if err == nil || err.Error() != "db error" {
+		t.Fatalf("uploadProcessedImage() error = %v, want db error", err)
+	}
+	if len(recorded) != 1 || !strings.HasPrefix(recorded[0], "products/") || !strings.HasSuffix(recorded[0], "_thumb.webp") {
+		t.Fatalf("recorded keys = %v, want one products/*_thumb.webp", recorded)
+	}
+	if len(deleted) != 1 || deleted[0] != recorded[0] {
+		t.Errorf("deleted records = %v, want the stored thumbnail %v", deleted, recorded)
+	}
+	if keys := backend.Keys(); len(keys) != 0 {
+		t.Errorf("stored files = %v, want none left behind", keys)
+	}
+}
+
+func Test_uploadProcessedImage_size(t *testing.T) {
+	backend := useMemoryStorage(t)
+
+	// A noisy full-size photo, saved at the quality phone cameras use.
+	rng := rand.New(rand.NewSource(1))
+	photo := image.NewRGBA(image.Rect(0, 0, constant.ProductImageFullSize, 960))
+	for y := 0; y < 960; y++ {
+		for x := 0; x < constant.ProductImageFullSize; x++ {
+			n := uint8(rng.Intn(32))
+			photo.SetRGBA(x, y, color.RGBA{R: uint8(x/5) + n, G: uint8(y/4) + n, B: 96 + n, A: 255})
+		}
+	}
+	var input bytes.Buffer
+	if err := jpeg.Encode(&input, photo, &jpeg.Options{Quality: 92}); err != nil {
+		t.Fatal(err)
+	}
+
+	urls, err := uploadProcessedImage(context.Background(), bytes.NewReader(input.Bytes()), "products", productImageVariants)
+	if err != nil {
+		t.Fatalf("uploadProcessedImage() error = %v", err)
+	}
+	for _, url := range urls {
+		key, _ := backend.Key(url)
+		data, err := backend.Get(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Get(%q) error = %v", key, err)
+		}
+		if len(data) > input.Len() {
+			t.Errorf("%s is %d bytes, want no more than the %d byte input", url, len(data), input.Len())
+		}
+	}
+}
+
+func Test_jpegOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "no EXIF block", data: encodeTestJPEG(t, 8, 8, 0), want: 1},
+		{name: "rotated 90 degrees", data: encodeTestJPEG(t, 8, 8, 6), want: 6},
+		{name: "mirrored", data: encodeTestJPEG(t, 8, 8, 2), want: 2},
+		{name: "out of range orientation", data: encodeTestJPEG(t, 8, 8, 12), want: 1},
+		{name: "not a jpeg", data: []byte("hello"), want: 1},
+		{name: "truncated segment", data: []byte{0xff, 0xd8, 0xff, 0xe1, 0x10, 0x00}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_orientImage(t *testing.T) {
+	// A 3x2 image with a marked top-left pixel.
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	tests := []struct {
+		orientation int
+		wantSize    image.Point
+		wantMarked  image.Point
+	}{
+		{orientation: 1, wantSize: image.Pt(3, 2), wantMarked: image.Pt(0, 0)},
+		{orientation: 2, wantSize: image.Pt(3, 2), wantMarked: image.Pt(2, 0)},
+		{orientation: 3, wantSize: image.Pt(3, 2), wantMarked: image.Pt(2, 1)},
+		{orientation: 4, wantSize: image.Pt(3, 2), wantMarked: image.Pt(0, 1)},
+		{orientation: 5, wantSize: image.Pt(2, 3), wantMarked: image.Pt(0, 0)},
+		{orientation: 6, wantSize: image.Pt(2, 3), wantMarked: image.Pt(1, 0)},
+		{orientation: 7, wantSize: image.Pt(2, 3), wantMarked: image.Pt(1, 2)},
+		{orientation: 8, wantSize: image.Pt(2, 3), wantMarked: image.Pt(0, 2)},
+	}
+	for _, tt := range tests {
+		got := orientImage(src, tt.orientation)
+		if size := got.Bounds().Size(); size != tt.wantSize {
+			t.Errorf("orientation %d: size = %v, want %v", tt.orientation, size, tt.wantSize)
+			continue
+		}
+		if r, _, _, _ := got.At(tt.wantMarked.X, tt.wantMarked.Y).RGBA(); r>>8 != 255 {
+			t.Errorf("orientation %d: marked pixel not at %v", tt.orientation, tt.wantMarked)
+		}
+	}
+}
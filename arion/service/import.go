@@ -0,0 +1,504 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/xlsx"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+type (
+	ImportService interface {
+		ImportCustomers(ctx context.Context, input ImportInput) (response.ImportResultData, error)
+		ImportProducts(ctx context.Context, input ImportInput) (response.ImportResultData, error)
+	}
+
+	imservice struct{}
+
+	// ImportInput is an uploaded spreadsheet. Mapping maps a field (name,
+	// phone, price, ...) to the header of the column holding it; fields left
+	// out are found by their usual header names.
+	ImportInput struct {
+		ShopID  int
+		Format  string
+		File    io.Reader
+		Mapping map[string]string
+		DryRun  bool
+	}
+
+	// importField is a column an import reads. Headers are the normalized
+	// header names it is recognised by when the mapping leaves it out.
+	importField struct {
+		name     string
+		headers  []string
+		required bool
+	}
+
+	// importRow is a data row keyed by field name. Line is its spreadsheet
+	// row number.
+	importRow struct {
+		line   int
+		values map[string]string
+	}
+
+	customerImport struct {
+		name    string
+		phone   string
+		address *string
+	}
+
+	productImport struct {
+		name          string
+		price         int
+		originalPrice *int
+		description   *string
+	}
+)
+
+var (
+	customerImportFields = []importField{
+		{name: "name", headers: []string{"name", "customer name", "nama", "nama pelanggan"}, required: true},
+		{name: "phone", headers: []string{"phone", "phone number", "no hp", "nomor hp", "hp", "telepon", "whatsapp", "wa"}, required: true},
+		{name: "address", headers: []string{"address", "alamat"}},
+	}
+
+	productImportFields = []importField{
+		{name: "name", headers: []string{"name", "product name", "nama", "nama produk", "produk"}, required: true},
+		{name: "price", headers: []string{"price", "harga", "harga jual"}, required: true},
+		{name: "original_price", headers: []string{"original price", "harga modal", "harga asli", "modal"}},
+		{name: "description", headers: []string{"description", "deskripsi", "keterangan"}},
+	}
+
+	// importThousandsPattern matches amounts written with thousands
+	// separators only, such as 85.000 or 1,250,000.
+	importThousandsPattern = regexp.MustCompile(`^\d{1,3}([.,]\d{3})+$`)
+)
+
+func NewImportService() ImportService {
+	cfg = config.GetConfig()
+
+	if customerStore == nil {
+		customerStore = store.NewCustomerStore()
+	}
+
+	if productStore == nil {
+		productStore = store.NewProductStore()
+	}
+
+	return &imservice{}
+}
+
+// ImportCustomers upserts customers by their normalized phone: new phones are
+// inserted and known ones get the name, and the address when the cell is not
+// empty, from the file. Nothing is written on a dry run or when any row is
+// invalid.
+func (i *imservice) ImportCustomers(ctx context.Context, input ImportInput) (response.ImportResultData, error) {
+	rows, err := readImportRows(input, customerImportFields)
+	if err != nil {
+		return response.ImportResultData{}, err
+	}
+
+	res := response.ImportResultData{
+		DryRun:    input.DryRun,
+		TotalRows: len(rows),
+		Errors:    []response.ImportRowErrorData{},
+	}
+
+	customers := make([]customerImport, 0, len(rows))
+	seen := map[string]bool{}
+	for _, row := range rows {
+		valid := true
+		name := row.values["name"]
+		if name == "" {
+			res.Errors = append(res.Errors, importRowError(row, "name", apierr.ErrNameRequired))
+			valid = false
+		}
+
+		phone := row.values["phone"]
+		if phone == "" {
+			res.Errors = append(res.Errors, importRowError(row, "phone", apierr.ErrPhoneRequired))
+			valid = false
+		} else if phone, err = normalizePhone(phone); err != nil {
+			res.Errors = append(res.Errors, importRowError(row, "phone", err.Error()))
+			valid = false
+		} else if seen[phone] {
+			res.Errors = append(res.Errors, importRowError(row, "phone", apierr.ErrImportDuplicateRow))
+			valid = false
+		}
+
+		if !valid {
+			continue
+		}
+		seen[phone] = true
+
+		c := customerImport{name: name, phone: phone}
+		if address := row.values["address"]; address != "" {
+			c.address = &address
+		}
+		customers = append(customers, c)
+	}
+
+	phones := make([]string, 0, len(customers))
+	for _, c := range customers {
+		phones = append(phones, c.phone)
+	}
+	existing, err := customerStore.GetCustomersByPhones(ctx, input.ShopID, phones)
+	if err != nil {
+		return response.ImportResultData{}, err
+	}
+	ids := make(map[string]int, len(existing))
+	for _, c := range existing {
+		ids[c.Phone] = c.ID
+	}
+
+	creates := []store.CreateCustomerInput{}
+	updates := []store.BulkUpdateCustomerInput{}
+	for _, c := range customers {
+		if id, ok := ids[c.phone]; ok {
+			updates = append(updates, store.BulkUpdateCustomerInput{ID: id, Name: c.name, Address: c.address})
+			continue
+		}
+		creates = append(creates, store.CreateCustomerInput{ShopID: input.ShopID, Name: c.name, Phone: c.phone, Address: c.address})
+	}
+
+	if len(res.Errors) > 0 && !input.DryRun {
+		return res, nil
+	}
+	res.Created, res.Updated = len(creates), len(updates)
+	if input.DryRun {
+		return res, nil
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.ImportResultData{}, err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(creates); start += constant.ImportBatchSize {
+		end := min(start+constant.ImportBatchSize, len(creates))
+		if err := customerStore.BulkCreateCustomers(ctx, tx, creates[start:end]); err != nil {
+			return response.ImportResultData{}, err
+		}
+	}
+	for start := 0; start < len(updates); start += constant.ImportBatchSize {
+		end := min(start+constant.ImportBatchSize, len(updates))
+		if err := customerStore.BulkUpdateCustomers(ctx, tx, updates[start:end]); err != nil {
+			return response.ImportResultData{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.ImportResultData{}, err
+	}
+
+	return res, nil
+}
+
+// ImportProducts upserts products by name: new names are inserted and known
+// ones get the price, and the original price and description when the cell
+// is not empty, from the file. Nothing is written on a dry run or when any
+// row is invalid.
+func (i *imservice) ImportProducts(ctx context.Context, input ImportInput) (response.ImportResultData, error) {
+	rows, err := readImportRows(input, productImportFields)
+	if err != nil {
+		return response.ImportResultData{}, err
+	}
+
+	res := response.ImportResultData{
+		DryRun:    input.DryRun,
+		TotalRows: len(rows),
+		Errors:    []response.ImportRowErrorData{},
+	}
+
+	products := make([]productImport, 0, len(rows))
+	seen := map[string]bool{}
+	for _, row := range rows {
+		valid := true
+		name := row.values["name"]
+		if name == "" {
+			res.Errors = append(res.Errors, importRowError(row, "name", apierr.ErrNameRequired))
+			valid = false
+		} else if seen[name] {
+			res.Errors = append(res.Errors, importRowError(row, "name", apierr.ErrImportDuplicateRow))
+			valid = false
+		}
+
+		price, ok := parseImportAmount(row.values["price"])
+		if !ok {
+			res.Errors = append(res.Errors, importRowError(row, "price", apierr.ErrPriceInvalid))
+			valid = false
+		}
+
+		p := productImport{name: name, price: price}
+		if raw := row.values["original_price"]; raw != "" {
+			originalPrice, ok := parseImportAmount(raw)
+			if !ok {
+				res.Errors = append(res.Errors, importRowError(row, "original_price", apierr.ErrPriceInvalid))
+				valid = false
+			}
+			p.originalPrice = &originalPrice
+		}
+		if description := row.values["description"]; description != "" {
+			p.description = &description
+		}
+
+		if !valid {
+			continue
+		}
+		seen[name] = true
+		products = append(products, p)
+	}
+
+	names := make([]string, 0, len(products))
+	for _, p := range products {
+		names = append(names, p.name)
+	}
+	existing, err := productStore.GetProductsByNames(ctx, input.ShopID, names)
+	if err != nil {
+		return response.ImportResultData{}, err
+	}
+	ids := make(map[string]int, len(existing))
+	for _, p := range existing {
+		ids[p.Name] = p.ID
+	}
+
+	creates := []store.BulkCreateProductInput{}
+	updates := []store.BulkUpdateProductInput{}
+	for _, p := range products {
+		if id, ok := ids[p.name]; ok {
+			updates = append(updates, store.BulkUpdateProductInput{ID: id, Price: p.price, OriginalPrice: p.originalPrice, Description: p.description})
+			continue
+		}
+
+		create := store.BulkCreateProductInput{ShopID: input.ShopID, Name: p.name, Price: p.price, OriginalPrice: p.price}
+		if p.originalPrice != nil {
+			create.OriginalPrice = *p.originalPrice
+		}
+		if p.description != nil {
+			create.Description = *p.description
+		}
+		creates = append(creates, create)
+	}
+
+	if len(res.Errors) > 0 && !input.DryRun {
+		return res, nil
+	}
+	res.Created, res.Updated = len(creates), len(updates)
+	if input.DryRun {
+		return res, nil
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.ImportResultData{}, err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(creates); start += constant.ImportBatchSize {
+		end := min(start+constant.ImportBatchSize, len(creates))
+		if err := productStore.BulkCreateProducts(ctx, tx, creates[start:end]); err != nil {
+			return response.ImportResultData{}, err
+		}
+	}
+	for start := 0; start < len(updates); start += constant.ImportBatchSize {
+		end := min(start+constant.ImportBatchSize, len(updates))
+		if err := productStore.BulkUpdateProducts(ctx, tx, updates[start:end]); err != nil {
+			return response.ImportResultData{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.ImportResultData{}, err
+	}
+
+	return res, nil
+}
+
+// readImportRows reads the file, finds the column of every field from the
+// header row (the first non-empty row) and returns the non-empty data rows
+// with trimmed values.
+func readImportRows(input ImportInput, fields []importField) ([]importRow, error) {
+	data, err := io.ReadAll(input.File)
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+	switch input.Format {
+	case constant.ImportFormatCSV:
+		records, err = readImportCSV(data)
+	case constant.ImportFormatXLSX:
+		records, err = xlsx.ReadAll(bytes.NewReader(data), int64(len(data)))
+	default:
+		return nil, errors.New(apierr.ErrImportFormatInvalid)
+	}
+	if err != nil {
+		return nil, errors.New(apierr.ErrImportFileInvalid)
+	}
+
+	header := -1
+	for i, rec := range records {
+		if !isBlankRecord(rec) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, errors.New(apierr.ErrImportFileEmpty)
+	}
+
+	columns, err := importColumns(records[header], fields, input.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []importRow{}
+	for i, rec := range records[header+1:] {
+		if isBlankRecord(rec) {
+			continue
+		}
+		if len(rows) == constant.ImportMaxRows {
+			return nil, errors.New(apierr.ErrImportTooManyRows)
+		}
+
+		row := importRow{line: header + i + 2, values: map[string]string{}}
+		for field, col := range columns {
+			if col < len(rec) {
+				row.values[field] = strings.TrimSpace(rec[col])
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New(apierr.ErrImportFileEmpty)
+	}
+
+	return rows, nil
+}
+
+// readImportCSV parses a CSV file separated by commas, semicolons (the
+// default of Excel in Indonesian locales) or tabs, whichever the first line
+// uses most.
+func readImportCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	comma := ','
+	for _, c := range []rune{';', '\t'} {
+		if bytes.Count(firstLine, []byte(string(c))) > bytes.Count(firstLine, []byte(string(comma))) {
+			comma = c
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// importColumns returns the column index of every field found in header.
+// Mapped fields must be found under the mapped header; required fields must
+// be found one way or the other.
+func importColumns(header []string, fields []importField, mapping map[string]string) (map[string]int, error) {
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f.name] = true
+	}
+	for field := range mapping {
+		if !known[field] {
+			return nil, errors.New(apierr.ErrImportMappingInvalid)
+		}
+	}
+
+	index := map[string]int{}
+	for i, h := range header {
+		h = normalizeImportHeader(h)
+		if _, ok := index[h]; !ok && h != "" {
+			index[h] = i
+		}
+	}
+
+	columns := map[string]int{}
+	for _, f := range fields {
+		if mapped, ok := mapping[f.name]; ok {
+			col, ok := index[normalizeImportHeader(mapped)]
+			if !ok {
+				return nil, errors.New(apierr.ErrImportColumnMissing)
+			}
+			columns[f.name] = col
+			continue
+		}
+
+		for _, h := range append([]string{f.name}, f.headers...) {
+			if col, ok := index[normalizeImportHeader(h)]; ok {
+				columns[f.name] = col
+				break
+			}
+		}
+		if _, ok := columns[f.name]; !ok && f.required {
+			return nil, errors.New(apierr.ErrImportColumnMissing)
+		}
+	}
+
+	return columns, nil
+}
+
+// normalizeImportHeader lowercases a header and treats underscores like
+// spaces, so "No HP", "no_hp" and " no  hp " are the same column.
+func normalizeImportHeader(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, "_", " "))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func isBlankRecord(rec []string) bool {
+	for _, c := range rec {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportAmount reads a whole rupiah amount. "85000", "85.000",
+// "Rp 1,250,000" and "85000.0" are all accepted; fractions are rounded.
+func parseImportAmount(s string) (int, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "RP"), ".")
+	s = strings.ReplaceAll(s, " ", "")
+	if s == "" {
+		return 0, false
+	}
+
+	if importThousandsPattern.MatchString(s) {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	}
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false
+	}
+
+	return int(math.Round(f)), true
+}
+
+func importRowError(row importRow, field, code string) response.ImportRowErrorData {
+	return response.ImportRowErrorData{Row: row.line, Field: field, Code: code}
+}
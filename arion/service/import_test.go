@@ -0,0 +1,429 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/xlsx"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+func Test_imservice_ImportCustomers(t *testing.T) {
+	address := "Jl. Merdeka 1"
+
+	tests := []struct {
+		name       string
+		input      ImportInput
+		file       string
+		mockSetup  func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_database.MockDB)
+		want       response.ImportResultData
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:  "creates new phones and updates known ones",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV},
+			file:  "Nama;No HP;Alamat\nBudi;0812-3456-789;Jl. Merdeka 1\n;;\nSiti;+62 813 1111 2222;\n",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomersByPhones(gomock.Any(), 10, []string{"+628123456789", "+6281311112222"}).
+					Return([]model.Customer{{ID: 7, Phone: "+6281311112222"}}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockCustomer.EXPECT().BulkCreateCustomers(gomock.Any(), mockTx, []store.CreateCustomerInput{
+					{ShopID: 10, Name: "Budi", Phone: "+628123456789", Address: &address},
+				}).Return(nil)
+				mockCustomer.EXPECT().BulkUpdateCustomers(gomock.Any(), mockTx, []store.BulkUpdateCustomerInput{
+					{ID: 7, Name: "Siti"},
+				}).Return(nil)
+				return mockCustomer, mockDB
+			},
+			want: response.ImportResultData{TotalRows: 2, Created: 1, Updated: 1, Errors: []response.ImportRowErrorData{}},
+		},
+		{
+			name:  "dry run reports counts without writing",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV, DryRun: true},
+			file:  "name,phone\nBudi,08123456789\n",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomersByPhones(gomock.Any(), 10, []string{"+628123456789"}).Return(nil, nil)
+				return mockCustomer, mock_database.NewMockDB(ctrl)
+			},
+			want: response.ImportResultData{DryRun: true, TotalRows: 1, Created: 1, Errors: []response.ImportRowErrorData{}},
+		},
+		{
+			name:  "invalid rows are reported and nothing is written",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV},
+			file:  "name,phone\nBudi,08123456789\n,abc\nSiti,+628123456789\n",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomersByPhones(gomock.Any(), 10, []string{"+628123456789"}).Return(nil, nil)
+				return mockCustomer, mock_database.NewMockDB(ctrl)
+			},
+			want: response.ImportResultData{TotalRows: 3, Errors: []response.ImportRowErrorData{
+				{Row: 3, Field: "name", Code: apierr.ErrNameRequired},
+				{Row: 3, Field: "phone", Code: apierr.ErrCustomerPhoneInvalid},
+				{Row: 4, Field: "phone", Code: apierr.ErrImportDuplicateRow},
+			}},
+		},
+		{
+			name:  "mapping picks the column",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV, DryRun: true, Mapping: map[string]string{"phone": "Nomor WA"}},
+			file:  "Nama,Telepon Rumah,Nomor WA\nBudi,0211234567,08123456789\n",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomersByPhones(gomock.Any(), 10, []string{"+628123456789"}).Return(nil, nil)
+				return mockCustomer, mock_database.NewMockDB(ctrl)
+			},
+			want: response.ImportResultData{DryRun: true, TotalRows: 1, Created: 1, Errors: []response.ImportRowErrorData{}},
+		},
+		{
+			name:  "missing phone column",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV},
+			file:  "name,address\nBudi,Jl. Merdeka 1\n",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_database.MockDB) {
+				return mock_store.NewMockCustomerStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrImportColumnMissing,
+		},
+		{
+			name:  "store failure rolls back",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV},
+			file:  "name,phone\nBudi,08123456789\n",
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockCustomerStore, *mock_database.MockDB) {
+				mockCustomer := mock_store.NewMockCustomerStore(ctrl)
+				mockCustomer.EXPECT().GetCustomersByPhones(gomock.Any(), 10, gomock.Any()).Return(nil, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockCustomer.EXPECT().BulkCreateCustomers(gomock.Any(), mockTx, gomock.Any()).Return(store.ErrDuplicatePhone)
+				return mockCustomer, mockDB
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrCustomerPhoneExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldCustomerStore, oldDBGetter := customerStore, dbGetter
+			defer func() {
+				customerStore, dbGetter = oldCustomerStore, oldDBGetter
+			}()
+
+			mockCustomer, mockDB := tt.mockSetup(ctrl)
+			customerStore = mockCustomer
+			dbGetter = func() database.DB { return mockDB }
+
+			tt.input.File = strings.NewReader(tt.file)
+
+			var s imservice
+			got, gotErr := s.ImportCustomers(context.Background(), tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("ImportCustomers() error = %v, wantErr %v", gotErr, tt.wantErr)
+				} else if gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("ImportCustomers() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("ImportCustomers() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ImportCustomers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_imservice_ImportProducts(t *testing.T) {
+	originalPrice := 60000
+	description := "Katun combed"
+
+	var sheet bytes.Buffer
+	w, _ := xlsx.NewWriter(&sheet, "Produk")
+	w.Write([]interface{}{"Nama Produk", "Harga", "Harga Modal", "Deskripsi"})
+	w.Write([]interface{}{"Kaos Polos", 85000, 60000, "Katun combed"})
+	w.Write([]interface{}{"Topi", "Rp 45.000", nil, nil})
+	w.Close()
+
+	tests := []struct {
+		name       string
+		input      ImportInput
+		file       []byte
+		mockSetup  func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB)
+		want       response.ImportResultData
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:  "creates new names and updates known ones from xlsx",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatXLSX},
+			file:  sheet.Bytes(),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				mockProduct := mock_store.NewMockProductStore(ctrl)
+				mockProduct.EXPECT().GetProductsByNames(gomock.Any(), 10, []string{"Kaos Polos", "Topi"}).
+					Return([]model.Product{{ID: 4, Name: "Topi"}}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockProduct.EXPECT().BulkCreateProducts(gomock.Any(), mockTx, []store.BulkCreateProductInput{
+					{ShopID: 10, Name: "Kaos Polos", Description: "Katun combed", Price: 85000, OriginalPrice: 60000},
+				}).Return(nil)
+				mockProduct.EXPECT().BulkUpdateProducts(gomock.Any(), mockTx, []store.BulkUpdateProductInput{
+					{ID: 4, Price: 45000},
+				}).Return(nil)
+				return mockProduct, mockDB
+			},
+			want: response.ImportResultData{TotalRows: 2, Created: 1, Updated: 1, Errors: []response.ImportRowErrorData{}},
+		},
+		{
+			name:  "original price defaults to the price on create",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV},
+			file:  []byte("name,price,original_price,description\nKaos Polos,85000,60000,Katun combed\nTopi,\"45,000\",,\n"),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				mockProduct := mock_store.NewMockProductStore(ctrl)
+				mockProduct.EXPECT().GetProductsByNames(gomock.Any(), 10, []string{"Kaos Polos", "Topi"}).
+					Return([]model.Product{{ID: 3, Name: "Kaos Polos"}}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockProduct.EXPECT().BulkCreateProducts(gomock.Any(), mockTx, []store.BulkCreateProductInput{
+					{ShopID: 10, Name: "Topi", Price: 45000, OriginalPrice: 45000},
+				}).Return(nil)
+				mockProduct.EXPECT().BulkUpdateProducts(gomock.Any(), mockTx, []store.BulkUpdateProductInput{
+					{ID: 3, Price: 85000, OriginalPrice: &originalPrice, Description: &description},
+				}).Return(nil)
+				return mockProduct, mockDB
+			},
+			want: response.ImportResultData{TotalRows: 2, Created: 1, Updated: 1, Errors: []response.ImportRowErrorData{}},
+		},
+		{
+			name:  "invalid rows are reported on a dry run",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV, DryRun: true},
+			file:  []byte("name,price,original_price\nKaos,abc,\nTopi,-5,\n,1000,murah\nKaos,2000,\nSandal,30000,\n"),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				mockProduct := mock_store.NewMockProductStore(ctrl)
+				mockProduct.EXPECT().GetProductsByNames(gomock.Any(), 10, []string{"Kaos", "Sandal"}).Return(nil, nil)
+				return mockProduct, mock_database.NewMockDB(ctrl)
+			},
+			want: response.ImportResultData{DryRun: true, TotalRows: 5, Created: 2, Errors: []response.ImportRowErrorData{
+				{Row: 2, Field: "price", Code: apierr.ErrPriceInvalid},
+				{Row: 3, Field: "price", Code: apierr.ErrPriceInvalid},
+				{Row: 4, Field: "name", Code: apierr.ErrNameRequired},
+				{Row: 4, Field: "original_price", Code: apierr.ErrPriceInvalid},
+			}},
+		},
+		{
+			name:  "unknown mapping field",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV, Mapping: map[string]string{"stock": "Stok"}},
+			file:  []byte("name,price\nKaos,1000\n"),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				return mock_store.NewMockProductStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrImportMappingInvalid,
+		},
+		{
+			name:  "unsupported format",
+			input: ImportInput{ShopID: 10, Format: "ods"},
+			file:  []byte("name,price\nKaos,1000\n"),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				return mock_store.NewMockProductStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrImportFormatInvalid,
+		},
+		{
+			name:  "unreadable xlsx",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatXLSX},
+			file:  []byte("name,price\nKaos,1000\n"),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				return mock_store.NewMockProductStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrImportFileInvalid,
+		},
+		{
+			name:  "header only",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV},
+			file:  []byte("\n\nname,price\n,\n"),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				return mock_store.NewMockProductStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrImportFileEmpty,
+		},
+		{
+			name:  "store lookup failure",
+			input: ImportInput{ShopID: 10, Format: constant.ImportFormatCSV},
+			file:  []byte("name,price\nKaos,1000\n"),
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockProductStore, *mock_database.MockDB) {
+				mockProduct := mock_store.NewMockProductStore(ctrl)
+				mockProduct.EXPECT().GetProductsByNames(gomock.Any(), 10, gomock.Any()).Return(nil, errors.New("database error"))
+				return mockProduct, mock_database.NewMockDB(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductStore, oldDBGetter := productStore, dbGetter
+			defer func() {
+				productStore, dbGetter = oldProductStore, oldDBGetter
+			}()
+
+			mockProduct, mockDB := tt.mockSetup(ctrl)
+			productStore = mockProduct
+			dbGetter = func() database.DB { return mockDB }
+
+			tt.input.File = bytes.NewReader(tt.file)
+
+			var s imservice
+			got, gotErr := s.ImportProducts(context.Background(), tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("ImportProducts() error = %v, wantErr %v", gotErr, tt.wantErr)
+				} else if gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("ImportProducts() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("ImportProducts() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ImportProducts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_imservice_ImportProducts_batches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldProductStore, oldDBGetter := productStore, dbGetter
+	defer func() {
+		productStore, dbGetter = oldProductStore, oldDBGetter
+	}()
+
+	var file strings.Builder
+	file.WriteString("name,price\n")
+	rows := constant.ImportBatchSize*2 + 1
+	for i := 0; i < rows; i++ {
+		file.WriteString("Produk " + strconv.Itoa(i) + ",1000\n")
+	}
+
+	mockTx := mock_database.NewMockTx(ctrl)
+	mockTx.EXPECT().Commit().Return(nil)
+	mockTx.EXPECT().Rollback().Return(nil)
+	mockDB := mock_database.NewMockDB(ctrl)
+	mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+	mockProduct := mock_store.NewMockProductStore(ctrl)
+	mockProduct.EXPECT().GetProductsByNames(gomock.Any(), 10, gomock.Any()).Return(nil, nil)
+	var sizes []int
+	mockProduct.EXPECT().BulkCreateProducts(gomock.Any(), mockTx, gomock.Any()).Times(3).
+		DoAndReturn(func(_ context.Context, _ database.Tx, inputs []store.BulkCreateProductInput) error {
+			sizes = append(sizes, len(inputs))
+			return nil
+		})
+
+	productStore = mockProduct
+	dbGetter = func() database.DB { return mockDB }
+
+	var s imservice
+	got, err := s.ImportProducts(context.Background(), ImportInput{ShopID: 10, Format: constant.ImportFormatCSV, File: strings.NewReader(file.String())})
+	if err != nil {
+		t.Fatalf("ImportProducts() error = %v", err)
+	}
+	if got.Created != rows {
+		t.Errorf("ImportProducts() created = %d, want %d", got.Created, rows)
+	}
+	if want := []int{constant.ImportBatchSize, constant.ImportBatchSize, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("batch sizes = %v, want %v", sizes, want)
+	}
+}
+
+func Test_readImportRows_tooManyRows(t *testing.T) {
+	var file strings.Builder
+	file.WriteString("name,price\n")
+	for i := 0; i <= constant.ImportMaxRows; i++ {
+		file.WriteString("Kaos,1000\n")
+	}
+
+	_, err := readImportRows(ImportInput{Format: constant.ImportFormatCSV, File: strings.NewReader(file.String())}, productImportFields)
+	if err == nil || err.Error() != apierr.ErrImportTooManyRows {
+		t.Errorf("readImportRows() error = %v, want %v", err, apierr.ErrImportTooManyRows)
+	}
+}
+
+func Test_parseImportAmount(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   int
+		wantOK bool
+	}{
+		{in: "85000", want: 85000, wantOK: true},
+		{in: "85.000", want: 85000, wantOK: true},
+		{in: "1,250,000", want: 1250000, wantOK: true},
+		{in: "Rp 1.250.000", want: 1250000, wantOK: true},
+		{in: "Rp. 45.000", want: 45000, wantOK: true},
+		{in: "85000.0", want: 85000, wantOK: true},
+		{in: "12.5", want: 13, wantOK: true},
+		{in: "0", want: 0, wantOK: true},
+		{in: "", wantOK: false},
+		{in: "-5", wantOK: false},
+		{in: "abc", wantOK: false},
+		{in: "NaN", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := parseImportAmount(tt.in)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseImportAmount(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -170,6 +170,10 @@ func (s *iservice) AcceptInvite(ctx context.Context, token, name, password strin
 		return response.TokenResponse{}, errors.New(apierr.ErrInvitationAlreadyAccepted)
 	}
 
+	if err := checkShopOpen(ctx, inv.ShopID); err != nil {
+		return response.TokenResponse{}, err
+	}
+
 	// Check plan user limit before creating the user
 	sub, err := subscriptionStore.GetSubscriptionByShopID(ctx, inv.ShopID)
 	if err != nil {
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"reflect"
 	"testing"
@@ -518,6 +519,7 @@ func Test_iservice_AcceptInvite(t *testing.T) {
 		username  string
 		password  string
 		mockSetup func(ctrl *gomock.Controller)
+		shop      *model.Shop
 		wantErr   bool
 	}{
 		{
@@ -562,6 +564,21 @@ func Test_iservice_AcceptInvite(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:     "closed shop returns error",
+			token:    "validtoken",
+			username: "New Admin",
+			password: "pass1234",
+			mockSetup: func(ctrl *gomock.Controller) {
+				mockInvitation := mock_store.NewMockInvitationStore(ctrl)
+				mockInvitation.EXPECT().
+					GetInvitationByToken(gomock.Any(), "validtoken").
+					Return(&model.Invitation{ID: 1, ShopID: 5, Email: "invite@example.com", Status: "pending", CreatedAt: fixedTime}, nil)
+				invitationStore = mockInvitation
+			},
+			shop:    &model.Shop{ID: 5, ClosedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+			wantErr: true,
+		},
 		{
 			name:     "GetSubscriptionByShopID returns error on accept",
 			token:    "validtoken",
@@ -857,16 +874,21 @@ func Test_iservice_AcceptInvite(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldInvitation, oldUser, oldToken, oldSub := invitationStore, userStore, tokenStore, subscriptionStore
+			oldInvitation, oldUser, oldToken, oldSub, oldShop := invitationStore, userStore, tokenStore, subscriptionStore, shopStore
 			oldDBGetter := dbGetter
 			defer func() {
 				invitationStore = oldInvitation
 				userStore = oldUser
 				tokenStore = oldToken
 				subscriptionStore = oldSub
+				shopStore = oldShop
 				dbGetter = oldDBGetter
 			}()
 
+			mockShop := mock_store.NewMockShopStore(ctrl)
+			mockShop.EXPECT().GetShopByID(gomock.Any(), gomock.Any()).Return(tt.shop, nil).AnyTimes()
+			shopStore = mockShop
+
 			tt.mockSetup(ctrl)
 
 			var s iservice
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zeirash/recapo/arion/common/constant"
+)
+
+// invoiceNumberToken matches one placeholder of an invoice number pattern,
+// e.g. {YYYY} or {seq:4}.
+var invoiceNumberToken = regexp.MustCompile(`\{([A-Za-z]+)(?::(\d+))?\}`)
+
+// validInvoiceNumberPattern reports whether pattern only uses known
+// placeholders and contains the sequence, which keeps every number unique
+// within the shop.
+func validInvoiceNumberPattern(pattern string) bool {
+	if pattern == "" || utf8.RuneCountInString(pattern) > constant.InvoiceNumberPatternMaxLen {
+		return false
+	}
+
+	hasSeq := false
+	for _, m := range invoiceNumberToken.FindAllStringSubmatch(pattern, -1) {
+		switch m[1] {
+		case "YYYY", "YY", "MM", "DD":
+			if m[2] != "" {
+				return false
+			}
+		case "seq":
+			if m[2] != "" {
+				width, _ := strconv.Atoi(m[2])
+				if width < 1 || width > 9 {
+					return false
+				}
+			}
+			hasSeq = true
+		default:
+			return false
+		}
+	}
+
+	// a brace left over is a typo such as "{seq" or "{MM}}"
+	if strings.ContainsAny(invoiceNumberToken.ReplaceAllString(pattern, ""), "{}") {
+		return false
+	}
+	return hasSeq
+}
+
+// formatInvoiceNumber fills pattern with the issue date and the shop sequence
+// value, e.g. "INV/{YYYY}/{MM}/{seq:4}" becomes "INV/2024/01/0042".
+func formatInvoiceNumber(pattern string, issuedAt time.Time, seq int) string {
+	return invoiceNumberToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		m := invoiceNumberToken.FindStringSubmatch(token)
+		switch m[1] {
+		case "YYYY":
+			return issuedAt.Format("2006")
+		case "YY":
+			return issuedAt.Format("06")
+		case "MM":
+			return issuedAt.Format("01")
+		case "DD":
+			return issuedAt.Format("02")
+		case "seq":
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+		return token
+	})
+}
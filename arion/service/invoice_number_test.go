@@ -0,0 +1,60 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_validInvoiceNumberPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    bool
+	}{
+		{name: "default pattern", pattern: "INV/{YYYY}/{MM}/{seq}", want: true},
+		{name: "padded sequence", pattern: "{YY}{MM}{DD}-{seq:5}", want: true},
+		{name: "sequence only", pattern: "{seq}", want: true},
+		{name: "empty", pattern: "", want: false},
+		{name: "missing sequence", pattern: "INV/{YYYY}/{MM}", want: false},
+		{name: "unknown placeholder", pattern: "INV/{shop}/{seq}", want: false},
+		{name: "width on date placeholder", pattern: "INV/{YYYY:2}/{seq}", want: false},
+		{name: "zero width", pattern: "INV/{seq:0}", want: false},
+		{name: "width too large", pattern: "INV/{seq:12}", want: false},
+		{name: "unclosed brace", pattern: "INV/{seq", want: false},
+		{name: "stray brace", pattern: "INV/{MM}}/{seq}", want: false},
+		{name: "too long", pattern: strings.Repeat("X", 40) + "{seq}", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validInvoiceNumberPattern(tt.pattern); got != tt.want {
+				t.Errorf("validInvoiceNumberPattern(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatInvoiceNumber(t *testing.T) {
+	issuedAt := time.Date(2024, 3, 7, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		pattern string
+		seq     int
+		want    string
+	}{
+		{name: "default pattern", pattern: "INV/{YYYY}/{MM}/{seq}", seq: 42, want: "INV/2024/03/42"},
+		{name: "padded sequence", pattern: "{YY}{MM}{DD}-{seq:5}", seq: 42, want: "240307-00042"},
+		{name: "sequence wider than padding", pattern: "F{seq:2}", seq: 1234, want: "F1234"},
+		{name: "literal text kept", pattern: "TOKO/{seq}/LUNAS", seq: 1, want: "TOKO/1/LUNAS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatInvoiceNumber(tt.pattern, issuedAt, tt.seq); got != tt.want {
+				t.Errorf("formatInvoiceNumber(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,24 +3,40 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-pdf/fpdf"
+	"github.com/sirupsen/logrus"
 	"github.com/zeirash/recapo/arion/common/apierr"
 	"github.com/zeirash/recapo/arion/common/config"
 	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/i18n"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/pdffont"
 	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/storage"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/store"
 )
 
 type (
 	OrderService interface {
-		CreateOrder(ctx context.Context, customerID int, shopID int, notes *string) (response.OrderData, error)
+		CreateOrder(ctx context.Context, customerID int, shopID int, notes *string, addressID *int) (response.OrderData, error)
 		GetOrderByID(ctx context.Context, id int, shopID ...int) (*response.OrderData, error)
 		GetOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]response.OrderData, error)
 		GetOrdersStats(ctx context.Context, shopID int, opts model.OrderFilterOptions) (response.OrderStatsData, error)
+		GetOrdersTimeseries(ctx context.Context, input GetOrdersTimeseriesInput) (response.OrderTimeseriesData, error)
 		UpdateOrderByID(ctx context.Context, input UpdateOrderInput) (response.OrderData, error)
 		DeleteOrderByID(ctx context.Context, id int) error
 
@@ -35,11 +51,23 @@ type (
 		GetOrderPaymentsByOrderID(ctx context.Context, orderID int) ([]response.OrderPaymentData, error)
 		DeleteOrderPaymentByID(ctx context.Context, orderPaymentID, orderID int) error
 		DeleteOrderPaymentsByOrderID(ctx context.Context, orderID int) error
+		ApplyCustomerCredit(ctx context.Context, orderID, shopID, amount int) (response.OrderPaymentData, error)
+		CarryOverOverpayment(ctx context.Context, orderID, shopID int) (response.CustomerCreditData, error)
 
-		GenerateOrderInvoice(ctx context.Context, orderID, shopID int, message string) ([]byte, error)
+		ApplyDPRule(ctx context.Context, orderID, shopID, ruleID int) (response.OrderData, error)
+		FlagOverdueDownPayments(ctx context.Context) error
+
+		CancelOrder(ctx context.Context, input CancelOrderInput) (response.OrderData, error)
+		RefundOrder(ctx context.Context, input RefundOrderInput) (response.OrderRefundData, error)
+		GetOrderRefundsByOrderID(ctx context.Context, orderID, shopID int) ([]response.OrderRefundData, error)
+		UploadRefundProof(ctx context.Context, file io.Reader) (string, error)
+		ClearShortageNotify(ctx context.Context, orderID, shopID int) error
+
+		GenerateOrderInvoice(ctx context.Context, orderID, shopID int, message, lang string) ([]byte, error)
+		GenerateOrderDocuments(ctx context.Context, input GenerateOrderDocumentsInput) (OrderDocuments, error)
 
 		MergeTempOrder(ctx context.Context, tempOrderID, customerID, shopID int, activeOrderID *int) (*response.OrderData, error)
-		CreateTempOrder(ctx context.Context, customerName, customerPhone, shareToken string, items []CreateTempOrderItemInput) (response.TempOrderData, error)
+		CreateTempOrder(ctx context.Context, customerName, customerPhone, shareToken string, address *model.ShippingAddress, items []CreateTempOrderItemInput) (response.TempOrderData, error)
 		GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*response.TempOrderData, error)
 		GetTempOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]response.TempOrderData, error)
 		RejectTempOrderByID(ctx context.Context, id int) (response.TempOrderData, error)
@@ -53,6 +81,7 @@ type (
 		Status        *string
 		PaymentStatus *string
 		Notes         *string
+		AddressID     *int // re-copies this address book entry onto the order
 	}
 
 	UpdateOrderItemInput struct {
@@ -66,6 +95,24 @@ type (
 		ProductID int
 		Qty       int
 	}
+
+	CancelOrderInput struct {
+		OrderID int
+		ShopID  int
+		Reason  string
+		Notes   string
+		Refund  *RefundOrderInput // optional; OrderID, ShopID and Reason are taken from the cancellation
+	}
+
+	RefundOrderInput struct {
+		OrderID  int
+		ShopID   int
+		Amount   int
+		Method   string
+		Reason   string
+		ProofURL string
+		Notes    string
+	}
 )
 
 func NewOrderService() OrderService {
@@ -83,10 +130,60 @@ func NewOrderService() OrderService {
 		orderPaymentStore = store.NewOrderPaymentStore()
 	}
 
+	if orderRefundStore == nil {
+		orderRefundStore = store.NewOrderRefundStore()
+	}
+
+	if shopStore == nil {
+		shopStore = store.NewShopStore()
+	}
+
+	if customerCreditStore == nil {
+		customerCreditStore = store.NewCustomerCreditStore()
+	}
+
+	if dpRuleStore == nil {
+		dpRuleStore = store.NewDPRuleStore()
+	}
+
+	if documentSequenceStore == nil {
+		documentSequenceStore = store.NewDocumentSequenceStore()
+	}
+
+	if customerStore == nil {
+		customerStore = store.NewCustomerStore()
+	}
+
+	if customerAddressStore == nil {
+		customerAddressStore = store.NewCustomerAddressStore()
+	}
+
+	if statsStore == nil {
+		statsStore = store.NewStatsStore()
+	}
+	if shareLinkStore == nil {
+		shareLinkStore = store.NewShareLinkStore()
+	}
+
+	if productStore == nil {
+		productStore = store.NewProductStore()
+	}
+
+	if uploadedObjectStore == nil {
+		uploadedObjectStore = store.NewUploadedObjectStore()
+	}
+
+	if storageBackend == nil {
+		storageBackend = storage.New(cfg)
+	}
+
 	return &oservice{}
 }
 
-func (o *oservice) CreateOrder(ctx context.Context, customerID int, shopID int, notes *string) (response.OrderData, error) {
+// CreateOrder opens a new order for the customer. The order ships to the
+// given address book entry, or to the customer's default address when
+// addressID is nil.
+func (o *oservice) CreateOrder(ctx context.Context, customerID int, shopID int, notes *string, addressID *int) (response.OrderData, error) {
 	activeOrder, err := orderStore.GetActiveOrderByCustomerID(ctx, customerID, shopID)
 	if err != nil {
 		return response.OrderData{}, err
@@ -95,24 +192,92 @@ func (o *oservice) CreateOrder(ctx context.Context, customerID int, shopID int,
 		return response.OrderData{}, errors.New(apierr.ErrActiveOrderExists)
 	}
 
-	order, err := orderStore.CreateOrder(ctx, nil, customerID, shopID, notes, nil)
+	shop, err := shopStore.GetShopByID(ctx, shopID)
 	if err != nil {
 		return response.OrderData{}, err
 	}
 
-	res := response.OrderData{
-		ID:            order.ID,
-		CustomerName:  order.CustomerName,
-		TotalPrice:    order.TotalPrice,
-		Status:        order.Status,
-		PaymentStatus: order.PaymentStatus,
-		Notes:         order.Notes,
-		CreatedAt:     order.CreatedAt,
+	rule, err := dpRuleStore.GetDefaultDPRuleByShopID(ctx, shopID)
+	if err != nil {
+		return response.OrderData{}, err
 	}
 
+	address, err := customerShippingAddress(ctx, customerID, addressID)
+	if err != nil {
+		return response.OrderData{}, err
+	}
+
+	var order *model.Order
+	uniqueCode := shop != nil && shop.UniqueCodeEnabled
+	loc := loadShopLocation(shop)
+	if uniqueCode || rule != nil || address != nil {
+		order, err = o.createOrderInTx(ctx, customerID, shopID, notes, uniqueCode, rule, address, loc)
+	} else {
+		order, err = orderStore.CreateOrder(ctx, nil, customerID, shopID, notes, nil)
+	}
+	if err != nil {
+		return response.OrderData{}, err
+	}
+
+	res := response.OrderData{
+		ID:              order.ID,
+		CustomerName:    order.CustomerName,
+		TotalPrice:      order.TotalPrice,
+		UniqueCode:      order.UniqueCode,
+		AmountDue:       amountDue(order.TotalPrice, order.UniqueCode),
+		Status:          order.Status,
+		PaymentStatus:   order.PaymentStatus,
+		Notes:           order.Notes,
+		ShippingAddress: toShippingAddressData(order.ShippingAddress),
+		CreatedAt:       order.CreatedAt,
+	}
+	setDownPaymentData(&res, order)
+
 	return res, nil
 }
 
+// createOrderInTx creates the order together with its unique code, default
+// DP rule and shipping address, so a half-initialised order is never visible.
+func (o *oservice) createOrderInTx(ctx context.Context, customerID int, shopID int, notes *string, uniqueCode bool, rule *model.DPRule, address *model.ShippingAddress, loc *time.Location) (*model.Order, error) {
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	order, err := orderStore.CreateOrder(ctx, tx, customerID, shopID, notes, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if uniqueCode {
+		order.UniqueCode, err = orderStore.AssignUniqueCode(ctx, tx, order.ID, shopID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule != nil {
+		if err = applyDownPayment(ctx, tx, order, rule, 0, loc); err != nil {
+			return nil, err
+		}
+	}
+
+	if address != nil {
+		if err = orderStore.SetShippingAddress(ctx, tx, order.ID, address); err != nil {
+			return nil, err
+		}
+		order.ShippingAddress = address
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
 func (o *oservice) GetOrderByID(ctx context.Context, id int, shopID ...int) (*response.OrderData, error) {
 	order, err := orderStore.GetOrderByID(ctx, id, shopID...)
 	if err != nil {
@@ -123,6 +288,11 @@ func (o *oservice) GetOrderByID(ctx context.Context, id int, shopID ...int) (*re
 		return nil, errors.New(apierr.ErrOrderNotFound)
 	}
 
+	return orderDetailData(ctx, order)
+}
+
+// orderDetailData maps order to its response with items and payments loaded.
+func orderDetailData(ctx context.Context, order *model.Order) (*response.OrderData, error) {
 	orderItems, err := orderItemStore.GetOrderItemsByOrderID(ctx, order.ID)
 	if err != nil {
 		return nil, err
@@ -135,15 +305,20 @@ func (o *oservice) GetOrderByID(ctx context.Context, id int, shopID ...int) (*re
 			ProductName:   orderItem.ProductName,
 			Price:         orderItem.Price,
 			Qty:           orderItem.Qty,
+			ShortageQty:   orderItem.ShortageQty,
 			CreatedAt:     orderItem.CreatedAt,
 		})
+		if orderItem.AllocatedQty.Valid {
+			n := int(orderItem.AllocatedQty.Int64)
+			orderItemsData[len(orderItemsData)-1].AllocatedQty = &n
+		}
 		if orderItem.UpdatedAt.Valid {
 			t := orderItem.UpdatedAt.Time
 			orderItemsData[len(orderItemsData)-1].UpdatedAt = &t
 		}
 	}
 
-	orderPayments, err := orderPaymentStore.GetOrderPaymentsByOrderID(ctx, order.ID)
+	orderPayments, err := orderPaymentStore.GetOrderPaymentsByOrderID(ctx, nil, order.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -167,13 +342,20 @@ func (o *oservice) GetOrderByID(ctx context.Context, id int, shopID ...int) (*re
 		CustomerName:      order.CustomerName,
 		IsCustomerDeleted: order.IsCustomerDeleted,
 		TotalPrice:        order.TotalPrice,
+		UniqueCode:        order.UniqueCode,
+		AmountDue:         amountDue(order.TotalPrice, order.UniqueCode),
+		InvoiceNumber:     order.InvoiceNumber.String,
 		Status:            order.Status,
 		PaymentStatus:     order.PaymentStatus,
+		ShortageNotify:    order.ShortageNotify,
 		Notes:             order.Notes,
+		ShippingAddress:   toShippingAddressData(order.ShippingAddress),
 		OrderItems:        orderItemsData,
 		OrderPayments:     orderPaymentsData,
 		CreatedAt:         order.CreatedAt,
 	}
+	setDownPaymentData(&res, order)
+	setCancelData(&res, order)
 
 	if order.UpdatedAt.Valid {
 		t := order.UpdatedAt.Time
@@ -184,6 +366,10 @@ func (o *oservice) GetOrderByID(ctx context.Context, id int, shopID ...int) (*re
 }
 
 func (o *oservice) GetOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]response.OrderData, error) {
+	if err := shopDateRange(ctx, shopID, &opts); err != nil {
+		return []response.OrderData{}, err
+	}
+
 	orders, err := orderStore.GetOrdersByShopID(ctx, shopID, opts)
 	if err != nil {
 		return []response.OrderData{}, err
@@ -196,11 +382,18 @@ func (o *oservice) GetOrdersByShopID(ctx context.Context, shopID int, opts model
 			CustomerName:      order.CustomerName,
 			IsCustomerDeleted: order.IsCustomerDeleted,
 			TotalPrice:        order.TotalPrice,
+			UniqueCode:        order.UniqueCode,
+			AmountDue:         amountDue(order.TotalPrice, order.UniqueCode),
+			InvoiceNumber:     order.InvoiceNumber.String,
 			Status:            order.Status,
 			PaymentStatus:     order.PaymentStatus,
+			ShortageNotify:    order.ShortageNotify,
 			Notes:             order.Notes,
+			ShippingAddress:   toShippingAddressData(order.ShippingAddress),
 			CreatedAt:         order.CreatedAt,
 		}
+		setDownPaymentData(&res, &order)
+		setCancelData(&res, &order)
 
 		if order.UpdatedAt.Valid {
 			t := order.UpdatedAt.Time
@@ -214,15 +407,23 @@ func (o *oservice) GetOrdersByShopID(ctx context.Context, shopID int, opts model
 }
 
 func (o *oservice) GetOrdersStats(ctx context.Context, shopID int, opts model.OrderFilterOptions) (response.OrderStatsData, error) {
+	if err := shopDateRange(ctx, shopID, &opts); err != nil {
+		return response.OrderStatsData{}, err
+	}
+
 	total, err := orderPaymentStore.GetPaymentsSumByShopID(ctx, shopID, opts)
 	if err != nil {
 		return response.OrderStatsData{}, err
 	}
+	refunds, err := orderRefundStore.GetRefundsSumByShopID(ctx, shopID, opts)
+	if err != nil {
+		return response.OrderStatsData{}, err
+	}
 	netSales, err := orderItemStore.GetNetSalesByShopID(ctx, shopID, opts)
 	if err != nil {
 		return response.OrderStatsData{}, err
 	}
-	return response.OrderStatsData{TotalRevenue: total, NetSales: netSales}, nil
+	return response.OrderStatsData{TotalRevenue: total, TotalRefunds: refunds, NetSales: netSales}, nil
 }
 
 func (o *oservice) UpdateOrderByID(ctx context.Context, input UpdateOrderInput) (response.OrderData, error) {
@@ -242,21 +443,64 @@ func (o *oservice) UpdateOrderByID(ctx context.Context, input UpdateOrderInput)
 		Notes:         input.Notes,
 	}
 
-	orderData, err := orderStore.UpdateOrder(ctx, nil, input.ID, updateData)
+	var address *model.ShippingAddress
+	if input.AddressID != nil {
+		address, err = customerShippingAddress(ctx, order.CustomerID, input.AddressID)
+		if err != nil {
+			return response.OrderData{}, err
+		}
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
 	if err != nil {
 		return response.OrderData{}, err
 	}
+	defer tx.Rollback()
 
-	res := response.OrderData{
-		ID:            orderData.ID,
-		CustomerName:  orderData.CustomerName,
-		TotalPrice:    orderData.TotalPrice,
-		Status:        orderData.Status,
-		PaymentStatus: orderData.PaymentStatus,
-		Notes:         orderData.Notes,
-		CreatedAt:     orderData.CreatedAt,
+	status, paymentStatus := order.Status, order.PaymentStatus
+	if input.Status != nil {
+		status = *input.Status
+	}
+	if input.PaymentStatus != nil {
+		paymentStatus = *input.PaymentStatus
+	}
+
+	if _, err := reclaimUniqueCode(ctx, tx, order, status, paymentStatus); err != nil {
+		return response.OrderData{}, err
 	}
 
+	orderData, err := orderStore.UpdateOrder(ctx, tx, input.ID, updateData)
+	if err != nil {
+		return response.OrderData{}, err
+	}
+
+	if address != nil {
+		if err := orderStore.SetShippingAddress(ctx, tx, input.ID, address); err != nil {
+			return response.OrderData{}, err
+		}
+		orderData.ShippingAddress = address
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.OrderData{}, err
+	}
+
+	res := response.OrderData{
+		ID:              orderData.ID,
+		CustomerName:    orderData.CustomerName,
+		TotalPrice:      orderData.TotalPrice,
+		UniqueCode:      orderData.UniqueCode,
+		AmountDue:       amountDue(orderData.TotalPrice, orderData.UniqueCode),
+		InvoiceNumber:   orderData.InvoiceNumber.String,
+		Status:          orderData.Status,
+		PaymentStatus:   orderData.PaymentStatus,
+		Notes:           orderData.Notes,
+		ShippingAddress: toShippingAddressData(orderData.ShippingAddress),
+		CreatedAt:       orderData.CreatedAt,
+	}
+	setDownPaymentData(&res, orderData)
+
 	if orderData.UpdatedAt.Valid {
 		res.UpdatedAt = &orderData.UpdatedAt.Time
 	}
@@ -273,6 +517,10 @@ func (o *oservice) DeleteOrderByID(ctx context.Context, id int) error {
 	}
 	defer tx.Rollback()
 
+	if err := checkOrderPaymentCredits(ctx, tx, id, 0); err != nil {
+		return err
+	}
+
 	err = orderItemStore.DeleteOrderItemsByOrderID(ctx, tx, id)
 	if err != nil {
 		return err
@@ -379,9 +627,15 @@ func (o *oservice) GetOrderItemByID(ctx context.Context, orderItemID, orderID in
 		ProductName:   orderItem.ProductName,
 		Price:         orderItem.Price,
 		Qty:           orderItem.Qty,
+		ShortageQty:   orderItem.ShortageQty,
 		CreatedAt:     orderItem.CreatedAt,
 	}
 
+	if orderItem.AllocatedQty.Valid {
+		n := int(orderItem.AllocatedQty.Int64)
+		res.AllocatedQty = &n
+	}
+
 	if orderItem.UpdatedAt.Valid {
 		t := orderItem.UpdatedAt.Time
 		res.UpdatedAt = &t
@@ -405,6 +659,21 @@ func (o *oservice) CreateOrderPayment(ctx context.Context, orderID, amount int)
 		return response.OrderPaymentData{}, err
 	}
 
+	if order.UniqueCode > 0 || order.DPPercent > 0 {
+		paid, err := sumOrderPayments(ctx, nil, orderID)
+		if err != nil {
+			return response.OrderPaymentData{}, err
+		}
+
+		if err := settleUniqueCode(ctx, nil, order, paid); err != nil {
+			return response.OrderPaymentData{}, err
+		}
+
+		if err := settleDownPayment(ctx, nil, order, paid); err != nil {
+			return response.OrderPaymentData{}, err
+		}
+	}
+
 	res := response.OrderPaymentData{
 		ID:        orderPayment.ID,
 		OrderID:   orderPayment.OrderID,
@@ -436,7 +705,7 @@ func (o *oservice) UpdateOrderPaymentAmountByID(ctx context.Context, id, orderID
 }
 
 func (o *oservice) GetOrderPaymentsByOrderID(ctx context.Context, orderID int) ([]response.OrderPaymentData, error) {
-	orderPayments, err := orderPaymentStore.GetOrderPaymentsByOrderID(ctx, orderID)
+	orderPayments, err := orderPaymentStore.GetOrderPaymentsByOrderID(ctx, nil, orderID)
 	if err != nil {
 		return []response.OrderPaymentData{}, err
 	}
@@ -462,165 +731,743 @@ func (o *oservice) GetOrderPaymentsByOrderID(ctx context.Context, orderID int) (
 }
 
 func (o *oservice) DeleteOrderPaymentByID(ctx context.Context, orderPaymentID, orderID int) error {
-	err := orderPaymentStore.DeleteOrderPaymentByID(ctx, orderPaymentID, orderID)
+	db := dbGetter()
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := checkOrderPaymentCredits(ctx, tx, orderID, orderPaymentID); err != nil {
+		return err
+	}
 
-	return nil
+	err = orderPaymentStore.DeleteOrderPaymentByID(ctx, tx, orderPaymentID, orderID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (o *oservice) DeleteOrderPaymentsByOrderID(ctx context.Context, orderID int) error {
-	return orderPaymentStore.DeleteOrderPaymentsByOrderID(ctx, nil, orderID)
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkOrderPaymentCredits(ctx, tx, orderID, 0); err != nil {
+		return err
+	}
+
+	if err := orderPaymentStore.DeleteOrderPaymentsByOrderID(ctx, tx, orderID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (o *oservice) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]response.OrderItemData, error) {
-	orderItems, err := orderItemStore.GetOrderItemsByOrderID(ctx, orderID)
+// checkOrderPaymentCredits rejects deleting the order's payment paymentID,
+// or all its payments when paymentID is 0, while a customer credit entry was
+// booked with it; deleting it would leave the credit balance counting money
+// the order no longer shows. The order row and the customer's ledger stay
+// locked until tx ends, so no such entry can be booked in the meantime.
+func checkOrderPaymentCredits(ctx context.Context, tx database.Tx, orderID, paymentID int) error {
+	order, err := orderStore.GetOrderByIDForUpdate(ctx, tx, orderID)
 	if err != nil {
-		return []response.OrderItemData{}, err
+		return err
 	}
 
-	orderItemsData := make([]response.OrderItemData, 0, len(orderItems))
-	for _, orderItem := range orderItems {
-		res := response.OrderItemData{
-			ID:            orderItem.ID,
-			ProductName:   orderItem.ProductName,
-			Price:         orderItem.Price,
-			Qty:           orderItem.Qty,
-			CreatedAt:     orderItem.CreatedAt,
-		}
+	// Nothing of an order that does not exist is left to delete.
+	if order == nil {
+		return nil
+	}
 
-		if orderItem.UpdatedAt.Valid {
-			t := orderItem.UpdatedAt.Time
-			res.UpdatedAt = &t
-		}
+	if _, err := customerCreditStore.GetCustomerCreditBalance(ctx, tx, order.CustomerID); err != nil {
+		return err
+	}
 
-		orderItemsData = append(orderItemsData, res)
+	linked, err := customerCreditStore.HasOrderPaymentCredits(ctx, tx, orderID, paymentID)
+	if err != nil {
+		return err
 	}
 
-	return orderItemsData, nil
+	if linked {
+		return errors.New(apierr.ErrPaymentHasCredit)
+	}
+
+	return nil
 }
 
-func (o *oservice) CreateTempOrder(ctx context.Context, customerName, customerPhone, shareToken string, items []CreateTempOrderItemInput) (response.TempOrderData, error) {
-	shop, err := shopStore.GetShopByShareToken(ctx, shareToken)
+// ApplyCustomerCredit pays part of an order from the customer's credit
+// balance. The amount may not exceed what is still outstanding on the order.
+func (o *oservice) ApplyCustomerCredit(ctx context.Context, orderID, shopID, amount int) (response.OrderPaymentData, error) {
+	order, err := orderStore.GetOrderByID(ctx, orderID, shopID)
 	if err != nil {
-		return response.TempOrderData{}, err
+		return response.OrderPaymentData{}, err
 	}
 
-	if shop == nil {
-		return response.TempOrderData{}, errors.New(apierr.ErrShopNotFound)
+	if order == nil {
+		return response.OrderPaymentData{}, errors.New(apierr.ErrOrderNotFound)
 	}
 
 	db := dbGetter()
-
 	tx, err := db.Begin()
 	if err != nil {
-		return response.TempOrderData{}, err
+		return response.OrderPaymentData{}, err
 	}
 	defer tx.Rollback()
 
-	tempOrder, err := orderStore.CreateTempOrder(ctx, tx, customerName, customerPhone, shop.ID)
+	balance, err := customerCreditStore.GetCustomerCreditBalance(ctx, tx, order.CustomerID)
 	if err != nil {
-		return response.TempOrderData{}, err
-	}
-
-	tempOrderItemsData := make([]response.TempOrderItemData, 0, len(items))
-	totalPrice := 0
-	for _, item := range items {
-		orderItemTemp, err := orderItemStore.CreateTempOrderItem(ctx, tx, tempOrder.ID, item.ProductID, item.Qty)
-		if err != nil {
-			return response.TempOrderData{}, err
-		}
-		tempOrderItemsData = append(tempOrderItemsData, response.TempOrderItemData{
-			ID:          orderItemTemp.ID,
-			TempOrderID: orderItemTemp.TempOrderID,
-			ProductName: orderItemTemp.ProductName,
-			Price:       orderItemTemp.Price,
-			Qty:         orderItemTemp.Qty,
-			CreatedAt:   orderItemTemp.CreatedAt,
-		})
-		totalPrice += item.Qty * orderItemTemp.Price
+		return response.OrderPaymentData{}, err
 	}
 
-	err = orderStore.UpdateTempOrderTotalPrice(ctx, tx, tempOrder.ID, totalPrice)
-	if err != nil {
-		return response.TempOrderData{}, err
+	if balance < amount {
+		return response.OrderPaymentData{}, errors.New(apierr.ErrInsufficientCredit)
 	}
 
-	err = tx.Commit()
+	paid, err := sumOrderPayments(ctx, tx, orderID)
 	if err != nil {
-		return response.TempOrderData{}, err
+		return response.OrderPaymentData{}, err
 	}
 
-	res := response.TempOrderData{
-		ID:             tempOrder.ID,
-		CustomerName:   tempOrder.CustomerName,
-		CustomerPhone:  tempOrder.CustomerPhone,
-		TotalPrice:     tempOrder.TotalPrice,
-		Status:         tempOrder.Status,
-		TempOrderItems: tempOrderItemsData,
-		CreatedAt:      tempOrder.CreatedAt,
-	}
-	if tempOrder.UpdatedAt.Valid {
-		res.UpdatedAt = &tempOrder.UpdatedAt.Time
+	if amount > order.TotalPrice+order.UniqueCode-paid {
+		return response.OrderPaymentData{}, errors.New(apierr.ErrCreditAmountInvalid)
 	}
-	return res, nil
-}
 
-func (o *oservice) GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*response.TempOrderData, error) {
-	tempOrder, err := orderStore.GetTempOrderByID(ctx, id, shopID...)
+	orderPayment, err := orderPaymentStore.CreateOrderPayment(ctx, tx, orderID, amount)
 	if err != nil {
-		return nil, err
+		return response.OrderPaymentData{}, err
 	}
 
-	if tempOrder == nil {
-		return nil, errors.New(apierr.ErrTempOrderNotFound)
+	_, err = customerCreditStore.CreateCustomerCredit(ctx, tx, store.CreateCustomerCreditInput{
+		ShopID:     order.ShopID,
+		CustomerID: order.CustomerID,
+		OrderID:    &orderID,
+		PaymentID:  &orderPayment.ID,
+		Type:       constant.CustomerCreditTypePayment,
+		Amount:     -amount,
+	})
+	if err != nil {
+		return response.OrderPaymentData{}, err
 	}
 
-	tempOrderItems, err := orderItemStore.GetTempOrderItemsByTempOrderID(ctx, tempOrder.ID)
-	if err != nil {
-		return nil, err
+	if err := settleUniqueCode(ctx, tx, order, paid+amount); err != nil {
+		return response.OrderPaymentData{}, err
 	}
 
-	tempOrderItemsData := make([]response.TempOrderItemData, 0, len(tempOrderItems))
-	for _, tempOrderItem := range tempOrderItems {
-		tempOrderItemsData = append(tempOrderItemsData, response.TempOrderItemData{
-			ID:          tempOrderItem.ID,
-			TempOrderID: tempOrderItem.TempOrderID,
-			ProductID:   tempOrderItem.ProductID,
-			ProductName: tempOrderItem.ProductName,
-			Price:       tempOrderItem.Price,
-			Qty:         tempOrderItem.Qty,
-			CreatedAt:   tempOrderItem.CreatedAt,
-		})
+	if err := settleDownPayment(ctx, tx, order, paid+amount); err != nil {
+		return response.OrderPaymentData{}, err
 	}
 
-	res := response.TempOrderData{
-		ID:             tempOrder.ID,
-		CustomerName:   tempOrder.CustomerName,
-		CustomerPhone:  tempOrder.CustomerPhone,
-		TotalPrice:     tempOrder.TotalPrice,
-		Status:         tempOrder.Status,
-		TempOrderItems: tempOrderItemsData,
-		CreatedAt:      tempOrder.CreatedAt,
+	if err := tx.Commit(); err != nil {
+		return response.OrderPaymentData{}, err
 	}
 
-	if tempOrder.UpdatedAt.Valid {
-		t := tempOrder.UpdatedAt.Time
-		res.UpdatedAt = &t
+	res := response.OrderPaymentData{
+		ID:        orderPayment.ID,
+		OrderID:   orderPayment.OrderID,
+		Amount:    orderPayment.Amount,
+		CreatedAt: orderPayment.CreatedAt,
 	}
 
-	return &res, nil
+	return res, nil
 }
 
-func (o *oservice) GetTempOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]response.TempOrderData, error) {
-	tempOrders, err := orderStore.GetTempOrdersByShopID(ctx, shopID, opts)
+// CarryOverOverpayment moves whatever was paid beyond the order's amount due
+// into the customer's credit balance. A negative payment is booked on the
+// order for the same amount so the money is not counted twice.
+func (o *oservice) CarryOverOverpayment(ctx context.Context, orderID, shopID int) (response.CustomerCreditData, error) {
+	order, err := orderStore.GetOrderByID(ctx, orderID, shopID)
 	if err != nil {
-		return []response.TempOrderData{}, err
+		return response.CustomerCreditData{}, err
 	}
 
-	tempOrdersData := make([]response.TempOrderData, 0, len(tempOrders))
-	for _, tempOrder := range tempOrders {
+	if order == nil {
+		return response.CustomerCreditData{}, errors.New(apierr.ErrOrderNotFound)
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+	defer tx.Rollback()
+
+	// Taking the ledger lock first serialises concurrent carry-overs of the same order.
+	if _, err := customerCreditStore.GetCustomerCreditBalance(ctx, tx, order.CustomerID); err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	paid, err := sumOrderPayments(ctx, tx, orderID)
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	excess := paid - (order.TotalPrice + order.UniqueCode)
+	if excess <= 0 {
+		return response.CustomerCreditData{}, errors.New(apierr.ErrNoOverpayment)
+	}
+
+	payment, err := orderPaymentStore.CreateOrderPayment(ctx, tx, orderID, -excess)
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	credit, err := customerCreditStore.CreateCustomerCredit(ctx, tx, store.CreateCustomerCreditInput{
+		ShopID:     order.ShopID,
+		CustomerID: order.CustomerID,
+		OrderID:    &orderID,
+		PaymentID:  &payment.ID,
+		Type:       constant.CustomerCreditTypeOverpayment,
+		Amount:     excess,
+	})
+	if err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.CustomerCreditData{}, err
+	}
+
+	return toCustomerCreditData(*credit), nil
+}
+
+// ApplyDPRule gives the order the DP requirement of the rule, e.g. the rule of
+// the trip the order belongs to. A ruleID of 0 removes the requirement.
+func (o *oservice) ApplyDPRule(ctx context.Context, orderID, shopID, ruleID int) (response.OrderData, error) {
+	order, err := orderStore.GetOrderByID(ctx, orderID, shopID)
+	if err != nil {
+		return response.OrderData{}, err
+	}
+
+	if order == nil {
+		return response.OrderData{}, errors.New(apierr.ErrOrderNotFound)
+	}
+
+	var rule *model.DPRule
+	loc := time.UTC
+	if ruleID > 0 {
+		rule, err = dpRuleStore.GetDPRuleByID(ctx, ruleID, shopID)
+		if err != nil {
+			return response.OrderData{}, err
+		}
+
+		if rule == nil {
+			return response.OrderData{}, errors.New(apierr.ErrDPRuleNotFound)
+		}
+
+		loc, err = shopLocation(ctx, shopID)
+		if err != nil {
+			return response.OrderData{}, err
+		}
+	}
+
+	paid, err := sumOrderPayments(ctx, nil, orderID)
+	if err != nil {
+		return response.OrderData{}, err
+	}
+
+	if err := applyDownPayment(ctx, nil, order, rule, paid, loc); err != nil {
+		return response.OrderData{}, err
+	}
+
+	res := response.OrderData{
+		ID:                order.ID,
+		CustomerName:      order.CustomerName,
+		IsCustomerDeleted: order.IsCustomerDeleted,
+		TotalPrice:        order.TotalPrice,
+		UniqueCode:        order.UniqueCode,
+		AmountDue:         amountDue(order.TotalPrice, order.UniqueCode),
+		InvoiceNumber:     order.InvoiceNumber.String,
+		Status:            order.Status,
+		PaymentStatus:     order.PaymentStatus,
+		Notes:             order.Notes,
+		CreatedAt:         order.CreatedAt,
+	}
+	setDownPaymentData(&res, order)
+
+	return res, nil
+}
+
+// FlagOverdueDownPayments flags orders whose DP deadline passed without the
+// DP being paid, so sellers can send reminders. Orders whose rule says so are
+// cancelled as well. A failing order is logged and skipped.
+func (o *oservice) FlagOverdueDownPayments(ctx context.Context) error {
+	orders, err := orderStore.GetDPOverdueOrders(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	flagged, cancelled := 0, 0
+	for _, order := range orders {
+		ok, err := flagOverdueDownPayment(ctx, order)
+		if err != nil {
+			logger.WithError(err).WithField("order_id", order.ID).Error("flag_overdue_down_payment_error")
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		flagged++
+		if order.AutoCancel {
+			cancelled++
+		}
+	}
+
+	if flagged > 0 {
+		logger.WithFields(logrus.Fields{"flagged": flagged, "cancelled": cancelled}).Info("flagged overdue down payments")
+	}
+
+	return nil
+}
+
+// flagOverdueDownPayment flags the order and, when its rule says so, cancels
+// it the way CancelOrder does and puts what was paid on the customer's
+// credit. The order is re-checked under its row lock, as a payment may have
+// come in since it was listed; it returns false when there was nothing to do.
+func flagOverdueDownPayment(ctx context.Context, overdue model.DPOverdueOrder) (bool, error) {
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	order, err := orderStore.GetOrderByIDForUpdate(ctx, tx, overdue.ID)
+	if err != nil {
+		return false, err
+	}
+	if order == nil || order.Status == constant.OrderStatusCancelled || order.Status == constant.OrderStatusDone || order.DPPercent == 0 || order.DPOverdueAt.Valid {
+		return false, nil
+	}
+
+	paid, err := sumOrderPayments(ctx, tx, order.ID)
+	if err != nil {
+		return false, err
+	}
+	if paid >= dpAmount(order.TotalPrice, order.DPPercent) {
+		return false, nil
+	}
+
+	if err := orderStore.MarkDPOverdue(ctx, tx, order.ID); err != nil {
+		return false, err
+	}
+
+	if overdue.AutoCancel {
+		if err := cancelOrder(ctx, tx, order, constant.OrderReasonDPOverdue, ""); err != nil {
+			return false, err
+		}
+		if paid > 0 {
+			refund := RefundOrderInput{Amount: paid, Method: constant.RefundMethodCredit, Reason: constant.OrderReasonDPOverdue}
+			if _, err := refundOrder(ctx, tx, order, refund); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// CancelOrder cancels the order with a reason code and, when input.Refund is
+// set, refunds part or all of what was paid in the same transaction. The
+// order's items stay on the order but no longer count in the purchase list.
+func (o *oservice) CancelOrder(ctx context.Context, input CancelOrderInput) (response.OrderData, error) {
+	order, err := orderStore.GetOrderByID(ctx, input.OrderID, input.ShopID)
+	if err != nil {
+		return response.OrderData{}, err
+	}
+
+	if order == nil {
+		return response.OrderData{}, errors.New(apierr.ErrOrderNotFound)
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.OrderData{}, err
+	}
+	defer tx.Rollback()
+
+	// Checked under the row lock so two cancellations can't both refund.
+	order, err = orderStore.GetOrderByIDForUpdate(ctx, tx, order.ID)
+	if err != nil {
+		return response.OrderData{}, err
+	}
+	if order == nil {
+		return response.OrderData{}, errors.New(apierr.ErrOrderNotFound)
+	}
+	if err := cancelOrder(ctx, tx, order, input.Reason, input.Notes); err != nil {
+		return response.OrderData{}, err
+	}
+
+	if input.Refund != nil {
+		refund := *input.Refund
+		refund.Reason = input.Reason
+		if _, err := refundOrder(ctx, tx, order, refund); err != nil {
+			return response.OrderData{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.OrderData{}, err
+	}
+
+	logger.WithFields(logrus.Fields{"order_id": order.ID, "reason": input.Reason}).Info("order cancelled")
+
+	res := response.OrderData{
+		ID:                order.ID,
+		CustomerName:      order.CustomerName,
+		IsCustomerDeleted: order.IsCustomerDeleted,
+		TotalPrice:        order.TotalPrice,
+		UniqueCode:        order.UniqueCode,
+		AmountDue:         amountDue(order.TotalPrice, order.UniqueCode),
+		InvoiceNumber:     order.InvoiceNumber.String,
+		Status:            order.Status,
+		PaymentStatus:     order.PaymentStatus,
+		Notes:             order.Notes,
+		CreatedAt:         order.CreatedAt,
+	}
+	setDownPaymentData(&res, order)
+	setCancelData(&res, order)
+
+	return res, nil
+}
+
+// cancelOrder cancels the order locked in tx and updates order in place.
+func cancelOrder(ctx context.Context, tx database.Tx, order *model.Order, reason, notes string) error {
+	if order.Status == constant.OrderStatusCancelled {
+		return errors.New(apierr.ErrOrderAlreadyCancelled)
+	}
+
+	if err := orderStore.CancelOrder(ctx, tx, order.ID, reason, notes); err != nil {
+		return err
+	}
+	order.Status = constant.OrderStatusCancelled
+	order.CancelReason = sql.NullString{String: reason, Valid: true}
+	order.CancelNotes = notes
+	order.CancelledAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	return nil
+}
+
+// RefundOrder pays money back to the customer, either for a cancelled order
+// or for part of an order that goes ahead (a damaged item, a price change).
+func (o *oservice) RefundOrder(ctx context.Context, input RefundOrderInput) (response.OrderRefundData, error) {
+	order, err := orderStore.GetOrderByID(ctx, input.OrderID, input.ShopID)
+	if err != nil {
+		return response.OrderRefundData{}, err
+	}
+
+	if order == nil {
+		return response.OrderRefundData{}, errors.New(apierr.ErrOrderNotFound)
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.OrderRefundData{}, err
+	}
+	defer tx.Rollback()
+
+	// The refund cap is what was paid, so it is read under the row lock;
+	// otherwise two refunds could each pass the check against the same sum.
+	order, err = orderStore.GetOrderByIDForUpdate(ctx, tx, order.ID)
+	if err != nil {
+		return response.OrderRefundData{}, err
+	}
+	if order == nil {
+		return response.OrderRefundData{}, errors.New(apierr.ErrOrderNotFound)
+	}
+
+	refund, err := refundOrder(ctx, tx, order, input)
+	if err != nil {
+		return response.OrderRefundData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.OrderRefundData{}, err
+	}
+
+	return toOrderRefundData(*refund), nil
+}
+
+func (o *oservice) GetOrderRefundsByOrderID(ctx context.Context, orderID, shopID int) ([]response.OrderRefundData, error) {
+	order, err := orderStore.GetOrderByID(ctx, orderID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order == nil {
+		return nil, errors.New(apierr.ErrOrderNotFound)
+	}
+
+	refunds, err := orderRefundStore.GetOrderRefundsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]response.OrderRefundData, 0, len(refunds))
+	for _, refund := range refunds {
+		res = append(res, toOrderRefundData(refund))
+	}
+
+	return res, nil
+}
+
+func (o *oservice) UploadRefundProof(ctx context.Context, file io.Reader) (string, error) {
+	return uploadImage(ctx, file, "refunds")
+}
+
+// ClearShortageNotify marks the customer as told about a purchase list
+// shortage on their order.
+func (o *oservice) ClearShortageNotify(ctx context.Context, orderID, shopID int) error {
+	order, err := orderStore.GetOrderByID(ctx, orderID, shopID)
+	if err != nil {
+		return err
+	}
+
+	if order == nil {
+		return errors.New(apierr.ErrOrderNotFound)
+	}
+
+	notify := false
+	_, err = orderStore.UpdateOrder(ctx, nil, orderID, store.UpdateOrderInput{ShortageNotify: &notify})
+	return err
+}
+
+func (o *oservice) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]response.OrderItemData, error) {
+	orderItems, err := orderItemStore.GetOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return []response.OrderItemData{}, err
+	}
+
+	orderItemsData := make([]response.OrderItemData, 0, len(orderItems))
+	for _, orderItem := range orderItems {
+		res := response.OrderItemData{
+			ID:            orderItem.ID,
+			ProductName:   orderItem.ProductName,
+			Price:         orderItem.Price,
+			Qty:           orderItem.Qty,
+			ShortageQty:   orderItem.ShortageQty,
+			CreatedAt:     orderItem.CreatedAt,
+		}
+
+		if orderItem.AllocatedQty.Valid {
+			n := int(orderItem.AllocatedQty.Int64)
+			res.AllocatedQty = &n
+		}
+
+		if orderItem.UpdatedAt.Valid {
+			t := orderItem.UpdatedAt.Time
+			res.UpdatedAt = &t
+		}
+
+		orderItemsData = append(orderItemsData, res)
+	}
+
+	return orderItemsData, nil
+}
+
+func (o *oservice) CreateTempOrder(ctx context.Context, customerName, customerPhone, shareToken string, address *model.ShippingAddress, items []CreateTempOrderItemInput) (response.TempOrderData, error) {
+	customerPhone, err := normalizePhone(customerPhone)
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+
+	if address != nil && address.Phone != "" {
+		address.Phone, err = normalizePhone(address.Phone)
+		if err != nil {
+			return response.TempOrderData{}, err
+		}
+	}
+
+	link, shop, err := getActiveShareLink(ctx, shareToken)
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+
+	customer, err := customerStore.GetCustomerByPhone(ctx, customerPhone, shop.ID)
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+	if customer != nil && customer.Blocked {
+		return response.TempOrderData{}, errors.New(apierr.ErrCustomerBlocked)
+	}
+
+	for _, item := range items {
+		if !shareLinkAllows(*link, item.ProductID) {
+			return response.TempOrderData{}, errors.New(apierr.ErrProductNotFound)
+		}
+
+		product, err := productStore.GetProductByID(ctx, item.ProductID, shop.ID)
+		if err != nil {
+			return response.TempOrderData{}, err
+		}
+		if product == nil {
+			return response.TempOrderData{}, errors.New(apierr.ErrProductNotFound)
+		}
+		if !product.IsActive {
+			return response.TempOrderData{}, errors.New(apierr.ErrProductInactive)
+		}
+	}
+
+	db := dbGetter()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+	defer tx.Rollback()
+
+	fingerprint := tempOrderFingerprint(customerPhone, items)
+	duplicate, err := orderStore.HasRecentTempOrder(ctx, tx, shop.ID, fingerprint, time.Now().Add(-constant.TempOrderDuplicateWindow))
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+	if duplicate {
+		return response.TempOrderData{}, errors.New(apierr.ErrDuplicateTempOrder)
+	}
+
+	tempOrder, err := orderStore.CreateTempOrder(ctx, tx, customerName, customerPhone, shop.ID, &link.ID, fingerprint, address)
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+
+	tempOrderItemsData := make([]response.TempOrderItemData, 0, len(items))
+	totalPrice := 0
+	for _, item := range items {
+		orderItemTemp, err := orderItemStore.CreateTempOrderItem(ctx, tx, tempOrder.ID, item.ProductID, item.Qty)
+		if err != nil {
+			return response.TempOrderData{}, err
+		}
+		tempOrderItemsData = append(tempOrderItemsData, response.TempOrderItemData{
+			ID:          orderItemTemp.ID,
+			TempOrderID: orderItemTemp.TempOrderID,
+			ProductName: orderItemTemp.ProductName,
+			Price:       orderItemTemp.Price,
+			Qty:         orderItemTemp.Qty,
+			CreatedAt:   orderItemTemp.CreatedAt,
+		})
+		totalPrice += item.Qty * orderItemTemp.Price
+	}
+
+	err = orderStore.UpdateTempOrderTotalPrice(ctx, tx, tempOrder.ID, totalPrice)
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return response.TempOrderData{}, err
+	}
+
+	res := response.TempOrderData{
+		ID:              tempOrder.ID,
+		CustomerName:    tempOrder.CustomerName,
+		CustomerPhone:   tempOrder.CustomerPhone,
+		TotalPrice:      tempOrder.TotalPrice,
+		Status:          tempOrder.Status,
+		ShippingAddress: toShippingAddressData(tempOrder.ShippingAddress),
+		TempOrderItems:  tempOrderItemsData,
+		CreatedAt:       tempOrder.CreatedAt,
+	}
+	if tempOrder.ShareLinkID.Valid {
+		id := int(tempOrder.ShareLinkID.Int64)
+		res.ShareLinkID = &id
+	}
+	if tempOrder.UpdatedAt.Valid {
+		res.UpdatedAt = &tempOrder.UpdatedAt.Time
+	}
+	return res, nil
+}
+
+// tempOrderFingerprint identifies a submission by the normalized phone and
+// the items ordered, so a resubmitted form matches however the items were
+// listed.
+func tempOrderFingerprint(customerPhone string, items []CreateTempOrderItemInput) string {
+	qty := make(map[int]int, len(items))
+	for _, item := range items {
+		qty[item.ProductID] += item.Qty
+	}
+	lines := make([]string, 0, len(qty))
+	for productID, n := range qty {
+		lines = append(lines, fmt.Sprintf("%d:%d", productID, n))
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(customerPhone + "|" + strings.Join(lines, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (o *oservice) GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*response.TempOrderData, error) {
+	tempOrder, err := orderStore.GetTempOrderByID(ctx, id, shopID...)
+	if err != nil {
+		return nil, err
+	}
+
+	if tempOrder == nil {
+		return nil, errors.New(apierr.ErrTempOrderNotFound)
+	}
+
+	tempOrderItems, err := orderItemStore.GetTempOrderItemsByTempOrderID(ctx, tempOrder.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tempOrderItemsData := make([]response.TempOrderItemData, 0, len(tempOrderItems))
+	for _, tempOrderItem := range tempOrderItems {
+		tempOrderItemsData = append(tempOrderItemsData, response.TempOrderItemData{
+			ID:          tempOrderItem.ID,
+			TempOrderID: tempOrderItem.TempOrderID,
+			ProductID:   tempOrderItem.ProductID,
+			ProductName: tempOrderItem.ProductName,
+			Price:       tempOrderItem.Price,
+			Qty:         tempOrderItem.Qty,
+			CreatedAt:   tempOrderItem.CreatedAt,
+		})
+	}
+
+	res := response.TempOrderData{
+		ID:              tempOrder.ID,
+		CustomerName:    tempOrder.CustomerName,
+		CustomerPhone:   tempOrder.CustomerPhone,
+		TotalPrice:      tempOrder.TotalPrice,
+		Status:          tempOrder.Status,
+		ShippingAddress: toShippingAddressData(tempOrder.ShippingAddress),
+		TempOrderItems:  tempOrderItemsData,
+		CreatedAt:       tempOrder.CreatedAt,
+	}
+
+	if tempOrder.ShareLinkID.Valid {
+		id := int(tempOrder.ShareLinkID.Int64)
+		res.ShareLinkID = &id
+	}
+
+	if tempOrder.UpdatedAt.Valid {
+		t := tempOrder.UpdatedAt.Time
+		res.UpdatedAt = &t
+	}
+
+	return &res, nil
+}
+
+func (o *oservice) GetTempOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]response.TempOrderData, error) {
+	if err := shopDateRange(ctx, shopID, &opts); err != nil {
+		return []response.TempOrderData{}, err
+	}
+
+	tempOrders, err := orderStore.GetTempOrdersByShopID(ctx, shopID, opts)
+	if err != nil {
+		return []response.TempOrderData{}, err
+	}
+
+	tempOrdersData := make([]response.TempOrderData, 0, len(tempOrders))
+	for _, tempOrder := range tempOrders {
 		res := response.TempOrderData{
 			ID:            tempOrder.ID,
 			CustomerName:  tempOrder.CustomerName,
@@ -630,6 +1477,11 @@ func (o *oservice) GetTempOrdersByShopID(ctx context.Context, shopID int, opts m
 			CreatedAt:     tempOrder.CreatedAt,
 		}
 
+		if tempOrder.ShareLinkID.Valid {
+			id := int(tempOrder.ShareLinkID.Int64)
+			res.ShareLinkID = &id
+		}
+
 		if tempOrder.UpdatedAt.Valid {
 			t := tempOrder.UpdatedAt.Time
 			res.UpdatedAt = &t
@@ -665,43 +1517,71 @@ func (o *oservice) RejectTempOrderByID(ctx context.Context, id int) (response.Te
 	return *tempOrder, nil
 }
 
-func (o *oservice) GenerateOrderInvoice(ctx context.Context, orderID, shopID int, message string) ([]byte, error) {
+func (o *oservice) GenerateOrderInvoice(ctx context.Context, orderID, shopID int, message, lang string) ([]byte, error) {
 	order, err := o.GetOrderByID(ctx, orderID, shopID)
 	if err != nil {
 		return nil, err
 	}
 
-	pdf := fpdf.New("P", "mm", "A4", "")
-	pdf.SetCompression(false)
+	shop, err := shopStore.GetShopByID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if shop == nil {
+		return nil, errors.New(apierr.ErrShopNotFound)
+	}
+
+	invoiceNumber, err := assignInvoiceNumber(ctx, order.ID, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, err := newDocumentPDF("A4", documentText(shop, message, documentOrder{order: order}))
+	if err != nil {
+		return nil, err
+	}
+	renderInvoicePage(pdf, shop, loadShopLogo(ctx, shop.LogoURL), order, invoiceNumber, message, documentLang(shop, lang))
+	return outputPDF(pdf)
+}
+
+// renderInvoicePage adds a page with the invoice for order. Long orders flow
+// onto further pages.
+func renderInvoicePage(pdf *fpdf.Fpdf, shop *model.Shop, logo *shopLogo, order *response.OrderData, invoiceNumber, message, lang string) {
 	pdf.AddPage()
+	drawShopHeader(pdf, shop, logo)
 
-	// Header
-	pdf.SetFont("Arial", "B", 18)
-	pdf.CellFormat(190, 10, "INVOICE", "", 1, "C", false, 0, "")
+	pdf.SetFont(pdffont.Family, "B", 18)
+	pdf.CellFormat(190, 10, i18n.T(lang, "invoice_title"), "", 1, "C", false, 0, "")
 	pdf.Ln(4)
 
 	// Invoice meta
-	pdf.SetFont("Arial", "", 11)
-	pdf.CellFormat(40, 7, "Invoice #:", "", 0, "L", false, 0, "")
-	pdf.CellFormat(150, 7, strconv.Itoa(order.ID), "", 1, "L", false, 0, "")
-	pdf.CellFormat(40, 7, "Date:", "", 0, "L", false, 0, "")
-	pdf.CellFormat(150, 7, order.CreatedAt.Format("02 January 2006"), "", 1, "L", false, 0, "")
-	pdf.CellFormat(40, 7, "Customer:", "", 0, "L", false, 0, "")
+	pdf.SetFont(pdffont.Family, "", 11)
+	pdf.CellFormat(40, 7, i18n.T(lang, "invoice_number"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(150, 7, invoiceNumber, "", 1, "L", false, 0, "")
+	pdf.CellFormat(40, 7, i18n.T(lang, "invoice_date"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(150, 7, formatInvoiceDate(order.CreatedAt.In(loadShopLocation(shop)), lang), "", 1, "L", false, 0, "")
+	pdf.CellFormat(40, 7, i18n.T(lang, "invoice_customer"), "", 0, "L", false, 0, "")
 	pdf.CellFormat(150, 7, order.CustomerName, "", 1, "L", false, 0, "")
 	pdf.Ln(6)
 
 	// Items table header
-	pdf.SetFont("Arial", "B", 11)
+	pdf.SetFont(pdffont.Family, "B", 11)
 	pdf.SetFillColor(230, 230, 230)
-	pdf.CellFormat(80, 8, "Product", "1", 0, "L", true, 0, "")
-	pdf.CellFormat(25, 8, "Qty", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(42, 8, "Price (Rp)", "1", 0, "R", true, 0, "")
-	pdf.CellFormat(43, 8, "Subtotal (Rp)", "1", 1, "R", true, 0, "")
+	pdf.CellFormat(80, 8, i18n.T(lang, "invoice_product"), "1", 0, "L", true, 0, "")
+	pdf.CellFormat(25, 8, i18n.T(lang, "invoice_qty"), "1", 0, "C", true, 0, "")
+	currency := currencySymbol(shop.Currency)
+	pdf.CellFormat(42, 8, fmt.Sprintf(i18n.T(lang, "invoice_price"), currency), "1", 0, "R", true, 0, "")
+	pdf.CellFormat(43, 8, fmt.Sprintf(i18n.T(lang, "invoice_subtotal"), currency), "1", 1, "R", true, 0, "")
 
 	// Items rows
-	pdf.SetFont("Arial", "", 10)
+	pdf.SetFont(pdffont.Family, "", 10)
 	for _, item := range order.OrderItems {
-		pdf.CellFormat(80, 7, item.ProductName, "1", 0, "L", false, 0, "")
+		// qty is what was allocated; units that could not be bought are only noted
+		name := item.ProductName
+		if item.ShortageQty > 0 {
+			name += " (" + fmt.Sprintf(i18n.T(lang, "invoice_out_of_stock"), item.ShortageQty) + ")"
+		}
+		pdf.CellFormat(80, 7, name, "1", 0, "L", false, 0, "")
 		pdf.CellFormat(25, 7, strconv.Itoa(item.Qty), "1", 0, "C", false, 0, "")
 		pdf.CellFormat(42, 7, formatRupiah(item.Price), "1", 0, "R", false, 0, "")
 		pdf.CellFormat(43, 7, formatRupiah(item.Price*item.Qty), "1", 1, "R", false, 0, "")
@@ -709,24 +1589,201 @@ func (o *oservice) GenerateOrderInvoice(ctx context.Context, orderID, shopID int
 
 	// Total row
 	pdf.Ln(2)
-	pdf.SetFont("Arial", "B", 11)
-	pdf.CellFormat(147, 8, "Total", "1", 0, "R", false, 0, "")
+	pdf.SetFont(pdffont.Family, "B", 11)
+	pdf.CellFormat(147, 8, i18n.T(lang, "invoice_total"), "1", 0, "R", false, 0, "")
 	pdf.CellFormat(43, 8, formatRupiah(order.TotalPrice), "1", 1, "R", false, 0, "")
+	if order.UniqueCode > 0 {
+		pdf.SetFont(pdffont.Family, "", 11)
+		pdf.CellFormat(147, 8, i18n.T(lang, "invoice_unique_code"), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(43, 8, strconv.Itoa(order.UniqueCode), "1", 1, "R", false, 0, "")
+		pdf.SetFont(pdffont.Family, "B", 11)
+		pdf.CellFormat(147, 8, i18n.T(lang, "invoice_amount_due"), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(43, 8, formatRupiah(order.AmountDue), "1", 1, "R", false, 0, "")
+	}
+
+	// Bank accounts to transfer to
+	if shop.BankAccounts != "" {
+		pdf.Ln(8)
+		pdf.SetFont(pdffont.Family, "B", 10)
+		pdf.CellFormat(190, 6, i18n.T(lang, "invoice_pay_to"), "", 1, "L", false, 0, "")
+		pdf.SetFont(pdffont.Family, "", 10)
+		pdf.MultiCell(190, 6, shop.BankAccounts, "", "L", false)
+	}
 
-	// Custom message footer
+	// Custom message, or the shop's default one
+	if message == "" {
+		message = shop.InvoiceMessage
+	}
 	if message != "" {
 		pdf.Ln(8)
-		pdf.SetFont("Arial", "I", 10)
+		pdf.SetFont(pdffont.Family, "I", 10)
 		pdf.MultiCell(190, 6, message, "", "L", false)
 	}
 
+	// Shop footer
+	if shop.InvoiceFooter != "" {
+		pdf.Ln(8)
+		pdf.SetFont(pdffont.Family, "", 9)
+		pdf.MultiCell(190, 5, shop.InvoiceFooter, "", "C", false)
+	}
+}
+
+// assignInvoiceNumber returns the order's invoice number. On first export it
+// takes the shop's next sequence value and formats it with the shop's pattern.
+// The order row stays locked until the number is stored, so concurrent exports
+// of the same order agree on it, and the sequence increment rolls back with a
+// failed export so numbers are gap-free.
+func assignInvoiceNumber(ctx context.Context, orderID int, shop *model.Shop) (string, error) {
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	number, err := orderStore.GetInvoiceNumberForUpdate(ctx, tx, orderID)
+	if err != nil {
+		return "", err
+	}
+	if number != "" {
+		return number, nil
+	}
+
+	seq, err := documentSequenceStore.NextValue(ctx, tx, shop.ID, constant.DocumentTypeInvoice)
+	if err != nil {
+		return "", err
+	}
+	pattern := shop.InvoiceNumberPattern
+	if pattern == "" {
+		pattern = constant.DefaultInvoiceNumberPattern
+	}
+	number = formatInvoiceNumber(pattern, time.Now().In(loadShopLocation(shop)), seq)
+	if err := orderStore.SetInvoiceNumber(ctx, tx, orderID, seq, number); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return number, nil
+}
+
+type shopLogo struct {
+	data []byte
+	opts fpdf.ImageOptions
+}
+
+// loadShopLogo reads the shop logo for document headers, or returns nil when
+// there is none. fpdf only embeds JPEG and PNG, and records a bad image as an
+// error on the whole document, so the logo is tried on a scratch document
+// first and skipped when it fails.
+func loadShopLogo(ctx context.Context, logoURL string) *shopLogo {
+	if logoURL == "" {
+		return nil
+	}
+
+	data, err := readUploadedImage(ctx, logoURL)
+	if err != nil {
+		logger.WithError(err).WithField("logo_url", logoURL).Warn("shop_logo_read_error")
+		return nil
+	}
+
+	var opts fpdf.ImageOptions
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		opts.ImageType = "JPG"
+	case "image/png":
+		opts.ImageType = "PNG"
+	default:
+		return nil
+	}
+
+	probe := fpdf.New("P", "mm", "A4", "")
+	probe.RegisterImageOptionsReader("logo", opts, bytes.NewReader(data))
+	if err := probe.Error(); err != nil {
+		logger.WithError(err).WithField("logo_url", logoURL).Warn("shop_logo_decode_error")
+		return nil
+	}
+
+	return &shopLogo{data: data, opts: opts}
+}
+
+// newDocumentPDF starts an uncompressed PDF in the given page size with the
+// Unicode font registered, one that has glyphs for the document's text.
+func newDocumentPDF(size string, text []string) (*fpdf.Fpdf, error) {
+	pdf := fpdf.New("P", "mm", size, "")
+	pdf.SetCompression(false)
+	if err := pdffont.Register(pdf, cfg.PDFFontFile, cfg.PDFFallbackFontFile, text...); err != nil {
+		return nil, err
+	}
+	return pdf, nil
+}
+
+func outputPDF(pdf *fpdf.Fpdf) ([]byte, error) {
 	var buf bytes.Buffer
-	if err = pdf.Output(&buf); err != nil {
+	if err := pdf.Output(&buf); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
+// drawShopHeader prints the logo on the left of an A4 page with the shop name
+// and address beside it.
+func drawShopHeader(pdf *fpdf.Fpdf, shop *model.Shop, logo *shopLogo) {
+	top := pdf.GetY()
+	left := 10.0
+	if logo != nil {
+		pdf.RegisterImageOptionsReader("logo", logo.opts, bytes.NewReader(logo.data))
+		pdf.ImageOptions("logo", 10, top, 0, 20, false, logo.opts, 0, "")
+		left = 45
+	}
+	pdf.SetLeftMargin(left)
+	pdf.SetXY(left, top)
+	pdf.SetFont(pdffont.Family, "B", 14)
+	pdf.CellFormat(200-left, 7, shop.Name, "", 1, "L", false, 0, "")
+	if shop.Address != "" {
+		pdf.SetFont(pdffont.Family, "", 9)
+		pdf.MultiCell(200-left, 5, shop.Address, "", "L", false)
+	}
+	pdf.SetLeftMargin(10)
+	if left > 10 && pdf.GetY() < top+20 {
+		pdf.SetY(top + 20)
+	}
+	pdf.Ln(4)
+}
+
+var indonesianMonths = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+// formatInvoiceDate formats t as "02 January 2006", with Indonesian month
+// names for lang "id".
+func formatInvoiceDate(t time.Time, lang string) string {
+	if lang == "id" {
+		return fmt.Sprintf("%02d %s %d", t.Day(), indonesianMonths[t.Month()-1], t.Year())
+	}
+	return t.Format("02 January 2006")
+}
+
+// documentLang is the language customer-facing documents are printed in: the
+// shop's locale, or lang for a shop without one.
+func documentLang(shop *model.Shop, lang string) string {
+	if shop.Locale != "" {
+		return shop.Locale
+	}
+	return lang
+}
+
+// currencySymbol is how amounts are labelled on invoices: "Rp" for rupiah,
+// the ISO code otherwise.
+func currencySymbol(code string) string {
+	if code == "" || code == constant.DefaultShopCurrency {
+		return "Rp"
+	}
+	return code
+}
+
 // formatRupiah formats integer price with period thousands separator (e.g. 1500000 → "1.500.000")
 func formatRupiah(price int) string {
 	s := strconv.Itoa(price)
@@ -744,12 +1801,295 @@ func formatRupiah(price int) string {
 	return string(result)
 }
 
+// amountDue is what the customer is asked to transfer: the order total plus
+// its unique code. Orders without a code report 0 so the field is omitted.
+func amountDue(totalPrice, uniqueCode int) int {
+	if uniqueCode == 0 {
+		return 0
+	}
+	return totalPrice + uniqueCode
+}
+
+// settleUniqueCode marks an order with a unique code as paid once paid covers
+// the total plus the code. The code is then released for reuse by the shop's
+// other orders, unless the shop keeps it on the order as a fee.
+func settleUniqueCode(ctx context.Context, tx database.Tx, order *model.Order, paid int) error {
+	if order.UniqueCode == 0 || paid < order.TotalPrice+order.UniqueCode {
+		return nil
+	}
+
+	shop, err := shopStore.GetShopByID(ctx, order.ShopID)
+	if err != nil {
+		return err
+	}
+
+	paidStatus := constant.OrderPaymentStatusPaid
+	input := store.UpdateOrderInput{PaymentStatus: &paidStatus}
+	if shop == nil || !shop.UniqueCodeAsFee {
+		released := 0
+		input.UniqueCode = &released
+	}
+
+	_, err = orderStore.UpdateOrder(ctx, tx, order.ID, input)
+	return err
+}
+
+// holdsUniqueCode reports whether an order with the given statuses counts
+// against the shop's unique codes, the scope of the partial unique index on
+// orders.unique_code.
+func holdsUniqueCode(status, paymentStatus string) bool {
+	return status != constant.OrderStatusCancelled && paymentStatus != constant.OrderPaymentStatusPaid
+}
+
+// reclaimUniqueCode must run before an order that still holds a code, e.g. one
+// the shop keeps as a fee, moves from paid or cancelled back into the scope of
+// holdsUniqueCode. Another open order may have been given the code since, so
+// the order keeps it only if it is free and gets a new one otherwise. It
+// reports whether it ran and updates order.UniqueCode in place.
+func reclaimUniqueCode(ctx context.Context, tx database.Tx, order *model.Order, status, paymentStatus string) (bool, error) {
+	if order.UniqueCode == 0 || holdsUniqueCode(order.Status, order.PaymentStatus) || !holdsUniqueCode(status, paymentStatus) {
+		return false, nil
+	}
+
+	code, err := orderStore.ReclaimUniqueCode(ctx, tx, order.ID, order.ShopID)
+	if err != nil {
+		return false, err
+	}
+
+	order.UniqueCode = code
+	return true, nil
+}
+
+// dpAmount is the DP the customer must pay, rounded up to whole rupiah.
+func dpAmount(totalPrice, percent int) int {
+	return (totalPrice*percent + 99) / 100
+}
+
+// dpDueAt is the rule's fixed deadline, or the end of the shop's local day
+// the rule's number of days after the order was created: an order placed on
+// the 15th with 3 days to pay is due by the end of the 18th.
+func dpDueAt(rule *model.DPRule, createdAt time.Time, loc *time.Location) time.Time {
+	if rule.DueDate.Valid {
+		return rule.DueDate.Time
+	}
+	day := localDate(createdAt.In(loc), loc)
+	return day.AddDate(0, 0, int(rule.DueDays.Int64)+1)
+}
+
+// downPaymentStatus derives the payment status of an order from what has been
+// paid so far. An order without items is still awaiting its DP.
+func downPaymentStatus(order *model.Order, paid int) string {
+	due := order.TotalPrice + order.UniqueCode
+	switch {
+	case due > 0 && paid >= due:
+		return constant.OrderPaymentStatusPaid
+	case order.DPPercent == 0:
+		return constant.OrderPaymentStatusOutstanding
+	case order.TotalPrice > 0 && paid >= dpAmount(order.TotalPrice, order.DPPercent):
+		return constant.OrderPaymentStatusDPPaid
+	default:
+		return constant.OrderPaymentStatusAwaitingDP
+	}
+}
+
+// applyDownPayment writes the rule's DP requirement onto the order, or clears
+// it when rule is nil, and updates order in place. Deadlines counted in days
+// end at midnight in loc.
+func applyDownPayment(ctx context.Context, tx database.Tx, order *model.Order, rule *model.DPRule, paid int, loc *time.Location) error {
+	input := store.SetDownPaymentInput{}
+	order.DPPercent = 0
+	order.DPDueAt = sql.NullTime{}
+	order.DPOverdueAt = sql.NullTime{}
+	if rule != nil {
+		dueAt := dpDueAt(rule, order.CreatedAt, loc)
+		input.RuleID = &rule.ID
+		input.Percent = rule.Percent
+		input.DueAt = &dueAt
+		input.AutoCancel = rule.AutoCancel
+		order.DPPercent = rule.Percent
+		order.DPDueAt = sql.NullTime{Time: dueAt, Valid: true}
+	}
+	input.PaymentStatus = downPaymentStatus(order, paid)
+
+	if err := orderStore.SetDownPayment(ctx, tx, order.ID, input); err != nil {
+		return err
+	}
+
+	order.PaymentStatus = input.PaymentStatus
+	return nil
+}
+
+// settleDownPayment moves an order with a DP requirement between awaiting_dp,
+// dp_paid and paid after a payment. Full payment of an order with a unique
+// code is left to settleUniqueCode.
+func settleDownPayment(ctx context.Context, tx database.Tx, order *model.Order, paid int) error {
+	if order.DPPercent == 0 {
+		return nil
+	}
+
+	status := downPaymentStatus(order, paid)
+	if status == order.PaymentStatus || (status == constant.OrderPaymentStatusPaid && order.UniqueCode > 0) {
+		return nil
+	}
+
+	_, err := orderStore.UpdateOrder(ctx, tx, order.ID, store.UpdateOrderInput{PaymentStatus: &status})
+	return err
+}
+
+func setDownPaymentData(res *response.OrderData, order *model.Order) {
+	if order.DPPercent == 0 {
+		return
+	}
+
+	res.DPAmount = dpAmount(order.TotalPrice, order.DPPercent)
+	if order.DPDueAt.Valid {
+		t := order.DPDueAt.Time
+		res.DPDueAt = &t
+	}
+	res.DPOverdue = order.DPOverdueAt.Valid && order.PaymentStatus == constant.OrderPaymentStatusAwaitingDP
+}
+
+// refundOrder books the refund as a negative payment so paid totals and
+// revenue stay net, and records its reason, method and proof. A refund to
+// credit is added to the customer's credit balance instead of paid out. The
+// order is marked refunded once nothing paid is left on it.
+func refundOrder(ctx context.Context, tx database.Tx, order *model.Order, input RefundOrderInput) (*model.OrderRefund, error) {
+	paid, err := sumOrderPayments(ctx, tx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Amount <= 0 || input.Amount > paid {
+		return nil, errors.New(apierr.ErrRefundAmountInvalid)
+	}
+
+	payment, err := orderPaymentStore.CreateOrderPayment(ctx, tx, order.ID, -input.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Method == constant.RefundMethodCredit {
+		_, err = customerCreditStore.CreateCustomerCredit(ctx, tx, store.CreateCustomerCreditInput{
+			ShopID:     order.ShopID,
+			CustomerID: order.CustomerID,
+			OrderID:    &order.ID,
+			PaymentID:  &payment.ID,
+			Type:       constant.CustomerCreditTypeRefund,
+			Amount:     input.Amount,
+			Notes:      input.Notes,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	refund, err := orderRefundStore.CreateOrderRefund(ctx, tx, store.CreateOrderRefundInput{
+		OrderID:   order.ID,
+		PaymentID: payment.ID,
+		Amount:    input.Amount,
+		Method:    input.Method,
+		Reason:    input.Reason,
+		ProofURL:  input.ProofURL,
+		Notes:     input.Notes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A cancelled order keeps its payment status until everything is refunded;
+	// an open order goes back to what the remaining payments cover.
+	remaining := paid - input.Amount
+	status := order.PaymentStatus
+	if remaining == 0 {
+		status = constant.OrderPaymentStatusRefunded
+	} else if order.Status != constant.OrderStatusCancelled {
+		status = downPaymentStatus(order, remaining)
+	}
+
+	reclaimed, err := reclaimUniqueCode(ctx, tx, order, order.Status, status)
+	if err != nil {
+		return nil, err
+	}
+	if reclaimed && remaining > 0 {
+		status = downPaymentStatus(order, remaining)
+	}
+
+	if status != order.PaymentStatus {
+		if _, err := orderStore.UpdateOrder(ctx, tx, order.ID, store.UpdateOrderInput{PaymentStatus: &status}); err != nil {
+			return nil, err
+		}
+		order.PaymentStatus = status
+	}
+
+	return refund, nil
+}
+
+func setCancelData(res *response.OrderData, order *model.Order) {
+	if !order.CancelReason.Valid {
+		return
+	}
+
+	res.CancelReason = order.CancelReason.String
+	res.CancelNotes = order.CancelNotes
+	if order.CancelledAt.Valid {
+		t := order.CancelledAt.Time
+		res.CancelledAt = &t
+	}
+}
+
+func toOrderRefundData(refund model.OrderRefund) response.OrderRefundData {
+	return response.OrderRefundData{
+		ID:        refund.ID,
+		OrderID:   refund.OrderID,
+		PaymentID: refund.PaymentID,
+		Amount:    refund.Amount,
+		Method:    refund.Method,
+		Reason:    refund.Reason,
+		ProofURL:  refund.ProofURL,
+		Notes:     refund.Notes,
+		CreatedAt: refund.CreatedAt,
+	}
+}
+
+func sumOrderPayments(ctx context.Context, tx database.Tx, orderID int) (int, error) {
+	payments, err := orderPaymentStore.GetOrderPaymentsByOrderID(ctx, tx, orderID)
+	if err != nil {
+		return 0, err
+	}
+
+	paid := 0
+	for _, p := range payments {
+		paid += p.Amount
+	}
+
+	return paid, nil
+}
+
 func (o *oservice) createOrderFromTempOrder(ctx context.Context, tempOrderID, customerID, shopID int) (*response.OrderData, error) {
 	tempOrder, err := o.GetTempOrderByID(ctx, tempOrderID, shopID)
 	if err != nil {
 		return nil, err
 	}
 
+	shop, err := shopStore.GetShopByID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := dpRuleStore.GetDefaultDPRuleByShopID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the address the buyer typed on the form wins over the address book
+	address := fromShippingAddressData(tempOrder.ShippingAddress)
+	if address == nil {
+		address, err = customerShippingAddress(ctx, customerID, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	db := dbGetter()
 	tx, err := db.Begin()
 	if err != nil {
@@ -762,6 +2102,26 @@ func (o *oservice) createOrderFromTempOrder(ctx context.Context, tempOrderID, cu
 		return nil, err
 	}
 
+	if shop != nil && shop.UniqueCodeEnabled {
+		order.UniqueCode, err = orderStore.AssignUniqueCode(ctx, tx, order.ID, shopID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule != nil {
+		if err = applyDownPayment(ctx, tx, order, rule, 0, loadShopLocation(shop)); err != nil {
+			return nil, err
+		}
+	}
+
+	if address != nil {
+		if err = orderStore.SetShippingAddress(ctx, tx, order.ID, address); err != nil {
+			return nil, err
+		}
+		order.ShippingAddress = address
+	}
+
 	orderItems := make([]response.OrderItemData, 0, len(tempOrder.TempOrderItems))
 	for _, tempOrderItem := range tempOrder.TempOrderItems {
 		orderItem, err := orderItemStore.CreateOrderItem(ctx, tx, order.ID, tempOrderItem.ProductID, tempOrderItem.Qty)
@@ -788,16 +2148,22 @@ func (o *oservice) createOrderFromTempOrder(ctx context.Context, tempOrderID, cu
 		return nil, err
 	}
 
-	return &response.OrderData{
-		ID:            order.ID,
-		CustomerName:  order.CustomerName,
-		TotalPrice:    order.TotalPrice,
-		Status:        order.Status,
-		PaymentStatus: order.PaymentStatus,
-		Notes:         order.Notes,
-		OrderItems:    orderItems,
-		CreatedAt:     order.CreatedAt,
-	}, nil
+	res := &response.OrderData{
+		ID:              order.ID,
+		CustomerName:    order.CustomerName,
+		TotalPrice:      order.TotalPrice,
+		UniqueCode:      order.UniqueCode,
+		AmountDue:       amountDue(order.TotalPrice, order.UniqueCode),
+		Status:          order.Status,
+		PaymentStatus:   order.PaymentStatus,
+		Notes:           order.Notes,
+		ShippingAddress: toShippingAddressData(order.ShippingAddress),
+		OrderItems:      orderItems,
+		CreatedAt:       order.CreatedAt,
+	}
+	setDownPaymentData(res, order)
+
+	return res, nil
 }
 
 func (o *oservice) resolveActiveOrderConflict(ctx context.Context, tempOrderID, shopID, activeOrderID int) (*response.OrderData, error) {
@@ -843,6 +2209,16 @@ func (o *oservice) resolveActiveOrderConflict(ctx context.Context, tempOrderID,
 		}
 	}
 
+	// an address already on the order was chosen by the seller; keep it
+	shippingAddress := activeOrder.ShippingAddress
+	if shippingAddress == nil && tempOrder.ShippingAddress != nil {
+		err = orderStore.SetShippingAddress(ctx, tx, activeOrder.ID, fromShippingAddressData(tempOrder.ShippingAddress))
+		if err != nil {
+			return nil, err
+		}
+		shippingAddress = tempOrder.ShippingAddress
+	}
+
 	err = orderStore.UpdateTempOrderStatus(ctx, tx, tempOrder.ID, constant.TempOrderStatusAccepted)
 	if err != nil {
 		return nil, err
@@ -864,13 +2240,86 @@ func (o *oservice) resolveActiveOrderConflict(ctx context.Context, tempOrderID,
 	}
 
 	return &response.OrderData{
-		ID:            activeOrder.ID,
-		CustomerName:  activeOrder.CustomerName,
-		TotalPrice:    totalPrice,
-		Status:        activeOrder.Status,
-		PaymentStatus: activeOrder.PaymentStatus,
-		Notes:         activeOrder.Notes,
-		OrderItems:    orderItems,
-		CreatedAt:     activeOrder.CreatedAt,
+		ID:              activeOrder.ID,
+		CustomerName:    activeOrder.CustomerName,
+		TotalPrice:      totalPrice,
+		UniqueCode:      activeOrder.UniqueCode,
+		AmountDue:       amountDue(totalPrice, activeOrder.UniqueCode),
+		Status:          activeOrder.Status,
+		PaymentStatus:   activeOrder.PaymentStatus,
+		Notes:           activeOrder.Notes,
+		ShippingAddress: shippingAddress,
+		OrderItems:      orderItems,
+		CreatedAt:       activeOrder.CreatedAt,
+	}, nil
+}
+
+// customerShippingAddress returns the address book entry to copy onto an
+// order: the one given, or the customer's default when addressID is nil.
+// It returns nil when the customer has no default address.
+func customerShippingAddress(ctx context.Context, customerID int, addressID *int) (*model.ShippingAddress, error) {
+	var address *model.CustomerAddress
+	var err error
+	if addressID != nil {
+		address, err = customerAddressStore.GetCustomerAddressByID(ctx, *addressID, customerID)
+		if err != nil {
+			return nil, err
+		}
+		if address == nil {
+			return nil, errors.New(apierr.ErrCustomerAddressNotFound)
+		}
+	} else {
+		address, err = customerAddressStore.GetDefaultCustomerAddress(ctx, customerID)
+		if err != nil || address == nil {
+			return nil, err
+		}
+	}
+
+	return &model.ShippingAddress{
+		AddressID:  &address.ID,
+		Label:      address.Label,
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Address:    address.Address,
+		Province:   address.Province,
+		City:       address.City,
+		District:   address.District,
+		PostalCode: address.PostalCode,
 	}, nil
 }
+
+func toShippingAddressData(address *model.ShippingAddress) *response.ShippingAddressData {
+	if address == nil {
+		return nil
+	}
+
+	return &response.ShippingAddressData{
+		AddressID:  address.AddressID,
+		Label:      address.Label,
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Address:    address.Address,
+		Province:   address.Province,
+		City:       address.City,
+		District:   address.District,
+		PostalCode: address.PostalCode,
+	}
+}
+
+func fromShippingAddressData(address *response.ShippingAddressData) *model.ShippingAddress {
+	if address == nil {
+		return nil
+	}
+
+	return &model.ShippingAddress{
+		AddressID:  address.AddressID,
+		Label:      address.Label,
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Address:    address.Address,
+		Province:   address.Province,
+		City:       address.City,
+		District:   address.District,
+		PostalCode: address.PostalCode,
+	}
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/i18n"
+	"github.com/zeirash/recapo/arion/common/pdffont"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	// GenerateOrderDocumentsInput selects orders either by OrderIDs or, when
+	// none are given, by Filter.
+	GenerateOrderDocumentsInput struct {
+		ShopID   int
+		OrderIDs []int
+		Filter   *model.OrderFilterOptions
+		Type     string
+		Format   string // defaults to pdf
+		Message  string // printed on invoices
+		Lang     string
+	}
+
+	OrderDocuments struct {
+		Filename    string
+		ContentType string
+		Content     []byte
+	}
+
+	// documentOrder is what a document page needs about one order.
+	documentOrder struct {
+		order    *response.OrderData
+		customer *model.Customer // nil when the customer was deleted
+	}
+)
+
+var documentFilePrefixes = map[string]string{
+	constant.DocumentTypeInvoice:     "invoice",
+	constant.DocumentTypePackingSlip: "packing-slip",
+	constant.DocumentTypeLabel:       "label",
+}
+
+func (o *oservice) GenerateOrderDocuments(ctx context.Context, input GenerateOrderDocumentsInput) (OrderDocuments, error) {
+	prefix, ok := documentFilePrefixes[input.Type]
+	if !ok {
+		return OrderDocuments{}, errors.New(apierr.ErrDocumentTypeInvalid)
+	}
+	format := input.Format
+	if format == "" {
+		format = constant.DocumentFormatPDF
+	}
+	if format != constant.DocumentFormatPDF && format != constant.DocumentFormatZIP {
+		return OrderDocuments{}, errors.New(apierr.ErrDocumentFormatInvalid)
+	}
+
+	orders, err := selectDocumentOrders(ctx, input)
+	if err != nil {
+		return OrderDocuments{}, err
+	}
+
+	shop, err := shopStore.GetShopByID(ctx, input.ShopID)
+	if err != nil {
+		return OrderDocuments{}, err
+	}
+	if shop == nil {
+		return OrderDocuments{}, errors.New(apierr.ErrShopNotFound)
+	}
+
+	docs := make([]documentOrder, 0, len(orders))
+	for i := range orders {
+		data, err := orderDetailData(ctx, &orders[i])
+		if err != nil {
+			return OrderDocuments{}, err
+		}
+		doc := documentOrder{order: data}
+		if input.Type != constant.DocumentTypeInvoice {
+			doc.customer, err = customerStore.GetCustomerByID(ctx, orders[i].CustomerID, input.ShopID)
+			if err != nil {
+				return OrderDocuments{}, err
+			}
+		}
+		docs = append(docs, doc)
+	}
+
+	// invoice numbers are assigned before rendering so a failed render does
+	// not leave half a batch without numbers
+	invoiceNumbers := make([]string, len(docs))
+	if input.Type == constant.DocumentTypeInvoice {
+		for i, doc := range docs {
+			invoiceNumbers[i], err = assignInvoiceNumber(ctx, doc.order.ID, shop)
+			if err != nil {
+				return OrderDocuments{}, err
+			}
+		}
+	}
+
+	logo := loadShopLogo(ctx, shop.LogoURL)
+	lang := documentLang(shop, input.Lang)
+	render := func(pdf *fpdf.Fpdf, i int) {
+		switch input.Type {
+		case constant.DocumentTypeInvoice:
+			renderInvoicePage(pdf, shop, logo, docs[i].order, invoiceNumbers[i], input.Message, lang)
+		case constant.DocumentTypePackingSlip:
+			renderPackingSlipPage(pdf, shop, logo, docs[i], lang)
+		case constant.DocumentTypeLabel:
+			renderLabelPage(pdf, shop, docs[i], lang)
+		}
+	}
+	size := "A4"
+	if input.Type == constant.DocumentTypeLabel {
+		size = "A6"
+	}
+
+	if format == constant.DocumentFormatPDF {
+		pdf, err := newDocumentPDF(size, documentText(shop, input.Message, docs...))
+		if err != nil {
+			return OrderDocuments{}, err
+		}
+		for i := range docs {
+			render(pdf, i)
+		}
+		content, err := outputPDF(pdf)
+		if err != nil {
+			return OrderDocuments{}, err
+		}
+		return OrderDocuments{
+			Filename:    prefix + "s.pdf",
+			ContentType: "application/pdf",
+			Content:     content,
+		}, nil
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, doc := range docs {
+		pdf, err := newDocumentPDF(size, documentText(shop, input.Message, doc))
+		if err != nil {
+			return OrderDocuments{}, err
+		}
+		render(pdf, i)
+		content, err := outputPDF(pdf)
+		if err != nil {
+			return OrderDocuments{}, err
+		}
+		w, err := zw.Create(fmt.Sprintf("%s-%d.pdf", prefix, doc.order.ID))
+		if err != nil {
+			return OrderDocuments{}, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return OrderDocuments{}, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return OrderDocuments{}, err
+	}
+
+	return OrderDocuments{
+		Filename:    prefix + "s.zip",
+		ContentType: "application/zip",
+		Content:     buf.Bytes(),
+	}, nil
+}
+
+// selectDocumentOrders loads the orders named in input.OrderIDs, in the given
+// order, or the orders matching input.Filter.
+func selectDocumentOrders(ctx context.Context, input GenerateOrderDocumentsInput) ([]model.Order, error) {
+	if len(input.OrderIDs) == 0 {
+		if input.Filter == nil {
+			return nil, errors.New(apierr.ErrNoOrdersSelected)
+		}
+		filter := *input.Filter
+		if err := shopDateRange(ctx, input.ShopID, &filter); err != nil {
+			return nil, err
+		}
+		orders, err := orderStore.GetOrdersByShopID(ctx, input.ShopID, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(orders) == 0 {
+			return nil, errors.New(apierr.ErrNoOrdersSelected)
+		}
+		if len(orders) > constant.MaxDocumentOrders {
+			return nil, errors.New(apierr.ErrTooManyOrders)
+		}
+		return orders, nil
+	}
+
+	seen := make(map[int]bool, len(input.OrderIDs))
+	ids := make([]int, 0, len(input.OrderIDs))
+	for _, id := range input.OrderIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > constant.MaxDocumentOrders {
+		return nil, errors.New(apierr.ErrTooManyOrders)
+	}
+
+	orders := make([]model.Order, 0, len(ids))
+	for _, id := range ids {
+		order, err := orderStore.GetOrderByID(ctx, id, input.ShopID)
+		if err != nil {
+			return nil, err
+		}
+		if order == nil {
+			return nil, errors.New(apierr.ErrOrderNotFound)
+		}
+		orders = append(orders, *order)
+	}
+	return orders, nil
+}
+
+// renderPackingSlipPage adds a page listing what goes into the parcel for
+// doc, with a column to tick items off while packing. Prices are left out.
+func renderPackingSlipPage(pdf *fpdf.Fpdf, shop *model.Shop, logo *shopLogo, doc documentOrder, lang string) {
+	order := doc.order
+	pdf.AddPage()
+	drawShopHeader(pdf, shop, logo)
+
+	pdf.SetFont(pdffont.Family, "B", 18)
+	pdf.CellFormat(190, 10, i18n.T(lang, "packing_title"), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont(pdffont.Family, "", 11)
+	pdf.CellFormat(40, 7, i18n.T(lang, "doc_order_number"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(150, 7, strconv.Itoa(order.ID), "", 1, "L", false, 0, "")
+	pdf.CellFormat(40, 7, i18n.T(lang, "invoice_date"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(150, 7, formatInvoiceDate(order.CreatedAt.In(loadShopLocation(shop)), lang), "", 1, "L", false, 0, "")
+	pdf.CellFormat(40, 7, i18n.T(lang, "invoice_customer"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(150, 7, order.CustomerName, "", 1, "L", false, 0, "")
+	recipient, phone, address := doc.shipTo()
+	if recipient != order.CustomerName {
+		pdf.CellFormat(40, 7, i18n.T(lang, "doc_recipient"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(150, 7, recipient, "", 1, "L", false, 0, "")
+	}
+	if phone != "" {
+		pdf.CellFormat(40, 7, i18n.T(lang, "doc_phone"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(150, 7, phone, "", 1, "L", false, 0, "")
+	}
+	if address != "" {
+		pdf.CellFormat(40, 7, i18n.T(lang, "doc_address"), "", 0, "L", false, 0, "")
+		pdf.SetLeftMargin(50)
+		pdf.MultiCell(150, 7, address, "", "L", false)
+		pdf.SetLeftMargin(10)
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont(pdffont.Family, "B", 11)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(12, 8, i18n.T(lang, "packing_no"), "1", 0, "C", true, 0, "")
+	pdf.CellFormat(118, 8, i18n.T(lang, "invoice_product"), "1", 0, "L", true, 0, "")
+	pdf.CellFormat(25, 8, i18n.T(lang, "invoice_qty"), "1", 0, "C", true, 0, "")
+	pdf.CellFormat(35, 8, i18n.T(lang, "packing_packed"), "1", 1, "C", true, 0, "")
+
+	pdf.SetFont(pdffont.Family, "", 10)
+	for i, item := range order.OrderItems {
+		name := item.ProductName
+		if item.ShortageQty > 0 {
+			name += " (" + fmt.Sprintf(i18n.T(lang, "invoice_out_of_stock"), item.ShortageQty) + ")"
+		}
+		pdf.CellFormat(12, 7, strconv.Itoa(i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(118, 7, name, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(25, 7, strconv.Itoa(item.Qty), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(35, 7, "", "1", 1, "C", false, 0, "")
+	}
+
+	if order.Notes != "" {
+		pdf.Ln(8)
+		pdf.SetFont(pdffont.Family, "B", 10)
+		pdf.CellFormat(190, 6, i18n.T(lang, "doc_notes"), "", 1, "L", false, 0, "")
+		pdf.SetFont(pdffont.Family, "", 10)
+		pdf.MultiCell(190, 6, order.Notes, "", "L", false)
+	}
+}
+
+// renderLabelPage adds an A6 shipping label for doc: recipient in large type,
+// the shop as sender, and the order number with the item count so the parcel
+// can be matched to its packing slip.
+func renderLabelPage(pdf *fpdf.Fpdf, shop *model.Shop, doc documentOrder, lang string) {
+	const margin, width = 6.0, 93.0
+
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.AddPage()
+
+	pdf.SetFont(pdffont.Family, "", 9)
+	pdf.CellFormat(width, 5, i18n.T(lang, "label_to"), "", 1, "L", false, 0, "")
+	recipient, phone, address := doc.shipTo()
+	pdf.SetFont(pdffont.Family, "B", 16)
+	pdf.MultiCell(width, 8, recipient, "", "L", false)
+	pdf.SetFont(pdffont.Family, "", 11)
+	if phone != "" {
+		pdf.CellFormat(width, 6, phone, "", 1, "L", false, 0, "")
+	}
+	if address != "" {
+		pdf.MultiCell(width, 6, address, "", "L", false)
+	}
+
+	pdf.Ln(4)
+	y := pdf.GetY()
+	pdf.Line(margin, y, margin+width, y)
+	pdf.Ln(4)
+
+	pdf.SetFont(pdffont.Family, "", 9)
+	pdf.CellFormat(width, 5, i18n.T(lang, "label_from"), "", 1, "L", false, 0, "")
+	pdf.SetFont(pdffont.Family, "B", 11)
+	pdf.CellFormat(width, 6, shop.Name, "", 1, "L", false, 0, "")
+	if shop.Address != "" {
+		pdf.SetFont(pdffont.Family, "", 9)
+		pdf.MultiCell(width, 5, shop.Address, "", "L", false)
+	}
+
+	qty := 0
+	for _, item := range doc.order.OrderItems {
+		qty += item.Qty
+	}
+	pdf.Ln(4)
+	pdf.SetFont(pdffont.Family, "", 9)
+	pdf.CellFormat(width/2, 5, i18n.T(lang, "doc_order_number")+" "+strconv.Itoa(doc.order.ID), "", 0, "L", false, 0, "")
+	pdf.CellFormat(width/2, 5, fmt.Sprintf(i18n.T(lang, "label_items"), qty), "", 1, "R", false, 0, "")
+}
+
+// shipTo returns who and where the parcel goes: the address copied onto the
+// order when it has one, otherwise the customer's own phone and address.
+func (doc documentOrder) shipTo() (recipient, phone, address string) {
+	if a := doc.order.ShippingAddress; a != nil {
+		return a.Recipient, a.Phone, formatShippingAddress(a)
+	}
+
+	recipient = doc.order.CustomerName
+	if doc.customer != nil {
+		phone, address = doc.customer.Phone, doc.customer.Address
+	}
+	return recipient, phone, address
+}
+
+// documentText returns the text documents for docs print that shops and
+// customers wrote, which the font has to have glyphs for.
+func documentText(shop *model.Shop, message string, docs ...documentOrder) []string {
+	text := []string{shop.Name, shop.Address, shop.BankAccounts, shop.InvoiceMessage, shop.InvoiceFooter, message}
+	for _, doc := range docs {
+		recipient, phone, address := doc.shipTo()
+		text = append(text, doc.order.CustomerName, doc.order.Notes, recipient, phone, address)
+		for _, item := range doc.order.OrderItems {
+			text = append(text, item.ProductName)
+		}
+	}
+	return text
+}
+
+// formatShippingAddress writes the address the way couriers expect it:
+// street, then district, city and province, with the postal code last.
+func formatShippingAddress(a *response.ShippingAddressData) string {
+	parts := []string{}
+	for _, part := range []string{a.Address, a.District, a.City, a.Province} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	res := strings.Join(parts, ", ")
+	if a.PostalCode != "" {
+		res += " " + a.PostalCode
+	}
+	return res
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_oservice_GenerateOrderDocuments(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	shop := &model.Shop{ID: 1, Name: "Toko Jastip", Address: "Jl. Melati 5, Bandung"}
+	pending := "created"
+
+	type mocks struct {
+		order    *mock_store.MockOrderStore
+		item     *mock_store.MockOrderItemStore
+		payment  *mock_store.MockOrderPaymentStore
+		customer *mock_store.MockCustomerStore
+		shop     *mock_store.MockShopStore
+		db       *mock_database.MockDB
+		tx       *mock_database.MockTx
+	}
+
+	order1 := model.Order{ID: 1, CustomerID: 10, CustomerName: "John Doe", TotalPrice: 15000, Notes: "Wrap as a gift", CreatedAt: fixedTime}
+	order2 := model.Order{ID: 2, CustomerID: 11, CustomerName: "Jane Doe", TotalPrice: 5000, CreatedAt: fixedTime}
+
+	// expectDetails sets up the item and payment lookups for order.
+	expectDetails := func(m mocks, order model.Order, items []model.OrderItem) {
+		m.item.EXPECT().GetOrderItemsByOrderID(gomock.Any(), order.ID).Return(items, nil)
+		m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), order.ID).Return([]model.OrderPayment{}, nil)
+	}
+	items1 := []model.OrderItem{
+		{ID: 1, OrderID: 1, ProductName: "Product A", Price: 10000, Qty: 2, CreatedAt: fixedTime},
+		{ID: 2, OrderID: 1, ProductName: "Product B", Price: 5000, Qty: 1, ShortageQty: 1, CreatedAt: fixedTime},
+	}
+	items2 := []model.OrderItem{
+		{ID: 3, OrderID: 2, ProductName: "Product C", Price: 5000, Qty: 1, CreatedAt: fixedTime},
+	}
+
+	tooMany := make([]int, constant.MaxDocumentOrders+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	tests := []struct {
+		name            string
+		input           GenerateOrderDocumentsInput
+		mockSetup       func(m mocks)
+		wantFilename    string
+		wantContentType string
+		wantFiles       map[string][]string // zip entry name -> text it must contain
+		wantContains    []string            // text that must appear in the PDF
+		wantAbsent      []string            // text that must NOT appear in the PDF
+		wantA6          bool
+		wantErrMsg      string
+	}{
+		{
+			name:  "packing slips for selected orders in one PDF",
+			input: GenerateOrderDocumentsInput{ShopID: 1, OrderIDs: []int{1, 2, 1}, Type: constant.DocumentTypePackingSlip, Lang: "en"},
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 1, 1).Return(&order1, nil)
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 2, 1).Return(&order2, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(shop, nil)
+				expectDetails(m, order1, items1)
+				m.customer.EXPECT().GetCustomerByID(gomock.Any(), 10, 1).Return(&model.Customer{ID: 10, Name: "John Doe", Phone: "08123456789", Address: "Jl. Mawar 1, Jakarta"}, nil)
+				expectDetails(m, order2, items2)
+				m.customer.EXPECT().GetCustomerByID(gomock.Any(), 11, 1).Return(nil, nil)
+			},
+			wantFilename:    "packing-slips.pdf",
+			wantContentType: "application/pdf",
+			wantContains: []string{
+				"Toko Jastip",
+				"PACKING LIST",
+				"John Doe",
+				"08123456789",
+				"Jl. Mawar 1, Jakarta",
+				"Product A",
+				"Product B (1 out of stock)",
+				"Wrap as a gift",
+				"Jane Doe",
+				"Product C",
+				"Packed",
+			},
+			wantAbsent: []string{"10.000", "INVOICE"},
+		},
+		{
+			name: "labels for filtered orders as a ZIP",
+			input: GenerateOrderDocumentsInput{
+				ShopID: 1,
+				Filter: &model.OrderFilterOptions{Status: []string{pending}},
+				Type:   constant.DocumentTypeLabel,
+				Format: constant.DocumentFormatZIP,
+				Lang:   "id",
+			},
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrdersByShopID(gomock.Any(), 1, model.OrderFilterOptions{Status: []string{pending}}).Return([]model.Order{order1, order2}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(shop, nil)
+				expectDetails(m, order1, items1)
+				m.customer.EXPECT().GetCustomerByID(gomock.Any(), 10, 1).Return(&model.Customer{ID: 10, Name: "John Doe", Phone: "08123456789", Address: "Jl. Mawar 1, Jakarta"}, nil)
+				expectDetails(m, order2, items2)
+				m.customer.EXPECT().GetCustomerByID(gomock.Any(), 11, 1).Return(nil, nil)
+			},
+			wantFilename:    "labels.zip",
+			wantContentType: "application/zip",
+			wantFiles: map[string][]string{
+				"label-1.pdf": {"Kepada", "John Doe", "08123456789", "Jl. Mawar 1, Jakarta", "Pengirim", "Toko Jastip", "3 barang"},
+				"label-2.pdf": {"Jane Doe", "1 barang"},
+			},
+			wantA6: true,
+		},
+		{
+			name:  "invoices reuse assigned numbers",
+			input: GenerateOrderDocumentsInput{ShopID: 1, OrderIDs: []int{1, 2}, Type: constant.DocumentTypeInvoice, Message: "Thank you!", Lang: "en"},
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 1, 1).Return(&order1, nil)
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 2, 1).Return(&order2, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(shop, nil)
+				expectDetails(m, order1, items1)
+				expectDetails(m, order2, items2)
+				m.db.EXPECT().Begin().Return(m.tx, nil).Times(2)
+				m.order.EXPECT().GetInvoiceNumberForUpdate(gomock.Any(), m.tx, 1).Return("INV/2024/01/1", nil)
+				m.order.EXPECT().GetInvoiceNumberForUpdate(gomock.Any(), m.tx, 2).Return("INV/2024/01/2", nil)
+				m.tx.EXPECT().Rollback().Return(nil).Times(2)
+			},
+			wantFilename:    "invoices.pdf",
+			wantContentType: "application/pdf",
+			wantContains:    []string{"INVOICE", "INV/2024/01/1", "INV/2024/01/2", "John Doe", "Jane Doe", "Thank you!"},
+		},
+		{
+			name:       "returns error on unknown document type",
+			input:      GenerateOrderDocumentsInput{ShopID: 1, OrderIDs: []int{1}, Type: "receipt"},
+			mockSetup:  func(m mocks) {},
+			wantErrMsg: apierr.ErrDocumentTypeInvalid,
+		},
+		{
+			name:       "returns error on unknown format",
+			input:      GenerateOrderDocumentsInput{ShopID: 1, OrderIDs: []int{1}, Type: constant.DocumentTypeLabel, Format: "docx"},
+			mockSetup:  func(m mocks) {},
+			wantErrMsg: apierr.ErrDocumentFormatInvalid,
+		},
+		{
+			name:       "returns error when neither ids nor filter are given",
+			input:      GenerateOrderDocumentsInput{ShopID: 1, Type: constant.DocumentTypeLabel},
+			mockSetup:  func(m mocks) {},
+			wantErrMsg: apierr.ErrNoOrdersSelected,
+		},
+		{
+			name:  "returns error when the filter matches nothing",
+			input: GenerateOrderDocumentsInput{ShopID: 1, Filter: &model.OrderFilterOptions{}, Type: constant.DocumentTypeLabel},
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrdersByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return([]model.Order{}, nil)
+			},
+			wantErrMsg: apierr.ErrNoOrdersSelected,
+		},
+		{
+			name:       "returns error on too many orders",
+			input:      GenerateOrderDocumentsInput{ShopID: 1, OrderIDs: tooMany, Type: constant.DocumentTypeLabel},
+			mockSetup:  func(m mocks) {},
+			wantErrMsg: apierr.ErrTooManyOrders,
+		},
+		{
+			name:  "returns error when an order is not in the shop",
+			input: GenerateOrderDocumentsInput{ShopID: 1, OrderIDs: []int{1, 999}, Type: constant.DocumentTypeLabel},
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 1, 1).Return(&order1, nil)
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 999, 1).Return(nil, nil)
+			},
+			wantErrMsg: apierr.ErrOrderNotFound,
+		},
+		{
+			name:  "returns error when shop not found",
+			input: GenerateOrderDocumentsInput{ShopID: 1, OrderIDs: []int{1}, Type: constant.DocumentTypeLabel},
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 1, 1).Return(&order1, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(nil, nil)
+			},
+			wantErrMsg: apierr.ErrShopNotFound,
+		},
+		{
+			name:  "returns error when store fails",
+			input: GenerateOrderDocumentsInput{ShopID: 1, Filter: &model.OrderFilterOptions{}, Type: constant.DocumentTypeLabel},
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrdersByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(nil, errors.New("database error"))
+			},
+			wantErrMsg: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldOrderItemStore, oldOrderPaymentStore := orderStore, orderItemStore, orderPaymentStore
+			oldCustomerStore, oldShopStore, oldDBGetter := customerStore, shopStore, dbGetter
+			defer func() {
+				orderStore, orderItemStore, orderPaymentStore = oldOrderStore, oldOrderItemStore, oldOrderPaymentStore
+				customerStore, shopStore, dbGetter = oldCustomerStore, oldShopStore, oldDBGetter
+			}()
+
+			m := mocks{
+				order:    mock_store.NewMockOrderStore(ctrl),
+				item:     mock_store.NewMockOrderItemStore(ctrl),
+				payment:  mock_store.NewMockOrderPaymentStore(ctrl),
+				customer: mock_store.NewMockCustomerStore(ctrl),
+				shop:     mock_store.NewMockShopStore(ctrl),
+				db:       mock_database.NewMockDB(ctrl),
+				tx:       mock_database.NewMockTx(ctrl),
+			}
+			tt.mockSetup(m)
+			orderStore = m.order
+			orderItemStore = m.item
+			orderPaymentStore = m.payment
+			customerStore = m.customer
+			shopStore = m.shop
+			dbGetter = func() database.DB { return m.db }
+
+			var o oservice
+			got, gotErr := o.GenerateOrderDocuments(context.Background(), tt.input)
+
+			if gotErr != nil {
+				if tt.wantErrMsg == "" || !strings.Contains(gotErr.Error(), tt.wantErrMsg) {
+					t.Errorf("GenerateOrderDocuments() error = %v, want %q", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if tt.wantErrMsg != "" {
+				t.Fatal("GenerateOrderDocuments() succeeded unexpectedly")
+			}
+			if got.Filename != tt.wantFilename || got.ContentType != tt.wantContentType {
+				t.Errorf("GenerateOrderDocuments() = %q (%s), want %q (%s)", got.Filename, got.ContentType, tt.wantFilename, tt.wantContentType)
+			}
+
+			if tt.wantFiles == nil {
+				if !strings.HasPrefix(string(got.Content), "%PDF") {
+					t.Fatal("GenerateOrderDocuments() output is not a PDF")
+				}
+				for _, want := range tt.wantContains {
+					if !strings.Contains(string(got.Content), pdfText(want)) {
+						t.Errorf("GenerateOrderDocuments() PDF missing expected content %q", want)
+					}
+				}
+				for _, absent := range tt.wantAbsent {
+					if strings.Contains(string(got.Content), pdfText(absent)) {
+						t.Errorf("GenerateOrderDocuments() PDF contains unexpected content %q", absent)
+					}
+				}
+				return
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(got.Content), int64(len(got.Content)))
+			if err != nil {
+				t.Fatalf("GenerateOrderDocuments() output is not a ZIP: %v", err)
+			}
+			if len(zr.File) != len(tt.wantFiles) {
+				t.Errorf("GenerateOrderDocuments() ZIP has %d files, want %d", len(zr.File), len(tt.wantFiles))
+			}
+			for _, f := range zr.File {
+				wants, ok := tt.wantFiles[f.Name]
+				if !ok {
+					t.Errorf("GenerateOrderDocuments() ZIP has unexpected file %q", f.Name)
+					continue
+				}
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				content, _ := io.ReadAll(rc)
+				rc.Close()
+				for _, want := range wants {
+					if !strings.Contains(string(content), pdfText(want)) {
+						t.Errorf("GenerateOrderDocuments() %s missing expected content %q", f.Name, want)
+					}
+				}
+				// fpdf sizes A6 as 105 x 148.5 mm
+				if gotA6 := strings.Contains(string(content), "/MediaBox [0 0 297.64 420.94]"); gotA6 != tt.wantA6 {
+					t.Errorf("GenerateOrderDocuments() %s is A6 = %v, want %v", f.Name, gotA6, tt.wantA6)
+				}
+			}
+		})
+	}
+}
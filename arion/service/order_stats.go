@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+// GetOrdersTimeseriesInput selects the local dates and bucket size of an
+// order stats timeseries. Dates only use their year, month and day; a nil
+// DateTo is today and a nil DateFrom is StatsDefaultDays before DateTo.
+type GetOrdersTimeseriesInput struct {
+	ShopID   int
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Bucket   string
+	Top      int
+}
+
+// GetOrdersTimeseries returns order activity per day, week or month in the
+// shop's timezone. Every bucket of the range has a point, so quiet days show
+// up as zeros instead of gaps in the chart.
+func (o *oservice) GetOrdersTimeseries(ctx context.Context, input GetOrdersTimeseriesInput) (response.OrderTimeseriesData, error) {
+	bucket := input.Bucket
+	if bucket == "" {
+		bucket = constant.StatsBucketDay
+	}
+	if bucket != constant.StatsBucketDay && bucket != constant.StatsBucketWeek && bucket != constant.StatsBucketMonth {
+		return response.OrderTimeseriesData{}, errors.New(apierr.ErrStatsBucketInvalid)
+	}
+
+	top := input.Top
+	if top == 0 {
+		top = constant.StatsDefaultTop
+	}
+	if top < 1 || top > constant.StatsMaxTop {
+		return response.OrderTimeseriesData{}, errors.New(apierr.ErrStatsTopInvalid)
+	}
+
+	loc, err := shopLocation(ctx, input.ShopID)
+	if err != nil {
+		return response.OrderTimeseriesData{}, err
+	}
+
+	to := localDate(time.Now().In(loc), loc)
+	if input.DateTo != nil {
+		to = localDate(*input.DateTo, loc)
+	}
+	from := to.AddDate(0, 0, 1-constant.StatsDefaultDays)
+	if input.DateFrom != nil {
+		from = localDate(*input.DateFrom, loc)
+	}
+	if to.Before(from) {
+		return response.OrderTimeseriesData{}, errors.New(apierr.ErrStatsRangeInvalid)
+	}
+
+	var starts []time.Time
+	for t := bucketStart(from, bucket); !t.After(to); t = nextBucket(t, bucket) {
+		if len(starts) == constant.StatsMaxBuckets {
+			return response.OrderTimeseriesData{}, errors.New(apierr.ErrStatsRangeTooLong)
+		}
+		starts = append(starts, t)
+	}
+
+	period := model.StatsPeriod{
+		From:     from.UTC(),
+		To:       to.AddDate(0, 0, 1).UTC(),
+		Bucket:   bucket,
+		Timezone: loc.String(),
+	}
+
+	buckets, err := statsStore.GetTimeseries(ctx, input.ShopID, period)
+	if err != nil {
+		return response.OrderTimeseriesData{}, err
+	}
+	products, err := statsStore.GetTopProducts(ctx, input.ShopID, period, top)
+	if err != nil {
+		return response.OrderTimeseriesData{}, err
+	}
+	customers, err := statsStore.GetTopCustomers(ctx, input.ShopID, period, top)
+	if err != nil {
+		return response.OrderTimeseriesData{}, err
+	}
+
+	// bucket starts come back as local wall clocks
+	byDate := make(map[string]model.StatsBucket, len(buckets))
+	for _, b := range buckets {
+		byDate[b.Start.Format("2006-01-02")] = b
+	}
+
+	res := response.OrderTimeseriesData{
+		Bucket:       bucket,
+		Timezone:     loc.String(),
+		DateFrom:     from.Format("2006-01-02"),
+		DateTo:       to.Format("2006-01-02"),
+		Points:       make([]response.OrderTimeseriesPoint, 0, len(starts)),
+		TopProducts:  make([]response.TopProductData, 0, len(products)),
+		TopCustomers: make([]response.TopCustomerData, 0, len(customers)),
+	}
+	for _, start := range starts {
+		date := start.Format("2006-01-02")
+		b := byDate[date]
+		point := response.OrderTimeseriesPoint{
+			Date:         date,
+			Orders:       b.Orders,
+			Revenue:      b.Revenue,
+			NetSales:     b.NetSales,
+			NewCustomers: b.NewCustomers,
+		}
+		if b.Orders > 0 {
+			point.AverageOrderValue = b.Sales / b.Orders
+		}
+		res.Points = append(res.Points, point)
+	}
+	for _, p := range products {
+		res.TopProducts = append(res.TopProducts, response.TopProductData{
+			ProductID:   p.ProductID,
+			ProductName: p.ProductName,
+			Qty:         p.Qty,
+			Sales:       p.Sales,
+		})
+	}
+	for _, c := range customers {
+		res.TopCustomers = append(res.TopCustomers, response.TopCustomerData{
+			CustomerID:   c.CustomerID,
+			CustomerName: c.CustomerName,
+			Orders:       c.Orders,
+			Sales:        c.Sales,
+		})
+	}
+
+	return res, nil
+}
+
+// bucketStart returns the first day of the bucket day falls in.
+func bucketStart(day time.Time, bucket string) time.Time {
+	switch bucket {
+	case constant.StatsBucketWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case constant.StatsBucketMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+func nextBucket(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case constant.StatsBucketWeek:
+		return start.AddDate(0, 0, 7)
+	case constant.StatsBucketMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_oservice_GetOrdersTimeseries(t *testing.T) {
+	date := func(y int, m time.Month, d int) *time.Time {
+		t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	wallClock := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Asia/Jakarta is UTC+7, so local midnight is 17:00 UTC the day before
+	janPeriod := func(bucket string) model.StatsPeriod {
+		return model.StatsPeriod{
+			From:     time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC),
+			To:       time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC),
+			Bucket:   bucket,
+			Timezone: "Asia/Jakarta",
+		}
+	}
+
+	tests := []struct {
+		name      string
+		timezone  string
+		input     GetOrdersTimeseriesInput
+		mockSetup func(m *mock_store.MockStatsStore)
+		want      response.OrderTimeseriesData
+		wantErr   string
+	}{
+		{
+			name:  "fills empty days and ranks top products and customers",
+			input: GetOrdersTimeseriesInput{ShopID: 1, DateFrom: date(2024, 1, 1), DateTo: date(2024, 1, 3)},
+			mockSetup: func(m *mock_store.MockStatsStore) {
+				period := model.StatsPeriod{
+					From:     time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC),
+					To:       time.Date(2024, 1, 3, 17, 0, 0, 0, time.UTC),
+					Bucket:   constant.StatsBucketDay,
+					Timezone: "Asia/Jakarta",
+				}
+				m.EXPECT().GetTimeseries(gomock.Any(), 1, period).Return([]model.StatsBucket{
+					{Start: wallClock(2024, 1, 1), Orders: 3, Sales: 100000, Revenue: 50000, NetSales: 20000, NewCustomers: 2},
+					{Start: wallClock(2024, 1, 3), Revenue: 25000},
+				}, nil)
+				m.EXPECT().GetTopProducts(gomock.Any(), 1, period, 5).Return([]model.TopProduct{
+					{ProductID: 1, ProductName: "Product A", Qty: 4, Sales: 80000},
+				}, nil)
+				m.EXPECT().GetTopCustomers(gomock.Any(), 1, period, 5).Return([]model.TopCustomer{
+					{CustomerID: 10, CustomerName: "John Doe", Orders: 2, Sales: 70000},
+				}, nil)
+			},
+			want: response.OrderTimeseriesData{
+				Bucket:   "day",
+				Timezone: "Asia/Jakarta",
+				DateFrom: "2024-01-01",
+				DateTo:   "2024-01-03",
+				Points: []response.OrderTimeseriesPoint{
+					{Date: "2024-01-01", Orders: 3, Revenue: 50000, NetSales: 20000, NewCustomers: 2, AverageOrderValue: 33333},
+					{Date: "2024-01-02"},
+					{Date: "2024-01-03", Revenue: 25000},
+				},
+				TopProducts:  []response.TopProductData{{ProductID: 1, ProductName: "Product A", Qty: 4, Sales: 80000}},
+				TopCustomers: []response.TopCustomerData{{CustomerID: 10, CustomerName: "John Doe", Orders: 2, Sales: 70000}},
+			},
+		},
+		{
+			name:  "weeks start on monday",
+			input: GetOrdersTimeseriesInput{ShopID: 1, DateFrom: date(2024, 1, 1), DateTo: date(2024, 1, 31), Bucket: "week", Top: 3},
+			mockSetup: func(m *mock_store.MockStatsStore) {
+				m.EXPECT().GetTimeseries(gomock.Any(), 1, janPeriod("week")).Return([]model.StatsBucket{
+					{Start: wallClock(2024, 1, 8), Orders: 2, Sales: 30000},
+				}, nil)
+				m.EXPECT().GetTopProducts(gomock.Any(), 1, janPeriod("week"), 3).Return([]model.TopProduct{}, nil)
+				m.EXPECT().GetTopCustomers(gomock.Any(), 1, janPeriod("week"), 3).Return([]model.TopCustomer{}, nil)
+			},
+			want: response.OrderTimeseriesData{
+				Bucket:   "week",
+				Timezone: "Asia/Jakarta",
+				DateFrom: "2024-01-01",
+				DateTo:   "2024-01-31",
+				Points: []response.OrderTimeseriesPoint{
+					{Date: "2024-01-01"},
+					{Date: "2024-01-08", Orders: 2, AverageOrderValue: 15000},
+					{Date: "2024-01-15"},
+					{Date: "2024-01-22"},
+					{Date: "2024-01-29"},
+				},
+				TopProducts:  []response.TopProductData{},
+				TopCustomers: []response.TopCustomerData{},
+			},
+		},
+		{
+			name:  "months start on the first, also for a mid-month range",
+			input: GetOrdersTimeseriesInput{ShopID: 1, DateFrom: date(2024, 1, 15), DateTo: date(2024, 3, 10), Bucket: "month"},
+			mockSetup: func(m *mock_store.MockStatsStore) {
+				m.EXPECT().GetTimeseries(gomock.Any(), 1, gomock.Any()).Return([]model.StatsBucket{}, nil)
+				m.EXPECT().GetTopProducts(gomock.Any(), 1, gomock.Any(), 5).Return([]model.TopProduct{}, nil)
+				m.EXPECT().GetTopCustomers(gomock.Any(), 1, gomock.Any(), 5).Return([]model.TopCustomer{}, nil)
+			},
+			want: response.OrderTimeseriesData{
+				Bucket:   "month",
+				Timezone: "Asia/Jakarta",
+				DateFrom: "2024-01-15",
+				DateTo:   "2024-03-10",
+				Points: []response.OrderTimeseriesPoint{
+					{Date: "2024-01-01"},
+					{Date: "2024-02-01"},
+					{Date: "2024-03-01"},
+				},
+				TopProducts:  []response.TopProductData{},
+				TopCustomers: []response.TopCustomerData{},
+			},
+		},
+		{
+			name:     "cuts days in the shop's timezone",
+			timezone: "America/New_York",
+			input:    GetOrdersTimeseriesInput{ShopID: 1, DateFrom: date(2024, 1, 1), DateTo: date(2024, 1, 1)},
+			mockSetup: func(m *mock_store.MockStatsStore) {
+				period := model.StatsPeriod{
+					From:     time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC),
+					To:       time.Date(2024, 1, 2, 5, 0, 0, 0, time.UTC),
+					Bucket:   constant.StatsBucketDay,
+					Timezone: "America/New_York",
+				}
+				m.EXPECT().GetTimeseries(gomock.Any(), 1, period).Return([]model.StatsBucket{}, nil)
+				m.EXPECT().GetTopProducts(gomock.Any(), 1, period, 5).Return([]model.TopProduct{}, nil)
+				m.EXPECT().GetTopCustomers(gomock.Any(), 1, period, 5).Return([]model.TopCustomer{}, nil)
+			},
+			want: response.OrderTimeseriesData{
+				Bucket:       "day",
+				Timezone:     "America/New_York",
+				DateFrom:     "2024-01-01",
+				DateTo:       "2024-01-01",
+				Points:       []response.OrderTimeseriesPoint{{Date: "2024-01-01"}},
+				TopProducts:  []response.TopProductData{},
+				TopCustomers: []response.TopCustomerData{},
+			},
+		},
+		{
+			name:      "returns error on unknown bucket",
+			input:     GetOrdersTimeseriesInput{ShopID: 1, Bucket: "hour"},
+			mockSetup: func(m *mock_store.MockStatsStore) {},
+			wantErr:   apierr.ErrStatsBucketInvalid,
+		},
+		{
+			name:      "returns error when top is over the limit",
+			input:     GetOrdersTimeseriesInput{ShopID: 1, Top: 51},
+			mockSetup: func(m *mock_store.MockStatsStore) {},
+			wantErr:   apierr.ErrStatsTopInvalid,
+		},
+		{
+			name:      "returns error when date_to is before date_from",
+			input:     GetOrdersTimeseriesInput{ShopID: 1, DateFrom: date(2024, 2, 1), DateTo: date(2024, 1, 31)},
+			mockSetup: func(m *mock_store.MockStatsStore) {},
+			wantErr:   apierr.ErrStatsRangeInvalid,
+		},
+		{
+			name:      "returns error when the range has too many buckets",
+			input:     GetOrdersTimeseriesInput{ShopID: 1, DateFrom: date(2023, 1, 1), DateTo: date(2024, 6, 30)},
+			mockSetup: func(m *mock_store.MockStatsStore) {},
+			wantErr:   apierr.ErrStatsRangeTooLong,
+		},
+		{
+			name:  "returns error when store fails",
+			input: GetOrdersTimeseriesInput{ShopID: 1, DateFrom: date(2024, 1, 1), DateTo: date(2024, 1, 31)},
+			mockSetup: func(m *mock_store.MockStatsStore) {
+				m.EXPECT().GetTimeseries(gomock.Any(), 1, gomock.Any()).Return(nil, errors.New("database error"))
+			},
+			wantErr: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldStatsStore := statsStore
+			oldShopStore := shopStore
+			defer func() {
+				statsStore = oldStatsStore
+				shopStore = oldShopStore
+			}()
+
+			timezone := tt.timezone
+			if timezone == "" {
+				timezone = "Asia/Jakarta"
+			}
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, Timezone: timezone}, nil).AnyTimes()
+			shopStore = shopMock
+
+			m := mock_store.NewMockStatsStore(ctrl)
+			tt.mockSetup(m)
+			statsStore = m
+
+			o := &oservice{}
+			got, err := o.GetOrdersTimeseries(context.Background(), tt.input)
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("GetOrdersTimeseries() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetOrdersTimeseries() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOrdersTimeseries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_oservice_GetOrdersTimeseries_defaultRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldStatsStore := statsStore
+	oldShopStore := shopStore
+	defer func() {
+		statsStore = oldStatsStore
+		shopStore = oldShopStore
+	}()
+
+	shopMock := mock_store.NewMockShopStore(ctrl)
+	shopMock.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, Timezone: "Asia/Jakarta"}, nil)
+	shopStore = shopMock
+
+	m := mock_store.NewMockStatsStore(ctrl)
+	m.EXPECT().GetTimeseries(gomock.Any(), 1, gomock.Any()).Return([]model.StatsBucket{}, nil)
+	m.EXPECT().GetTopProducts(gomock.Any(), 1, gomock.Any(), constant.StatsDefaultTop).Return([]model.TopProduct{}, nil)
+	m.EXPECT().GetTopCustomers(gomock.Any(), 1, gomock.Any(), constant.StatsDefaultTop).Return([]model.TopCustomer{}, nil)
+	statsStore = m
+
+	o := &oservice{}
+	got, err := o.GetOrdersTimeseries(context.Background(), GetOrdersTimeseriesInput{ShopID: 1})
+	if err != nil {
+		t.Fatalf("GetOrdersTimeseries() error = %v", err)
+	}
+
+	from, _ := time.Parse("2006-01-02", got.DateFrom)
+	to, _ := time.Parse("2006-01-02", got.DateTo)
+	if days := int(to.Sub(from).Hours()/24) + 1; days != constant.StatsDefaultDays {
+		t.Errorf("GetOrdersTimeseries() range %s to %s has %d days, want %d", got.DateFrom, got.DateTo, days, constant.StatsDefaultDays)
+	}
+	if len(got.Points) != constant.StatsDefaultDays {
+		t.Errorf("GetOrdersTimeseries() returned %d points, want %d", len(got.Points), constant.StatsDefaultDays)
+	}
+}
@@ -1,13 +1,17 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
+	"image"
+	"image/png"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
 	"github.com/golang/mock/gomock"
 	"github.com/zeirash/recapo/arion/common/apierr"
@@ -22,16 +26,25 @@ import (
 
 func Test_oservice_CreateOrder(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	// three days are counted from the Jakarta calendar date and end at its midnight
+	ruleDueAt := time.Date(2024, 1, 19, 0, 0, 0, 0, jakarta)
 
 	tests := []struct {
-		name       string
-		customerID int
-		shopID     int
-		notes      *string
-		mockSetup  func(ctrl *gomock.Controller) *mock_store.MockOrderStore
-		wantResult response.OrderData
-		wantErr    bool
-		wantErrMsg string // if non-empty, error must contain this string
+		name         string
+		customerID   int
+		shopID       int
+		notes        *string
+		addressID    *int
+		shop         *model.Shop
+		dpRule       *model.DPRule
+		address      *model.CustomerAddress // the customer's default address
+		mockSetup    func(ctrl *gomock.Controller) *mock_store.MockOrderStore
+		addressSetup func(ctrl *gomock.Controller) *mock_store.MockCustomerAddressStore
+		dbSetup      func(ctrl *gomock.Controller) *mock_database.MockDB
+		wantResult   response.OrderData
+		wantErr      bool
+		wantErrMsg   string // if non-empty, error must contain this string
 	}{
 		{
 			name:       "successfully create order",
@@ -63,6 +76,206 @@ func Test_oservice_CreateOrder(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:       "assigns unique code when shop has it enabled",
+			customerID: 1,
+			shopID:     1,
+			notes:      nil,
+			shop:       &model.Shop{ID: 1, UniqueCodeEnabled: true},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetActiveOrderByCustomerID(gomock.Any(), 1, 1).
+					Return(nil, nil)
+				mock.EXPECT().
+					CreateOrder(gomock.Any(), gomock.Any(), 1, 1, nil, nil).
+					Return(&model.Order{
+						ID:           1,
+						ShopID:       1,
+						CustomerName: "John Doe",
+						TotalPrice:   150000,
+						Status:       constant.OrderStatusCreated,
+						CreatedAt:    fixedTime,
+					}, nil)
+				mock.EXPECT().
+					AssignUniqueCode(gomock.Any(), gomock.Any(), 1, 1).
+					Return(417, nil)
+				return mock
+			},
+			dbSetup: func(ctrl *gomock.Controller) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				return mockDB
+			},
+			wantResult: response.OrderData{
+				ID:           1,
+				CustomerName: "John Doe",
+				TotalPrice:   150000,
+				UniqueCode:   417,
+				AmountDue:    150417,
+				Status:       constant.OrderStatusCreated,
+				CreatedAt:    fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name:       "applies shop default DP rule",
+			customerID: 1,
+			shopID:     1,
+			notes:      nil,
+			dpRule:     &model.DPRule{ID: 3, ShopID: 1, Percent: 50, DueDays: sql.NullInt64{Int64: 3, Valid: true}, AutoCancel: true},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				ruleID := 3
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetActiveOrderByCustomerID(gomock.Any(), 1, 1).
+					Return(nil, nil)
+				mock.EXPECT().
+					CreateOrder(gomock.Any(), gomock.Any(), 1, 1, nil, nil).
+					Return(&model.Order{
+						ID:            1,
+						ShopID:        1,
+						CustomerName:  "John Doe",
+						Status:        constant.OrderStatusCreated,
+						PaymentStatus: constant.OrderPaymentStatusOutstanding,
+						CreatedAt:     fixedTime,
+					}, nil)
+				mock.EXPECT().
+					SetDownPayment(gomock.Any(), gomock.Any(), 1, store.SetDownPaymentInput{
+						RuleID:        &ruleID,
+						Percent:       50,
+						DueAt:         &ruleDueAt,
+						AutoCancel:    true,
+						PaymentStatus: constant.OrderPaymentStatusAwaitingDP,
+					}).
+					Return(nil)
+				return mock
+			},
+			dbSetup: func(ctrl *gomock.Controller) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				return mockDB
+			},
+			wantResult: response.OrderData{
+				ID:            1,
+				CustomerName:  "John Doe",
+				Status:        constant.OrderStatusCreated,
+				PaymentStatus: constant.OrderPaymentStatusAwaitingDP,
+				DPDueAt:       &ruleDueAt,
+				CreatedAt:     fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name:       "copies the customer's default address onto the order",
+			customerID: 1,
+			shopID:     1,
+			address:    &model.CustomerAddress{ID: 4, CustomerID: 1, Label: "Rumah", Recipient: "John Doe", Phone: "+62812345678", Address: "Jl. Merdeka 1", City: "Bandung", PostalCode: "40111", IsDefault: true},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				addressID := 4
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetActiveOrderByCustomerID(gomock.Any(), 1, 1).
+					Return(nil, nil)
+				mock.EXPECT().
+					CreateOrder(gomock.Any(), gomock.Any(), 1, 1, nil, nil).
+					Return(&model.Order{ID: 1, ShopID: 1, CustomerName: "John Doe", Status: constant.OrderStatusCreated, CreatedAt: fixedTime}, nil)
+				mock.EXPECT().
+					SetShippingAddress(gomock.Any(), gomock.Any(), 1, &model.ShippingAddress{
+						AddressID:  &addressID,
+						Label:      "Rumah",
+						Recipient:  "John Doe",
+						Phone:      "+62812345678",
+						Address:    "Jl. Merdeka 1",
+						City:       "Bandung",
+						PostalCode: "40111",
+					}).
+					Return(nil)
+				return mock
+			},
+			dbSetup: func(ctrl *gomock.Controller) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				return mockDB
+			},
+			wantResult: response.OrderData{
+				ID:           1,
+				CustomerName: "John Doe",
+				Status:       constant.OrderStatusCreated,
+				ShippingAddress: &response.ShippingAddressData{
+					AddressID:  func() *int { id := 4; return &id }(),
+					Label:      "Rumah",
+					Recipient:  "John Doe",
+					Phone:      "+62812345678",
+					Address:    "Jl. Merdeka 1",
+					City:       "Bandung",
+					PostalCode: "40111",
+				},
+				CreatedAt: fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name:       "create order returns error when chosen address not found",
+			customerID: 1,
+			shopID:     1,
+			addressID:  func() *int { id := 99; return &id }(),
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetActiveOrderByCustomerID(gomock.Any(), 1, 1).
+					Return(nil, nil)
+				return mock
+			},
+			addressSetup: func(ctrl *gomock.Controller) *mock_store.MockCustomerAddressStore {
+				mock := mock_store.NewMockCustomerAddressStore(ctrl)
+				mock.EXPECT().
+					GetCustomerAddressByID(gomock.Any(), 99, 1).
+					Return(nil, nil)
+				return mock
+			},
+			wantResult: response.OrderData{},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrCustomerAddressNotFound,
+		},
+		{
+			name:       "create order returns error when AssignUniqueCode fails",
+			customerID: 1,
+			shopID:     1,
+			notes:      nil,
+			shop:       &model.Shop{ID: 1, UniqueCodeEnabled: true},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetActiveOrderByCustomerID(gomock.Any(), 1, 1).
+					Return(nil, nil)
+				mock.EXPECT().
+					CreateOrder(gomock.Any(), gomock.Any(), 1, 1, nil, nil).
+					Return(&model.Order{ID: 1, ShopID: 1, CustomerName: "John Doe"}, nil)
+				mock.EXPECT().
+					AssignUniqueCode(gomock.Any(), gomock.Any(), 1, 1).
+					Return(0, errors.New("database error"))
+				return mock
+			},
+			dbSetup: func(ctrl *gomock.Controller) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				return mockDB
+			},
+			wantResult: response.OrderData{},
+			wantErr:    true,
+		},
 		{
 			name:       "create order returns error when customer has active order",
 			customerID: 1,
@@ -120,11 +333,43 @@ func Test_oservice_CreateOrder(t *testing.T) {
 			defer ctrl.Finish()
 
 			oldStore := orderStore
-			defer func() { orderStore = oldStore }()
+			oldShopStore := shopStore
+			oldDPRuleStore := dpRuleStore
+			oldAddressStore := customerAddressStore
+			oldDBGetter := dbGetter
+			defer func() {
+				orderStore = oldStore
+				shopStore = oldShopStore
+				dpRuleStore = oldDPRuleStore
+				customerAddressStore = oldAddressStore
+				dbGetter = oldDBGetter
+			}()
 			orderStore = tt.mockSetup(ctrl)
 
+			shop := tt.shop
+			if shop == nil {
+				shop = &model.Shop{ID: tt.shopID}
+			}
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), tt.shopID).Return(shop, nil).AnyTimes()
+			shopStore = shopMock
+			dpRuleMock := mock_store.NewMockDPRuleStore(ctrl)
+			dpRuleMock.EXPECT().GetDefaultDPRuleByShopID(gomock.Any(), tt.shopID).Return(tt.dpRule, nil).AnyTimes()
+			dpRuleStore = dpRuleMock
+			if tt.addressSetup != nil {
+				customerAddressStore = tt.addressSetup(ctrl)
+			} else {
+				addressMock := mock_store.NewMockCustomerAddressStore(ctrl)
+				addressMock.EXPECT().GetDefaultCustomerAddress(gomock.Any(), tt.customerID).Return(tt.address, nil).AnyTimes()
+				customerAddressStore = addressMock
+			}
+			if tt.dbSetup != nil {
+				mockDB := tt.dbSetup(ctrl)
+				dbGetter = func() database.DB { return mockDB }
+			}
+
 			var o oservice
-			got, gotErr := o.CreateOrder(context.Background(), tt.customerID, tt.shopID, tt.notes)
+			got, gotErr := o.CreateOrder(context.Background(), tt.customerID, tt.shopID, tt.notes, tt.addressID)
 
 			if gotErr != nil {
 				if !tt.wantErr {
@@ -183,7 +428,7 @@ func Test_oservice_GetOrderByID(t *testing.T) {
 
 				mockOrderPayment := mock_store.NewMockOrderPaymentStore(ctrl)
 				mockOrderPayment.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 1).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 1).
 					Return([]model.OrderPayment{
 						{ID: 1, OrderID: 1, Amount: 50000, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
 					}, nil)
@@ -229,7 +474,7 @@ func Test_oservice_GetOrderByID(t *testing.T) {
 
 				mockOrderPayment := mock_store.NewMockOrderPaymentStore(ctrl)
 				mockOrderPayment.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 1).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 1).
 					Return([]model.OrderPayment{}, nil)
 
 				return mockOrder, mockOrderItem, mockOrderPayment
@@ -329,7 +574,7 @@ func Test_oservice_GetOrderByID(t *testing.T) {
 
 				mockOrderPayment := mock_store.NewMockOrderPaymentStore(ctrl)
 				mockOrderPayment.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 1).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 1).
 					Return(nil, errors.New("order payments error"))
 				return mockOrder, mockOrderItem, mockOrderPayment
 			},
@@ -459,7 +704,7 @@ func Test_oservice_GetOrdersByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateFrom: ptrTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
-				dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+				dateFrom := time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC)
 				mock.EXPECT().GetOrdersByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &dateFrom}).
 					Return([]model.Order{
 						{ID: 1, CustomerName: "John Doe", TotalPrice: 100, Status: constant.OrderStatusCreated, CreatedAt: fixedTime},
@@ -477,7 +722,7 @@ func Test_oservice_GetOrdersByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateTo: ptrTime(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
-				dateTo := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+				dateTo := time.Date(2024, 2, 1, 17, 0, 0, 0, time.UTC)
 				mock.EXPECT().GetOrdersByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateTo: &dateTo}).
 					Return([]model.Order{
 						{ID: 1, CustomerName: "John Doe", TotalPrice: 100, Status: constant.OrderStatusCreated, CreatedAt: fixedTime},
@@ -498,8 +743,8 @@ func Test_oservice_GetOrdersByShopID(t *testing.T) {
 			},
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
-				dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
-				dateTo := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+				dateFrom := time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC)
+				dateTo := time.Date(2024, 2, 1, 17, 0, 0, 0, time.UTC)
 				mock.EXPECT().GetOrdersByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo}).
 					Return([]model.Order{
 						{ID: 1, CustomerName: "John Doe", TotalPrice: 100, Status: constant.OrderStatusCreated, CreatedAt: fixedTime},
@@ -519,9 +764,17 @@ func Test_oservice_GetOrdersByShopID(t *testing.T) {
 			defer ctrl.Finish()
 
 			oldStore := orderStore
-			defer func() { orderStore = oldStore }()
+			oldShopStore := shopStore
+			defer func() {
+				orderStore = oldStore
+				shopStore = oldShopStore
+			}()
 			orderStore = tt.mockSetup(ctrl)
 
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), tt.shopID).Return(&model.Shop{ID: tt.shopID, Timezone: "Asia/Jakarta"}, nil).AnyTimes()
+			shopStore = shopMock
+
 			var o oservice
 			got, gotErr := o.GetOrdersByShopID(context.Background(), tt.shopID, tt.opts)
 
@@ -552,7 +805,8 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 	tests := []struct {
 		name       string
 		input      UpdateOrderInput
-		mockSetup  func(ctrl *gomock.Controller) *mock_store.MockOrderStore
+		address    *model.CustomerAddress // returned for input.AddressID
+		mockSetup  func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore
 		wantResult response.OrderData
 		wantErr    bool
 	}{
@@ -562,13 +816,13 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 				ID:     1,
 				Status: strPtr(constant.OrderStatusDone),
 			},
-			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
 				mock.EXPECT().
 					GetOrderByID(gomock.Any(), 1).
 					Return(&model.Order{ID: 1, CustomerName: "John Doe", Status: constant.OrderStatusCreated}, nil)
 				mock.EXPECT().
-					UpdateOrder(gomock.Any(), nil, 1, store.UpdateOrderInput{Status: strPtr(constant.OrderStatusDone)}).
+					UpdateOrder(gomock.Any(), tx, 1, store.UpdateOrderInput{Status: strPtr(constant.OrderStatusDone)}).
 					Return(&model.Order{
 						ID:           1,
 						CustomerName: "John Doe",
@@ -589,6 +843,75 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "copies the chosen address onto the order",
+			input: UpdateOrderInput{
+				ID:        1,
+				AddressID: intPtr(4),
+			},
+			address: &model.CustomerAddress{ID: 4, CustomerID: 3, Label: "Kantor", Recipient: "John Doe", Address: "Jl. Sudirman 10"},
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetOrderByID(gomock.Any(), 1).
+					Return(&model.Order{ID: 1, CustomerID: 3, CustomerName: "John Doe", Status: constant.OrderStatusCreated}, nil)
+				mock.EXPECT().
+					UpdateOrder(gomock.Any(), tx, 1, store.UpdateOrderInput{}).
+					Return(&model.Order{ID: 1, CustomerID: 3, CustomerName: "John Doe", Status: constant.OrderStatusCreated, CreatedAt: fixedTime}, nil)
+				mock.EXPECT().
+					SetShippingAddress(gomock.Any(), tx, 1, &model.ShippingAddress{AddressID: intPtr(4), Label: "Kantor", Recipient: "John Doe", Address: "Jl. Sudirman 10"}).
+					Return(nil)
+				return mock
+			},
+			wantResult: response.OrderData{
+				ID:              1,
+				CustomerName:    "John Doe",
+				Status:          constant.OrderStatusCreated,
+				ShippingAddress: &response.ShippingAddressData{AddressID: intPtr(4), Label: "Kantor", Recipient: "John Doe", Address: "Jl. Sudirman 10"},
+				CreatedAt:       fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns error when the address can't be saved",
+			input: UpdateOrderInput{
+				ID:        1,
+				Status:    strPtr(constant.OrderStatusDone),
+				AddressID: intPtr(4),
+			},
+			address: &model.CustomerAddress{ID: 4, CustomerID: 3, Label: "Kantor", Recipient: "John Doe", Address: "Jl. Sudirman 10"},
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetOrderByID(gomock.Any(), 1).
+					Return(&model.Order{ID: 1, CustomerID: 3, CustomerName: "John Doe", Status: constant.OrderStatusCreated}, nil)
+				mock.EXPECT().
+					UpdateOrder(gomock.Any(), tx, 1, store.UpdateOrderInput{Status: strPtr(constant.OrderStatusDone)}).
+					Return(&model.Order{ID: 1, CustomerID: 3, CustomerName: "John Doe", Status: constant.OrderStatusDone, CreatedAt: fixedTime}, nil)
+				mock.EXPECT().
+					SetShippingAddress(gomock.Any(), tx, 1, gomock.Any()).
+					Return(errors.New("database error"))
+				return mock
+			},
+			wantResult: response.OrderData{},
+			wantErr:    true,
+		},
+		{
+			name: "returns error when the chosen address is not the customer's",
+			input: UpdateOrderInput{
+				ID:        1,
+				AddressID: intPtr(4),
+			},
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetOrderByID(gomock.Any(), 1).
+					Return(&model.Order{ID: 1, CustomerID: 3, CustomerName: "John Doe", Status: constant.OrderStatusCreated}, nil)
+				return mock
+			},
+			wantResult: response.OrderData{},
+			wantErr:    true,
+		},
 		{
 			name: "update order with multiple fields",
 			input: UpdateOrderInput{
@@ -596,13 +919,13 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 				TotalPrice: intPtr(500),
 				Status:     strPtr(constant.OrderStatusDone),
 			},
-			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
 				mock.EXPECT().
 					GetOrderByID(gomock.Any(), 1).
 					Return(&model.Order{ID: 1, CustomerName: "John Doe", Status: constant.OrderStatusCreated}, nil)
 				mock.EXPECT().
-					UpdateOrder(gomock.Any(), nil, 1, store.UpdateOrderInput{TotalPrice: intPtr(500), Status: strPtr(constant.OrderStatusDone)}).
+					UpdateOrder(gomock.Any(), tx, 1, store.UpdateOrderInput{TotalPrice: intPtr(500), Status: strPtr(constant.OrderStatusDone)}).
 					Return(&model.Order{
 						ID:           1,
 						CustomerName: "Jane Doe",
@@ -623,13 +946,65 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "reopening a cancelled order reclaims its unique code",
+			input: UpdateOrderInput{
+				ID:     1,
+				Status: strPtr(constant.OrderStatusCreated),
+			},
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetOrderByID(gomock.Any(), 1).
+					Return(&model.Order{ID: 1, ShopID: 10, CustomerName: "John Doe", TotalPrice: 100000, UniqueCode: 417, Status: constant.OrderStatusCancelled, PaymentStatus: constant.OrderPaymentStatusOutstanding}, nil)
+				gomock.InOrder(
+					mock.EXPECT().
+						ReclaimUniqueCode(gomock.Any(), tx, 1, 10).
+						Return(88, nil),
+					mock.EXPECT().
+						UpdateOrder(gomock.Any(), tx, 1, store.UpdateOrderInput{Status: strPtr(constant.OrderStatusCreated)}).
+						Return(&model.Order{ID: 1, ShopID: 10, CustomerName: "John Doe", TotalPrice: 100000, UniqueCode: 88, Status: constant.OrderStatusCreated, PaymentStatus: constant.OrderPaymentStatusOutstanding, CreatedAt: fixedTime}, nil),
+				)
+				return mock
+			},
+			wantResult: response.OrderData{
+				ID:            1,
+				CustomerName:  "John Doe",
+				TotalPrice:    100000,
+				UniqueCode:    88,
+				AmountDue:     100088,
+				Status:        constant.OrderStatusCreated,
+				PaymentStatus: constant.OrderPaymentStatusOutstanding,
+				CreatedAt:     fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns error when the unique code can't be reclaimed",
+			input: UpdateOrderInput{
+				ID:            1,
+				PaymentStatus: strPtr(constant.OrderPaymentStatusOutstanding),
+			},
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
+				mock := mock_store.NewMockOrderStore(ctrl)
+				mock.EXPECT().
+					GetOrderByID(gomock.Any(), 1).
+					Return(&model.Order{ID: 1, ShopID: 10, TotalPrice: 100000, UniqueCode: 417, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid}, nil)
+				mock.EXPECT().
+					ReclaimUniqueCode(gomock.Any(), tx, 1, 10).
+					Return(0, errors.New("database error"))
+				return mock
+			},
+			wantResult: response.OrderData{},
+			wantErr:    true,
+		},
 		{
 			name: "update order not found returns error",
 			input: UpdateOrderInput{
 				ID:     999,
 				Status: strPtr(constant.OrderStatusDone),
 			},
-			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
 				mock.EXPECT().
 					GetOrderByID(gomock.Any(), 999).
@@ -645,7 +1020,7 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 				ID:     1,
 				Status: strPtr(constant.OrderStatusDone),
 			},
-			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
 				mock.EXPECT().
 					GetOrderByID(gomock.Any(), 1).
@@ -661,13 +1036,13 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 				ID:     1,
 				Status: strPtr(constant.OrderStatusDone),
 			},
-			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderStore {
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx) *mock_store.MockOrderStore {
 				mock := mock_store.NewMockOrderStore(ctrl)
 				mock.EXPECT().
 					GetOrderByID(gomock.Any(), 1).
 					Return(&model.Order{ID: 1, CustomerName: "John Doe"}, nil)
 				mock.EXPECT().
-					UpdateOrder(gomock.Any(), nil, 1, store.UpdateOrderInput{Status: strPtr(constant.OrderStatusDone)}).
+					UpdateOrder(gomock.Any(), tx, 1, store.UpdateOrderInput{Status: strPtr(constant.OrderStatusDone)}).
 					Return(nil, errors.New("update error"))
 				return mock
 			},
@@ -682,8 +1057,26 @@ func Test_oservice_UpdateOrderByID(t *testing.T) {
 			defer ctrl.Finish()
 
 			oldStore := orderStore
-			defer func() { orderStore = oldStore }()
-			orderStore = tt.mockSetup(ctrl)
+			oldAddressStore := customerAddressStore
+			oldDBGetter := dbGetter
+			defer func() {
+				orderStore = oldStore
+				customerAddressStore = oldAddressStore
+				dbGetter = oldDBGetter
+			}()
+			// The update is only committed when every write succeeds.
+			mockTx := mock_database.NewMockTx(ctrl)
+			if !tt.wantErr {
+				mockTx.EXPECT().Commit().Return(nil)
+			}
+			mockTx.EXPECT().Rollback().Return(nil).AnyTimes()
+			mockDB := mock_database.NewMockDB(ctrl)
+			mockDB.EXPECT().Begin().Return(mockTx, nil).AnyTimes()
+			dbGetter = func() database.DB { return mockDB }
+			orderStore = tt.mockSetup(ctrl, mockTx)
+			addressMock := mock_store.NewMockCustomerAddressStore(ctrl)
+			addressMock.EXPECT().GetCustomerAddressByID(gomock.Any(), gomock.Any(), 3).Return(tt.address, nil).AnyTimes()
+			customerAddressStore = addressMock
 
 			var o oservice
 			got, gotErr := o.UpdateOrderByID(context.Background(), tt.input)
@@ -710,7 +1103,9 @@ func Test_oservice_DeleteOrderByID(t *testing.T) {
 		name      string
 		id        int
 		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore, *mock_database.MockDB, *mock_database.MockTx)
-		wantErr   bool
+		// hasCredits is what the credit check reports once the order is locked.
+		hasCredits bool
+		wantErr    bool
 	}{
 		{
 			name: "successfully delete order",
@@ -758,8 +1153,9 @@ func Test_oservice_DeleteOrderByID(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "delete order returns error on delete order items failure",
-			id:   1,
+			name:       "order with payments tied to credit entries is not deleted",
+			id:         1,
+			hasCredits: true,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore, *mock_database.MockDB, *mock_database.MockTx) {
 				mockTx := mock_database.NewMockTx(ctrl)
 				mockTx.EXPECT().Rollback().Return(nil)
@@ -767,14 +1163,28 @@ func Test_oservice_DeleteOrderByID(t *testing.T) {
 				mockDB := mock_database.NewMockDB(ctrl)
 				mockDB.EXPECT().Begin().Return(mockTx, nil)
 
-				mockOrderItem := mock_store.NewMockOrderItemStore(ctrl)
-				mockOrderItem.EXPECT().
-					DeleteOrderItemsByOrderID(gomock.Any(), mockTx, 1).
-					Return(errors.New("delete items error"))
-
-				mockOrder := mock_store.NewMockOrderStore(ctrl)
-				mockOrderPayment := mock_store.NewMockOrderPaymentStore(ctrl)
-
+				return mock_store.NewMockOrderStore(ctrl), mock_store.NewMockOrderItemStore(ctrl), mock_store.NewMockOrderPaymentStore(ctrl), mockDB, mockTx
+			},
+			wantErr: true,
+		},
+		{
+			name: "delete order returns error on delete order items failure",
+			id:   1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore, *mock_database.MockDB, *mock_database.MockTx) {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				mockOrderItem := mock_store.NewMockOrderItemStore(ctrl)
+				mockOrderItem.EXPECT().
+					DeleteOrderItemsByOrderID(gomock.Any(), mockTx, 1).
+					Return(errors.New("delete items error"))
+
+				mockOrder := mock_store.NewMockOrderStore(ctrl)
+				mockOrderPayment := mock_store.NewMockOrderPaymentStore(ctrl)
+
 				return mockOrder, mockOrderItem, mockOrderPayment, mockDB, mockTx
 			},
 			wantErr: true,
@@ -871,17 +1281,24 @@ func Test_oservice_DeleteOrderByID(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldOrderStore, oldOrderItemStore, oldOrderPaymentStore := orderStore, orderItemStore, orderPaymentStore
+			oldOrderStore, oldOrderItemStore, oldOrderPaymentStore, oldCreditStore := orderStore, orderItemStore, orderPaymentStore, customerCreditStore
 			oldDBGetter := dbGetter
 			defer func() {
-				orderStore, orderItemStore, orderPaymentStore = oldOrderStore, oldOrderItemStore, oldOrderPaymentStore
+				orderStore, orderItemStore, orderPaymentStore, customerCreditStore = oldOrderStore, oldOrderItemStore, oldOrderPaymentStore, oldCreditStore
 				dbGetter = oldDBGetter
 			}()
 
-			mockOrder, mockOrderItem, mockOrderPayment, mockDB, _ := tt.mockSetup(ctrl)
+			mockOrder, mockOrderItem, mockOrderPayment, mockDB, mockTx := tt.mockSetup(ctrl)
+			mockCredit := mock_store.NewMockCustomerCreditStore(ctrl)
+			if mockTx != nil {
+				mockOrder.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, tt.id).Return(&model.Order{ID: tt.id, CustomerID: 5}, nil)
+				mockCredit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(0, nil)
+				mockCredit.EXPECT().HasOrderPaymentCredits(gomock.Any(), mockTx, tt.id, 0).Return(tt.hasCredits, nil)
+			}
 			orderStore = mockOrder
 			orderItemStore = mockOrderItem
 			orderPaymentStore = mockOrderPayment
+			customerCreditStore = mockCredit
 			dbGetter = func() database.DB { return mockDB }
 
 			var o oservice
@@ -1465,7 +1882,12 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 		customerName  string
 		customerPhone string
 		shareToken    string
+		address       *model.ShippingAddress
+		link          *model.ShareLink
+		linkErr       error
 		items         []CreateTempOrderItemInput
+		products      []model.Product
+		customer      *model.Customer
 		mockSetup     func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB)
 		wantResult    response.TempOrderData
 		wantErr       bool
@@ -1475,12 +1897,13 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			customerName:  "Jane Doe",
 			customerPhone: "+62812345678",
 			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
 			items:         nil,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
 				shopMock := mock_store.NewMockShopStore(ctrl)
 				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "share-abc123").
-					Return(&model.Shop{ID: 5, Name: "Test Shop", ShareToken: "share-abc123", CreatedAt: fixedTime}, nil)
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
 				mockTx := mock_database.NewMockTx(ctrl)
 				mockTx.EXPECT().Commit().Return(nil)
 				mockTx.EXPECT().Rollback().Return(nil)
@@ -1488,12 +1911,16 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 				mockDB.EXPECT().Begin().Return(mockTx, nil)
 				orderMock := mock_store.NewMockOrderStore(ctrl)
 				orderMock.EXPECT().
-					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5).
+					HasRecentTempOrder(gomock.Any(), gomock.Any(), 5, gomock.Any(), gomock.Any()).
+					Return(false, nil)
+				orderMock.EXPECT().
+					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5, intPtr(3), gomock.Any(), nil).
 					Return(&model.TempOrder{
 						ID:            1,
 						CustomerName:  "Jane Doe",
 						CustomerPhone: "+62812345678",
 						ShopID:        5,
+						ShareLinkID:   sql.NullInt64{Int64: 3, Valid: true},
 						TotalPrice:    0,
 						Status:        "pending",
 						CreatedAt:     fixedTime,
@@ -1506,6 +1933,7 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			},
 			wantResult: response.TempOrderData{
 				ID:             1,
+				ShareLinkID:    intPtr(3),
 				CustomerName:   "Jane Doe",
 				CustomerPhone:  "+62812345678",
 				TotalPrice:     0,
@@ -1516,17 +1944,74 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:          "stores the shipping address with its phone normalized",
+			customerName:  "Jane Doe",
+			customerPhone: "+62812345678",
+			shareToken:    "share-abc123",
+			address:       &model.ShippingAddress{Recipient: "Budi", Phone: "0813-1111-2222", Address: "Jl. Merdeka 1", City: "Bandung"},
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
+			items:         nil,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				address := &model.ShippingAddress{Recipient: "Budi", Phone: "+6281311112222", Address: "Jl. Merdeka 1", City: "Bandung"}
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					HasRecentTempOrder(gomock.Any(), gomock.Any(), 5, gomock.Any(), gomock.Any()).
+					Return(false, nil)
+				orderMock.EXPECT().
+					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5, intPtr(3), gomock.Any(), address).
+					Return(&model.TempOrder{
+						ID:              1,
+						CustomerName:    "Jane Doe",
+						CustomerPhone:   "+62812345678",
+						ShopID:          5,
+						Status:          "pending",
+						ShippingAddress: address,
+						CreatedAt:       fixedTime,
+					}, nil)
+				orderMock.EXPECT().
+					UpdateTempOrderTotalPrice(gomock.Any(), gomock.Any(), 1, 0).
+					Return(nil)
+				return shopMock, orderMock, nil, mockDB
+			},
+			wantResult: response.TempOrderData{
+				ID:            1,
+				CustomerName:  "Jane Doe",
+				CustomerPhone: "+62812345678",
+				Status:        "pending",
+				ShippingAddress: &response.ShippingAddressData{
+					Recipient: "Budi",
+					Phone:     "+6281311112222",
+					Address:   "Jl. Merdeka 1",
+					City:      "Bandung",
+				},
+				TempOrderItems: []response.TempOrderItemData{},
+				CreatedAt:      fixedTime,
+			},
+			wantErr: false,
+		},
 		{
 			name:          "successfully create temp order with items",
 			customerName:  "Jane Doe",
 			customerPhone: "+62812345678",
 			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
 			items:         []CreateTempOrderItemInput{{ProductID: 10, Qty: 2}, {ProductID: 20, Qty: 1}},
+			products:      []model.Product{{ID: 10, ShopID: 5, IsActive: true}, {ID: 20, ShopID: 5, IsActive: true}},
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
 				shopMock := mock_store.NewMockShopStore(ctrl)
 				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "share-abc123").
-					Return(&model.Shop{ID: 5, Name: "Test Shop", ShareToken: "share-abc123", CreatedAt: fixedTime}, nil)
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
 				mockTx := mock_database.NewMockTx(ctrl)
 				mockTx.EXPECT().Commit().Return(nil)
 				mockTx.EXPECT().Rollback().Return(nil)
@@ -1534,7 +2019,10 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 				mockDB.EXPECT().Begin().Return(mockTx, nil)
 				orderMock := mock_store.NewMockOrderStore(ctrl)
 				orderMock.EXPECT().
-					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5).
+					HasRecentTempOrder(gomock.Any(), gomock.Any(), 5, gomock.Any(), gomock.Any()).
+					Return(false, nil)
+				orderMock.EXPECT().
+					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5, intPtr(3), gomock.Any(), nil).
 					Return(&model.TempOrder{
 						ID:            1,
 						CustomerName:  "Jane Doe",
@@ -1573,33 +2061,26 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name:          "create temp order returns error when shop not found",
+			name:          "create temp order returns error when share link lookup fails",
 			customerName:  "Jane Doe",
 			customerPhone: "+62812345678",
 			shareToken:    "invalid-token",
+			linkErr:       errors.New("database error"),
 			items:         nil,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
-				shopMock := mock_store.NewMockShopStore(ctrl)
-				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "invalid-token").
-					Return(nil, errors.New("shop not found"))
-				return shopMock, nil, nil, nil
+				return mock_store.NewMockShopStore(ctrl), nil, nil, nil
 			},
 			wantResult: response.TempOrderData{},
 			wantErr:    true,
 		},
 		{
-			name:          "create temp order returns error when shop is nil",
+			name:          "create temp order returns error when share token is unknown",
 			customerName:  "Jane Doe",
 			customerPhone: "+62812345678",
 			shareToken:    "share-abc123",
 			items:         nil,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
-				shopMock := mock_store.NewMockShopStore(ctrl)
-				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "share-abc123").
-					Return(nil, nil)
-				return shopMock, nil, nil, nil
+				return mock_store.NewMockShopStore(ctrl), nil, nil, nil
 			},
 			wantResult: response.TempOrderData{},
 			wantErr:    true,
@@ -1609,19 +2090,23 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			customerName:  "Jane Doe",
 			customerPhone: "+62812345678",
 			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
 			items:         nil,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
 				shopMock := mock_store.NewMockShopStore(ctrl)
 				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "share-abc123").
-					Return(&model.Shop{ID: 5, Name: "Test Shop", ShareToken: "share-abc123", CreatedAt: fixedTime}, nil)
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
 				mockTx := mock_database.NewMockTx(ctrl)
 				mockTx.EXPECT().Rollback().Return(nil)
 				mockDB := mock_database.NewMockDB(ctrl)
 				mockDB.EXPECT().Begin().Return(mockTx, nil)
 				orderMock := mock_store.NewMockOrderStore(ctrl)
 				orderMock.EXPECT().
-					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5).
+					HasRecentTempOrder(gomock.Any(), gomock.Any(), 5, gomock.Any(), gomock.Any()).
+					Return(false, nil)
+				orderMock.EXPECT().
+					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5, intPtr(3), gomock.Any(), nil).
 					Return(nil, errors.New("database error"))
 				return shopMock, orderMock, nil, mockDB
 			},
@@ -1633,19 +2118,23 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			customerName:  "Jane Doe",
 			customerPhone: "+62812345678",
 			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
 			items:         nil,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
 				shopMock := mock_store.NewMockShopStore(ctrl)
 				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "share-abc123").
-					Return(&model.Shop{ID: 5, Name: "Test Shop", ShareToken: "share-abc123", CreatedAt: fixedTime}, nil)
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
 				mockTx := mock_database.NewMockTx(ctrl)
 				mockTx.EXPECT().Rollback().Return(nil)
 				mockDB := mock_database.NewMockDB(ctrl)
 				mockDB.EXPECT().Begin().Return(mockTx, nil)
 				orderMock := mock_store.NewMockOrderStore(ctrl)
 				orderMock.EXPECT().
-					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5).
+					HasRecentTempOrder(gomock.Any(), gomock.Any(), 5, gomock.Any(), gomock.Any()).
+					Return(false, nil)
+				orderMock.EXPECT().
+					CreateTempOrder(gomock.Any(), gomock.Any(), "Jane Doe", "+62812345678", 5, intPtr(3), gomock.Any(), nil).
 					Return(&model.TempOrder{
 						ID:            1,
 						CustomerName:  "Jane Doe",
@@ -1664,6 +2153,116 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			wantResult: response.TempOrderData{},
 			wantErr:    true,
 		},
+		{
+			name:          "create temp order rejects product outside the link's subset",
+			customerName:  "Jane Doe",
+			customerPhone: "+62812345678",
+			shareToken:    "share-trip",
+			link:          &model.ShareLink{ID: 4, ShopID: 5, Token: "share-trip", ProductIDs: []int{10}},
+			items:         []CreateTempOrderItemInput{{ProductID: 10, Qty: 1}, {ProductID: 20, Qty: 1}},
+			products:      []model.Product{{ID: 10, ShopID: 5, IsActive: true}, {ID: 20, ShopID: 5, IsActive: true}},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				return shopMock, nil, nil, nil
+			},
+			wantResult: response.TempOrderData{},
+			wantErr:    true,
+		},
+		{
+			name:          "create temp order returns error when link is revoked",
+			customerName:  "Jane Doe",
+			customerPhone: "+62812345678",
+			shareToken:    "share-old",
+			link:          &model.ShareLink{ID: 5, ShopID: 5, Token: "share-old", RevokedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+			items:         nil,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				return mock_store.NewMockShopStore(ctrl), nil, nil, nil
+			},
+			wantResult: response.TempOrderData{},
+			wantErr:    true,
+		},
+		{
+			name:          "create temp order rejects inactive product",
+			customerName:  "Jane Doe",
+			customerPhone: "+62812345678",
+			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
+			items:         []CreateTempOrderItemInput{{ProductID: 10, Qty: 1}},
+			products:      []model.Product{{ID: 10, ShopID: 5, IsActive: false}},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				return shopMock, nil, nil, nil
+			},
+			wantResult: response.TempOrderData{},
+			wantErr:    true,
+		},
+		{
+			name:          "create temp order rejects product from another shop",
+			customerName:  "Jane Doe",
+			customerPhone: "+62812345678",
+			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
+			items:         []CreateTempOrderItemInput{{ProductID: 99, Qty: 1}},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				return shopMock, nil, nil, nil
+			},
+			wantResult: response.TempOrderData{},
+			wantErr:    true,
+		},
+		{
+			name:          "create temp order rejects duplicate submission",
+			customerName:  "Jane Doe",
+			customerPhone: "+62812345678",
+			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
+			items:         []CreateTempOrderItemInput{{ProductID: 10, Qty: 2}},
+			products:      []model.Product{{ID: 10, ShopID: 5, IsActive: true}},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					HasRecentTempOrder(gomock.Any(), gomock.Any(), 5, tempOrderFingerprint("+62812345678", []CreateTempOrderItemInput{{ProductID: 10, Qty: 2}}), gomock.Any()).
+					Return(true, nil)
+				return shopMock, orderMock, nil, mockDB
+			},
+			wantResult: response.TempOrderData{},
+			wantErr:    true,
+		},
+		{
+			name:          "create temp order rejects a blocked phone",
+			customerName:  "Jane Doe",
+			customerPhone: "+62812345678",
+			shareToken:    "share-abc123",
+			link:          &model.ShareLink{ID: 3, ShopID: 5, Token: "share-abc123"},
+			items:         []CreateTempOrderItemInput{{ProductID: 10, Qty: 2}},
+			customer:      &model.Customer{ID: 7, Phone: "+62812345678", Blocked: true},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				return shopMock, mock_store.NewMockOrderStore(ctrl), nil, nil
+			},
+			wantResult: response.TempOrderData{},
+			wantErr:    true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1671,16 +2270,34 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			defer ctrl.Finish()
 
 			shopMock, orderMock, orderItemMock, mockDB := tt.mockSetup(ctrl)
+			linkMock := mock_store.NewMockShareLinkStore(ctrl)
+			linkMock.EXPECT().GetShareLinkByToken(gomock.Any(), tt.shareToken).Return(tt.link, tt.linkErr)
+			productMock := mock_store.NewMockProductStore(ctrl)
+			for i := range tt.products {
+				productMock.EXPECT().GetProductByID(gomock.Any(), tt.products[i].ID, 5).Return(&tt.products[i], nil).AnyTimes()
+			}
+			productMock.EXPECT().GetProductByID(gomock.Any(), gomock.Any(), 5).Return(nil, nil).AnyTimes()
+			customerMock := mock_store.NewMockCustomerStore(ctrl)
+			customerMock.EXPECT().GetCustomerByPhone(gomock.Any(), tt.customerPhone, 5).Return(tt.customer, nil).AnyTimes()
+			oldProductStore := productStore
+			oldCustomerStore := customerStore
+			oldShareLinkStore := shareLinkStore
 			oldShopStore := shopStore
 			oldOrderStore := orderStore
 			oldOrderItemStore := orderItemStore
 			oldDBGetter := dbGetter
 			defer func() {
+				shareLinkStore = oldShareLinkStore
+				productStore = oldProductStore
+				customerStore = oldCustomerStore
 				shopStore = oldShopStore
 				orderStore = oldOrderStore
 				orderItemStore = oldOrderItemStore
 				dbGetter = oldDBGetter
 			}()
+			shareLinkStore = linkMock
+			productStore = productMock
+			customerStore = customerMock
 			shopStore = shopMock
 			orderStore = orderMock
 			if orderItemMock != nil {
@@ -1691,12 +2308,15 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 			}
 
 			var o oservice
-			got, gotErr := o.CreateTempOrder(context.Background(), tt.customerName, tt.customerPhone, tt.shareToken, tt.items)
+			got, gotErr := o.CreateTempOrder(context.Background(), tt.customerName, tt.customerPhone, tt.shareToken, tt.address, tt.items)
 
 			if gotErr != nil {
 				if !tt.wantErr {
 					t.Errorf("CreateTempOrder() error = %v, wantErr %v", gotErr, tt.wantErr)
 				}
+				if tt.customer != nil && tt.customer.Blocked && gotErr.Error() != apierr.ErrCustomerBlocked {
+					t.Errorf("CreateTempOrder() error = %v, want %v", gotErr, apierr.ErrCustomerBlocked)
+				}
 				return
 			}
 			if tt.wantErr {
@@ -1710,6 +2330,24 @@ func Test_oservice_CreateTempOrder(t *testing.T) {
 	}
 }
 
+func Test_tempOrderFingerprint(t *testing.T) {
+	items := []CreateTempOrderItemInput{{ProductID: 10, Qty: 2}, {ProductID: 20, Qty: 1}}
+	want := tempOrderFingerprint("+62812345678", items)
+
+	if got := tempOrderFingerprint("+62812345678", []CreateTempOrderItemInput{{ProductID: 20, Qty: 1}, {ProductID: 10, Qty: 2}}); got != want {
+		t.Errorf("tempOrderFingerprint() differs for reordered items")
+	}
+	if got := tempOrderFingerprint("+62812345678", []CreateTempOrderItemInput{{ProductID: 10, Qty: 1}, {ProductID: 10, Qty: 1}, {ProductID: 20, Qty: 1}}); got != want {
+		t.Errorf("tempOrderFingerprint() differs when a product's qty is split across lines")
+	}
+	if got := tempOrderFingerprint("+62812345678", []CreateTempOrderItemInput{{ProductID: 10, Qty: 3}, {ProductID: 20, Qty: 1}}); got == want {
+		t.Errorf("tempOrderFingerprint() matches for a different qty")
+	}
+	if got := tempOrderFingerprint("+62899999999", items); got == want {
+		t.Errorf("tempOrderFingerprint() matches for a different phone")
+	}
+}
+
 func Test_oservice_GetTempOrdersByShopID(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 	updatedTime := time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC)
@@ -1785,8 +2423,8 @@ func Test_oservice_GetTempOrdersByShopID(t *testing.T) {
 				mock.EXPECT().
 					GetTempOrdersByShopID(gomock.Any(), 5, model.OrderFilterOptions{
 						SearchQuery: strPtr("62812"),
-						DateFrom:    ptrTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
-						DateTo:      ptrTime(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+						DateFrom:    ptrTime(time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC)),
+						DateTo:      ptrTime(time.Date(2024, 2, 1, 17, 0, 0, 0, time.UTC)),
 					}).
 					Return([]model.TempOrder{
 						{ID: 1, ShopID: 5, CustomerName: "Jane Doe", CustomerPhone: "+62812345678", TotalPrice: 2500, Status: "pending", CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Valid: true, Time: updatedTime}},
@@ -1806,9 +2444,17 @@ func Test_oservice_GetTempOrdersByShopID(t *testing.T) {
 			defer ctrl.Finish()
 
 			oldStore := orderStore
-			defer func() { orderStore = oldStore }()
+			oldShopStore := shopStore
+			defer func() {
+				orderStore = oldStore
+				shopStore = oldShopStore
+			}()
 			orderStore = tt.mockSetup(ctrl)
 
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), tt.shopID).Return(&model.Shop{ID: tt.shopID, Timezone: "Asia/Jakarta"}, nil).AnyTimes()
+			shopStore = shopMock
+
 			var o oservice
 			got, gotErr := o.GetTempOrdersByShopID(context.Background(), tt.shopID, tt.opts)
 
@@ -2022,19 +2668,22 @@ func Test_oservice_createOrderFromTempOrder(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 
 	tests := []struct {
-		name        string
-		tempOrderID int
-		customerID  int
-		shopID      int
-		mockSetup   func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB)
-		want        *response.OrderData
-		wantErr     bool
+		name              string
+		tempOrderID       int
+		customerID        int
+		shopID            int
+		uniqueCodeEnabled bool
+		defaultAddress    *model.CustomerAddress
+		mockSetup         func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB)
+		want              *response.OrderData
+		wantErr           bool
 	}{
 		{
-			name:        "successfully create order from temp order with items",
-			tempOrderID: 10,
-			customerID:  5,
-			shopID:      1,
+			name:              "assigns unique code when shop has it enabled",
+			tempOrderID:       20,
+			customerID:        3,
+			shopID:            2,
+			uniqueCodeEnabled: true,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
 				mockTx := mock_database.NewMockTx(ctrl)
 				mockTx.EXPECT().Commit().Return(nil)
@@ -2044,65 +2693,50 @@ func Test_oservice_createOrderFromTempOrder(t *testing.T) {
 
 				orderMock := mock_store.NewMockOrderStore(ctrl)
 				orderMock.EXPECT().
-					GetTempOrderByID(gomock.Any(), 10, 1).
+					GetTempOrderByID(gomock.Any(), 20, 2).
 					Return(&model.TempOrder{
-						ID:            10,
-						ShopID:        1,
-						CustomerName:  "Jane Doe",
-						CustomerPhone: "+62812345678",
-						TotalPrice:    2500,
-						Status:        "pending",
-						CreatedAt:     fixedTime,
-						UpdatedAt:     sql.NullTime{},
+						ID: 20, ShopID: 2, CustomerName: "Alice", CustomerPhone: "+62811111111", TotalPrice: 50000, Status: "pending", CreatedAt: fixedTime,
 					}, nil)
 				orderMock.EXPECT().
-					CreateOrder(gomock.Any(), gomock.Any(), 5, 1, nil, gomock.Any()).
+					CreateOrder(gomock.Any(), gomock.Any(), 3, 2, nil, gomock.Any()).
 					Return(&model.Order{
-						ID:           1,
-						CustomerName: "John Doe",
-						TotalPrice:   0,
-						Status:       constant.OrderStatusCreated,
-						CreatedAt:    fixedTime,
+						ID: 2, ShopID: 2, CustomerName: "Alice", TotalPrice: 50000, Status: constant.OrderStatusCreated, CreatedAt: fixedTime,
 					}, nil)
 				orderMock.EXPECT().
-					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 10, constant.TempOrderStatusAccepted).
+					AssignUniqueCode(gomock.Any(), gomock.Any(), 2, 2).
+					Return(38, nil)
+				orderMock.EXPECT().
+					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 20, constant.TempOrderStatusAccepted).
 					Return(nil)
 
 				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
 				orderItemMock.EXPECT().
-					GetTempOrderItemsByTempOrderID(gomock.Any(), 10).
-					Return([]model.TempOrderItem{
-						{ID: 1, TempOrderID: 10, ProductID: 10, ProductName: "Product A", Price: 1000, Qty: 2, CreatedAt: fixedTime},
-						{ID: 2, TempOrderID: 10, ProductID: 20, ProductName: "Product B", Price: 500, Qty: 1, CreatedAt: fixedTime},
-					}, nil)
-				orderItemMock.EXPECT().
-					CreateOrderItem(gomock.Any(), gomock.Any(), 1, 10, 2).
-					Return(&model.OrderItem{ID: 1, OrderID: 1, ProductName: "Product A", Price: 1000, Qty: 2, CreatedAt: fixedTime}, nil)
-				orderItemMock.EXPECT().
-					CreateOrderItem(gomock.Any(), gomock.Any(), 1, 20, 1).
-					Return(&model.OrderItem{ID: 2, OrderID: 1, ProductName: "Product B", Price: 500, Qty: 1, CreatedAt: fixedTime}, nil)
+					GetTempOrderItemsByTempOrderID(gomock.Any(), 20).
+					Return([]model.TempOrderItem{}, nil)
 
 				return orderMock, orderItemMock, mockDB
 			},
 			want: &response.OrderData{
-				ID:           1,
-				CustomerName: "John Doe",
-				TotalPrice:   0,
+				ID:           2,
+				CustomerName: "Alice",
+				TotalPrice:   50000,
+				UniqueCode:   38,
+				AmountDue:    50038,
 				Status:       constant.OrderStatusCreated,
-				OrderItems: []response.OrderItemData{
-					{ID: 1, OrderID: 1, ProductName: "Product A", Price: 1000, Qty: 2, CreatedAt: fixedTime},
-					{ID: 2, OrderID: 1, ProductName: "Product B", Price: 500, Qty: 1, CreatedAt: fixedTime},
-				},
-				CreatedAt: fixedTime,
+				OrderItems:   []response.OrderItemData{},
+				CreatedAt:    fixedTime,
 			},
 			wantErr: false,
 		},
 		{
-			name:        "successfully create order from temp order with no items",
-			tempOrderID: 20,
+			name:        "copies the address sent with the temp order",
+			tempOrderID: 30,
 			customerID:  3,
 			shopID:      2,
+			// the form address wins over the customer's default
+			defaultAddress: &model.CustomerAddress{ID: 9, CustomerID: 3, Recipient: "Alice", Address: "Jl. Lama 2", IsDefault: true},
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				address := &model.ShippingAddress{Recipient: "Alice", Address: "Jl. Baru 5", City: "Bandung"}
 				mockTx := mock_database.NewMockTx(ctrl)
 				mockTx.EXPECT().Commit().Return(nil)
 				mockTx.EXPECT().Rollback().Return(nil)
@@ -2111,67 +2745,232 @@ func Test_oservice_createOrderFromTempOrder(t *testing.T) {
 
 				orderMock := mock_store.NewMockOrderStore(ctrl)
 				orderMock.EXPECT().
-					GetTempOrderByID(gomock.Any(), 20, 2).
+					GetTempOrderByID(gomock.Any(), 30, 2).
 					Return(&model.TempOrder{
-						ID:            20,
-						ShopID:        2,
-						CustomerName:  "Alice",
-						CustomerPhone: "+62811111111",
-						TotalPrice:    0,
-						Status:        "pending",
-						CreatedAt:     fixedTime,
-						UpdatedAt:     sql.NullTime{},
+						ID: 30, ShopID: 2, CustomerName: "Alice", TotalPrice: 50000, Status: "pending", ShippingAddress: address, CreatedAt: fixedTime,
 					}, nil)
 				orderMock.EXPECT().
 					CreateOrder(gomock.Any(), gomock.Any(), 3, 2, nil, gomock.Any()).
 					Return(&model.Order{
-						ID:           2,
-						CustomerName: "Alice",
-						TotalPrice:   0,
-						Status:       constant.OrderStatusCreated,
-						CreatedAt:    fixedTime,
+						ID: 4, ShopID: 2, CustomerName: "Alice", TotalPrice: 50000, Status: constant.OrderStatusCreated, CreatedAt: fixedTime,
 					}, nil)
 				orderMock.EXPECT().
-					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 20, constant.TempOrderStatusAccepted).
+					SetShippingAddress(gomock.Any(), gomock.Any(), 4, address).
+					Return(nil)
+				orderMock.EXPECT().
+					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 30, constant.TempOrderStatusAccepted).
 					Return(nil)
 
 				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
 				orderItemMock.EXPECT().
-					GetTempOrderItemsByTempOrderID(gomock.Any(), 20).
+					GetTempOrderItemsByTempOrderID(gomock.Any(), 30).
 					Return([]model.TempOrderItem{}, nil)
 
 				return orderMock, orderItemMock, mockDB
 			},
 			want: &response.OrderData{
-				ID:           2,
-				CustomerName: "Alice",
-				TotalPrice:   0,
-				Status:       constant.OrderStatusCreated,
-				OrderItems:   []response.OrderItemData{},
-				CreatedAt:    fixedTime,
+				ID:              4,
+				CustomerName:    "Alice",
+				TotalPrice:      50000,
+				Status:          constant.OrderStatusCreated,
+				ShippingAddress: &response.ShippingAddressData{Recipient: "Alice", Address: "Jl. Baru 5", City: "Bandung"},
+				OrderItems:      []response.OrderItemData{},
+				CreatedAt:       fixedTime,
 			},
 			wantErr: false,
 		},
 		{
-			name:        "returns error when GetTempOrderByID fails",
-			tempOrderID: 10,
-			customerID:  5,
-			shopID:      1,
+			name:           "falls back to the customer's default address",
+			tempOrderID:    31,
+			customerID:     3,
+			shopID:         2,
+			defaultAddress: &model.CustomerAddress{ID: 9, CustomerID: 3, Recipient: "Alice", Address: "Jl. Lama 2", IsDefault: true},
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				addressID := 9
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
 				orderMock := mock_store.NewMockOrderStore(ctrl)
 				orderMock.EXPECT().
-					GetTempOrderByID(gomock.Any(), 10, 1).
-					Return(nil, errors.New("database error"))
-				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
-				return orderMock, orderItemMock, nil
-			},
-			want:    nil,
-			wantErr: true,
-		},
-		{
-			name:        "returns error when temp order not found",
-			tempOrderID: 999,
-			customerID:  5,
+					GetTempOrderByID(gomock.Any(), 31, 2).
+					Return(&model.TempOrder{
+						ID: 31, ShopID: 2, CustomerName: "Alice", TotalPrice: 50000, Status: "pending", CreatedAt: fixedTime,
+					}, nil)
+				orderMock.EXPECT().
+					CreateOrder(gomock.Any(), gomock.Any(), 3, 2, nil, gomock.Any()).
+					Return(&model.Order{
+						ID: 5, ShopID: 2, CustomerName: "Alice", TotalPrice: 50000, Status: constant.OrderStatusCreated, CreatedAt: fixedTime,
+					}, nil)
+				orderMock.EXPECT().
+					SetShippingAddress(gomock.Any(), gomock.Any(), 5, &model.ShippingAddress{AddressID: &addressID, Recipient: "Alice", Address: "Jl. Lama 2"}).
+					Return(nil)
+				orderMock.EXPECT().
+					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 31, constant.TempOrderStatusAccepted).
+					Return(nil)
+
+				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
+				orderItemMock.EXPECT().
+					GetTempOrderItemsByTempOrderID(gomock.Any(), 31).
+					Return([]model.TempOrderItem{}, nil)
+
+				return orderMock, orderItemMock, mockDB
+			},
+			want: &response.OrderData{
+				ID:              5,
+				CustomerName:    "Alice",
+				TotalPrice:      50000,
+				Status:          constant.OrderStatusCreated,
+				ShippingAddress: &response.ShippingAddressData{AddressID: func() *int { id := 9; return &id }(), Recipient: "Alice", Address: "Jl. Lama 2"},
+				OrderItems:      []response.OrderItemData{},
+				CreatedAt:       fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "successfully create order from temp order with items",
+			tempOrderID: 10,
+			customerID:  5,
+			shopID:      1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					GetTempOrderByID(gomock.Any(), 10, 1).
+					Return(&model.TempOrder{
+						ID:            10,
+						ShopID:        1,
+						CustomerName:  "Jane Doe",
+						CustomerPhone: "+62812345678",
+						TotalPrice:    2500,
+						Status:        "pending",
+						CreatedAt:     fixedTime,
+						UpdatedAt:     sql.NullTime{},
+					}, nil)
+				orderMock.EXPECT().
+					CreateOrder(gomock.Any(), gomock.Any(), 5, 1, nil, gomock.Any()).
+					Return(&model.Order{
+						ID:           1,
+						CustomerName: "John Doe",
+						TotalPrice:   0,
+						Status:       constant.OrderStatusCreated,
+						CreatedAt:    fixedTime,
+					}, nil)
+				orderMock.EXPECT().
+					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 10, constant.TempOrderStatusAccepted).
+					Return(nil)
+
+				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
+				orderItemMock.EXPECT().
+					GetTempOrderItemsByTempOrderID(gomock.Any(), 10).
+					Return([]model.TempOrderItem{
+						{ID: 1, TempOrderID: 10, ProductID: 10, ProductName: "Product A", Price: 1000, Qty: 2, CreatedAt: fixedTime},
+						{ID: 2, TempOrderID: 10, ProductID: 20, ProductName: "Product B", Price: 500, Qty: 1, CreatedAt: fixedTime},
+					}, nil)
+				orderItemMock.EXPECT().
+					CreateOrderItem(gomock.Any(), gomock.Any(), 1, 10, 2).
+					Return(&model.OrderItem{ID: 1, OrderID: 1, ProductName: "Product A", Price: 1000, Qty: 2, CreatedAt: fixedTime}, nil)
+				orderItemMock.EXPECT().
+					CreateOrderItem(gomock.Any(), gomock.Any(), 1, 20, 1).
+					Return(&model.OrderItem{ID: 2, OrderID: 1, ProductName: "Product B", Price: 500, Qty: 1, CreatedAt: fixedTime}, nil)
+
+				return orderMock, orderItemMock, mockDB
+			},
+			want: &response.OrderData{
+				ID:           1,
+				CustomerName: "John Doe",
+				TotalPrice:   0,
+				Status:       constant.OrderStatusCreated,
+				OrderItems: []response.OrderItemData{
+					{ID: 1, OrderID: 1, ProductName: "Product A", Price: 1000, Qty: 2, CreatedAt: fixedTime},
+					{ID: 2, OrderID: 1, ProductName: "Product B", Price: 500, Qty: 1, CreatedAt: fixedTime},
+				},
+				CreatedAt: fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "successfully create order from temp order with no items",
+			tempOrderID: 20,
+			customerID:  3,
+			shopID:      2,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					GetTempOrderByID(gomock.Any(), 20, 2).
+					Return(&model.TempOrder{
+						ID:            20,
+						ShopID:        2,
+						CustomerName:  "Alice",
+						CustomerPhone: "+62811111111",
+						TotalPrice:    0,
+						Status:        "pending",
+						CreatedAt:     fixedTime,
+						UpdatedAt:     sql.NullTime{},
+					}, nil)
+				orderMock.EXPECT().
+					CreateOrder(gomock.Any(), gomock.Any(), 3, 2, nil, gomock.Any()).
+					Return(&model.Order{
+						ID:           2,
+						CustomerName: "Alice",
+						TotalPrice:   0,
+						Status:       constant.OrderStatusCreated,
+						CreatedAt:    fixedTime,
+					}, nil)
+				orderMock.EXPECT().
+					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 20, constant.TempOrderStatusAccepted).
+					Return(nil)
+
+				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
+				orderItemMock.EXPECT().
+					GetTempOrderItemsByTempOrderID(gomock.Any(), 20).
+					Return([]model.TempOrderItem{}, nil)
+
+				return orderMock, orderItemMock, mockDB
+			},
+			want: &response.OrderData{
+				ID:           2,
+				CustomerName: "Alice",
+				TotalPrice:   0,
+				Status:       constant.OrderStatusCreated,
+				OrderItems:   []response.OrderItemData{},
+				CreatedAt:    fixedTime,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "returns error when GetTempOrderByID fails",
+			tempOrderID: 10,
+			customerID:  5,
+			shopID:      1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					GetTempOrderByID(gomock.Any(), 10, 1).
+					Return(nil, errors.New("database error"))
+				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
+				return orderMock, orderItemMock, nil
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:        "returns error when temp order not found",
+			tempOrderID: 999,
+			customerID:  5,
 			shopID:      1,
 			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_database.MockDB) {
 				orderMock := mock_store.NewMockOrderStore(ctrl)
@@ -2294,14 +3093,29 @@ func Test_oservice_createOrderFromTempOrder(t *testing.T) {
 			orderMock, orderItemMock, mockDB := tt.mockSetup(ctrl)
 			oldOrderStore := orderStore
 			oldOrderItemStore := orderItemStore
+			oldShopStore := shopStore
+			oldDPRuleStore := dpRuleStore
+			oldAddressStore := customerAddressStore
 			oldDBGetter := dbGetter
 			defer func() {
 				orderStore = oldOrderStore
 				orderItemStore = oldOrderItemStore
+				shopStore = oldShopStore
+				dpRuleStore = oldDPRuleStore
+				customerAddressStore = oldAddressStore
 				dbGetter = oldDBGetter
 			}()
 			orderStore = orderMock
 			orderItemStore = orderItemMock
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), tt.shopID).Return(&model.Shop{ID: tt.shopID, UniqueCodeEnabled: tt.uniqueCodeEnabled}, nil).AnyTimes()
+			shopStore = shopMock
+			dpRuleMock := mock_store.NewMockDPRuleStore(ctrl)
+			dpRuleMock.EXPECT().GetDefaultDPRuleByShopID(gomock.Any(), tt.shopID).Return(nil, nil).AnyTimes()
+			dpRuleStore = dpRuleMock
+			addressMock := mock_store.NewMockCustomerAddressStore(ctrl)
+			addressMock.EXPECT().GetDefaultCustomerAddress(gomock.Any(), tt.customerID).Return(tt.defaultAddress, nil).AnyTimes()
+			customerAddressStore = addressMock
 			if mockDB != nil {
 				dbGetter = func() database.DB { return mockDB }
 			}
@@ -2390,7 +3204,7 @@ func Test_oservice_resolveActiveOrderConflict(t *testing.T) {
 
 				orderPaymentMock := mock_store.NewMockOrderPaymentStore(ctrl)
 				orderPaymentMock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 7).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
 					Return([]model.OrderPayment{}, nil)
 
 				return orderMock, orderItemMock, orderPaymentMock, mockDB
@@ -2408,6 +3222,63 @@ func Test_oservice_resolveActiveOrderConflict(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:          "copies the temp order address onto an active order without one",
+			tempOrderID:   11,
+			shopID:        1,
+			activeOrderID: 7,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore, *mock_database.MockDB) {
+				address := &model.ShippingAddress{Recipient: "Jane", Address: "Jl. Baru 5"}
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					GetTempOrderByID(gomock.Any(), 11, 1).
+					Return(&model.TempOrder{
+						ID: 11, ShopID: 1, CustomerName: "Jane", CustomerPhone: "+62", Status: "pending", ShippingAddress: address, CreatedAt: fixedTime,
+					}, nil)
+				orderMock.EXPECT().
+					GetOrderByID(gomock.Any(), 7, 1).
+					Return(&model.Order{
+						ID: 7, ShopID: 1, CustomerName: "John Doe", Status: constant.OrderStatusInProgress, CreatedAt: fixedTime,
+					}, nil)
+				orderMock.EXPECT().
+					SetShippingAddress(gomock.Any(), gomock.Any(), 7, address).
+					Return(nil)
+				orderMock.EXPECT().
+					UpdateTempOrderStatus(gomock.Any(), gomock.Any(), 11, constant.TempOrderStatusAccepted).
+					Return(nil)
+
+				orderItemMock := mock_store.NewMockOrderItemStore(ctrl)
+				orderItemMock.EXPECT().
+					GetTempOrderItemsByTempOrderID(gomock.Any(), 11).
+					Return([]model.TempOrderItem{}, nil)
+				orderItemMock.EXPECT().
+					GetOrderItemsByOrderID(gomock.Any(), 7).
+					Return([]model.OrderItem{}, nil).
+					Times(2)
+
+				orderPaymentMock := mock_store.NewMockOrderPaymentStore(ctrl)
+				orderPaymentMock.EXPECT().
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
+					Return([]model.OrderPayment{}, nil)
+
+				return orderMock, orderItemMock, orderPaymentMock, mockDB
+			},
+			want: &response.OrderData{
+				ID:              7,
+				CustomerName:    "John Doe",
+				Status:          constant.OrderStatusInProgress,
+				ShippingAddress: &response.ShippingAddressData{Recipient: "Jane", Address: "Jl. Baru 5"},
+				OrderItems:      []response.OrderItemData{},
+				CreatedAt:       fixedTime,
+			},
+			wantErr: false,
+		},
 		{
 			name:          "successfully merges temp order item that exists in active order (update qty)",
 			tempOrderID:   10,
@@ -2461,7 +3332,7 @@ func Test_oservice_resolveActiveOrderConflict(t *testing.T) {
 
 				orderPaymentMock := mock_store.NewMockOrderPaymentStore(ctrl)
 				orderPaymentMock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 7).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
 					Return([]model.OrderPayment{}, nil)
 
 				return orderMock, orderItemMock, orderPaymentMock, mockDB
@@ -2541,7 +3412,7 @@ func Test_oservice_resolveActiveOrderConflict(t *testing.T) {
 
 				orderPaymentMock := mock_store.NewMockOrderPaymentStore(ctrl)
 				orderPaymentMock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 7).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
 					Return([]model.OrderPayment{}, nil)
 
 				return orderMock, orderItemMock, orderPaymentMock, mockDB
@@ -2654,7 +3525,7 @@ func Test_oservice_resolveActiveOrderConflict(t *testing.T) {
 
 				orderPaymentMock := mock_store.NewMockOrderPaymentStore(ctrl)
 				orderPaymentMock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 7).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
 					Return([]model.OrderPayment{}, nil)
 
 				return orderMock, orderItemMock, orderPaymentMock, mockDB
@@ -2823,7 +3694,7 @@ func Test_oservice_MergeTempOrder(t *testing.T) {
 
 				orderPaymentMock := mock_store.NewMockOrderPaymentStore(ctrl)
 				orderPaymentMock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 7).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
 					Return([]model.OrderPayment{}, nil)
 
 				return orderMock, orderItemMock, orderPaymentMock, mockDB
@@ -2848,16 +3719,31 @@ func Test_oservice_MergeTempOrder(t *testing.T) {
 			oldOrderStore := orderStore
 			oldOrderItemStore := orderItemStore
 			oldOrderPaymentStore := orderPaymentStore
+			oldShopStore := shopStore
+			oldDPRuleStore := dpRuleStore
+			oldAddressStore := customerAddressStore
 			oldDBGetter := dbGetter
 			defer func() {
 				orderStore = oldOrderStore
 				orderItemStore = oldOrderItemStore
 				orderPaymentStore = oldOrderPaymentStore
+				shopStore = oldShopStore
+				dpRuleStore = oldDPRuleStore
+				customerAddressStore = oldAddressStore
 				dbGetter = oldDBGetter
 			}()
 			orderStore = orderMock
 			orderItemStore = orderItemMock
 			orderPaymentStore = orderPaymentMock
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), tt.shopID).Return(&model.Shop{ID: tt.shopID}, nil).AnyTimes()
+			shopStore = shopMock
+			dpRuleMock := mock_store.NewMockDPRuleStore(ctrl)
+			dpRuleMock.EXPECT().GetDefaultDPRuleByShopID(gomock.Any(), tt.shopID).Return(nil, nil).AnyTimes()
+			dpRuleStore = dpRuleMock
+			addressMock := mock_store.NewMockCustomerAddressStore(ctrl)
+			addressMock.EXPECT().GetDefaultCustomerAddress(gomock.Any(), tt.customerID).Return(nil, nil).AnyTimes()
+			customerAddressStore = addressMock
 			if mockDB != nil {
 				dbGetter = func() database.DB { return mockDB }
 			}
@@ -3032,110 +3918,194 @@ func Test_oservice_RejectTempOrderByID(t *testing.T) {
 func Test_oservice_GenerateOrderInvoice(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 
+	backend := useMemoryStorage(t)
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Put(context.Background(), "logos/logo.png", "image/png", logo.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Put(context.Background(), "logos/logo.webp", "image/webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")); err != nil {
+		t.Fatal(err)
+	}
+
+	type mocks struct {
+		order    *mock_store.MockOrderStore
+		item     *mock_store.MockOrderItemStore
+		payment  *mock_store.MockOrderPaymentStore
+		shop     *mock_store.MockShopStore
+		sequence *mock_store.MockDocumentSequenceStore
+		db       *mock_database.MockDB
+		tx       *mock_database.MockTx
+	}
+
+	// expectOrder sets up GetOrderByID for an order with the given items.
+	expectOrder := func(m mocks, order *model.Order, items []model.OrderItem) {
+		m.order.EXPECT().GetOrderByID(gomock.Any(), order.ID, 1).Return(order, nil)
+		m.item.EXPECT().GetOrderItemsByOrderID(gomock.Any(), order.ID).Return(items, nil)
+		m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), order.ID).Return([]model.OrderPayment{}, nil)
+	}
+	// expectExistingNumber sets up an order that already has an invoice number.
+	expectExistingNumber := func(m mocks, orderID int, number string) {
+		m.db.EXPECT().Begin().Return(m.tx, nil)
+		m.order.EXPECT().GetInvoiceNumberForUpdate(gomock.Any(), m.tx, orderID).Return(number, nil)
+		m.tx.EXPECT().Rollback().Return(nil)
+	}
+
 	tests := []struct {
 		name         string
 		orderID      int
-		shopID       int
 		message      string
-		mockSetup    func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore)
-		wantContains []string // strings that must appear in the PDF bytes
-		wantAbsent   []string // strings that must NOT appear in the PDF bytes
+		lang         string
+		mockSetup    func(m mocks)
+		wantContains []string // text that must appear in the PDF
+		wantAbsent   []string // text that must NOT appear in the PDF
+		wantImage    bool
 		wantErr      bool
 		wantErrMsg   string
 	}{
 		{
-			name:    "invoice contains order metadata, items, totals and custom message",
+			name:    "invoice contains shop branding, order metadata, items, totals and custom message",
 			orderID: 1,
-			shopID:  1,
 			message: "Thank you!\nSee you again.",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore) {
-				mockOrder := mock_store.NewMockOrderStore(ctrl)
-				mockOrder.EXPECT().
-					GetOrderByID(gomock.Any(), 1, 1).
-					Return(&model.Order{
-						ID:           1,
-						CustomerName: "John Doe",
-						TotalPrice:   15000,
-						Status:       "done",
-						CreatedAt:    fixedTime,
-					}, nil)
-				mockItem := mock_store.NewMockOrderItemStore(ctrl)
-				mockItem.EXPECT().
-					GetOrderItemsByOrderID(gomock.Any(), 1).
-					Return([]model.OrderItem{
-						{ID: 1, OrderID: 1, ProductName: "Product A", Price: 10000, Qty: 1, CreatedAt: fixedTime},
-						{ID: 2, OrderID: 1, ProductName: "Product B", Price: 5000, Qty: 1, CreatedAt: fixedTime},
-					}, nil)
-				mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
-				mockPayment.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 1).
-					Return([]model.OrderPayment{}, nil)
-				return mockOrder, mockItem, mockPayment
+			lang:    "en",
+			mockSetup: func(m mocks) {
+				expectOrder(m, &model.Order{
+					ID:           1,
+					CustomerName: "John Doe",
+					TotalPrice:   15000,
+					Status:       "done",
+					CreatedAt:    fixedTime,
+				}, []model.OrderItem{
+					{ID: 1, OrderID: 1, ProductName: "Product A", Price: 10000, Qty: 1, CreatedAt: fixedTime},
+					{ID: 2, OrderID: 1, ProductName: "Product B", Price: 5000, Qty: 1, CreatedAt: fixedTime},
+				})
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{
+					ID:                   1,
+					Name:                 "Toko Jastip",
+					Address:              "Jl. Melati 5, Bandung",
+					BankAccounts:         "BCA 1234567890 a.n. Toko Jastip\nMandiri 0987654321",
+					InvoiceFooter:        "Barang yang sudah dibeli tidak dapat dikembalikan",
+					InvoiceNumberPattern: "FAK-{seq:5}",
+				}, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.order.EXPECT().GetInvoiceNumberForUpdate(gomock.Any(), m.tx, 1).Return("", nil)
+				m.sequence.EXPECT().NextValue(gomock.Any(), m.tx, 1, constant.DocumentTypeInvoice).Return(42, nil)
+				m.order.EXPECT().SetInvoiceNumber(gomock.Any(), m.tx, 1, 42, "FAK-00042").Return(nil)
+				m.tx.EXPECT().Commit().Return(nil)
+				m.tx.EXPECT().Rollback().Return(nil)
 			},
 			wantContains: []string{
-				"%PDF",
+				"Toko Jastip",
+				"Jl. Melati 5, Bandung",
 				"INVOICE",
-				"John Doe",           // customer name
-				"15 January 2024",    // formatted date
-				"Product A",          // item name
-				"Product B",          // item name
-				"10.000",             // unit price of Product A
-				"5.000",              // unit price of Product B
-				"15.000",             // total price
-				"Thank you!",         // first line of message
-				"See you again.",     // second line of message
+				"FAK-00042",       // invoice number
+				"John Doe",        // customer name
+				"15 January 2024", // formatted date
+				"Product A",
+				"Product B",
+				"10.000", // unit price of Product A
+				"5.000",  // unit price of Product B
+				"15.000", // total price
+				"Pay to:",
+				"BCA 1234567890 a.n. Toko Jastip",
+				"Mandiri 0987654321",
+				"Thank you!",     // first line of message
+				"See you again.", // second line of message
+				"Barang yang sudah dibeli tidak dapat dikembalikan",
 			},
 		},
 		{
-			name:    "invoice without message has no footer text",
+			name:    "invoice without message or bank accounts reuses the assigned number",
 			orderID: 2,
-			shopID:  1,
-			message: "",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore) {
-				mockOrder := mock_store.NewMockOrderStore(ctrl)
-				mockOrder.EXPECT().
-					GetOrderByID(gomock.Any(), 2, 1).
-					Return(&model.Order{
-						ID:           2,
-						CustomerName: "Jane Doe",
-						TotalPrice:   5000,
-						Status:       "done",
-						CreatedAt:    fixedTime,
-					}, nil)
-				mockItem := mock_store.NewMockOrderItemStore(ctrl)
-				mockItem.EXPECT().
-					GetOrderItemsByOrderID(gomock.Any(), 2).
-					Return([]model.OrderItem{
-						{ID: 3, OrderID: 2, ProductName: "Product C", Price: 5000, Qty: 1, CreatedAt: fixedTime},
-					}, nil)
-				mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
-				mockPayment.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 2).
-					Return([]model.OrderPayment{}, nil)
-				return mockOrder, mockItem, mockPayment
+			lang:    "en",
+			mockSetup: func(m mocks) {
+				expectOrder(m, &model.Order{
+					ID:           2,
+					CustomerName: "Jane Doe",
+					TotalPrice:   5000,
+					Status:       "done",
+					CreatedAt:    fixedTime,
+				}, []model.OrderItem{
+					{ID: 3, OrderID: 2, ProductName: "Product C", Price: 5000, Qty: 1, CreatedAt: fixedTime},
+				})
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, Name: "Toko Jastip"}, nil)
+				expectExistingNumber(m, 2, "INV/2024/01/7")
 			},
 			wantContains: []string{
-				"%PDF",
 				"INVOICE",
+				"INV/2024/01/7",
 				"Jane Doe",
 				"15 January 2024",
 				"Product C",
 				"5.000",
 			},
+			wantAbsent: []string{"Pay to:"},
+		},
+		{
+			name:    "indonesian labels and non-latin product names",
+			orderID: 3,
+			lang:    "id",
+			mockSetup: func(m mocks) {
+				expectOrder(m, &model.Order{
+					ID:           3,
+					CustomerName: "Nguyễn Thị Hương",
+					TotalPrice:   20000,
+					UniqueCode:   123,
+					Status:       "created",
+					CreatedAt:    fixedTime,
+				}, []model.OrderItem{
+					{ID: 4, OrderID: 3, ProductName: "Nón lá Việt", Price: 10000, Qty: 1, CreatedAt: fixedTime},
+					{ID: 5, OrderID: 3, ProductName: "Матрёшка", Price: 10000, Qty: 1, ShortageQty: 2, CreatedAt: fixedTime},
+				})
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, Name: "Toko Jastip", BankAccounts: "BCA 1234567890"}, nil)
+				expectExistingNumber(m, 3, "INV/2024/01/8")
+			},
+			wantContains: []string{
+				"FAKTUR",
+				"No. Faktur:",
+				"15 Januari 2024",
+				"Pelanggan:",
+				"Nguyễn Thị Hương",
+				"Nón lá Việt",
+				"Матрёшка (2 stok habis)",
+				"Kode unik",
+				"Jumlah transfer (harus tepat)",
+				"20.123",
+				"Pembayaran ke:",
+			},
+			wantAbsent: []string{"INVOICE", "January"},
+		},
+		{
+			name:    "invoice embeds the shop logo",
+			orderID: 4,
+			lang:    "en",
+			mockSetup: func(m mocks) {
+				expectOrder(m, &model.Order{ID: 4, CustomerName: "John Doe", CreatedAt: fixedTime}, []model.OrderItem{})
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, Name: "Toko Jastip", LogoURL: "/uploads/logos/logo.png"}, nil)
+				expectExistingNumber(m, 4, "INV/2024/01/9")
+			},
+			wantContains: []string{"Toko Jastip"},
+			wantImage:    true,
+		},
+		{
+			name:    "invoice skips a logo fpdf cannot embed",
+			orderID: 5,
+			lang:    "en",
+			mockSetup: func(m mocks) {
+				expectOrder(m, &model.Order{ID: 5, CustomerName: "John Doe", CreatedAt: fixedTime}, []model.OrderItem{})
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, Name: "Toko Jastip", LogoURL: "/uploads/logos/logo.webp"}, nil)
+				expectExistingNumber(m, 5, "INV/2024/01/10")
+			},
+			wantContains: []string{"Toko Jastip"},
 		},
 		{
 			name:    "returns error when order not found",
 			orderID: 999,
-			shopID:  1,
-			message: "",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore) {
-				mockOrder := mock_store.NewMockOrderStore(ctrl)
-				mockOrder.EXPECT().
-					GetOrderByID(gomock.Any(), 999, 1).
-					Return(nil, nil)
-				mockItem := mock_store.NewMockOrderItemStore(ctrl)
-				mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
-				return mockOrder, mockItem, mockPayment
+			lang:    "en",
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 999, 1).Return(nil, nil)
 			},
 			wantErr:    true,
 			wantErrMsg: apierr.ErrOrderNotFound,
@@ -3143,16 +4113,23 @@ func Test_oservice_GenerateOrderInvoice(t *testing.T) {
 		{
 			name:    "returns error when store fails",
 			orderID: 1,
-			shopID:  1,
-			message: "",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderItemStore, *mock_store.MockOrderPaymentStore) {
-				mockOrder := mock_store.NewMockOrderStore(ctrl)
-				mockOrder.EXPECT().
-					GetOrderByID(gomock.Any(), 1, 1).
-					Return(nil, errors.New("database error"))
-				mockItem := mock_store.NewMockOrderItemStore(ctrl)
-				mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
-				return mockOrder, mockItem, mockPayment
+			lang:    "en",
+			mockSetup: func(m mocks) {
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 1, 1).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "returns error when the invoice number cannot be allocated",
+			orderID: 1,
+			lang:    "en",
+			mockSetup: func(m mocks) {
+				expectOrder(m, &model.Order{ID: 1, CreatedAt: fixedTime}, []model.OrderItem{})
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1}, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.order.EXPECT().GetInvoiceNumberForUpdate(gomock.Any(), m.tx, 1).Return("", nil)
+				m.sequence.EXPECT().NextValue(gomock.Any(), m.tx, 1, constant.DocumentTypeInvoice).Return(0, errors.New("database error"))
+				m.tx.EXPECT().Rollback().Return(nil)
 			},
 			wantErr: true,
 		},
@@ -3164,15 +4141,31 @@ func Test_oservice_GenerateOrderInvoice(t *testing.T) {
 			defer ctrl.Finish()
 
 			oldOrderStore, oldOrderItemStore, oldOrderPaymentStore := orderStore, orderItemStore, orderPaymentStore
-			defer func() { orderStore, orderItemStore, orderPaymentStore = oldOrderStore, oldOrderItemStore, oldOrderPaymentStore }()
+			oldShopStore, oldSequenceStore, oldDBGetter := shopStore, documentSequenceStore, dbGetter
+			defer func() {
+				orderStore, orderItemStore, orderPaymentStore = oldOrderStore, oldOrderItemStore, oldOrderPaymentStore
+				shopStore, documentSequenceStore, dbGetter = oldShopStore, oldSequenceStore, oldDBGetter
+			}()
 
-			mockOrder, mockItem, mockPayment := tt.mockSetup(ctrl)
-			orderStore = mockOrder
-			orderItemStore = mockItem
-			orderPaymentStore = mockPayment
+			m := mocks{
+				order:    mock_store.NewMockOrderStore(ctrl),
+				item:     mock_store.NewMockOrderItemStore(ctrl),
+				payment:  mock_store.NewMockOrderPaymentStore(ctrl),
+				shop:     mock_store.NewMockShopStore(ctrl),
+				sequence: mock_store.NewMockDocumentSequenceStore(ctrl),
+				db:       mock_database.NewMockDB(ctrl),
+				tx:       mock_database.NewMockTx(ctrl),
+			}
+			tt.mockSetup(m)
+			orderStore = m.order
+			orderItemStore = m.item
+			orderPaymentStore = m.payment
+			shopStore = m.shop
+			documentSequenceStore = m.sequence
+			dbGetter = func() database.DB { return m.db }
 
 			var o oservice
-			got, gotErr := o.GenerateOrderInvoice(context.Background(), tt.orderID, tt.shopID, tt.message)
+			got, gotErr := o.GenerateOrderInvoice(context.Background(), tt.orderID, 1, tt.message, tt.lang)
 
 			if gotErr != nil {
 				if !tt.wantErr {
@@ -3186,20 +4179,38 @@ func Test_oservice_GenerateOrderInvoice(t *testing.T) {
 			if tt.wantErr {
 				t.Fatal("GenerateOrderInvoice() succeeded unexpectedly")
 			}
+			if !strings.HasPrefix(string(got), "%PDF") {
+				t.Errorf("GenerateOrderInvoice() output is not a PDF")
+			}
 			for _, want := range tt.wantContains {
-				if !strings.Contains(string(got), want) {
+				if !strings.Contains(string(got), pdfText(want)) {
 					t.Errorf("GenerateOrderInvoice() PDF missing expected content %q", want)
 				}
 			}
 			for _, absent := range tt.wantAbsent {
-				if strings.Contains(string(got), absent) {
+				if strings.Contains(string(got), pdfText(absent)) {
 					t.Errorf("GenerateOrderInvoice() PDF contains unexpected content %q", absent)
 				}
 			}
+			if gotImage := strings.Contains(string(got), "/Subtype /Image"); gotImage != tt.wantImage {
+				t.Errorf("GenerateOrderInvoice() PDF has image = %v, want %v", gotImage, tt.wantImage)
+			}
 		})
 	}
 }
 
+// pdfText returns s the way fpdf writes text set in a UTF-8 font into an
+// uncompressed content stream: UTF-16BE, with PDF string escapes.
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, u := range utf16.Encode([]rune(s)) {
+		b.WriteByte(byte(u >> 8))
+		b.WriteByte(byte(u))
+	}
+	r := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`)
+	return r.Replace(b.String())
+}
+
 func Test_oservice_CreateOrderPayment(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 
@@ -3230,6 +4241,28 @@ func Test_oservice_CreateOrderPayment(t *testing.T) {
 			want:    response.OrderPaymentData{ID: 1, OrderID: 1, Amount: 50000, CreatedAt: fixedTime},
 			wantErr: false,
 		},
+		{
+			name:    "keeps order outstanding while payments are below amount due",
+			orderID: 1,
+			amount:  50000,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockOrderPaymentStore) {
+				mockOrder := mock_store.NewMockOrderStore(ctrl)
+				mockOrder.EXPECT().
+					GetOrderByID(gomock.Any(), 1).
+					Return(&model.Order{ID: 1, ShopID: 1, TotalPrice: 150000, UniqueCode: 417, Status: constant.OrderStatusCreated}, nil)
+
+				mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
+				mockPayment.EXPECT().
+					CreateOrderPayment(gomock.Any(), nil, 1, 50000).
+					Return(&model.OrderPayment{ID: 1, OrderID: 1, Amount: 50000, CreatedAt: fixedTime}, nil)
+				mockPayment.EXPECT().
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 1).
+					Return([]model.OrderPayment{{ID: 1, OrderID: 1, Amount: 50000}}, nil)
+				return mockOrder, mockPayment
+			},
+			want:    response.OrderPaymentData{ID: 1, OrderID: 1, Amount: 50000, CreatedAt: fixedTime},
+			wantErr: false,
+		},
 		{
 			name:    "returns error when order not found",
 			orderID: 999,
@@ -3314,6 +4347,91 @@ func Test_oservice_CreateOrderPayment(t *testing.T) {
 	}
 }
 
+func Test_settleUniqueCode(t *testing.T) {
+	paid := constant.OrderPaymentStatusPaid
+	released := 0
+
+	tests := []struct {
+		name      string
+		order     *model.Order
+		paid      int
+		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockShopStore)
+		wantErr   bool
+	}{
+		{
+			name:  "does nothing for orders without a code",
+			order: &model.Order{ID: 1, ShopID: 1, TotalPrice: 150000},
+			paid:  150000,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockShopStore) {
+				return mock_store.NewMockOrderStore(ctrl), mock_store.NewMockShopStore(ctrl)
+			},
+		},
+		{
+			name:  "does nothing while payments are below amount due",
+			order: &model.Order{ID: 1, ShopID: 1, TotalPrice: 150000, UniqueCode: 417},
+			paid:  150000,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockShopStore) {
+				return mock_store.NewMockOrderStore(ctrl), mock_store.NewMockShopStore(ctrl)
+			},
+		},
+		{
+			name:  "marks paid and releases the code",
+			order: &model.Order{ID: 1, ShopID: 1, TotalPrice: 150000, UniqueCode: 417},
+			paid:  150417,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockShopStore) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, UniqueCodeEnabled: true}, nil)
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					UpdateOrder(gomock.Any(), nil, 1, store.UpdateOrderInput{PaymentStatus: &paid, UniqueCode: &released}).
+					Return(&model.Order{ID: 1}, nil)
+				return orderMock, shopMock
+			},
+		},
+		{
+			name:  "marks paid and keeps the code as a fee",
+			order: &model.Order{ID: 1, ShopID: 1, TotalPrice: 150000, UniqueCode: 417},
+			paid:  150417,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockShopStore) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, UniqueCodeEnabled: true, UniqueCodeAsFee: true}, nil)
+				orderMock := mock_store.NewMockOrderStore(ctrl)
+				orderMock.EXPECT().
+					UpdateOrder(gomock.Any(), nil, 1, store.UpdateOrderInput{PaymentStatus: &paid}).
+					Return(&model.Order{ID: 1}, nil)
+				return orderMock, shopMock
+			},
+		},
+		{
+			name:  "returns error on shop store failure",
+			order: &model.Order{ID: 1, ShopID: 1, TotalPrice: 150000, UniqueCode: 417},
+			paid:  150417,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockOrderStore, *mock_store.MockShopStore) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().GetShopByID(gomock.Any(), 1).Return(nil, errors.New("database error"))
+				return mock_store.NewMockOrderStore(ctrl), shopMock
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldShopStore := orderStore, shopStore
+			defer func() { orderStore, shopStore = oldOrderStore, oldShopStore }()
+			orderStore, shopStore = tt.mockSetup(ctrl)
+
+			err := settleUniqueCode(context.Background(), nil, tt.order, tt.paid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("settleUniqueCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_oservice_UpdateOrderPaymentAmountByID(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 	updatedTime := time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC)
@@ -3427,7 +4545,7 @@ func Test_oservice_GetOrderPaymentsByOrderID(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderPaymentStore {
 				mock := mock_store.NewMockOrderPaymentStore(ctrl)
 				mock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 1).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 1).
 					Return([]model.OrderPayment{
 						{ID: 1, OrderID: 1, Amount: 50000, CreatedAt: fixedTime},
 						{ID: 2, OrderID: 1, Amount: 25000, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true}},
@@ -3446,7 +4564,7 @@ func Test_oservice_GetOrderPaymentsByOrderID(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderPaymentStore {
 				mock := mock_store.NewMockOrderPaymentStore(ctrl)
 				mock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 9999).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 9999).
 					Return([]model.OrderPayment{}, nil)
 				return mock
 			},
@@ -3459,7 +4577,7 @@ func Test_oservice_GetOrderPaymentsByOrderID(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderPaymentStore {
 				mock := mock_store.NewMockOrderPaymentStore(ctrl)
 				mock.EXPECT().
-					GetOrderPaymentsByOrderID(gomock.Any(), 1).
+					GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 1).
 					Return(nil, errors.New("database error"))
 				return mock
 			},
@@ -3497,38 +4615,90 @@ func Test_oservice_GetOrderPaymentsByOrderID(t *testing.T) {
 }
 
 func Test_oservice_DeleteOrderPaymentByID(t *testing.T) {
+	type mocks struct {
+		order   *mock_store.MockOrderStore
+		payment *mock_store.MockOrderPaymentStore
+		credit  *mock_store.MockCustomerCreditStore
+		db      *mock_database.MockDB
+	}
+
 	tests := []struct {
 		name           string
 		orderPaymentID int
 		orderID        int
-		mockSetup      func(ctrl *gomock.Controller) *mock_store.MockOrderPaymentStore
-		wantErr        bool
+		mockSetup      func(ctrl *gomock.Controller) mocks
+		wantErr        string
 	}{
 		{
 			name:           "successfully delete order payment",
 			orderPaymentID: 1,
 			orderID:        10,
-			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderPaymentStore {
-				mock := mock_store.NewMockOrderPaymentStore(ctrl)
-				mock.EXPECT().
-					DeleteOrderPaymentByID(gomock.Any(), 1, 10).
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := mocks{
+					order:   mock_store.NewMockOrderStore(ctrl),
+					payment: mock_store.NewMockOrderPaymentStore(ctrl),
+					credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+					db:      mock_database.NewMockDB(ctrl),
+				}
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 10).Return(&model.Order{ID: 10, CustomerID: 5}, nil)
+				m.credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(0, nil)
+				m.credit.EXPECT().HasOrderPaymentCredits(gomock.Any(), mockTx, 10, 1).Return(false, nil)
+				m.payment.EXPECT().
+					DeleteOrderPaymentByID(gomock.Any(), mockTx, 1, 10).
 					Return(nil)
-				return mock
+				return m
 			},
-			wantErr: false,
+		},
+		{
+			name:           "payment tied to a credit entry is not deleted",
+			orderPaymentID: 1,
+			orderID:        10,
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := mocks{
+					order:   mock_store.NewMockOrderStore(ctrl),
+					payment: mock_store.NewMockOrderPaymentStore(ctrl),
+					credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+					db:      mock_database.NewMockDB(ctrl),
+				}
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+				gomock.InOrder(
+					m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 10).Return(&model.Order{ID: 10, CustomerID: 5}, nil),
+					m.credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(150000, nil),
+					m.credit.EXPECT().HasOrderPaymentCredits(gomock.Any(), mockTx, 10, 1).Return(true, nil),
+				)
+				return m
+			},
+			wantErr: apierr.ErrPaymentHasCredit,
 		},
 		{
 			name:           "returns error on store failure",
 			orderPaymentID: 1,
 			orderID:        10,
-			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockOrderPaymentStore {
-				mock := mock_store.NewMockOrderPaymentStore(ctrl)
-				mock.EXPECT().
-					DeleteOrderPaymentByID(gomock.Any(), 1, 10).
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := mocks{
+					order:   mock_store.NewMockOrderStore(ctrl),
+					payment: mock_store.NewMockOrderPaymentStore(ctrl),
+					credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+					db:      mock_database.NewMockDB(ctrl),
+				}
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 10).Return(&model.Order{ID: 10, CustomerID: 5}, nil)
+				m.credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(0, nil)
+				m.credit.EXPECT().HasOrderPaymentCredits(gomock.Any(), mockTx, 10, 1).Return(false, nil)
+				m.payment.EXPECT().
+					DeleteOrderPaymentByID(gomock.Any(), mockTx, 1, 10).
 					Return(errors.New("database error"))
-				return mock
+				return m
 			},
-			wantErr: true,
+			wantErr: "database error",
 		},
 	}
 
@@ -3537,20 +4707,27 @@ func Test_oservice_DeleteOrderPaymentByID(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			old := orderPaymentStore
-			defer func() { orderPaymentStore = old }()
-			orderPaymentStore = tt.mockSetup(ctrl)
+			oldOrderStore, oldPaymentStore, oldCreditStore, oldDBGetter := orderStore, orderPaymentStore, customerCreditStore, dbGetter
+			defer func() {
+				orderStore, orderPaymentStore, customerCreditStore, dbGetter = oldOrderStore, oldPaymentStore, oldCreditStore, oldDBGetter
+			}()
+
+			m := tt.mockSetup(ctrl)
+			orderStore = m.order
+			orderPaymentStore = m.payment
+			customerCreditStore = m.credit
+			dbGetter = func() database.DB { return m.db }
 
 			var o oservice
 			gotErr := o.DeleteOrderPaymentByID(context.Background(), tt.orderPaymentID, tt.orderID)
 
 			if gotErr != nil {
-				if !tt.wantErr {
+				if gotErr.Error() != tt.wantErr {
 					t.Errorf("DeleteOrderPaymentByID() error = %v, wantErr %v", gotErr, tt.wantErr)
 				}
 				return
 			}
-			if tt.wantErr {
+			if tt.wantErr != "" {
 				t.Fatal("DeleteOrderPaymentByID() succeeded unexpectedly")
 			}
 		})
@@ -3560,10 +4737,14 @@ func Test_oservice_DeleteOrderPaymentByID(t *testing.T) {
 func Test_oservice_GetOrdersStats(t *testing.T) {
 	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	dateTo := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	// the same dates bounded in Asia/Jakarta
+	fromAt := time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC)
+	toAt := time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC)
 
 	type mocks struct {
 		payment  *mock_store.MockOrderPaymentStore
 		orderItem *mock_store.MockOrderItemStore
+		refund    *mock_store.MockOrderRefundStore
 	}
 
 	tests := []struct {
@@ -3581,11 +4762,13 @@ func Test_oservice_GetOrdersStats(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) mocks {
 				p := mock_store.NewMockOrderPaymentStore(ctrl)
 				p.EXPECT().GetPaymentsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(150000, nil)
+				r := mock_store.NewMockOrderRefundStore(ctrl)
+				r.EXPECT().GetRefundsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(25000, nil)
 				oi := mock_store.NewMockOrderItemStore(ctrl)
 				oi.EXPECT().GetNetSalesByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(30000, nil)
-				return mocks{p, oi}
+				return mocks{p, oi, r}
 			},
-			want:    response.OrderStatsData{TotalRevenue: 150000, NetSales: 30000},
+			want:    response.OrderStatsData{TotalRevenue: 150000, TotalRefunds: 25000, NetSales: 30000},
 			wantErr: false,
 		},
 		{
@@ -3595,25 +4778,29 @@ func Test_oservice_GetOrdersStats(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) mocks {
 				p := mock_store.NewMockOrderPaymentStore(ctrl)
 				p.EXPECT().GetPaymentsSumByShopID(gomock.Any(), 99, model.OrderFilterOptions{}).Return(0, nil)
+				r := mock_store.NewMockOrderRefundStore(ctrl)
+				r.EXPECT().GetRefundsSumByShopID(gomock.Any(), 99, model.OrderFilterOptions{}).Return(0, nil)
 				oi := mock_store.NewMockOrderItemStore(ctrl)
 				oi.EXPECT().GetNetSalesByShopID(gomock.Any(), 99, model.OrderFilterOptions{}).Return(0, nil)
-				return mocks{p, oi}
+				return mocks{p, oi, r}
 			},
 			want:    response.OrderStatsData{TotalRevenue: 0, NetSales: 0},
 			wantErr: false,
 		},
 		{
-			name:   "passes date filters to both stores",
+			name:   "passes date filters to all stores",
 			shopID: 1,
 			opts:   model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo},
 			mockSetup: func(ctrl *gomock.Controller) mocks {
 				p := mock_store.NewMockOrderPaymentStore(ctrl)
-				p.EXPECT().GetPaymentsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo}).Return(75000, nil)
+				p.EXPECT().GetPaymentsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &fromAt, DateTo: &toAt}).Return(75000, nil)
+				r := mock_store.NewMockOrderRefundStore(ctrl)
+				r.EXPECT().GetRefundsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &fromAt, DateTo: &toAt}).Return(5000, nil)
 				oi := mock_store.NewMockOrderItemStore(ctrl)
-				oi.EXPECT().GetNetSalesByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo}).Return(15000, nil)
-				return mocks{p, oi}
+				oi.EXPECT().GetNetSalesByShopID(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &fromAt, DateTo: &toAt}).Return(15000, nil)
+				return mocks{p, oi, r}
 			},
-			want:    response.OrderStatsData{TotalRevenue: 75000, NetSales: 15000},
+			want:    response.OrderStatsData{TotalRevenue: 75000, TotalRefunds: 5000, NetSales: 15000},
 			wantErr: false,
 		},
 		{
@@ -3623,8 +4810,9 @@ func Test_oservice_GetOrdersStats(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) mocks {
 				p := mock_store.NewMockOrderPaymentStore(ctrl)
 				p.EXPECT().GetPaymentsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(0, errors.New("database error"))
+				r := mock_store.NewMockOrderRefundStore(ctrl)
 				oi := mock_store.NewMockOrderItemStore(ctrl)
-				return mocks{p, oi}
+				return mocks{p, oi, r}
 			},
 			want:    response.OrderStatsData{},
 			wantErr: true,
@@ -3636,9 +4824,26 @@ func Test_oservice_GetOrdersStats(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) mocks {
 				p := mock_store.NewMockOrderPaymentStore(ctrl)
 				p.EXPECT().GetPaymentsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(150000, nil)
+				r := mock_store.NewMockOrderRefundStore(ctrl)
+				r.EXPECT().GetRefundsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(25000, nil)
 				oi := mock_store.NewMockOrderItemStore(ctrl)
 				oi.EXPECT().GetNetSalesByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(0, errors.New("database error"))
-				return mocks{p, oi}
+				return mocks{p, oi, r}
+			},
+			want:    response.OrderStatsData{},
+			wantErr: true,
+		},
+		{
+			name:   "returns error on refund store failure",
+			shopID: 1,
+			opts:   model.OrderFilterOptions{},
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				p := mock_store.NewMockOrderPaymentStore(ctrl)
+				p.EXPECT().GetPaymentsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(150000, nil)
+				r := mock_store.NewMockOrderRefundStore(ctrl)
+				r.EXPECT().GetRefundsSumByShopID(gomock.Any(), 1, model.OrderFilterOptions{}).Return(0, errors.New("database error"))
+				oi := mock_store.NewMockOrderItemStore(ctrl)
+				return mocks{p, oi, r}
 			},
 			want:    response.OrderStatsData{},
 			wantErr: true,
@@ -3660,6 +4865,16 @@ func Test_oservice_GetOrdersStats(t *testing.T) {
 			defer func() { orderItemStore = oldItem }()
 			orderItemStore = m.orderItem
 
+			oldRefund := orderRefundStore
+			defer func() { orderRefundStore = oldRefund }()
+			orderRefundStore = m.refund
+
+			oldShop := shopStore
+			defer func() { shopStore = oldShop }()
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), tt.shopID).Return(&model.Shop{ID: tt.shopID, Timezone: "Asia/Jakarta"}, nil).AnyTimes()
+			shopStore = shopMock
+
 			var o oservice
 			got, gotErr := o.GetOrdersStats(context.Background(), tt.shopID, tt.opts)
 			if gotErr != nil {
@@ -3677,3 +4892,1029 @@ func Test_oservice_GetOrdersStats(t *testing.T) {
 		})
 	}
 }
+
+func Test_oservice_ApplyCustomerCredit(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	orderID := 7
+	paymentID := 2
+
+	type mocks struct {
+		order   *mock_store.MockOrderStore
+		payment *mock_store.MockOrderPaymentStore
+		credit  *mock_store.MockCustomerCreditStore
+		db      *mock_database.MockDB
+	}
+
+	tests := []struct {
+		name      string
+		amount    int
+		mockSetup func(ctrl *gomock.Controller) mocks
+		want      response.OrderPaymentData
+		wantErr   string
+	}{
+		{
+			name:   "pays order from credit and debits ledger",
+			amount: 100000,
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := mocks{
+					order:   mock_store.NewMockOrderStore(ctrl),
+					payment: mock_store.NewMockOrderPaymentStore(ctrl),
+					credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+					db:      mock_database.NewMockDB(ctrl),
+				}
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).
+					Return(&model.Order{ID: 7, ShopID: 10, CustomerID: 5, TotalPrice: 300000}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+
+				m.credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(250000, nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
+					Return([]model.OrderPayment{{ID: 1, OrderID: 7, Amount: 200000}}, nil)
+				m.payment.EXPECT().CreateOrderPayment(gomock.Any(), mockTx, 7, 100000).
+					Return(&model.OrderPayment{ID: 2, OrderID: 7, Amount: 100000, CreatedAt: fixedTime}, nil)
+				m.credit.EXPECT().CreateCustomerCredit(gomock.Any(), mockTx, store.CreateCustomerCreditInput{
+					ShopID: 10, CustomerID: 5, OrderID: &orderID, PaymentID: &paymentID, Type: constant.CustomerCreditTypePayment, Amount: -100000,
+				}).Return(&model.CustomerCredit{ID: 9}, nil)
+				return m
+			},
+			want: response.OrderPaymentData{ID: 2, OrderID: 7, Amount: 100000, CreatedAt: fixedTime},
+		},
+		{
+			name:   "insufficient balance is rejected",
+			amount: 100000,
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := mocks{
+					order:   mock_store.NewMockOrderStore(ctrl),
+					payment: mock_store.NewMockOrderPaymentStore(ctrl),
+					credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+					db:      mock_database.NewMockDB(ctrl),
+				}
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).
+					Return(&model.Order{ID: 7, ShopID: 10, CustomerID: 5, TotalPrice: 300000}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+
+				m.credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(50000, nil)
+				return m
+			},
+			wantErr: apierr.ErrInsufficientCredit,
+		},
+		{
+			name:   "amount above outstanding is rejected",
+			amount: 150000,
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := mocks{
+					order:   mock_store.NewMockOrderStore(ctrl),
+					payment: mock_store.NewMockOrderPaymentStore(ctrl),
+					credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+					db:      mock_database.NewMockDB(ctrl),
+				}
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).
+					Return(&model.Order{ID: 7, ShopID: 10, CustomerID: 5, TotalPrice: 300000}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+
+				m.credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(500000, nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).
+					Return([]model.OrderPayment{{ID: 1, OrderID: 7, Amount: 200000}}, nil)
+				return m
+			},
+			wantErr: apierr.ErrCreditAmountInvalid,
+		},
+		{
+			name:   "order not found",
+			amount: 1000,
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := mocks{
+					order:   mock_store.NewMockOrderStore(ctrl),
+					payment: mock_store.NewMockOrderPaymentStore(ctrl),
+					credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+					db:      mock_database.NewMockDB(ctrl),
+				}
+				m.order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(nil, nil)
+				return m
+			},
+			wantErr: apierr.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldPaymentStore, oldCreditStore, oldDBGetter := orderStore, orderPaymentStore, customerCreditStore, dbGetter
+			defer func() {
+				orderStore, orderPaymentStore, customerCreditStore, dbGetter = oldOrderStore, oldPaymentStore, oldCreditStore, oldDBGetter
+			}()
+
+			m := tt.mockSetup(ctrl)
+			orderStore = m.order
+			orderPaymentStore = m.payment
+			customerCreditStore = m.credit
+			dbGetter = func() database.DB { return m.db }
+
+			var o oservice
+			got, gotErr := o.ApplyCustomerCredit(context.Background(), 7, 10, tt.amount)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("ApplyCustomerCredit() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("ApplyCustomerCredit() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyCustomerCredit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_oservice_CarryOverOverpayment(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	orderID := 7
+	paymentID := 3
+
+	tests := []struct {
+		name      string
+		payments  []model.OrderPayment
+		mockSetup func(ctrl *gomock.Controller, tx *mock_database.MockTx, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore)
+		want      response.CustomerCreditData
+		wantErr   string
+	}{
+		{
+			name:     "moves excess into customer credit",
+			payments: []model.OrderPayment{{Amount: 200000}, {Amount: 150000}},
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore) {
+				tx.EXPECT().Commit().Return(nil)
+				payment.EXPECT().CreateOrderPayment(gomock.Any(), tx, 7, -50000).Return(&model.OrderPayment{ID: 3}, nil)
+				credit.EXPECT().CreateCustomerCredit(gomock.Any(), tx, store.CreateCustomerCreditInput{
+					ShopID: 10, CustomerID: 5, OrderID: &orderID, PaymentID: &paymentID, Type: constant.CustomerCreditTypeOverpayment, Amount: 50000,
+				}).Return(&model.CustomerCredit{
+					ID: 4, ShopID: 10, CustomerID: 5, OrderID: sql.NullInt64{Int64: 7, Valid: true},
+					Type: constant.CustomerCreditTypeOverpayment, Amount: 50000, CreatedAt: fixedTime,
+				}, nil)
+			},
+			want: response.CustomerCreditData{ID: 4, OrderID: intPtr(7), Type: constant.CustomerCreditTypeOverpayment, Amount: 50000, CreatedAt: fixedTime},
+		},
+		{
+			name:     "order paid exactly has nothing to carry over",
+			payments: []model.OrderPayment{{Amount: 300000}},
+			mockSetup: func(ctrl *gomock.Controller, tx *mock_database.MockTx, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore) {
+			},
+			wantErr: apierr.ErrNoOverpayment,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldPaymentStore, oldCreditStore, oldDBGetter := orderStore, orderPaymentStore, customerCreditStore, dbGetter
+			defer func() {
+				orderStore, orderPaymentStore, customerCreditStore, dbGetter = oldOrderStore, oldPaymentStore, oldCreditStore, oldDBGetter
+			}()
+
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			mockOrder.EXPECT().GetOrderByID(gomock.Any(), 7, 10).
+				Return(&model.Order{ID: 7, ShopID: 10, CustomerID: 5, TotalPrice: 300000}, nil)
+
+			mockTx := mock_database.NewMockTx(ctrl)
+			mockTx.EXPECT().Rollback().Return(nil)
+			mockDB := mock_database.NewMockDB(ctrl)
+			mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+			mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
+			mockPayment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).Return(tt.payments, nil)
+			mockCredit := mock_store.NewMockCustomerCreditStore(ctrl)
+			mockCredit.EXPECT().GetCustomerCreditBalance(gomock.Any(), mockTx, 5).Return(0, nil)
+			tt.mockSetup(ctrl, mockTx, mockPayment, mockCredit)
+
+			orderStore = mockOrder
+			orderPaymentStore = mockPayment
+			customerCreditStore = mockCredit
+			dbGetter = func() database.DB { return mockDB }
+
+			var o oservice
+			got, gotErr := o.CarryOverOverpayment(context.Background(), 7, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("CarryOverOverpayment() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("CarryOverOverpayment() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CarryOverOverpayment() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_downPaymentStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		order model.Order
+		paid  int
+		want  string
+	}{
+		{name: "no DP requirement", order: model.Order{TotalPrice: 300000}, paid: 100000, want: constant.OrderPaymentStatusOutstanding},
+		{name: "DP not yet paid", order: model.Order{TotalPrice: 300000, DPPercent: 50}, paid: 149999, want: constant.OrderPaymentStatusAwaitingDP},
+		{name: "DP paid", order: model.Order{TotalPrice: 300000, DPPercent: 50}, paid: 150000, want: constant.OrderPaymentStatusDPPaid},
+		{name: "DP rounds up", order: model.Order{TotalPrice: 100001, DPPercent: 50}, paid: 50000, want: constant.OrderPaymentStatusAwaitingDP},
+		{name: "fully paid", order: model.Order{TotalPrice: 300000, DPPercent: 50}, paid: 300000, want: constant.OrderPaymentStatusPaid},
+		{name: "fully paid includes unique code", order: model.Order{TotalPrice: 300000, UniqueCode: 417, DPPercent: 50}, paid: 300000, want: constant.OrderPaymentStatusDPPaid},
+		{name: "empty order still awaits DP", order: model.Order{DPPercent: 50}, paid: 0, want: constant.OrderPaymentStatusAwaitingDP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := downPaymentStatus(&tt.order, tt.paid); got != tt.want {
+				t.Errorf("downPaymentStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_settleDownPayment(t *testing.T) {
+	tests := []struct {
+		name       string
+		order      model.Order
+		paid       int
+		wantStatus string // empty when the order must not be updated
+	}{
+		{
+			name:       "moves to dp_paid once DP is covered",
+			order:      model.Order{ID: 1, TotalPrice: 300000, DPPercent: 50, PaymentStatus: constant.OrderPaymentStatusAwaitingDP},
+			paid:       150000,
+			wantStatus: constant.OrderPaymentStatusDPPaid,
+		},
+		{
+			name:       "moves to paid once total is covered",
+			order:      model.Order{ID: 1, TotalPrice: 300000, DPPercent: 50, PaymentStatus: constant.OrderPaymentStatusDPPaid},
+			paid:       300000,
+			wantStatus: constant.OrderPaymentStatusPaid,
+		},
+		{
+			name:  "leaves unchanged status alone",
+			order: model.Order{ID: 1, TotalPrice: 300000, DPPercent: 50, PaymentStatus: constant.OrderPaymentStatusAwaitingDP},
+			paid:  100000,
+		},
+		{
+			name:  "leaves full payment with unique code to settleUniqueCode",
+			order: model.Order{ID: 1, TotalPrice: 300000, UniqueCode: 417, DPPercent: 50, PaymentStatus: constant.OrderPaymentStatusDPPaid},
+			paid:  300417,
+		},
+		{
+			name:  "ignores orders without DP requirement",
+			order: model.Order{ID: 1, TotalPrice: 300000, PaymentStatus: constant.OrderPaymentStatusOutstanding},
+			paid:  300000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore := orderStore
+			defer func() { orderStore = oldOrderStore }()
+
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			if tt.wantStatus != "" {
+				mockOrder.EXPECT().
+					UpdateOrder(gomock.Any(), nil, 1, store.UpdateOrderInput{PaymentStatus: &tt.wantStatus}).
+					Return(&model.Order{}, nil)
+			}
+			orderStore = mockOrder
+
+			if err := settleDownPayment(context.Background(), nil, &tt.order, tt.paid); err != nil {
+				t.Errorf("settleDownPayment() error = %v", err)
+			}
+		})
+	}
+}
+
+func Test_oservice_ApplyDPRule(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	departure := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	ruleID := 3
+
+	tests := []struct {
+		name      string
+		ruleID    int
+		order     *model.Order
+		mockSetup func(order *mock_store.MockOrderStore, rule *mock_store.MockDPRuleStore, payment *mock_store.MockOrderPaymentStore)
+		want      response.OrderData
+		wantErr   string
+	}{
+		{
+			name:   "applies trip rule with fixed deadline",
+			ruleID: 3,
+			order:  &model.Order{ID: 7, ShopID: 10, CustomerName: "Jane", TotalPrice: 300000, Status: constant.OrderStatusCreated, PaymentStatus: constant.OrderPaymentStatusOutstanding, CreatedAt: fixedTime},
+			mockSetup: func(order *mock_store.MockOrderStore, rule *mock_store.MockDPRuleStore, payment *mock_store.MockOrderPaymentStore) {
+				rule.EXPECT().GetDPRuleByID(gomock.Any(), 3, 10).
+					Return(&model.DPRule{ID: 3, ShopID: 10, Percent: 30, DueDate: sql.NullTime{Time: departure, Valid: true}}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).Return([]model.OrderPayment{{Amount: 90000}}, nil)
+				order.EXPECT().SetDownPayment(gomock.Any(), nil, 7, store.SetDownPaymentInput{
+					RuleID:        &ruleID,
+					Percent:       30,
+					DueAt:         &departure,
+					PaymentStatus: constant.OrderPaymentStatusDPPaid,
+				}).Return(nil)
+			},
+			want: response.OrderData{
+				ID:            7,
+				CustomerName:  "Jane",
+				TotalPrice:    300000,
+				Status:        constant.OrderStatusCreated,
+				PaymentStatus: constant.OrderPaymentStatusDPPaid,
+				DPAmount:      90000,
+				DPDueAt:       &departure,
+				CreatedAt:     fixedTime,
+			},
+		},
+		{
+			name:   "rule 0 clears the requirement",
+			ruleID: 0,
+			order:  &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, DPPercent: 50, DPDueAt: sql.NullTime{Time: departure, Valid: true}, PaymentStatus: constant.OrderPaymentStatusAwaitingDP, CreatedAt: fixedTime},
+			mockSetup: func(order *mock_store.MockOrderStore, rule *mock_store.MockDPRuleStore, payment *mock_store.MockOrderPaymentStore) {
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), gomock.Any(), 7).Return([]model.OrderPayment{}, nil)
+				order.EXPECT().SetDownPayment(gomock.Any(), nil, 7, store.SetDownPaymentInput{
+					PaymentStatus: constant.OrderPaymentStatusOutstanding,
+				}).Return(nil)
+			},
+			want: response.OrderData{
+				ID:            7,
+				TotalPrice:    300000,
+				PaymentStatus: constant.OrderPaymentStatusOutstanding,
+				CreatedAt:     fixedTime,
+			},
+		},
+		{
+			name:   "returns error when rule belongs to another shop",
+			ruleID: 3,
+			order:  &model.Order{ID: 7, ShopID: 10},
+			mockSetup: func(order *mock_store.MockOrderStore, rule *mock_store.MockDPRuleStore, payment *mock_store.MockOrderPaymentStore) {
+				rule.EXPECT().GetDPRuleByID(gomock.Any(), 3, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrDPRuleNotFound,
+		},
+		{
+			name:   "returns error when order not found",
+			ruleID: 3,
+			mockSetup: func(order *mock_store.MockOrderStore, rule *mock_store.MockDPRuleStore, payment *mock_store.MockOrderPaymentStore) {
+			},
+			wantErr: apierr.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldDPRuleStore, oldPaymentStore, oldShopStore := orderStore, dpRuleStore, orderPaymentStore, shopStore
+			defer func() {
+				orderStore, dpRuleStore, orderPaymentStore, shopStore = oldOrderStore, oldDPRuleStore, oldPaymentStore, oldShopStore
+			}()
+
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			mockOrder.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(tt.order, nil)
+			mockRule := mock_store.NewMockDPRuleStore(ctrl)
+			mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
+			tt.mockSetup(mockOrder, mockRule, mockPayment)
+
+			mockShop := mock_store.NewMockShopStore(ctrl)
+			mockShop.EXPECT().GetShopByID(gomock.Any(), 10).Return(&model.Shop{ID: 10, Timezone: "Asia/Jakarta"}, nil).AnyTimes()
+
+			orderStore = mockOrder
+			dpRuleStore = mockRule
+			orderPaymentStore = mockPayment
+			shopStore = mockShop
+
+			var o oservice
+			got, gotErr := o.ApplyDPRule(context.Background(), 7, 10, tt.ruleID)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("ApplyDPRule() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("ApplyDPRule() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyDPRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_oservice_FlagOverdueDownPayments(t *testing.T) {
+	orderID := 2
+	paymentID := 9
+	refunded := constant.OrderPaymentStatusRefunded
+
+	type mocks struct {
+		order   *mock_store.MockOrderStore
+		payment *mock_store.MockOrderPaymentStore
+		refund  *mock_store.MockOrderRefundStore
+		credit  *mock_store.MockCustomerCreditStore
+		db      *mock_database.MockDB
+	}
+
+	awaitingDP := func(id int) *model.Order {
+		return &model.Order{ID: id, ShopID: 10, CustomerID: 5, TotalPrice: 200000, DPPercent: 50, Status: constant.OrderStatusCreated, PaymentStatus: constant.OrderPaymentStatusAwaitingDP}
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(ctrl *gomock.Controller, m mocks)
+		wantErr   bool
+	}{
+		{
+			name: "flags overdue orders and cancels those with auto cancel",
+			mockSetup: func(ctrl *gomock.Controller, m mocks) {
+				m.order.EXPECT().GetDPOverdueOrders(gomock.Any(), gomock.Any()).
+					Return([]model.DPOverdueOrder{{ID: 1, ShopID: 10}, {ID: 2, ShopID: 10, AutoCancel: true}}, nil)
+
+				tx1 := mock_database.NewMockTx(ctrl)
+				tx1.EXPECT().Commit().Return(nil)
+				tx1.EXPECT().Rollback().Return(nil)
+				tx2 := mock_database.NewMockTx(ctrl)
+				tx2.EXPECT().Commit().Return(nil)
+				tx2.EXPECT().Rollback().Return(nil)
+				gomock.InOrder(
+					m.db.EXPECT().Begin().Return(tx1, nil),
+					m.db.EXPECT().Begin().Return(tx2, nil),
+				)
+
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx1, 1).Return(awaitingDP(1), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx1, 1).Return(nil, nil)
+				m.order.EXPECT().MarkDPOverdue(gomock.Any(), tx1, 1).Return(nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx2, 2).Return(awaitingDP(2), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx2, 2).Return(nil, nil)
+				m.order.EXPECT().MarkDPOverdue(gomock.Any(), tx2, 2).Return(nil)
+				m.order.EXPECT().CancelOrder(gomock.Any(), tx2, 2, constant.OrderReasonDPOverdue, "").Return(nil)
+			},
+		},
+		{
+			name: "cancelled order puts what was paid on the customer's credit",
+			mockSetup: func(ctrl *gomock.Controller, m mocks) {
+				m.order.EXPECT().GetDPOverdueOrders(gomock.Any(), gomock.Any()).
+					Return([]model.DPOverdueOrder{{ID: 2, ShopID: 10, AutoCancel: true}}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 2).Return(awaitingDP(2), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), mockTx, 2).
+					Return([]model.OrderPayment{{ID: 8, OrderID: 2, Amount: 40000}}, nil).Times(2)
+				m.order.EXPECT().MarkDPOverdue(gomock.Any(), mockTx, 2).Return(nil)
+				m.order.EXPECT().CancelOrder(gomock.Any(), mockTx, 2, constant.OrderReasonDPOverdue, "").Return(nil)
+				m.payment.EXPECT().CreateOrderPayment(gomock.Any(), mockTx, 2, -40000).
+					Return(&model.OrderPayment{ID: 9, OrderID: 2, Amount: -40000}, nil)
+				m.credit.EXPECT().CreateCustomerCredit(gomock.Any(), mockTx, store.CreateCustomerCreditInput{
+					ShopID: 10, CustomerID: 5, OrderID: &orderID, PaymentID: &paymentID, Type: constant.CustomerCreditTypeRefund, Amount: 40000,
+				}).Return(&model.CustomerCredit{ID: 4}, nil)
+				m.refund.EXPECT().CreateOrderRefund(gomock.Any(), mockTx, store.CreateOrderRefundInput{
+					OrderID: 2, PaymentID: 9, Amount: 40000, Method: constant.RefundMethodCredit, Reason: constant.OrderReasonDPOverdue,
+				}).Return(&model.OrderRefund{ID: 1}, nil)
+				m.order.EXPECT().UpdateOrder(gomock.Any(), mockTx, 2, store.UpdateOrderInput{PaymentStatus: &refunded}).Return(&model.Order{}, nil)
+			},
+		},
+		{
+			name: "skips order whose DP was paid or that was cancelled since it was listed",
+			mockSetup: func(ctrl *gomock.Controller, m mocks) {
+				m.order.EXPECT().GetDPOverdueOrders(gomock.Any(), gomock.Any()).
+					Return([]model.DPOverdueOrder{{ID: 1, ShopID: 10, AutoCancel: true}, {ID: 2, ShopID: 10, AutoCancel: true}}, nil)
+
+				tx1 := mock_database.NewMockTx(ctrl)
+				tx1.EXPECT().Rollback().Return(nil)
+				tx2 := mock_database.NewMockTx(ctrl)
+				tx2.EXPECT().Rollback().Return(nil)
+				gomock.InOrder(
+					m.db.EXPECT().Begin().Return(tx1, nil),
+					m.db.EXPECT().Begin().Return(tx2, nil),
+				)
+
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx1, 1).Return(awaitingDP(1), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx1, 1).
+					Return([]model.OrderPayment{{ID: 8, OrderID: 1, Amount: 100000}}, nil)
+				cancelled := awaitingDP(2)
+				cancelled.Status = constant.OrderStatusCancelled
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx2, 2).Return(cancelled, nil)
+			},
+		},
+		{
+			name: "skips order that fails and continues",
+			mockSetup: func(ctrl *gomock.Controller, m mocks) {
+				m.order.EXPECT().GetDPOverdueOrders(gomock.Any(), gomock.Any()).
+					Return([]model.DPOverdueOrder{{ID: 1, ShopID: 10}, {ID: 2, ShopID: 10}}, nil)
+
+				tx1 := mock_database.NewMockTx(ctrl)
+				tx1.EXPECT().Rollback().Return(nil)
+				tx2 := mock_database.NewMockTx(ctrl)
+				tx2.EXPECT().Commit().Return(nil)
+				tx2.EXPECT().Rollback().Return(nil)
+				gomock.InOrder(
+					m.db.EXPECT().Begin().Return(tx1, nil),
+					m.db.EXPECT().Begin().Return(tx2, nil),
+				)
+
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx1, 1).Return(awaitingDP(1), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx1, 1).Return(nil, nil)
+				m.order.EXPECT().MarkDPOverdue(gomock.Any(), tx1, 1).Return(errors.New("database error"))
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx2, 2).Return(awaitingDP(2), nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx2, 2).Return(nil, nil)
+				m.order.EXPECT().MarkDPOverdue(gomock.Any(), tx2, 2).Return(nil)
+			},
+		},
+		{
+			name: "returns error when overdue orders cannot be loaded",
+			mockSetup: func(ctrl *gomock.Controller, m mocks) {
+				m.order.EXPECT().GetDPOverdueOrders(gomock.Any(), gomock.Any()).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldPaymentStore, oldRefundStore, oldCreditStore, oldDBGetter := orderStore, orderPaymentStore, orderRefundStore, customerCreditStore, dbGetter
+			defer func() {
+				orderStore, orderPaymentStore, orderRefundStore, customerCreditStore, dbGetter = oldOrderStore, oldPaymentStore, oldRefundStore, oldCreditStore, oldDBGetter
+			}()
+
+			m := mocks{
+				order:   mock_store.NewMockOrderStore(ctrl),
+				payment: mock_store.NewMockOrderPaymentStore(ctrl),
+				refund:  mock_store.NewMockOrderRefundStore(ctrl),
+				credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+				db:      mock_database.NewMockDB(ctrl),
+			}
+			tt.mockSetup(ctrl, m)
+			orderStore = m.order
+			orderPaymentStore = m.payment
+			orderRefundStore = m.refund
+			customerCreditStore = m.credit
+			dbGetter = func() database.DB { return m.db }
+
+			var o oservice
+			gotErr := o.FlagOverdueDownPayments(context.Background())
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("FlagOverdueDownPayments() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_oservice_CancelOrder(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	orderID := 7
+	paymentID := 2
+	refunded := constant.OrderPaymentStatusRefunded
+
+	type mocks struct {
+		order   *mock_store.MockOrderStore
+		payment *mock_store.MockOrderPaymentStore
+		refund  *mock_store.MockOrderRefundStore
+		credit  *mock_store.MockCustomerCreditStore
+		db      *mock_database.MockDB
+	}
+
+	newMocks := func(ctrl *gomock.Controller, order *model.Order) mocks {
+		m := mocks{
+			order:   mock_store.NewMockOrderStore(ctrl),
+			payment: mock_store.NewMockOrderPaymentStore(ctrl),
+			refund:  mock_store.NewMockOrderRefundStore(ctrl),
+			credit:  mock_store.NewMockCustomerCreditStore(ctrl),
+			db:      mock_database.NewMockDB(ctrl),
+		}
+		m.order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(order, nil)
+		return m
+	}
+
+	openOrder := func() *model.Order {
+		return &model.Order{ID: 7, ShopID: 10, CustomerID: 5, CustomerName: "John Doe", TotalPrice: 300000, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid, CreatedAt: fixedTime}
+	}
+
+	tests := []struct {
+		name      string
+		input     CancelOrderInput
+		mockSetup func(ctrl *gomock.Controller) mocks
+		want      response.OrderData
+		wantErr   string
+	}{
+		{
+			name:  "cancels order without refund",
+			input: CancelOrderInput{OrderID: 7, ShopID: 10, Reason: constant.OrderReasonOutOfStock, Notes: "supplier sold out"},
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := newMocks(ctrl, openOrder())
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 7).Return(openOrder(), nil)
+				m.order.EXPECT().CancelOrder(gomock.Any(), mockTx, 7, constant.OrderReasonOutOfStock, "supplier sold out").Return(nil)
+				return m
+			},
+			want: response.OrderData{
+				ID: 7, CustomerName: "John Doe", TotalPrice: 300000, Status: constant.OrderStatusCancelled, PaymentStatus: constant.OrderPaymentStatusPaid,
+				CancelReason: constant.OrderReasonOutOfStock, CancelNotes: "supplier sold out", CreatedAt: fixedTime,
+			},
+		},
+		{
+			name: "cancels order and refunds everything to credit",
+			input: CancelOrderInput{OrderID: 7, ShopID: 10, Reason: constant.OrderReasonCustomerCancelled,
+				Refund: &RefundOrderInput{Amount: 300000, Method: constant.RefundMethodCredit, Notes: "kept as balance"}},
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := newMocks(ctrl, openOrder())
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 7).Return(openOrder(), nil)
+				m.order.EXPECT().CancelOrder(gomock.Any(), mockTx, 7, constant.OrderReasonCustomerCancelled, "").Return(nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), mockTx, 7).
+					Return([]model.OrderPayment{{ID: 1, OrderID: 7, Amount: 300000}}, nil)
+				m.payment.EXPECT().CreateOrderPayment(gomock.Any(), mockTx, 7, -300000).
+					Return(&model.OrderPayment{ID: 2, OrderID: 7, Amount: -300000}, nil)
+				m.credit.EXPECT().CreateCustomerCredit(gomock.Any(), mockTx, store.CreateCustomerCreditInput{
+					ShopID: 10, CustomerID: 5, OrderID: &orderID, PaymentID: &paymentID, Type: constant.CustomerCreditTypeRefund, Amount: 300000, Notes: "kept as balance",
+				}).Return(&model.CustomerCredit{ID: 4}, nil)
+				m.refund.EXPECT().CreateOrderRefund(gomock.Any(), mockTx, store.CreateOrderRefundInput{
+					OrderID: 7, PaymentID: 2, Amount: 300000, Method: constant.RefundMethodCredit, Reason: constant.OrderReasonCustomerCancelled, Notes: "kept as balance",
+				}).Return(&model.OrderRefund{ID: 1}, nil)
+				m.order.EXPECT().UpdateOrder(gomock.Any(), mockTx, 7, store.UpdateOrderInput{PaymentStatus: &refunded}).Return(&model.Order{}, nil)
+				return m
+			},
+			want: response.OrderData{
+				ID: 7, CustomerName: "John Doe", TotalPrice: 300000, Status: constant.OrderStatusCancelled, PaymentStatus: constant.OrderPaymentStatusRefunded,
+				CancelReason: constant.OrderReasonCustomerCancelled, CreatedAt: fixedTime,
+			},
+		},
+		{
+			name: "refund above paid amount is rejected",
+			input: CancelOrderInput{OrderID: 7, ShopID: 10, Reason: constant.OrderReasonDuplicate,
+				Refund: &RefundOrderInput{Amount: 400000, Method: constant.RefundMethodTransfer}},
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := newMocks(ctrl, openOrder())
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 7).Return(openOrder(), nil)
+				m.order.EXPECT().CancelOrder(gomock.Any(), mockTx, 7, constant.OrderReasonDuplicate, "").Return(nil)
+				m.payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), mockTx, 7).
+					Return([]model.OrderPayment{{ID: 1, OrderID: 7, Amount: 300000}}, nil)
+				return m
+			},
+			wantErr: apierr.ErrRefundAmountInvalid,
+		},
+		{
+			name:  "order cancelled since it was read is rejected",
+			input: CancelOrderInput{OrderID: 7, ShopID: 10, Reason: constant.OrderReasonOther},
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				m := newMocks(ctrl, openOrder())
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				m.db.EXPECT().Begin().Return(mockTx, nil)
+				cancelled := openOrder()
+				cancelled.Status = constant.OrderStatusCancelled
+				m.order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), mockTx, 7).Return(cancelled, nil)
+				return m
+			},
+			wantErr: apierr.ErrOrderAlreadyCancelled,
+		},
+		{
+			name:  "order not found",
+			input: CancelOrderInput{OrderID: 7, ShopID: 10, Reason: constant.OrderReasonOther},
+			mockSetup: func(ctrl *gomock.Controller) mocks {
+				return newMocks(ctrl, nil)
+			},
+			wantErr: apierr.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldPaymentStore, oldRefundStore, oldCreditStore, oldDBGetter := orderStore, orderPaymentStore, orderRefundStore, customerCreditStore, dbGetter
+			defer func() {
+				orderStore, orderPaymentStore, orderRefundStore, customerCreditStore, dbGetter = oldOrderStore, oldPaymentStore, oldRefundStore, oldCreditStore, oldDBGetter
+			}()
+
+			m := tt.mockSetup(ctrl)
+			orderStore = m.order
+			orderPaymentStore = m.payment
+			orderRefundStore = m.refund
+			customerCreditStore = m.credit
+			dbGetter = func() database.DB { return m.db }
+
+			var o oservice
+			got, gotErr := o.CancelOrder(context.Background(), tt.input)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("CancelOrder() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("CancelOrder() succeeded unexpectedly")
+			}
+			if got.CancelledAt == nil {
+				t.Fatal("CancelOrder() CancelledAt is nil")
+			}
+			got.CancelledAt = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CancelOrder() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_oservice_RefundOrder(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	outstanding := constant.OrderPaymentStatusOutstanding
+	input := RefundOrderInput{OrderID: 7, ShopID: 10, Amount: 50000, Method: constant.RefundMethodTransfer, Reason: constant.OrderReasonDamaged, ProofURL: "https://cdn.example.com/refunds/a.jpg"}
+
+	tests := []struct {
+		name      string
+		order     *model.Order
+		mockSetup func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore)
+		want      response.OrderRefundData
+		wantErr   string
+	}{
+		{
+			name:  "partial refund reopens payment of an open order",
+			order: &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore) {
+				tx.EXPECT().Commit().Return(nil)
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(&model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 7).Return([]model.OrderPayment{{Amount: 300000}}, nil)
+				payment.EXPECT().CreateOrderPayment(gomock.Any(), tx, 7, -50000).Return(&model.OrderPayment{ID: 2}, nil)
+				refund.EXPECT().CreateOrderRefund(gomock.Any(), tx, store.CreateOrderRefundInput{
+					OrderID: 7, PaymentID: 2, Amount: 50000, Method: constant.RefundMethodTransfer, Reason: constant.OrderReasonDamaged, ProofURL: "https://cdn.example.com/refunds/a.jpg",
+				}).Return(&model.OrderRefund{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000, Method: constant.RefundMethodTransfer, Reason: constant.OrderReasonDamaged, ProofURL: "https://cdn.example.com/refunds/a.jpg", CreatedAt: fixedTime}, nil)
+				order.EXPECT().UpdateOrder(gomock.Any(), tx, 7, store.UpdateOrderInput{PaymentStatus: &outstanding}).Return(&model.Order{}, nil)
+			},
+			want: response.OrderRefundData{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000, Method: constant.RefundMethodTransfer, Reason: constant.OrderReasonDamaged, ProofURL: "https://cdn.example.com/refunds/a.jpg", CreatedAt: fixedTime},
+		},
+		{
+			name:  "partial refund reclaims the unique code kept on a paid order",
+			order: &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, UniqueCode: 417, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore) {
+				tx.EXPECT().Commit().Return(nil)
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(&model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, UniqueCode: 417, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 7).Return([]model.OrderPayment{{Amount: 300417}}, nil)
+				payment.EXPECT().CreateOrderPayment(gomock.Any(), tx, 7, -50000).Return(&model.OrderPayment{ID: 2}, nil)
+				refund.EXPECT().CreateOrderRefund(gomock.Any(), tx, gomock.Any()).Return(&model.OrderRefund{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000}, nil)
+				// Another open order was given 417 after this one was paid.
+				gomock.InOrder(
+					order.EXPECT().ReclaimUniqueCode(gomock.Any(), tx, 7, 10).Return(88, nil),
+					order.EXPECT().UpdateOrder(gomock.Any(), tx, 7, store.UpdateOrderInput{PaymentStatus: &outstanding}).Return(&model.Order{}, nil),
+				)
+			},
+			want: response.OrderRefundData{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000},
+		},
+		{
+			name:  "returns error when the unique code can't be reclaimed",
+			order: &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, UniqueCode: 417, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore) {
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(&model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, UniqueCode: 417, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 7).Return([]model.OrderPayment{{Amount: 300417}}, nil)
+				payment.EXPECT().CreateOrderPayment(gomock.Any(), tx, 7, -50000).Return(&model.OrderPayment{ID: 2}, nil)
+				refund.EXPECT().CreateOrderRefund(gomock.Any(), tx, gomock.Any()).Return(&model.OrderRefund{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000}, nil)
+				order.EXPECT().ReclaimUniqueCode(gomock.Any(), tx, 7, 10).Return(0, errors.New("database error"))
+			},
+			wantErr: "database error",
+		},
+		{
+			name:  "partial refund keeps payment status of a cancelled order",
+			order: &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusCancelled, PaymentStatus: constant.OrderPaymentStatusPaid},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore) {
+				tx.EXPECT().Commit().Return(nil)
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(&model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusCancelled, PaymentStatus: constant.OrderPaymentStatusPaid}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 7).Return([]model.OrderPayment{{Amount: 300000}}, nil)
+				payment.EXPECT().CreateOrderPayment(gomock.Any(), tx, 7, -50000).Return(&model.OrderPayment{ID: 2}, nil)
+				refund.EXPECT().CreateOrderRefund(gomock.Any(), tx, gomock.Any()).Return(&model.OrderRefund{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000}, nil)
+			},
+			want: response.OrderRefundData{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000},
+		},
+		{
+			name:  "order without payments cannot be refunded",
+			order: &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusCreated},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore) {
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(&model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusCreated}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 7).Return([]model.OrderPayment{}, nil)
+			},
+			wantErr: apierr.ErrRefundAmountInvalid,
+		},
+		{
+			name:  "paid amount is read after the order is locked",
+			order: &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore) {
+				// A refund committed while this one waited on the lock already
+				// took 280000 back, so only 20000 is left to refund.
+				gomock.InOrder(
+					order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(&model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusOutstanding}, nil),
+					payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 7).Return([]model.OrderPayment{{Amount: 300000}, {Amount: -280000}}, nil),
+				)
+			},
+			wantErr: apierr.ErrRefundAmountInvalid,
+		},
+		{
+			name:  "order deleted since it was read",
+			order: &model.Order{ID: 7, ShopID: 10, TotalPrice: 300000, Status: constant.OrderStatusInProgress, PaymentStatus: constant.OrderPaymentStatusPaid},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, payment *mock_store.MockOrderPaymentStore, refund *mock_store.MockOrderRefundStore) {
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(nil, nil)
+			},
+			wantErr: apierr.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldPaymentStore, oldRefundStore, oldDBGetter := orderStore, orderPaymentStore, orderRefundStore, dbGetter
+			defer func() {
+				orderStore, orderPaymentStore, orderRefundStore, dbGetter = oldOrderStore, oldPaymentStore, oldRefundStore, oldDBGetter
+			}()
+
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			mockOrder.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(tt.order, nil)
+
+			mockTx := mock_database.NewMockTx(ctrl)
+			mockTx.EXPECT().Rollback().Return(nil)
+			mockDB := mock_database.NewMockDB(ctrl)
+			mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+			mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
+			mockRefund := mock_store.NewMockOrderRefundStore(ctrl)
+			tt.mockSetup(mockTx, mockOrder, mockPayment, mockRefund)
+
+			orderStore = mockOrder
+			orderPaymentStore = mockPayment
+			orderRefundStore = mockRefund
+			dbGetter = func() database.DB { return mockDB }
+
+			var o oservice
+			got, gotErr := o.RefundOrder(context.Background(), input)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("RefundOrder() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("RefundOrder() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RefundOrder() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_oservice_GetOrderRefundsByOrderID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(order *mock_store.MockOrderStore, refund *mock_store.MockOrderRefundStore)
+		want      []response.OrderRefundData
+		wantErr   string
+	}{
+		{
+			name: "returns refunds of the order",
+			mockSetup: func(order *mock_store.MockOrderStore, refund *mock_store.MockOrderRefundStore) {
+				order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(&model.Order{ID: 7, ShopID: 10}, nil)
+				refund.EXPECT().GetOrderRefundsByOrderID(gomock.Any(), 7).Return([]model.OrderRefund{
+					{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000, Method: constant.RefundMethodCash, Reason: constant.OrderReasonDamaged, CreatedAt: fixedTime},
+				}, nil)
+			},
+			want: []response.OrderRefundData{
+				{ID: 1, OrderID: 7, PaymentID: 2, Amount: 50000, Method: constant.RefundMethodCash, Reason: constant.OrderReasonDamaged, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "order not found",
+			mockSetup: func(order *mock_store.MockOrderStore, refund *mock_store.MockOrderRefundStore) {
+				order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore, oldRefundStore := orderStore, orderRefundStore
+			defer func() { orderStore, orderRefundStore = oldOrderStore, oldRefundStore }()
+
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			mockRefund := mock_store.NewMockOrderRefundStore(ctrl)
+			tt.mockSetup(mockOrder, mockRefund)
+			orderStore = mockOrder
+			orderRefundStore = mockRefund
+
+			var o oservice
+			got, gotErr := o.GetOrderRefundsByOrderID(context.Background(), 7, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("GetOrderRefundsByOrderID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("GetOrderRefundsByOrderID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOrderRefundsByOrderID() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_oservice_ClearShortageNotify(t *testing.T) {
+	notify := false
+
+	tests := []struct {
+		name      string
+		mockSetup func(order *mock_store.MockOrderStore)
+		wantErr   string
+	}{
+		{
+			name: "clears the flag",
+			mockSetup: func(order *mock_store.MockOrderStore) {
+				order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(&model.Order{ID: 7, ShopID: 10, ShortageNotify: true}, nil)
+				order.EXPECT().UpdateOrder(gomock.Any(), nil, 7, store.UpdateOrderInput{ShortageNotify: &notify}).Return(&model.Order{ID: 7}, nil)
+			},
+		},
+		{
+			name: "order not found",
+			mockSetup: func(order *mock_store.MockOrderStore) {
+				order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrOrderNotFound,
+		},
+		{
+			name: "update error is returned",
+			mockSetup: func(order *mock_store.MockOrderStore) {
+				order.EXPECT().GetOrderByID(gomock.Any(), 7, 10).Return(&model.Order{ID: 7, ShopID: 10}, nil)
+				order.EXPECT().UpdateOrder(gomock.Any(), nil, 7, gomock.Any()).Return(nil, errors.New("database error"))
+			},
+			wantErr: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldOrderStore := orderStore
+			defer func() { orderStore = oldOrderStore }()
+
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			tt.mockSetup(mockOrder)
+			orderStore = mockOrder
+
+			var o oservice
+			gotErr := o.ClearShortageNotify(context.Background(), 7, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("ClearShortageNotify() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("ClearShortageNotify() succeeded unexpectedly")
+			}
+		})
+	}
+}
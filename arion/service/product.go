@@ -3,20 +3,15 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/zeirash/recapo/arion/common/apierr"
 	"github.com/zeirash/recapo/arion/common/config"
-	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/storage"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/store"
 )
@@ -31,68 +26,32 @@ type (
 		GetPurchaseListProducts(ctx context.Context, shopID int) ([]response.PurchaseListProductData, error)
 		UploadProductImage(ctx context.Context, file io.Reader) (string, error)
 		DeleteProductImage(ctx context.Context, imageURL string) error
+		AddProductImage(ctx context.Context, productID, shopID int, file io.Reader) (response.ProductImageData, error)
+		GetProductImages(ctx context.Context, productID, shopID int) ([]response.ProductImageData, error)
+		SetPrimaryProductImage(ctx context.Context, imageID, productID, shopID int) error
+		ReorderProductImages(ctx context.Context, productID, shopID int, imageIDs []int) error
+		DeleteProductImageByID(ctx context.Context, imageID, productID, shopID int) error
 		ActivateAllProductsByShopID(ctx context.Context, shopID int) error
 		DeactivateAllProductsByShopID(ctx context.Context, shopID int) error
+		ReorderProducts(ctx context.Context, shopID int, productIDs []int) error
 	}
 
 	pservice struct{}
 
 	UpdateProductInput struct {
 		ID            int
+		ShopID        int
 		Name          *string
 		Description   *string
 		Price         *int
 		OriginalPrice *int
-		ImageURL      *string
+		ImageURL      *string // replaces the primary image
 		IsActive      *bool
+		CategoryID    *int // 0 removes the product from its category
+		Tags          *[]string
 	}
 )
 
-// r2UploadFunc uploads an object to R2; overridable in tests.
-var r2UploadFunc = func(key string, body io.Reader, contentType string) error {
-	client, err := newR2Client(cfg)
-	if err != nil {
-		return err
-	}
-	_, err = client.PutObject(context.Background(), &s3.PutObjectInput{
-		Bucket:      aws.String(cfg.R2BucketName),
-		Key:         aws.String(key),
-		Body:        body,
-		ContentType: aws.String(contentType),
-	})
-	return err
-}
-
-// r2DeleteFunc deletes an object from R2; overridable in tests.
-var r2DeleteFunc = func(key string) error {
-	client, err := newR2Client(cfg)
-	if err != nil {
-		return err
-	}
-	_, err = client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
-		Bucket: aws.String(cfg.R2BucketName),
-		Key:    aws.String(key),
-	})
-	return err
-}
-
-func newR2Client(c config.Config) (*s3.Client, error) {
-	endpoint := fmt.Sprintf("https://%s.r2.cloudflarestorage.com", c.R2AccountID)
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(),
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			c.R2AccessKeyID, c.R2SecretAccessKey, "",
-		)),
-		awsconfig.WithRegion("auto"),
-	)
-	if err != nil {
-		return nil, err
-	}
-	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		o.BaseEndpoint = aws.String(endpoint)
-		o.UsePathStyle = true
-	}), nil
-}
-
 func NewProductService() ProductService {
 	cfg = config.GetConfig()
 
@@ -108,24 +67,48 @@ func NewProductService() ProductService {
 		orderStore = store.NewOrderStore()
 	}
 
+	if productCategoryStore == nil {
+		productCategoryStore = store.NewProductCategoryStore()
+	}
+
+	if productImageStore == nil {
+		productImageStore = store.NewProductImageStore()
+	}
+
+	if uploadedObjectStore == nil {
+		uploadedObjectStore = store.NewUploadedObjectStore()
+	}
+
+	if storageBackend == nil {
+		storageBackend = storage.New(cfg)
+	}
+
 	return &pservice{}
 }
 
+// CreateProduct adds a product. An imageURL from UploadProductImage becomes
+// its primary image.
 func (p *pservice) CreateProduct(ctx context.Context, shopID int, name string, description *string, price int, originalPrice *int, imageURL *string) (response.ProductData, error) {
 	product, err := productStore.CreateProduct(ctx, name, description, price, shopID, originalPrice, imageURL)
 	if err != nil {
 		return response.ProductData{}, err
 	}
 
-	res := response.ProductData{
-		ID:            product.ID,
-		Name:          product.Name,
-		Description:   product.Description,
-		Price:         product.Price,
-		OriginalPrice: product.OriginalPrice,
-		ImageURL:      product.ImageURL,
-		IsActive:      product.IsActive,
-		CreatedAt:     product.CreatedAt,
+	res := toProductData(*product)
+	if product.ImageURL != "" {
+		uploaded := uploadedProductImage(product.ImageURL)
+		image, err := createProductImage(ctx, store.CreateProductImageInput{
+			ProductID:    product.ID,
+			ShopID:       shopID,
+			ThumbnailURL: uploaded.ThumbnailURL,
+			MediumURL:    uploaded.MediumURL,
+			FullURL:      uploaded.FullURL,
+			IsPrimary:    true,
+		})
+		if err != nil {
+			return response.ProductData{}, err
+		}
+		res.Images = []response.ProductImageData{toProductImageData(*image)}
 	}
 
 	return res, nil
@@ -141,22 +124,11 @@ func (p *pservice) GetProductByID(ctx context.Context, productID int, shopID ...
 		return nil, errors.New(apierr.ErrProductNotFound)
 	}
 
-	res := response.ProductData{
-		ID:            product.ID,
-		Name:          product.Name,
-		Description:   product.Description,
-		Price:         product.Price,
-		OriginalPrice: product.OriginalPrice,
-		ImageURL:      product.ImageURL,
-		IsActive:      product.IsActive,
-		CreatedAt:     product.CreatedAt,
-	}
-
-	if product.UpdatedAt.Valid {
-		res.UpdatedAt = &product.UpdatedAt.Time
+	res := []response.ProductData{toProductData(*product)}
+	if err := attachProductImages(ctx, res); err != nil {
+		return nil, err
 	}
-
-	return &res, nil
+	return &res[0], nil
 }
 
 func (p *pservice) GetProductsByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]response.ProductData, error) {
@@ -167,36 +139,35 @@ func (p *pservice) GetProductsByShopID(ctx context.Context, shopID int, filter m
 
 	productsData := make([]response.ProductData, 0, len(products))
 	for _, product := range products {
-		res := response.ProductData{
-			ID:            product.ID,
-			Name:          product.Name,
-			Description:   product.Description,
-			Price:         product.Price,
-			OriginalPrice: product.OriginalPrice,
-			ImageURL:      product.ImageURL,
-			IsActive:      product.IsActive,
-			CreatedAt:     product.CreatedAt,
-		}
-
-		if product.UpdatedAt.Valid {
-			t := product.UpdatedAt.Time
-			res.UpdatedAt = &t
-		}
+		productsData = append(productsData, toProductData(product))
+	}
 
-		productsData = append(productsData, res)
+	if err := attachProductImages(ctx, productsData); err != nil {
+		return []response.ProductData{}, err
 	}
 
 	return productsData, nil
 }
 
+// UpdateProduct changes the given fields of the product. CategoryID moves it
+// to the end of another of the shop's categories; Tags replaces its tags.
+// ImageURL replaces the primary image, or removes it when empty.
 func (p *pservice) UpdateProduct(ctx context.Context, input UpdateProductInput) (response.ProductData, error) {
-	// Capture old image URL before updating so we can delete it if replaced.
-	var oldImageURL string
-	if input.ImageURL != nil {
-		current, err := productStore.GetProductByID(ctx, input.ID)
-		if err == nil && current != nil {
-			oldImageURL = current.ImageURL
+	if input.CategoryID != nil && *input.CategoryID != 0 {
+		category, err := productCategoryStore.GetProductCategoryByID(ctx, *input.CategoryID, input.ShopID)
+		if err != nil {
+			return response.ProductData{}, err
 		}
+		if category == nil {
+			return response.ProductData{}, errors.New(apierr.ErrProductCategoryNotFound)
+		}
+	}
+	if input.Tags != nil {
+		tags, err := normalizeProductTags(*input.Tags)
+		if err != nil {
+			return response.ProductData{}, err
+		}
+		input.Tags = &tags
 	}
 
 	updateData := store.UpdateProductInput{
@@ -204,42 +175,91 @@ func (p *pservice) UpdateProduct(ctx context.Context, input UpdateProductInput)
 		Description:   input.Description,
 		Price:         input.Price,
 		OriginalPrice: input.OriginalPrice,
-		ImageURL:      input.ImageURL,
 		IsActive:      input.IsActive,
+		CategoryID:    input.CategoryID,
+		Tags:          input.Tags,
 	}
 	productData, err := productStore.UpdateProduct(ctx, input.ID, updateData)
 	if err != nil {
 		return response.ProductData{}, err
 	}
 
-	// Delete old image if it was replaced with a different one.
-	if oldImageURL != "" && oldImageURL != *input.ImageURL {
-		if delErr := p.DeleteProductImage(ctx, oldImageURL); delErr != nil {
-			logger.WithError(delErr).Warn("failed to delete old product image on update")
+	if input.ImageURL != nil && *input.ImageURL != productData.ImageURL {
+		if err := replacePrimaryProductImage(ctx, productData.ID, productData.ShopID, *input.ImageURL); err != nil {
+			return response.ProductData{}, err
 		}
 	}
 
-	res := response.ProductData{
-		ID:            productData.ID,
-		Name:          productData.Name,
-		Description:   productData.Description,
-		Price:         productData.Price,
-		OriginalPrice: productData.OriginalPrice,
-		ImageURL:      productData.ImageURL,
-		IsActive:      productData.IsActive,
-		CreatedAt:     productData.CreatedAt,
+	res := []response.ProductData{toProductData(*productData)}
+	if err := attachProductImages(ctx, res); err != nil {
+		return response.ProductData{}, err
 	}
+	if input.ImageURL != nil {
+		// image_url follows the primary image, which is the next image in
+		// order when the primary one was removed.
+		res[0].ImageURL = ""
+		for _, image := range res[0].Images {
+			if image.IsPrimary {
+				res[0].ImageURL = image.FullURL
+			}
+		}
+	}
+	return res[0], nil
+}
 
-	if productData.UpdatedAt.Valid {
-		res.UpdatedAt = &productData.UpdatedAt.Time
+// replacePrimaryProductImage swaps the product's primary image for one at
+// imageURL, or just removes it when imageURL is empty, then deletes the
+// replaced image's files.
+func replacePrimaryProductImage(ctx context.Context, productID, shopID int, imageURL string) error {
+	images, err := productImageStore.GetProductImagesByProductID(ctx, productID)
+	if err != nil {
+		return err
 	}
 
-	return res, nil
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var replaced []model.ProductImage
+	for _, image := range images {
+		if !image.IsPrimary {
+			continue
+		}
+		if err := productImageStore.DeleteProductImageByID(ctx, tx, image.ID, productID); err != nil {
+			return err
+		}
+		replaced = append(replaced, image)
+	}
+
+	if imageURL != "" {
+		uploaded := uploadedProductImage(imageURL)
+		_, err := productImageStore.CreateProductImage(ctx, tx, store.CreateProductImageInput{
+			ProductID:    productID,
+			ShopID:       shopID,
+			ThumbnailURL: uploaded.ThumbnailURL,
+			MediumURL:    uploaded.MediumURL,
+			FullURL:      uploaded.FullURL,
+			IsPrimary:    true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	deleteProductImageFiles(ctx, replaced...)
+	return nil
 }
 
 func (p *pservice) DeleteProductByID(ctx context.Context, id int) error {
-	// Fetch product image URL before deletion so we can clean up storage.
-	product, err := productStore.GetProductByID(ctx, id)
+	// Fetch the product's images before deletion so we can clean up storage.
+	images, err := productImageStore.GetProductImagesByProductID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -277,11 +297,12 @@ func (p *pservice) DeleteProductByID(ctx context.Context, id int) error {
 		return err
 	}
 
-	// Delete associated image after successful DB deletion.
-	if product != nil && product.ImageURL != "" {
-		if delErr := p.DeleteProductImage(ctx, product.ImageURL); delErr != nil {
-			logger.WithError(delErr).Warn("failed to delete product image on delete")
+	// Products are only marked deleted, so their images are removed here.
+	if len(images) > 0 {
+		if err := productImageStore.DeleteProductImagesByProductID(ctx, nil, id); err != nil {
+			return err
 		}
+		deleteProductImageFiles(ctx, images...)
 	}
 
 	return nil
@@ -296,41 +317,58 @@ func (p *pservice) GetPurchaseListProducts(ctx context.Context, shopID int) ([]r
 	productsData := make([]response.PurchaseListProductData, 0, len(products))
 	for _, product := range products {
 		productsData = append(productsData, response.PurchaseListProductData{
+			ProductID:   product.ProductID,
 			ProductName: product.ProductName,
 			Price:       product.Price,
 			Qty:         product.Qty,
+			Status:      product.Status,
+			BoughtQty:   product.BoughtQty,
+			ActualCost:  product.ActualCost,
+			Notes:       product.Notes,
 		})
 	}
 
 	return productsData, nil
 }
 
+// UploadProductImage processes the image into its variants for a product to
+// be created or updated with, and returns the URL of the full-size copy. The
+// product picks up the other variants from it.
 func (p *pservice) UploadProductImage(ctx context.Context, file io.Reader) (string, error) {
-	return uploadImage(file, "products")
+	urls, err := uploadProcessedImage(ctx, file, "products", productImageVariants)
+	if err != nil {
+		return "", err
+	}
+	return urls[2], nil
 }
 
+// DeleteProductImage removes an image uploaded by UploadProductImage along
+// with its other variants.
 func (p *pservice) DeleteProductImage(ctx context.Context, imageURL string) error {
-	// Cloud path: delete from Cloudflare R2.
-	if cfg.R2BucketName != "" && cfg.R2PublicURL != "" && strings.HasPrefix(imageURL, cfg.R2PublicURL) {
-		objectKey := strings.TrimPrefix(imageURL, cfg.R2PublicURL+"/")
-		return r2DeleteFunc(objectKey)
-	}
-
-	// Local filesystem path.
-	const urlPrefix = "/uploads/products/"
-	if !strings.HasPrefix(imageURL, urlPrefix) {
+	key, ok := storageBackend.Key(imageURL)
+	if !ok || !strings.HasPrefix(key, "products/") {
 		return errors.New(apierr.ErrInvalidImageURL)
 	}
 
-	filename := strings.TrimPrefix(imageURL, urlPrefix)
-	filePath := filepath.Join(cfg.UploadDir, "products", filename)
-	if err := os.Remove(filePath); err != nil {
-		if os.IsNotExist(err) {
+	if err := storageBackend.Delete(ctx, key); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
 			return errors.New(apierr.ErrImageNotFound)
 		}
 		return err
 	}
+	if err := uploadedObjectStore.DeleteUploadedObject(ctx, key); err != nil {
+		return err
+	}
 
+	uploaded := uploadedProductImage(imageURL)
+	for _, url := range []string{uploaded.ThumbnailURL, uploaded.MediumURL} {
+		if url == imageURL {
+			continue
+		}
+		if err := deleteUploadedImage(ctx, url); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -341,3 +379,56 @@ func (p *pservice) ActivateAllProductsByShopID(ctx context.Context, shopID int)
 func (p *pservice) DeactivateAllProductsByShopID(ctx context.Context, shopID int) error {
 	return productStore.SetAllProductsStatusByShopID(ctx, shopID, false)
 }
+
+// ReorderProducts sets the order of products within their category to their
+// order in productIDs.
+func (p *pservice) ReorderProducts(ctx context.Context, shopID int, productIDs []int) error {
+	return productStore.ReorderProducts(ctx, shopID, productIDs)
+}
+
+// normalizeProductTags cleans product tags with uniqueTags and checks them
+// against the product tag limits.
+func normalizeProductTags(raw []string) ([]string, error) {
+	tags := uniqueTags(raw)
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > constant.ProductTagMaxLength {
+			return nil, errors.New(apierr.ErrProductTagInvalid)
+		}
+	}
+	if len(tags) > constant.ProductMaxTags {
+		return nil, errors.New(apierr.ErrTooManyProductTags)
+	}
+	return tags, nil
+}
+
+func toProductData(product model.Product) response.ProductData {
+	res := response.ProductData{
+		ID:            product.ID,
+		Name:          product.Name,
+		Description:   product.Description,
+		Price:         product.Price,
+		OriginalPrice: product.OriginalPrice,
+		ImageURL:      product.ImageURL,
+		IsActive:      product.IsActive,
+		Position:      product.Position,
+		Tags:          product.Tags,
+		CreatedAt:     product.CreatedAt,
+	}
+
+	if res.Tags == nil {
+		res.Tags = []string{}
+	}
+	res.Images = []response.ProductImageData{}
+
+	if product.CategoryID.Valid {
+		categoryID := int(product.CategoryID.Int64)
+		res.CategoryID = &categoryID
+	}
+
+	if product.UpdatedAt.Valid {
+		t := product.UpdatedAt.Time
+		res.UpdatedAt = &t
+	}
+
+	return res
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+type (
+	ProductCategoryService interface {
+		CreateProductCategory(ctx context.Context, input CreateProductCategoryInput) (response.ProductCategoryData, error)
+		GetProductCategoriesByShopID(ctx context.Context, shopID int) ([]response.ProductCategoryData, error)
+		UpdateProductCategory(ctx context.Context, input UpdateProductCategoryInput) (response.ProductCategoryData, error)
+		DeleteProductCategoryByID(ctx context.Context, id, shopID int) error
+		ReorderProductCategories(ctx context.Context, shopID int, categoryIDs []int) error
+	}
+
+	pcservice struct{}
+
+	CreateProductCategoryInput struct {
+		ShopID   int
+		ParentID *int
+		Name     string
+	}
+
+	UpdateProductCategoryInput struct {
+		ID       int
+		ShopID   int
+		Name     *string
+		ParentID *int // 0 makes the category top-level
+	}
+)
+
+func NewProductCategoryService() ProductCategoryService {
+	if productCategoryStore == nil {
+		productCategoryStore = store.NewProductCategoryStore()
+	}
+
+	return &pcservice{}
+}
+
+// CreateProductCategory adds a category after its siblings. ParentID places
+// it under a top-level category.
+func (c *pcservice) CreateProductCategory(ctx context.Context, input CreateProductCategoryInput) (response.ProductCategoryData, error) {
+	if input.ParentID != nil {
+		categories, err := productCategoryStore.GetProductCategoriesByShopID(ctx, input.ShopID)
+		if err != nil {
+			return response.ProductCategoryData{}, err
+		}
+		if !validCategoryParent(categories, 0, *input.ParentID) {
+			return response.ProductCategoryData{}, errors.New(apierr.ErrProductCategoryParentInvalid)
+		}
+	}
+
+	category, err := productCategoryStore.CreateProductCategory(ctx, store.CreateProductCategoryInput{
+		ShopID:   input.ShopID,
+		ParentID: input.ParentID,
+		Name:     input.Name,
+	})
+	if err != nil {
+		return response.ProductCategoryData{}, err
+	}
+
+	return toProductCategoryData(*category), nil
+}
+
+func (c *pcservice) GetProductCategoriesByShopID(ctx context.Context, shopID int) ([]response.ProductCategoryData, error) {
+	categories, err := productCategoryStore.GetProductCategoriesByShopID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []response.ProductCategoryData{}
+	for _, category := range categories {
+		res = append(res, toProductCategoryData(category))
+	}
+
+	return res, nil
+}
+
+func (c *pcservice) UpdateProductCategory(ctx context.Context, input UpdateProductCategoryInput) (response.ProductCategoryData, error) {
+	category, err := productCategoryStore.GetProductCategoryByID(ctx, input.ID, input.ShopID)
+	if err != nil {
+		return response.ProductCategoryData{}, err
+	}
+
+	if category == nil {
+		return response.ProductCategoryData{}, errors.New(apierr.ErrProductCategoryNotFound)
+	}
+
+	if input.ParentID != nil && *input.ParentID != 0 {
+		categories, err := productCategoryStore.GetProductCategoriesByShopID(ctx, input.ShopID)
+		if err != nil {
+			return response.ProductCategoryData{}, err
+		}
+		if !validCategoryParent(categories, input.ID, *input.ParentID) {
+			return response.ProductCategoryData{}, errors.New(apierr.ErrProductCategoryParentInvalid)
+		}
+	}
+
+	category, err = productCategoryStore.UpdateProductCategory(ctx, input.ID, store.UpdateProductCategoryInput{
+		Name:     input.Name,
+		ParentID: input.ParentID,
+	})
+	if err != nil {
+		return response.ProductCategoryData{}, err
+	}
+
+	return toProductCategoryData(*category), nil
+}
+
+// DeleteProductCategoryByID deletes the category. Its subcategories become
+// top-level and its products uncategorized.
+func (c *pcservice) DeleteProductCategoryByID(ctx context.Context, id, shopID int) error {
+	category, err := productCategoryStore.GetProductCategoryByID(ctx, id, shopID)
+	if err != nil {
+		return err
+	}
+
+	if category == nil {
+		return errors.New(apierr.ErrProductCategoryNotFound)
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := productCategoryStore.DeleteProductCategoryByID(ctx, tx, id, shopID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderProductCategories sets the order of categories among their
+// siblings to their order in categoryIDs.
+func (c *pcservice) ReorderProductCategories(ctx context.Context, shopID int, categoryIDs []int) error {
+	return productCategoryStore.ReorderProductCategories(ctx, shopID, categoryIDs)
+}
+
+// validCategoryParent reports whether category id (0 for a new one) can be
+// placed under parentID. Categories nest one level deep: the parent must be
+// a top-level category and the category must have no subcategories itself.
+func validCategoryParent(categories []model.ProductCategory, id, parentID int) bool {
+	if parentID == id {
+		return false
+	}
+
+	parentFound := false
+	for _, category := range categories {
+		if category.ID == parentID {
+			if category.ParentID.Valid {
+				return false
+			}
+			parentFound = true
+		}
+		if id != 0 && category.ParentID.Valid && int(category.ParentID.Int64) == id {
+			return false
+		}
+	}
+
+	return parentFound
+}
+
+func toProductCategoryData(category model.ProductCategory) response.ProductCategoryData {
+	res := response.ProductCategoryData{
+		ID:        category.ID,
+		Name:      category.Name,
+		Position:  category.Position,
+		CreatedAt: category.CreatedAt,
+	}
+
+	if category.ParentID.Valid {
+		parentID := int(category.ParentID.Int64)
+		res.ParentID = &parentID
+	}
+
+	if category.UpdatedAt.Valid {
+		res.UpdatedAt = &category.UpdatedAt.Time
+	}
+
+	return res
+}
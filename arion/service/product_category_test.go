@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+func Test_pcservice_CreateProductCategory(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	parentID := 1
+	subID := 2
+
+	categories := []model.ProductCategory{
+		{ID: 1, ShopID: 10, Name: "Snacks"},
+		{ID: 2, ShopID: 10, ParentID: sql.NullInt64{Int64: 1, Valid: true}, Name: "Chips"},
+	}
+
+	tests := []struct {
+		name      string
+		input     CreateProductCategoryInput
+		mockSetup func(category *mock_store.MockProductCategoryStore)
+		want      response.ProductCategoryData
+		wantErr   string
+	}{
+		{
+			name:  "creates top-level category",
+			input: CreateProductCategoryInput{ShopID: 10, Name: "Drinks"},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().CreateProductCategory(gomock.Any(), store.CreateProductCategoryInput{ShopID: 10, Name: "Drinks"}).
+					Return(&model.ProductCategory{ID: 3, ShopID: 10, Name: "Drinks", Position: 1, CreatedAt: fixedTime}, nil)
+			},
+			want: response.ProductCategoryData{ID: 3, Name: "Drinks", Position: 1, CreatedAt: fixedTime},
+		},
+		{
+			name:  "creates subcategory under a top-level category",
+			input: CreateProductCategoryInput{ShopID: 10, ParentID: &parentID, Name: "Cookies"},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 10).Return(categories, nil)
+				category.EXPECT().CreateProductCategory(gomock.Any(), store.CreateProductCategoryInput{ShopID: 10, ParentID: &parentID, Name: "Cookies"}).
+					Return(&model.ProductCategory{ID: 4, ShopID: 10, ParentID: sql.NullInt64{Int64: 1, Valid: true}, Name: "Cookies", Position: 1, CreatedAt: fixedTime}, nil)
+			},
+			want: response.ProductCategoryData{ID: 4, ParentID: &parentID, Name: "Cookies", Position: 1, CreatedAt: fixedTime},
+		},
+		{
+			name:  "rejects a subcategory as parent",
+			input: CreateProductCategoryInput{ShopID: 10, ParentID: &subID, Name: "Potato"},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 10).Return(categories, nil)
+			},
+			wantErr: apierr.ErrProductCategoryParentInvalid,
+		},
+		{
+			name:  "returns store error",
+			input: CreateProductCategoryInput{ShopID: 10, Name: "Snacks"},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().CreateProductCategory(gomock.Any(), gomock.Any()).Return(nil, store.ErrDuplicateProductCategoryName)
+			},
+			wantErr: apierr.ErrProductCategoryNameExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductCategoryStore := productCategoryStore
+			defer func() { productCategoryStore = oldProductCategoryStore }()
+
+			mockCategory := mock_store.NewMockProductCategoryStore(ctrl)
+			tt.mockSetup(mockCategory)
+			productCategoryStore = mockCategory
+
+			var c pcservice
+			got, gotErr := c.CreateProductCategory(context.Background(), tt.input)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("CreateProductCategory() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("CreateProductCategory() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateProductCategory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pcservice_GetProductCategoriesByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	parentID := 1
+
+	tests := []struct {
+		name      string
+		mockSetup func(category *mock_store.MockProductCategoryStore)
+		want      []response.ProductCategoryData
+		wantErr   bool
+	}{
+		{
+			name: "returns categories",
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 10).Return([]model.ProductCategory{
+					{ID: 1, ShopID: 10, Name: "Snacks", CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+					{ID: 2, ShopID: 10, ParentID: sql.NullInt64{Int64: 1, Valid: true}, Name: "Chips", CreatedAt: fixedTime},
+				}, nil)
+			},
+			want: []response.ProductCategoryData{
+				{ID: 1, Name: "Snacks", CreatedAt: fixedTime, UpdatedAt: &fixedTime},
+				{ID: 2, ParentID: &parentID, Name: "Chips", CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when shop has no categories",
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 10).Return([]model.ProductCategory{}, nil)
+			},
+			want: []response.ProductCategoryData{},
+		},
+		{
+			name: "returns error on store failure",
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 10).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductCategoryStore := productCategoryStore
+			defer func() { productCategoryStore = oldProductCategoryStore }()
+
+			mockCategory := mock_store.NewMockProductCategoryStore(ctrl)
+			tt.mockSetup(mockCategory)
+			productCategoryStore = mockCategory
+
+			var c pcservice
+			got, gotErr := c.GetProductCategoriesByShopID(context.Background(), 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetProductCategoriesByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetProductCategoriesByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProductCategoriesByShopID() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pcservice_UpdateProductCategory(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	name := "Sweets"
+	parentID := 1
+	topLevel := 0
+
+	categories := []model.ProductCategory{
+		{ID: 1, ShopID: 10, Name: "Snacks"},
+		{ID: 2, ShopID: 10, Name: "Candy"},
+		{ID: 3, ShopID: 10, ParentID: sql.NullInt64{Int64: 2, Valid: true}, Name: "Lollipops"},
+	}
+
+	tests := []struct {
+		name      string
+		input     UpdateProductCategoryInput
+		mockSetup func(category *mock_store.MockProductCategoryStore)
+		want      response.ProductCategoryData
+		wantErr   string
+	}{
+		{
+			name:  "renames category",
+			input: UpdateProductCategoryInput{ID: 2, ShopID: 10, Name: &name},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 2, 10).Return(&categories[1], nil)
+				category.EXPECT().UpdateProductCategory(gomock.Any(), 2, store.UpdateProductCategoryInput{Name: &name}).
+					Return(&model.ProductCategory{ID: 2, ShopID: 10, Name: "Sweets", CreatedAt: fixedTime}, nil)
+			},
+			want: response.ProductCategoryData{ID: 2, Name: "Sweets", CreatedAt: fixedTime},
+		},
+		{
+			name:  "moves subcategory to top level without checking parents",
+			input: UpdateProductCategoryInput{ID: 3, ShopID: 10, ParentID: &topLevel},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 3, 10).Return(&categories[2], nil)
+				category.EXPECT().UpdateProductCategory(gomock.Any(), 3, store.UpdateProductCategoryInput{ParentID: &topLevel}).
+					Return(&model.ProductCategory{ID: 3, ShopID: 10, Name: "Lollipops", Position: 2, CreatedAt: fixedTime}, nil)
+			},
+			want: response.ProductCategoryData{ID: 3, Name: "Lollipops", Position: 2, CreatedAt: fixedTime},
+		},
+		{
+			name:  "rejects moving a category that has subcategories",
+			input: UpdateProductCategoryInput{ID: 2, ShopID: 10, ParentID: &parentID},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 2, 10).Return(&categories[1], nil)
+				category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 10).Return(categories, nil)
+			},
+			wantErr: apierr.ErrProductCategoryParentInvalid,
+		},
+		{
+			name:  "returns error when category not found",
+			input: UpdateProductCategoryInput{ID: 9, ShopID: 10, Name: &name},
+			mockSetup: func(category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 9, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrProductCategoryNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductCategoryStore := productCategoryStore
+			defer func() { productCategoryStore = oldProductCategoryStore }()
+
+			mockCategory := mock_store.NewMockProductCategoryStore(ctrl)
+			tt.mockSetup(mockCategory)
+			productCategoryStore = mockCategory
+
+			var c pcservice
+			got, gotErr := c.UpdateProductCategory(context.Background(), tt.input)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("UpdateProductCategory() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("UpdateProductCategory() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateProductCategory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pcservice_DeleteProductCategoryByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(ctrl *gomock.Controller, category *mock_store.MockProductCategoryStore) *mock_database.MockDB
+		wantErr   string
+	}{
+		{
+			name: "deletes category in a transaction",
+			mockSetup: func(ctrl *gomock.Controller, category *mock_store.MockProductCategoryStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 2, 10).Return(&model.ProductCategory{ID: 2, ShopID: 10}, nil)
+				category.EXPECT().DeleteProductCategoryByID(gomock.Any(), mockTx, 2, 10).Return(nil)
+				return mockDB
+			},
+		},
+		{
+			name: "returns error when category not found",
+			mockSetup: func(ctrl *gomock.Controller, category *mock_store.MockProductCategoryStore) *mock_database.MockDB {
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 2, 10).Return(nil, nil)
+				return mock_database.NewMockDB(ctrl)
+			},
+			wantErr: apierr.ErrProductCategoryNotFound,
+		},
+		{
+			name: "returns store error without committing",
+			mockSetup: func(ctrl *gomock.Controller, category *mock_store.MockProductCategoryStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 2, 10).Return(&model.ProductCategory{ID: 2, ShopID: 10}, nil)
+				category.EXPECT().DeleteProductCategoryByID(gomock.Any(), mockTx, 2, 10).Return(store.ErrDuplicateProductCategoryName)
+				return mockDB
+			},
+			wantErr: apierr.ErrProductCategoryNameExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductCategoryStore, oldDBGetter := productCategoryStore, dbGetter
+			defer func() { productCategoryStore, dbGetter = oldProductCategoryStore, oldDBGetter }()
+
+			mockCategory := mock_store.NewMockProductCategoryStore(ctrl)
+			mockDB := tt.mockSetup(ctrl, mockCategory)
+			productCategoryStore = mockCategory
+			dbGetter = func() database.DB { return mockDB }
+
+			var c pcservice
+			gotErr := c.DeleteProductCategoryByID(context.Background(), 2, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("DeleteProductCategoryByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("DeleteProductCategoryByID() succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func Test_validCategoryParent(t *testing.T) {
+	categories := []model.ProductCategory{
+		{ID: 1, Name: "Snacks"},
+		{ID: 2, Name: "Candy"},
+		{ID: 3, ParentID: sql.NullInt64{Int64: 2, Valid: true}, Name: "Lollipops"},
+	}
+
+	tests := []struct {
+		name     string
+		id       int
+		parentID int
+		want     bool
+	}{
+		{name: "new category under top-level category", id: 0, parentID: 1, want: true},
+		{name: "existing leaf under top-level category", id: 3, parentID: 1, want: true},
+		{name: "parent is a subcategory", id: 0, parentID: 3, want: false},
+		{name: "category under itself", id: 1, parentID: 1, want: false},
+		{name: "category with subcategories", id: 2, parentID: 1, want: false},
+		{name: "unknown parent", id: 0, parentID: 99, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validCategoryParent(categories, tt.id, tt.parentID); got != tt.want {
+				t.Errorf("validCategoryParent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
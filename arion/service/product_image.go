@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+// productImageVariants are the copies stored for each product image, in the
+// order uploadProcessedImage returns their URLs.
+var productImageVariants = []imageVariant{
+	{suffix: "thumb", maxSize: constant.ProductImageThumbnailSize},
+	{suffix: "medium", maxSize: constant.ProductImageMediumSize},
+	{suffix: "full", maxSize: constant.ProductImageFullSize},
+}
+
+// uploadedProductImage returns the variants of the image UploadProductImage
+// stored with its full-size copy at fullURL; the other copies share its name
+// but for the suffix. Any other URL, such as one stored before images had
+// variants, stands in for all three.
+func uploadedProductImage(fullURL string) model.ProductImage {
+	name, ok := strings.CutSuffix(fullURL, "_full.webp")
+	if !ok {
+		return model.ProductImage{ThumbnailURL: fullURL, MediumURL: fullURL, FullURL: fullURL}
+	}
+	return model.ProductImage{ThumbnailURL: name + "_thumb.webp", MediumURL: name + "_medium.webp", FullURL: fullURL}
+}
+
+// AddProductImage processes the uploaded image into its variants and adds it
+// after the product's other images. The product's first image is primary.
+func (p *pservice) AddProductImage(ctx context.Context, productID, shopID int, file io.Reader) (response.ProductImageData, error) {
+	if _, err := getShopProduct(ctx, productID, shopID); err != nil {
+		return response.ProductImageData{}, err
+	}
+
+	images, err := productImageStore.GetProductImagesByProductID(ctx, productID)
+	if err != nil {
+		return response.ProductImageData{}, err
+	}
+	if len(images) >= constant.ProductMaxImages {
+		return response.ProductImageData{}, errors.New(apierr.ErrTooManyProductImages)
+	}
+
+	urls, err := uploadProcessedImage(ctx, file, "products", productImageVariants)
+	if err != nil {
+		return response.ProductImageData{}, err
+	}
+	uploaded := model.ProductImage{ThumbnailURL: urls[0], MediumURL: urls[1], FullURL: urls[2]}
+
+	image, err := createProductImage(ctx, store.CreateProductImageInput{
+		ProductID:    productID,
+		ShopID:       shopID,
+		ThumbnailURL: uploaded.ThumbnailURL,
+		MediumURL:    uploaded.MediumURL,
+		FullURL:      uploaded.FullURL,
+	})
+	if err != nil {
+		deleteProductImageFiles(ctx, uploaded)
+		return response.ProductImageData{}, err
+	}
+
+	return toProductImageData(*image), nil
+}
+
+// GetProductImages returns the product's images in display order.
+func (p *pservice) GetProductImages(ctx context.Context, productID, shopID int) ([]response.ProductImageData, error) {
+	if _, err := getShopProduct(ctx, productID, shopID); err != nil {
+		return nil, err
+	}
+
+	images, err := productImageStore.GetProductImagesByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	imagesData := make([]response.ProductImageData, 0, len(images))
+	for _, image := range images {
+		imagesData = append(imagesData, toProductImageData(image))
+	}
+
+	return imagesData, nil
+}
+
+// SetPrimaryProductImage makes the image the product's primary image; its
+// full-size URL becomes the product's image_url.
+func (p *pservice) SetPrimaryProductImage(ctx context.Context, imageID, productID, shopID int) error {
+	if _, err := getProductImage(ctx, imageID, productID, shopID); err != nil {
+		return err
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := productImageStore.SetPrimaryProductImage(ctx, tx, imageID, productID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderProductImages sets the order of the product's images to their order
+// in imageIDs.
+func (p *pservice) ReorderProductImages(ctx context.Context, productID, shopID int, imageIDs []int) error {
+	if _, err := getShopProduct(ctx, productID, shopID); err != nil {
+		return err
+	}
+
+	return productImageStore.ReorderProductImages(ctx, productID, imageIDs)
+}
+
+// DeleteProductImageByID removes the image and its files. When it was the
+// primary image, the next image in order becomes primary.
+func (p *pservice) DeleteProductImageByID(ctx context.Context, imageID, productID, shopID int) error {
+	image, err := getProductImage(ctx, imageID, productID, shopID)
+	if err != nil {
+		return err
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := productImageStore.DeleteProductImageByID(ctx, tx, imageID, productID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	deleteProductImageFiles(ctx, *image)
+	return nil
+}
+
+// createProductImage adds an image row in a transaction of its own, as
+// creating it also updates the product's image_url.
+func createProductImage(ctx context.Context, input store.CreateProductImageInput) (*model.ProductImage, error) {
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	image, err := productImageStore.CreateProductImage(ctx, tx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// getShopProduct returns the shop's product, or ErrProductNotFound.
+func getShopProduct(ctx context.Context, productID, shopID int) (*model.Product, error) {
+	product, err := productStore.GetProductByID(ctx, productID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New(apierr.ErrProductNotFound)
+	}
+	return product, nil
+}
+
+// getProductImage returns the image of the shop's product, or the error for
+// whichever of the two is missing.
+func getProductImage(ctx context.Context, imageID, productID, shopID int) (*model.ProductImage, error) {
+	if _, err := getShopProduct(ctx, productID, shopID); err != nil {
+		return nil, err
+	}
+
+	image, err := productImageStore.GetProductImageByID(ctx, imageID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if image == nil {
+		return nil, errors.New(apierr.ErrProductImageNotFound)
+	}
+	return image, nil
+}
+
+// attachProductImages fills in the images of each product with one query.
+func attachProductImages(ctx context.Context, products []response.ProductData) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	productIDs := make([]int, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	images, err := productImageStore.GetProductImagesByProductIDs(ctx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	byProduct := map[int][]response.ProductImageData{}
+	for _, image := range images {
+		byProduct[image.ProductID] = append(byProduct[image.ProductID], toProductImageData(image))
+	}
+	for i := range products {
+		if images, ok := byProduct[products[i].ID]; ok {
+			products[i].Images = images
+		}
+	}
+
+	return nil
+}
+
+// deleteProductImageFiles removes the stored variants of the images.
+// Images that predate processing point every variant at the same file, so
+// each URL is deleted once. Failures are logged, not returned: the rows are
+// already gone.
+func deleteProductImageFiles(ctx context.Context, images ...model.ProductImage) {
+	deleted := map[string]bool{}
+	for _, image := range images {
+		for _, url := range []string{image.ThumbnailURL, image.MediumURL, image.FullURL} {
+			if url == "" || deleted[url] {
+				continue
+			}
+			deleted[url] = true
+			if err := deleteUploadedImage(ctx, url); err != nil {
+				logger.WithError(err).Warn("failed to delete product image file")
+			}
+		}
+	}
+}
+
+func toProductImageData(image model.ProductImage) response.ProductImageData {
+	return response.ProductImageData{
+		ID:           image.ID,
+		ThumbnailURL: image.ThumbnailURL,
+		MediumURL:    image.MediumURL,
+		FullURL:      image.FullURL,
+		Position:     image.Position,
+		IsPrimary:    image.IsPrimary,
+		CreatedAt:    image.CreatedAt,
+	}
+}
@@ -0,0 +1,736 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+// emptyProductImageStore returns a ProductImageStore mock for products that
+// have no images.
+func emptyProductImageStore(ctrl *gomock.Controller) *mock_store.MockProductImageStore {
+	mock := mock_store.NewMockProductImageStore(ctrl)
+	mock.EXPECT().GetProductImagesByProductIDs(gomock.Any(), gomock.Any()).Return([]model.ProductImage{}, nil).AnyTimes()
+	return mock
+}
+
+// writeUploadedFiles stores an image in storageBackend for each of the URLs.
+func writeUploadedFiles(t *testing.T, urls ...string) {
+	t.Helper()
+	for _, url := range urls {
+		key, _ := storageBackend.Key(url)
+		if err := storageBackend.Put(context.Background(), key, "image/webp", []byte("image")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func uploadedFileExists(url string) bool {
+	key, _ := storageBackend.Key(url)
+	_, err := storageBackend.Get(context.Background(), key)
+	return err == nil
+}
+
+func Test_pservice_AddProductImage(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	type mocks struct {
+		product *mock_store.MockProductStore
+		image   *mock_store.MockProductImageStore
+		db      *mock_database.MockDB
+		tx      *mock_database.MockTx
+	}
+
+	tenImages := make([]model.ProductImage, constant.ProductMaxImages)
+
+	tests := []struct {
+		name      string
+		file      []byte
+		mockSetup func(m mocks)
+		wantFiles int
+		wantErr   string
+	}{
+		{
+			name: "stores the variants and adds the image",
+			file: encodeTestPNG(t, 900, 600),
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				m.image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{}, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.image.EXPECT().CreateProductImage(gomock.Any(), m.tx, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ database.Tx, input store.CreateProductImageInput) (*model.ProductImage, error) {
+						return &model.ProductImage{
+							ID:           7,
+							ProductID:    input.ProductID,
+							ShopID:       input.ShopID,
+							ThumbnailURL: input.ThumbnailURL,
+							MediumURL:    input.MediumURL,
+							FullURL:      input.FullURL,
+							IsPrimary:    true,
+							CreatedAt:    fixedTime,
+						}, nil
+					})
+				m.tx.EXPECT().Commit().Return(nil)
+				m.tx.EXPECT().Rollback().Return(nil)
+			},
+			wantFiles: 3,
+		},
+		{
+			name: "product not found",
+			file: encodeTestPNG(t, 10, 10),
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrProductNotFound,
+		},
+		{
+			name: "product already has the most images allowed",
+			file: encodeTestPNG(t, 10, 10),
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				m.image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return(tenImages, nil)
+			},
+			wantErr: apierr.ErrTooManyProductImages,
+		},
+		{
+			name: "unsupported file type",
+			file: []byte("hello plain text"),
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				m.image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{}, nil)
+			},
+			wantErr: apierr.ErrUnsupportedImageType,
+		},
+		{
+			name: "store error removes the stored variants",
+			file: encodeTestPNG(t, 10, 10),
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				m.image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{}, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.image.EXPECT().CreateProductImage(gomock.Any(), m.tx, gomock.Any()).Return(nil, errors.New("db error"))
+				m.tx.EXPECT().Rollback().Return(nil)
+			},
+			wantErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			backend := useMemoryStorage(t)
+			oldProductStore, oldProductImageStore, oldDBGetter := productStore, productImageStore, dbGetter
+			defer func() {
+				productStore, productImageStore, dbGetter = oldProductStore, oldProductImageStore, oldDBGetter
+			}()
+
+			m := mocks{
+				product: mock_store.NewMockProductStore(ctrl),
+				image:   mock_store.NewMockProductImageStore(ctrl),
+				db:      mock_database.NewMockDB(ctrl),
+				tx:      mock_database.NewMockTx(ctrl),
+			}
+			tt.mockSetup(m)
+			productStore, productImageStore = m.product, m.image
+			dbGetter = func() database.DB { return m.db }
+
+			var p pservice
+			got, gotErr := p.AddProductImage(context.Background(), 1, 10, bytes.NewReader(tt.file))
+
+			files := backend.Keys()
+			if len(files) != tt.wantFiles {
+				t.Errorf("stored %d files, want %d", len(files), tt.wantFiles)
+			}
+
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("AddProductImage() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("AddProductImage() succeeded unexpectedly")
+			}
+
+			for _, url := range []string{got.ThumbnailURL, got.MediumURL, got.FullURL} {
+				if !uploadedFileExists(url) {
+					t.Errorf("variant %s was not stored", url)
+				}
+			}
+			if got.ID != 7 || !got.IsPrimary {
+				t.Errorf("AddProductImage() = %+v, want image 7 as primary", got)
+			}
+		})
+	}
+}
+
+func Test_pservice_GetProductImages(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(product *mock_store.MockProductStore, image *mock_store.MockProductImageStore)
+		want      []response.ProductImageData
+		wantErr   string
+	}{
+		{
+			name: "returns the product's images",
+			mockSetup: func(product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{
+					{ID: 2, ProductID: 1, ThumbnailURL: "t2", MediumURL: "m2", FullURL: "f2", Position: 0, IsPrimary: true, CreatedAt: fixedTime},
+					{ID: 3, ProductID: 1, ThumbnailURL: "t3", MediumURL: "m3", FullURL: "f3", Position: 1, CreatedAt: fixedTime},
+				}, nil)
+			},
+			want: []response.ProductImageData{
+				{ID: 2, ThumbnailURL: "t2", MediumURL: "m2", FullURL: "f2", Position: 0, IsPrimary: true, CreatedAt: fixedTime},
+				{ID: 3, ThumbnailURL: "t3", MediumURL: "m3", FullURL: "f3", Position: 1, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "product without images",
+			mockSetup: func(product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{}, nil)
+			},
+			want: []response.ProductImageData{},
+		},
+		{
+			name: "product not found",
+			mockSetup: func(product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductStore, oldProductImageStore := productStore, productImageStore
+			defer func() { productStore, productImageStore = oldProductStore, oldProductImageStore }()
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			mockImage := mock_store.NewMockProductImageStore(ctrl)
+			tt.mockSetup(mockProduct, mockImage)
+			productStore, productImageStore = mockProduct, mockImage
+
+			var p pservice
+			got, gotErr := p.GetProductImages(context.Background(), 1, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("GetProductImages() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("GetProductImages() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProductImages() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pservice_SetPrimaryProductImage(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB
+		wantErr   string
+	}{
+		{
+			name: "makes the image primary",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				image.EXPECT().GetProductImageByID(gomock.Any(), 3, 1).Return(&model.ProductImage{ID: 3, ProductID: 1}, nil)
+				image.EXPECT().SetPrimaryProductImage(gomock.Any(), mockTx, 3, 1).Return(nil)
+				return mockDB
+			},
+		},
+		{
+			name: "image not found",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				image.EXPECT().GetProductImageByID(gomock.Any(), 3, 1).Return(nil, nil)
+				return mock_database.NewMockDB(ctrl)
+			},
+			wantErr: apierr.ErrProductImageNotFound,
+		},
+		{
+			name: "product of another shop",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(nil, nil)
+				return mock_database.NewMockDB(ctrl)
+			},
+			wantErr: apierr.ErrProductNotFound,
+		},
+		{
+			name: "store error is not committed",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				image.EXPECT().GetProductImageByID(gomock.Any(), 3, 1).Return(&model.ProductImage{ID: 3, ProductID: 1}, nil)
+				image.EXPECT().SetPrimaryProductImage(gomock.Any(), mockTx, 3, 1).Return(errors.New("db error"))
+				return mockDB
+			},
+			wantErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductStore, oldProductImageStore, oldDBGetter := productStore, productImageStore, dbGetter
+			defer func() { productStore, productImageStore, dbGetter = oldProductStore, oldProductImageStore, oldDBGetter }()
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			mockImage := mock_store.NewMockProductImageStore(ctrl)
+			mockDB := tt.mockSetup(ctrl, mockProduct, mockImage)
+			productStore, productImageStore = mockProduct, mockImage
+			dbGetter = func() database.DB { return mockDB }
+
+			var p pservice
+			gotErr := p.SetPrimaryProductImage(context.Background(), 3, 1, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("SetPrimaryProductImage() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("SetPrimaryProductImage() succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func Test_pservice_ReorderProductImages(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(product *mock_store.MockProductStore, image *mock_store.MockProductImageStore)
+		wantErr   string
+	}{
+		{
+			name: "reorders the product's images",
+			mockSetup: func(product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				image.EXPECT().ReorderProductImages(gomock.Any(), 1, []int{3, 2}).Return(nil)
+			},
+		},
+		{
+			name: "product not found",
+			mockSetup: func(product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductStore, oldProductImageStore := productStore, productImageStore
+			defer func() { productStore, productImageStore = oldProductStore, oldProductImageStore }()
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			mockImage := mock_store.NewMockProductImageStore(ctrl)
+			tt.mockSetup(mockProduct, mockImage)
+			productStore, productImageStore = mockProduct, mockImage
+
+			var p pservice
+			gotErr := p.ReorderProductImages(context.Background(), 1, 10, []int{3, 2})
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("ReorderProductImages() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("ReorderProductImages() succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func Test_pservice_DeleteProductImageByID(t *testing.T) {
+	image := model.ProductImage{
+		ID:           3,
+		ProductID:    1,
+		ThumbnailURL: "/uploads/products/a_thumb.webp",
+		MediumURL:    "/uploads/products/a_medium.webp",
+		FullURL:      "/uploads/products/a_full.webp",
+	}
+
+	tests := []struct {
+		name          string
+		mockSetup     func(ctrl *gomock.Controller, product *mock_store.MockProductStore, images *mock_store.MockProductImageStore) *mock_database.MockDB
+		wantFilesGone bool
+		wantErr       string
+	}{
+		{
+			name: "deletes the image and its files",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, images *mock_store.MockProductImageStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				images.EXPECT().GetProductImageByID(gomock.Any(), 3, 1).Return(&image, nil)
+				images.EXPECT().DeleteProductImageByID(gomock.Any(), mockTx, 3, 1).Return(nil)
+				return mockDB
+			},
+			wantFilesGone: true,
+		},
+		{
+			name: "image not found",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, images *mock_store.MockProductImageStore) *mock_database.MockDB {
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				images.EXPECT().GetProductImageByID(gomock.Any(), 3, 1).Return(nil, nil)
+				return mock_database.NewMockDB(ctrl)
+			},
+			wantErr: apierr.ErrProductImageNotFound,
+		},
+		{
+			name: "files are kept when the row is not deleted",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, images *mock_store.MockProductImageStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				product.EXPECT().GetProductByID(gomock.Any(), 1, 10).Return(&model.Product{ID: 1, ShopID: 10}, nil)
+				images.EXPECT().GetProductImageByID(gomock.Any(), 3, 1).Return(&image, nil)
+				images.EXPECT().DeleteProductImageByID(gomock.Any(), mockTx, 3, 1).Return(errors.New("db error"))
+				return mockDB
+			},
+			wantErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			useMemoryStorage(t)
+			oldProductStore, oldProductImageStore, oldDBGetter := productStore, productImageStore, dbGetter
+			defer func() {
+				productStore, productImageStore, dbGetter = oldProductStore, oldProductImageStore, oldDBGetter
+			}()
+			writeUploadedFiles(t, image.ThumbnailURL, image.MediumURL, image.FullURL)
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			mockImage := mock_store.NewMockProductImageStore(ctrl)
+			mockDB := tt.mockSetup(ctrl, mockProduct, mockImage)
+			productStore, productImageStore = mockProduct, mockImage
+			dbGetter = func() database.DB { return mockDB }
+
+			var p pservice
+			gotErr := p.DeleteProductImageByID(context.Background(), 3, 1, 10)
+
+			for _, url := range []string{image.ThumbnailURL, image.MediumURL, image.FullURL} {
+				if uploadedFileExists(url) == tt.wantFilesGone {
+					t.Errorf("file %s exists = %v, want %v", url, !tt.wantFilesGone, tt.wantFilesGone)
+				}
+			}
+
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("DeleteProductImageByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("DeleteProductImageByID() succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func Test_pservice_CreateProduct_ImageURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	imageURL := "/uploads/products/a_full.webp"
+	thumbURL, mediumURL := "/uploads/products/a_thumb.webp", "/uploads/products/a_medium.webp"
+
+	oldProductStore, oldProductImageStore, oldDBGetter := productStore, productImageStore, dbGetter
+	defer func() { productStore, productImageStore, dbGetter = oldProductStore, oldProductImageStore, oldDBGetter }()
+
+	mockTx := mock_database.NewMockTx(ctrl)
+	mockTx.EXPECT().Commit().Return(nil)
+	mockTx.EXPECT().Rollback().Return(nil)
+	mockDB := mock_database.NewMockDB(ctrl)
+	mockDB.EXPECT().Begin().Return(mockTx, nil)
+	dbGetter = func() database.DB { return mockDB }
+
+	mockProduct := mock_store.NewMockProductStore(ctrl)
+	mockProduct.EXPECT().CreateProduct(gomock.Any(), "Product A", nil, 1000, 10, nil, &imageURL).
+		Return(&model.Product{ID: 1, ShopID: 10, Name: "Product A", Price: 1000, ImageURL: imageURL, CreatedAt: fixedTime}, nil)
+	mockImage := mock_store.NewMockProductImageStore(ctrl)
+	mockImage.EXPECT().CreateProductImage(gomock.Any(), mockTx, store.CreateProductImageInput{
+		ProductID:    1,
+		ShopID:       10,
+		ThumbnailURL: thumbURL,
+		MediumURL:    mediumURL,
+		FullURL:      imageURL,
+		IsPrimary:    true,
+	}).Return(&model.ProductImage{ID: 5, ProductID: 1, ThumbnailURL: thumbURL, MediumURL: mediumURL, FullURL: imageURL, IsPrimary: true, CreatedAt: fixedTime}, nil)
+	productStore, productImageStore = mockProduct, mockImage
+
+	var p pservice
+	got, err := p.CreateProduct(context.Background(), 10, "Product A", nil, 1000, nil, &imageURL)
+	if err != nil {
+		t.Fatalf("CreateProduct() error = %v", err)
+	}
+	want := []response.ProductImageData{{ID: 5, ThumbnailURL: thumbURL, MediumURL: mediumURL, FullURL: imageURL, IsPrimary: true, CreatedAt: fixedTime}}
+	if got.ImageURL != imageURL || !reflect.DeepEqual(got.Images, want) {
+		t.Errorf("CreateProduct() = %+v, want image_url %s and images %+v", got, imageURL, want)
+	}
+}
+
+func Test_pservice_UpdateProduct_ImageURL(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	oldImage := model.ProductImage{ID: 2, ProductID: 1, ThumbnailURL: "/uploads/products/old_thumb.webp", MediumURL: "/uploads/products/old_medium.webp", FullURL: "/uploads/products/old_full.webp", IsPrimary: true}
+	otherImage := model.ProductImage{ID: 3, ProductID: 1, ThumbnailURL: "/uploads/products/other_thumb.webp", MediumURL: "/uploads/products/other_medium.webp", FullURL: "/uploads/products/other_full.webp", Position: 1}
+
+	tests := []struct {
+		name           string
+		imageURL       string
+		mockSetup      func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB
+		afterImages    []model.ProductImage
+		wantImageURL   string
+		wantOldDeleted bool
+		wantErr        bool
+	}{
+		{
+			name:     "replaces the primary image",
+			imageURL: "/uploads/products/new_full.webp",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				product.EXPECT().UpdateProduct(gomock.Any(), 1, store.UpdateProductInput{}).
+					Return(&model.Product{ID: 1, ShopID: 10, ImageURL: oldImage.FullURL, CreatedAt: fixedTime}, nil)
+				image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{oldImage, otherImage}, nil)
+				image.EXPECT().DeleteProductImageByID(gomock.Any(), mockTx, 2, 1).Return(nil)
+				image.EXPECT().CreateProductImage(gomock.Any(), mockTx, store.CreateProductImageInput{
+					ProductID:    1,
+					ShopID:       10,
+					ThumbnailURL: "/uploads/products/new_thumb.webp",
+					MediumURL:    "/uploads/products/new_medium.webp",
+					FullURL:      "/uploads/products/new_full.webp",
+					IsPrimary:    true,
+				}).Return(&model.ProductImage{ID: 4}, nil)
+				return mockDB
+			},
+			afterImages:    []model.ProductImage{otherImage, {ID: 4, ProductID: 1, FullURL: "/uploads/products/new_full.webp", Position: 2, IsPrimary: true}},
+			wantImageURL:   "/uploads/products/new_full.webp",
+			wantOldDeleted: true,
+		},
+		{
+			name:     "empty image_url removes the primary image and promotes the next",
+			imageURL: "",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				product.EXPECT().UpdateProduct(gomock.Any(), 1, store.UpdateProductInput{}).
+					Return(&model.Product{ID: 1, ShopID: 10, ImageURL: oldImage.FullURL, CreatedAt: fixedTime}, nil)
+				image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{oldImage, otherImage}, nil)
+				image.EXPECT().DeleteProductImageByID(gomock.Any(), mockTx, 2, 1).Return(nil)
+				return mockDB
+			},
+			afterImages:    []model.ProductImage{{ID: 3, ProductID: 1, FullURL: otherImage.FullURL, Position: 1, IsPrimary: true}},
+			wantImageURL:   otherImage.FullURL,
+			wantOldDeleted: true,
+		},
+		{
+			name:     "same image_url leaves the images alone",
+			imageURL: oldImage.FullURL,
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				product.EXPECT().UpdateProduct(gomock.Any(), 1, store.UpdateProductInput{}).
+					Return(&model.Product{ID: 1, ShopID: 10, ImageURL: oldImage.FullURL, CreatedAt: fixedTime}, nil)
+				return mock_database.NewMockDB(ctrl)
+			},
+			afterImages:  []model.ProductImage{oldImage, otherImage},
+			wantImageURL: oldImage.FullURL,
+		},
+		{
+			name:     "store error keeps the old files",
+			imageURL: "/uploads/products/new_full.webp",
+			mockSetup: func(ctrl *gomock.Controller, product *mock_store.MockProductStore, image *mock_store.MockProductImageStore) *mock_database.MockDB {
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				product.EXPECT().UpdateProduct(gomock.Any(), 1, store.UpdateProductInput{}).
+					Return(&model.Product{ID: 1, ShopID: 10, ImageURL: oldImage.FullURL, CreatedAt: fixedTime}, nil)
+				image.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return([]model.ProductImage{oldImage}, nil)
+				image.EXPECT().DeleteProductImageByID(gomock.Any(), mockTx, 2, 1).Return(nil)
+				image.EXPECT().CreateProductImage(gomock.Any(), mockTx, gomock.Any()).Return(nil, errors.New("db error"))
+				return mockDB
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			useMemoryStorage(t)
+			oldProductStore, oldProductImageStore, oldDBGetter := productStore, productImageStore, dbGetter
+			defer func() {
+				productStore, productImageStore, dbGetter = oldProductStore, oldProductImageStore, oldDBGetter
+			}()
+			writeUploadedFiles(t, oldImage.ThumbnailURL, oldImage.MediumURL, oldImage.FullURL, otherImage.FullURL)
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			mockImage := mock_store.NewMockProductImageStore(ctrl)
+			mockDB := tt.mockSetup(ctrl, mockProduct, mockImage)
+			mockImage.EXPECT().GetProductImagesByProductIDs(gomock.Any(), []int{1}).Return(tt.afterImages, nil).AnyTimes()
+			productStore, productImageStore = mockProduct, mockImage
+			dbGetter = func() database.DB { return mockDB }
+
+			imageURL := tt.imageURL
+			var p pservice
+			got, gotErr := p.UpdateProduct(context.Background(), UpdateProductInput{ID: 1, ShopID: 10, ImageURL: &imageURL})
+
+			if uploadedFileExists(oldImage.FullURL) == tt.wantOldDeleted {
+				t.Errorf("old image deleted = %v, want %v", uploadedFileExists(oldImage.FullURL), tt.wantOldDeleted)
+			}
+			if !uploadedFileExists(otherImage.FullURL) {
+				t.Error("image that was not primary was deleted")
+			}
+
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("UpdateProduct() error = %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("UpdateProduct() succeeded unexpectedly")
+			}
+			if got.ImageURL != tt.wantImageURL {
+				t.Errorf("UpdateProduct() image_url = %q, want %q", got.ImageURL, tt.wantImageURL)
+			}
+		})
+	}
+}
+
+func Test_pservice_DeleteProductByID_Images(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	backend := useMemoryStorage(t)
+	oldProductStore, oldOrderItemStore, oldProductImageStore := productStore, orderItemStore, productImageStore
+	defer func() {
+		productStore, orderItemStore, productImageStore = oldProductStore, oldOrderItemStore, oldProductImageStore
+	}()
+
+	// A backfilled image points every variant at the original upload.
+	images := []model.ProductImage{
+		{ID: 2, ProductID: 1, ThumbnailURL: "/uploads/products/a.jpg", MediumURL: "/uploads/products/a.jpg", FullURL: "/uploads/products/a.jpg", IsPrimary: true},
+		{ID: 3, ProductID: 1, ThumbnailURL: "/uploads/products/b_thumb.webp", MediumURL: "/uploads/products/b_medium.webp", FullURL: "/uploads/products/b_full.webp"},
+	}
+	writeUploadedFiles(t, "/uploads/products/a.jpg", "/uploads/products/b_thumb.webp", "/uploads/products/b_medium.webp", "/uploads/products/b_full.webp")
+
+	mockImage := mock_store.NewMockProductImageStore(ctrl)
+	mockImage.EXPECT().GetProductImagesByProductID(gomock.Any(), 1).Return(images, nil)
+	mockImage.EXPECT().DeleteProductImagesByProductID(gomock.Any(), nil, 1).Return(nil)
+	mockOrderItem := mock_store.NewMockOrderItemStore(ctrl)
+	mockOrderItem.EXPECT().GetOrderTotalsExcludingProduct(gomock.Any(), 1).Return(map[int]int{}, nil)
+	mockProduct := mock_store.NewMockProductStore(ctrl)
+	mockProduct.EXPECT().DeleteProductByID(gomock.Any(), 1).Return(nil)
+	productStore, orderItemStore, productImageStore = mockProduct, mockOrderItem, mockImage
+
+	var p pservice
+	if err := p.DeleteProductByID(context.Background(), 1); err != nil {
+		t.Fatalf("DeleteProductByID() error = %v", err)
+	}
+
+	if files := backend.Keys(); len(files) != 0 {
+		t.Errorf("files left after delete: %v", files)
+	}
+}
+
+func Test_attachProductImages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldProductImageStore := productImageStore
+	defer func() { productImageStore = oldProductImageStore }()
+
+	mockImage := mock_store.NewMockProductImageStore(ctrl)
+	mockImage.EXPECT().GetProductImagesByProductIDs(gomock.Any(), []int{1, 2, 3}).Return([]model.ProductImage{
+		{ID: 10, ProductID: 1, FullURL: "a", IsPrimary: true},
+		{ID: 11, ProductID: 3, FullURL: "b", IsPrimary: true},
+		{ID: 12, ProductID: 3, FullURL: "c", Position: 1},
+	}, nil)
+	productImageStore = mockImage
+
+	products := []response.ProductData{
+		{ID: 1, Images: []response.ProductImageData{}},
+		{ID: 2, Images: []response.ProductImageData{}},
+		{ID: 3, Images: []response.ProductImageData{}},
+	}
+	if err := attachProductImages(context.Background(), products); err != nil {
+		t.Fatalf("attachProductImages() error = %v", err)
+	}
+
+	want := [][]int{{10}, {}, {11, 12}}
+	for i, product := range products {
+		ids := []int{}
+		for _, image := range product.Images {
+			ids = append(ids, image.ID)
+		}
+		if !reflect.DeepEqual(ids, want[i]) {
+			t.Errorf("product %d images = %v, want %v", product.ID, ids, want[i])
+		}
+	}
+}
@@ -5,9 +5,6 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"io"
-	"os"
-	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -15,6 +12,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/database"
 	"github.com/zeirash/recapo/arion/common/response"
 	mock_database "github.com/zeirash/recapo/arion/mock/database"
@@ -73,6 +71,8 @@ func Test_pservice_CreateProduct(t *testing.T) {
 				Description:   "A great product",
 				Price:         1000,
 				OriginalPrice: 1000,
+				Tags:          []string{},
+				Images:        []response.ProductImageData{},
 				CreatedAt:     fixedTime,
 			},
 			wantErr: false,
@@ -104,6 +104,8 @@ func Test_pservice_CreateProduct(t *testing.T) {
 				Name:          "Product B",
 				Price:         500,
 				OriginalPrice: 500,
+				Tags:          []string{},
+				Images:        []response.ProductImageData{},
 				CreatedAt:     fixedTime,
 			},
 			wantErr: false,
@@ -134,9 +136,10 @@ func Test_pservice_CreateProduct(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldStore := productStore
-			defer func() { productStore = oldStore }()
+			oldStore, oldImageStore := productStore, productImageStore
+			defer func() { productStore, productImageStore = oldStore, oldImageStore }()
 			productStore = tt.mockSetup(ctrl)
+			productImageStore = emptyProductImageStore(ctrl)
 
 			var p pservice
 			got, gotErr := p.CreateProduct(context.Background(), tt.input.shopID, tt.input.name, tt.input.description, tt.input.price, tt.input.originalPrice, tt.input.imageURL)
@@ -200,6 +203,8 @@ func Test_pservice_GetProductByID(t *testing.T) {
 				Description:   "A great product",
 				Price:         1000,
 				OriginalPrice: 1000,
+				Tags:          []string{},
+				Images:        []response.ProductImageData{},
 				CreatedAt:     fixedTime,
 				UpdatedAt:     &fixedTime,
 			},
@@ -231,6 +236,8 @@ func Test_pservice_GetProductByID(t *testing.T) {
 				Description:   "A great product",
 				Price:         1000,
 				OriginalPrice: 1000,
+				Tags:          []string{},
+				Images:        []response.ProductImageData{},
 				CreatedAt:     fixedTime,
 			},
 			wantErr: false,
@@ -274,9 +281,10 @@ func Test_pservice_GetProductByID(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldStore := productStore
-			defer func() { productStore = oldStore }()
+			oldStore, oldImageStore := productStore, productImageStore
+			defer func() { productStore, productImageStore = oldStore, oldImageStore }()
 			productStore = tt.mockSetup(ctrl)
+			productImageStore = emptyProductImageStore(ctrl)
 
 			var p pservice
 			got, gotErr := p.GetProductByID(context.Background(), tt.input.productID, tt.input.shopID...)
@@ -326,8 +334,8 @@ func Test_pservice_GetProductsByShopID(t *testing.T) {
 				return mock
 			},
 			wantResult: []response.ProductData{
-				{ID: 1, Name: "Product A", Description: "Desc A", Price: 1000, OriginalPrice: 800, CreatedAt: fixedTime, UpdatedAt: &fixedTime},
-				{ID: 2, Name: "Product B", Price: 500, OriginalPrice: 500, CreatedAt: fixedTime},
+				{ID: 1, Name: "Product A", Description: "Desc A", Price: 1000, OriginalPrice: 800, Tags: []string{}, Images: []response.ProductImageData{}, CreatedAt: fixedTime, UpdatedAt: &fixedTime},
+				{ID: 2, Name: "Product B", Price: 500, OriginalPrice: 500, Tags: []string{}, Images: []response.ProductImageData{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
@@ -373,7 +381,7 @@ func Test_pservice_GetProductsByShopID(t *testing.T) {
 				return mock
 			},
 			wantResult: []response.ProductData{
-				{ID: 1, Name: "Widget A", Description: "A useful widget", Price: 1000, OriginalPrice: 800, CreatedAt: fixedTime},
+				{ID: 1, Name: "Widget A", Description: "A useful widget", Price: 1000, OriginalPrice: 800, Tags: []string{}, Images: []response.ProductImageData{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
@@ -384,9 +392,10 @@ func Test_pservice_GetProductsByShopID(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldStore := productStore
-			defer func() { productStore = oldStore }()
+			oldStore, oldImageStore := productStore, productImageStore
+			defer func() { productStore, productImageStore = oldStore, oldImageStore }()
 			productStore = tt.mockSetup(ctrl)
+			productImageStore = emptyProductImageStore(ctrl)
 
 			var p pservice
 			got, gotErr := p.GetProductsByShopID(context.Background(), tt.shopID, tt.filter)
@@ -449,6 +458,8 @@ func Test_pservice_UpdateProduct(t *testing.T) {
 				Description:   "Desc",
 				Price:         1000,
 				OriginalPrice: 800,
+				Tags:          []string{},
+				Images:        []response.ProductImageData{},
 				CreatedAt:     fixedTime,
 				UpdatedAt:     &updatedTime,
 			},
@@ -481,6 +492,8 @@ func Test_pservice_UpdateProduct(t *testing.T) {
 				Description:   "Desc",
 				Price:         2000,
 				OriginalPrice: 800,
+				Tags:          []string{},
+				Images:        []response.ProductImageData{},
 				CreatedAt:     fixedTime,
 				UpdatedAt:     &updatedTime,
 			},
@@ -509,9 +522,10 @@ func Test_pservice_UpdateProduct(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldStore := productStore
-			defer func() { productStore = oldStore }()
+			oldStore, oldImageStore := productStore, productImageStore
+			defer func() { productStore, productImageStore = oldStore, oldImageStore }()
 			productStore = tt.mockSetup(ctrl)
+			productImageStore = emptyProductImageStore(ctrl)
 
 			var p pservice
 			got, gotErr := p.UpdateProduct(context.Background(), tt.input)
@@ -533,6 +547,143 @@ func Test_pservice_UpdateProduct(t *testing.T) {
 	}
 }
 
+func Test_pservice_UpdateProduct_CategoryAndTags(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	categoryID := 3
+	uncategorized := 0
+	rawTags := []string{" Sale ", "new", "sale", ""}
+	cleanTags := []string{"Sale", "new"}
+	tooManyTags := make([]string, constant.ProductMaxTags+1)
+	for i := range tooManyTags {
+		tooManyTags[i] = strings.Repeat("t", i+1)
+	}
+	longTag := []string{strings.Repeat("t", constant.ProductTagMaxLength+1)}
+
+	tests := []struct {
+		name      string
+		input     UpdateProductInput
+		mockSetup func(product *mock_store.MockProductStore, category *mock_store.MockProductCategoryStore)
+		want      response.ProductData
+		wantErr   string
+	}{
+		{
+			name:  "moves product to one of the shop's categories and cleans tags",
+			input: UpdateProductInput{ID: 1, ShopID: 10, CategoryID: &categoryID, Tags: &rawTags},
+			mockSetup: func(product *mock_store.MockProductStore, category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 3, 10).Return(&model.ProductCategory{ID: 3, ShopID: 10}, nil)
+				product.EXPECT().
+					UpdateProduct(gomock.Any(), 1, store.UpdateProductInput{CategoryID: &categoryID, Tags: &cleanTags}).
+					Return(&model.Product{ID: 1, Name: "Product A", CategoryID: sql.NullInt64{Int64: 3, Valid: true}, Position: 4, Tags: cleanTags, CreatedAt: fixedTime}, nil)
+			},
+			want: response.ProductData{ID: 1, Name: "Product A", CategoryID: &categoryID, Position: 4, Tags: cleanTags, Images: []response.ProductImageData{}, CreatedAt: fixedTime},
+		},
+		{
+			name:  "category 0 removes the product from its category without a lookup",
+			input: UpdateProductInput{ID: 1, ShopID: 10, CategoryID: &uncategorized},
+			mockSetup: func(product *mock_store.MockProductStore, category *mock_store.MockProductCategoryStore) {
+				product.EXPECT().
+					UpdateProduct(gomock.Any(), 1, store.UpdateProductInput{CategoryID: &uncategorized}).
+					Return(&model.Product{ID: 1, Name: "Product A", CreatedAt: fixedTime}, nil)
+			},
+			want: response.ProductData{ID: 1, Name: "Product A", Tags: []string{}, Images: []response.ProductImageData{}, CreatedAt: fixedTime},
+		},
+		{
+			name:  "returns error when category belongs to another shop",
+			input: UpdateProductInput{ID: 1, ShopID: 10, CategoryID: &categoryID},
+			mockSetup: func(product *mock_store.MockProductStore, category *mock_store.MockProductCategoryStore) {
+				category.EXPECT().GetProductCategoryByID(gomock.Any(), 3, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrProductCategoryNotFound,
+		},
+		{
+			name:      "returns error on too many tags",
+			input:     UpdateProductInput{ID: 1, ShopID: 10, Tags: &tooManyTags},
+			mockSetup: func(product *mock_store.MockProductStore, category *mock_store.MockProductCategoryStore) {},
+			wantErr:   apierr.ErrTooManyProductTags,
+		},
+		{
+			name:      "returns error on too long tag",
+			input:     UpdateProductInput{ID: 1, ShopID: 10, Tags: &longTag},
+			mockSetup: func(product *mock_store.MockProductStore, category *mock_store.MockProductCategoryStore) {},
+			wantErr:   apierr.ErrProductTagInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductStore, oldProductCategoryStore, oldProductImageStore := productStore, productCategoryStore, productImageStore
+			defer func() {
+				productStore, productCategoryStore, productImageStore = oldProductStore, oldProductCategoryStore, oldProductImageStore
+			}()
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			mockCategory := mock_store.NewMockProductCategoryStore(ctrl)
+			tt.mockSetup(mockProduct, mockCategory)
+			productStore, productCategoryStore = mockProduct, mockCategory
+			productImageStore = emptyProductImageStore(ctrl)
+
+			var p pservice
+			got, gotErr := p.UpdateProduct(context.Background(), tt.input)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("UpdateProduct() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("UpdateProduct() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateProduct() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pservice_ReorderProducts(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(product *mock_store.MockProductStore)
+		wantErr   bool
+	}{
+		{
+			name: "reorders products",
+			mockSetup: func(product *mock_store.MockProductStore) {
+				product.EXPECT().ReorderProducts(gomock.Any(), 10, []int{3, 1, 2}).Return(nil)
+			},
+		},
+		{
+			name: "returns error on store failure",
+			mockSetup: func(product *mock_store.MockProductStore) {
+				product.EXPECT().ReorderProducts(gomock.Any(), 10, []int{3, 1, 2}).Return(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldStore := productStore
+			defer func() { productStore = oldStore }()
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			tt.mockSetup(mockProduct)
+			productStore = mockProduct
+
+			var p pservice
+			if gotErr := p.ReorderProducts(context.Background(), 10, []int{3, 1, 2}); (gotErr != nil) != tt.wantErr {
+				t.Errorf("ReorderProducts() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_pservice_DeleteProductByID(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -550,9 +701,6 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 					Return(map[int]int{}, nil)
 
 				mockProduct := mock_store.NewMockProductStore(ctrl)
-				mockProduct.EXPECT().
-					GetProductByID(gomock.Any(), 1).
-					Return(&model.Product{ID: 1, Name: "Test"}, nil)
 				mockProduct.EXPECT().
 					DeleteProductByID(gomock.Any(), 1).
 					Return(nil)
@@ -586,9 +734,6 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 					Return(&model.Order{ID: 10, TotalPrice: 5000}, nil)
 
 				mockProduct := mock_store.NewMockProductStore(ctrl)
-				mockProduct.EXPECT().
-					GetProductByID(gomock.Any(), 1).
-					Return(&model.Product{ID: 1, Name: "Test"}, nil)
 				mockProduct.EXPECT().
 					DeleteProductByID(gomock.Any(), 1).
 					Return(nil)
@@ -607,9 +752,6 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 					Return(nil, errors.New("database error"))
 
 				mockProduct := mock_store.NewMockProductStore(ctrl)
-				mockProduct.EXPECT().
-					GetProductByID(gomock.Any(), 1).
-					Return(&model.Product{ID: 1, Name: "Test"}, nil)
 
 				return mockProduct, mockOrderItem, mock_store.NewMockOrderStore(ctrl), nil, nil
 			},
@@ -628,9 +770,6 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 					Return(map[int]int{10: 0}, nil)
 
 				mockProduct := mock_store.NewMockProductStore(ctrl)
-				mockProduct.EXPECT().
-					GetProductByID(gomock.Any(), 1).
-					Return(&model.Product{ID: 1, Name: "Test"}, nil)
 
 				return mockProduct, mockOrderItem, mock_store.NewMockOrderStore(ctrl), mockDB, nil
 			},
@@ -655,9 +794,6 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 					Return(errors.New("delete error"))
 
 				mockProduct := mock_store.NewMockProductStore(ctrl)
-				mockProduct.EXPECT().
-					GetProductByID(gomock.Any(), 1).
-					Return(&model.Product{ID: 1, Name: "Test"}, nil)
 
 				return mockProduct, mockOrderItem, mock_store.NewMockOrderStore(ctrl), mockDB, mockTx
 			},
@@ -687,9 +823,6 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 					Return(nil, errors.New("update error"))
 
 				mockProduct := mock_store.NewMockProductStore(ctrl)
-				mockProduct.EXPECT().
-					GetProductByID(gomock.Any(), 1).
-					Return(&model.Product{ID: 1, Name: "Test"}, nil)
 
 				return mockProduct, mockOrderItem, mockOrder, mockDB, mockTx
 			},
@@ -705,9 +838,6 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 					Return(map[int]int{}, nil)
 
 				mockProduct := mock_store.NewMockProductStore(ctrl)
-				mockProduct.EXPECT().
-					GetProductByID(gomock.Any(), 1).
-					Return(&model.Product{ID: 1, Name: "Test"}, nil)
 				mockProduct.EXPECT().
 					DeleteProductByID(gomock.Any(), 1).
 					Return(errors.New("database error"))
@@ -724,12 +854,16 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 			defer ctrl.Finish()
 
 			oldProductStore, oldOrderItemStore, oldOrderStore := productStore, orderItemStore, orderStore
-			oldDBGetter := dbGetter
+			oldProductImageStore, oldDBGetter := productImageStore, dbGetter
 			defer func() {
 				productStore, orderItemStore, orderStore = oldProductStore, oldOrderItemStore, oldOrderStore
-				dbGetter = oldDBGetter
+				productImageStore, dbGetter = oldProductImageStore, oldDBGetter
 			}()
 
+			mockImage := mock_store.NewMockProductImageStore(ctrl)
+			mockImage.EXPECT().GetProductImagesByProductID(gomock.Any(), tt.id).Return([]model.ProductImage{}, nil)
+			productImageStore = mockImage
+
 			mockProduct, mockOrderItem, mockOrder, mockDB, _ := tt.mockSetup(ctrl)
 			productStore = mockProduct
 			orderItemStore = mockOrderItem
@@ -755,21 +889,14 @@ func Test_pservice_DeleteProductByID(t *testing.T) {
 }
 
 func Test_pservice_UploadProductImage(t *testing.T) {
-	jpegBytes := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00, 0x01}
-
-	tmpDir := t.TempDir()
-	oldCfg := cfg
-	cfg.UploadDir = tmpDir
-	defer func() { cfg = oldCfg }()
+	jpegBytes := encodeTestJPEG(t, 40, 30, 0)
 
 	tests := []struct {
-		name         string
-		fileContent  []byte
-		r2BucketName string // if non-empty, enables R2 path
-		r2UploadErr  error  // error r2UploadFunc should return (nil = success with mock URL)
-		wantURLPfx   string
-		wantErr      bool
-		wantErrMsg   string
+		name        string
+		fileContent []byte
+		wantURLPfx  string
+		wantErr     bool
+		wantErrMsg  string
 	}{
 		{
 			name:        "successfully upload jpeg image",
@@ -783,41 +910,11 @@ func Test_pservice_UploadProductImage(t *testing.T) {
 			wantErr:     true,
 			wantErrMsg:  apierr.ErrUnsupportedImageType,
 		},
-		{
-			name:         "with R2 configured, delegates to R2 upload",
-			fileContent:  jpegBytes,
-			r2BucketName: "test-bucket",
-			r2UploadErr:  errors.New("mock R2 unavailable"),
-			wantErr:      true,
-			wantErrMsg:   "mock R2 unavailable",
-		},
-		{
-			name:         "with R2 configured, returns R2 URL on success",
-			fileContent:  jpegBytes,
-			r2BucketName: "test-bucket",
-			r2UploadErr:  nil,
-			wantURLPfx:   "https://pub-test.r2.dev/products/",
-			wantErr:      false,
-		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.r2BucketName != "" {
-				cfg.R2BucketName = tt.r2BucketName
-				cfg.R2PublicURL = "https://pub-test.r2.dev"
-				defer func() {
-					cfg.R2BucketName = ""
-					cfg.R2PublicURL = ""
-				}()
-
-				uploadErr := tt.r2UploadErr
-				old := r2UploadFunc
-				r2UploadFunc = func(key string, body io.Reader, contentType string) error {
-					return uploadErr
-				}
-				defer func() { r2UploadFunc = old }()
-			}
+			backend := useMemoryStorage(t)
 
 			var p pservice
 			got, gotErr := p.UploadProductImage(context.Background(), bytes.NewReader(tt.fileContent))
@@ -837,93 +934,84 @@ func Test_pservice_UploadProductImage(t *testing.T) {
 			if !strings.HasPrefix(got, tt.wantURLPfx) {
 				t.Errorf("UploadProductImage() url = %v, want prefix %v", got, tt.wantURLPfx)
 			}
+			if !strings.HasSuffix(got, "_full.webp") {
+				t.Errorf("UploadProductImage() url = %v, want a full-size webp", got)
+			}
+			uploaded := uploadedProductImage(got)
+			var want []string
+			for _, url := range []string{uploaded.FullURL, uploaded.MediumURL, uploaded.ThumbnailURL} {
+				key, _ := backend.Key(url)
+				want = append(want, key)
+			}
+			if keys := backend.Keys(); !reflect.DeepEqual(keys, want) {
+				t.Errorf("stored = %v, want the variants %v", keys, want)
+			}
 		})
 	}
 }
 
 func Test_pservice_DeleteProductImage(t *testing.T) {
-	tmpDir := t.TempDir()
-	oldCfg := cfg
-	cfg.UploadDir = tmpDir
-	defer func() { cfg = oldCfg }()
-
-	// create a real temp file to test successful deletion
-	createTempImage := func(t *testing.T) string {
-		t.Helper()
-		productDir := filepath.Join(tmpDir, "products")
-		if err := os.MkdirAll(productDir, 0755); err != nil {
-			t.Fatalf("failed to create upload dir: %v", err)
-		}
-		f, err := os.CreateTemp(productDir, "test-*.jpg")
-		if err != nil {
-			t.Fatalf("failed to create temp file: %v", err)
-		}
-		f.Close()
-		return "/uploads/products/" + filepath.Base(f.Name())
-	}
-
 	tests := []struct {
-		name         string
-		imageURL     func(t *testing.T) string
-		r2BucketName string // if non-empty, enables R2 path
-		r2DeleteErr  error  // error r2DeleteFunc should return
-		wantErr      bool
-		wantErrMsg   string
+		name       string
+		imageURL   string
+		mockSetup  func(m *mock_store.MockUploadedObjectStore)
+		wantErr    bool
+		wantErrMsg string
 	}{
 		{
-			name:     "successfully delete image",
-			imageURL: func(t *testing.T) string { return createTempImage(t) },
-			wantErr:  false,
+			name:     "successfully delete image and its other variants",
+			imageURL: "/uploads/products/a_full.webp",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "products/a_full.webp").Return(nil)
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "products/a_thumb.webp").Return(nil)
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "products/a_medium.webp").Return(nil)
+			},
+			wantErr: false,
 		},
 		{
 			name:       "returns error for invalid URL prefix",
-			imageURL:   func(t *testing.T) string { return "/some/other/path/image.jpg" },
+			imageURL:   "/some/other/path/image.jpg",
 			wantErr:    true,
 			wantErrMsg: apierr.ErrInvalidImageURL,
 		},
 		{
-			name:       "returns error when file does not exist",
-			imageURL:   func(t *testing.T) string { return "/uploads/products/nonexistent.jpg" },
+			name:       "returns error for an upload that is not a product image",
+			imageURL:   "/uploads/logos/a.png",
 			wantErr:    true,
-			wantErrMsg: apierr.ErrImageNotFound,
+			wantErrMsg: apierr.ErrInvalidImageURL,
 		},
 		{
-			name:         "with R2 configured, delegates to R2 delete for R2 URL",
-			imageURL:     func(t *testing.T) string { return "https://pub-test.r2.dev/products/abc.jpg" },
-			r2BucketName: "test-bucket",
-			r2DeleteErr:  errors.New("mock R2 unavailable"),
-			wantErr:      true,
-			wantErrMsg:   "mock R2 unavailable",
+			name:       "returns error when file does not exist",
+			imageURL:   "/uploads/products/nonexistent.jpg",
+			wantErr:    true,
+			wantErrMsg: apierr.ErrImageNotFound,
 		},
 		{
-			name:         "with R2 configured, falls back to local path for non-R2 URL",
-			imageURL:     func(t *testing.T) string { return "/uploads/products/nonexistent.jpg" },
-			r2BucketName: "test-bucket",
-			wantErr:      true,
-			wantErrMsg:   apierr.ErrImageNotFound,
+			name:     "returns error when the record can't be deleted",
+			imageURL: "/uploads/products/a_full.webp",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "products/a_full.webp").Return(errors.New("db error"))
+			},
+			wantErr:    true,
+			wantErrMsg: "db error",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.r2BucketName != "" {
-				cfg.R2BucketName = tt.r2BucketName
-				cfg.R2PublicURL = "https://pub-test.r2.dev"
-				defer func() {
-					cfg.R2BucketName = ""
-					cfg.R2PublicURL = ""
-				}()
-
-				deleteErr := tt.r2DeleteErr
-				old := r2DeleteFunc
-				r2DeleteFunc = func(key string) error {
-					return deleteErr
-				}
-				defer func() { r2DeleteFunc = old }()
+			backend := useMemoryStorage(t)
+			for _, key := range []string{"products/a_thumb.webp", "products/a_medium.webp", "products/a_full.webp"} {
+				backend.Put(context.Background(), key, "image/jpeg", []byte("x"))
+			}
+			backend.Put(context.Background(), "logos/a.png", "image/png", []byte("x"))
+			m := mock_store.NewMockUploadedObjectStore(gomock.NewController(t))
+			if tt.mockSetup != nil {
+				tt.mockSetup(m)
 			}
+			uploadedObjectStore = m
 
 			var p pservice
-			gotErr := p.DeleteProductImage(context.Background(), tt.imageURL(t))
+			gotErr := p.DeleteProductImage(context.Background(), tt.imageURL)
 
 			if gotErr != nil {
 				if !tt.wantErr {
@@ -937,6 +1025,9 @@ func Test_pservice_DeleteProductImage(t *testing.T) {
 			if tt.wantErr {
 				t.Fatal("DeleteProductImage() succeeded unexpectedly")
 			}
+			if keys := backend.Keys(); len(keys) != 1 || keys[0] != "logos/a.png" {
+				t.Errorf("stored = %v, want the variants deleted", keys)
+			}
 		})
 	}
 }
@@ -957,14 +1048,14 @@ func Test_pservice_GetPurchaseListProducts(t *testing.T) {
 				mock.EXPECT().
 					GetProductsListByActiveOrders(gomock.Any(), 10).
 					Return([]model.PurchaseProduct{
-						{ProductName: "Product A", Price: 1000, Qty: 5},
-						{ProductName: "Product B", Price: 2000, Qty: 3},
+						{ProductID: 1, ProductName: "Product A", Price: 1000, Qty: 5, Status: constant.PurchaseStatusBought, BoughtQty: 4, ActualCost: 3800},
+						{ProductID: 2, ProductName: "Product B", Price: 2000, Qty: 3, Status: constant.PurchaseStatusPending},
 					}, nil)
 				return mock
 			},
 			want: []response.PurchaseListProductData{
-				{ProductName: "Product A", Price: 1000, Qty: 5},
-				{ProductName: "Product B", Price: 2000, Qty: 3},
+				{ProductID: 1, ProductName: "Product A", Price: 1000, Qty: 5, Status: constant.PurchaseStatusBought, BoughtQty: 4, ActualCost: 3800},
+				{ProductID: 2, ProductName: "Product B", Price: 2000, Qty: 3, Status: constant.PurchaseStatusPending},
 			},
 			wantErr: false,
 		},
@@ -1000,9 +1091,10 @@ func Test_pservice_GetPurchaseListProducts(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldStore := productStore
-			defer func() { productStore = oldStore }()
+			oldStore, oldImageStore := productStore, productImageStore
+			defer func() { productStore, productImageStore = oldStore, oldImageStore }()
 			productStore = tt.mockSetup(ctrl)
+			productImageStore = emptyProductImageStore(ctrl)
 
 			var p pservice
 			got, gotErr := p.GetPurchaseListProducts(context.Background(), tt.shopID)
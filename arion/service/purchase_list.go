@@ -0,0 +1,563 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+type (
+	PurchaseListService interface {
+		UpdatePurchaseListItem(ctx context.Context, input UpdatePurchaseListItemInput) (response.PurchaseListItemData, error)
+		ResetPurchaseList(ctx context.Context, shopID int) error
+		AllocateShortage(ctx context.Context, input AllocateShortageInput) (response.ShortageAllocationData, error)
+		AllocatePurchaseList(ctx context.Context, input AllocatePurchaseListInput) (response.StockAllocationData, error)
+	}
+
+	plservice struct{}
+
+	UpdatePurchaseListItemInput struct {
+		ShopID     int
+		ProductID  int
+		Status     string
+		BoughtQty  int
+		ActualCost int
+		Notes      string
+	}
+
+	AllocateShortageInput struct {
+		ShopID    int
+		ProductID int
+		Policy    string
+		Preview   bool
+	}
+
+	AllocatePurchaseListInput struct {
+		ShopID  int
+		Policy  string
+		Preview bool
+	}
+
+	// productAllocation is the worked out allocation of one product: its open
+	// order items and how many units each of them loses.
+	productAllocation struct {
+		items []model.ProductOrderItem
+		cuts  []int
+		res   response.ShortageAllocationData
+	}
+
+	// orderShortage is what a shortage takes off one order in total, as an
+	// order can hold the same product on more than one item.
+	orderShortage struct {
+		orderID int
+		amount  int
+	}
+)
+
+func NewPurchaseListService() PurchaseListService {
+	if purchaseListStore == nil {
+		purchaseListStore = store.NewPurchaseListStore()
+	}
+
+	if productStore == nil {
+		productStore = store.NewProductStore()
+	}
+
+	if orderStore == nil {
+		orderStore = store.NewOrderStore()
+	}
+
+	if orderItemStore == nil {
+		orderItemStore = store.NewOrderItemStore()
+	}
+
+	if orderPaymentStore == nil {
+		orderPaymentStore = store.NewOrderPaymentStore()
+	}
+
+	if customerCreditStore == nil {
+		customerCreditStore = store.NewCustomerCreditStore()
+	}
+
+	if shopStore == nil {
+		shopStore = store.NewShopStore()
+	}
+
+	return &plservice{}
+}
+
+// UpdatePurchaseListItem ticks a product off the purchase list. An unavailable
+// product was not bought at all, so its quantity and cost are cleared.
+func (p *plservice) UpdatePurchaseListItem(ctx context.Context, input UpdatePurchaseListItemInput) (response.PurchaseListItemData, error) {
+	product, err := productStore.GetProductByID(ctx, input.ProductID, input.ShopID)
+	if err != nil {
+		return response.PurchaseListItemData{}, err
+	}
+
+	if product == nil {
+		return response.PurchaseListItemData{}, errors.New(apierr.ErrProductNotFound)
+	}
+
+	if input.Status == constant.PurchaseStatusUnavailable {
+		input.BoughtQty = 0
+		input.ActualCost = 0
+	}
+
+	item, err := purchaseListStore.UpsertPurchaseListItem(ctx, nil, store.UpsertPurchaseListItemInput{
+		ShopID:     input.ShopID,
+		ProductID:  input.ProductID,
+		Status:     input.Status,
+		BoughtQty:  input.BoughtQty,
+		ActualCost: input.ActualCost,
+		Notes:      input.Notes,
+	})
+	if err != nil {
+		return response.PurchaseListItemData{}, err
+	}
+
+	res := response.PurchaseListItemData{
+		ProductID:  item.ProductID,
+		Status:     item.Status,
+		BoughtQty:  item.BoughtQty,
+		ActualCost: item.ActualCost,
+		Notes:      item.Notes,
+		CreatedAt:  item.CreatedAt,
+	}
+
+	if item.UpdatedAt.Valid {
+		t := item.UpdatedAt.Time
+		res.UpdatedAt = &t
+	}
+
+	return res, nil
+}
+
+func (p *plservice) ResetPurchaseList(ctx context.Context, shopID int) error {
+	return purchaseListStore.ResetPurchaseList(ctx, shopID)
+}
+
+// AllocateShortage hands the bought units of a product out over the open
+// orders holding it. Every item is settled at what it was allocated; cut units
+// come off the order total, anything already paid beyond the new total goes to
+// the customer's credit, and every order that lost units is flagged so the
+// shop knows to tell the customer. A preview works all of this out without
+// writing it.
+func (p *plservice) AllocateShortage(ctx context.Context, input AllocateShortageInput) (response.ShortageAllocationData, error) {
+	if !validShortagePolicy(input.Policy) {
+		return response.ShortageAllocationData{}, errors.New(apierr.ErrShortagePolicyInvalid)
+	}
+
+	// The purchase list row and the order items are read inside the
+	// transaction and stay locked until it ends.
+	var tx database.Tx
+	if !input.Preview {
+		db := dbGetter()
+		var err error
+		tx, err = db.Begin()
+		if err != nil {
+			return response.ShortageAllocationData{}, err
+		}
+		defer tx.Rollback()
+	}
+
+	item, err := purchaseListStore.GetPurchaseListItem(ctx, tx, input.ShopID, input.ProductID)
+	if err != nil {
+		return response.ShortageAllocationData{}, err
+	}
+
+	if item == nil || item.Status == constant.PurchaseStatusPending {
+		return response.ShortageAllocationData{}, errors.New(apierr.ErrPurchaseNotRecorded)
+	}
+
+	plan, err := planAllocation(ctx, tx, *item, input.Policy)
+	if err != nil {
+		return response.ShortageAllocationData{}, err
+	}
+
+	if plan.res.Shortage == 0 {
+		return response.ShortageAllocationData{}, errors.New(apierr.ErrNoShortage)
+	}
+
+	plans := []productAllocation{plan}
+	if err := runAllocation(ctx, tx, plans, input.Preview); err != nil {
+		return response.ShortageAllocationData{}, err
+	}
+
+	if !input.Preview {
+		if err := tx.Commit(); err != nil {
+			return response.ShortageAllocationData{}, err
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"shop_id":    input.ShopID,
+		"product_id": input.ProductID,
+		"policy":     input.Policy,
+		"preview":    input.Preview,
+		"shortage":   plans[0].res.Shortage,
+	}).Info("purchase list shortage allocated")
+
+	return plans[0].res, nil
+}
+
+// AllocatePurchaseList runs the allocation for every product ticked off the
+// purchase list in one go. Products bought in full are allocated as ordered.
+func (p *plservice) AllocatePurchaseList(ctx context.Context, input AllocatePurchaseListInput) (response.StockAllocationData, error) {
+	if !validShortagePolicy(input.Policy) {
+		return response.StockAllocationData{}, errors.New(apierr.ErrShortagePolicyInvalid)
+	}
+
+	var tx database.Tx
+	if !input.Preview {
+		db := dbGetter()
+		var err error
+		tx, err = db.Begin()
+		if err != nil {
+			return response.StockAllocationData{}, err
+		}
+		defer tx.Rollback()
+	}
+
+	items, err := purchaseListStore.GetPurchaseListItemsByShopID(ctx, tx, input.ShopID)
+	if err != nil {
+		return response.StockAllocationData{}, err
+	}
+
+	plans := []productAllocation{}
+	for _, item := range items {
+		if item.Status == constant.PurchaseStatusPending {
+			continue
+		}
+
+		plan, err := planAllocation(ctx, tx, item, input.Policy)
+		if err != nil {
+			return response.StockAllocationData{}, err
+		}
+
+		if len(plan.items) > 0 {
+			plans = append(plans, plan)
+		}
+	}
+
+	if len(plans) == 0 {
+		return response.StockAllocationData{}, errors.New(apierr.ErrPurchaseNotRecorded)
+	}
+
+	if err := runAllocation(ctx, tx, plans, input.Preview); err != nil {
+		return response.StockAllocationData{}, err
+	}
+
+	if !input.Preview {
+		if err := tx.Commit(); err != nil {
+			return response.StockAllocationData{}, err
+		}
+	}
+
+	res := response.StockAllocationData{
+		Policy:   input.Policy,
+		Preview:  input.Preview,
+		Products: make([]response.ShortageAllocationData, 0, len(plans)),
+	}
+	for _, plan := range plans {
+		res.Products = append(res.Products, plan.res)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"shop_id":  input.ShopID,
+		"policy":   input.Policy,
+		"preview":  input.Preview,
+		"products": len(plans),
+	}).Info("purchase list allocated")
+
+	return res, nil
+}
+
+func validShortagePolicy(policy string) bool {
+	switch policy {
+	case constant.ShortagePolicyFirstCome, constant.ShortagePolicyProRata, constant.ShortagePolicyPriority, constant.ShortagePolicyPaidFirst:
+		return true
+	}
+	return false
+}
+
+// planAllocation works out how the bought units of one purchase list product
+// are handed out over its open order items, which are read within tx.
+func planAllocation(ctx context.Context, tx database.Tx, item model.PurchaseListItem, policy string) (productAllocation, error) {
+	available := 0
+	if item.Status == constant.PurchaseStatusBought {
+		available = item.BoughtQty
+	}
+
+	items, err := orderItemStore.GetOpenOrderItemsByProductID(ctx, tx, item.ShopID, item.ProductID)
+	if err != nil {
+		return productAllocation{}, err
+	}
+
+	ordered := 0
+	for _, it := range items {
+		ordered += it.Qty
+	}
+
+	plan := productAllocation{
+		items: items,
+		cuts:  make([]int, len(items)),
+		res: response.ShortageAllocationData{
+			ProductID: item.ProductID,
+			Policy:    policy,
+			Ordered:   ordered,
+			Available: available,
+			Orders:    []response.ShortageOrderData{},
+		},
+	}
+
+	if available < ordered {
+		plan.res.Shortage = ordered - available
+		plan.cuts = shortageCuts(items, available, policy)
+	}
+
+	return plan, nil
+}
+
+// runAllocation settles the planned order items and lowers the totals of the
+// orders that lost units within tx, which the caller commits. An order losing
+// units of several products is settled once. With preview nothing is
+// written, but the resulting totals and credits are still filled in.
+func runAllocation(ctx context.Context, tx database.Tx, plans []productAllocation, preview bool) error {
+	var orders []orderShortage
+	seen := map[int]int{}
+	for p := range plans {
+		plan := &plans[p]
+		plan.res.Preview = preview
+		for i, it := range plan.items {
+			if !preview {
+				if err := orderItemStore.AllocateOrderItem(ctx, tx, it.ID, plan.cuts[i]); err != nil {
+					return err
+				}
+			}
+
+			if plan.cuts[i] == 0 {
+				continue
+			}
+
+			idx, ok := seen[it.OrderID]
+			if !ok {
+				idx = len(orders)
+				seen[it.OrderID] = idx
+				orders = append(orders, orderShortage{orderID: it.OrderID})
+			}
+			orders[idx].amount += it.Price * plan.cuts[i]
+
+			plan.res.Orders = append(plan.res.Orders, response.ShortageOrderData{
+				OrderID:      it.OrderID,
+				CustomerName: it.CustomerName,
+				OrderItemID:  it.ID,
+				Qty:          it.Qty - plan.cuts[i],
+				Cut:          plan.cuts[i],
+			})
+		}
+	}
+
+	totals := map[int]int{}
+	credits := map[int]int{}
+	for _, short := range orders {
+		total, credit, err := applyOrderShortage(ctx, tx, short, preview)
+		if err != nil {
+			return err
+		}
+		totals[short.orderID] = total
+		credits[short.orderID] = credit
+	}
+
+	for _, plan := range plans {
+		for i := range plan.res.Orders {
+			plan.res.Orders[i].TotalPrice = totals[plan.res.Orders[i].OrderID]
+			plan.res.Orders[i].CreditAmount = credits[plan.res.Orders[i].OrderID]
+		}
+	}
+
+	return nil
+}
+
+// applyOrderShortage lowers the order total by the shortage, carries any
+// resulting overpayment into customer credit and flags the order for a
+// shortage notification. It returns the new total and the credited amount;
+// with preview they are only worked out.
+func applyOrderShortage(ctx context.Context, tx database.Tx, short orderShortage, preview bool) (int, int, error) {
+	// Locked so that a payment booked meanwhile can't change what is credited.
+	// A preview runs without a transaction and only reads.
+	var order *model.Order
+	var err error
+	if preview {
+		order, err = orderStore.GetOrderByID(ctx, short.orderID)
+	} else {
+		order, err = orderStore.GetOrderByIDForUpdate(ctx, tx, short.orderID)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if order == nil {
+		return 0, 0, errors.New(apierr.ErrOrderNotFound)
+	}
+
+	paid, err := sumOrderPayments(ctx, tx, order.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	order.TotalPrice -= short.amount
+	if order.TotalPrice < 0 {
+		order.TotalPrice = 0
+	}
+
+	excess := paid - (order.TotalPrice + order.UniqueCode)
+	if excess < 0 {
+		excess = 0
+	}
+
+	if preview {
+		return order.TotalPrice, excess, nil
+	}
+
+	if excess > 0 {
+		// Taking the ledger lock first serialises this with other credit bookings of the customer.
+		if _, err := customerCreditStore.GetCustomerCreditBalance(ctx, tx, order.CustomerID); err != nil {
+			return 0, 0, err
+		}
+
+		payment, err := orderPaymentStore.CreateOrderPayment(ctx, tx, order.ID, -excess)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		_, err = customerCreditStore.CreateCustomerCredit(ctx, tx, store.CreateCustomerCreditInput{
+			ShopID:     order.ShopID,
+			CustomerID: order.CustomerID,
+			OrderID:    &order.ID,
+			PaymentID:  &payment.ID,
+			Type:       constant.CustomerCreditTypeOverpayment,
+			Amount:     excess,
+			Notes:      "purchase list shortage",
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		paid -= excess
+	}
+
+	paymentStatus := downPaymentStatus(order, paid)
+	notify := true
+	_, err = orderStore.UpdateOrder(ctx, tx, order.ID, store.UpdateOrderInput{
+		TotalPrice:     &order.TotalPrice,
+		PaymentStatus:  &paymentStatus,
+		ShortageNotify: &notify,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := settleUniqueCode(ctx, tx, order, paid); err != nil {
+		return 0, 0, err
+	}
+
+	return order.TotalPrice, excess, nil
+}
+
+// shortageCuts returns, per order item, how many units it loses when only
+// available units can be handed out. Items come oldest order first. pro_rata
+// gives every order the same share of what is available, with leftover units
+// going to the largest remainders and then the oldest orders; the other
+// policies fill orders one by one: first_come by age, priority by customer
+// priority and paid_first by how much of the order is paid.
+func shortageCuts(items []model.ProductOrderItem, available int, policy string) []int {
+	if policy == constant.ShortagePolicyProRata {
+		return proRataCuts(items, available)
+	}
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	// stable sorts keep orders that rank the same oldest first
+	switch policy {
+	case constant.ShortagePolicyPriority:
+		sort.SliceStable(order, func(a, b int) bool {
+			return items[order[a]].CustomerPriority > items[order[b]].CustomerPriority
+		})
+	case constant.ShortagePolicyPaidFirst:
+		sort.SliceStable(order, func(a, b int) bool {
+			return paymentRank(items[order[a]].OrderPaymentStatus) < paymentRank(items[order[b]].OrderPaymentStatus)
+		})
+	}
+
+	cuts := make([]int, len(items))
+	for _, i := range order {
+		give := items[i].Qty
+		if give > available {
+			give = available
+		}
+		available -= give
+		cuts[i] = items[i].Qty - give
+	}
+	return cuts
+}
+
+// paymentRank orders payment statuses for the paid_first policy.
+func paymentRank(status string) int {
+	switch status {
+	case constant.OrderPaymentStatusPaid:
+		return 0
+	case constant.OrderPaymentStatusDPPaid:
+		return 1
+	}
+	return 2
+}
+
+func proRataCuts(items []model.ProductOrderItem, available int) []int {
+	ordered := 0
+	for _, it := range items {
+		ordered += it.Qty
+	}
+
+	gives := make([]int, len(items))
+	rems := make([]int, len(items))
+	left := available
+	for i, it := range items {
+		gives[i] = it.Qty * available / ordered
+		rems[i] = it.Qty * available % ordered
+		left -= gives[i]
+	}
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	// items are oldest first, so a stable sort keeps ties with the oldest order
+	sort.SliceStable(order, func(a, b int) bool {
+		return rems[order[a]] > rems[order[b]]
+	})
+	for _, i := range order {
+		if left == 0 {
+			break
+		}
+		if gives[i] < items[i].Qty {
+			gives[i]++
+			left--
+		}
+	}
+
+	cuts := make([]int, len(items))
+	for i, it := range items {
+		cuts[i] = it.Qty - gives[i]
+	}
+	return cuts
+}
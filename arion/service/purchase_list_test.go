@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+func Test_plservice_UpdatePurchaseListItem(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     UpdatePurchaseListItemInput
+		mockSetup func(product *mock_store.MockProductStore, purchaseList *mock_store.MockPurchaseListStore)
+		want      response.PurchaseListItemData
+		wantErr   string
+	}{
+		{
+			name:  "records bought quantity and cost",
+			input: UpdatePurchaseListItemInput{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000},
+			mockSetup: func(product *mock_store.MockProductStore, purchaseList *mock_store.MockPurchaseListStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 5, 10).Return(&model.Product{ID: 5}, nil)
+				purchaseList.EXPECT().UpsertPurchaseListItem(gomock.Any(), nil, store.UpsertPurchaseListItemInput{
+					ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000,
+				}).Return(&model.PurchaseListItem{ID: 1, ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000, CreatedAt: fixedTime}, nil)
+			},
+			want: response.PurchaseListItemData{ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000, CreatedAt: fixedTime},
+		},
+		{
+			name:  "unavailable product clears quantity and cost",
+			input: UpdatePurchaseListItemInput{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusUnavailable, BoughtQty: 3, ActualCost: 9000, Notes: "sold out"},
+			mockSetup: func(product *mock_store.MockProductStore, purchaseList *mock_store.MockPurchaseListStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 5, 10).Return(&model.Product{ID: 5}, nil)
+				purchaseList.EXPECT().UpsertPurchaseListItem(gomock.Any(), nil, store.UpsertPurchaseListItemInput{
+					ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusUnavailable, Notes: "sold out",
+				}).Return(&model.PurchaseListItem{ID: 1, ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusUnavailable, Notes: "sold out", CreatedAt: fixedTime}, nil)
+			},
+			want: response.PurchaseListItemData{ProductID: 5, Status: constant.PurchaseStatusUnavailable, Notes: "sold out", CreatedAt: fixedTime},
+		},
+		{
+			name:  "product of another shop is not found",
+			input: UpdatePurchaseListItemInput{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought},
+			mockSetup: func(product *mock_store.MockProductStore, purchaseList *mock_store.MockPurchaseListStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 5, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrProductNotFound,
+		},
+		{
+			name:  "store error is returned",
+			input: UpdatePurchaseListItemInput{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought},
+			mockSetup: func(product *mock_store.MockProductStore, purchaseList *mock_store.MockPurchaseListStore) {
+				product.EXPECT().GetProductByID(gomock.Any(), 5, 10).Return(&model.Product{ID: 5}, nil)
+				purchaseList.EXPECT().UpsertPurchaseListItem(gomock.Any(), nil, gomock.Any()).Return(nil, errors.New("database error"))
+			},
+			wantErr: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldProductStore, oldPurchaseListStore := productStore, purchaseListStore
+			defer func() {
+				productStore, purchaseListStore = oldProductStore, oldPurchaseListStore
+			}()
+
+			mockProduct := mock_store.NewMockProductStore(ctrl)
+			mockPurchaseList := mock_store.NewMockPurchaseListStore(ctrl)
+			tt.mockSetup(mockProduct, mockPurchaseList)
+			productStore = mockProduct
+			purchaseListStore = mockPurchaseList
+
+			var p plservice
+			got, gotErr := p.UpdatePurchaseListItem(context.Background(), tt.input)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("UpdatePurchaseListItem() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("UpdatePurchaseListItem() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdatePurchaseListItem() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_plservice_AllocateShortage(t *testing.T) {
+	items := []model.ProductOrderItem{
+		{ID: 3, OrderID: 7, CustomerName: "Ani", Price: 10000, Qty: 2},
+		{ID: 4, OrderID: 9, CustomerName: "Budi", Price: 10000, Qty: 2},
+	}
+	orderID := 9
+	paymentID := 2
+
+	tests := []struct {
+		name      string
+		policy    string
+		preview   bool
+		item      *model.PurchaseListItem
+		mockSetup func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore)
+		withTx    bool
+		want      response.ShortageAllocationData
+		wantErr   string
+	}{
+		{
+			name:   "first come cuts the newest order and credits its overpayment",
+			policy: constant.ShortagePolicyFirstCome,
+			item:   &model.PurchaseListItem{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 3},
+			withTx: true,
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore) {
+				orderItem.EXPECT().GetOpenOrderItemsByProductID(gomock.Any(), tx, 10, 5).Return(items, nil)
+				orderItem.EXPECT().AllocateOrderItem(gomock.Any(), tx, 3, 0).Return(nil)
+				orderItem.EXPECT().AllocateOrderItem(gomock.Any(), tx, 4, 1).Return(nil)
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 9).Return(&model.Order{ID: 9, ShopID: 10, CustomerID: 6, TotalPrice: 20000}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 9).Return([]model.OrderPayment{{Amount: 20000}}, nil)
+				credit.EXPECT().GetCustomerCreditBalance(gomock.Any(), tx, 6).Return(0, nil)
+				payment.EXPECT().CreateOrderPayment(gomock.Any(), tx, 9, -10000).Return(&model.OrderPayment{ID: 2}, nil)
+				credit.EXPECT().CreateCustomerCredit(gomock.Any(), tx, store.CreateCustomerCreditInput{
+					ShopID: 10, CustomerID: 6, OrderID: &orderID, PaymentID: &paymentID, Type: constant.CustomerCreditTypeOverpayment, Amount: 10000, Notes: "purchase list shortage",
+				}).Return(&model.CustomerCredit{ID: 1}, nil)
+				total, status, notify := 10000, constant.OrderPaymentStatusPaid, true
+				order.EXPECT().UpdateOrder(gomock.Any(), tx, 9, store.UpdateOrderInput{TotalPrice: &total, PaymentStatus: &status, ShortageNotify: &notify}).
+					Return(&model.Order{ID: 9}, nil)
+				tx.EXPECT().Commit().Return(nil)
+			},
+			want: response.ShortageAllocationData{
+				ProductID: 5, Policy: constant.ShortagePolicyFirstCome, Ordered: 4, Available: 3, Shortage: 1,
+				Orders: []response.ShortageOrderData{
+					{OrderID: 9, CustomerName: "Budi", OrderItemID: 4, Qty: 1, Cut: 1, TotalPrice: 10000, CreditAmount: 10000},
+				},
+			},
+		},
+		{
+			name:    "preview works out totals without writing",
+			policy:  constant.ShortagePolicyFirstCome,
+			preview: true,
+			item:    &model.PurchaseListItem{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 3},
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore) {
+				orderItem.EXPECT().GetOpenOrderItemsByProductID(gomock.Any(), nil, 10, 5).Return(items, nil)
+				order.EXPECT().GetOrderByID(gomock.Any(), 9).Return(&model.Order{ID: 9, ShopID: 10, CustomerID: 6, TotalPrice: 20000}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), nil, 9).Return([]model.OrderPayment{{Amount: 20000}}, nil)
+			},
+			want: response.ShortageAllocationData{
+				ProductID: 5, Policy: constant.ShortagePolicyFirstCome, Preview: true, Ordered: 4, Available: 3, Shortage: 1,
+				Orders: []response.ShortageOrderData{
+					{OrderID: 9, CustomerName: "Budi", OrderItemID: 4, Qty: 1, Cut: 1, TotalPrice: 10000, CreditAmount: 10000},
+				},
+			},
+		},
+		{
+			name:   "store error rolls back",
+			policy: constant.ShortagePolicyProRata,
+			item:   &model.PurchaseListItem{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusUnavailable},
+			withTx: true,
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore) {
+				orderItem.EXPECT().GetOpenOrderItemsByProductID(gomock.Any(), tx, 10, 5).Return(items, nil)
+				orderItem.EXPECT().AllocateOrderItem(gomock.Any(), tx, 3, 2).Return(errors.New("database error"))
+			},
+			wantErr: "database error",
+		},
+		{
+			name:   "bought quantity covers all orders",
+			policy: constant.ShortagePolicyFirstCome,
+			item:   &model.PurchaseListItem{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 4},
+			withTx: true,
+			mockSetup: func(tx *mock_database.MockTx, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore, credit *mock_store.MockCustomerCreditStore) {
+				orderItem.EXPECT().GetOpenOrderItemsByProductID(gomock.Any(), tx, 10, 5).Return(items, nil)
+			},
+			wantErr: apierr.ErrNoShortage,
+		},
+		{
+			name:    "pending product has nothing to allocate",
+			policy:  constant.ShortagePolicyFirstCome,
+			item:    &model.PurchaseListItem{Status: constant.PurchaseStatusPending},
+			withTx:  true,
+			wantErr: apierr.ErrPurchaseNotRecorded,
+		},
+		{
+			name:    "product never ticked off",
+			policy:  constant.ShortagePolicyFirstCome,
+			withTx:  true,
+			wantErr: apierr.ErrPurchaseNotRecorded,
+		},
+		{
+			name:    "unknown policy",
+			policy:  "random",
+			wantErr: apierr.ErrShortagePolicyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldPurchaseListStore, oldOrderStore, oldOrderItemStore, oldPaymentStore, oldCreditStore, oldDBGetter := purchaseListStore, orderStore, orderItemStore, orderPaymentStore, customerCreditStore, dbGetter
+			defer func() {
+				purchaseListStore, orderStore, orderItemStore, orderPaymentStore, customerCreditStore, dbGetter = oldPurchaseListStore, oldOrderStore, oldOrderItemStore, oldPaymentStore, oldCreditStore, oldDBGetter
+			}()
+
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			mockOrderItem := mock_store.NewMockOrderItemStore(ctrl)
+			mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
+			mockCredit := mock_store.NewMockCustomerCreditStore(ctrl)
+			mockTx := mock_database.NewMockTx(ctrl)
+			mockDB := mock_database.NewMockDB(ctrl)
+			// The purchase list row is read, and locked, inside the transaction.
+			var tx database.Tx
+			if tt.withTx {
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				tx = mockTx
+			}
+			mockPurchaseList := mock_store.NewMockPurchaseListStore(ctrl)
+			if tt.wantErr != apierr.ErrShortagePolicyInvalid {
+				mockPurchaseList.EXPECT().GetPurchaseListItem(gomock.Any(), tx, 10, 5).Return(tt.item, nil)
+			}
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockTx, mockOrder, mockOrderItem, mockPayment, mockCredit)
+			}
+
+			purchaseListStore = mockPurchaseList
+			orderStore = mockOrder
+			orderItemStore = mockOrderItem
+			orderPaymentStore = mockPayment
+			customerCreditStore = mockCredit
+			dbGetter = func() database.DB { return mockDB }
+
+			var p plservice
+			got, gotErr := p.AllocateShortage(context.Background(), AllocateShortageInput{ShopID: 10, ProductID: 5, Policy: tt.policy, Preview: tt.preview})
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("AllocateShortage() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("AllocateShortage() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AllocateShortage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_plservice_AllocatePurchaseList(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		preview   bool
+		mockSetup func(tx *mock_database.MockTx, purchaseList *mock_store.MockPurchaseListStore, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore)
+		withTx    bool
+		want      response.StockAllocationData
+		wantErr   string
+	}{
+		{
+			name:   "allocates every ticked off product and settles each order once",
+			policy: constant.ShortagePolicyFirstCome,
+			withTx: true,
+			mockSetup: func(tx *mock_database.MockTx, purchaseList *mock_store.MockPurchaseListStore, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore) {
+				purchaseList.EXPECT().GetPurchaseListItemsByShopID(gomock.Any(), tx, 10).Return([]model.PurchaseListItem{
+					{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 2},
+					{ShopID: 10, ProductID: 6, Status: constant.PurchaseStatusUnavailable},
+					{ShopID: 10, ProductID: 7, Status: constant.PurchaseStatusPending},
+				}, nil)
+				orderItem.EXPECT().GetOpenOrderItemsByProductID(gomock.Any(), tx, 10, 5).Return([]model.ProductOrderItem{
+					{ID: 3, OrderID: 7, CustomerName: "Ani", Price: 10000, Qty: 2},
+				}, nil)
+				orderItem.EXPECT().GetOpenOrderItemsByProductID(gomock.Any(), tx, 10, 6).Return([]model.ProductOrderItem{
+					{ID: 4, OrderID: 7, CustomerName: "Ani", Price: 5000, Qty: 1},
+				}, nil)
+				orderItem.EXPECT().AllocateOrderItem(gomock.Any(), tx, 3, 0).Return(nil)
+				orderItem.EXPECT().AllocateOrderItem(gomock.Any(), tx, 4, 1).Return(nil)
+				order.EXPECT().GetOrderByIDForUpdate(gomock.Any(), tx, 7).Return(&model.Order{ID: 7, ShopID: 10, CustomerID: 6, TotalPrice: 25000}, nil)
+				payment.EXPECT().GetOrderPaymentsByOrderID(gomock.Any(), tx, 7).Return([]model.OrderPayment{}, nil)
+				total, status, notify := 20000, constant.OrderPaymentStatusOutstanding, true
+				order.EXPECT().UpdateOrder(gomock.Any(), tx, 7, store.UpdateOrderInput{TotalPrice: &total, PaymentStatus: &status, ShortageNotify: &notify}).
+					Return(&model.Order{ID: 7}, nil)
+				tx.EXPECT().Commit().Return(nil)
+			},
+			want: response.StockAllocationData{
+				Policy: constant.ShortagePolicyFirstCome,
+				Products: []response.ShortageAllocationData{
+					{ProductID: 5, Policy: constant.ShortagePolicyFirstCome, Ordered: 2, Available: 2, Orders: []response.ShortageOrderData{}},
+					{ProductID: 6, Policy: constant.ShortagePolicyFirstCome, Ordered: 1, Shortage: 1, Orders: []response.ShortageOrderData{
+						{OrderID: 7, CustomerName: "Ani", OrderItemID: 4, Cut: 1, TotalPrice: 20000},
+					}},
+				},
+			},
+		},
+		{
+			name:    "preview writes nothing",
+			policy:  constant.ShortagePolicyPriority,
+			preview: true,
+			mockSetup: func(tx *mock_database.MockTx, purchaseList *mock_store.MockPurchaseListStore, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore) {
+				purchaseList.EXPECT().GetPurchaseListItemsByShopID(gomock.Any(), nil, 10).Return([]model.PurchaseListItem{
+					{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 4},
+				}, nil)
+				orderItem.EXPECT().GetOpenOrderItemsByProductID(gomock.Any(), nil, 10, 5).Return([]model.ProductOrderItem{
+					{ID: 3, OrderID: 7, CustomerName: "Ani", Price: 10000, Qty: 2},
+				}, nil)
+			},
+			want: response.StockAllocationData{
+				Policy:  constant.ShortagePolicyPriority,
+				Preview: true,
+				Products: []response.ShortageAllocationData{
+					{ProductID: 5, Policy: constant.ShortagePolicyPriority, Preview: true, Ordered: 2, Available: 4, Orders: []response.ShortageOrderData{}},
+				},
+			},
+		},
+		{
+			name:   "nothing ticked off",
+			policy: constant.ShortagePolicyFirstCome,
+			withTx: true,
+			mockSetup: func(tx *mock_database.MockTx, purchaseList *mock_store.MockPurchaseListStore, order *mock_store.MockOrderStore, orderItem *mock_store.MockOrderItemStore, payment *mock_store.MockOrderPaymentStore) {
+				purchaseList.EXPECT().GetPurchaseListItemsByShopID(gomock.Any(), tx, 10).Return([]model.PurchaseListItem{}, nil)
+			},
+			wantErr: apierr.ErrPurchaseNotRecorded,
+		},
+		{
+			name:    "unknown policy",
+			policy:  "random",
+			wantErr: apierr.ErrShortagePolicyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldPurchaseListStore, oldOrderStore, oldOrderItemStore, oldPaymentStore, oldDBGetter := purchaseListStore, orderStore, orderItemStore, orderPaymentStore, dbGetter
+			defer func() {
+				purchaseListStore, orderStore, orderItemStore, orderPaymentStore, dbGetter = oldPurchaseListStore, oldOrderStore, oldOrderItemStore, oldPaymentStore, oldDBGetter
+			}()
+
+			mockPurchaseList := mock_store.NewMockPurchaseListStore(ctrl)
+			mockOrder := mock_store.NewMockOrderStore(ctrl)
+			mockOrderItem := mock_store.NewMockOrderItemStore(ctrl)
+			mockPayment := mock_store.NewMockOrderPaymentStore(ctrl)
+			mockTx := mock_database.NewMockTx(ctrl)
+			mockDB := mock_database.NewMockDB(ctrl)
+			if tt.withTx {
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+			}
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockTx, mockPurchaseList, mockOrder, mockOrderItem, mockPayment)
+			}
+
+			purchaseListStore = mockPurchaseList
+			orderStore = mockOrder
+			orderItemStore = mockOrderItem
+			orderPaymentStore = mockPayment
+			dbGetter = func() database.DB { return mockDB }
+
+			var p plservice
+			got, gotErr := p.AllocatePurchaseList(context.Background(), AllocatePurchaseListInput{ShopID: 10, Policy: tt.policy, Preview: tt.preview})
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("AllocatePurchaseList() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("AllocatePurchaseList() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AllocatePurchaseList() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shortageCuts(t *testing.T) {
+	items := []model.ProductOrderItem{
+		{Qty: 5, OrderPaymentStatus: constant.OrderPaymentStatusOutstanding},
+		{Qty: 3, CustomerPriority: 2, OrderPaymentStatus: constant.OrderPaymentStatusDPPaid},
+		{Qty: 2, CustomerPriority: 1, OrderPaymentStatus: constant.OrderPaymentStatusPaid},
+	}
+
+	tests := []struct {
+		name      string
+		available int
+		policy    string
+		want      []int
+	}{
+		{name: "first come fills oldest orders", available: 6, policy: constant.ShortagePolicyFirstCome, want: []int{0, 2, 2}},
+		{name: "first come with nothing bought", available: 0, policy: constant.ShortagePolicyFirstCome, want: []int{5, 3, 2}},
+		{name: "pro rata splits evenly", available: 5, policy: constant.ShortagePolicyProRata, want: []int{2, 2, 1}},
+		// shares are 3.5, 2.1 and 1.4; the leftover unit goes to the largest remainder
+		{name: "pro rata leftover to largest remainder", available: 7, policy: constant.ShortagePolicyProRata, want: []int{1, 1, 1}},
+		// shares are 0.5, 0.3 and 0.2; the single unit goes to the oldest order
+		{name: "pro rata single unit", available: 1, policy: constant.ShortagePolicyProRata, want: []int{4, 3, 2}},
+		{name: "priority fills highest priority customers", available: 4, policy: constant.ShortagePolicyPriority, want: []int{5, 0, 1}},
+		{name: "paid first fills paid then down-paid orders", available: 4, policy: constant.ShortagePolicyPaidFirst, want: []int{5, 1, 0}},
+		{name: "paid first leaves the rest to unpaid orders", available: 7, policy: constant.ShortagePolicyPaidFirst, want: []int{3, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shortageCuts(items, tt.available, tt.policy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shortageCuts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
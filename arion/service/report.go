@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/i18n"
+	"github.com/zeirash/recapo/arion/common/xlsx"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+type (
+	ReportService interface {
+		ExportReport(ctx context.Context, input ExportReportInput, w io.Writer) error
+	}
+
+	rservice struct{}
+
+	ExportReportInput struct {
+		ShopID int
+		Report string
+		Format string
+		Filter model.OrderFilterOptions
+		Lang   string
+	}
+
+	// reportWriter is a CSV or XLSX sheet rows are written to.
+	reportWriter interface {
+		Write(record []interface{}) error
+		Close() error
+	}
+)
+
+func NewReportService() ReportService {
+	if reportStore == nil {
+		reportStore = store.NewReportStore()
+	}
+
+	if shopStore == nil {
+		shopStore = store.NewShopStore()
+	}
+
+	return &rservice{}
+}
+
+// reportColumns holds the i18n keys of each report's header row.
+var reportColumns = map[string][]string{
+	constant.ReportSalesByProduct:  {"report_product_id", "report_product", "report_orders", "report_qty", "report_sales", "report_cost", "report_margin"},
+	constant.ReportSalesByCustomer: {"report_customer_id", "report_customer", "report_phone", "report_orders", "report_total", "report_paid", "report_outstanding"},
+	constant.ReportDailyRevenue:    {"report_date", "report_payments", "report_received", "report_refunded", "report_net"},
+	constant.ReportReceivables:     {"report_order_id", "report_invoice_number", "report_date", "report_customer", "report_phone", "report_payment_status", "report_amount_due", "report_paid", "report_outstanding", "report_dp_due"},
+	constant.ReportPayments:        {"report_payment_id", "report_date", "report_order_id", "report_invoice_number", "report_customer", "report_type", "report_amount"},
+}
+
+// ExportReport writes the report as CSV or XLSX to w. Rows are written as
+// the store reads them. Input errors are returned before anything is written.
+// Dates, both in the filter and in the rows, are in the shop's timezone.
+func (s *rservice) ExportReport(ctx context.Context, input ExportReportInput, w io.Writer) error {
+	columns, ok := reportColumns[input.Report]
+	if !ok {
+		return errors.New(apierr.ErrReportInvalid)
+	}
+
+	var rw reportWriter
+	switch input.Format {
+	case constant.ReportFormatCSV:
+		rw = newCSVReportWriter(w)
+	case constant.ReportFormatXLSX:
+		xw, err := xlsx.NewWriter(w, input.Report)
+		if err != nil {
+			return err
+		}
+		rw = xw
+	default:
+		return errors.New(apierr.ErrReportFormatInvalid)
+	}
+
+	loc, err := shopLocation(ctx, input.ShopID)
+	if err != nil {
+		return err
+	}
+	filter := input.Filter
+	localDateRange(&filter, loc)
+
+	header := make([]interface{}, len(columns))
+	for i, key := range columns {
+		header[i] = i18n.T(input.Lang, key)
+	}
+	if err := rw.Write(header); err != nil {
+		return err
+	}
+
+	switch input.Report {
+	case constant.ReportSalesByProduct:
+		err = reportStore.StreamSalesByProduct(ctx, input.ShopID, filter, func(row model.ProductSalesRow) error {
+			return rw.Write([]interface{}{row.ProductID, row.ProductName, row.Orders, row.Qty, row.Sales, row.Cost, row.Sales - row.Cost})
+		})
+	case constant.ReportSalesByCustomer:
+		err = reportStore.StreamSalesByCustomer(ctx, input.ShopID, filter, func(row model.CustomerSalesRow) error {
+			return rw.Write([]interface{}{row.CustomerID, row.CustomerName, row.Phone, row.Orders, row.Total, row.Paid, row.Total - row.Paid})
+		})
+	case constant.ReportDailyRevenue:
+		err = reportStore.StreamDailyRevenue(ctx, input.ShopID, filter, func(row model.DailyRevenueRow) error {
+			return rw.Write([]interface{}{row.Date.Format("2006-01-02"), row.Payments, row.Received, row.Refunded, row.Received - row.Refunded})
+		})
+	case constant.ReportReceivables:
+		err = reportStore.StreamReceivables(ctx, input.ShopID, filter, func(row model.ReceivableRow) error {
+			dpDue := ""
+			if row.DPDueAt.Valid {
+				dpDue = row.DPDueAt.Time.In(loc).Format("2006-01-02")
+			}
+			return rw.Write([]interface{}{row.OrderID, row.InvoiceNumber.String, row.CreatedAt.In(loc).Format("2006-01-02"), row.CustomerName, row.Phone, row.PaymentStatus, row.AmountDue, row.Paid, row.AmountDue - row.Paid, dpDue})
+		})
+	case constant.ReportPayments:
+		err = reportStore.StreamPayments(ctx, input.ShopID, filter, func(row model.PaymentReportRow) error {
+			kind := i18n.T(input.Lang, "report_type_payment")
+			if row.Amount < 0 {
+				kind = i18n.T(input.Lang, "report_type_refund")
+			}
+			return rw.Write([]interface{}{row.ID, row.CreatedAt.In(loc).Format("2006-01-02 15:04"), row.OrderID, row.InvoiceNumber.String, row.CustomerName, kind, row.Amount})
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return rw.Close()
+}
+
+// csvReportWriter writes report rows as CSV. The file starts with a UTF-8
+// byte order mark, without which Excel reads customer names as Latin-1.
+type csvReportWriter struct {
+	w       io.Writer
+	cw      *csv.Writer
+	started bool
+}
+
+func newCSVReportWriter(w io.Writer) *csvReportWriter {
+	return &csvReportWriter{w: w, cw: csv.NewWriter(w)}
+}
+
+func (c *csvReportWriter) Write(record []interface{}) error {
+	if !c.started {
+		c.started = true
+		if _, err := io.WriteString(c.w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	fields := make([]string, len(record))
+	for i, value := range record {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			fields[i] = csvText(v)
+		default:
+			fields[i] = fmt.Sprint(v)
+		}
+	}
+	return c.cw.Write(fields)
+}
+
+func (c *csvReportWriter) Close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// csvText keeps spreadsheet apps from running text as a formula. Customer
+// names come from the public order form, so a name like "=HYPERLINK(...)"
+// is written with a leading quote.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_rservice_ExportReport(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := model.OrderFilterOptions{DateFrom: &dateFrom}
+	// start of 2024-01-01 in Asia/Jakarta
+	fromAt := time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     ExportReportInput
+		mockSetup func(m *mock_store.MockReportStore)
+		want      string   // exact CSV output
+		wantSheet []string // XLSX sheet fragments
+		wantErr   string
+	}{
+		{
+			name:  "sales by product as csv with margin",
+			input: ExportReportInput{ShopID: 1, Report: constant.ReportSalesByProduct, Format: constant.ReportFormatCSV, Filter: filter, Lang: "en"},
+			mockSetup: func(m *mock_store.MockReportStore) {
+				m.EXPECT().StreamSalesByProduct(gomock.Any(), 1, model.OrderFilterOptions{DateFrom: &fromAt}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, _ model.OrderFilterOptions, fn func(model.ProductSalesRow) error) error {
+						if err := fn(model.ProductSalesRow{ProductID: 1, ProductName: "Kaos, Polos", Orders: 3, Qty: 5, Sales: 50000, Cost: 40000}); err != nil {
+							return err
+						}
+						return fn(model.ProductSalesRow{ProductID: 2, ProductName: "=HYPERLINK(\"x\")", Orders: 1, Qty: 1, Sales: 5000, Cost: 4500})
+					})
+			},
+			want: "\ufeffProduct ID,Product,Orders,Qty,Sales,Cost,Margin\n" +
+				"1,\"Kaos, Polos\",3,5,50000,40000,10000\n" +
+				"2,\"'=HYPERLINK(\"\"x\"\")\",1,1,5000,4500,500\n",
+		},
+		{
+			name:  "sales by customer as csv",
+			input: ExportReportInput{ShopID: 1, Report: constant.ReportSalesByCustomer, Format: constant.ReportFormatCSV, Lang: "en"},
+			mockSetup: func(m *mock_store.MockReportStore) {
+				m.EXPECT().StreamSalesByCustomer(gomock.Any(), 1, model.OrderFilterOptions{}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, _ model.OrderFilterOptions, fn func(model.CustomerSalesRow) error) error {
+						return fn(model.CustomerSalesRow{CustomerID: 10, CustomerName: "John Doe", Phone: "08123", Orders: 2, Total: 30000, Paid: 25000})
+					})
+			},
+			want: "\ufeffCustomer ID,Customer,Phone,Orders,Total,Paid,Outstanding\n" +
+				"10,John Doe,08123,2,30000,25000,5000\n",
+		},
+		{
+			name:  "daily revenue in indonesian",
+			input: ExportReportInput{ShopID: 1, Report: constant.ReportDailyRevenue, Format: constant.ReportFormatCSV, Lang: "id"},
+			mockSetup: func(m *mock_store.MockReportStore) {
+				m.EXPECT().StreamDailyRevenue(gomock.Any(), 1, model.OrderFilterOptions{}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, _ model.OrderFilterOptions, fn func(model.DailyRevenueRow) error) error {
+						return fn(model.DailyRevenueRow{Date: day, Payments: 3, Received: 45000, Refunded: 5000})
+					})
+			},
+			want: "\ufeffTanggal,Pembayaran,Diterima,Dikembalikan,Bersih\n" +
+				"2024-01-15,3,45000,5000,40000\n",
+		},
+		{
+			name:  "payments mark refunds",
+			input: ExportReportInput{ShopID: 1, Report: constant.ReportPayments, Format: constant.ReportFormatCSV, Lang: "en"},
+			mockSetup: func(m *mock_store.MockReportStore) {
+				m.EXPECT().StreamPayments(gomock.Any(), 1, model.OrderFilterOptions{}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, _ model.OrderFilterOptions, fn func(model.PaymentReportRow) error) error {
+						number := sql.NullString{String: "INV/2024/01/1", Valid: true}
+						if err := fn(model.PaymentReportRow{ID: 5, OrderID: 1, InvoiceNumber: number, CustomerName: "John Doe", Amount: 15000, CreatedAt: fixedTime}); err != nil {
+							return err
+						}
+						return fn(model.PaymentReportRow{ID: 6, OrderID: 1, InvoiceNumber: number, CustomerName: "John Doe", Amount: -5000, CreatedAt: fixedTime})
+					})
+			},
+			want: "\ufeffPayment ID,Date,Order ID,Invoice #,Customer,Type,Amount\n" +
+				"5,2024-01-15 17:30,1,INV/2024/01/1,John Doe,Payment,15000\n" +
+				"6,2024-01-15 17:30,1,INV/2024/01/1,John Doe,Refund,-5000\n",
+		},
+		{
+			name:  "receivables as xlsx",
+			input: ExportReportInput{ShopID: 1, Report: constant.ReportReceivables, Format: constant.ReportFormatXLSX, Lang: "en"},
+			mockSetup: func(m *mock_store.MockReportStore) {
+				m.EXPECT().StreamReceivables(gomock.Any(), 1, model.OrderFilterOptions{}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, _ model.OrderFilterOptions, fn func(model.ReceivableRow) error) error {
+						return fn(model.ReceivableRow{
+							OrderID:       1,
+							CustomerName:  "Nguyễn Thị Hương",
+							PaymentStatus: "awaiting_dp",
+							AmountDue:     15123,
+							Paid:          5000,
+							DPDueAt:       sql.NullTime{Time: day, Valid: true},
+							CreatedAt:     fixedTime,
+						})
+					})
+			},
+			wantSheet: []string{
+				`<t xml:space="preserve">Amount due</t>`,
+				`<t xml:space="preserve">Nguyễn Thị Hương</t>`,
+				`<c r="G2"><v>15123</v></c><c r="H2"><v>5000</v></c><c r="I2"><v>10123</v></c>`,
+				`<t xml:space="preserve">2024-01-15</t>`,
+			},
+		},
+		{
+			name:      "returns error on unknown report",
+			input:     ExportReportInput{ShopID: 1, Report: "profit", Format: constant.ReportFormatCSV},
+			mockSetup: func(m *mock_store.MockReportStore) {},
+			wantErr:   apierr.ErrReportInvalid,
+		},
+		{
+			name:      "returns error on unknown format",
+			input:     ExportReportInput{ShopID: 1, Report: constant.ReportPayments, Format: "pdf"},
+			mockSetup: func(m *mock_store.MockReportStore) {},
+			wantErr:   apierr.ErrReportFormatInvalid,
+		},
+		{
+			name:  "returns error when store fails",
+			input: ExportReportInput{ShopID: 1, Report: constant.ReportPayments, Format: constant.ReportFormatCSV},
+			mockSetup: func(m *mock_store.MockReportStore) {
+				m.EXPECT().StreamPayments(gomock.Any(), 1, model.OrderFilterOptions{}, gomock.Any()).Return(errors.New("database error"))
+			},
+			wantErr: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldReportStore := reportStore
+			oldShopStore := shopStore
+			defer func() {
+				reportStore = oldReportStore
+				shopStore = oldShopStore
+			}()
+
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			shopMock.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, Timezone: "Asia/Jakarta"}, nil).AnyTimes()
+			shopStore = shopMock
+
+			m := mock_store.NewMockReportStore(ctrl)
+			tt.mockSetup(m)
+			reportStore = m
+
+			var buf bytes.Buffer
+			s := &rservice{}
+			err := s.ExportReport(context.Background(), tt.input, &buf)
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ExportReport() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantErr != "database error" && buf.Len() != 0 {
+					t.Errorf("ExportReport() wrote %d bytes before a validation error", buf.Len())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExportReport() error = %v", err)
+			}
+
+			if tt.wantSheet == nil {
+				if got := buf.String(); got != tt.want {
+					t.Errorf("ExportReport() = %q, want %q", got, tt.want)
+				}
+				return
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("ExportReport() output is not a zip: %v", err)
+			}
+			var sheet string
+			for _, f := range zr.File {
+				if f.Name == "xl/worksheets/sheet1.xml" {
+					rc, _ := f.Open()
+					b, _ := io.ReadAll(rc)
+					rc.Close()
+					sheet = string(b)
+				}
+			}
+			for _, want := range tt.wantSheet {
+				if !strings.Contains(sheet, want) {
+					t.Errorf("ExportReport() sheet missing %s\n%s", want, sheet)
+				}
+			}
+		})
+	}
+}
@@ -3,23 +3,41 @@ package service
 import (
 	"github.com/zeirash/recapo/arion/common/config"
 	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/storage"
 	"github.com/zeirash/recapo/arion/store"
 )
 
 var (
 	cfg config.Config
 
-	userStore         store.UserStore
-	tokenStore        store.TokenStore
-	shopStore         store.ShopStore
-	customerStore     store.CustomerStore
-	productStore      store.ProductStore
-	orderStore        store.OrderStore
-	orderItemStore    store.OrderItemStore
-	orderPaymentStore store.OrderPaymentStore
-	subscriptionStore store.SubscriptionStore
-	systemStore       store.SystemStore
-	invitationStore store.InvitationStore
+	userStore             store.UserStore
+	tokenStore            store.TokenStore
+	shopStore             store.ShopStore
+	customerStore         store.CustomerStore
+	productStore          store.ProductStore
+	productCategoryStore  store.ProductCategoryStore
+	productImageStore     store.ProductImageStore
+	orderStore            store.OrderStore
+	orderItemStore        store.OrderItemStore
+	orderPaymentStore     store.OrderPaymentStore
+	orderRefundStore      store.OrderRefundStore
+	subscriptionStore     store.SubscriptionStore
+	systemStore           store.SystemStore
+	invitationStore       store.InvitationStore
+	customerCreditStore   store.CustomerCreditStore
+	customerAddressStore  store.CustomerAddressStore
+	dpRuleStore           store.DPRuleStore
+	purchaseListStore     store.PurchaseListStore
+	documentSequenceStore store.DocumentSequenceStore
+	reportStore           store.ReportStore
+	statsStore            store.StatsStore
+	shareLinkStore        store.ShareLinkStore
+	archiveStore          store.ArchiveStore
+	uploadedObjectStore   store.UploadedObjectStore
+
+	// storageBackend holds uploaded files. Tests replace it with
+	// storage.NewMemory.
+	storageBackend storage.Backend
 
 	subscriptionService SubscriptionService
 
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+type (
+	ShareLinkService interface {
+		CreateShareLink(ctx context.Context, input CreateShareLinkInput) (response.ShareLinkData, error)
+		GetShareLinksByShopID(ctx context.Context, shopID int) ([]response.ShareLinkData, error)
+		RevokeShareLink(ctx context.Context, id, shopID int) error
+		RegenerateShareLink(ctx context.Context, id, shopID int) (response.ShareLinkData, error)
+	}
+
+	slservice struct{}
+
+	CreateShareLinkInput struct {
+		ShopID     int
+		Label      string
+		ExpiresAt  *time.Time
+		ProductIDs []int
+	}
+)
+
+func NewShareLinkService() ShareLinkService {
+	if shareLinkStore == nil {
+		shareLinkStore = store.NewShareLinkStore()
+	}
+
+	if productStore == nil {
+		productStore = store.NewProductStore()
+	}
+
+	return &slservice{}
+}
+
+// CreateShareLink adds a storefront link. With ProductIDs the storefront only
+// shows, and only takes orders for, those products. Every ID must be one of
+// the shop's products, otherwise ErrProductNotFound is returned: a link left
+// with none of the requested products would offer the whole catalogue.
+func (s *slservice) CreateShareLink(ctx context.Context, input CreateShareLinkInput) (response.ShareLinkData, error) {
+	for _, productID := range input.ProductIDs {
+		product, err := productStore.GetProductByID(ctx, productID, input.ShopID)
+		if err != nil {
+			return response.ShareLinkData{}, err
+		}
+		if product == nil {
+			return response.ShareLinkData{}, errors.New(apierr.ErrProductNotFound)
+		}
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return response.ShareLinkData{}, err
+	}
+	defer tx.Rollback()
+
+	link, err := shareLinkStore.CreateShareLink(ctx, tx, store.CreateShareLinkInput{
+		ShopID:     input.ShopID,
+		Label:      input.Label,
+		ExpiresAt:  input.ExpiresAt,
+		ProductIDs: input.ProductIDs,
+	})
+	if err != nil {
+		return response.ShareLinkData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response.ShareLinkData{}, err
+	}
+
+	return toShareLinkData(*link), nil
+}
+
+func (s *slservice) GetShareLinksByShopID(ctx context.Context, shopID int) ([]response.ShareLinkData, error) {
+	links, err := shareLinkStore.GetShareLinksByShopID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []response.ShareLinkData{}
+	for _, link := range links {
+		res = append(res, toShareLinkData(link))
+	}
+
+	return res, nil
+}
+
+// RevokeShareLink turns the link off. Temp orders placed through it keep
+// pointing at it.
+func (s *slservice) RevokeShareLink(ctx context.Context, id, shopID int) error {
+	link, err := shareLinkStore.GetShareLinkByID(ctx, id, shopID)
+	if err != nil {
+		return err
+	}
+
+	if link == nil {
+		return errors.New(apierr.ErrShareLinkNotFound)
+	}
+
+	return shareLinkStore.RevokeShareLink(ctx, id, shopID)
+}
+
+// RegenerateShareLink replaces the link's token, e.g. after it leaked. The old
+// URL stops working at once; label, expiry and products stay.
+func (s *slservice) RegenerateShareLink(ctx context.Context, id, shopID int) (response.ShareLinkData, error) {
+	token, err := shareLinkStore.RegenerateShareLinkToken(ctx, id, shopID)
+	if err != nil {
+		return response.ShareLinkData{}, err
+	}
+
+	if token == "" {
+		return response.ShareLinkData{}, errors.New(apierr.ErrShareLinkNotFound)
+	}
+
+	link, err := shareLinkStore.GetShareLinkByID(ctx, id, shopID)
+	if err != nil {
+		return response.ShareLinkData{}, err
+	}
+
+	if link == nil {
+		return response.ShareLinkData{}, errors.New(apierr.ErrShareLinkNotFound)
+	}
+
+	return toShareLinkData(*link), nil
+}
+
+// getActiveShareLink resolves a public share token to its link and shop.
+// Unknown tokens and closed shops are ErrShopNotFound; revoked and expired links are
+// ErrShareLinkExpired so the storefront can tell the buyer why.
+func getActiveShareLink(ctx context.Context, token string) (*model.ShareLink, *model.Shop, error) {
+	link, err := shareLinkStore.GetShareLinkByToken(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if link == nil {
+		return nil, nil, errors.New(apierr.ErrShopNotFound)
+	}
+
+	if !isShareLinkActive(*link, time.Now()) {
+		return nil, nil, errors.New(apierr.ErrShareLinkExpired)
+	}
+
+	shop, err := shopStore.GetShopByID(ctx, link.ShopID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if shop == nil || shop.ClosedAt.Valid {
+		return nil, nil, errors.New(apierr.ErrShopNotFound)
+	}
+
+	return link, shop, nil
+}
+
+func isShareLinkActive(link model.ShareLink, now time.Time) bool {
+	if link.RevokedAt.Valid {
+		return false
+	}
+	return !link.ExpiresAt.Valid || now.Before(link.ExpiresAt.Time)
+}
+
+// shareLinkAllows reports whether the link's storefront offers the product.
+// Links without a product subset offer everything.
+func shareLinkAllows(link model.ShareLink, productID int) bool {
+	if len(link.ProductIDs) == 0 {
+		return true
+	}
+	for _, id := range link.ProductIDs {
+		if id == productID {
+			return true
+		}
+	}
+	return false
+}
+
+func toShareLinkData(link model.ShareLink) response.ShareLinkData {
+	res := response.ShareLinkData{
+		ID:         link.ID,
+		Token:      link.Token,
+		Label:      link.Label,
+		Active:     isShareLinkActive(link, time.Now()),
+		ProductIDs: link.ProductIDs,
+		TempOrders: link.TempOrders,
+		Accepted:   link.Accepted,
+		CreatedAt:  link.CreatedAt,
+	}
+	if res.ProductIDs == nil {
+		res.ProductIDs = []int{}
+	}
+	if link.ExpiresAt.Valid {
+		t := link.ExpiresAt.Time
+		res.ExpiresAt = &t
+	}
+	if link.RevokedAt.Valid {
+		t := link.RevokedAt.Time
+		res.RevokedAt = &t
+	}
+	if link.UpdatedAt.Valid {
+		t := link.UpdatedAt.Time
+		res.UpdatedAt = &t
+	}
+	return res
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
+	mock_store "github.com/zeirash/recapo/arion/mock/store"
+	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+)
+
+func Test_slservice_CreateShareLink(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	type mocks struct {
+		db      *mock_database.MockDB
+		tx      *mock_database.MockTx
+		link    *mock_store.MockShareLinkStore
+		product *mock_store.MockProductStore
+	}
+
+	tests := []struct {
+		name       string
+		input      CreateShareLinkInput
+		mockSetup  func(m mocks)
+		want       response.ShareLinkData
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:  "creates link with label, expiry and products",
+			input: CreateShareLinkInput{ShopID: 10, Label: "Trip Jepang", ExpiresAt: &expiresAt, ProductIDs: []int{3, 4}},
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 3, 10).Return(&model.Product{ID: 3, ShopID: 10}, nil)
+				m.product.EXPECT().GetProductByID(gomock.Any(), 4, 10).Return(&model.Product{ID: 4, ShopID: 10}, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.tx.EXPECT().Rollback().Return(nil)
+				m.tx.EXPECT().Commit().Return(nil)
+				m.link.EXPECT().CreateShareLink(gomock.Any(), m.tx, store.CreateShareLinkInput{ShopID: 10, Label: "Trip Jepang", ExpiresAt: &expiresAt, ProductIDs: []int{3, 4}}).
+					Return(&model.ShareLink{ID: 2, ShopID: 10, Token: "abc123", Label: "Trip Jepang", ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true}, ProductIDs: []int{3, 4}, CreatedAt: fixedTime}, nil)
+			},
+			want: response.ShareLinkData{ID: 2, Token: "abc123", Label: "Trip Jepang", ExpiresAt: &expiresAt, Active: true, ProductIDs: []int{3, 4}, CreatedAt: fixedTime},
+		},
+		{
+			name:  "returns ErrProductNotFound when every product is another shop's",
+			input: CreateShareLinkInput{ShopID: 10, ProductIDs: []int{7, 8}},
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 7, 10).Return(nil, nil)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrProductNotFound,
+		},
+		{
+			name:  "returns ErrProductNotFound when one product is unknown",
+			input: CreateShareLinkInput{ShopID: 10, ProductIDs: []int{3, 99}},
+			mockSetup: func(m mocks) {
+				m.product.EXPECT().GetProductByID(gomock.Any(), 3, 10).Return(&model.Product{ID: 3, ShopID: 10}, nil)
+				m.product.EXPECT().GetProductByID(gomock.Any(), 99, 10).Return(nil, nil)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrProductNotFound,
+		},
+		{
+			name:  "returns error when create fails",
+			input: CreateShareLinkInput{ShopID: 10},
+			mockSetup: func(m mocks) {
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.tx.EXPECT().Rollback().Return(nil)
+				m.link.EXPECT().CreateShareLink(gomock.Any(), m.tx, gomock.Any()).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldShareLinkStore, oldProductStore, oldDBGetter := shareLinkStore, productStore, dbGetter
+			defer func() { shareLinkStore, productStore, dbGetter = oldShareLinkStore, oldProductStore, oldDBGetter }()
+
+			m := mocks{
+				db:      mock_database.NewMockDB(ctrl),
+				tx:      mock_database.NewMockTx(ctrl),
+				link:    mock_store.NewMockShareLinkStore(ctrl),
+				product: mock_store.NewMockProductStore(ctrl),
+			}
+			tt.mockSetup(m)
+
+			shareLinkStore, productStore = m.link, m.product
+			dbGetter = func() database.DB { return m.db }
+
+			var s slservice
+			got, gotErr := s.CreateShareLink(context.Background(), tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateShareLink() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				if tt.wantErrMsg != "" && gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("CreateShareLink() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateShareLink() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateShareLink() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_slservice_GetShareLinksByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(link *mock_store.MockShareLinkStore)
+		want      []response.ShareLinkData
+		wantErr   bool
+	}{
+		{
+			name: "returns links with counts and whether they still work",
+			mockSetup: func(link *mock_store.MockShareLinkStore) {
+				link.EXPECT().GetShareLinksByShopID(gomock.Any(), 10).Return([]model.ShareLink{
+					{ID: 3, ShopID: 10, Token: "expired", ExpiresAt: sql.NullTime{Time: fixedTime, Valid: true}, ProductIDs: []int{}, TempOrders: 4, Accepted: 1, CreatedAt: fixedTime},
+					{ID: 2, ShopID: 10, Token: "revoked", RevokedAt: sql.NullTime{Time: fixedTime, Valid: true}, ProductIDs: []int{}, CreatedAt: fixedTime},
+					{ID: 1, ShopID: 10, Token: "default", ProductIDs: []int{}, TempOrders: 9, Accepted: 7, CreatedAt: fixedTime},
+				}, nil)
+			},
+			want: []response.ShareLinkData{
+				{ID: 3, Token: "expired", ExpiresAt: &fixedTime, ProductIDs: []int{}, TempOrders: 4, Accepted: 1, CreatedAt: fixedTime},
+				{ID: 2, Token: "revoked", RevokedAt: &fixedTime, ProductIDs: []int{}, CreatedAt: fixedTime},
+				{ID: 1, Token: "default", Active: true, ProductIDs: []int{}, TempOrders: 9, Accepted: 7, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns error on store failure",
+			mockSetup: func(link *mock_store.MockShareLinkStore) {
+				link.EXPECT().GetShareLinksByShopID(gomock.Any(), 10).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldShareLinkStore := shareLinkStore
+			defer func() { shareLinkStore = oldShareLinkStore }()
+
+			mockLink := mock_store.NewMockShareLinkStore(ctrl)
+			tt.mockSetup(mockLink)
+			shareLinkStore = mockLink
+
+			var s slservice
+			got, gotErr := s.GetShareLinksByShopID(context.Background(), 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetShareLinksByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetShareLinksByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetShareLinksByShopID() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_slservice_RevokeShareLink(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(link *mock_store.MockShareLinkStore)
+		wantErr   string
+	}{
+		{
+			name: "revokes the link",
+			mockSetup: func(link *mock_store.MockShareLinkStore) {
+				link.EXPECT().GetShareLinkByID(gomock.Any(), 2, 10).Return(&model.ShareLink{ID: 2, ShopID: 10}, nil)
+				link.EXPECT().RevokeShareLink(gomock.Any(), 2, 10).Return(nil)
+			},
+		},
+		{
+			name: "returns error when link not found",
+			mockSetup: func(link *mock_store.MockShareLinkStore) {
+				link.EXPECT().GetShareLinkByID(gomock.Any(), 2, 10).Return(nil, nil)
+			},
+			wantErr: apierr.ErrShareLinkNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldShareLinkStore := shareLinkStore
+			defer func() { shareLinkStore = oldShareLinkStore }()
+
+			mockLink := mock_store.NewMockShareLinkStore(ctrl)
+			tt.mockSetup(mockLink)
+			shareLinkStore = mockLink
+
+			var s slservice
+			gotErr := s.RevokeShareLink(context.Background(), 2, 10)
+			if tt.wantErr == "" && gotErr != nil {
+				t.Errorf("RevokeShareLink() error = %v", gotErr)
+			}
+			if tt.wantErr != "" && (gotErr == nil || gotErr.Error() != tt.wantErr) {
+				t.Errorf("RevokeShareLink() error = %v, want %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_slservice_RegenerateShareLink(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(link *mock_store.MockShareLinkStore)
+		want      response.ShareLinkData
+		wantErr   string
+	}{
+		{
+			name: "returns the link with its new token",
+			mockSetup: func(link *mock_store.MockShareLinkStore) {
+				link.EXPECT().RegenerateShareLinkToken(gomock.Any(), 2, 10).Return("newtoken", nil)
+				link.EXPECT().GetShareLinkByID(gomock.Any(), 2, 10).
+					Return(&model.ShareLink{ID: 2, ShopID: 10, Token: "newtoken", Label: "IG post", ProductIDs: []int{}, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}}, nil)
+			},
+			want: response.ShareLinkData{ID: 2, Token: "newtoken", Label: "IG post", Active: true, ProductIDs: []int{}, CreatedAt: fixedTime, UpdatedAt: &fixedTime},
+		},
+		{
+			name: "returns error when link not found",
+			mockSetup: func(link *mock_store.MockShareLinkStore) {
+				link.EXPECT().RegenerateShareLinkToken(gomock.Any(), 2, 10).Return("", nil)
+			},
+			wantErr: apierr.ErrShareLinkNotFound,
+		},
+		{
+			name: "returns error on store failure",
+			mockSetup: func(link *mock_store.MockShareLinkStore) {
+				link.EXPECT().RegenerateShareLinkToken(gomock.Any(), 2, 10).Return("", errors.New("database error"))
+			},
+			wantErr: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldShareLinkStore := shareLinkStore
+			defer func() { shareLinkStore = oldShareLinkStore }()
+
+			mockLink := mock_store.NewMockShareLinkStore(ctrl)
+			tt.mockSetup(mockLink)
+			shareLinkStore = mockLink
+
+			var s slservice
+			got, gotErr := s.RegenerateShareLink(context.Background(), 2, 10)
+			if gotErr != nil {
+				if gotErr.Error() != tt.wantErr {
+					t.Errorf("RegenerateShareLink() error = %v, want %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("RegenerateShareLink() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RegenerateShareLink() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
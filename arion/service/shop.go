@@ -3,25 +3,68 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/zeirash/recapo/arion/common/apierr"
 	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/i18n"
+	"github.com/zeirash/recapo/arion/common/logger"
+	"github.com/zeirash/recapo/arion/common/phone"
+	"github.com/zeirash/recapo/arion/common/pow"
 	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/storage"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/store"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type (
 	ShopService interface {
 		GetShareTokenByID(ctx context.Context, shopID int) (string, error)
-		GetPublicProducts(ctx context.Context, shareToken string) ([]response.ProductData, error)
+		GetPublicProducts(ctx context.Context, shareToken string, filter model.FilterOptions) (response.PublicShopProductsData, error)
+		GetShopByID(ctx context.Context, shopID int) (response.ShopData, error)
+		UpdateShopByID(ctx context.Context, input UpdateShopInput) (response.ShopData, error)
+		UploadShopLogo(ctx context.Context, shopID int, file io.Reader) (response.ShopData, error)
+		GetOrderChallenge(ctx context.Context, shareToken string) (response.OrderChallengeData, error)
+		VerifyOrderChallenge(ctx context.Context, shareToken, challenge, solution string) error
+		CloseShop(ctx context.Context, shopID, userID int, password string) error
+		ReopenShop(ctx context.Context, shopID int) error
+		PurgeClosedShops(ctx context.Context) error
 	}
 
 	shopService struct{}
+
+	UpdateShopInput struct {
+		ID                   int
+		Name                 *string
+		UniqueCodeEnabled    *bool
+		UniqueCodeAsFee      *bool
+		Address              *string
+		BankAccounts         *string
+		InvoiceFooter        *string
+		InvoiceNumberPattern *string
+		Timezone             *string
+		Locale               *string
+		Description          *string
+		WhatsApp             *string
+		Instagram            *string
+		InvoiceMessage       *string
+		Currency             *string
+	}
+)
+
+var (
+	instagramHandle = regexp.MustCompile(`^[a-z0-9._]{1,30}$`)
+	currencyCode    = regexp.MustCompile(`^[A-Z]{3}$`)
 )
 
 func NewShopService() ShopService {
-	_ = config.GetConfig()
+	cfg = config.GetConfig()
 
 	if shopStore == nil {
 		shopStore = store.NewShopStore()
@@ -29,56 +72,445 @@ func NewShopService() ShopService {
 	if productStore == nil {
 		productStore = store.NewProductStore()
 	}
+	if shareLinkStore == nil {
+		shareLinkStore = store.NewShareLinkStore()
+	}
+	if userStore == nil {
+		userStore = store.NewUserStore()
+	}
+	if subscriptionStore == nil {
+		subscriptionStore = store.NewSubscriptionStore()
+	}
+	if productCategoryStore == nil {
+		productCategoryStore = store.NewProductCategoryStore()
+	}
+	if productImageStore == nil {
+		productImageStore = store.NewProductImageStore()
+	}
+	if uploadedObjectStore == nil {
+		uploadedObjectStore = store.NewUploadedObjectStore()
+	}
+	if storageBackend == nil {
+		storageBackend = storage.New(cfg)
+	}
 
 	return &shopService{}
 }
 
+// GetShareTokenByID returns the token of the shop's default link, the one
+// that shows every product and never expires. If the seller revoked it or
+// never had one, a new default link is created.
 func (s *shopService) GetShareTokenByID(ctx context.Context, shopID int) (string, error) {
-	token, err := shopStore.GetShareTokenByID(ctx, shopID)
+	link, err := shareLinkStore.GetDefaultShareLink(ctx, shopID)
+	if err != nil {
+		return "", err
+	}
+	if link != nil {
+		return link.Token, nil
+	}
+
+	shop, err := shopStore.GetShopByID(ctx, shopID)
 	if err != nil {
 		return "", err
 	}
-	if token == "" {
+	if shop == nil {
 		return "", errors.New(apierr.ErrShopNotFound)
 	}
-	return token, nil
-}
 
-func (s *shopService) GetPublicProducts(ctx context.Context, shareToken string) ([]response.ProductData, error) {
-	shop, err := shopStore.GetShopByShareToken(ctx, shareToken)
+	db := dbGetter()
+	tx, err := db.Begin()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	defer tx.Rollback()
 
-	if shop == nil {
-		return nil, errors.New(apierr.ErrShopNotFound)
+	link, err = shareLinkStore.CreateShareLink(ctx, tx, store.CreateShareLinkInput{ShopID: shopID})
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return link.Token, nil
+}
+
+// GetPublicProducts returns the active products offered by the share link
+// that match the filter, with the seller's public profile for the storefront
+// header and the shop's categories. Without a sort, products are listed the
+// way the shop arranged them.
+func (s *shopService) GetPublicProducts(ctx context.Context, shareToken string, filter model.FilterOptions) (response.PublicShopProductsData, error) {
+	link, shop, err := getActiveShareLink(ctx, shareToken)
+	if err != nil {
+		return response.PublicShopProductsData{}, err
 	}
 
 	active := true
-	products, err := productStore.GetProductsByShopID(ctx, shop.ID, model.FilterOptions{
-		IsActive: &active,
-	})
+	filter.IsActive = &active
+	if filter.Sort == nil {
+		sort := "position,asc"
+		filter.Sort = &sort
+	}
+	products, err := productStore.GetProductsByShopID(ctx, shop.ID, filter)
+	if err != nil {
+		return response.PublicShopProductsData{}, err
+	}
+
+	categories, err := productCategoryStore.GetProductCategoriesByShopID(ctx, shop.ID)
 	if err != nil {
-		return nil, err
+		return response.PublicShopProductsData{}, err
+	}
+
+	categoriesData := []response.ProductCategoryData{}
+	for _, category := range categories {
+		categoriesData = append(categoriesData, toProductCategoryData(category))
 	}
 
 	productsData := []response.ProductData{}
 	for _, product := range products {
-		res := response.ProductData{
-			ID:            product.ID,
-			Name:          product.Name,
-			Description:   product.Description,
-			Price:         product.Price,
-			OriginalPrice: product.OriginalPrice,
-			ImageURL:      product.ImageURL,
-			CreatedAt:     product.CreatedAt,
+		if !shareLinkAllows(*link, product.ID) {
+			continue
+		}
+		productsData = append(productsData, toProductData(product))
+	}
+	if err := attachProductImages(ctx, productsData); err != nil {
+		return response.PublicShopProductsData{}, err
+	}
+
+	return response.PublicShopProductsData{
+		Shop: response.PublicShopData{
+			Name:        shop.Name,
+			Description: shop.Description,
+			LogoURL:     shop.LogoURL,
+			WhatsApp:    shop.WhatsApp,
+			Instagram:   shop.Instagram,
+			Currency:    shop.Currency,
+		},
+		Categories: categoriesData,
+		Products:   productsData,
+	}, nil
+}
+
+func (s *shopService) GetShopByID(ctx context.Context, shopID int) (response.ShopData, error) {
+	shop, err := shopStore.GetShopByID(ctx, shopID)
+	if err != nil {
+		return response.ShopData{}, err
+	}
+	if shop == nil {
+		return response.ShopData{}, errors.New(apierr.ErrShopNotFound)
+	}
+
+	return toShopData(shop), nil
+}
+
+func (s *shopService) UpdateShopByID(ctx context.Context, input UpdateShopInput) (response.ShopData, error) {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return response.ShopData{}, errors.New(apierr.ErrNameRequired)
+		}
+		input.Name = &name
+	}
+	if input.WhatsApp != nil {
+		// Stored as the international digits wa.me links use. An empty
+		// number clears it.
+		number := strings.TrimSpace(*input.WhatsApp)
+		if number != "" {
+			normalized, err := phone.Normalize(number)
+			if err != nil {
+				return response.ShopData{}, errors.New(apierr.ErrShopWhatsAppInvalid)
+			}
+			number = strings.TrimPrefix(normalized, "+")
+		}
+		input.WhatsApp = &number
+	}
+	if input.Instagram != nil {
+		handle, ok := normalizeInstagram(*input.Instagram)
+		if !ok {
+			return response.ShopData{}, errors.New(apierr.ErrShopInstagramInvalid)
+		}
+		input.Instagram = &handle
+	}
+	if input.Currency != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*input.Currency))
+		if !currencyCode.MatchString(currency) {
+			return response.ShopData{}, errors.New(apierr.ErrShopCurrencyInvalid)
+		}
+		input.Currency = &currency
+	}
+	if input.InvoiceNumberPattern != nil && !validInvoiceNumberPattern(*input.InvoiceNumberPattern) {
+		return response.ShopData{}, errors.New(apierr.ErrInvoiceNumberPatternInvalid)
+	}
+	if input.Timezone != nil && !validTimezone(*input.Timezone) {
+		return response.ShopData{}, errors.New(apierr.ErrShopTimezoneInvalid)
+	}
+	if input.Locale != nil && !i18n.IsSupported(*input.Locale) {
+		return response.ShopData{}, errors.New(apierr.ErrShopLocaleInvalid)
+	}
+
+	shop, err := shopStore.UpdateShop(ctx, input.ID, store.UpdateShopInput{
+		Name:                 input.Name,
+		UniqueCodeEnabled:    input.UniqueCodeEnabled,
+		UniqueCodeAsFee:      input.UniqueCodeAsFee,
+		Address:              input.Address,
+		BankAccounts:         input.BankAccounts,
+		InvoiceFooter:        input.InvoiceFooter,
+		InvoiceNumberPattern: input.InvoiceNumberPattern,
+		Timezone:             input.Timezone,
+		Locale:               input.Locale,
+		Description:          input.Description,
+		WhatsApp:             input.WhatsApp,
+		Instagram:            input.Instagram,
+		InvoiceMessage:       input.InvoiceMessage,
+		Currency:             input.Currency,
+	})
+	if err != nil {
+		return response.ShopData{}, err
+	}
+	if shop == nil {
+		return response.ShopData{}, errors.New(apierr.ErrShopNotFound)
+	}
+
+	return toShopData(shop), nil
+}
+
+// UploadShopLogo stores the image and makes it the logo printed on invoices.
+func (s *shopService) UploadShopLogo(ctx context.Context, shopID int, file io.Reader) (response.ShopData, error) {
+	logoURL, err := uploadImage(ctx, file, "logos")
+	if err != nil {
+		return response.ShopData{}, err
+	}
+
+	shop, err := shopStore.UpdateShop(ctx, shopID, store.UpdateShopInput{
+		LogoURL: &logoURL,
+	})
+	if err != nil {
+		return response.ShopData{}, err
+	}
+	if shop == nil {
+		return response.ShopData{}, errors.New(apierr.ErrShopNotFound)
+	}
+
+	return toShopData(shop), nil
+}
+
+func toShopData(shop *model.Shop) response.ShopData {
+	res := response.ShopData{
+		ID:                   shop.ID,
+		Name:                 shop.Name,
+		UniqueCodeEnabled:    shop.UniqueCodeEnabled,
+		UniqueCodeAsFee:      shop.UniqueCodeAsFee,
+		Address:              shop.Address,
+		LogoURL:              shop.LogoURL,
+		BankAccounts:         shop.BankAccounts,
+		InvoiceFooter:        shop.InvoiceFooter,
+		InvoiceNumberPattern: shop.InvoiceNumberPattern,
+		Timezone:             shop.Timezone,
+		Locale:               shop.Locale,
+		Description:          shop.Description,
+		WhatsApp:             shop.WhatsApp,
+		Instagram:            shop.Instagram,
+		InvoiceMessage:       shop.InvoiceMessage,
+		Currency:             shop.Currency,
+		CreatedAt:            shop.CreatedAt,
+	}
+	if shop.UpdatedAt.Valid {
+		t := shop.UpdatedAt.Time
+		res.UpdatedAt = &t
+	}
+	return res
+}
+
+// normalizeInstagram accepts a handle with or without "@". Handles are case
+// insensitive and stored lowercase. An empty handle clears it.
+func normalizeInstagram(handle string) (string, bool) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	if handle == "" {
+		return "", true
+	}
+	return handle, instagramHandle.MatchString(handle)
+}
+
+// GetOrderChallenge issues the proof-of-work challenge the share page solves
+// before placing an order through the link.
+func (s *shopService) GetOrderChallenge(ctx context.Context, shareToken string) (response.OrderChallengeData, error) {
+	if _, _, err := getActiveShareLink(ctx, shareToken); err != nil {
+		return response.OrderChallengeData{}, err
+	}
+
+	c, err := pow.Issue(cfg.SecretKey, shareToken, cfg.PublicOrderPoWDifficulty, constant.PublicOrderChallengeTTL)
+	if err != nil {
+		return response.OrderChallengeData{}, err
+	}
+
+	return response.OrderChallengeData{
+		Challenge:  c.Token,
+		Difficulty: c.Difficulty,
+		ExpiresAt:  c.ExpiresAt,
+	}, nil
+}
+
+// VerifyOrderChallenge checks a solved challenge for the link. Nothing is
+// checked when the difficulty is configured as 0.
+func (s *shopService) VerifyOrderChallenge(ctx context.Context, shareToken, challenge, solution string) error {
+	if cfg.PublicOrderPoWDifficulty <= 0 {
+		return nil
+	}
+
+	if !pow.Verify(cfg.SecretKey, shareToken, challenge, solution) {
+		return errors.New(apierr.ErrChallengeInvalid)
+	}
+
+	return nil
+}
+
+// CloseShop closes the shop on request of its owner, who confirms with their
+// password. Every session of the shop's users ends, nobody can log in and the
+// storefront goes offline. The subscription is cancelled. After
+// constant.ShopClosureGracePeriod the data is purged by PurgeClosedShops.
+func (s *shopService) CloseShop(ctx context.Context, shopID, userID int, password string) error {
+	caller, err := userStore.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if caller == nil || caller.ShopID != shopID || caller.Role != constant.RoleOwner {
+		return errors.New(apierr.ErrNotOwner)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(caller.Password), []byte(password)) != nil {
+		return errors.New(apierr.ErrPasswordIncorrect)
+	}
+
+	shop, err := shopStore.GetShopByID(ctx, shopID)
+	if err != nil {
+		return err
+	}
+	if shop == nil {
+		return errors.New(apierr.ErrShopNotFound)
+	}
+	if shop.ClosedAt.Valid {
+		return errors.New(apierr.ErrShopClosed)
+	}
+
+	sub, err := subscriptionStore.GetSubscriptionByShopID(ctx, shopID)
+	if err != nil {
+		return err
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := shopStore.CloseShop(ctx, tx, shopID); err != nil {
+		return err
+	}
+	if err := userStore.ResetSessionTokensByShopID(ctx, tx, shopID); err != nil {
+		return err
+	}
+	if sub != nil && (sub.Status == constant.SubStatusActive || sub.Status == constant.SubStatusTrialing) {
+		if err := subscriptionStore.CancelSubscription(ctx, tx, sub.ID); err != nil {
+			return err
 		}
-		if product.UpdatedAt.Valid {
-			t := product.UpdatedAt.Time
-			res.UpdatedAt = &t
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logger.WithFields(logrus.Fields{"shop_id": shopID, "user_id": userID}).Info("shop closed")
+	return nil
+}
+
+// ReopenShop undoes the closure of a shop whose data has not been purged
+// yet. Its users log in again; the subscription stays cancelled.
+func (s *shopService) ReopenShop(ctx context.Context, shopID int) error {
+	shop, err := shopStore.GetShopByID(ctx, shopID)
+	if err != nil {
+		return err
+	}
+	if shop == nil {
+		return errors.New(apierr.ErrShopNotFound)
+	}
+
+	reopened, err := shopStore.ReopenShop(ctx, shopID)
+	if err != nil {
+		return err
+	}
+	if !reopened {
+		return errors.New(apierr.ErrShopNotClosed)
+	}
+
+	return nil
+}
+
+// PurgeClosedShops deletes the data, users and uploaded images of the shops
+// closed longer than constant.ShopClosureGracePeriod ago. A shop that fails
+// is logged and retried on the next run.
+func (s *shopService) PurgeClosedShops(ctx context.Context) error {
+	shopIDs, err := shopStore.GetShopsToPurge(ctx, time.Now().Add(-constant.ShopClosureGracePeriod))
+	if err != nil {
+		return err
+	}
+
+	purged := 0
+	for _, shopID := range shopIDs {
+		if err := purgeShop(ctx, shopID); err != nil {
+			logger.WithError(err).WithField("shop_id", shopID).Error("purge_shop_error")
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		logger.WithFields(logrus.Fields{"count": purged}).Info("purged closed shops")
+	}
+
+	return nil
+}
+
+// checkShopOpen returns ErrShopClosed when the shop has been closed, which
+// keeps its users from signing in.
+func checkShopOpen(ctx context.Context, shopID int) error {
+	shop, err := shopStore.GetShopByID(ctx, shopID)
+	if err != nil {
+		return err
+	}
+	if shop != nil && shop.ClosedAt.Valid {
+		return errors.New(apierr.ErrShopClosed)
+	}
+	return nil
+}
+
+// purgeShop deletes the shop's rows, then its images. The image URLs are read
+// first because the rows pointing to them are gone afterwards; an image that
+// can't be deleted is only logged.
+func purgeShop(ctx context.Context, shopID int) error {
+	imageURLs, err := shopStore.GetShopImageURLs(ctx, shopID)
+	if err != nil {
+		return err
+	}
+
+	db := dbGetter()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := shopStore.PurgeShop(ctx, tx, shopID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, url := range imageURLs {
+		if err := deleteUploadedImage(ctx, url); err != nil {
+			logger.WithError(err).WithField("image_url", url).Warn("failed to delete image of purged shop")
 		}
-		productsData = append(productsData, res)
 	}
 
-	return productsData, nil
+	return nil
 }
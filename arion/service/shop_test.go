@@ -1,144 +1,254 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/common/pow"
 	"github.com/zeirash/recapo/arion/common/response"
+	mock_database "github.com/zeirash/recapo/arion/mock/database"
 	mock_store "github.com/zeirash/recapo/arion/mock/store"
 	"github.com/zeirash/recapo/arion/model"
+	"github.com/zeirash/recapo/arion/store"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func Test_shopService_GetPublicProducts(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 	active := true
+	positionSort := "position,asc"
+	priceSort := "price,desc"
+	categoryID := 3
+
+	type mocks struct {
+		shop     *mock_store.MockShopStore
+		product  *mock_store.MockProductStore
+		category *mock_store.MockProductCategoryStore
+		link     *mock_store.MockShareLinkStore
+		image    *mock_store.MockProductImageStore
+	}
+
+	productA := model.Product{
+		ID:            1,
+		Name:          "Product A",
+		Description:   "Desc A",
+		Price:         1000,
+		OriginalPrice: 1200,
+		ImageURL:      "/uploads/products/test.jpg",
+		CreatedAt:     fixedTime,
+		UpdatedAt:     sql.NullTime{Time: fixedTime, Valid: true},
+	}
+	productAData := response.ProductData{
+		ID:            1,
+		Name:          "Product A",
+		Description:   "Desc A",
+		Price:         1000,
+		OriginalPrice: 1200,
+		ImageURL:      "/uploads/products/test.jpg",
+		CreatedAt:     fixedTime,
+		Tags:          []string{},
+		Images:        []response.ProductImageData{},
+		UpdatedAt:     &fixedTime,
+	}
+	productAImage := model.ProductImage{
+		ID:           4,
+		ProductID:    1,
+		ShopID:       5,
+		ThumbnailURL: "/uploads/products/a_thumb.webp",
+		MediumURL:    "/uploads/products/a_medium.webp",
+		FullURL:      "/uploads/products/a_full.webp",
+		IsPrimary:    true,
+		CreatedAt:    fixedTime,
+	}
+	productADataWithImage := productAData
+	productADataWithImage.Images = []response.ProductImageData{{
+		ID:           4,
+		ThumbnailURL: "/uploads/products/a_thumb.webp",
+		MediumURL:    "/uploads/products/a_medium.webp",
+		FullURL:      "/uploads/products/a_full.webp",
+		IsPrimary:    true,
+		CreatedAt:    fixedTime,
+	}}
+
 	tests := []struct {
-		name      string
+		name       string
 		shareToken string
-		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockProductStore)
-		want      []response.ProductData
-		wantErr   bool
+		filter     model.FilterOptions
+		mockSetup  func(m mocks)
+		want       response.PublicShopProductsData
+		wantErr    bool
+		wantErrMsg string
 	}{
 		{
 			name:       "success - returns products",
 			shareToken: "abc123xyz",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockProductStore) {
-				shopMock := mock_store.NewMockShopStore(ctrl)
-				productMock := mock_store.NewMockProductStore(ctrl)
-
-				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "abc123xyz").
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "abc123xyz").
+					Return(&model.ShareLink{ID: 1, ShopID: 5, Token: "abc123xyz"}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 5).
 					Return(&model.Shop{
-						ID:         5,
-						Name:       "Test Shop",
-						ShareToken: "abc123xyz",
-						CreatedAt:  fixedTime,
-					}, nil)
-
-				productMock.EXPECT().
-					GetProductsByShopID(gomock.Any(), 5, model.FilterOptions{IsActive: &active}).
-					Return([]model.Product{
-						{
-							ID:            1,
-							Name:          "Product A",
-							Description:   "Desc A",
-							Price:         1000,
-							OriginalPrice: 1200,
-							ImageURL:      "/uploads/products/test.jpg",
-							CreatedAt:     fixedTime,
-							UpdatedAt:     sql.NullTime{Time: fixedTime, Valid: true},
-						},
+						ID:           5,
+						Name:         "Test Shop",
+						WhatsApp:     "6281234567890",
+						Instagram:    "testshop",
+						Currency:     "IDR",
+						BankAccounts: "BCA 1234567890",
+						CreatedAt:    fixedTime,
 					}, nil)
-
-				return shopMock, productMock
+				m.product.EXPECT().
+					GetProductsByShopID(gomock.Any(), 5, model.FilterOptions{IsActive: &active, Sort: &positionSort}).
+					Return([]model.Product{productA}, nil)
+				m.category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 5).
+					Return([]model.ProductCategory{{ID: 3, ShopID: 5, Name: "Snacks", CreatedAt: fixedTime}}, nil)
+				m.image.EXPECT().GetProductImagesByProductIDs(gomock.Any(), []int{1}).
+					Return([]model.ProductImage{productAImage}, nil)
+			},
+			want: response.PublicShopProductsData{
+				Shop:       response.PublicShopData{Name: "Test Shop", WhatsApp: "6281234567890", Instagram: "testshop", Currency: "IDR"},
+				Categories: []response.ProductCategoryData{{ID: 3, Name: "Snacks", CreatedAt: fixedTime}},
+				Products:   []response.ProductData{productADataWithImage},
+			},
+		},
+		{
+			name:       "success - filters are passed to the store with storefront defaults",
+			shareToken: "abc123xyz",
+			filter:     model.FilterOptions{CategoryID: &categoryID, Tags: []string{"spicy"}, Sort: &priceSort},
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "abc123xyz").
+					Return(&model.ShareLink{ID: 1, ShopID: 5, Token: "abc123xyz"}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				m.product.EXPECT().
+					GetProductsByShopID(gomock.Any(), 5, model.FilterOptions{IsActive: &active, CategoryID: &categoryID, Tags: []string{"spicy"}, Sort: &priceSort}).
+					Return([]model.Product{productA}, nil)
+				m.category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 5).Return([]model.ProductCategory{}, nil)
+			},
+			want: response.PublicShopProductsData{
+				Shop:       response.PublicShopData{Name: "Test Shop"},
+				Categories: []response.ProductCategoryData{},
+				Products:   []response.ProductData{productAData},
 			},
-			want: []response.ProductData{
-				{
-					ID:            1,
-					Name:          "Product A",
-					Description:   "Desc A",
-					Price:         1000,
-					OriginalPrice: 1200,
-					ImageURL:      "/uploads/products/test.jpg",
-					CreatedAt:     fixedTime,
-					UpdatedAt:     &fixedTime,
-				},
+		},
+		{
+			name:       "success - link with product subset only shows those products",
+			shareToken: "trip123",
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "trip123").
+					Return(&model.ShareLink{ID: 2, ShopID: 5, Token: "trip123", ProductIDs: []int{1}}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 5).
+					Return(&model.Shop{ID: 5, Name: "Test Shop", CreatedAt: fixedTime}, nil)
+				m.product.EXPECT().
+					GetProductsByShopID(gomock.Any(), 5, model.FilterOptions{IsActive: &active, Sort: &positionSort}).
+					Return([]model.Product{productA, {ID: 2, Name: "Product B", Price: 500, CreatedAt: fixedTime}}, nil)
+				m.category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 5).Return([]model.ProductCategory{}, nil)
+			},
+			want: response.PublicShopProductsData{
+				Shop:       response.PublicShopData{Name: "Test Shop"},
+				Categories: []response.ProductCategoryData{},
+				Products:   []response.ProductData{productAData},
 			},
-			wantErr: false,
 		},
 		{
 			name:       "success - empty products",
 			shareToken: "empty123",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockProductStore) {
-				shopMock := mock_store.NewMockShopStore(ctrl)
-				productMock := mock_store.NewMockProductStore(ctrl)
-
-				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "empty123").
-					Return(&model.Shop{ID: 1, Name: "Shop", ShareToken: "empty123", CreatedAt: fixedTime}, nil)
-
-				productMock.EXPECT().
-					GetProductsByShopID(gomock.Any(), 1, model.FilterOptions{IsActive: &active}).
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "empty123").
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "empty123"}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).
+					Return(&model.Shop{ID: 1, Name: "Shop", CreatedAt: fixedTime}, nil)
+				m.product.EXPECT().
+					GetProductsByShopID(gomock.Any(), 1, model.FilterOptions{IsActive: &active, Sort: &positionSort}).
 					Return([]model.Product{}, nil)
-
-				return shopMock, productMock
+				m.category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 1).Return([]model.ProductCategory{}, nil)
 			},
-			want:    []response.ProductData{},
-			wantErr: false,
+			want: response.PublicShopProductsData{Shop: response.PublicShopData{Name: "Shop"}, Categories: []response.ProductCategoryData{}, Products: []response.ProductData{}},
 		},
 		{
-			name:       "shop not found",
+			name:       "unknown token returns shop not found",
 			shareToken: "invalid",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockProductStore) {
-				shopMock := mock_store.NewMockShopStore(ctrl)
-
-				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "invalid").
-					Return(nil, nil)
-
-				return shopMock, nil
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "invalid").Return(nil, nil)
 			},
-			want:    nil,
-			wantErr: true,
+			wantErr:    true,
+			wantErrMsg: apierr.ErrShopNotFound,
 		},
 		{
-			name:       "GetShopByShareToken returns error",
+			name:       "revoked link returns share link expired",
+			shareToken: "revoked",
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "revoked").
+					Return(&model.ShareLink{ID: 3, ShopID: 1, RevokedAt: sql.NullTime{Time: fixedTime, Valid: true}}, nil)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrShareLinkExpired,
+		},
+		{
+			name:       "expired link returns share link expired",
+			shareToken: "expired",
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "expired").
+					Return(&model.ShareLink{ID: 4, ShopID: 1, ExpiresAt: sql.NullTime{Time: fixedTime, Valid: true}}, nil)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrShareLinkExpired,
+		},
+		{
+			name:       "GetShareLinkByToken returns error",
 			shareToken: "token",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockProductStore) {
-				shopMock := mock_store.NewMockShopStore(ctrl)
-
-				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "token").
-					Return(nil, errors.New("db error"))
-
-				return shopMock, nil
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "token").Return(nil, errors.New("db error"))
 			},
-			want:    nil,
 			wantErr: true,
 		},
+		{
+			name:       "closed shop is not found",
+			shareToken: "token",
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "token").
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "token"}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).
+					Return(&model.Shop{ID: 1, ClosedAt: sql.NullTime{Time: fixedTime, Valid: true}, CreatedAt: fixedTime}, nil)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrShopNotFound,
+		},
 		{
 			name:       "GetProductsByShopID returns error",
 			shareToken: "token",
-			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShopStore, *mock_store.MockProductStore) {
-				shopMock := mock_store.NewMockShopStore(ctrl)
-				productMock := mock_store.NewMockProductStore(ctrl)
-
-				shopMock.EXPECT().
-					GetShopByShareToken(gomock.Any(), "token").
-					Return(&model.Shop{ID: 1, ShareToken: "token", CreatedAt: fixedTime}, nil)
-
-				productMock.EXPECT().
-					GetProductsByShopID(gomock.Any(), 1, model.FilterOptions{IsActive: &active}).
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "token").
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "token"}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).
+					Return(&model.Shop{ID: 1, CreatedAt: fixedTime}, nil)
+				m.product.EXPECT().
+					GetProductsByShopID(gomock.Any(), 1, model.FilterOptions{IsActive: &active, Sort: &positionSort}).
 					Return(nil, errors.New("query failed"))
-
-				return shopMock, productMock
 			},
-			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:       "GetProductCategoriesByShopID returns error",
+			shareToken: "token",
+			mockSetup: func(m mocks) {
+				m.link.EXPECT().GetShareLinkByToken(gomock.Any(), "token").
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "token"}, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).
+					Return(&model.Shop{ID: 1, CreatedAt: fixedTime}, nil)
+				m.product.EXPECT().
+					GetProductsByShopID(gomock.Any(), 1, model.FilterOptions{IsActive: &active, Sort: &positionSort}).
+					Return([]model.Product{}, nil)
+				m.category.EXPECT().GetProductCategoriesByShopID(gomock.Any(), 1).Return(nil, errors.New("query failed"))
+			},
 			wantErr: true,
 		},
 	}
@@ -148,24 +258,35 @@ func Test_shopService_GetPublicProducts(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			shopMock, productMock := tt.mockSetup(ctrl)
+			m := mocks{
+				shop:     mock_store.NewMockShopStore(ctrl),
+				product:  mock_store.NewMockProductStore(ctrl),
+				category: mock_store.NewMockProductCategoryStore(ctrl),
+				link:     mock_store.NewMockShareLinkStore(ctrl),
+				image:    mock_store.NewMockProductImageStore(ctrl),
+			}
+			tt.mockSetup(m)
+			m.image.EXPECT().GetProductImagesByProductIDs(gomock.Any(), gomock.Any()).Return([]model.ProductImage{}, nil).AnyTimes()
 
-			oldShop := shopStore
-			oldProduct := productStore
+			oldShop, oldProduct, oldCategory, oldLink := shopStore, productStore, productCategoryStore, shareLinkStore
+			oldImage := productImageStore
 			defer func() {
-				shopStore = oldShop
-				productStore = oldProduct
+				shopStore, productStore, productCategoryStore, shareLinkStore = oldShop, oldProduct, oldCategory, oldLink
+				productImageStore = oldImage
 			}()
-			shopStore = shopMock
-			productStore = productMock
+			shopStore, productStore, productCategoryStore, shareLinkStore = m.shop, m.product, m.category, m.link
+			productImageStore = m.image
 
 			var s shopService
-			got, gotErr := s.GetPublicProducts(context.Background(), tt.shareToken)
+			got, gotErr := s.GetPublicProducts(context.Background(), tt.shareToken, tt.filter)
 
 			if gotErr != nil {
 				if !tt.wantErr {
 					t.Errorf("GetPublicProducts() error = %v, wantErr %v", gotErr, tt.wantErr)
 				}
+				if tt.wantErrMsg != "" && gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("GetPublicProducts() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
 				return
 			}
 			if tt.wantErr {
@@ -183,34 +304,132 @@ func Test_shopService_GetShareTokenByID(t *testing.T) {
 	tests := []struct {
 		name      string
 		shopID    int
-		mockSetup func(ctrl *gomock.Controller) *mock_store.MockShopStore
+		mockSetup func(ctrl *gomock.Controller) (*mock_store.MockShareLinkStore, *mock_store.MockShopStore, *mock_database.MockDB)
 		want      string
 		wantErr   bool
 	}{
 		{
-			name:   "success - returns share token",
+			name:   "success - returns default link token",
+			shopID: 1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShareLinkStore, *mock_store.MockShopStore, *mock_database.MockDB) {
+				linkMock := mock_store.NewMockShareLinkStore(ctrl)
+				linkMock.EXPECT().
+					GetDefaultShareLink(gomock.Any(), 1).
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "abc123xyz789"}, nil)
+				return linkMock, mock_store.NewMockShopStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			want: "abc123xyz789",
+		},
+		{
+			name:   "success - creates default link when shop has none",
+			shopID: 1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShareLinkStore, *mock_store.MockShopStore, *mock_database.MockDB) {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1}, nil)
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Commit().Return(nil)
+				mockTx.EXPECT().Rollback().Return(nil)
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().Begin().Return(mockTx, nil)
+
+				linkMock := mock_store.NewMockShareLinkStore(ctrl)
+				linkMock.EXPECT().GetDefaultShareLink(gomock.Any(), 1).Return(nil, nil)
+				linkMock.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(&model.ShareLink{ID: 7, ShopID: 1, Token: "newtoken"}, nil)
+				return linkMock, shopMock, mockDB
+			},
+			want: "newtoken",
+		},
+		{
+			name:   "shop not found",
+			shopID: 999,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShareLinkStore, *mock_store.MockShopStore, *mock_database.MockDB) {
+				linkMock := mock_store.NewMockShareLinkStore(ctrl)
+				linkMock.EXPECT().GetDefaultShareLink(gomock.Any(), 999).Return(nil, nil)
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().GetShopByID(gomock.Any(), 999).Return(nil, nil)
+				return linkMock, shopMock, mock_database.NewMockDB(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:   "store returns error",
+			shopID: 1,
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockShareLinkStore, *mock_store.MockShopStore, *mock_database.MockDB) {
+				linkMock := mock_store.NewMockShareLinkStore(ctrl)
+				linkMock.EXPECT().
+					GetDefaultShareLink(gomock.Any(), 1).
+					Return(nil, errors.New("db error"))
+				return linkMock, mock_store.NewMockShopStore(ctrl), mock_database.NewMockDB(ctrl)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			linkMock, shopMock, mockDB := tt.mockSetup(ctrl)
+
+			oldLink, oldShop, oldDBGetter := shareLinkStore, shopStore, dbGetter
+			defer func() { shareLinkStore, shopStore, dbGetter = oldLink, oldShop, oldDBGetter }()
+			shareLinkStore = linkMock
+			shopStore = shopMock
+			dbGetter = func() database.DB { return mockDB }
+
+			var s shopService
+			got, gotErr := s.GetShareTokenByID(context.Background(), tt.shopID)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetShareTokenByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetShareTokenByID() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("GetShareTokenByID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shopService_GetShopByID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		shopID    int
+		mockSetup func(ctrl *gomock.Controller) *mock_store.MockShopStore
+		want      response.ShopData
+		wantErr   bool
+	}{
+		{
+			name:   "success - returns shop settings",
 			shopID: 1,
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
 				shopMock := mock_store.NewMockShopStore(ctrl)
 				shopMock.EXPECT().
-					GetShareTokenByID(gomock.Any(), 1).
-					Return("abc123xyz789", nil)
+					GetShopByID(gomock.Any(), 1).
+					Return(&model.Shop{ID: 1, Name: "My Shop", UniqueCodeEnabled: true, CreatedAt: fixedTime}, nil)
 				return shopMock
 			},
-			want:    "abc123xyz789",
-			wantErr: false,
+			want: response.ShopData{ID: 1, Name: "My Shop", UniqueCodeEnabled: true, CreatedAt: fixedTime},
 		},
 		{
-			name:   "shop not found - store returns empty token",
+			name:   "shop not found",
 			shopID: 999,
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
 				shopMock := mock_store.NewMockShopStore(ctrl)
 				shopMock.EXPECT().
-					GetShareTokenByID(gomock.Any(), 999).
-					Return("", nil)
+					GetShopByID(gomock.Any(), 999).
+					Return(nil, nil)
 				return shopMock
 			},
-			want:    "",
 			wantErr: true,
 		},
 		{
@@ -219,11 +438,10 @@ func Test_shopService_GetShareTokenByID(t *testing.T) {
 			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
 				shopMock := mock_store.NewMockShopStore(ctrl)
 				shopMock.EXPECT().
-					GetShareTokenByID(gomock.Any(), 1).
-					Return("", errors.New("db error"))
+					GetShopByID(gomock.Any(), 1).
+					Return(nil, errors.New("db error"))
 				return shopMock
 			},
-			want:    "",
 			wantErr: true,
 		},
 	}
@@ -232,26 +450,651 @@ func Test_shopService_GetShareTokenByID(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			shopMock := tt.mockSetup(ctrl)
+			oldShop := shopStore
+			defer func() { shopStore = oldShop }()
+			shopStore = tt.mockSetup(ctrl)
+
+			var s shopService
+			got, gotErr := s.GetShopByID(context.Background(), tt.shopID)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetShopByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetShopByID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetShopByID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shopService_UpdateShopByID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	updatedTime := time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC)
+	enabled := true
+	address := "Jl. Melati 5, Bandung"
+	bankAccounts := "BCA 1234567890 a.n. My Shop"
+	footer := "Thank you for shopping"
+	pattern := "TOKO/{YY}{MM}/{seq:4}"
+	badPattern := "TOKO/{YY}{MM}"
+	timezone := "Asia/Makassar"
+	locale := "en"
+	badTimezone := "Asia/Bandung"
+	local := "Local"
+	badLocale := "fr"
+	name := "  Toko Jastip "
+	blank := " "
+	whatsApp := "0812-3456-7890"
+	instagram := "@TokoJastip"
+	currency := "sgd"
+	badWhatsApp := "0812 3456 789a"
+	badInstagram := "toko jastip"
+	badCurrency := "RP"
+
+	tests := []struct {
+		name      string
+		input     UpdateShopInput
+		mockSetup func(ctrl *gomock.Controller) *mock_store.MockShopStore
+		want      response.ShopData
+		wantErr   bool
+	}{
+		{
+			name:  "success - enables unique code",
+			input: UpdateShopInput{ID: 1, UniqueCodeEnabled: &enabled},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 1, store.UpdateShopInput{UniqueCodeEnabled: &enabled}).
+					Return(&model.Shop{ID: 1, Name: "My Shop", UniqueCodeEnabled: true, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true}}, nil)
+				return shopMock
+			},
+			want: response.ShopData{ID: 1, Name: "My Shop", UniqueCodeEnabled: true, CreatedAt: fixedTime, UpdatedAt: &updatedTime},
+		},
+		{
+			name:  "success - updates invoice branding",
+			input: UpdateShopInput{ID: 1, Address: &address, BankAccounts: &bankAccounts, InvoiceFooter: &footer},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 1, store.UpdateShopInput{Address: &address, BankAccounts: &bankAccounts, InvoiceFooter: &footer}).
+					Return(&model.Shop{ID: 1, Name: "My Shop", Address: address, LogoURL: "/uploads/logos/a.png", BankAccounts: bankAccounts, InvoiceFooter: footer, CreatedAt: fixedTime}, nil)
+				return shopMock
+			},
+			want: response.ShopData{ID: 1, Name: "My Shop", Address: address, LogoURL: "/uploads/logos/a.png", BankAccounts: bankAccounts, InvoiceFooter: footer, CreatedAt: fixedTime},
+		},
+		{
+			name:  "success - sets invoice number pattern",
+			input: UpdateShopInput{ID: 1, InvoiceNumberPattern: &pattern},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 1, store.UpdateShopInput{InvoiceNumberPattern: &pattern}).
+					Return(&model.Shop{ID: 1, Name: "My Shop", InvoiceNumberPattern: pattern, CreatedAt: fixedTime}, nil)
+				return shopMock
+			},
+			want: response.ShopData{ID: 1, Name: "My Shop", InvoiceNumberPattern: pattern, CreatedAt: fixedTime},
+		},
+		{
+			name:  "rejects invoice number pattern without sequence",
+			input: UpdateShopInput{ID: 1, InvoiceNumberPattern: &badPattern},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "success - sets timezone and locale",
+			input: UpdateShopInput{ID: 1, Timezone: &timezone, Locale: &locale},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 1, store.UpdateShopInput{Timezone: &timezone, Locale: &locale}).
+					Return(&model.Shop{ID: 1, Name: "My Shop", Timezone: timezone, Locale: locale, CreatedAt: fixedTime}, nil)
+				return shopMock
+			},
+			want: response.ShopData{ID: 1, Name: "My Shop", Timezone: timezone, Locale: locale, CreatedAt: fixedTime},
+		},
+		{
+			name:  "success - normalizes profile fields",
+			input: UpdateShopInput{ID: 1, Name: &name, WhatsApp: &whatsApp, Instagram: &instagram, Currency: &currency},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 1, store.UpdateShopInput{
+						Name:      strPtr("Toko Jastip"),
+						WhatsApp:  strPtr("6281234567890"),
+						Instagram: strPtr("tokojastip"),
+						Currency:  strPtr("SGD"),
+					}).
+					Return(&model.Shop{ID: 1, Name: "Toko Jastip", WhatsApp: "6281234567890", Instagram: "tokojastip", Currency: "SGD", CreatedAt: fixedTime}, nil)
+				return shopMock
+			},
+			want: response.ShopData{ID: 1, Name: "Toko Jastip", WhatsApp: "6281234567890", Instagram: "tokojastip", Currency: "SGD", CreatedAt: fixedTime},
+		},
+		{
+			name:  "rejects blank name",
+			input: UpdateShopInput{ID: 1, Name: &blank},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects whatsapp number with letters",
+			input: UpdateShopInput{ID: 1, WhatsApp: &badWhatsApp},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects instagram handle with spaces",
+			input: UpdateShopInput{ID: 1, Instagram: &badInstagram},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects currency that is not an ISO code",
+			input: UpdateShopInput{ID: 1, Currency: &badCurrency},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects unknown timezone",
+			input: UpdateShopInput{ID: 1, Timezone: &badTimezone},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects the server's local timezone",
+			input: UpdateShopInput{ID: 1, Timezone: &local},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects unsupported locale",
+			input: UpdateShopInput{ID: 1, Locale: &badLocale},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "shop not found",
+			input: UpdateShopInput{ID: 999, UniqueCodeAsFee: &enabled},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 999, gomock.Any()).
+					Return(nil, nil)
+				return shopMock
+			},
+			wantErr: true,
+		},
+		{
+			name:  "store returns error",
+			input: UpdateShopInput{ID: 1, UniqueCodeEnabled: &enabled},
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 1, gomock.Any()).
+					Return(nil, errors.New("db error"))
+				return shopMock
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
 
 			oldShop := shopStore
 			defer func() { shopStore = oldShop }()
-			shopStore = shopMock
+			shopStore = tt.mockSetup(ctrl)
 
 			var s shopService
-			got, gotErr := s.GetShareTokenByID(context.Background(), tt.shopID)
+			got, gotErr := s.UpdateShopByID(context.Background(), tt.input)
 			if gotErr != nil {
 				if !tt.wantErr {
-					t.Errorf("GetShareTokenByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+					t.Errorf("UpdateShopByID() error = %v, wantErr %v", gotErr, tt.wantErr)
 				}
 				return
 			}
 			if tt.wantErr {
-				t.Fatal("GetShareTokenByID() succeeded unexpectedly")
+				t.Fatal("UpdateShopByID() succeeded unexpectedly")
 			}
-			if got != tt.want {
-				t.Errorf("GetShareTokenByID() = %v, want %v", got, tt.want)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateShopByID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shopService_UploadShopLogo(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	pngBytes := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+
+	tests := []struct {
+		name       string
+		file       []byte
+		mockSetup  func(ctrl *gomock.Controller) *mock_store.MockShopStore
+		wantLogo   string // expected logo_url prefix
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name: "stores the logo and saves its URL on the shop",
+			file: pngBytes,
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().
+					UpdateShop(gomock.Any(), 1, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, input store.UpdateShopInput) (*model.Shop, error) {
+						return &model.Shop{ID: 1, Name: "My Shop", LogoURL: *input.LogoURL, CreatedAt: fixedTime}, nil
+					})
+				return shopMock
+			},
+			wantLogo: "/uploads/logos/",
+		},
+		{
+			name: "rejects unsupported file type",
+			file: []byte("hello plain text"),
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				return mock_store.NewMockShopStore(ctrl)
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrUnsupportedImageType,
+		},
+		{
+			name: "shop not found",
+			file: pngBytes,
+			mockSetup: func(ctrl *gomock.Controller) *mock_store.MockShopStore {
+				shopMock := mock_store.NewMockShopStore(ctrl)
+				shopMock.EXPECT().UpdateShop(gomock.Any(), 1, gomock.Any()).Return(nil, nil)
+				return shopMock
+			},
+			wantErr:    true,
+			wantErrMsg: apierr.ErrShopNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldShop := shopStore
+			defer func() { shopStore = oldShop }()
+			shopStore = tt.mockSetup(ctrl)
+			useMemoryStorage(t)
+
+			var s shopService
+			got, gotErr := s.UploadShopLogo(context.Background(), 1, bytes.NewReader(tt.file))
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("UploadShopLogo() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				if tt.wantErrMsg != "" && gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("UploadShopLogo() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("UploadShopLogo() succeeded unexpectedly")
+			}
+			if !strings.HasPrefix(got.LogoURL, tt.wantLogo) || !strings.HasSuffix(got.LogoURL, ".png") {
+				t.Errorf("UploadShopLogo() logo_url = %q, want %q prefix and .png suffix", got.LogoURL, tt.wantLogo)
+			}
+		})
+	}
+}
+
+func Test_shopService_GetOrderChallenge(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		mockSetup  func(link *mock_store.MockShareLinkStore, shop *mock_store.MockShopStore)
+		wantErrMsg string
+	}{
+		{
+			name: "issues a challenge for an active link",
+			mockSetup: func(link *mock_store.MockShareLinkStore, shop *mock_store.MockShopStore) {
+				link.EXPECT().GetShareLinkByToken(gomock.Any(), "token").
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "token"}, nil)
+				shop.EXPECT().GetShopByID(gomock.Any(), 1).
+					Return(&model.Shop{ID: 1, CreatedAt: fixedTime}, nil)
+			},
+		},
+		{
+			name: "returns shop not found for an unknown token",
+			mockSetup: func(link *mock_store.MockShareLinkStore, shop *mock_store.MockShopStore) {
+				link.EXPECT().GetShareLinkByToken(gomock.Any(), "token").Return(nil, nil)
+			},
+			wantErrMsg: apierr.ErrShopNotFound,
+		},
+		{
+			name: "returns expired for a revoked link",
+			mockSetup: func(link *mock_store.MockShareLinkStore, shop *mock_store.MockShopStore) {
+				link.EXPECT().GetShareLinkByToken(gomock.Any(), "token").
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "token", RevokedAt: sql.NullTime{Time: fixedTime, Valid: true}}, nil)
+			},
+			wantErrMsg: apierr.ErrShareLinkExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			linkMock := mock_store.NewMockShareLinkStore(ctrl)
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			tt.mockSetup(linkMock, shopMock)
+
+			oldShop, oldLink, oldCfg := shopStore, shareLinkStore, cfg
+			defer func() {
+				shopStore, shareLinkStore, cfg = oldShop, oldLink, oldCfg
+			}()
+			shopStore, shareLinkStore = shopMock, linkMock
+			cfg.SecretKey = "testsecret"
+			cfg.PublicOrderPoWDifficulty = 4
+
+			var s shopService
+			got, gotErr := s.GetOrderChallenge(context.Background(), "token")
+
+			if tt.wantErrMsg != "" {
+				if gotErr == nil || gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("GetOrderChallenge() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if gotErr != nil {
+				t.Fatalf("GetOrderChallenge() error = %v", gotErr)
+			}
+			if got.Difficulty != 4 || got.Challenge == "" {
+				t.Errorf("GetOrderChallenge() = %+v, want a challenge with difficulty 4", got)
 			}
 		})
 	}
 }
+
+func Test_shopService_VerifyOrderChallenge(t *testing.T) {
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+	cfg.SecretKey = "testsecret"
+
+	c, err := pow.Issue("testsecret", "token", 4, time.Minute)
+	if err != nil {
+		t.Fatalf("pow.Issue() error = %v", err)
+	}
+	solution := pow.Solve(c.Token, c.Difficulty)
+
+	tests := []struct {
+		name       string
+		difficulty int
+		shareToken string
+		challenge  string
+		solution   string
+		wantErr    bool
+	}{
+		{name: "skips the check when difficulty is 0", difficulty: 0, shareToken: "token"},
+		{name: "rejects a missing challenge", difficulty: 4, shareToken: "token", wantErr: true},
+		{name: "rejects a challenge issued for another link", difficulty: 4, shareToken: "other", challenge: c.Token, solution: solution, wantErr: true},
+		{name: "accepts a solved challenge", difficulty: 4, shareToken: "token", challenge: c.Token, solution: solution},
+		{name: "rejects a reused challenge", difficulty: 4, shareToken: "token", challenge: c.Token, solution: solution, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.PublicOrderPoWDifficulty = tt.difficulty
+
+			var s shopService
+			gotErr := s.VerifyOrderChallenge(context.Background(), tt.shareToken, tt.challenge, tt.solution)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("VerifyOrderChallenge() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if gotErr != nil && gotErr.Error() != apierr.ErrChallengeInvalid {
+				t.Errorf("VerifyOrderChallenge() error = %v, want %v", gotErr, apierr.ErrChallengeInvalid)
+			}
+		})
+	}
+}
+
+func Test_shopService_CloseShop(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	owner := &model.User{ID: 3, ShopID: 1, Role: constant.RoleOwner, Password: string(hashed)}
+
+	type mocks struct {
+		shop *mock_store.MockShopStore
+		user *mock_store.MockUserStore
+		sub  *mock_store.MockSubscriptionStore
+		db   *mock_database.MockDB
+		tx   *mock_database.MockTx
+	}
+
+	tests := []struct {
+		name       string
+		password   string
+		mockSetup  func(m mocks)
+		wantErrMsg string
+	}{
+		{
+			name:     "closes the shop, signs everyone out and cancels the subscription",
+			password: "secret123",
+			mockSetup: func(m mocks) {
+				m.user.EXPECT().GetUserByID(gomock.Any(), 3).Return(owner, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, CreatedAt: fixedTime}, nil)
+				m.sub.EXPECT().GetSubscriptionByShopID(gomock.Any(), 1).Return(&model.Subscription{ID: 9, ShopID: 1, Status: constant.SubStatusActive}, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.shop.EXPECT().CloseShop(gomock.Any(), m.tx, 1).Return(nil)
+				m.user.EXPECT().ResetSessionTokensByShopID(gomock.Any(), m.tx, 1).Return(nil)
+				m.sub.EXPECT().CancelSubscription(gomock.Any(), m.tx, 9).Return(nil)
+				m.tx.EXPECT().Commit().Return(nil)
+				m.tx.EXPECT().Rollback().Return(nil)
+			},
+		},
+		{
+			name:     "leaves an expired subscription alone",
+			password: "secret123",
+			mockSetup: func(m mocks) {
+				m.user.EXPECT().GetUserByID(gomock.Any(), 3).Return(owner, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, CreatedAt: fixedTime}, nil)
+				m.sub.EXPECT().GetSubscriptionByShopID(gomock.Any(), 1).Return(&model.Subscription{ID: 9, ShopID: 1, Status: constant.SubStatusExpired}, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.shop.EXPECT().CloseShop(gomock.Any(), m.tx, 1).Return(nil)
+				m.user.EXPECT().ResetSessionTokensByShopID(gomock.Any(), m.tx, 1).Return(nil)
+				m.tx.EXPECT().Commit().Return(nil)
+				m.tx.EXPECT().Rollback().Return(nil)
+			},
+		},
+		{
+			name:     "only the owner can close the shop",
+			password: "secret123",
+			mockSetup: func(m mocks) {
+				m.user.EXPECT().GetUserByID(gomock.Any(), 3).Return(&model.User{ID: 3, ShopID: 1, Role: constant.RoleAdmin, Password: string(hashed)}, nil)
+			},
+			wantErrMsg: apierr.ErrNotOwner,
+		},
+		{
+			name:     "wrong password",
+			password: "wrong",
+			mockSetup: func(m mocks) {
+				m.user.EXPECT().GetUserByID(gomock.Any(), 3).Return(owner, nil)
+			},
+			wantErrMsg: apierr.ErrPasswordIncorrect,
+		},
+		{
+			name:     "shop already closed",
+			password: "secret123",
+			mockSetup: func(m mocks) {
+				m.user.EXPECT().GetUserByID(gomock.Any(), 3).Return(owner, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, ClosedAt: sql.NullTime{Time: fixedTime, Valid: true}}, nil)
+			},
+			wantErrMsg: apierr.ErrShopClosed,
+		},
+		{
+			name:     "store error rolls back",
+			password: "secret123",
+			mockSetup: func(m mocks) {
+				m.user.EXPECT().GetUserByID(gomock.Any(), 3).Return(owner, nil)
+				m.shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1, CreatedAt: fixedTime}, nil)
+				m.sub.EXPECT().GetSubscriptionByShopID(gomock.Any(), 1).Return(nil, nil)
+				m.db.EXPECT().Begin().Return(m.tx, nil)
+				m.shop.EXPECT().CloseShop(gomock.Any(), m.tx, 1).Return(nil)
+				m.user.EXPECT().ResetSessionTokensByShopID(gomock.Any(), m.tx, 1).Return(errors.New("db error"))
+				m.tx.EXPECT().Rollback().Return(nil)
+			},
+			wantErrMsg: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks{
+				shop: mock_store.NewMockShopStore(ctrl),
+				user: mock_store.NewMockUserStore(ctrl),
+				sub:  mock_store.NewMockSubscriptionStore(ctrl),
+				db:   mock_database.NewMockDB(ctrl),
+				tx:   mock_database.NewMockTx(ctrl),
+			}
+			tt.mockSetup(m)
+
+			oldShop, oldUser, oldSub, oldDBGetter := shopStore, userStore, subscriptionStore, dbGetter
+			defer func() {
+				shopStore, userStore, subscriptionStore, dbGetter = oldShop, oldUser, oldSub, oldDBGetter
+			}()
+			shopStore, userStore, subscriptionStore = m.shop, m.user, m.sub
+			dbGetter = func() database.DB { return m.db }
+
+			var s shopService
+			gotErr := s.CloseShop(context.Background(), 1, 3, tt.password)
+			if tt.wantErrMsg != "" {
+				if gotErr == nil || gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("CloseShop() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if gotErr != nil {
+				t.Errorf("CloseShop() error = %v", gotErr)
+			}
+		})
+	}
+}
+
+func Test_shopService_ReopenShop(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockSetup  func(shop *mock_store.MockShopStore)
+		wantErrMsg string
+	}{
+		{
+			name: "reopens a closed shop",
+			mockSetup: func(shop *mock_store.MockShopStore) {
+				shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1}, nil)
+				shop.EXPECT().ReopenShop(gomock.Any(), 1).Return(true, nil)
+			},
+		},
+		{
+			name: "shop not found",
+			mockSetup: func(shop *mock_store.MockShopStore) {
+				shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(nil, nil)
+			},
+			wantErrMsg: apierr.ErrShopNotFound,
+		},
+		{
+			name: "shop not closed or already purged",
+			mockSetup: func(shop *mock_store.MockShopStore) {
+				shop.EXPECT().GetShopByID(gomock.Any(), 1).Return(&model.Shop{ID: 1}, nil)
+				shop.EXPECT().ReopenShop(gomock.Any(), 1).Return(false, nil)
+			},
+			wantErrMsg: apierr.ErrShopNotClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			shopMock := mock_store.NewMockShopStore(ctrl)
+			tt.mockSetup(shopMock)
+
+			oldShop := shopStore
+			defer func() { shopStore = oldShop }()
+			shopStore = shopMock
+
+			var s shopService
+			gotErr := s.ReopenShop(context.Background(), 1)
+			if tt.wantErrMsg != "" {
+				if gotErr == nil || gotErr.Error() != tt.wantErrMsg {
+					t.Errorf("ReopenShop() error = %v, want %v", gotErr, tt.wantErrMsg)
+				}
+				return
+			}
+			if gotErr != nil {
+				t.Errorf("ReopenShop() error = %v", gotErr)
+			}
+		})
+	}
+}
+
+func Test_shopService_PurgeClosedShops(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oldShop, oldDBGetter := shopStore, dbGetter
+	defer func() { shopStore, dbGetter = oldShop, oldDBGetter }()
+
+	backend := useMemoryStorage(t)
+	if err := backend.Put(context.Background(), "logos/a.png", "image/png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+
+	shopMock := mock_store.NewMockShopStore(ctrl)
+	shopMock.EXPECT().GetShopsToPurge(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, closedBefore time.Time) ([]int, error) {
+			if d := time.Until(closedBefore) + constant.ShopClosureGracePeriod; d < -time.Minute || d > time.Minute {
+				t.Errorf("GetShopsToPurge() closedBefore = %v, want grace period ago", closedBefore)
+			}
+			return []int{4, 5}, nil
+		})
+
+	// shop 4 fails and is left for the next run; shop 5 is purged
+	shopMock.EXPECT().GetShopImageURLs(gomock.Any(), 4).Return(nil, errors.New("db error"))
+	shopMock.EXPECT().GetShopImageURLs(gomock.Any(), 5).Return([]string{"/uploads/logos/a.png", "/uploads/products/gone.jpg"}, nil)
+
+	mockTx := mock_database.NewMockTx(ctrl)
+	mockDB := mock_database.NewMockDB(ctrl)
+	mockDB.EXPECT().Begin().Return(mockTx, nil)
+	shopMock.EXPECT().PurgeShop(gomock.Any(), mockTx, 5).Return(nil)
+	mockTx.EXPECT().Commit().Return(nil)
+	mockTx.EXPECT().Rollback().Return(nil)
+
+	shopStore = shopMock
+	dbGetter = func() database.DB { return mockDB }
+
+	var s shopService
+	if err := s.PurgeClosedShops(context.Background()); err != nil {
+		t.Fatalf("PurgeClosedShops() error = %v", err)
+	}
+	if keys := backend.Keys(); len(keys) != 0 {
+		t.Errorf("PurgeClosedShops() left %v behind", keys)
+	}
+}
@@ -2,8 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"github.com/zeirash/recapo/arion/common/config"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/logger"
 	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/storage"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/store"
 )
@@ -13,15 +20,24 @@ type (
 		GetSystemStats(ctx context.Context) (*response.SystemStatsData, error)
 		GetSystemShops(ctx context.Context) ([]response.SystemShopData, error)
 		GetSystemPayments(ctx context.Context, opts model.SystemPaymentFilterOptions) ([]response.SystemPaymentData, error)
+		CollectUnreferencedUploads(ctx context.Context) (response.SystemUploadGCData, error)
 	}
 
 	sysservice struct{}
 )
 
 func NewSystemService() SystemService {
+	cfg = config.GetConfig()
+
 	if systemStore == nil {
 		systemStore = store.NewSystemStore()
 	}
+	if uploadedObjectStore == nil {
+		uploadedObjectStore = store.NewUploadedObjectStore()
+	}
+	if storageBackend == nil {
+		storageBackend = storage.New(cfg)
+	}
 	return &sysservice{}
 }
 
@@ -82,3 +98,51 @@ func (s *sysservice) GetSystemPayments(ctx context.Context, opts model.SystemPay
 	}
 	return results, nil
 }
+
+// CollectUnreferencedUploads deletes the uploaded files that no row points
+// to and that are older than constant.UploadGCMinAge, so a file uploaded for
+// a form that is still open is kept. A file that can't be deleted keeps its
+// record and is tried again on the next run.
+func (s *sysservice) CollectUnreferencedUploads(ctx context.Context) (response.SystemUploadGCData, error) {
+	result := response.SystemUploadGCData{Keys: []string{}}
+	createdBefore := time.Now().Add(-constant.UploadGCMinAge)
+
+	for {
+		objects, err := uploadedObjectStore.GetUnreferencedUploadedObjects(ctx, createdBefore, constant.UploadGCBatchSize)
+		if err != nil {
+			return result, err
+		}
+
+		failed := 0
+		for _, object := range objects {
+			if err := storageBackend.Delete(ctx, object.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				logger.WithError(err).WithField("key", object.Key).Warn("failed to delete unreferenced upload")
+				failed++
+				continue
+			}
+			if err := uploadedObjectStore.DeleteUploadedObject(ctx, object.Key); err != nil {
+				return result, err
+			}
+			result.DeletedCount++
+			result.FreedBytes += object.Size
+			result.Keys = append(result.Keys, object.Key)
+		}
+		result.FailedCount += failed
+
+		// Failed objects come back in the next lookup, so stop rather than
+		// retry them within this run.
+		if len(objects) < constant.UploadGCBatchSize || failed > 0 {
+			break
+		}
+	}
+
+	if result.DeletedCount > 0 || result.FailedCount > 0 {
+		logger.WithFields(logrus.Fields{
+			"count":  result.DeletedCount,
+			"bytes":  result.FreedBytes,
+			"failed": result.FailedCount,
+		}).Info("collected unreferenced uploads")
+	}
+
+	return result, nil
+}
@@ -3,10 +3,16 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/response"
+	"github.com/zeirash/recapo/arion/common/storage"
 	mock_store "github.com/zeirash/recapo/arion/mock/store"
 	"github.com/zeirash/recapo/arion/model"
 	"github.com/zeirash/recapo/arion/store"
@@ -193,3 +199,150 @@ func Test_sysservice_GetSystemPayments(t *testing.T) {
 		})
 	}
 }
+
+// failingDeleteStorage fails to delete the objects under the keys in fail.
+type failingDeleteStorage struct {
+	*storage.Memory
+	fail map[string]bool
+}
+
+func (f failingDeleteStorage) Delete(ctx context.Context, key string) error {
+	if f.fail[key] {
+		return errors.New("storage unavailable")
+	}
+	return f.Memory.Delete(ctx, key)
+}
+
+func Test_sysservice_CollectUnreferencedUploads(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	// fullBatch is a batch of unreferenced objects as large as a lookup returns.
+	fullBatch := make([]model.UploadedObject, constant.UploadGCBatchSize)
+	fullBatchKeys := make([]string, len(fullBatch))
+	for i := range fullBatch {
+		fullBatchKeys[i] = fmt.Sprintf("products/%d_full.webp", i)
+		fullBatch[i] = model.UploadedObject{Key: fullBatchKeys[i], Size: 1, CreatedAt: fixedTime}
+	}
+
+	tests := []struct {
+		name      string
+		stored    []string
+		fail      map[string]bool
+		mockSetup func(m *mock_store.MockUploadedObjectStore)
+		want      response.SystemUploadGCData
+		wantLeft  []string
+		wantErr   bool
+	}{
+		{
+			name:   "deletes unreferenced objects and reports what was freed",
+			stored: []string{"products/a_full.webp", "refunds/b.jpg", "logos/kept.png"},
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().GetUnreferencedUploadedObjects(gomock.Any(), gomock.Any(), constant.UploadGCBatchSize).
+					DoAndReturn(func(_ context.Context, createdBefore time.Time, _ int) ([]model.UploadedObject, error) {
+						if d := time.Until(createdBefore) + constant.UploadGCMinAge; d < -time.Minute || d > time.Minute {
+							t.Errorf("GetUnreferencedUploadedObjects() createdBefore = %v, want UploadGCMinAge ago", createdBefore)
+						}
+						return []model.UploadedObject{
+							{Key: "products/a_full.webp", Size: 2048, CreatedAt: fixedTime},
+							{Key: "refunds/b.jpg", Size: 512, CreatedAt: fixedTime},
+							{Key: "refunds/gone.jpg", Size: 100, CreatedAt: fixedTime},
+						}, nil
+					})
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "products/a_full.webp").Return(nil)
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "refunds/b.jpg").Return(nil)
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "refunds/gone.jpg").Return(nil)
+			},
+			want: response.SystemUploadGCData{
+				DeletedCount: 3,
+				FreedBytes:   2660,
+				Keys:         []string{"products/a_full.webp", "refunds/b.jpg", "refunds/gone.jpg"},
+			},
+			wantLeft: []string{"logos/kept.png"},
+		},
+		{
+			name:   "keeps the record of an object that can't be deleted",
+			stored: []string{"products/a_full.webp", "refunds/b.jpg"},
+			fail:   map[string]bool{"products/a_full.webp": true},
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().GetUnreferencedUploadedObjects(gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.UploadedObject{
+					{Key: "products/a_full.webp", Size: 2048, CreatedAt: fixedTime},
+					{Key: "refunds/b.jpg", Size: 512, CreatedAt: fixedTime},
+				}, nil)
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), "refunds/b.jpg").Return(nil)
+			},
+			want: response.SystemUploadGCData{
+				DeletedCount: 1,
+				FreedBytes:   512,
+				FailedCount:  1,
+				Keys:         []string{"refunds/b.jpg"},
+			},
+			wantLeft: []string{"products/a_full.webp"},
+		},
+		{
+			name: "looks up the next batch after a full one",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				gomock.InOrder(
+					m.EXPECT().GetUnreferencedUploadedObjects(gomock.Any(), gomock.Any(), gomock.Any()).Return(fullBatch, nil),
+					m.EXPECT().GetUnreferencedUploadedObjects(gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.UploadedObject{
+						{Key: "refunds/b.jpg", Size: 512, CreatedAt: fixedTime},
+					}, nil),
+				)
+				m.EXPECT().DeleteUploadedObject(gomock.Any(), gomock.Any()).Return(nil).Times(len(fullBatch) + 1)
+			},
+			want: response.SystemUploadGCData{
+				DeletedCount: len(fullBatch) + 1,
+				FreedBytes:   int64(len(fullBatch)) + 512,
+				Keys:         append(fullBatchKeys, "refunds/b.jpg"),
+			},
+		},
+		{
+			name: "nothing to collect",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().GetUnreferencedUploadedObjects(gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.UploadedObject{}, nil)
+			},
+			want: response.SystemUploadGCData{Keys: []string{}},
+		},
+		{
+			name: "returns error on store failure",
+			mockSetup: func(m *mock_store.MockUploadedObjectStore) {
+				m.EXPECT().GetUnreferencedUploadedObjects(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldBackend, oldStore := storageBackend, uploadedObjectStore
+			defer func() { storageBackend, uploadedObjectStore = oldBackend, oldStore }()
+
+			backend := storage.NewMemory(storage.LocalURLPrefix)
+			for _, key := range tt.stored {
+				backend.Put(context.Background(), key, "image/webp", []byte("x"))
+			}
+			storageBackend = failingDeleteStorage{Memory: backend, fail: tt.fail}
+			m := mock_store.NewMockUploadedObjectStore(ctrl)
+			tt.mockSetup(m)
+			uploadedObjectStore = m
+
+			svc := &sysservice{}
+			got, err := svc.CollectUnreferencedUploads(context.Background())
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CollectUnreferencedUploads() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CollectUnreferencedUploads() = %+v, want %+v", got, tt.want)
+			}
+			if left := backend.Keys(); strings.Join(left, ",") != strings.Join(tt.wantLeft, ",") {
+				t.Errorf("stored after CollectUnreferencedUploads() = %v, want %v", left, tt.wantLeft)
+			}
+		})
+	}
+}
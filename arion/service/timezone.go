@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+// shopLocation returns the timezone the shop's dates are read in.
+func shopLocation(ctx context.Context, shopID int) (*time.Location, error) {
+	shop, err := shopStore.GetShopByID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if shop == nil {
+		return nil, errors.New(apierr.ErrShopNotFound)
+	}
+	return loadShopLocation(shop), nil
+}
+
+// loadShopLocation loads the shop's timezone, falling back to
+// DefaultShopTimezone for a missing shop or an empty or unknown name.
+func loadShopLocation(shop *model.Shop) *time.Location {
+	if shop != nil && shop.Timezone != "" {
+		if loc, err := time.LoadLocation(shop.Timezone); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(constant.DefaultShopTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// validTimezone reports whether name is an IANA timezone a shop can use.
+// "Local" is the server's own zone and is refused.
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// shopDateRange turns the calendar dates of opts into instants in the shop's
+// timezone, see localDateRange. The shop is only looked up when a date is set.
+func shopDateRange(ctx context.Context, shopID int, opts *model.OrderFilterOptions) error {
+	if opts.DateFrom == nil && opts.DateTo == nil {
+		return nil
+	}
+
+	loc, err := shopLocation(ctx, shopID)
+	if err != nil {
+		return err
+	}
+	localDateRange(opts, loc)
+	return nil
+}
+
+// localDateRange turns the calendar dates of opts, as parsed from the
+// request, into the instants that bound them in loc. DateFrom becomes the
+// start of its local day and DateTo the start of the day after, which stores
+// compare exclusively. Both are returned in UTC.
+func localDateRange(opts *model.OrderFilterOptions, loc *time.Location) {
+	opts.DateFrom, opts.DateTo = localDateBounds(opts.DateFrom, opts.DateTo, loc)
+}
+
+// localDateBounds is localDateRange for a from and to pair of any filter.
+func localDateBounds(from, to *time.Time, loc *time.Location) (*time.Time, *time.Time) {
+	if from != nil {
+		f := localDate(*from, loc).UTC()
+		from = &f
+	}
+	if to != nil {
+		t := localDate(*to, loc).AddDate(0, 0, 1).UTC()
+		to = &t
+	}
+	return from, to
+}
+
+// localDate returns local midnight of t's calendar date.
+func localDate(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
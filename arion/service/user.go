@@ -61,6 +61,10 @@ func NewUserService() UserService {
 		shopStore = store.NewShopStore()
 	}
 
+	if shareLinkStore == nil {
+		shareLinkStore = store.NewShareLinkStore()
+	}
+
 	if subscriptionService == nil {
 		subscriptionService = NewSubscriptionService()
 	}
@@ -110,6 +114,10 @@ func (u *uservice) UserLogin(ctx context.Context, email, password string) (respo
 		return response.TokenResponse{}, errors.New(apierr.ErrPasswordIncorrect)
 	}
 
+	if err := checkShopOpen(ctx, user.ShopID); err != nil {
+		return response.TokenResponse{}, err
+	}
+
 	sessionToken, err := generateSessionToken()
 	if err != nil {
 		return response.TokenResponse{}, err
@@ -221,6 +229,11 @@ func (u *uservice) UserRegister(ctx context.Context, name, email, password strin
 		return response.TokenResponse{}, err
 	}
 
+	// The shop's default storefront link.
+	if _, err := shareLinkStore.CreateShareLink(ctx, tx, store.CreateShareLinkInput{ShopID: shop.ID}); err != nil {
+		return response.TokenResponse{}, err
+	}
+
 	newUser, err := userStore.CreateUser(ctx, tx, name, email, string(encryptedPassword), constant.RoleOwner, shop.ID)
 	if err != nil {
 		return response.TokenResponse{}, err
@@ -55,6 +55,7 @@ func Test_uservice_UserLogin(t *testing.T) {
 		name       string
 		input      input
 		mockSetup  func(ctrl *gomock.Controller) (*mock_store.MockUserStore, *mock_store.MockTokenStore)
+		shop       *model.Shop
 		wantResult response.TokenResponse
 		wantErr    bool
 	}{
@@ -233,6 +234,31 @@ func Test_uservice_UserLogin(t *testing.T) {
 			wantResult: response.TokenResponse{},
 			wantErr: true,
 		},
+		{
+			name: "login to a closed shop returns error",
+			input: input{
+				email:    "john@example.com",
+				password: "password123",
+			},
+			mockSetup: func(ctrl *gomock.Controller) (*mock_store.MockUserStore, *mock_store.MockTokenStore) {
+				mockUser := mock_store.NewMockUserStore(ctrl)
+				mockToken := mock_store.NewMockTokenStore(ctrl)
+
+				mockUser.EXPECT().
+					GetUserByEmail(gomock.Any(), "john@example.com").
+					Return(&model.User{
+						ID:       1,
+						ShopID:   10,
+						Email:    "john@example.com",
+						Password: string(hashedPassword),
+					}, nil)
+
+				return mockUser, mockToken
+			},
+			shop:       &model.Shop{ID: 10, ClosedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+			wantResult: response.TokenResponse{},
+			wantErr:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -240,12 +266,15 @@ func Test_uservice_UserLogin(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldUserStore, oldTokenStore := userStore, tokenStore
-			defer func() { userStore, tokenStore = oldUserStore, oldTokenStore }()
+			oldUserStore, oldTokenStore, oldShopStore := userStore, tokenStore, shopStore
+			defer func() { userStore, tokenStore, shopStore = oldUserStore, oldTokenStore, oldShopStore }()
 
 			mockUser, mockToken := tt.mockSetup(ctrl)
 			userStore = mockUser
 			tokenStore = mockToken
+			mockShop := mock_store.NewMockShopStore(ctrl)
+			mockShop.EXPECT().GetShopByID(gomock.Any(), gomock.Any()).Return(tt.shop, nil).AnyTimes()
+			shopStore = mockShop
 			cfg = config.Config{SecretKey: "testsecret"}
 
 			var u uservice
@@ -612,6 +641,44 @@ func Test_uservice_UserRegister(t *testing.T) {
 			wantResult: response.TokenResponse{},
 			wantErr: true,
 		},
+		{
+			name: "register returns error on CreateShareLink failure",
+			input: input{
+				name:     "John Doe",
+				email:    "john@example.com",
+				password: "password123",
+			},
+			mockSetup: func(ctrl *gomock.Controller) {
+				mockUser := mock_store.NewMockUserStore(ctrl)
+				mockUser.EXPECT().
+					GetUserByEmail(gomock.Any(), "john@example.com").
+					Return(nil, nil)
+				userStore = mockUser
+
+				mockTx := mock_database.NewMockTx(ctrl)
+				mockTx.EXPECT().Rollback().Return(nil)
+
+				mockDB := mock_database.NewMockDB(ctrl)
+				mockDB.EXPECT().
+					Begin().
+					Return(mockTx, nil)
+				dbGetter = func() database.DB { return mockDB }
+
+				mockShop := mock_store.NewMockShopStore(ctrl)
+				mockShop.EXPECT().
+					CreateShop(gomock.Any(), mockTx, "John Doe's Shop").
+					Return(&model.Shop{ID: 1, Name: "John Doe's Shop", CreatedAt: fixedTime}, nil)
+				shopStore = mockShop
+
+				mockShareLink := mock_store.NewMockShareLinkStore(ctrl)
+				mockShareLink.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(nil, errors.New("share link creation error"))
+				shareLinkStore = mockShareLink
+			},
+			wantResult: response.TokenResponse{},
+			wantErr:    true,
+		},
 		{
 			name: "register returns error on CreateUser failure",
 			input: input{
@@ -643,6 +710,12 @@ func Test_uservice_UserRegister(t *testing.T) {
 					CreateShop(gomock.Any(), mockTx, "John Doe's Shop").
 					Return(&model.Shop{ID: 1, Name: "John Doe's Shop", CreatedAt: fixedTime}, nil)
 				shopStore = mockShop
+
+				mockShareLink := mock_store.NewMockShareLinkStore(ctrl)
+				mockShareLink.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "abc123xyz789"}, nil)
+				shareLinkStore = mockShareLink
 			},
 			wantResult: response.TokenResponse{},
 			wantErr:    true,
@@ -679,6 +752,12 @@ func Test_uservice_UserRegister(t *testing.T) {
 					CreateShop(gomock.Any(), mockTx, "John Doe's Shop").
 					Return(&model.Shop{ID: 1, Name: "John Doe's Shop", CreatedAt: fixedTime}, nil)
 				shopStore = mockShop
+
+				mockShareLink := mock_store.NewMockShareLinkStore(ctrl)
+				mockShareLink.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "abc123xyz789"}, nil)
+				shareLinkStore = mockShareLink
 			},
 			wantResult: response.TokenResponse{},
 			wantErr: true,
@@ -719,6 +798,12 @@ func Test_uservice_UserRegister(t *testing.T) {
 					Return(&model.Shop{ID: 1, Name: "John Doe's Shop", CreatedAt: fixedTime}, nil)
 				shopStore = mockShop
 
+				mockShareLink := mock_store.NewMockShareLinkStore(ctrl)
+				mockShareLink.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "abc123xyz789"}, nil)
+				shareLinkStore = mockShareLink
+
 				mockToken := mock_store.NewMockTokenStore(ctrl)
 				mockToken.EXPECT().
 					CreateAccessToken(gomock.Any(), gomock.Any(), "testsecret", 2).
@@ -764,6 +849,12 @@ func Test_uservice_UserRegister(t *testing.T) {
 					Return(&model.Shop{ID: 1, Name: "John Doe's Shop", CreatedAt: fixedTime}, nil)
 				shopStore = mockShop
 
+				mockShareLink := mock_store.NewMockShareLinkStore(ctrl)
+				mockShareLink.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "abc123xyz789"}, nil)
+				shareLinkStore = mockShareLink
+
 				mockToken := mock_store.NewMockTokenStore(ctrl)
 				mockToken.EXPECT().
 					CreateAccessToken(gomock.Any(), gomock.Any(), "testsecret", 2).
@@ -812,6 +903,12 @@ func Test_uservice_UserRegister(t *testing.T) {
 					Return(&model.Shop{ID: 1, Name: "John Doe's Shop", CreatedAt: fixedTime}, nil)
 				shopStore = mockShop
 
+				mockShareLink := mock_store.NewMockShareLinkStore(ctrl)
+				mockShareLink.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "abc123xyz789"}, nil)
+				shareLinkStore = mockShareLink
+
 				mockToken := mock_store.NewMockTokenStore(ctrl)
 				mockToken.EXPECT().
 					CreateAccessToken(gomock.Any(), gomock.Any(), "testsecret", 2).
@@ -863,6 +960,12 @@ func Test_uservice_UserRegister(t *testing.T) {
 					Return(&model.Shop{ID: 1, Name: "John Doe's Shop", CreatedAt: fixedTime}, nil)
 				shopStore = mockShop
 
+				mockShareLink := mock_store.NewMockShareLinkStore(ctrl)
+				mockShareLink.EXPECT().
+					CreateShareLink(gomock.Any(), mockTx, store.CreateShareLinkInput{ShopID: 1}).
+					Return(&model.ShareLink{ID: 1, ShopID: 1, Token: "abc123xyz789"}, nil)
+				shareLinkStore = mockShareLink
+
 				mockToken := mock_store.NewMockTokenStore(ctrl)
 				mockToken.EXPECT().
 					CreateAccessToken(gomock.Any(), gomock.Any(), "testsecret", 2).
@@ -887,11 +990,11 @@ func Test_uservice_UserRegister(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			oldUserStore, oldShopStore, oldTokenStore := userStore, shopStore, tokenStore
+			oldUserStore, oldShopStore, oldShareLinkStore, oldTokenStore := userStore, shopStore, shareLinkStore, tokenStore
 			oldDBGetter := dbGetter
 			oldSubscriptionService := subscriptionService
 			defer func() {
-				userStore, shopStore, tokenStore = oldUserStore, oldShopStore, oldTokenStore
+				userStore, shopStore, shareLinkStore, tokenStore = oldUserStore, oldShopStore, oldShareLinkStore, oldTokenStore
 				dbGetter = oldDBGetter
 				subscriptionService = oldSubscriptionService
 			}()
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+)
+
+type (
+	// ArchiveStore reads and writes the tables of a shop archive. Rows travel
+	// as JSON objects keyed by column name, as produced by row_to_json, so a
+	// column added to a table is archived and restored without changes here.
+	ArchiveStore interface {
+		StreamRows(ctx context.Context, shopID int, table string, fn func(row []byte) error) error
+		GetColumns(ctx context.Context, tx database.Tx, table string) ([]string, error)
+		InsertRow(ctx context.Context, tx database.Tx, table string, columns []string, row []byte) (int, error)
+		UpdateSettings(ctx context.Context, tx database.Tx, shopID int, columns []string, row []byte) error
+		IsShopEmpty(ctx context.Context, shopID int) (bool, error)
+	}
+
+	archive struct {
+		db *sql.DB
+	}
+
+	archiveTable struct {
+		name   string // database table
+		scope  string // condition selecting the rows of shop $1
+		key    string // sort column; "id" when a restored row gets a new id
+		parent string // column pointing at a row of the same table, if any
+	}
+)
+
+var archiveTables = map[string]archiveTable{
+	constant.ArchiveTableSettings:          {name: "shops", scope: "t.id = $1", key: "id"},
+	constant.ArchiveTableDocumentSequences: {name: "document_sequences", scope: "t.shop_id = $1", key: "doc_type"},
+	constant.ArchiveTableDPRules:           {name: "dp_rules", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableCustomers:         {name: "customers", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableCustomerAddresses: {name: "customer_addresses", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableProductCategories: {name: "product_categories", scope: "t.shop_id = $1", key: "id", parent: "parent_id"},
+	constant.ArchiveTableProducts:          {name: "products", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableProductImages:     {name: "product_images", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableOrders:            {name: "orders", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableOrderItems:        {name: "order_items", scope: "t.order_id IN (SELECT id FROM orders WHERE shop_id = $1)", key: "id"},
+	constant.ArchiveTableOrderPayments:     {name: "order_payments", scope: "t.order_id IN (SELECT id FROM orders WHERE shop_id = $1)", key: "id"},
+	constant.ArchiveTableOrderRefunds:      {name: "order_refunds", scope: "t.order_id IN (SELECT id FROM orders WHERE shop_id = $1)", key: "id"},
+	constant.ArchiveTableCustomerCredits:   {name: "customer_credits", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableTempOrders:        {name: "temp_orders", scope: "t.shop_id = $1", key: "id"},
+	constant.ArchiveTableTempOrderItems:    {name: "temp_order_items", scope: "t.temp_order_id IN (SELECT id FROM temp_orders WHERE shop_id = $1)", key: "id"},
+}
+
+func NewArchiveStore() ArchiveStore {
+	return &archive{db: database.GetDB()}
+}
+
+// NewArchiveStoreWithDB creates an ArchiveStore with a custom db connection (for testing)
+func NewArchiveStoreWithDB(db *sql.DB) ArchiveStore {
+	return &archive{db: db}
+}
+
+func lookupArchiveTable(table string) (archiveTable, error) {
+	t, ok := archiveTables[table]
+	if !ok {
+		return archiveTable{}, fmt.Errorf("unknown archive table %q", table)
+	}
+	return t, nil
+}
+
+// quoteColumns returns the columns as a quoted, comma separated list.
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = pq.QuoteIdentifier(c)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// StreamRows hands the rows of an archive table that belong to the shop to
+// fn one at a time, as JSON objects. An error from fn stops the query and is
+// returned.
+func (a *archive) StreamRows(ctx context.Context, shopID int, table string, fn func(row []byte) error) error {
+	t, err := lookupArchiveTable(table)
+	if err != nil {
+		return err
+	}
+	// Rows pointing at a row of their own table come after the rows that
+	// don't. Categories nest one level deep, so a restored row's parent is
+	// always restored before it.
+	order := "t." + t.key
+	if t.parent != "" {
+		order = "t." + t.parent + " IS NOT NULL, " + order
+	}
+	q := `
+		SELECT row_to_json(t)
+		FROM ` + t.name + ` t
+		WHERE ` + t.scope + `
+		ORDER BY ` + order
+
+	rows, err := a.db.QueryContext(ctx, q, shopID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetColumns returns the columns the archive table has in this database.
+func (a *archive) GetColumns(ctx context.Context, tx database.Tx, table string) ([]string, error) {
+	t, err := lookupArchiveTable(table)
+	if err != nil {
+		return nil, err
+	}
+	q := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position
+	`
+
+	var rows *sql.Rows
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, q, t.name)
+	} else {
+		rows, err = a.db.QueryContext(ctx, q, t.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []string{}
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// InsertRow inserts the given columns of a JSON row into the archive table,
+// converting values to the column types. It returns the id of the new row,
+// or 0 for tables keyed by something else.
+func (a *archive) InsertRow(ctx context.Context, tx database.Tx, table string, columns []string, row []byte) (int, error) {
+	t, err := lookupArchiveTable(table)
+	if err != nil {
+		return 0, err
+	}
+	cols := quoteColumns(columns)
+	q := `
+		INSERT INTO ` + t.name + ` (` + cols + `)
+		SELECT ` + cols + ` FROM json_populate_record(NULL::` + t.name + `, $1)`
+
+	if t.key != "id" {
+		if tx != nil {
+			_, err = tx.ExecContext(ctx, q, string(row))
+		} else {
+			_, err = a.db.ExecContext(ctx, q, string(row))
+		}
+		return 0, err
+	}
+
+	q += `
+		RETURNING id`
+	var id int
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, q, string(row)).Scan(&id)
+	} else {
+		err = a.db.QueryRowContext(ctx, q, string(row)).Scan(&id)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdateSettings copies the given columns of an archived shop row onto the
+// shop.
+func (a *archive) UpdateSettings(ctx context.Context, tx database.Tx, shopID int, columns []string, row []byte) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	cols := quoteColumns(columns)
+	q := `
+		UPDATE shops
+		SET (` + cols + `) = (SELECT ` + cols + ` FROM json_populate_record(NULL::shops, $2))
+		WHERE id = $1
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, shopID, string(row))
+	} else {
+		_, err = a.db.ExecContext(ctx, q, shopID, string(row))
+	}
+	return err
+}
+
+// IsShopEmpty reports whether the shop has no customers, products, orders or
+// temp orders yet.
+func (a *archive) IsShopEmpty(ctx context.Context, shopID int) (bool, error) {
+	q := `
+		SELECT NOT EXISTS (SELECT 1 FROM customers WHERE shop_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM products WHERE shop_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM orders WHERE shop_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM temp_orders WHERE shop_id = $1)
+	`
+
+	var empty bool
+	if err := a.db.QueryRowContext(ctx, q, shopID).Scan(&empty); err != nil {
+		return false, err
+	}
+	return empty, nil
+}
@@ -0,0 +1,303 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/common/constant"
+)
+
+func Test_archive_StreamRows(t *testing.T) {
+	tests := []struct {
+		name      string
+		table     string
+		fnErr     error
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:  "streams the shop's rows as JSON",
+			table: constant.ArchiveTableCustomers,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"row_to_json"}).
+					AddRow([]byte(`{"id":1,"name":"Budi"}`)).
+					AddRow([]byte(`{"id":2,"name":"Siti"}`))
+				mock.ExpectQuery(`SELECT row_to_json\(t\)\s+FROM customers t\s+WHERE t\.shop_id = \$1\s+ORDER BY t\.id`).
+					WithArgs(1).
+					WillReturnRows(rows)
+			},
+			want: []string{`{"id":1,"name":"Budi"}`, `{"id":2,"name":"Siti"}`},
+		},
+		{
+			name:  "scopes child tables through their parent",
+			table: constant.ArchiveTableOrderItems,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT row_to_json\(t\)\s+FROM order_items t\s+WHERE t\.order_id IN \(SELECT id FROM orders WHERE shop_id = \$1\)\s+ORDER BY t\.id`).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"row_to_json"}))
+			},
+		},
+		{
+			name:  "settings come from the shop row",
+			table: constant.ArchiveTableSettings,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT row_to_json\(t\)\s+FROM shops t\s+WHERE t\.id = \$1\s+ORDER BY t\.id`).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"row_to_json"}).AddRow([]byte(`{"id":1}`)))
+			},
+			want: []string{`{"id":1}`},
+		},
+		{
+			name:  "stops at the first callback error",
+			table: constant.ArchiveTableCustomers,
+			fnErr: errors.New("client gone"),
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"row_to_json"}).
+					AddRow([]byte(`{"id":1}`)).
+					AddRow([]byte(`{"id":2}`))
+				mock.ExpectQuery(`SELECT row_to_json`).WithArgs(1).WillReturnRows(rows)
+			},
+			want:    []string{`{"id":1}`},
+			wantErr: true,
+		},
+		{
+			name:      "unknown table",
+			table:     "users",
+			mockSetup: func(mock sqlmock.Sqlmock) {},
+			wantErr:   true,
+		},
+		{
+			name:  "returns error on database failure",
+			table: constant.ArchiveTableCustomers,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT row_to_json`).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewArchiveStoreWithDB(db)
+			var got []string
+			gotErr := s.StreamRows(context.Background(), 1, tt.table, func(row []byte) error {
+				got = append(got, string(row))
+				return tt.fnErr
+			})
+
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("StreamRows() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StreamRows() rows = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_archive_GetColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT column_name\s+FROM information_schema\.columns\s+WHERE table_schema = current_schema\(\) AND table_name = \$1\s+ORDER BY ordinal_position`).
+		WithArgs("temp_orders").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("shop_id").AddRow("customer_name"))
+
+	got, err := NewArchiveStoreWithDB(db).GetColumns(context.Background(), nil, constant.ArchiveTableTempOrders)
+	if err != nil {
+		t.Fatalf("GetColumns() error = %v", err)
+	}
+	if want := []string{"id", "shop_id", "customer_name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetColumns() = %v, want %v", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_archive_InsertRow(t *testing.T) {
+	tests := []struct {
+		name      string
+		table     string
+		columns   []string
+		row       string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      int
+		wantErr   bool
+	}{
+		{
+			name:    "inserts the row and returns its new id",
+			table:   constant.ArchiveTableCustomers,
+			columns: []string{"shop_id", "name", "tags"},
+			row:     `{"shop_id":8,"name":"Budi","tags":["vip"]}`,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO customers \("shop_id", "name", "tags"\)\s+SELECT "shop_id", "name", "tags" FROM json_populate_record\(NULL::customers, \$1\)\s+RETURNING id`).
+					WithArgs(`{"shop_id":8,"name":"Budi","tags":["vip"]}`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(101))
+			},
+			want: 101,
+		},
+		{
+			name:    "tables without an id are only inserted",
+			table:   constant.ArchiveTableDocumentSequences,
+			columns: []string{"shop_id", "doc_type", "last_value"},
+			row:     `{"shop_id":8,"doc_type":"invoice","last_value":12}`,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`INSERT INTO document_sequences \("shop_id", "doc_type", "last_value"\)\s+SELECT "shop_id", "doc_type", "last_value" FROM json_populate_record\(NULL::document_sequences, \$1\)$`).
+					WithArgs(`{"shop_id":8,"doc_type":"invoice","last_value":12}`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:    "returns error on database failure",
+			table:   constant.ArchiveTableProducts,
+			columns: []string{"shop_id"},
+			row:     `{"shop_id":8}`,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO products`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			got, err := NewArchiveStoreWithDB(db).InsertRow(context.Background(), nil, tt.table, tt.columns, []byte(tt.row))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InsertRow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("InsertRow() = %d, want %d", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_archive_UpdateSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		columns   []string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name:    "copies the columns onto the shop",
+			columns: []string{"name", "currency"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE shops\s+SET \("name", "currency"\) = \(SELECT "name", "currency" FROM json_populate_record\(NULL::shops, \$2\)\)\s+WHERE id = \$1`).
+					WithArgs(8, `{"name":"Toko","currency":"IDR"}`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:      "nothing to copy",
+			mockSetup: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name:    "returns error on database failure",
+			columns: []string{"name"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE shops`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			err = NewArchiveStoreWithDB(db).UpdateSettings(context.Background(), nil, 8, tt.columns, []byte(`{"name":"Toko","currency":"IDR"}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UpdateSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_archive_IsShopEmpty(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name: "shop without data",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT NOT EXISTS \(SELECT 1 FROM customers WHERE shop_id = \$1\)\s+AND NOT EXISTS \(SELECT 1 FROM products WHERE shop_id = \$1\)\s+AND NOT EXISTS \(SELECT 1 FROM orders WHERE shop_id = \$1\)\s+AND NOT EXISTS \(SELECT 1 FROM temp_orders WHERE shop_id = \$1\)`).
+					WithArgs(8).
+					WillReturnRows(sqlmock.NewRows([]string{"empty"}).AddRow(true))
+			},
+			want: true,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT NOT EXISTS`).WithArgs(8).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			got, err := NewArchiveStoreWithDB(db).IsShopEmpty(context.Background(), 8)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IsShopEmpty() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsShopEmpty() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
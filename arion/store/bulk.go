@@ -0,0 +1,27 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bulkValues returns the VALUES list for rows of len(casts) columns, numbering
+// the placeholders from $1 row by row. A non-empty cast is appended to its
+// placeholder, which UPDATE ... FROM (VALUES ...) needs to type NULLs.
+func bulkValues(rows int, casts ...string) string {
+	values := make([]string, 0, rows)
+	n := 1
+	for i := 0; i < rows; i++ {
+		cols := make([]string, 0, len(casts))
+		for _, cast := range casts {
+			col := fmt.Sprintf("$%d", n)
+			if cast != "" {
+				col += "::" + cast
+			}
+			cols = append(cols, col)
+			n++
+		}
+		values = append(values, "("+strings.Join(cols, ", ")+")")
+	}
+	return strings.Join(values, ", ")
+}
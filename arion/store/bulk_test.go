@@ -0,0 +1,24 @@
+package store
+
+import "testing"
+
+func Test_bulkValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  int
+		casts []string
+		want  string
+	}{
+		{name: "single row", rows: 1, casts: []string{"", ""}, want: "($1, $2)"},
+		{name: "numbers continue across rows", rows: 3, casts: []string{"", "", ""}, want: "($1, $2, $3), ($4, $5, $6), ($7, $8, $9)"},
+		{name: "casts", rows: 2, casts: []string{"int", "", "text"}, want: "($1::int, $2, $3::text), ($4::int, $5, $6::text)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bulkValues(tt.rows, tt.casts...); got != tt.want {
+				t.Errorf("bulkValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
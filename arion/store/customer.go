@@ -12,20 +12,31 @@ import (
 
 	"github.com/lib/pq"
 	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/database"
 	"github.com/zeirash/recapo/arion/model"
 )
 
 var ErrDuplicatePhone = errors.New(apierr.ErrCustomerPhoneExists)
 
+// ErrMergeOrders is returned by MergeCustomers when more than one of the
+// customers has an open order, as a customer has at most one.
+var ErrMergeOrders = errors.New(apierr.ErrCustomerMergeOrders)
+
 type (
 	CustomerStore interface {
 		GetCustomerByID(ctx context.Context, id int, shopID ...int) (*model.Customer, error)
 		GetCustomerByPhone(ctx context.Context, phone string, shopID int) (*model.Customer, error)
-		GetCustomersByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]model.Customer, error)
+		GetCustomersByShopID(ctx context.Context, shopID int, filter model.CustomerFilterOptions) ([]model.Customer, error)
+		GetCustomerStats(ctx context.Context, id int) (*model.CustomerStats, error)
 		CreateCustomer(ctx context.Context, input CreateCustomerInput) (*model.Customer, error)
 		UpdateCustomer(ctx context.Context, id int, input UpdateCustomerInput) (*model.Customer, error)
 		DeleteCustomerByID(ctx context.Context, id int) error
+		MergeCustomers(ctx context.Context, tx database.Tx, shopID, survivorID int, duplicateIDs []int) error
+		GetCustomersByPhones(ctx context.Context, shopID int, phones []string) ([]model.Customer, error)
+		BulkCreateCustomers(ctx context.Context, tx database.Tx, inputs []CreateCustomerInput) error
+		BulkUpdateCustomers(ctx context.Context, tx database.Tx, inputs []BulkUpdateCustomerInput) error
+		AnonymizeCustomer(ctx context.Context, tx database.Tx, shopID, id int) error
 	}
 
 	customer struct {
@@ -40,12 +51,41 @@ type (
 	}
 
 	UpdateCustomerInput struct {
-		Name    *string
-		Phone   *string
+		Name     *string
+		Phone    *string
+		Address  *string
+		Priority *int
+		Tags     *[]string
+		Blocked  *bool
+	}
+
+	// BulkUpdateCustomerInput overwrites the name of a customer; a nil Address
+	// keeps the current one.
+	BulkUpdateCustomerInput struct {
+		ID      int
+		Name    string
 		Address *string
 	}
 )
 
+// customerStatsQuery aggregates the non-cancelled orders of the customer
+// whose id is the %s expression. $2 and $3 are the cancelled order status
+// and the paid payment status.
+const customerStatsQuery = `
+	SELECT COUNT(*) AS order_count,
+		COALESCE(SUM(o.total_price), 0) AS total_spent,
+		COALESCE(SUM(CASE WHEN o.payment_status = $3 THEN 0 ELSE GREATEST(o.total_price + o.unique_code - COALESCE(p.paid, 0), 0) END), 0) AS outstanding,
+		MIN(o.created_at) AS first_order_at,
+		MAX(o.created_at) AS last_order_at
+	FROM orders o
+	LEFT JOIN (
+		SELECT order_id, SUM(amount) as paid
+		FROM order_payments
+		GROUP BY order_id
+	) p ON p.order_id = o.id
+	WHERE o.customer_id = %s AND o.status != $2
+`
+
 func NewCustomerStore() CustomerStore {
 	return &customer{db: database.GetDB()}
 }
@@ -59,7 +99,7 @@ func (c *customer) GetCustomerByID(ctx context.Context, id int, shopID ...int) (
 	criteria := []interface{}{id}
 
 	q := `
-		SELECT id, name, phone, address, created_at, updated_at, deleted_at
+		SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at
 		FROM customers
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -70,7 +110,7 @@ func (c *customer) GetCustomerByID(ctx context.Context, id int, shopID ...int) (
 	}
 
 	var customer model.Customer
-	err := c.db.QueryRowContext(ctx, q, criteria...).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt)
+	err := c.db.QueryRowContext(ctx, q, criteria...).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.Priority, pq.Array(&customer.Tags), &customer.Blocked, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -83,12 +123,12 @@ func (c *customer) GetCustomerByID(ctx context.Context, id int, shopID ...int) (
 
 func (c *customer) GetCustomerByPhone(ctx context.Context, phone string, shopID int) (*model.Customer, error) {
 	q := `
-		SELECT id, name, phone, address, created_at, updated_at, deleted_at
+		SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at
 		FROM customers
 		WHERE phone = $1 AND shop_id = $2 AND deleted_at IS NULL
 	`
 	var customer model.Customer
-	err := c.db.QueryRowContext(ctx, q, phone, shopID).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt)
+	err := c.db.QueryRowContext(ctx, q, phone, shopID).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.Priority, pq.Array(&customer.Tags), &customer.Blocked, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -98,36 +138,85 @@ func (c *customer) GetCustomerByPhone(ctx context.Context, phone string, shopID
 	return &customer, nil
 }
 
-func (c *customer) GetCustomersByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]model.Customer, error) {
-	q := `
-		SELECT id, name, phone, address, created_at, updated_at, deleted_at
-		FROM customers
-		WHERE shop_id = $1 AND deleted_at IS NULL
-	`
-	args := []interface{}{shopID}
+func (c *customer) GetCustomersByShopID(ctx context.Context, shopID int, filter model.CustomerFilterOptions) ([]model.Customer, error) {
+	q := fmt.Sprintf(`
+		SELECT c.id, c.name, c.phone, c.address, c.priority, c.tags, c.blocked, c.created_at, c.updated_at, c.deleted_at,
+			s.order_count, s.total_spent, s.outstanding, s.first_order_at, s.last_order_at
+		FROM customers c
+		CROSS JOIN LATERAL (%s) s
+		WHERE c.shop_id = $1 AND c.deleted_at IS NULL
+	`, fmt.Sprintf(customerStatsQuery, "c.id"))
+	args := []interface{}{shopID, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid}
+	argNum := 4
 
 	if filter.SearchQuery != nil && strings.TrimSpace(*filter.SearchQuery) != "" {
-		q += ` AND (name ILIKE $2 OR phone ILIKE $2)`
+		q += fmt.Sprintf(` AND (c.name ILIKE $%d OR c.phone ILIKE $%d)`, argNum, argNum)
 		args = append(args, "%"+strings.TrimSpace(*filter.SearchQuery)+"%")
+		argNum++
+	}
+	if len(filter.Tags) > 0 {
+		tags := make([]string, 0, len(filter.Tags))
+		for _, tag := range filter.Tags {
+			tags = append(tags, strings.ToLower(tag))
+		}
+		q += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM unnest(c.tags) t WHERE LOWER(t) = ANY($%d))`, argNum)
+		args = append(args, pq.Array(tags))
+		argNum++
+	}
+	if filter.Blocked != nil {
+		q += fmt.Sprintf(` AND c.blocked = $%d`, argNum)
+		args = append(args, *filter.Blocked)
+		argNum++
+	}
+	if filter.MinOrders != nil {
+		q += fmt.Sprintf(` AND s.order_count >= $%d`, argNum)
+		args = append(args, *filter.MinOrders)
+		argNum++
+	}
+	if filter.MinSpent != nil {
+		q += fmt.Sprintf(` AND s.total_spent >= $%d`, argNum)
+		args = append(args, *filter.MinSpent)
+		argNum++
+	}
+	if filter.HasOutstanding != nil {
+		if *filter.HasOutstanding {
+			q += ` AND s.outstanding > 0`
+		} else {
+			q += ` AND s.outstanding = 0`
+		}
+	}
+	if filter.LastOrderFrom != nil {
+		q += fmt.Sprintf(` AND s.last_order_at >= $%d`, argNum)
+		args = append(args, *filter.LastOrderFrom)
+		argNum++
+	}
+	if filter.LastOrderTo != nil {
+		q += fmt.Sprintf(` AND s.last_order_at < $%d`, argNum)
+		args = append(args, *filter.LastOrderTo)
+		argNum++
 	}
 	if filter.Sort != nil {
 		sort := strings.Split(*filter.Sort, ",")
 		if len(sort) == 2 {
 			col, dir := sort[0], strings.ToUpper(sort[1])
-			allowedCols := map[string]bool{"id": true, "name": true, "phone": true, "created_at": true, "updated_at": true}
+			allowedCols := map[string]string{
+				"id": "c.id", "name": "c.name", "phone": "c.phone", "created_at": "c.created_at", "updated_at": "c.updated_at",
+				"order_count": "s.order_count", "total_spent": "s.total_spent", "outstanding": "s.outstanding",
+				"first_order_at": "s.first_order_at", "last_order_at": "s.last_order_at",
+			}
 			if dir != "ASC" && dir != "DESC" {
 				dir = "ASC"
 			}
-			if allowedCols[col] {
+			if expr, ok := allowedCols[col]; ok {
 				nullsOrder := "NULLS LAST"
 				if dir == "ASC" {
 					nullsOrder = "NULLS FIRST"
 				}
 				textCols := map[string]bool{"name": true}
 				if textCols[col] {
-					q += fmt.Sprintf(" ORDER BY LOWER(%s) %s %s", col, dir, nullsOrder)
+					q += fmt.Sprintf(" ORDER BY LOWER(%s) %s %s", expr, dir, nullsOrder)
 				} else {
-					q += fmt.Sprintf(" ORDER BY %s %s %s", col, dir, nullsOrder)
+					q += fmt.Sprintf(" ORDER BY %s %s %s", expr, dir, nullsOrder)
 				}
 			}
 		}
@@ -145,7 +234,8 @@ func (c *customer) GetCustomersByShopID(ctx context.Context, shopID int, filter
 	customers := []model.Customer{}
 	for rows.Next() {
 		var customer model.Customer
-		err := rows.Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt)
+		err := rows.Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.Priority, pq.Array(&customer.Tags), &customer.Blocked, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt,
+			&customer.Stats.OrderCount, &customer.Stats.TotalSpent, &customer.Stats.Outstanding, &customer.Stats.FirstOrderAt, &customer.Stats.LastOrderAt)
 		if err != nil {
 			return nil, err
 		}
@@ -155,6 +245,20 @@ func (c *customer) GetCustomersByShopID(ctx context.Context, shopID int, filter
 	return customers, nil
 }
 
+// GetCustomerStats returns the lifetime stats of a customer. A customer
+// without orders gets zero stats.
+func (c *customer) GetCustomerStats(ctx context.Context, id int) (*model.CustomerStats, error) {
+	q := fmt.Sprintf(customerStatsQuery, "$1")
+
+	var stats model.CustomerStats
+	err := c.db.QueryRowContext(ctx, q, id, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).Scan(&stats.OrderCount, &stats.TotalSpent, &stats.Outstanding, &stats.FirstOrderAt, &stats.LastOrderAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
 func (c *customer) CreateCustomer(ctx context.Context, input CreateCustomerInput) (*model.Customer, error) {
 	now := time.Now()
 	var id int
@@ -208,6 +312,21 @@ func (c *customer) UpdateCustomer(ctx context.Context, id int, input UpdateCusto
 		args = append(args, *input.Address)
 		argNum++
 	}
+	if input.Priority != nil {
+		set = append(set, fmt.Sprintf("priority = $%d", argNum))
+		args = append(args, *input.Priority)
+		argNum++
+	}
+	if input.Tags != nil {
+		set = append(set, fmt.Sprintf("tags = $%d", argNum))
+		args = append(args, pq.Array(*input.Tags))
+		argNum++
+	}
+	if input.Blocked != nil {
+		set = append(set, fmt.Sprintf("blocked = $%d", argNum))
+		args = append(args, *input.Blocked)
+		argNum++
+	}
 
 	set = append(set, "updated_at = now()")
 
@@ -215,10 +334,10 @@ func (c *customer) UpdateCustomer(ctx context.Context, id int, input UpdateCusto
 		UPDATE customers
 		SET %s
 		WHERE id = $1
-		RETURNING id, name, phone, address, created_at, updated_at
+		RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at
 	`, strings.Join(set, ","))
 
-	err := c.db.QueryRowContext(ctx, q, args...).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.CreatedAt, &customer.UpdatedAt)
+	err := c.db.QueryRowContext(ctx, q, args...).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.Priority, pq.Array(&customer.Tags), &customer.Blocked, &customer.CreatedAt, &customer.UpdatedAt)
 	if err != nil {
 		if isUniqueViolation(err) {
 			return nil, ErrDuplicatePhone
@@ -244,6 +363,171 @@ func (c *customer) DeleteCustomerByID(ctx context.Context, id int) error {
 	return nil
 }
 
+// MergeCustomers moves the orders, credit entries and addresses of the
+// duplicate customers to the survivor, then soft-deletes the duplicates. Moved
+// addresses lose their default flag so the survivor keeps its own default.
+// The open orders of all of them are locked first, and ErrMergeOrders is
+// returned when they belong to more than one customer.
+func (c *customer) MergeCustomers(ctx context.Context, tx database.Tx, shopID, survivorID int, duplicateIDs []int) error {
+	q := `
+		SELECT customer_id
+		FROM orders
+		WHERE shop_id = $2 AND (customer_id = $1 OR customer_id = ANY($3)) AND status IN ($4, $5)
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, q, survivorID, shopID, pq.Array(duplicateIDs), constant.OrderStatusCreated, constant.OrderStatusInProgress)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	withOrder := map[int]bool{}
+	for rows.Next() {
+		var customerID int
+		if err := rows.Scan(&customerID); err != nil {
+			return err
+		}
+		withOrder[customerID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(withOrder) > 1 {
+		return ErrMergeOrders
+	}
+
+	queries := []string{
+		`UPDATE orders SET customer_id = $1, updated_at = now() WHERE shop_id = $2 AND customer_id = ANY($3)`,
+		`UPDATE customer_credits SET customer_id = $1 WHERE shop_id = $2 AND customer_id = ANY($3)`,
+		`UPDATE customer_addresses SET customer_id = $1, is_default = FALSE, updated_at = now() WHERE shop_id = $2 AND customer_id = ANY($3)`,
+		`UPDATE customers SET deleted_at = now() WHERE id <> $1 AND shop_id = $2 AND id = ANY($3) AND deleted_at IS NULL`,
+	}
+
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q, survivorID, shopID, pq.Array(duplicateIDs)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetCustomersByPhones returns the live customers of the shop whose phone is
+// one of phones.
+func (c *customer) GetCustomersByPhones(ctx context.Context, shopID int, phones []string) ([]model.Customer, error) {
+	q := `
+		SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at
+		FROM customers
+		WHERE shop_id = $1 AND phone = ANY($2) AND deleted_at IS NULL
+	`
+
+	rows, err := c.db.QueryContext(ctx, q, shopID, pq.Array(phones))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []model.Customer{}
+	for rows.Next() {
+		var customer model.Customer
+		if err := rows.Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Address, &customer.Priority, pq.Array(&customer.Tags), &customer.Blocked, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt); err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, nil
+}
+
+// BulkCreateCustomers inserts the customers with a single statement.
+func (c *customer) BulkCreateCustomers(ctx context.Context, tx database.Tx, inputs []CreateCustomerInput) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	args := make([]interface{}, 0, len(inputs)*5)
+	for _, input := range inputs {
+		address := ""
+		if input.Address != nil {
+			address = *input.Address
+		}
+		args = append(args, input.Name, input.Phone, address, input.ShopID, now)
+	}
+
+	q := `
+		INSERT INTO customers (name, phone, address, shop_id, created_at)
+		VALUES ` + bulkValues(len(inputs), "", "", "", "", "")
+
+	_, err := tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicatePhone
+		}
+		return err
+	}
+
+	return nil
+}
+
+// BulkUpdateCustomers updates the customers with a single statement.
+func (c *customer) BulkUpdateCustomers(ctx context.Context, tx database.Tx, inputs []BulkUpdateCustomerInput) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(inputs)*3)
+	for _, input := range inputs {
+		args = append(args, input.ID, input.Name, input.Address)
+	}
+
+	q := `
+		UPDATE customers c
+		SET name = v.name, address = COALESCE(v.address, c.address), updated_at = now()
+		FROM (VALUES ` + bulkValues(len(inputs), "int", "text", "text") + `) AS v(id, name, address)
+		WHERE c.id = v.id
+	`
+
+	_, err := tx.ExecContext(ctx, q, args...)
+	return err
+}
+
+// AnonymizeCustomer erases the personal data of the customer: name, phone,
+// address, tags and saved addresses, plus the shipping addresses on their
+// orders and the name, phone and address on temp orders placed with their
+// phone. Orders, payments and totals are kept for reporting. The phone
+// becomes a unique placeholder so the number can be used again.
+func (c *customer) AnonymizeCustomer(ctx context.Context, tx database.Tx, shopID, id int) error {
+	q := `
+		UPDATE temp_orders
+		SET customer_name = $3, customer_phone = '', shipping_address = NULL, fingerprint = NULL
+		WHERE shop_id = $1 AND customer_phone = (SELECT phone FROM customers WHERE id = $2 AND shop_id = $1)
+	`
+	if _, err := tx.ExecContext(ctx, q, shopID, id, constant.AnonymizedCustomerName); err != nil {
+		return err
+	}
+
+	queries := []string{
+		`UPDATE orders SET shipping_address = NULL, updated_at = now() WHERE shop_id = $1 AND customer_id = $2 AND shipping_address IS NOT NULL`,
+		`DELETE FROM customer_addresses WHERE shop_id = $1 AND customer_id = $2`,
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q, shopID, id); err != nil {
+			return err
+		}
+	}
+
+	q = `
+		UPDATE customers
+		SET name = $3, phone = 'anon-' || id, address = '', tags = '{}', anonymized_at = now(), updated_at = now()
+		WHERE shop_id = $1 AND id = $2
+	`
+	_, err := tx.ExecContext(ctx, q, shopID, id, constant.AnonymizedCustomerName)
+	return err
+}
+
 // isUniqueViolation checks if the error is a PostgreSQL unique constraint violation
 func isUniqueViolation(err error) bool {
 	if pqErr, ok := err.(*pq.Error); ok {
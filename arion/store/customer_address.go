@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	CustomerAddressStore interface {
+		CreateCustomerAddress(ctx context.Context, tx database.Tx, input CreateCustomerAddressInput) (*model.CustomerAddress, error)
+		GetCustomerAddressByID(ctx context.Context, id, customerID int) (*model.CustomerAddress, error)
+		GetCustomerAddressesByCustomerID(ctx context.Context, customerID int) ([]model.CustomerAddress, error)
+		GetDefaultCustomerAddress(ctx context.Context, customerID int) (*model.CustomerAddress, error)
+		UpdateCustomerAddress(ctx context.Context, tx database.Tx, id int, input UpdateCustomerAddressInput) (*model.CustomerAddress, error)
+		ClearDefaultCustomerAddress(ctx context.Context, tx database.Tx, customerID int) error
+		DeleteCustomerAddressByID(ctx context.Context, id, customerID int) error
+	}
+
+	customeraddress struct {
+		db *sql.DB
+	}
+
+	CreateCustomerAddressInput struct {
+		ShopID     int
+		CustomerID int
+		Label      string
+		Recipient  string
+		Phone      string
+		Address    string
+		Province   string
+		City       string
+		District   string
+		PostalCode string
+		IsDefault  bool
+	}
+
+	UpdateCustomerAddressInput struct {
+		Label      *string
+		Recipient  *string
+		Phone      *string
+		Address    *string
+		Province   *string
+		City       *string
+		District   *string
+		PostalCode *string
+		IsDefault  *bool
+	}
+)
+
+const customerAddressColumns = `id, shop_id, customer_id, label, recipient, phone, address, province, city, district, postal_code, is_default, created_at, updated_at`
+
+func NewCustomerAddressStore() CustomerAddressStore {
+	return &customeraddress{db: database.GetDB()}
+}
+
+// NewCustomerAddressStoreWithDB creates a CustomerAddressStore with a custom db connection (for testing)
+func NewCustomerAddressStoreWithDB(db *sql.DB) CustomerAddressStore {
+	return &customeraddress{db: db}
+}
+
+func scanCustomerAddress(row interface{ Scan(...interface{}) error }, address *model.CustomerAddress) error {
+	return row.Scan(&address.ID, &address.ShopID, &address.CustomerID, &address.Label, &address.Recipient, &address.Phone, &address.Address, &address.Province, &address.City, &address.District, &address.PostalCode, &address.IsDefault, &address.CreatedAt, &address.UpdatedAt)
+}
+
+func (c *customeraddress) CreateCustomerAddress(ctx context.Context, tx database.Tx, input CreateCustomerAddressInput) (*model.CustomerAddress, error) {
+	q := `
+		INSERT INTO customer_addresses (shop_id, customer_id, label, recipient, phone, address, province, city, district, postal_code, is_default, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ` + customerAddressColumns
+	args := []interface{}{input.ShopID, input.CustomerID, input.Label, input.Recipient, input.Phone, input.Address, input.Province, input.City, input.District, input.PostalCode, input.IsDefault, time.Now()}
+
+	var address model.CustomerAddress
+	var err error
+	if tx != nil {
+		err = scanCustomerAddress(tx.QueryRowContext(ctx, q, args...), &address)
+	} else {
+		err = scanCustomerAddress(c.db.QueryRowContext(ctx, q, args...), &address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+func (c *customeraddress) GetCustomerAddressByID(ctx context.Context, id, customerID int) (*model.CustomerAddress, error) {
+	q := `
+		SELECT ` + customerAddressColumns + `
+		FROM customer_addresses
+		WHERE id = $1 AND customer_id = $2
+	`
+
+	var address model.CustomerAddress
+	err := scanCustomerAddress(c.db.QueryRowContext(ctx, q, id, customerID), &address)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+func (c *customeraddress) GetCustomerAddressesByCustomerID(ctx context.Context, customerID int) ([]model.CustomerAddress, error) {
+	q := `
+		SELECT ` + customerAddressColumns + `
+		FROM customer_addresses
+		WHERE customer_id = $1
+		ORDER BY is_default DESC, created_at DESC, id DESC
+	`
+
+	rows, err := c.db.QueryContext(ctx, q, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []model.CustomerAddress{}
+	for rows.Next() {
+		var address model.CustomerAddress
+		if err := scanCustomerAddress(rows, &address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+
+	return addresses, nil
+}
+
+func (c *customeraddress) GetDefaultCustomerAddress(ctx context.Context, customerID int) (*model.CustomerAddress, error) {
+	q := `
+		SELECT ` + customerAddressColumns + `
+		FROM customer_addresses
+		WHERE customer_id = $1 AND is_default
+	`
+
+	var address model.CustomerAddress
+	err := scanCustomerAddress(c.db.QueryRowContext(ctx, q, customerID), &address)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+func (c *customeraddress) UpdateCustomerAddress(ctx context.Context, tx database.Tx, id int, input UpdateCustomerAddressInput) (*model.CustomerAddress, error) {
+	set := []string{}
+	args := []interface{}{id}
+	argNum := 2
+
+	// build query
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{"label", input.Label},
+		{"recipient", input.Recipient},
+		{"phone", input.Phone},
+		{"address", input.Address},
+		{"province", input.Province},
+		{"city", input.City},
+		{"district", input.District},
+		{"postal_code", input.PostalCode},
+	}
+	for _, field := range fields {
+		if field.value != nil {
+			set = append(set, fmt.Sprintf("%s = $%d", field.column, argNum))
+			args = append(args, *field.value)
+			argNum++
+		}
+	}
+	if input.IsDefault != nil {
+		set = append(set, fmt.Sprintf("is_default = $%d", argNum))
+		args = append(args, *input.IsDefault)
+		argNum++
+	}
+
+	set = append(set, "updated_at = now()")
+
+	q := fmt.Sprintf(`
+		UPDATE customer_addresses
+		SET %s
+		WHERE id = $1
+		RETURNING %s
+	`, strings.Join(set, ","), customerAddressColumns)
+
+	var address model.CustomerAddress
+	var err error
+	if tx != nil {
+		err = scanCustomerAddress(tx.QueryRowContext(ctx, q, args...), &address)
+	} else {
+		err = scanCustomerAddress(c.db.QueryRowContext(ctx, q, args...), &address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+// ClearDefaultCustomerAddress unsets the customer's current default address,
+// so another one can take its place without tripping the one-default index.
+func (c *customeraddress) ClearDefaultCustomerAddress(ctx context.Context, tx database.Tx, customerID int) error {
+	q := `
+		UPDATE customer_addresses
+		SET is_default = FALSE, updated_at = now()
+		WHERE customer_id = $1 AND is_default
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, customerID)
+	} else {
+		_, err = c.db.ExecContext(ctx, q, customerID)
+	}
+
+	return err
+}
+
+func (c *customeraddress) DeleteCustomerAddressByID(ctx context.Context, id, customerID int) error {
+	q := `
+		DELETE FROM customer_addresses
+		WHERE id = $1 AND customer_id = $2
+	`
+
+	_, err := c.db.ExecContext(ctx, q, id, customerID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
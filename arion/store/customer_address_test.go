@@ -0,0 +1,355 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+var customerAddressRowColumns = []string{"id", "shop_id", "customer_id", "label", "recipient", "phone", "address", "province", "city", "district", "postal_code", "is_default", "created_at", "updated_at"}
+
+func Test_customeraddress_CreateCustomerAddress(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     CreateCustomerAddressInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.CustomerAddress
+		wantErr   bool
+	}{
+		{
+			name:  "successfully create address",
+			input: CreateCustomerAddressInput{ShopID: 1, CustomerID: 2, Label: "Home", Recipient: "Budi", Phone: "+628123456789", Address: "Jl. Merdeka 1", City: "Jakarta", PostalCode: "12345", IsDefault: true},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO customer_addresses \(shop_id, customer_id, label, recipient, phone, address, province, city, district, postal_code, is_default, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11, \$12\)\s+RETURNING id`).
+					WithArgs(1, 2, "Home", "Budi", "+628123456789", "Jl. Merdeka 1", "", "Jakarta", "", "12345", true, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(customerAddressRowColumns).
+						AddRow(5, 1, 2, "Home", "Budi", "+628123456789", "Jl. Merdeka 1", "", "Jakarta", "", "12345", true, fixedTime, nil))
+			},
+			want: &model.CustomerAddress{ID: 5, ShopID: 1, CustomerID: 2, Label: "Home", Recipient: "Budi", Phone: "+628123456789", Address: "Jl. Merdeka 1", City: "Jakarta", PostalCode: "12345", IsDefault: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "returns error on database failure",
+			input: CreateCustomerAddressInput{ShopID: 1, CustomerID: 2, Recipient: "Budi", Address: "Jl. Merdeka 1"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO customer_addresses`).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewCustomerAddressStoreWithDB(db)
+			got, gotErr := s.CreateCustomerAddress(context.Background(), nil, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateCustomerAddress() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateCustomerAddress() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateCustomerAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customeraddress_GetCustomerAddressByID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, customer_id, label, recipient, phone, address, province, city, district, postal_code, is_default, created_at, updated_at\s+FROM customer_addresses\s+WHERE id = \$1 AND customer_id = \$2`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.CustomerAddress
+		wantErr   bool
+	}{
+		{
+			name: "returns address",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(5, 2).WillReturnRows(sqlmock.NewRows(customerAddressRowColumns).
+					AddRow(5, 1, 2, "Home", "Budi", "", "Jl. Merdeka 1", "", "", "", "12345", false, fixedTime, fixedTime))
+			},
+			want: &model.CustomerAddress{ID: 5, ShopID: 1, CustomerID: 2, Label: "Home", Recipient: "Budi", Address: "Jl. Merdeka 1", PostalCode: "12345", CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+		},
+		{
+			name: "returns nil when not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(5, 2).WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(5, 2).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewCustomerAddressStoreWithDB(db)
+			got, gotErr := s.GetCustomerAddressByID(context.Background(), 5, 2)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomerAddressByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomerAddressByID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCustomerAddressByID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customeraddress_GetCustomerAddressesByCustomerID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, customer_id, label, recipient, phone, address, province, city, district, postal_code, is_default, created_at, updated_at\s+FROM customer_addresses\s+WHERE customer_id = \$1\s+ORDER BY is_default DESC, created_at DESC, id DESC`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.CustomerAddress
+		wantErr   bool
+	}{
+		{
+			name: "returns addresses with the default first",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(customerAddressRowColumns).
+					AddRow(5, 1, 2, "Home", "Budi", "", "Jl. Merdeka 1", "", "", "", "12345", true, fixedTime, nil).
+					AddRow(6, 1, 2, "Office", "Budi", "", "Jl. Sudirman 5", "", "", "", "10220", false, fixedTime, nil)
+				mock.ExpectQuery(query).WithArgs(2).WillReturnRows(rows)
+			},
+			want: []model.CustomerAddress{
+				{ID: 5, ShopID: 1, CustomerID: 2, Label: "Home", Recipient: "Budi", Address: "Jl. Merdeka 1", PostalCode: "12345", IsDefault: true, CreatedAt: fixedTime},
+				{ID: 6, ShopID: 1, CustomerID: 2, Label: "Office", Recipient: "Budi", Address: "Jl. Sudirman 5", PostalCode: "10220", CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty list when customer has no addresses",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2).WillReturnRows(sqlmock.NewRows(customerAddressRowColumns))
+			},
+			want: []model.CustomerAddress{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewCustomerAddressStoreWithDB(db)
+			got, gotErr := s.GetCustomerAddressesByCustomerID(context.Background(), 2)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomerAddressesByCustomerID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomerAddressesByCustomerID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCustomerAddressesByCustomerID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customeraddress_UpdateCustomerAddress(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	recipient := "Siti"
+	isDefault := true
+
+	tests := []struct {
+		name      string
+		input     UpdateCustomerAddressInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.CustomerAddress
+		wantErr   bool
+	}{
+		{
+			name:  "updates given fields",
+			input: UpdateCustomerAddressInput{Recipient: &recipient, IsDefault: &isDefault},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE customer_addresses\s+SET recipient = \$2,is_default = \$3,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id`).
+					WithArgs(5, "Siti", true).
+					WillReturnRows(sqlmock.NewRows(customerAddressRowColumns).
+						AddRow(5, 1, 2, "Home", "Siti", "", "Jl. Merdeka 1", "", "", "", "12345", true, fixedTime, fixedTime))
+			},
+			want: &model.CustomerAddress{ID: 5, ShopID: 1, CustomerID: 2, Label: "Home", Recipient: "Siti", Address: "Jl. Merdeka 1", PostalCode: "12345", IsDefault: true, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+		},
+		{
+			name:  "returns error on database failure",
+			input: UpdateCustomerAddressInput{Recipient: &recipient},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE customer_addresses`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewCustomerAddressStoreWithDB(db)
+			got, gotErr := s.UpdateCustomerAddress(context.Background(), nil, 5, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("UpdateCustomerAddress() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("UpdateCustomerAddress() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateCustomerAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customeraddress_ClearDefaultCustomerAddress(t *testing.T) {
+	query := `UPDATE customer_addresses\s+SET is_default = FALSE, updated_at = now\(\)\s+WHERE customer_id = \$1 AND is_default`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "clears default",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WithArgs(2).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			s := NewCustomerAddressStoreWithDB(db)
+			gotErr := s.ClearDefaultCustomerAddress(context.Background(), tx, 2)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("ClearDefaultCustomerAddress() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_customeraddress_DeleteCustomerAddressByID(t *testing.T) {
+	query := `DELETE FROM customer_addresses\s+WHERE id = \$1 AND customer_id = \$2`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "deletes address",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(5, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(5, 2).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewCustomerAddressStoreWithDB(db)
+			gotErr := s.DeleteCustomerAddressByID(context.Background(), 5, 2)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("DeleteCustomerAddressByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	CustomerCreditStore interface {
+		CreateCustomerCredit(ctx context.Context, tx database.Tx, input CreateCustomerCreditInput) (*model.CustomerCredit, error)
+		GetCustomerCreditsByCustomerID(ctx context.Context, customerID int) ([]model.CustomerCredit, error)
+		GetCustomerCreditBalance(ctx context.Context, tx database.Tx, customerID int) (int, error)
+		HasOrderPaymentCredits(ctx context.Context, tx database.Tx, orderID, paymentID int) (bool, error)
+	}
+
+	customercredit struct {
+		db *sql.DB
+	}
+
+	CreateCustomerCreditInput struct {
+		ShopID     int
+		CustomerID int
+		OrderID    *int
+		PaymentID  *int
+		Type       string
+		Amount     int
+		Notes      string
+	}
+)
+
+func NewCustomerCreditStore() CustomerCreditStore {
+	return &customercredit{db: database.GetDB()}
+}
+
+// NewCustomerCreditStoreWithDB creates a CustomerCreditStore with a custom db connection (for testing)
+func NewCustomerCreditStoreWithDB(db *sql.DB) CustomerCreditStore {
+	return &customercredit{db: db}
+}
+
+func (c *customercredit) CreateCustomerCredit(ctx context.Context, tx database.Tx, input CreateCustomerCreditInput) (*model.CustomerCredit, error) {
+	now := time.Now()
+	q := `
+		INSERT INTO customer_credits (shop_id, customer_id, order_id, payment_id, type, amount, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	var id int
+	var err error
+	args := []interface{}{input.ShopID, input.CustomerID, input.OrderID, input.PaymentID, input.Type, input.Amount, input.Notes, now}
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, q, args...).Scan(&id)
+	} else {
+		err = c.db.QueryRowContext(ctx, q, args...).Scan(&id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	credit := &model.CustomerCredit{
+		ID:         id,
+		ShopID:     input.ShopID,
+		CustomerID: input.CustomerID,
+		Type:       input.Type,
+		Amount:     input.Amount,
+		Notes:      input.Notes,
+		CreatedAt:  now,
+	}
+	if input.OrderID != nil {
+		credit.OrderID = sql.NullInt64{Int64: int64(*input.OrderID), Valid: true}
+	}
+
+	return credit, nil
+}
+
+func (c *customercredit) GetCustomerCreditsByCustomerID(ctx context.Context, customerID int) ([]model.CustomerCredit, error) {
+	q := `
+		SELECT id, shop_id, customer_id, order_id, type, amount, notes, created_at
+		FROM customer_credits
+		WHERE customer_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := c.db.QueryContext(ctx, q, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []model.CustomerCredit{}
+	for rows.Next() {
+		var credit model.CustomerCredit
+		err := rows.Scan(&credit.ID, &credit.ShopID, &credit.CustomerID, &credit.OrderID, &credit.Type, &credit.Amount, &credit.Notes, &credit.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, credit)
+	}
+
+	return credits, nil
+}
+
+// GetCustomerCreditBalance sums the customer's ledger. Inside a transaction
+// the ledger is locked first, so a debit written in the same transaction
+// cannot race another one into a negative balance.
+func (c *customercredit) GetCustomerCreditBalance(ctx context.Context, tx database.Tx, customerID int) (int, error) {
+	q := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM customer_credits
+		WHERE customer_id = $1
+	`
+
+	var balance int
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('customer_credits'), $1)`, customerID)
+		if err != nil {
+			return 0, err
+		}
+		err = tx.QueryRowContext(ctx, q, customerID).Scan(&balance)
+	} else {
+		err = c.db.QueryRowContext(ctx, q, customerID).Scan(&balance)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+// HasOrderPaymentCredits reports whether a credit entry was booked with the
+// order's payment paymentID, or with any payment of the order when paymentID
+// is 0. Entries without a payment_id count for every payment of their order.
+func (c *customercredit) HasOrderPaymentCredits(ctx context.Context, tx database.Tx, orderID, paymentID int) (bool, error) {
+	q := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM customer_credits
+			WHERE order_id = $1
+				AND ($2 = 0 OR payment_id = $2 OR payment_id IS NULL)
+		)
+	`
+
+	var exists bool
+	var err error
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, q, orderID, paymentID).Scan(&exists)
+	} else {
+		err = c.db.QueryRowContext(ctx, q, orderID, paymentID).Scan(&exists)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
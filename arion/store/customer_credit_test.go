@@ -0,0 +1,312 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_customercredit_CreateCustomerCredit(t *testing.T) {
+	orderID := 7
+	paymentID := 3
+
+	tests := []struct {
+		name      string
+		input     CreateCustomerCreditInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.CustomerCredit
+		wantErr   bool
+	}{
+		{
+			name:  "successfully create deposit",
+			input: CreateCustomerCreditInput{ShopID: 1, CustomerID: 2, Type: constant.CustomerCreditTypeDeposit, Amount: 500000, Notes: "DP trip Jepang"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO customer_credits \(shop_id, customer_id, order_id, payment_id, type, amount, notes, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)\s+RETURNING id`).
+					WithArgs(1, 2, nil, nil, constant.CustomerCreditTypeDeposit, 500000, "DP trip Jepang", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+			},
+			want: &model.CustomerCredit{ID: 10, ShopID: 1, CustomerID: 2, Type: constant.CustomerCreditTypeDeposit, Amount: 500000, Notes: "DP trip Jepang"},
+		},
+		{
+			name:  "successfully create entry linked to an order payment",
+			input: CreateCustomerCreditInput{ShopID: 1, CustomerID: 2, OrderID: &orderID, PaymentID: &paymentID, Type: constant.CustomerCreditTypePayment, Amount: -150000},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO customer_credits`).
+					WithArgs(1, 2, 7, 3, constant.CustomerCreditTypePayment, -150000, "", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+			},
+			want: &model.CustomerCredit{ID: 11, ShopID: 1, CustomerID: 2, OrderID: sql.NullInt64{Int64: 7, Valid: true}, Type: constant.CustomerCreditTypePayment, Amount: -150000},
+		},
+		{
+			name:  "returns error on database failure",
+			input: CreateCustomerCreditInput{ShopID: 1, CustomerID: 2, Type: constant.CustomerCreditTypeDeposit, Amount: 1000},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO customer_credits`).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewCustomerCreditStoreWithDB(db)
+			got, gotErr := s.CreateCustomerCredit(context.Background(), nil, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateCustomerCredit() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateCustomerCredit() succeeded unexpectedly")
+			}
+
+			tt.want.CreatedAt = got.CreatedAt
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateCustomerCredit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customercredit_GetCustomerCreditsByCustomerID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, customer_id, order_id, type, amount, notes, created_at\s+FROM customer_credits\s+WHERE customer_id = \$1\s+ORDER BY created_at DESC, id DESC`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.CustomerCredit
+		wantErr   bool
+	}{
+		{
+			name: "returns ledger entries",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "order_id", "type", "amount", "notes", "created_at"}).
+					AddRow(2, 1, 2, 7, constant.CustomerCreditTypePayment, -150000, "", fixedTime).
+					AddRow(1, 1, 2, nil, constant.CustomerCreditTypeDeposit, 500000, "DP", fixedTime)
+				mock.ExpectQuery(query).WithArgs(2).WillReturnRows(rows)
+			},
+			want: []model.CustomerCredit{
+				{ID: 2, ShopID: 1, CustomerID: 2, OrderID: sql.NullInt64{Int64: 7, Valid: true}, Type: constant.CustomerCreditTypePayment, Amount: -150000, CreatedAt: fixedTime},
+				{ID: 1, ShopID: 1, CustomerID: 2, Type: constant.CustomerCreditTypeDeposit, Amount: 500000, Notes: "DP", CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when ledger is empty",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "order_id", "type", "amount", "notes", "created_at"}))
+			},
+			want: []model.CustomerCredit{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewCustomerCreditStoreWithDB(db)
+			got, gotErr := s.GetCustomerCreditsByCustomerID(context.Background(), 2)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomerCreditsByCustomerID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomerCreditsByCustomerID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCustomerCreditsByCustomerID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customercredit_GetCustomerCreditBalance(t *testing.T) {
+	query := `SELECT COALESCE\(SUM\(amount\), 0\)\s+FROM customer_credits\s+WHERE customer_id = \$1`
+	lockQuery := `SELECT pg_advisory_xact_lock\(hashtext\('customer_credits'\), \$1\)`
+
+	tests := []struct {
+		name      string
+		useTx     bool
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      int
+		wantErr   bool
+	}{
+		{
+			name: "returns balance without transaction",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(350000))
+			},
+			want: 350000,
+		},
+		{
+			name:  "locks ledger inside transaction",
+			useTx: true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(query).WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+			},
+			want: 0,
+		},
+		{
+			name:  "returns error when lock fails",
+			useTx: true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(2).WillReturnError(errors.New("lock error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			var got int
+			var gotErr error
+			s := NewCustomerCreditStoreWithDB(db)
+			if tt.useTx {
+				tx, err := db.Begin()
+				if err != nil {
+					t.Fatalf("failed to begin transaction: %v", err)
+				}
+				got, gotErr = s.GetCustomerCreditBalance(context.Background(), tx, 2)
+			} else {
+				got, gotErr = s.GetCustomerCreditBalance(context.Background(), nil, 2)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomerCreditBalance() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomerCreditBalance() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("GetCustomerCreditBalance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customercredit_HasOrderPaymentCredits(t *testing.T) {
+	query := `SELECT EXISTS \(\s+SELECT 1\s+FROM customer_credits\s+WHERE order_id = \$1\s+AND \(\$2 = 0 OR payment_id = \$2 OR payment_id IS NULL\)\s+\)`
+
+	tests := []struct {
+		name      string
+		paymentID int
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "payment with a credit entry",
+			paymentID: 3,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(7, 3).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			want: true,
+		},
+		{
+			name:      "order without credit entries",
+			paymentID: 0,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(7, 0).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			want: false,
+		},
+		{
+			name:      "returns error on database failure",
+			paymentID: 3,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(7, 3).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			s := NewCustomerCreditStoreWithDB(db)
+			got, gotErr := s.HasOrderPaymentCredits(context.Background(), tx, 7, tt.paymentID)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("HasOrderPaymentCredits() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("HasOrderPaymentCredits() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("HasOrderPaymentCredits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
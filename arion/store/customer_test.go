@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"reflect"
 	"testing"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/model"
 )
 
@@ -29,9 +31,9 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 			id:     1,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, "John Doe", "1234567890", "123 Main St", fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL`).
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, "John Doe", "1234567890", "123 Main St", 0, "{}", false, fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -40,6 +42,7 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 				Name:      "John Doe",
 				Phone:     "1234567890",
 				Address:   "123 Main St",
+				Tags:      []string{},
 				CreatedAt: fixedTime,
 			},
 			wantErr: false,
@@ -49,9 +52,9 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 			id:     1,
 			shopID: []int{1},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, "Jane Doe", "0987654321", "456 Oak Ave", fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL\s+AND shop_id = \$2`).
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, "Jane Doe", "0987654321", "456 Oak Ave", 0, "{}", false, fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL\s+AND shop_id = \$2`).
 					WithArgs(1, 1).
 					WillReturnRows(rows)
 			},
@@ -60,6 +63,7 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 				Name:      "Jane Doe",
 				Phone:     "0987654321",
 				Address:   "456 Oak Ave",
+				Tags:      []string{},
 				CreatedAt: fixedTime,
 			},
 			wantErr: false,
@@ -69,7 +73,7 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 			id:     9999,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL`).
 					WithArgs(9999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -81,7 +85,7 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 			id:     1,
 			shopID: []int{9999},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL\s+AND shop_id = \$2`).
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL\s+AND shop_id = \$2`).
 					WithArgs(1, 9999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -93,7 +97,7 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 			id:     1,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE id = \$1 AND deleted_at IS NULL`).
 					WithArgs(1).
 					WillReturnError(errors.New("database error"))
 			},
@@ -135,34 +139,46 @@ func Test_customer_GetCustomerByID(t *testing.T) {
 
 func Test_customer_GetCustomersByShopID(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
-	strPtr := func(s string) *string { return &s }
+	lastOrderTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	boolPtr := func(b bool) *bool { return &b }
+	columns := []string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at", "deleted_at",
+		"order_count", "total_spent", "outstanding", "first_order_at", "last_order_at"}
+	selectQuery := `SELECT c.id, c.name, c.phone, c.address, c.priority, c.tags, c.blocked, c.created_at, c.updated_at, c.deleted_at,\s+s.order_count, s.total_spent, s.outstanding, s.first_order_at, s.last_order_at\s+FROM customers c\s+CROSS JOIN LATERAL \(.+WHERE o.customer_id = c.id AND o.status != \$2\s+\) s\s+WHERE c.shop_id = \$1 AND c.deleted_at IS NULL`
 
 	tests := []struct {
-		name        string
-		shopID      int
-		filter      model.FilterOptions
-		mockSetup   func(mock sqlmock.Sqlmock)
-		wantResult  []model.Customer
-		wantErr     bool
+		name       string
+		shopID     int
+		filter     model.CustomerFilterOptions
+		mockSetup  func(mock sqlmock.Sqlmock)
+		wantResult []model.Customer
+		wantErr    bool
 	}{
 		{
 			name:   "get customers by shop ID returns multiple customers",
 			shopID: 1,
-			filter: model.FilterOptions{},
+			filter: model.CustomerFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, "John Doe", "1234567890", "123 Main St", fixedTime, nil, nil).
-					AddRow(2, "Jane Doe", "0987654321", "456 Oak Ave", fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
-					WithArgs(1).
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "John Doe", "1234567890", "123 Main St", 0, "{VIP,reseller}", false, fixedTime, nil, nil, 3, 450000, 50000, fixedTime, lastOrderTime).
+					AddRow(2, "Jane Doe", "0987654321", "456 Oak Ave", 0, "{}", true, fixedTime, nil, nil, 0, 0, 0, nil, nil)
+				mock.ExpectQuery(selectQuery).
+					WithArgs(1, "cancelled", "paid").
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Customer{
 				{
-					ID:        1,
-					Name:      "John Doe",
-					Phone:     "1234567890",
-					Address:   "123 Main St",
+					ID:      1,
+					Name:    "John Doe",
+					Phone:   "1234567890",
+					Address: "123 Main St",
+					Tags:    []string{"VIP", "reseller"},
+					Stats: model.CustomerStats{
+						OrderCount:   3,
+						TotalSpent:   450000,
+						Outstanding:  50000,
+						FirstOrderAt: sql.NullTime{Time: fixedTime, Valid: true},
+						LastOrderAt:  sql.NullTime{Time: lastOrderTime, Valid: true},
+					},
 					CreatedAt: fixedTime,
 				},
 				{
@@ -170,6 +186,8 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 					Name:      "Jane Doe",
 					Phone:     "0987654321",
 					Address:   "456 Oak Ave",
+					Tags:      []string{},
+					Blocked:   true,
 					CreatedAt: fixedTime,
 				},
 			},
@@ -178,11 +196,11 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 		{
 			name:   "get customers by shop ID returns empty slice when no customers exist",
 			shopID: 9999,
-			filter: model.FilterOptions{},
+			filter: model.CustomerFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at", "deleted_at"})
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
-					WithArgs(9999).
+				rows := sqlmock.NewRows(columns)
+				mock.ExpectQuery(selectQuery).
+					WithArgs(9999, "cancelled", "paid").
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Customer{},
@@ -191,10 +209,10 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 		{
 			name:   "get customers by shop ID returns error on database failure",
 			shopID: 1,
-			filter: model.FilterOptions{},
+			filter: model.CustomerFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
-					WithArgs(1).
+				mock.ExpectQuery(selectQuery).
+					WithArgs(1, "cancelled", "paid").
 					WillReturnError(errors.New("database error"))
 			},
 			wantResult: nil,
@@ -203,10 +221,10 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 		{
 			name:   "get customers by shop ID returns nil when db returns no rows",
 			shopID: 1,
-			filter: model.FilterOptions{},
+			filter: model.CustomerFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
-					WithArgs(1).
+				mock.ExpectQuery(selectQuery).
+					WithArgs(1, "cancelled", "paid").
 					WillReturnError(sql.ErrNoRows)
 			},
 			wantResult: nil,
@@ -215,12 +233,12 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 		{
 			name:   "get customers by shop ID with search query filters by name, phone",
 			shopID: 1,
-			filter: model.FilterOptions{SearchQuery: strPtr("john")},
+			filter: model.CustomerFilterOptions{SearchQuery: strPtr("john")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, "John Doe", "1234567890", "123 Main St", fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+AND \(name ILIKE \$2 OR phone ILIKE \$2\)`).
-					WithArgs(1, "%john%").
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "John Doe", "1234567890", "123 Main St", 0, "{}", false, fixedTime, nil, nil, 0, 0, 0, nil, nil)
+				mock.ExpectQuery(selectQuery+`\s+AND \(c.name ILIKE \$4 OR c.phone ILIKE \$4\)`).
+					WithArgs(1, "cancelled", "paid", "%john%").
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Customer{
@@ -229,21 +247,59 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 					Name:      "John Doe",
 					Phone:     "1234567890",
 					Address:   "123 Main St",
+					Tags:      []string{},
 					CreatedAt: fixedTime,
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name:   "get customers by shop ID with profile filters",
+			shopID: 1,
+			filter: model.CustomerFilterOptions{
+				Tags:           []string{"VIP", "Late Payer"},
+				Blocked:        boolPtr(false),
+				MinOrders:      intPtr(2),
+				MinSpent:       intPtr(100000),
+				HasOutstanding: boolPtr(true),
+				LastOrderFrom:  &fixedTime,
+				LastOrderTo:    &lastOrderTime,
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns)
+				mock.ExpectQuery(selectQuery+
+					`\s+AND EXISTS \(SELECT 1 FROM unnest\(c.tags\) t WHERE LOWER\(t\) = ANY\(\$4\)\)`+
+					`\s+AND c.blocked = \$5 AND s.order_count >= \$6 AND s.total_spent >= \$7 AND s.outstanding > 0`+
+					`\s+AND s.last_order_at >= \$8 AND s.last_order_at < \$9`).
+					WithArgs(1, "cancelled", "paid", pq.Array([]string{"vip", "late payer"}), false, 2, 100000, fixedTime, lastOrderTime).
+					WillReturnRows(rows)
+			},
+			wantResult: []model.Customer{},
+			wantErr:    false,
+		},
+		{
+			name:   "get customers by shop ID without outstanding balance",
+			shopID: 1,
+			filter: model.CustomerFilterOptions{HasOutstanding: boolPtr(false)},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns)
+				mock.ExpectQuery(selectQuery + `\s+AND s.outstanding = 0`).
+					WithArgs(1, "cancelled", "paid").
+					WillReturnRows(rows)
+			},
+			wantResult: []model.Customer{},
+			wantErr:    false,
+		},
 		{
 			name:   "get customers by shop ID with sort returns ordered customers",
 			shopID: 1,
-			filter: model.FilterOptions{Sort: strPtr("name,asc")},
+			filter: model.CustomerFilterOptions{Sort: strPtr("name,asc")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, "Alpha", "1234567890", "123 Main St", fixedTime, nil, nil).
-					AddRow(2, "Beta", "0987654321", "456 Oak Ave", fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+ORDER BY LOWER\(name\) ASC NULLS FIRST`).
-					WithArgs(1).
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "Alpha", "1234567890", "123 Main St", 0, "{}", false, fixedTime, nil, nil, 0, 0, 0, nil, nil).
+					AddRow(2, "Beta", "0987654321", "456 Oak Ave", 0, "{}", false, fixedTime, nil, nil, 0, 0, 0, nil, nil)
+				mock.ExpectQuery(selectQuery + `\s+ORDER BY LOWER\(c.name\) ASC NULLS FIRST`).
+					WithArgs(1, "cancelled", "paid").
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Customer{
@@ -252,6 +308,7 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 					Name:      "Alpha",
 					Phone:     "1234567890",
 					Address:   "123 Main St",
+					Tags:      []string{},
 					CreatedAt: fixedTime,
 				},
 				{
@@ -259,11 +316,25 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 					Name:      "Beta",
 					Phone:     "0987654321",
 					Address:   "456 Oak Ave",
+					Tags:      []string{},
 					CreatedAt: fixedTime,
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name:   "get customers by shop ID sorted by a stats column",
+			shopID: 1,
+			filter: model.CustomerFilterOptions{Sort: strPtr("total_spent,desc")},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns)
+				mock.ExpectQuery(selectQuery + `\s+ORDER BY s.total_spent DESC NULLS LAST`).
+					WithArgs(1, "cancelled", "paid").
+					WillReturnRows(rows)
+			},
+			wantResult: []model.Customer{},
+			wantErr:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -292,6 +363,96 @@ func Test_customer_GetCustomersByShopID(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.wantResult) {
 				t.Errorf("GetCustomersByShopID() = %v, want %v", got, tt.wantResult)
 			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_customer_GetCustomerStats(t *testing.T) {
+	firstOrderTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	lastOrderTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	statsQuery := `SELECT COUNT\(\*\) AS order_count,.+FROM orders o.+WHERE o.customer_id = \$1 AND o.status != \$2`
+
+	tests := []struct {
+		name       string
+		id         int
+		mockSetup  func(mock sqlmock.Sqlmock)
+		wantResult *model.CustomerStats
+		wantErr    bool
+	}{
+		{
+			name: "get customer stats",
+			id:   1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"order_count", "total_spent", "outstanding", "first_order_at", "last_order_at"}).
+					AddRow(3, 450000, 50000, firstOrderTime, lastOrderTime)
+				mock.ExpectQuery(statsQuery).
+					WithArgs(1, "cancelled", "paid").
+					WillReturnRows(rows)
+			},
+			wantResult: &model.CustomerStats{
+				OrderCount:   3,
+				TotalSpent:   450000,
+				Outstanding:  50000,
+				FirstOrderAt: sql.NullTime{Time: firstOrderTime, Valid: true},
+				LastOrderAt:  sql.NullTime{Time: lastOrderTime, Valid: true},
+			},
+			wantErr: false,
+		},
+		{
+			name: "get customer stats without orders",
+			id:   2,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"order_count", "total_spent", "outstanding", "first_order_at", "last_order_at"}).
+					AddRow(0, 0, 0, nil, nil)
+				mock.ExpectQuery(statsQuery).
+					WithArgs(2, "cancelled", "paid").
+					WillReturnRows(rows)
+			},
+			wantResult: &model.CustomerStats{},
+			wantErr:    false,
+		},
+		{
+			name: "get customer stats returns error on database failure",
+			id:   1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(statsQuery).
+					WithArgs(1, "cancelled", "paid").
+					WillReturnError(errors.New("database error"))
+			},
+			wantResult: nil,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			store := NewCustomerStoreWithDB(db)
+
+			got, gotErr := store.GetCustomerStats(context.Background(), tt.id)
+
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomerStats() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomerStats() succeeded unexpectedly")
+			}
+
+			if !reflect.DeepEqual(got, tt.wantResult) {
+				t.Errorf("GetCustomerStats() = %v, want %v", got, tt.wantResult)
+			}
 		})
 	}
 }
@@ -423,6 +584,10 @@ func strPtr(s string) *string {
 	return &s
 }
 
+func intPtr(n int) *int {
+	return &n
+}
+
 func Test_customer_UpdateCustomer(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 	updatedTime := time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC)
@@ -446,9 +611,9 @@ func Test_customer_UpdateCustomer(t *testing.T) {
 				Address: strPtr("789 New St"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at"}).
-					AddRow(1, "John Updated", "9999999999", "789 New St", fixedTime, updatedTime)
-				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,phone = \$3,address = \$4,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, created_at, updated_at`).
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at"}).
+					AddRow(1, "John Updated", "9999999999", "789 New St", 0, "{}", false, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,phone = \$3,address = \$4,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at`).
 					WithArgs(1, "John Updated", "9999999999", "789 New St").
 					WillReturnRows(rows)
 			},
@@ -457,6 +622,32 @@ func Test_customer_UpdateCustomer(t *testing.T) {
 				Name:      "John Updated",
 				Phone:     "9999999999",
 				Address:   "789 New St",
+				Tags:      []string{},
+				CreatedAt: fixedTime,
+				UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "update customer priority",
+			id:   1,
+			input: UpdateCustomerInput{
+				Priority: func() *int { i := 2; return &i }(),
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at"}).
+					AddRow(1, "John Doe", "1234567890", "123 Main St", 2, "{}", false, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE customers\s+SET priority = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at`).
+					WithArgs(1, 2).
+					WillReturnRows(rows)
+			},
+			wantResult: &model.Customer{
+				ID:        1,
+				Name:      "John Doe",
+				Phone:     "1234567890",
+				Address:   "123 Main St",
+				Priority:  2,
+				Tags:      []string{},
 				CreatedAt: fixedTime,
 				UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
 			},
@@ -469,9 +660,9 @@ func Test_customer_UpdateCustomer(t *testing.T) {
 				Name: strPtr("Jane Updated"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at"}).
-					AddRow(1, "Jane Updated", "1234567890", "123 Main St", fixedTime, updatedTime)
-				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, created_at, updated_at`).
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at"}).
+					AddRow(1, "Jane Updated", "1234567890", "123 Main St", 0, "{}", false, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at`).
 					WithArgs(1, "Jane Updated").
 					WillReturnRows(rows)
 			},
@@ -480,6 +671,33 @@ func Test_customer_UpdateCustomer(t *testing.T) {
 				Name:      "Jane Updated",
 				Phone:     "1234567890",
 				Address:   "123 Main St",
+				Tags:      []string{},
+				CreatedAt: fixedTime,
+				UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "update customer tags and block flag",
+			id:   1,
+			input: UpdateCustomerInput{
+				Tags:    &[]string{"VIP", "late payer"},
+				Blocked: func() *bool { b := true; return &b }(),
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at"}).
+					AddRow(1, "John Doe", "1234567890", "123 Main St", 0, "{VIP,\"late payer\"}", true, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE customers\s+SET tags = \$2,blocked = \$3,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at`).
+					WithArgs(1, pq.Array([]string{"VIP", "late payer"}), true).
+					WillReturnRows(rows)
+			},
+			wantResult: &model.Customer{
+				ID:        1,
+				Name:      "John Doe",
+				Phone:     "1234567890",
+				Address:   "123 Main St",
+				Tags:      []string{"VIP", "late payer"},
+				Blocked:   true,
 				CreatedAt: fixedTime,
 				UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
 			},
@@ -492,7 +710,7 @@ func Test_customer_UpdateCustomer(t *testing.T) {
 				Phone: strPtr("1234567890"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`UPDATE customers\s+SET phone = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, created_at, updated_at`).
+				mock.ExpectQuery(`UPDATE customers\s+SET phone = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at`).
 					WithArgs(1, "1234567890").
 					WillReturnError(&pq.Error{Code: "23505"})
 			},
@@ -506,7 +724,7 @@ func Test_customer_UpdateCustomer(t *testing.T) {
 				Name: strPtr("Ghost"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, created_at, updated_at`).
+				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at`).
 					WithArgs(9999, "Ghost").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -520,7 +738,7 @@ func Test_customer_UpdateCustomer(t *testing.T) {
 				Name: strPtr("John"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, created_at, updated_at`).
+				mock.ExpectQuery(`UPDATE customers\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, phone, address, priority, tags, blocked, created_at, updated_at`).
 					WithArgs(1, "John").
 					WillReturnError(errors.New("database error"))
 			},
@@ -643,9 +861,9 @@ func Test_customer_GetCustomerByPhone(t *testing.T) {
 			phone:  "08123456789",
 			shopID: 1,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, "John Doe", "08123456789", "123 Main St", fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE phone = \$1 AND shop_id = \$2 AND deleted_at IS NULL`).
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, "John Doe", "08123456789", "123 Main St", 0, "{}", false, fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE phone = \$1 AND shop_id = \$2 AND deleted_at IS NULL`).
 					WithArgs("08123456789", 1).
 					WillReturnRows(rows)
 			},
@@ -654,6 +872,7 @@ func Test_customer_GetCustomerByPhone(t *testing.T) {
 				Name:      "John Doe",
 				Phone:     "08123456789",
 				Address:   "123 Main St",
+				Tags:      []string{},
 				CreatedAt: fixedTime,
 			},
 			wantErr: false,
@@ -663,7 +882,7 @@ func Test_customer_GetCustomerByPhone(t *testing.T) {
 			phone:  "08000000000",
 			shopID: 1,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE phone = \$1 AND shop_id = \$2 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE phone = \$1 AND shop_id = \$2 AND deleted_at IS NULL`).
 					WithArgs("08000000000", 1).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -675,7 +894,7 @@ func Test_customer_GetCustomerByPhone(t *testing.T) {
 			phone:  "08123456789",
 			shopID: 1,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, phone, address, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE phone = \$1 AND shop_id = \$2 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE phone = \$1 AND shop_id = \$2 AND deleted_at IS NULL`).
 					WithArgs("08123456789", 1).
 					WillReturnError(errors.New("database error"))
 			},
@@ -712,3 +931,341 @@ func Test_customer_GetCustomerByPhone(t *testing.T) {
 		})
 	}
 }
+
+func Test_customer_MergeCustomers(t *testing.T) {
+	moveOrders := `UPDATE orders SET customer_id = \$1, updated_at = now\(\) WHERE shop_id = \$2 AND customer_id = ANY\(\$3\)`
+	moveCredits := `UPDATE customer_credits SET customer_id = \$1 WHERE shop_id = \$2 AND customer_id = ANY\(\$3\)`
+	moveAddresses := `UPDATE customer_addresses SET customer_id = \$1, is_default = FALSE, updated_at = now\(\) WHERE shop_id = \$2 AND customer_id = ANY\(\$3\)`
+	deleteDuplicates := `UPDATE customers SET deleted_at = now\(\) WHERE id <> \$1 AND shop_id = \$2 AND id = ANY\(\$3\) AND deleted_at IS NULL`
+	lockOrders := `SELECT customer_id\s+FROM orders\s+WHERE shop_id = \$2 AND \(customer_id = \$1 OR customer_id = ANY\(\$3\)\) AND status IN \(\$4, \$5\)\s+FOR UPDATE`
+	args := []driver.Value{1, 10, pq.Array([]int{2, 3})}
+	lockArgs := append(args, constant.OrderStatusCreated, constant.OrderStatusInProgress)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "moves orders, credits and addresses and deletes duplicates",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockOrders).WithArgs(lockArgs...).WillReturnRows(sqlmock.NewRows([]string{"customer_id"}).AddRow(2))
+				mock.ExpectExec(moveOrders).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(moveCredits).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(moveAddresses).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(deleteDuplicates).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "rejects when more than one customer has an open order",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockOrders).WithArgs(lockArgs...).WillReturnRows(sqlmock.NewRows([]string{"customer_id"}).AddRow(1).AddRow(3))
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error when locking orders fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockOrders).WithArgs(lockArgs...).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error when moving orders fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockOrders).WithArgs(lockArgs...).WillReturnRows(sqlmock.NewRows([]string{"customer_id"}))
+				mock.ExpectExec(moveOrders).WithArgs(args...).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error when deleting duplicates fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockOrders).WithArgs(lockArgs...).WillReturnRows(sqlmock.NewRows([]string{"customer_id"}))
+				mock.ExpectExec(moveOrders).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(moveCredits).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(moveAddresses).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(deleteDuplicates).WithArgs(args...).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewCustomerStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := store.MergeCustomers(context.Background(), tx, 10, 1, []int{2, 3})
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("MergeCustomers() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("MergeCustomers() succeeded unexpectedly")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_customer_GetCustomersByPhones(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, name, phone, address, priority, tags, blocked, created_at, updated_at, deleted_at\s+FROM customers\s+WHERE shop_id = \$1 AND phone = ANY\(\$2\) AND deleted_at IS NULL`
+	phones := []string{"+628123456789", "+6281311112222"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.Customer
+		wantErr   bool
+	}{
+		{
+			name: "returns matching customers",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "phone", "address", "priority", "tags", "blocked", "created_at", "updated_at", "deleted_at"}).
+					AddRow(7, "Siti", "+6281311112222", "", 0, "{}", false, fixedTime, nil, nil)
+				mock.ExpectQuery(query).WithArgs(10, pq.Array(phones)).WillReturnRows(rows)
+			},
+			want: []model.Customer{
+				{ID: 7, Name: "Siti", Phone: "+6281311112222", Tags: []string{}, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10, pq.Array(phones)).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			store := NewCustomerStoreWithDB(db)
+			got, gotErr := store.GetCustomersByPhones(context.Background(), 10, phones)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetCustomersByPhones() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetCustomersByPhones() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCustomersByPhones() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_customer_BulkCreateCustomers(t *testing.T) {
+	address := "Jl. Merdeka 1"
+	query := `INSERT INTO customers \(name, phone, address, shop_id, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5\), \(\$6, \$7, \$8, \$9, \$10\)`
+	inputs := []CreateCustomerInput{
+		{ShopID: 10, Name: "Budi", Phone: "+628123456789", Address: &address},
+		{ShopID: 10, Name: "Siti", Phone: "+6281311112222"},
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "inserts all rows in one statement",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("Budi", "+628123456789", "Jl. Merdeka 1", 10, sqlmock.AnyArg(), "Siti", "+6281311112222", "", 10, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "maps unique violation to duplicate phone",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(&pq.Error{Code: "23505"})
+			},
+			wantErr: ErrDuplicatePhone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewCustomerStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := store.BulkCreateCustomers(context.Background(), tx, inputs)
+			if gotErr != tt.wantErr {
+				t.Errorf("BulkCreateCustomers() error = %v, want %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_customer_BulkUpdateCustomers(t *testing.T) {
+	address := "Jl. Merdeka 1"
+	query := `UPDATE customers c\s+SET name = v.name, address = COALESCE\(v.address, c.address\), updated_at = now\(\)\s+FROM \(VALUES \(\$1::int, \$2::text, \$3::text\), \(\$4::int, \$5::text, \$6::text\)\) AS v\(id, name, address\)\s+WHERE c.id = v.id`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "updates all rows in one statement",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(7, "Budi", "Jl. Merdeka 1", 8, "Siti", nil).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewCustomerStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := store.BulkUpdateCustomers(context.Background(), tx, []BulkUpdateCustomerInput{
+				{ID: 7, Name: "Budi", Address: &address},
+				{ID: 8, Name: "Siti"},
+			})
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("BulkUpdateCustomers() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_customer_AnonymizeCustomer(t *testing.T) {
+	clearTempOrders := `UPDATE temp_orders\s+SET customer_name = \$3, customer_phone = '', shipping_address = NULL, fingerprint = NULL\s+WHERE shop_id = \$1 AND customer_phone = \(SELECT phone FROM customers WHERE id = \$2 AND shop_id = \$1\)`
+	clearOrders := `UPDATE orders SET shipping_address = NULL, updated_at = now\(\) WHERE shop_id = \$1 AND customer_id = \$2 AND shipping_address IS NOT NULL`
+	deleteAddresses := `DELETE FROM customer_addresses WHERE shop_id = \$1 AND customer_id = \$2`
+	clearCustomer := `UPDATE customers\s+SET name = \$3, phone = 'anon-' \|\| id, address = '', tags = '\{\}', anonymized_at = now\(\), updated_at = now\(\)\s+WHERE shop_id = \$1 AND id = \$2`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "erases temp orders, shipping addresses, saved addresses and the customer",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(clearTempOrders).WithArgs(10, 1, "Deleted customer").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(clearOrders).WithArgs(10, 1).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(deleteAddresses).WithArgs(10, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(clearCustomer).WithArgs(10, 1, "Deleted customer").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error when clearing temp orders fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(clearTempOrders).WithArgs(10, 1, "Deleted customer").WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error when clearing the customer fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(clearTempOrders).WithArgs(10, 1, "Deleted customer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(clearOrders).WithArgs(10, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(deleteAddresses).WithArgs(10, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(clearCustomer).WithArgs(10, 1, "Deleted customer").WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewCustomerStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := store.AnonymizeCustomer(context.Background(), tx, 10, 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("AnonymizeCustomer() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("AnonymizeCustomer() succeeded unexpectedly")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
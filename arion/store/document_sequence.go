@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zeirash/recapo/arion/common/database"
+)
+
+type (
+	DocumentSequenceStore interface {
+		NextValue(ctx context.Context, tx database.Tx, shopID int, docType string) (int, error)
+	}
+
+	documentsequence struct {
+		db *sql.DB
+	}
+)
+
+func NewDocumentSequenceStore() DocumentSequenceStore {
+	return &documentsequence{db: database.GetDB()}
+}
+
+// NewDocumentSequenceStoreWithDB creates a DocumentSequenceStore with a custom db connection (for testing)
+func NewDocumentSequenceStoreWithDB(db *sql.DB) DocumentSequenceStore {
+	return &documentsequence{db: db}
+}
+
+// NextValue takes the next number of the shop's docType sequence, starting at 1.
+// The counter row stays locked until tx ends, so concurrent exports queue up
+// and a rolled back export hands its number to the next one.
+func (d *documentsequence) NextValue(ctx context.Context, tx database.Tx, shopID int, docType string) (int, error) {
+	q := `
+		INSERT INTO document_sequences (shop_id, doc_type, last_value)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (shop_id, doc_type) DO UPDATE
+		SET last_value = document_sequences.last_value + 1
+		RETURNING last_value
+	`
+
+	var value int
+	err := tx.QueryRowContext(ctx, q, shopID, docType).Scan(&value)
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
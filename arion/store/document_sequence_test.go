@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/common/constant"
+)
+
+func Test_documentsequence_NextValue(t *testing.T) {
+	query := `INSERT INTO document_sequences \(shop_id, doc_type, last_value\)\s+VALUES \(\$1, \$2, 1\)\s+ON CONFLICT \(shop_id, doc_type\) DO UPDATE\s+SET last_value = document_sequences.last_value \+ 1\s+RETURNING last_value`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      int
+		wantErr   bool
+	}{
+		{
+			name: "returns next number of the shop",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).
+					WithArgs(10, constant.DocumentTypeInvoice).
+					WillReturnRows(sqlmock.NewRows([]string{"last_value"}).AddRow(42))
+			},
+			want: 42,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).
+					WithArgs(10, constant.DocumentTypeInvoice).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			s := NewDocumentSequenceStoreWithDB(db)
+			got, gotErr := s.NextValue(context.Background(), tx, 10, constant.DocumentTypeInvoice)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("NextValue() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("NextValue() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("NextValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
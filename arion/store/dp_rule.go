@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	DPRuleStore interface {
+		CreateDPRule(ctx context.Context, tx database.Tx, input CreateDPRuleInput) (*model.DPRule, error)
+		GetDPRuleByID(ctx context.Context, id, shopID int) (*model.DPRule, error)
+		GetDPRulesByShopID(ctx context.Context, shopID int) ([]model.DPRule, error)
+		GetDefaultDPRuleByShopID(ctx context.Context, shopID int) (*model.DPRule, error)
+		UpdateDPRule(ctx context.Context, tx database.Tx, id int, input UpdateDPRuleInput) (*model.DPRule, error)
+		ClearDefaultDPRule(ctx context.Context, tx database.Tx, shopID int) error
+		DeleteDPRuleByID(ctx context.Context, id, shopID int) error
+	}
+
+	dprule struct {
+		db *sql.DB
+	}
+
+	CreateDPRuleInput struct {
+		ShopID     int
+		Name       string
+		Percent    int
+		DueDays    *int
+		DueDate    *time.Time
+		AutoCancel bool
+		IsDefault  bool
+	}
+
+	// UpdateDPRuleInput holds the fields to change. Setting DueDays clears
+	// DueDate and the other way around, since a rule has exactly one deadline.
+	UpdateDPRuleInput struct {
+		Name       *string
+		Percent    *int
+		DueDays    *int
+		DueDate    *time.Time
+		AutoCancel *bool
+		IsDefault  *bool
+	}
+)
+
+func NewDPRuleStore() DPRuleStore {
+	return &dprule{db: database.GetDB()}
+}
+
+// NewDPRuleStoreWithDB creates a DPRuleStore with a custom db connection (for testing)
+func NewDPRuleStoreWithDB(db *sql.DB) DPRuleStore {
+	return &dprule{db: db}
+}
+
+func (d *dprule) CreateDPRule(ctx context.Context, tx database.Tx, input CreateDPRuleInput) (*model.DPRule, error) {
+	q := `
+		INSERT INTO dp_rules (shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at
+	`
+	args := []interface{}{input.ShopID, input.Name, input.Percent, input.DueDays, input.DueDate, input.AutoCancel, input.IsDefault, time.Now()}
+
+	var rule model.DPRule
+	var err error
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, q, args...).Scan(&rule.ID, &rule.ShopID, &rule.Name, &rule.Percent, &rule.DueDays, &rule.DueDate, &rule.AutoCancel, &rule.IsDefault, &rule.CreatedAt, &rule.UpdatedAt)
+	} else {
+		err = d.db.QueryRowContext(ctx, q, args...).Scan(&rule.ID, &rule.ShopID, &rule.Name, &rule.Percent, &rule.DueDays, &rule.DueDate, &rule.AutoCancel, &rule.IsDefault, &rule.CreatedAt, &rule.UpdatedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (d *dprule) GetDPRuleByID(ctx context.Context, id, shopID int) (*model.DPRule, error) {
+	q := `
+		SELECT id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at
+		FROM dp_rules
+		WHERE id = $1 AND shop_id = $2
+	`
+
+	var rule model.DPRule
+	err := d.db.QueryRowContext(ctx, q, id, shopID).Scan(&rule.ID, &rule.ShopID, &rule.Name, &rule.Percent, &rule.DueDays, &rule.DueDate, &rule.AutoCancel, &rule.IsDefault, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (d *dprule) GetDPRulesByShopID(ctx context.Context, shopID int) ([]model.DPRule, error) {
+	q := `
+		SELECT id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at
+		FROM dp_rules
+		WHERE shop_id = $1
+		ORDER BY is_default DESC, created_at DESC
+	`
+
+	rows, err := d.db.QueryContext(ctx, q, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []model.DPRule{}
+	for rows.Next() {
+		var rule model.DPRule
+		if err := rows.Scan(&rule.ID, &rule.ShopID, &rule.Name, &rule.Percent, &rule.DueDays, &rule.DueDate, &rule.AutoCancel, &rule.IsDefault, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (d *dprule) GetDefaultDPRuleByShopID(ctx context.Context, shopID int) (*model.DPRule, error) {
+	q := `
+		SELECT id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at
+		FROM dp_rules
+		WHERE shop_id = $1 AND is_default
+	`
+
+	var rule model.DPRule
+	err := d.db.QueryRowContext(ctx, q, shopID).Scan(&rule.ID, &rule.ShopID, &rule.Name, &rule.Percent, &rule.DueDays, &rule.DueDate, &rule.AutoCancel, &rule.IsDefault, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (d *dprule) UpdateDPRule(ctx context.Context, tx database.Tx, id int, input UpdateDPRuleInput) (*model.DPRule, error) {
+	set := []string{}
+	args := []interface{}{id}
+	argNum := 2
+
+	// build query
+	if input.Name != nil {
+		set = append(set, fmt.Sprintf("name = $%d", argNum))
+		args = append(args, *input.Name)
+		argNum++
+	}
+	if input.Percent != nil {
+		set = append(set, fmt.Sprintf("percent = $%d", argNum))
+		args = append(args, *input.Percent)
+		argNum++
+	}
+	if input.DueDays != nil {
+		set = append(set, fmt.Sprintf("due_days = $%d", argNum), "due_date = NULL")
+		args = append(args, *input.DueDays)
+		argNum++
+	}
+	if input.DueDate != nil {
+		set = append(set, fmt.Sprintf("due_date = $%d", argNum), "due_days = NULL")
+		args = append(args, *input.DueDate)
+		argNum++
+	}
+	if input.AutoCancel != nil {
+		set = append(set, fmt.Sprintf("auto_cancel = $%d", argNum))
+		args = append(args, *input.AutoCancel)
+		argNum++
+	}
+	if input.IsDefault != nil {
+		set = append(set, fmt.Sprintf("is_default = $%d", argNum))
+		args = append(args, *input.IsDefault)
+		argNum++
+	}
+
+	set = append(set, "updated_at = now()")
+
+	q := fmt.Sprintf(`
+		UPDATE dp_rules
+		SET %s
+		WHERE id = $1
+		RETURNING id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at
+	`, strings.Join(set, ","))
+
+	var rule model.DPRule
+	var err error
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, q, args...).Scan(&rule.ID, &rule.ShopID, &rule.Name, &rule.Percent, &rule.DueDays, &rule.DueDate, &rule.AutoCancel, &rule.IsDefault, &rule.CreatedAt, &rule.UpdatedAt)
+	} else {
+		err = d.db.QueryRowContext(ctx, q, args...).Scan(&rule.ID, &rule.ShopID, &rule.Name, &rule.Percent, &rule.DueDays, &rule.DueDate, &rule.AutoCancel, &rule.IsDefault, &rule.CreatedAt, &rule.UpdatedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// ClearDefaultDPRule unsets the shop's current default rule, so another one
+// can take its place without tripping the one-default-per-shop index.
+func (d *dprule) ClearDefaultDPRule(ctx context.Context, tx database.Tx, shopID int) error {
+	q := `
+		UPDATE dp_rules
+		SET is_default = FALSE, updated_at = now()
+		WHERE shop_id = $1 AND is_default
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, shopID)
+	} else {
+		_, err = d.db.ExecContext(ctx, q, shopID)
+	}
+
+	return err
+}
+
+func (d *dprule) DeleteDPRuleByID(ctx context.Context, id, shopID int) error {
+	q := `
+		DELETE FROM dp_rules
+		WHERE id = $1 AND shop_id = $2
+	`
+
+	_, err := d.db.ExecContext(ctx, q, id, shopID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,426 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_dprule_CreateDPRule(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	dueDays := 3
+
+	tests := []struct {
+		name      string
+		input     CreateDPRuleInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.DPRule
+		wantErr   bool
+	}{
+		{
+			name:  "successfully create rule",
+			input: CreateDPRuleInput{ShopID: 1, Name: "DP 50%", Percent: 50, DueDays: &dueDays, IsDefault: true},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO dp_rules \(shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)\s+RETURNING id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at`).
+					WithArgs(1, "DP 50%", 50, &dueDays, nil, false, true, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "shop_id", "name", "percent", "due_days", "due_date", "auto_cancel", "is_default", "created_at", "updated_at"}).AddRow(1, 1, "DP 50%", 50, 3, nil, false, true, fixedTime, nil))
+			},
+			want: &model.DPRule{ID: 1, ShopID: 1, Name: "DP 50%", Percent: 50, DueDays: sql.NullInt64{Int64: 3, Valid: true}, IsDefault: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "returns error on database failure",
+			input: CreateDPRuleInput{ShopID: 1, Name: "DP 50%", Percent: 50, DueDays: &dueDays},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO dp_rules`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewDPRuleStoreWithDB(db)
+			got, gotErr := s.CreateDPRule(context.Background(), nil, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateDPRule() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateDPRule() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateDPRule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dprule_GetDPRuleByID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	departure := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at\s+FROM dp_rules\s+WHERE id = \$1 AND shop_id = \$2`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.DPRule
+		wantErr   bool
+	}{
+		{
+			name: "returns rule",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "shop_id", "name", "percent", "due_days", "due_date", "auto_cancel", "is_default", "created_at", "updated_at"}).AddRow(2, 1, "Trip Jepang", 30, nil, departure, true, false, fixedTime, fixedTime))
+			},
+			want: &model.DPRule{
+				ID:         2,
+				ShopID:     1,
+				Name:       "Trip Jepang",
+				Percent:    30,
+				DueDate:    sql.NullTime{Time: departure, Valid: true},
+				AutoCancel: true,
+				CreatedAt:  fixedTime,
+				UpdatedAt:  sql.NullTime{Time: fixedTime, Valid: true},
+			},
+		},
+		{
+			name: "returns nil when rule does not exist",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 1).WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewDPRuleStoreWithDB(db)
+			got, gotErr := s.GetDPRuleByID(context.Background(), 2, 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetDPRuleByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetDPRuleByID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDPRuleByID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dprule_GetDPRulesByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at\s+FROM dp_rules\s+WHERE shop_id = \$1\s+ORDER BY is_default DESC, created_at DESC`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.DPRule
+		wantErr   bool
+	}{
+		{
+			name: "returns rules",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "percent", "due_days", "due_date", "auto_cancel", "is_default", "created_at", "updated_at"}).
+					AddRow(1, 1, "DP 50%", 50, 3, nil, false, true, fixedTime, nil).
+					AddRow(2, 1, "Lunas", 100, 0, nil, true, false, fixedTime, nil)
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows)
+			},
+			want: []model.DPRule{
+				{ID: 1, ShopID: 1, Name: "DP 50%", Percent: 50, DueDays: sql.NullInt64{Int64: 3, Valid: true}, IsDefault: true, CreatedAt: fixedTime},
+				{ID: 2, ShopID: 1, Name: "Lunas", Percent: 100, DueDays: sql.NullInt64{Int64: 0, Valid: true}, AutoCancel: true, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when shop has no rules",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "shop_id", "name", "percent", "due_days", "due_date", "auto_cancel", "is_default", "created_at", "updated_at"}))
+			},
+			want: []model.DPRule{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewDPRuleStoreWithDB(db)
+			got, gotErr := s.GetDPRulesByShopID(context.Background(), 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetDPRulesByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetDPRulesByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDPRulesByShopID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dprule_GetDefaultDPRuleByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at\s+FROM dp_rules\s+WHERE shop_id = \$1 AND is_default`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.DPRule
+		wantErr   bool
+	}{
+		{
+			name: "returns default rule",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "shop_id", "name", "percent", "due_days", "due_date", "auto_cancel", "is_default", "created_at", "updated_at"}).AddRow(1, 1, "DP 50%", 50, 3, nil, false, true, fixedTime, nil))
+			},
+			want: &model.DPRule{ID: 1, ShopID: 1, Name: "DP 50%", Percent: 50, DueDays: sql.NullInt64{Int64: 3, Valid: true}, IsDefault: true, CreatedAt: fixedTime},
+		},
+		{
+			name: "returns nil when shop has no default rule",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewDPRuleStoreWithDB(db)
+			got, gotErr := s.GetDefaultDPRuleByShopID(context.Background(), 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetDefaultDPRuleByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetDefaultDPRuleByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDefaultDPRuleByShopID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dprule_UpdateDPRule(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	departure := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	percent := 30
+	isDefault := false
+
+	tests := []struct {
+		name      string
+		input     UpdateDPRuleInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.DPRule
+		wantErr   bool
+	}{
+		{
+			name:  "switching to a fixed date clears due_days",
+			input: UpdateDPRuleInput{Percent: &percent, DueDate: &departure, IsDefault: &isDefault},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE dp_rules\s+SET percent = \$2,due_date = \$3,due_days = NULL,is_default = \$4,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, percent, due_days, due_date, auto_cancel, is_default, created_at, updated_at`).
+					WithArgs(1, 30, departure, false).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "shop_id", "name", "percent", "due_days", "due_date", "auto_cancel", "is_default", "created_at", "updated_at"}).AddRow(1, 1, "DP", 30, nil, departure, false, false, fixedTime, fixedTime))
+			},
+			want: &model.DPRule{
+				ID:        1,
+				ShopID:    1,
+				Name:      "DP",
+				Percent:   30,
+				DueDate:   sql.NullTime{Time: departure, Valid: true},
+				CreatedAt: fixedTime,
+				UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true},
+			},
+		},
+		{
+			name:  "returns error on database failure",
+			input: UpdateDPRuleInput{Percent: &percent},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE dp_rules`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewDPRuleStoreWithDB(db)
+			got, gotErr := s.UpdateDPRule(context.Background(), nil, 1, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("UpdateDPRule() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("UpdateDPRule() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateDPRule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dprule_ClearDefaultDPRule(t *testing.T) {
+	query := `UPDATE dp_rules\s+SET is_default = FALSE, updated_at = now\(\)\s+WHERE shop_id = \$1 AND is_default`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "clears default",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewDPRuleStoreWithDB(db)
+			gotErr := s.ClearDefaultDPRule(context.Background(), nil, 1)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("ClearDefaultDPRule() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_dprule_DeleteDPRuleByID(t *testing.T) {
+	query := `DELETE FROM dp_rules\s+WHERE id = \$1 AND shop_id = \$2`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "deletes rule",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(2, 1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewDPRuleStoreWithDB(db)
+			gotErr := s.DeleteDPRuleByID(context.Background(), 2, 1)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("DeleteDPRuleByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -17,13 +18,25 @@ import (
 type (
 	OrderStore interface {
 		GetOrderByID(ctx context.Context, id int, shopID ...int) (*model.Order, error)
+		GetOrderByIDForUpdate(ctx context.Context, tx database.Tx, id int) (*model.Order, error)
 		GetOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]model.Order, error)
 		GetActiveOrderByCustomerID(ctx context.Context, customerID int, shopID int) (*model.Order, error)
+		GetOutstandingOrdersByShopID(ctx context.Context, shopID int) ([]model.OutstandingOrder, error)
 		CreateOrder(ctx context.Context, tx database.Tx, customerID int, shopID int, notes *string, totalPrice *int) (*model.Order, error)
+		AssignUniqueCode(ctx context.Context, tx database.Tx, id int, shopID int) (int, error)
+		ReclaimUniqueCode(ctx context.Context, tx database.Tx, id int, shopID int) (int, error)
+		SetDownPayment(ctx context.Context, tx database.Tx, id int, input SetDownPaymentInput) error
+		SetShippingAddress(ctx context.Context, tx database.Tx, id int, address *model.ShippingAddress) error
+		GetInvoiceNumberForUpdate(ctx context.Context, tx database.Tx, id int) (string, error)
+		SetInvoiceNumber(ctx context.Context, tx database.Tx, id, seq int, number string) error
+		GetDPOverdueOrders(ctx context.Context, now time.Time) ([]model.DPOverdueOrder, error)
+		MarkDPOverdue(ctx context.Context, tx database.Tx, id int) error
+		CancelOrder(ctx context.Context, tx database.Tx, id int, reason, notes string) error
 		UpdateOrder(ctx context.Context, tx database.Tx, id int, input UpdateOrderInput) (*model.Order, error)
 		DeleteOrderByID(ctx context.Context, tx database.Tx, id int) error
 
-		CreateTempOrder(ctx context.Context, tx database.Tx, customerName, customerPhone string, shopID int) (*model.TempOrder, error)
+		CreateTempOrder(ctx context.Context, tx database.Tx, customerName, customerPhone string, shopID int, shareLinkID *int, fingerprint string, shippingAddress *model.ShippingAddress) (*model.TempOrder, error)
+		HasRecentTempOrder(ctx context.Context, tx database.Tx, shopID int, fingerprint string, since time.Time) (bool, error)
 		UpdateTempOrderTotalPrice(ctx context.Context, tx database.Tx, tempOrderID int, totalPrice int) error
 		GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*model.TempOrder, error)
 		GetTempOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]model.TempOrder, error)
@@ -35,10 +48,22 @@ type (
 	}
 
 	UpdateOrderInput struct {
-		TotalPrice    *int
-		Status        *string
-		PaymentStatus *string
-		Notes         *string
+		TotalPrice     *int
+		UniqueCode     *int
+		Status         *string
+		PaymentStatus  *string
+		ShortageNotify *bool
+		Notes          *string
+	}
+
+	// SetDownPaymentInput is the DP snapshot written onto an order. A nil RuleID
+	// with Percent 0 removes the DP requirement.
+	SetDownPaymentInput struct {
+		RuleID        *int
+		Percent       int
+		DueAt         *time.Time
+		AutoCancel    bool
+		PaymentStatus string
 	}
 )
 
@@ -55,7 +80,7 @@ func (o *order) GetOrderByID(ctx context.Context, id int, shopID ...int) (*model
 	criteria := []interface{}{id}
 
 	q := `
-		SELECT o.id, o.shop_id, c.name as customer_name, (c.deleted_at IS NOT NULL) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at
+		SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, (c.deleted_at IS NOT NULL) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address
 		FROM orders o
 		INNER JOIN customers c ON o.customer_id = c.id
 		WHERE o.id = $1
@@ -67,7 +92,8 @@ func (o *order) GetOrderByID(ctx context.Context, id int, shopID ...int) (*model
 	}
 
 	var order model.Order
-	err := o.db.QueryRowContext(ctx, q, criteria...).Scan(&order.ID, &order.ShopID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
+	var shippingAddress []byte
+	err := o.db.QueryRowContext(ctx, q, criteria...).Scan(&order.ID, &order.ShopID, &order.CustomerID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.UniqueCode, &order.InvoiceNumber, &order.DPPercent, &order.DPDueAt, &order.DPOverdueAt, &order.CancelReason, &order.CancelNotes, &order.CancelledAt, &order.ShortageNotify, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt, &shippingAddress)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -75,12 +101,47 @@ func (o *order) GetOrderByID(ctx context.Context, id int, shopID ...int) (*model
 		return nil, err
 	}
 
+	order.ShippingAddress, err = decodeShippingAddress(shippingAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// GetOrderByIDForUpdate reads the order like GetOrderByID and locks its row
+// until tx ends, so that what is checked against it (its status, what was
+// paid on it) can't change before tx commits.
+func (o *order) GetOrderByIDForUpdate(ctx context.Context, tx database.Tx, id int) (*model.Order, error) {
+	q := `
+		SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, (c.deleted_at IS NOT NULL) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address
+		FROM orders o
+		INNER JOIN customers c ON o.customer_id = c.id
+		WHERE o.id = $1
+		FOR UPDATE OF o
+	`
+
+	var order model.Order
+	var shippingAddress []byte
+	err := tx.QueryRowContext(ctx, q, id).Scan(&order.ID, &order.ShopID, &order.CustomerID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.UniqueCode, &order.InvoiceNumber, &order.DPPercent, &order.DPDueAt, &order.DPOverdueAt, &order.CancelReason, &order.CancelNotes, &order.CancelledAt, &order.ShortageNotify, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt, &shippingAddress)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	order.ShippingAddress, err = decodeShippingAddress(shippingAddress)
+	if err != nil {
+		return nil, err
+	}
+
 	return &order, nil
 }
 
 func (o *order) GetOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]model.Order, error) {
 	q := `
-		SELECT o.id, o.shop_id, c.name as customer_name, (c.deleted_at IS NOT NULL) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at
+		SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, (c.deleted_at IS NOT NULL) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address
 		FROM orders o
 		INNER JOIN customers c ON o.customer_id = c.id
 		WHERE o.shop_id = $1
@@ -89,17 +150,17 @@ func (o *order) GetOrdersByShopID(ctx context.Context, shopID int, opts model.Or
 	argNum := 2
 
 	if opts.SearchQuery != nil && strings.TrimSpace(*opts.SearchQuery) != "" {
-		q += fmt.Sprintf(" AND (c.name ILIKE $%d OR c.phone ILIKE $%d)", argNum, argNum)
+		q += fmt.Sprintf(" AND (c.name ILIKE $%d OR c.phone ILIKE $%d OR o.invoice_number ILIKE $%d)", argNum, argNum, argNum)
 		args = append(args, "%"+strings.TrimSpace(*opts.SearchQuery)+"%")
 		argNum++
 	}
 	if opts.DateFrom != nil {
-		q += fmt.Sprintf(" AND o.created_at::date >= $%d", argNum)
+		q += fmt.Sprintf(" AND o.created_at >= $%d", argNum)
 		args = append(args, *opts.DateFrom)
 		argNum++
 	}
 	if opts.DateTo != nil {
-		q += fmt.Sprintf(" AND o.created_at::date <= $%d", argNum)
+		q += fmt.Sprintf(" AND o.created_at < $%d", argNum)
 		args = append(args, *opts.DateTo)
 		argNum++
 	}
@@ -113,6 +174,21 @@ func (o *order) GetOrdersByShopID(ctx context.Context, shopID int, opts model.Or
 		args = append(args, *opts.PaymentStatus)
 		argNum++
 	}
+	if opts.DPOverdue != nil {
+		// the flag stays on the order as history; it only counts while the DP is still unpaid
+		if *opts.DPOverdue {
+			q += fmt.Sprintf(" AND o.dp_overdue_at IS NOT NULL AND o.payment_status = $%d", argNum)
+		} else {
+			q += fmt.Sprintf(" AND (o.dp_overdue_at IS NULL OR o.payment_status != $%d)", argNum)
+		}
+		args = append(args, constant.OrderPaymentStatusAwaitingDP)
+		argNum++
+	}
+	if opts.ShortageNotify != nil {
+		q += fmt.Sprintf(" AND o.shortage_notify = $%d", argNum)
+		args = append(args, *opts.ShortageNotify)
+		argNum++
+	}
 	if opts.Sort != nil {
 		sort := strings.Split(*opts.Sort, ",")
 		if len(sort) == 2 {
@@ -145,7 +221,12 @@ func (o *order) GetOrdersByShopID(ctx context.Context, shopID int, opts model.Or
 	orders := []model.Order{}
 	for rows.Next() {
 		var order model.Order
-		err := rows.Scan(&order.ID, &order.ShopID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
+		var shippingAddress []byte
+		err := rows.Scan(&order.ID, &order.ShopID, &order.CustomerID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.UniqueCode, &order.InvoiceNumber, &order.DPPercent, &order.DPDueAt, &order.DPOverdueAt, &order.CancelReason, &order.CancelNotes, &order.CancelledAt, &order.ShortageNotify, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt, &shippingAddress)
+		if err != nil {
+			return nil, err
+		}
+		order.ShippingAddress, err = decodeShippingAddress(shippingAddress)
 		if err != nil {
 			return nil, err
 		}
@@ -157,7 +238,7 @@ func (o *order) GetOrdersByShopID(ctx context.Context, shopID int, opts model.Or
 
 func (o *order) GetActiveOrderByCustomerID(ctx context.Context, customerID int, shopID int) (*model.Order, error) {
 	q := `
-		SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at
+		SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.unique_code, o.status, o.payment_status, o.notes, o.created_at, o.updated_at
 		FROM orders o
 		INNER JOIN customers c ON o.customer_id = c.id
 		WHERE o.customer_id = $1 AND o.shop_id = $2 AND o.status IN ($3, $4)
@@ -170,6 +251,7 @@ func (o *order) GetActiveOrderByCustomerID(ctx context.Context, customerID int,
 		&order.ShopID,
 		&order.CustomerName,
 		&order.TotalPrice,
+		&order.UniqueCode,
 		&order.Status,
 		&order.PaymentStatus,
 		&order.Notes,
@@ -185,6 +267,40 @@ func (o *order) GetActiveOrderByCustomerID(ctx context.Context, customerID int,
 	return &order, nil
 }
 
+// GetOutstandingOrdersByShopID returns the shop's non-cancelled orders whose
+// payments are still below the amount due (total plus unique code), oldest first.
+func (o *order) GetOutstandingOrdersByShopID(ctx context.Context, shopID int) ([]model.OutstandingOrder, error) {
+	q := `
+		SELECT o.id, c.name as customer_name, o.total_price, o.unique_code, COALESCE(p.paid, 0) as paid_amount, o.created_at
+		FROM orders o
+		INNER JOIN customers c ON o.customer_id = c.id
+		LEFT JOIN (
+			SELECT order_id, SUM(amount) as paid
+			FROM order_payments
+			GROUP BY order_id
+		) p ON p.order_id = o.id
+		WHERE o.shop_id = $1 AND o.status != $2 AND o.payment_status != $3
+			AND o.total_price + o.unique_code > COALESCE(p.paid, 0)
+		ORDER BY o.created_at ASC
+	`
+	rows, err := o.db.QueryContext(ctx, q, shopID, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []model.OutstandingOrder{}
+	for rows.Next() {
+		var order model.OutstandingOrder
+		if err := rows.Scan(&order.ID, &order.CustomerName, &order.TotalPrice, &order.UniqueCode, &order.PaidAmount, &order.CreatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
 func (o *order) CreateOrder(ctx context.Context, tx database.Tx, customerID int, shopID int, notes *string, totalPrice *int) (*model.Order, error) {
 	now := time.Now()
 	var order model.Order
@@ -223,6 +339,232 @@ func (o *order) CreateOrder(ctx context.Context, tx database.Tx, customerID int,
 	return &order, nil
 }
 
+// AssignUniqueCode gives the order a random code from 1-999 that no other open
+// order of the shop holds, and returns it. It returns 0 when every code is
+// taken. Assignments are serialized per shop with a transaction-scoped
+// advisory lock, so tx is required.
+func (o *order) AssignUniqueCode(ctx context.Context, tx database.Tx, id int, shopID int) (int, error) {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('orders.unique_code'), $1)`, shopID)
+	if err != nil {
+		return 0, err
+	}
+
+	q := `
+		UPDATE orders
+		SET unique_code = COALESCE((
+			SELECT code
+			FROM generate_series(1, $3::int) code
+			WHERE code NOT IN (
+				SELECT unique_code
+				FROM orders
+				WHERE shop_id = $2 AND unique_code > 0 AND status != $4 AND payment_status != $5
+			)
+			ORDER BY random()
+			LIMIT 1
+		), 0), updated_at = now()
+		WHERE id = $1 AND shop_id = $2
+		RETURNING unique_code
+	`
+
+	var code int
+	err = tx.QueryRowContext(ctx, q, id, shopID, constant.UniqueCodeMax, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).Scan(&code)
+	if err != nil {
+		return 0, err
+	}
+
+	return code, nil
+}
+
+// ReclaimUniqueCode is AssignUniqueCode for an order that is about to be open
+// again, e.g. paid and then partly refunded, while still holding a code. The
+// order keeps its code unless another open order of the shop has taken it in
+// the meantime, in which case it gets a free one, or 0 when every code is
+// taken. tx is required.
+func (o *order) ReclaimUniqueCode(ctx context.Context, tx database.Tx, id int, shopID int) (int, error) {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('orders.unique_code'), $1)`, shopID)
+	if err != nil {
+		return 0, err
+	}
+
+	q := `
+		WITH taken AS (
+			SELECT unique_code
+			FROM orders
+			WHERE shop_id = $2 AND id != $1 AND unique_code > 0 AND status != $4 AND payment_status != $5
+		)
+		UPDATE orders o
+		SET unique_code = CASE
+			WHEN o.unique_code NOT IN (SELECT unique_code FROM taken) THEN o.unique_code
+			ELSE COALESCE((
+				SELECT code
+				FROM generate_series(1, $3::int) code
+				WHERE code NOT IN (SELECT unique_code FROM taken)
+				ORDER BY random()
+				LIMIT 1
+			), 0)
+		END, updated_at = now()
+		WHERE o.id = $1 AND o.shop_id = $2
+		RETURNING o.unique_code
+	`
+
+	var code int
+	err = tx.QueryRowContext(ctx, q, id, shopID, constant.UniqueCodeMax, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).Scan(&code)
+	if err != nil {
+		return 0, err
+	}
+
+	return code, nil
+}
+
+// GetInvoiceNumberForUpdate returns the order's invoice number, "" when it has
+// none yet, and locks the order row until tx ends so that concurrent exports of
+// the same order agree on one number.
+func (o *order) GetInvoiceNumberForUpdate(ctx context.Context, tx database.Tx, id int) (string, error) {
+	q := `
+		SELECT invoice_number
+		FROM orders
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	var number sql.NullString
+	err := tx.QueryRowContext(ctx, q, id).Scan(&number)
+	if err != nil {
+		return "", err
+	}
+
+	return number.String, nil
+}
+
+// SetInvoiceNumber stores the shop sequence value the invoice number was built
+// from alongside the formatted number.
+func (o *order) SetInvoiceNumber(ctx context.Context, tx database.Tx, id, seq int, number string) error {
+	q := `
+		UPDATE orders
+		SET invoice_seq = $2, invoice_number = $3, updated_at = now()
+		WHERE id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, q, id, seq, number)
+	return err
+}
+
+// SetDownPayment stores the order's DP requirement and the payment status that
+// follows from it. Any earlier overdue flag is cleared.
+func (o *order) SetDownPayment(ctx context.Context, tx database.Tx, id int, input SetDownPaymentInput) error {
+	q := `
+		UPDATE orders
+		SET dp_rule_id = $2, dp_percent = $3, dp_due_at = $4, dp_auto_cancel = $5, dp_overdue_at = NULL, payment_status = $6, updated_at = now()
+		WHERE id = $1
+	`
+
+	args := []interface{}{id, input.RuleID, input.Percent, input.DueAt, input.AutoCancel, input.PaymentStatus}
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, args...)
+	} else {
+		_, err = o.db.ExecContext(ctx, q, args...)
+	}
+
+	return err
+}
+
+// SetShippingAddress stores a copy of the address the order ships to. A nil
+// address removes it.
+func (o *order) SetShippingAddress(ctx context.Context, tx database.Tx, id int, address *model.ShippingAddress) error {
+	value, err := encodeShippingAddress(address)
+	if err != nil {
+		return err
+	}
+
+	q := `
+		UPDATE orders
+		SET shipping_address = $2, updated_at = now()
+		WHERE id = $1
+	`
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, id, value)
+	} else {
+		_, err = o.db.ExecContext(ctx, q, id, value)
+	}
+
+	return err
+}
+
+// GetDPOverdueOrders returns open orders whose DP deadline has passed while
+// their payments are still below the required DP, and that were not flagged yet.
+// Closed shops are left out.
+func (o *order) GetDPOverdueOrders(ctx context.Context, now time.Time) ([]model.DPOverdueOrder, error) {
+	q := `
+		SELECT o.id, o.shop_id, o.dp_auto_cancel
+		FROM orders o
+		JOIN shops s ON s.id = o.shop_id AND s.closed_at IS NULL
+		LEFT JOIN (
+			SELECT order_id, SUM(amount) as paid
+			FROM order_payments
+			GROUP BY order_id
+		) p ON p.order_id = o.id
+		WHERE o.dp_percent > 0 AND o.dp_overdue_at IS NULL AND o.dp_due_at < $1
+			AND o.status NOT IN ($2, $3)
+			AND COALESCE(p.paid, 0) < CEIL(o.total_price * o.dp_percent / 100.0)
+		ORDER BY o.dp_due_at ASC
+	`
+	rows, err := o.db.QueryContext(ctx, q, now, constant.OrderStatusCancelled, constant.OrderStatusDone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []model.DPOverdueOrder{}
+	for rows.Next() {
+		var order model.DPOverdueOrder
+		if err := rows.Scan(&order.ID, &order.ShopID, &order.AutoCancel); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+func (o *order) MarkDPOverdue(ctx context.Context, tx database.Tx, id int) error {
+	q := `
+		UPDATE orders
+		SET dp_overdue_at = now(), updated_at = now()
+		WHERE id = $1
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, id)
+	} else {
+		_, err = o.db.ExecContext(ctx, q, id)
+	}
+
+	return err
+}
+
+// CancelOrder sets the order to cancelled and records why. Order items are
+// kept; the purchase list only counts open orders.
+func (o *order) CancelOrder(ctx context.Context, tx database.Tx, id int, reason, notes string) error {
+	q := `
+		UPDATE orders
+		SET status = $2, cancel_reason = $3, cancel_notes = $4, cancelled_at = now(), updated_at = now()
+		WHERE id = $1
+	`
+
+	args := []interface{}{id, constant.OrderStatusCancelled, reason, notes}
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, args...)
+	} else {
+		_, err = o.db.ExecContext(ctx, q, args...)
+	}
+
+	return err
+}
+
 func (o *order) UpdateOrder(ctx context.Context, tx database.Tx, id int, input UpdateOrderInput) (*model.Order, error) {
 	set := []string{}
 	args := []interface{}{id}
@@ -235,6 +577,11 @@ func (o *order) UpdateOrder(ctx context.Context, tx database.Tx, id int, input U
 		args = append(args, *input.TotalPrice)
 		argNum++
 	}
+	if input.UniqueCode != nil {
+		set = append(set, fmt.Sprintf("unique_code = $%d", argNum))
+		args = append(args, *input.UniqueCode)
+		argNum++
+	}
 	if input.Status != nil {
 		set = append(set, fmt.Sprintf("status = $%d", argNum))
 		args = append(args, *input.Status)
@@ -245,6 +592,11 @@ func (o *order) UpdateOrder(ctx context.Context, tx database.Tx, id int, input U
 		args = append(args, *input.PaymentStatus)
 		argNum++
 	}
+	if input.ShortageNotify != nil {
+		set = append(set, fmt.Sprintf("shortage_notify = $%d", argNum))
+		args = append(args, *input.ShortageNotify)
+		argNum++
+	}
 	if input.Notes != nil {
 		set = append(set, fmt.Sprintf("notes = $%d", argNum))
 		args = append(args, *input.Notes)
@@ -258,18 +610,18 @@ func (o *order) UpdateOrder(ctx context.Context, tx database.Tx, id int, input U
 			UPDATE orders
 			SET %s
 			WHERE id = $1
-			RETURNING id, shop_id, customer_id, total_price, status, payment_status, notes, created_at, updated_at
+			RETURNING id, shop_id, customer_id, total_price, unique_code, invoice_number, dp_percent, dp_due_at, dp_overdue_at, cancel_reason, cancel_notes, cancelled_at, shortage_notify, status, payment_status, notes, created_at, updated_at
 		)
-		SELECT u.id, u.shop_id, c.name as customer_name, (c.deleted_at IS NOT NULL) as is_customer_deleted, u.total_price, u.status, u.payment_status, u.notes, u.created_at, u.updated_at
+		SELECT u.id, u.shop_id, u.customer_id, c.name as customer_name, (c.deleted_at IS NOT NULL) as is_customer_deleted, u.total_price, u.unique_code, u.invoice_number, u.dp_percent, u.dp_due_at, u.dp_overdue_at, u.cancel_reason, u.cancel_notes, u.cancelled_at, u.shortage_notify, u.status, u.payment_status, u.notes, u.created_at, u.updated_at
 		FROM updated u
 		INNER JOIN customers c ON u.customer_id = c.id
 	`, strings.Join(set, ","))
 
 	var err error
 	if tx != nil {
-		err = tx.QueryRowContext(ctx, q, args...).Scan(&order.ID, &order.ShopID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
+		err = tx.QueryRowContext(ctx, q, args...).Scan(&order.ID, &order.ShopID, &order.CustomerID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.UniqueCode, &order.InvoiceNumber, &order.DPPercent, &order.DPDueAt, &order.DPOverdueAt, &order.CancelReason, &order.CancelNotes, &order.CancelledAt, &order.ShortageNotify, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
 	} else {
-		err = o.db.QueryRowContext(ctx, q, args...).Scan(&order.ID, &order.ShopID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
+		err = o.db.QueryRowContext(ctx, q, args...).Scan(&order.ID, &order.ShopID, &order.CustomerID, &order.CustomerName, &order.IsCustomerDeleted, &order.TotalPrice, &order.UniqueCode, &order.InvoiceNumber, &order.DPPercent, &order.DPDueAt, &order.DPOverdueAt, &order.CancelReason, &order.CancelNotes, &order.CancelledAt, &order.ShortageNotify, &order.Status, &order.PaymentStatus, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
 	}
 	if err != nil {
 		return nil, err
@@ -292,24 +644,54 @@ func (o *order) DeleteOrderByID(ctx context.Context, tx database.Tx, id int) err
 	return nil
 }
 
-func (o *order) CreateTempOrder(ctx context.Context, tx database.Tx, customerName, customerPhone string, shopID int) (*model.TempOrder, error) {
+func (o *order) CreateTempOrder(ctx context.Context, tx database.Tx, customerName, customerPhone string, shopID int, shareLinkID *int, fingerprint string, shippingAddress *model.ShippingAddress) (*model.TempOrder, error) {
 	now := time.Now()
 	var tempOrder model.TempOrder
 
+	address, err := encodeShippingAddress(shippingAddress)
+	if err != nil {
+		return nil, err
+	}
+
 	q := `
-		INSERT INTO temp_orders (customer_name, customer_phone, status, shop_id, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, customer_name, customer_phone, shop_id, total_price, status, created_at
+		INSERT INTO temp_orders (customer_name, customer_phone, status, shop_id, share_link_id, fingerprint, shipping_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, customer_name, customer_phone, shop_id, share_link_id, total_price, status, created_at
 	`
 
-	err := tx.QueryRowContext(ctx, q, customerName, customerPhone, constant.TempOrderStatusPending, shopID, now).Scan(&tempOrder.ID, &tempOrder.CustomerName, &tempOrder.CustomerPhone, &tempOrder.ShopID, &tempOrder.TotalPrice, &tempOrder.Status, &tempOrder.CreatedAt)
+	err = tx.QueryRowContext(ctx, q, customerName, customerPhone, constant.TempOrderStatusPending, shopID, shareLinkID, fingerprint, address, now).Scan(&tempOrder.ID, &tempOrder.CustomerName, &tempOrder.CustomerPhone, &tempOrder.ShopID, &tempOrder.ShareLinkID, &tempOrder.TotalPrice, &tempOrder.Status, &tempOrder.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
+	tempOrder.ShippingAddress = shippingAddress
 
 	return &tempOrder, nil
 }
 
+// HasRecentTempOrder reports whether the shop got a temp order with the
+// fingerprint since the given time. It first takes a transaction lock on the
+// fingerprint, so two identical submissions racing each other are still seen
+// as a duplicate once the first commits.
+func (o *order) HasRecentTempOrder(ctx context.Context, tx database.Tx, shopID int, fingerprint string, since time.Time) (bool, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, fingerprint); err != nil {
+		return false, err
+	}
+
+	q := `
+		SELECT EXISTS (
+			SELECT 1 FROM temp_orders
+			WHERE shop_id = $1 AND fingerprint = $2 AND created_at >= $3
+		)
+	`
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, q, shopID, fingerprint, since).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
 func (o *order) UpdateTempOrderTotalPrice(ctx context.Context, tx database.Tx, tempOrderID int, totalPrice int) error {
 	q := `
 		UPDATE temp_orders
@@ -327,7 +709,7 @@ func (o *order) GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*m
 	criteria := []interface{}{id}
 
 	q := `
-		SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at
+		SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at, shipping_address
 		FROM temp_orders
 		WHERE id = $1
 	`
@@ -338,7 +720,8 @@ func (o *order) GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*m
 	}
 
 	var tempOrder model.TempOrder
-	err := o.db.QueryRowContext(ctx, q, criteria...).Scan(&tempOrder.ID, &tempOrder.ShopID, &tempOrder.CustomerName, &tempOrder.CustomerPhone, &tempOrder.TotalPrice, &tempOrder.Status, &tempOrder.CreatedAt, &tempOrder.UpdatedAt)
+	var shippingAddress []byte
+	err := o.db.QueryRowContext(ctx, q, criteria...).Scan(&tempOrder.ID, &tempOrder.ShopID, &tempOrder.ShareLinkID, &tempOrder.CustomerName, &tempOrder.CustomerPhone, &tempOrder.TotalPrice, &tempOrder.Status, &tempOrder.CreatedAt, &tempOrder.UpdatedAt, &shippingAddress)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -346,12 +729,17 @@ func (o *order) GetTempOrderByID(ctx context.Context, id int, shopID ...int) (*m
 		return nil, err
 	}
 
+	tempOrder.ShippingAddress, err = decodeShippingAddress(shippingAddress)
+	if err != nil {
+		return nil, err
+	}
+
 	return &tempOrder, nil
 }
 
 func (o *order) GetTempOrdersByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) ([]model.TempOrder, error) {
 	q := `
-		SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at
+		SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at
 		FROM temp_orders
 		WHERE shop_id = $1
 	`
@@ -364,12 +752,12 @@ func (o *order) GetTempOrdersByShopID(ctx context.Context, shopID int, opts mode
 		argNum++
 	}
 	if opts.DateFrom != nil {
-		q += fmt.Sprintf(" AND created_at::date >= $%d", argNum)
+		q += fmt.Sprintf(" AND created_at >= $%d", argNum)
 		args = append(args, *opts.DateFrom)
 		argNum++
 	}
 	if opts.DateTo != nil {
-		q += fmt.Sprintf(" AND created_at::date <= $%d", argNum)
+		q += fmt.Sprintf(" AND created_at < $%d", argNum)
 		args = append(args, *opts.DateTo)
 		argNum++
 	}
@@ -408,7 +796,7 @@ func (o *order) GetTempOrdersByShopID(ctx context.Context, shopID int, opts mode
 	tempOrders := []model.TempOrder{}
 	for rows.Next() {
 		var tempOrder model.TempOrder
-		err := rows.Scan(&tempOrder.ID, &tempOrder.ShopID, &tempOrder.CustomerName, &tempOrder.CustomerPhone, &tempOrder.TotalPrice, &tempOrder.Status, &tempOrder.CreatedAt, &tempOrder.UpdatedAt)
+		err := rows.Scan(&tempOrder.ID, &tempOrder.ShopID, &tempOrder.ShareLinkID, &tempOrder.CustomerName, &tempOrder.CustomerPhone, &tempOrder.TotalPrice, &tempOrder.Status, &tempOrder.CreatedAt, &tempOrder.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -435,3 +823,29 @@ func (o *order) UpdateTempOrderStatus(ctx context.Context, tx database.Tx, tempO
 	}
 	return nil
 }
+
+// encodeShippingAddress turns address into the value of a shipping_address
+// column; nil is stored as NULL. The JSON is passed as a string because pq
+// sends []byte as bytea.
+func encodeShippingAddress(address *model.ShippingAddress) (interface{}, error) {
+	if address == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(address)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// decodeShippingAddress reads a shipping_address column; NULL gives nil.
+func decodeShippingAddress(raw []byte) (*model.ShippingAddress, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var address model.ShippingAddress
+	if err := json.Unmarshal(raw, &address); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/database"
 	"github.com/zeirash/recapo/arion/model"
 )
@@ -23,6 +24,8 @@ type (
 		GetOrderItemByProductID(ctx context.Context, productID int, orderID int) (*model.OrderItem, error)
 		GetOrderTotalsExcludingProduct(ctx context.Context, productID int) (map[int]int, error)
 		GetNetSalesByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) (int, error)
+		GetOpenOrderItemsByProductID(ctx context.Context, tx database.Tx, shopID, productID int) ([]model.ProductOrderItem, error)
+		AllocateOrderItem(ctx context.Context, tx database.Tx, id, cut int) error
 
 		CreateTempOrderItem(ctx context.Context, tx database.Tx, tempOrderID, productID, qty int) (*model.TempOrderItem, error)
 		GetTempOrderItemsByTempOrderID(ctx context.Context, tempOrderID int) ([]model.TempOrderItem, error)
@@ -49,14 +52,14 @@ func NewOrderItemStoreWithDB(db *sql.DB) OrderItemStore {
 
 func (o *orderitem) GetOrderItemByID(ctx context.Context, id int) (*model.OrderItem, error) {
 	q := `
-		SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at
+		SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at
 		FROM order_items oi
 		INNER JOIN products p ON oi.product_id = p.id
 		WHERE oi.id = $1
 	`
 
 	var orderItem model.OrderItem
-	err := o.db.QueryRowContext(ctx, q, id).Scan(&orderItem.ID, &orderItem.OrderID, &orderItem.ProductName, &orderItem.Price, &orderItem.OriginalPrice, &orderItem.Qty, &orderItem.CreatedAt, &orderItem.UpdatedAt)
+	err := o.db.QueryRowContext(ctx, q, id).Scan(&orderItem.ID, &orderItem.OrderID, &orderItem.ProductName, &orderItem.Price, &orderItem.OriginalPrice, &orderItem.Qty, &orderItem.ShortageQty, &orderItem.AllocatedQty, &orderItem.CreatedAt, &orderItem.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -69,7 +72,7 @@ func (o *orderitem) GetOrderItemByID(ctx context.Context, id int) (*model.OrderI
 
 func (o *orderitem) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]model.OrderItem, error) {
 	q := `
-		SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at
+		SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at
 		FROM order_items oi
 		INNER JOIN products p ON oi.product_id = p.id
 		WHERE oi.order_id = $1
@@ -85,7 +88,7 @@ func (o *orderitem) GetOrderItemsByOrderID(ctx context.Context, orderID int) ([]
 	orderItems := []model.OrderItem{}
 	for rows.Next() {
 		var orderItem model.OrderItem
-		err := rows.Scan(&orderItem.ID, &orderItem.OrderID, &orderItem.ProductName, &orderItem.Price, &orderItem.OriginalPrice, &orderItem.Qty, &orderItem.CreatedAt, &orderItem.UpdatedAt)
+		err := rows.Scan(&orderItem.ID, &orderItem.OrderID, &orderItem.ProductName, &orderItem.Price, &orderItem.OriginalPrice, &orderItem.Qty, &orderItem.ShortageQty, &orderItem.AllocatedQty, &orderItem.CreatedAt, &orderItem.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -292,14 +295,14 @@ func (o *orderitem) GetTempOrderItemsByTempOrderID(ctx context.Context, tempOrde
 
 func (o *orderitem) GetOrderItemByProductID(ctx context.Context, productID int, orderID int) (*model.OrderItem, error) {
 	q := `
-		SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at
+		SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at
 		FROM order_items oi
 		INNER JOIN products p ON oi.product_id = p.id
 		WHERE oi.product_id = $1 AND oi.order_id = $2
 	`
 
 	var orderItem model.OrderItem
-	err := o.db.QueryRowContext(ctx, q, productID, orderID).Scan(&orderItem.ID, &orderItem.OrderID, &orderItem.ProductName, &orderItem.Price, &orderItem.OriginalPrice, &orderItem.Qty, &orderItem.CreatedAt, &orderItem.UpdatedAt)
+	err := o.db.QueryRowContext(ctx, q, productID, orderID).Scan(&orderItem.ID, &orderItem.OrderID, &orderItem.ProductName, &orderItem.Price, &orderItem.OriginalPrice, &orderItem.Qty, &orderItem.ShortageQty, &orderItem.AllocatedQty, &orderItem.CreatedAt, &orderItem.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -310,24 +313,26 @@ func (o *orderitem) GetOrderItemByProductID(ctx context.Context, productID int,
 	return &orderItem, nil
 }
 
+// GetNetSalesByShopID sums the margin of ordered items. Cancelled orders are
+// left out since their goods were never sold.
 func (o *orderitem) GetNetSalesByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) (int, error) {
-	args := []interface{}{shopID}
+	args := []interface{}{shopID, constant.OrderStatusCancelled}
 	q := `
 		SELECT COALESCE(SUM((p.price - p.original_price) * oi.qty), 0)
 		FROM order_items oi
 		JOIN orders ord ON ord.id = oi.order_id
 		JOIN products p ON p.id = oi.product_id
-		WHERE ord.shop_id = $1
+		WHERE ord.shop_id = $1 AND ord.status != $2
 	`
 
-	argIdx := 2
+	argIdx := 3
 	if opts.DateFrom != nil {
-		q += fmt.Sprintf(" AND ord.created_at::date >= $%d", argIdx)
+		q += fmt.Sprintf(" AND ord.created_at >= $%d", argIdx)
 		args = append(args, *opts.DateFrom)
 		argIdx++
 	}
 	if opts.DateTo != nil {
-		q += fmt.Sprintf(" AND ord.created_at::date <= $%d", argIdx)
+		q += fmt.Sprintf(" AND ord.created_at < $%d", argIdx)
 		args = append(args, *opts.DateTo)
 		argIdx++
 	}
@@ -339,3 +344,63 @@ func (o *orderitem) GetNetSalesByShopID(ctx context.Context, shopID int, opts mo
 	}
 	return total, nil
 }
+
+// GetOpenOrderItemsByProductID returns the product's items in the shop's open
+// orders, oldest order first. These are the items bought stock of the product
+// is allocated over. Inside a transaction the items are locked until tx ends.
+func (o *orderitem) GetOpenOrderItemsByProductID(ctx context.Context, tx database.Tx, shopID, productID int) ([]model.ProductOrderItem, error) {
+	q := `
+		SELECT oi.id, oi.order_id, c.name as customer_name, c.priority, o.payment_status, p.price, oi.qty, o.created_at
+		FROM order_items oi
+		INNER JOIN orders o ON oi.order_id = o.id
+		INNER JOIN customers c ON o.customer_id = c.id
+		INNER JOIN products p ON oi.product_id = p.id
+		WHERE o.shop_id = $1 AND oi.product_id = $2 AND o.status IN ($3, $4) AND oi.qty > 0
+		ORDER BY o.created_at ASC, o.id ASC
+	`
+
+	args := []interface{}{shopID, productID, constant.OrderStatusCreated, constant.OrderStatusInProgress}
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, q+" FOR UPDATE OF oi", args...)
+	} else {
+		rows, err = o.db.QueryContext(ctx, q, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.ProductOrderItem{}
+	for rows.Next() {
+		var item model.ProductOrderItem
+		err := rows.Scan(&item.ID, &item.OrderID, &item.CustomerName, &item.CustomerPriority, &item.OrderPaymentStatus, &item.Price, &item.Qty, &item.OrderCreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// AllocateOrderItem settles an order item at what it was allocated: cut units
+// come off qty and are recorded as short, so the order still shows what the
+// customer originally asked for, and the remaining qty is kept as allocated.
+func (o *orderitem) AllocateOrderItem(ctx context.Context, tx database.Tx, id, cut int) error {
+	q := `
+		UPDATE order_items
+		SET qty = qty - $2, shortage_qty = shortage_qty + $2, allocated_qty = qty - $2, updated_at = now()
+		WHERE id = $1
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, id, cut)
+	} else {
+		_, err = o.db.ExecContext(ctx, q, id, cut)
+	}
+
+	return err
+}
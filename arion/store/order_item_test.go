@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/model"
 )
 
@@ -26,9 +27,9 @@ func Test_orderitem_GetOrderItemByID(t *testing.T) {
 			name: "get order item by ID",
 			id:   1,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "created_at", "updated_at"}).
-					AddRow(1, 10, "Product A", 1000, 800, 2, fixedTime, nil)
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "shortage_qty", "allocated_qty", "created_at", "updated_at"}).
+					AddRow(1, 10, "Product A", 1000, 800, 2, 0, nil, fixedTime, nil)
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.id = \$1`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -47,7 +48,7 @@ func Test_orderitem_GetOrderItemByID(t *testing.T) {
 			name: "get non-existent order item returns nil",
 			id:   9999,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.id = \$1`).
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.id = \$1`).
 					WithArgs(9999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -58,7 +59,7 @@ func Test_orderitem_GetOrderItemByID(t *testing.T) {
 			name: "get order item returns error on database failure",
 			id:   1,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.id = \$1`).
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.id = \$1`).
 					WithArgs(1).
 					WillReturnError(errors.New("database error"))
 			},
@@ -111,10 +112,10 @@ func Test_orderitem_GetOrderItemsByOrderID(t *testing.T) {
 			name:    "get order items by order ID returns multiple items",
 			orderID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "created_at", "updated_at"}).
-					AddRow(1, 10, "Product A", 1000, 800, 2, fixedTime, nil).
-					AddRow(2, 10, "Product B", 2000, 1500, 1, fixedTime, nil)
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.order_id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "shortage_qty", "allocated_qty", "created_at", "updated_at"}).
+					AddRow(1, 10, "Product A", 1000, 800, 2, 0, nil, fixedTime, nil).
+					AddRow(2, 10, "Product B", 2000, 1500, 1, 0, nil, fixedTime, nil)
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.order_id = \$1`).
 					WithArgs(10).
 					WillReturnRows(rows)
 			},
@@ -144,8 +145,8 @@ func Test_orderitem_GetOrderItemsByOrderID(t *testing.T) {
 			name:    "get order items by order ID returns empty slice when no items exist",
 			orderID: 9999,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "created_at", "updated_at"})
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.order_id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "shortage_qty", "allocated_qty", "created_at", "updated_at"})
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.order_id = \$1`).
 					WithArgs(9999).
 					WillReturnRows(rows)
 			},
@@ -156,7 +157,7 @@ func Test_orderitem_GetOrderItemsByOrderID(t *testing.T) {
 			name:    "get order items returns error on database failure",
 			orderID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.order_id = \$1`).
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.order_id = \$1`).
 					WithArgs(10).
 					WillReturnError(errors.New("database error"))
 			},
@@ -769,9 +770,9 @@ func Test_orderitem_GetOrderItemByProductID(t *testing.T) {
 			productID: 5,
 			orderID:   10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "created_at", "updated_at"}).
-					AddRow(1, 10, "Product A", 1000, 800, 3, fixedTime, nil)
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.product_id = \$1 AND oi.order_id = \$2`).
+				rows := sqlmock.NewRows([]string{"id", "order_id", "product_name", "price", "original_price", "qty", "shortage_qty", "allocated_qty", "created_at", "updated_at"}).
+					AddRow(1, 10, "Product A", 1000, 800, 3, 0, nil, fixedTime, nil)
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.product_id = \$1 AND oi.order_id = \$2`).
 					WithArgs(5, 10).
 					WillReturnRows(rows)
 			},
@@ -791,7 +792,7 @@ func Test_orderitem_GetOrderItemByProductID(t *testing.T) {
 			productID: 99,
 			orderID:   10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.product_id = \$1 AND oi.order_id = \$2`).
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.product_id = \$1 AND oi.order_id = \$2`).
 					WithArgs(99, 10).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -803,7 +804,7 @@ func Test_orderitem_GetOrderItemByProductID(t *testing.T) {
 			productID: 5,
 			orderID:   10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.product_id = \$1 AND oi.order_id = \$2`).
+				mock.ExpectQuery(`SELECT oi.id, oi.order_id, p.name as product_name, p.price as price, p.original_price, oi.qty, oi.shortage_qty, oi.allocated_qty, oi.created_at, oi.updated_at\s+FROM order_items oi\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE oi.product_id = \$1 AND oi.order_id = \$2`).
 					WithArgs(5, 10).
 					WillReturnError(errors.New("database error"))
 			},
@@ -1001,8 +1002,8 @@ func Test_orderitem_GetNetSalesByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(50000)
-				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)`).
-					WithArgs(1).
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)\s+FROM order_items oi\s+JOIN orders ord ON ord\.id = oi\.order_id\s+JOIN products p ON p\.id = oi\.product_id\s+WHERE ord\.shop_id = \$1 AND ord\.status != \$2`).
+					WithArgs(1, constant.OrderStatusCancelled).
 					WillReturnRows(rows)
 			},
 			wantResult: 50000,
@@ -1014,8 +1015,8 @@ func Test_orderitem_GetNetSalesByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(0)
-				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)`).
-					WithArgs(99).
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)\s+FROM order_items oi\s+JOIN orders ord ON ord\.id = oi\.order_id\s+JOIN products p ON p\.id = oi\.product_id\s+WHERE ord\.shop_id = \$1 AND ord\.status != \$2`).
+					WithArgs(99, constant.OrderStatusCancelled).
 					WillReturnRows(rows)
 			},
 			wantResult: 0,
@@ -1027,8 +1028,8 @@ func Test_orderitem_GetNetSalesByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateFrom: &dateFrom},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(20000)
-				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)`).
-					WithArgs(1, dateFrom).
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)\s+FROM order_items oi\s+JOIN orders ord ON ord\.id = oi\.order_id\s+JOIN products p ON p\.id = oi\.product_id\s+WHERE ord\.shop_id = \$1 AND ord\.status != \$2`).
+					WithArgs(1, constant.OrderStatusCancelled, dateFrom).
 					WillReturnRows(rows)
 			},
 			wantResult: 20000,
@@ -1040,8 +1041,8 @@ func Test_orderitem_GetNetSalesByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateTo: &dateTo},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(35000)
-				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)`).
-					WithArgs(1, dateTo).
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)\s+FROM order_items oi\s+JOIN orders ord ON ord\.id = oi\.order_id\s+JOIN products p ON p\.id = oi\.product_id\s+WHERE ord\.shop_id = \$1 AND ord\.status != \$2`).
+					WithArgs(1, constant.OrderStatusCancelled, dateTo).
 					WillReturnRows(rows)
 			},
 			wantResult: 35000,
@@ -1053,8 +1054,8 @@ func Test_orderitem_GetNetSalesByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(15000)
-				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)`).
-					WithArgs(1, dateFrom, dateTo).
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)\s+FROM order_items oi\s+JOIN orders ord ON ord\.id = oi\.order_id\s+JOIN products p ON p\.id = oi\.product_id\s+WHERE ord\.shop_id = \$1 AND ord\.status != \$2`).
+					WithArgs(1, constant.OrderStatusCancelled, dateFrom, dateTo).
 					WillReturnRows(rows)
 			},
 			wantResult: 15000,
@@ -1065,8 +1066,8 @@ func Test_orderitem_GetNetSalesByShopID(t *testing.T) {
 			shopID: 1,
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)`).
-					WithArgs(1).
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(\(p\.price - p\.original_price\) \* oi\.qty\), 0\)\s+FROM order_items oi\s+JOIN orders ord ON ord\.id = oi\.order_id\s+JOIN products p ON p\.id = oi\.product_id\s+WHERE ord\.shop_id = \$1 AND ord\.status != \$2`).
+					WithArgs(1, constant.OrderStatusCancelled).
 					WillReturnError(errors.New("database error"))
 			},
 			wantResult: 0,
@@ -1102,3 +1103,142 @@ func Test_orderitem_GetNetSalesByShopID(t *testing.T) {
 		})
 	}
 }
+
+func Test_orderitem_GetOpenOrderItemsByProductID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT oi.id, oi.order_id, c.name as customer_name, c.priority, o.payment_status, p.price, oi.qty, o.created_at\s+FROM order_items oi\s+INNER JOIN orders o ON oi.order_id = o.id\s+INNER JOIN customers c ON o.customer_id = c.id\s+INNER JOIN products p ON oi.product_id = p.id\s+WHERE o.shop_id = \$1 AND oi.product_id = \$2 AND o.status IN \(\$3, \$4\) AND oi.qty > 0\s+ORDER BY o.created_at ASC, o.id ASC`
+	columns := []string{"id", "order_id", "customer_name", "priority", "payment_status", "price", "qty", "created_at"}
+
+	tests := []struct {
+		name      string
+		useTx     bool
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.ProductOrderItem
+		wantErr   bool
+	}{
+		{
+			name: "returns open order items oldest first",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(3, 7, "Ani", 0, constant.OrderPaymentStatusOutstanding, 10000, 2, fixedTime).
+					AddRow(4, 9, "Budi", 2, constant.OrderPaymentStatusPaid, 10000, 5, fixedTime.Add(time.Hour))
+				mock.ExpectQuery(query).
+					WithArgs(10, 5, constant.OrderStatusCreated, constant.OrderStatusInProgress).
+					WillReturnRows(rows)
+			},
+			want: []model.ProductOrderItem{
+				{ID: 3, OrderID: 7, CustomerName: "Ani", OrderPaymentStatus: constant.OrderPaymentStatusOutstanding, Price: 10000, Qty: 2, OrderCreatedAt: fixedTime},
+				{ID: 4, OrderID: 9, CustomerName: "Budi", CustomerPriority: 2, OrderPaymentStatus: constant.OrderPaymentStatusPaid, Price: 10000, Qty: 5, OrderCreatedAt: fixedTime.Add(time.Hour)},
+			},
+		},
+		{
+			name: "returns empty slice when no open order has the product",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs(10, 5, constant.OrderStatusCreated, constant.OrderStatusInProgress).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.ProductOrderItem{},
+		},
+		{
+			name:  "locks the items inside transaction",
+			useTx: true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query+`\s+FOR UPDATE OF oi`).
+					WithArgs(10, 5, constant.OrderStatusCreated, constant.OrderStatusInProgress).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.ProductOrderItem{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			var got []model.ProductOrderItem
+			var gotErr error
+			store := NewOrderItemStoreWithDB(db)
+			if tt.useTx {
+				tx, err := db.Begin()
+				if err != nil {
+					t.Fatalf("failed to begin transaction: %v", err)
+				}
+				got, gotErr = store.GetOpenOrderItemsByProductID(context.Background(), tx, 10, 5)
+			} else {
+				got, gotErr = store.GetOpenOrderItemsByProductID(context.Background(), nil, 10, 5)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetOpenOrderItemsByProductID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetOpenOrderItemsByProductID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOpenOrderItemsByProductID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_orderitem_AllocateOrderItem(t *testing.T) {
+	query := `UPDATE order_items\s+SET qty = qty - \$2, shortage_qty = shortage_qty \+ \$2, allocated_qty = qty - \$2, updated_at = now\(\)\s+WHERE id = \$1`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "moves cut units to shortage and keeps the rest as allocated",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(3, 2).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			store := NewOrderItemStoreWithDB(db)
+			gotErr := store.AllocateOrderItem(context.Background(), nil, 3, 2)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("AllocateOrderItem() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -13,10 +13,11 @@ import (
 type (
 	OrderPaymentStore interface {
 		CreateOrderPayment(ctx context.Context, tx database.Tx, orderID int, amount int) (*model.OrderPayment, error)
-		GetOrderPaymentsByOrderID(ctx context.Context, orderID int) ([]model.OrderPayment, error)
+		CreateStatementLine(ctx context.Context, tx database.Tx, input CreateStatementLineInput) (bool, error)
+		GetOrderPaymentsByOrderID(ctx context.Context, tx database.Tx, orderID int) ([]model.OrderPayment, error)
 		GetPaymentsSumByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) (int, error)
 		UpdateOrderPaymentAmountByID(ctx context.Context, tx database.Tx, id, orderID, amount int) (*model.OrderPayment, error)
-		DeleteOrderPaymentByID(ctx context.Context, id, orderID int) error
+		DeleteOrderPaymentByID(ctx context.Context, tx database.Tx, id, orderID int) error
 		DeleteOrderPaymentsByOrderID(ctx context.Context, tx database.Tx, orderID int) error
 	}
 
@@ -28,6 +29,16 @@ type (
 		OrderID int
 		Amount  int
 	}
+
+	// CreateStatementLineInput is a bank statement credit recorded as the
+	// payment PaymentID.
+	CreateStatementLineInput struct {
+		ShopID      int
+		PaymentID   int
+		Date        time.Time
+		Description string
+		Amount      int
+	}
 )
 
 func NewOrderPaymentStore() OrderPaymentStore {
@@ -66,13 +77,48 @@ func (o *orderpayment) CreateOrderPayment(ctx context.Context, tx database.Tx, o
 	}, nil
 }
 
-func (o *orderpayment) GetOrderPaymentsByOrderID(ctx context.Context, orderID int) ([]model.OrderPayment, error) {
+// CreateStatementLine records that the statement line was confirmed as a
+// payment. It returns false, and records nothing, when the shop already
+// matched the line.
+func (o *orderpayment) CreateStatementLine(ctx context.Context, tx database.Tx, input CreateStatementLineInput) (bool, error) {
+	q := `
+		INSERT INTO bank_statement_lines (shop_id, payment_id, date, description, amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (shop_id, date, description, amount) DO NOTHING
+	`
+	args := []interface{}{input.ShopID, input.PaymentID, input.Date, input.Description, input.Amount, time.Now()}
+	var res sql.Result
+	var err error
+	if tx != nil {
+		res, err = tx.ExecContext(ctx, q, args...)
+	} else {
+		res, err = o.db.ExecContext(ctx, q, args...)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (o *orderpayment) GetOrderPaymentsByOrderID(ctx context.Context, tx database.Tx, orderID int) ([]model.OrderPayment, error) {
 	q := `
 		SELECT id, order_id, amount, created_at, updated_at
 		FROM order_payments
 		WHERE order_id = $1
 	`
-	rows, err := o.db.QueryContext(ctx, q, orderID)
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, q, orderID)
+	} else {
+		rows, err = o.db.QueryContext(ctx, q, orderID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -114,13 +160,18 @@ func (o *orderpayment) UpdateOrderPaymentAmountByID(ctx context.Context, tx data
 	return &orderPayment, nil
 }
 
-func (o *orderpayment) DeleteOrderPaymentByID(ctx context.Context, id, orderID int) error {
+func (o *orderpayment) DeleteOrderPaymentByID(ctx context.Context, tx database.Tx, id, orderID int) error {
 	q := `
 		DELETE FROM order_payments
 		WHERE id = $1 AND order_id = $2
 	`
 
-	_, err := o.db.ExecContext(ctx, q, id, orderID)
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, id, orderID)
+	} else {
+		_, err = o.db.ExecContext(ctx, q, id, orderID)
+	}
 	if err != nil {
 		return err
 	}
@@ -157,12 +208,12 @@ func (o *orderpayment) GetPaymentsSumByShopID(ctx context.Context, shopID int, o
 
 	argIdx := 2
 	if opts.DateFrom != nil {
-		q += fmt.Sprintf(" AND op.created_at::date >= $%d", argIdx)
+		q += fmt.Sprintf(" AND op.created_at >= $%d", argIdx)
 		args = append(args, *opts.DateFrom)
 		argIdx++
 	}
 	if opts.DateTo != nil {
-		q += fmt.Sprintf(" AND op.created_at::date <= $%d", argIdx)
+		q += fmt.Sprintf(" AND op.created_at < $%d", argIdx)
 		args = append(args, *opts.DateTo)
 		argIdx++
 	}
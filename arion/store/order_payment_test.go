@@ -112,6 +112,69 @@ func Test_orderpayment_CreateOrderPayment(t *testing.T) {
 	}
 }
 
+func Test_orderpayment_CreateStatementLine(t *testing.T) {
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	input := CreateStatementLineInput{ShopID: 10, PaymentID: 7, Date: date, Description: "TRSF E-BANKING CR SITI", Amount: 150000}
+	query := `INSERT INTO bank_statement_lines \(shop_id, payment_id, date, description, amount, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\)\s+ON CONFLICT \(shop_id, date, description, amount\) DO NOTHING`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name: "records a new line",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(10, 7, date, "TRSF E-BANKING CR SITI", 150000, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			want: true,
+		},
+		{
+			name: "returns false when the shop already matched the line",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(10, 7, date, "TRSF E-BANKING CR SITI", 150000, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			want: false,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			store := NewOrderPaymentStoreWithDB(db)
+
+			got, gotErr := store.CreateStatementLine(context.Background(), nil, input)
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("CreateStatementLine() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CreateStatementLine() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
 func Test_orderpayment_GetOrderPaymentsByOrderID(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 	updatedTime := time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC)
@@ -119,6 +182,7 @@ func Test_orderpayment_GetOrderPaymentsByOrderID(t *testing.T) {
 	tests := []struct {
 		name       string
 		orderID    int
+		useTx      bool
 		mockSetup  func(mock sqlmock.Sqlmock)
 		wantResult []model.OrderPayment
 		wantErr    bool
@@ -152,6 +216,23 @@ func Test_orderpayment_GetOrderPaymentsByOrderID(t *testing.T) {
 			wantResult: []model.OrderPayment{},
 			wantErr:    false,
 		},
+		{
+			name:    "reads through the transaction when one is given",
+			orderID: 10,
+			useTx:   true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"id", "order_id", "amount", "created_at", "updated_at"}).
+					AddRow(1, 10, 50000, fixedTime, nil)
+				mock.ExpectQuery(`SELECT id, order_id, amount, created_at, updated_at\s+FROM order_payments\s+WHERE order_id = \$1`).
+					WithArgs(10).
+					WillReturnRows(rows)
+			},
+			wantResult: []model.OrderPayment{
+				{ID: 1, OrderID: 10, Amount: 50000, CreatedAt: fixedTime},
+			},
+			wantErr: false,
+		},
 		{
 			name:    "returns error on database failure",
 			orderID: 10,
@@ -176,7 +257,17 @@ func Test_orderpayment_GetOrderPaymentsByOrderID(t *testing.T) {
 			tt.mockSetup(mock)
 			store := NewOrderPaymentStoreWithDB(db)
 
-			got, gotErr := store.GetOrderPaymentsByOrderID(context.Background(), tt.orderID)
+			var got []model.OrderPayment
+			var gotErr error
+			if tt.useTx {
+				tx, err := db.Begin()
+				if err != nil {
+					t.Fatalf("failed to begin transaction: %v", err)
+				}
+				got, gotErr = store.GetOrderPaymentsByOrderID(context.Background(), tx, tt.orderID)
+			} else {
+				got, gotErr = store.GetOrderPaymentsByOrderID(context.Background(), nil, tt.orderID)
+			}
 
 			if gotErr != nil {
 				if !tt.wantErr {
@@ -338,6 +429,7 @@ func Test_orderpayment_DeleteOrderPaymentByID(t *testing.T) {
 			id:      1,
 			orderID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
 				mock.ExpectExec(`DELETE FROM order_payments\s+WHERE id = \$1 AND order_id = \$2`).
 					WithArgs(1, 10).
 					WillReturnResult(sqlmock.NewResult(0, 1))
@@ -349,6 +441,7 @@ func Test_orderpayment_DeleteOrderPaymentByID(t *testing.T) {
 			id:      9999,
 			orderID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
 				mock.ExpectExec(`DELETE FROM order_payments\s+WHERE id = \$1 AND order_id = \$2`).
 					WithArgs(9999, 10).
 					WillReturnResult(sqlmock.NewResult(0, 0))
@@ -360,6 +453,7 @@ func Test_orderpayment_DeleteOrderPaymentByID(t *testing.T) {
 			id:      1,
 			orderID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
 				mock.ExpectExec(`DELETE FROM order_payments\s+WHERE id = \$1 AND order_id = \$2`).
 					WithArgs(1, 10).
 					WillReturnError(errors.New("database error"))
@@ -377,9 +471,14 @@ func Test_orderpayment_DeleteOrderPaymentByID(t *testing.T) {
 			defer db.Close()
 
 			tt.mockSetup(mock)
-			store := NewOrderPaymentStoreWithDB(db)
 
-			gotErr := store.DeleteOrderPaymentByID(context.Background(), tt.id, tt.orderID)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			store := NewOrderPaymentStoreWithDB(db)
+			gotErr := store.DeleteOrderPaymentByID(context.Background(), tx, tt.id, tt.orderID)
 
 			if gotErr != nil {
 				if !tt.wantErr {
@@ -513,7 +612,7 @@ func Test_orderpayment_GetPaymentsSumByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateFrom: &dateFrom},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(75000)
-				mock.ExpectQuery(baseQuery + ` AND op\.created_at::date >= \$2`).
+				mock.ExpectQuery(baseQuery + ` AND op\.created_at >= \$2`).
 					WithArgs(1, dateFrom).
 					WillReturnRows(rows)
 			},
@@ -526,7 +625,7 @@ func Test_orderpayment_GetPaymentsSumByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateTo: &dateTo},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(50000)
-				mock.ExpectQuery(baseQuery + ` AND op\.created_at::date <= \$2`).
+				mock.ExpectQuery(baseQuery + ` AND op\.created_at < \$2`).
 					WithArgs(1, dateTo).
 					WillReturnRows(rows)
 			},
@@ -539,7 +638,7 @@ func Test_orderpayment_GetPaymentsSumByShopID(t *testing.T) {
 			opts:   model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(120000)
-				mock.ExpectQuery(baseQuery + ` AND op\.created_at::date >= \$2 AND op\.created_at::date <= \$3`).
+				mock.ExpectQuery(baseQuery + ` AND op\.created_at >= \$2 AND op\.created_at < \$3`).
 					WithArgs(1, dateFrom, dateTo).
 					WillReturnRows(rows)
 			},
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	OrderRefundStore interface {
+		CreateOrderRefund(ctx context.Context, tx database.Tx, input CreateOrderRefundInput) (*model.OrderRefund, error)
+		GetOrderRefundsByOrderID(ctx context.Context, orderID int) ([]model.OrderRefund, error)
+		GetRefundsSumByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) (int, error)
+	}
+
+	orderrefund struct {
+		db *sql.DB
+	}
+
+	CreateOrderRefundInput struct {
+		OrderID   int
+		PaymentID int
+		Amount    int
+		Method    string
+		Reason    string
+		ProofURL  string
+		Notes     string
+	}
+)
+
+func NewOrderRefundStore() OrderRefundStore {
+	return &orderrefund{db: database.GetDB()}
+}
+
+// NewOrderRefundStoreWithDB creates an OrderRefundStore with a custom db connection (for testing)
+func NewOrderRefundStoreWithDB(db *sql.DB) OrderRefundStore {
+	return &orderrefund{db: db}
+}
+
+func (o *orderrefund) CreateOrderRefund(ctx context.Context, tx database.Tx, input CreateOrderRefundInput) (*model.OrderRefund, error) {
+	now := time.Now()
+	q := `
+		INSERT INTO order_refunds (order_id, payment_id, amount, method, reason, proof_url, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	var id int
+	var err error
+	args := []interface{}{input.OrderID, input.PaymentID, input.Amount, input.Method, input.Reason, input.ProofURL, input.Notes, now}
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, q, args...).Scan(&id)
+	} else {
+		err = o.db.QueryRowContext(ctx, q, args...).Scan(&id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OrderRefund{
+		ID:        id,
+		OrderID:   input.OrderID,
+		PaymentID: input.PaymentID,
+		Amount:    input.Amount,
+		Method:    input.Method,
+		Reason:    input.Reason,
+		ProofURL:  input.ProofURL,
+		Notes:     input.Notes,
+		CreatedAt: now,
+	}, nil
+}
+
+func (o *orderrefund) GetOrderRefundsByOrderID(ctx context.Context, orderID int) ([]model.OrderRefund, error) {
+	q := `
+		SELECT id, order_id, payment_id, amount, method, reason, proof_url, notes, created_at
+		FROM order_refunds
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := o.db.QueryContext(ctx, q, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []model.OrderRefund{}
+	for rows.Next() {
+		var refund model.OrderRefund
+		err := rows.Scan(&refund.ID, &refund.OrderID, &refund.PaymentID, &refund.Amount, &refund.Method, &refund.Reason, &refund.ProofURL, &refund.Notes, &refund.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, nil
+}
+
+func (o *orderrefund) GetRefundsSumByShopID(ctx context.Context, shopID int, opts model.OrderFilterOptions) (int, error) {
+	args := []interface{}{shopID}
+	q := `
+		SELECT COALESCE(SUM(r.amount), 0)
+		FROM order_refunds r
+		JOIN orders ord ON ord.id = r.order_id
+		WHERE ord.shop_id = $1
+	`
+
+	argIdx := 2
+	if opts.DateFrom != nil {
+		q += fmt.Sprintf(" AND r.created_at >= $%d", argIdx)
+		args = append(args, *opts.DateFrom)
+		argIdx++
+	}
+	if opts.DateTo != nil {
+		q += fmt.Sprintf(" AND r.created_at < $%d", argIdx)
+		args = append(args, *opts.DateTo)
+		argIdx++
+	}
+
+	var total int
+	err := o.db.QueryRowContext(ctx, q, args...).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_orderrefund_CreateOrderRefund(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     CreateOrderRefundInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.OrderRefund
+		wantErr   bool
+	}{
+		{
+			name:  "successfully create refund",
+			input: CreateOrderRefundInput{OrderID: 7, PaymentID: 21, Amount: 150000, Method: constant.RefundMethodTransfer, Reason: constant.OrderReasonOutOfStock, ProofURL: "https://cdn.example.com/refunds/a.jpg", Notes: "BCA"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO order_refunds \(order_id, payment_id, amount, method, reason, proof_url, notes, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)\s+RETURNING id`).
+					WithArgs(7, 21, 150000, constant.RefundMethodTransfer, constant.OrderReasonOutOfStock, "https://cdn.example.com/refunds/a.jpg", "BCA", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+			},
+			want: &model.OrderRefund{ID: 3, OrderID: 7, PaymentID: 21, Amount: 150000, Method: constant.RefundMethodTransfer, Reason: constant.OrderReasonOutOfStock, ProofURL: "https://cdn.example.com/refunds/a.jpg", Notes: "BCA"},
+		},
+		{
+			name:  "returns error on database failure",
+			input: CreateOrderRefundInput{OrderID: 7, PaymentID: 21, Amount: 1000, Method: constant.RefundMethodCash, Reason: constant.OrderReasonOther},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO order_refunds`).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewOrderRefundStoreWithDB(db)
+			got, gotErr := s.CreateOrderRefund(context.Background(), nil, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateOrderRefund() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateOrderRefund() succeeded unexpectedly")
+			}
+
+			tt.want.CreatedAt = got.CreatedAt
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateOrderRefund() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_orderrefund_GetOrderRefundsByOrderID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, order_id, payment_id, amount, method, reason, proof_url, notes, created_at\s+FROM order_refunds\s+WHERE order_id = \$1\s+ORDER BY created_at ASC, id ASC`
+	columns := []string{"id", "order_id", "payment_id", "amount", "method", "reason", "proof_url", "notes", "created_at"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.OrderRefund
+		wantErr   bool
+	}{
+		{
+			name: "returns refunds",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, 7, 20, 50000, constant.RefundMethodCash, constant.OrderReasonDamaged, "", "", fixedTime).
+					AddRow(2, 7, 21, 100000, constant.RefundMethodCredit, constant.OrderReasonCustomerCancelled, "", "rest of DP", fixedTime)
+				mock.ExpectQuery(query).WithArgs(7).WillReturnRows(rows)
+			},
+			want: []model.OrderRefund{
+				{ID: 1, OrderID: 7, PaymentID: 20, Amount: 50000, Method: constant.RefundMethodCash, Reason: constant.OrderReasonDamaged, CreatedAt: fixedTime},
+				{ID: 2, OrderID: 7, PaymentID: 21, Amount: 100000, Method: constant.RefundMethodCredit, Reason: constant.OrderReasonCustomerCancelled, Notes: "rest of DP", CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when order has no refunds",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(7).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.OrderRefund{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(7).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewOrderRefundStoreWithDB(db)
+			got, gotErr := s.GetOrderRefundsByOrderID(context.Background(), 7)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetOrderRefundsByOrderID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetOrderRefundsByOrderID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOrderRefundsByOrderID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_orderrefund_GetRefundsSumByShopID(t *testing.T) {
+	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	query := `SELECT COALESCE\(SUM\(r\.amount\), 0\)\s+FROM order_refunds r\s+JOIN orders ord ON ord\.id = r\.order_id\s+WHERE ord\.shop_id = \$1`
+
+	tests := []struct {
+		name      string
+		opts      model.OrderFilterOptions
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      int
+		wantErr   bool
+	}{
+		{
+			name: "returns refunds with no date filter",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(250000))
+			},
+			want: 250000,
+		},
+		{
+			name: "filters by date range",
+			opts: model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query+`\s+AND r\.created_at >= \$2 AND r\.created_at < \$3`).
+					WithArgs(1, dateFrom, dateTo).
+					WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(50000))
+			},
+			want: 50000,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewOrderRefundStoreWithDB(db)
+			got, gotErr := s.GetRefundsSumByShopID(context.Background(), 1, tt.opts)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetRefundsSumByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetRefundsSumByShopID() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("GetRefundsSumByShopID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
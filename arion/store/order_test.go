@@ -30,15 +30,16 @@ func Test_order_GetOrderByID(t *testing.T) {
 			id:     1,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
 			wantResult: &model.Order{
 				ID:           1,
 				ShopID:       10,
+				CustomerID:   5,
 				CustomerName: "John Doe",
 				TotalPrice:   5000,
 				Status:       "in_progress",
@@ -51,15 +52,16 @@ func Test_order_GetOrderByID(t *testing.T) {
 			id:     1,
 			shopID: []int{10},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1\s+AND o.shop_id = \$2`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1\s+AND o.shop_id = \$2`).
 					WithArgs(1, 10).
 					WillReturnRows(rows)
 			},
 			wantResult: &model.Order{
 				ID:           1,
 				ShopID:       10,
+				CustomerID:   5,
 				CustomerName: "John Doe",
 				TotalPrice:   5000,
 				Status:       "in_progress",
@@ -72,7 +74,7 @@ func Test_order_GetOrderByID(t *testing.T) {
 			id:     9999,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1`).
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1`).
 					WithArgs(9999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -84,7 +86,7 @@ func Test_order_GetOrderByID(t *testing.T) {
 			id:     1,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1`).
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1`).
 					WithArgs(1).
 					WillReturnError(errors.New("database error"))
 			},
@@ -123,6 +125,77 @@ func Test_order_GetOrderByID(t *testing.T) {
 	}
 }
 
+func Test_order_GetOrderByIDForUpdate(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.id = \$1\s+FOR UPDATE OF o`
+	columns := []string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.Order
+		wantErr   bool
+	}{
+		{
+			name: "returns and locks the order",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "cancelled", "paid", "", fixedTime, nil, nil)
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows)
+			},
+			want: &model.Order{ID: 1, ShopID: 10, CustomerID: 5, CustomerName: "John Doe", TotalPrice: 5000, Status: "cancelled", PaymentStatus: "paid", CreatedAt: fixedTime},
+		},
+		{
+			name: "returns nil when the order does not exist",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			store := NewOrderStoreWithDB(db)
+			got, gotErr := store.GetOrderByIDForUpdate(context.Background(), tx, 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetOrderByIDForUpdate() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetOrderByIDForUpdate() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOrderByIDForUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_order_GetOrdersByShopID(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
 	strPtr := func(s string) *string { return &s }
@@ -140,10 +213,10 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 			name:   "get orders by shop ID returns multiple orders",
 			shopID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "", fixedTime, nil).
-					AddRow(2, 10, "Jane Doe", false, 3000, "done", "", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, nil, nil).
+					AddRow(2, 10, 5, "Jane Doe", false, 3000, 0, nil, 0, nil, nil, nil, "", nil, false, "done", "", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1`).
 					WithArgs(10).
 					WillReturnRows(rows)
 			},
@@ -151,6 +224,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				{
 					ID:           1,
 					ShopID:       10,
+					CustomerID:   5,
 					CustomerName: "John Doe",
 					TotalPrice:   5000,
 					Status:       "in_progress",
@@ -159,6 +233,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				{
 					ID:           2,
 					ShopID:       10,
+					CustomerID:   5,
 					CustomerName: "Jane Doe",
 					TotalPrice:   3000,
 					Status:       "done",
@@ -172,8 +247,8 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 			shopID: 9999,
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"})
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"})
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1`).
 					WithArgs(9999).
 					WillReturnRows(rows)
 			},
@@ -185,7 +260,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 			shopID: 10,
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1`).
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1`).
 					WithArgs(10).
 					WillReturnError(errors.New("database error"))
 			},
@@ -197,9 +272,9 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 			shopID: 10,
 			opts:   model.OrderFilterOptions{SearchQuery: strPtr("john")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND \(c.name ILIKE \$2 OR c.phone ILIKE \$2\)`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND \(c.name ILIKE \$2 OR c.phone ILIKE \$2 OR o.invoice_number ILIKE \$2\)`).
 					WithArgs(10, "%john%").
 					WillReturnRows(rows)
 			},
@@ -207,6 +282,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				{
 					ID:           1,
 					ShopID:       10,
+					CustomerID:   5,
 					CustomerName: "John Doe",
 					TotalPrice:   5000,
 					Status:       "in_progress",
@@ -215,14 +291,42 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:   "get orders by shop ID with dp_overdue filter",
+			shopID: 10,
+			opts:   model.OrderFilterOptions{DPOverdue: func() *bool { b := true; return &b }()},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 50, fixedTime, fixedTime, nil, "", nil, false, "created", "awaiting_dp", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, .+\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.dp_overdue_at IS NOT NULL AND o.payment_status = \$2`).
+					WithArgs(10, constant.OrderPaymentStatusAwaitingDP).
+					WillReturnRows(rows)
+			},
+			wantResult: []model.Order{
+				{
+					ID:            1,
+					ShopID:        10,
+					CustomerID:    5,
+					CustomerName:  "John Doe",
+					TotalPrice:    5000,
+					DPPercent:     50,
+					DPDueAt:       sql.NullTime{Time: fixedTime, Valid: true},
+					DPOverdueAt:   sql.NullTime{Time: fixedTime, Valid: true},
+					Status:        "created",
+					PaymentStatus: "awaiting_dp",
+					CreatedAt:     fixedTime,
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:   "get orders by shop ID with status filter",
 			shopID: 10,
 			opts:   model.OrderFilterOptions{Status: []string{"in_progress", "done"}},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.status = ANY\(\$2\)`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.status = ANY\(\$2\)`).
 					WithArgs(10, pq.Array([]string{"in_progress", "done"})).
 					WillReturnRows(rows)
 			},
@@ -230,6 +334,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				{
 					ID:           1,
 					ShopID:       10,
+					CustomerID:   5,
 					CustomerName: "John Doe",
 					TotalPrice:   5000,
 					Status:       "in_progress",
@@ -246,9 +351,9 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				DateTo:   ptrTime(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.created_at::date >= \$2\s+AND o.created_at::date <= \$3`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.created_at >= \$2\s+AND o.created_at < \$3`).
 					WithArgs(10, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)).
 					WillReturnRows(rows)
 			},
@@ -256,6 +361,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				{
 					ID:           1,
 					ShopID:       10,
+					CustomerID:   5,
 					CustomerName: "John Doe",
 					TotalPrice:   5000,
 					Status:       "in_progress",
@@ -271,9 +377,9 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				Sort: strPtr("created_at,desc"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+ORDER BY created_at DESC NULLS LAST`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+ORDER BY created_at DESC NULLS LAST`).
 					WithArgs(10).
 					WillReturnRows(rows)
 			},
@@ -281,6 +387,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				{
 					ID:           1,
 					ShopID:       10,
+					CustomerID:   5,
 					CustomerName: "John Doe",
 					TotalPrice:   5000,
 					Status:       "in_progress",
@@ -289,14 +396,40 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:   "get orders by shop ID with shortage notify filter",
+			shopID: 10,
+			opts:   model.OrderFilterOptions{ShortageNotify: func() *bool { b := true; return &b }()},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 4000, 0, nil, 0, nil, nil, nil, "", nil, true, "in_progress", "outstanding", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, .+\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.shortage_notify = \$2`).
+					WithArgs(10, true).
+					WillReturnRows(rows)
+			},
+			wantResult: []model.Order{
+				{
+					ID:             1,
+					ShopID:         10,
+					CustomerID:     5,
+					CustomerName:   "John Doe",
+					TotalPrice:     4000,
+					ShortageNotify: true,
+					Status:         "in_progress",
+					PaymentStatus:  "outstanding",
+					CreatedAt:      fixedTime,
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:   "get orders by shop ID with payment status filter",
 			shopID: 10,
 			opts:   model.OrderFilterOptions{PaymentStatus: strPtr("paid")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "done", "paid", "", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.payment_status = \$2`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "done", "paid", "", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, o.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, o.total_price, o.unique_code, o.invoice_number, o.dp_percent, o.dp_due_at, o.dp_overdue_at, o.cancel_reason, o.cancel_notes, o.cancelled_at, o.shortage_notify, o.status, o.payment_status, o.notes, o.created_at, o.updated_at, o.shipping_address\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.shop_id = \$1\s+AND o.payment_status = \$2`).
 					WithArgs(10, "paid").
 					WillReturnRows(rows)
 			},
@@ -304,6 +437,7 @@ func Test_order_GetOrdersByShopID(t *testing.T) {
 				{
 					ID:            1,
 					ShopID:        10,
+					CustomerID:    5,
 					CustomerName:  "John Doe",
 					TotalPrice:    5000,
 					Status:        "done",
@@ -504,15 +638,16 @@ func Test_order_UpdateOrder(t *testing.T) {
 				Status: strPtr("done"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "done", "", "", fixedTime, updatedTime)
-				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "done", "", "", fixedTime, updatedTime)
+				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, unique_code, invoice_number, dp_percent, dp_due_at, dp_overdue_at, cancel_reason, cancel_notes, cancelled_at, shortage_notify, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, u.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.unique_code, u.invoice_number, u.dp_percent, u.dp_due_at, u.dp_overdue_at, u.cancel_reason, u.cancel_notes, u.cancelled_at, u.shortage_notify, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
 					WithArgs(1, "done").
 					WillReturnRows(rows)
 			},
 			wantResult: &model.Order{
 				ID:           1,
 				ShopID:       10,
+				CustomerID:   5,
 				CustomerName: "John Doe",
 				TotalPrice:   5000,
 				Status:       "done",
@@ -528,15 +663,16 @@ func Test_order_UpdateOrder(t *testing.T) {
 				PaymentStatus: strPtr("paid"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "done", "paid", "", fixedTime, updatedTime)
-				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET payment_status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "done", "paid", "", fixedTime, updatedTime)
+				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET payment_status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, unique_code, invoice_number, dp_percent, dp_due_at, dp_overdue_at, cancel_reason, cancel_notes, cancelled_at, shortage_notify, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, u.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.unique_code, u.invoice_number, u.dp_percent, u.dp_due_at, u.dp_overdue_at, u.cancel_reason, u.cancel_notes, u.cancelled_at, u.shortage_notify, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
 					WithArgs(1, "paid").
 					WillReturnRows(rows)
 			},
 			wantResult: &model.Order{
 				ID:            1,
 				ShopID:        10,
+				CustomerID:    5,
 				CustomerName:  "John Doe",
 				TotalPrice:    5000,
 				Status:        "done",
@@ -546,6 +682,33 @@ func Test_order_UpdateOrder(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "update order with shortage notify flag",
+			id:   1,
+			input: UpdateOrderInput{
+				ShortageNotify: func() *bool { b := true; return &b }(),
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, true, "in_progress", "outstanding", "", fixedTime, updatedTime)
+				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET shortage_notify = \$2,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(1, true).
+					WillReturnRows(rows)
+			},
+			wantResult: &model.Order{
+				ID:             1,
+				ShopID:         10,
+				CustomerID:     5,
+				CustomerName:   "John Doe",
+				TotalPrice:     5000,
+				ShortageNotify: true,
+				Status:         "in_progress",
+				PaymentStatus:  "outstanding",
+				CreatedAt:      fixedTime,
+				UpdatedAt:      sql.NullTime{Time: updatedTime, Valid: true},
+			},
+			wantErr: false,
+		},
 		{
 			name: "update order with total price",
 			id:   1,
@@ -553,15 +716,16 @@ func Test_order_UpdateOrder(t *testing.T) {
 				TotalPrice: intPtr(10000),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 10000, "in_progress", "", "", fixedTime, updatedTime)
-				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET total_price = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at"}).
+					AddRow(1, 10, 5, "John Doe", false, 10000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "", fixedTime, updatedTime)
+				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET total_price = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, unique_code, invoice_number, dp_percent, dp_due_at, dp_overdue_at, cancel_reason, cancel_notes, cancelled_at, shortage_notify, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, u.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.unique_code, u.invoice_number, u.dp_percent, u.dp_due_at, u.dp_overdue_at, u.cancel_reason, u.cancel_notes, u.cancelled_at, u.shortage_notify, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
 					WithArgs(1, 10000).
 					WillReturnRows(rows)
 			},
 			wantResult: &model.Order{
 				ID:           1,
 				ShopID:       10,
+				CustomerID:   5,
 				CustomerName: "John Doe",
 				TotalPrice:   10000,
 				Status:       "in_progress",
@@ -577,15 +741,16 @@ func Test_order_UpdateOrder(t *testing.T) {
 				Notes: strPtr("updated notes"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "is_customer_deleted", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(1, 10, "John Doe", false, 5000, "in_progress", "", "updated notes", fixedTime, updatedTime)
-				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET notes = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_id", "customer_name", "is_customer_deleted", "total_price", "unique_code", "invoice_number", "dp_percent", "dp_due_at", "dp_overdue_at", "cancel_reason", "cancel_notes", "cancelled_at", "shortage_notify", "status", "payment_status", "notes", "created_at", "updated_at"}).
+					AddRow(1, 10, 5, "John Doe", false, 5000, 0, nil, 0, nil, nil, nil, "", nil, false, "in_progress", "", "updated notes", fixedTime, updatedTime)
+				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET notes = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, unique_code, invoice_number, dp_percent, dp_due_at, dp_overdue_at, cancel_reason, cancel_notes, cancelled_at, shortage_notify, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, u.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.unique_code, u.invoice_number, u.dp_percent, u.dp_due_at, u.dp_overdue_at, u.cancel_reason, u.cancel_notes, u.cancelled_at, u.shortage_notify, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
 					WithArgs(1, "updated notes").
 					WillReturnRows(rows)
 			},
 			wantResult: &model.Order{
 				ID:           1,
 				ShopID:       10,
+				CustomerID:   5,
 				CustomerName: "John Doe",
 				TotalPrice:   5000,
 				Status:       "in_progress",
@@ -602,7 +767,7 @@ func Test_order_UpdateOrder(t *testing.T) {
 				Status: strPtr("done"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
+				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, unique_code, invoice_number, dp_percent, dp_due_at, dp_overdue_at, cancel_reason, cancel_notes, cancelled_at, shortage_notify, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, u.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.unique_code, u.invoice_number, u.dp_percent, u.dp_due_at, u.dp_overdue_at, u.cancel_reason, u.cancel_notes, u.cancelled_at, u.shortage_notify, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
 					WithArgs(9999, "done").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -616,7 +781,7 @@ func Test_order_UpdateOrder(t *testing.T) {
 				Status: strPtr("done"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
+				mock.ExpectQuery(`WITH updated AS \(\s+UPDATE orders\s+SET status = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, customer_id, total_price, unique_code, invoice_number, dp_percent, dp_due_at, dp_overdue_at, cancel_reason, cancel_notes, cancelled_at, shortage_notify, status, payment_status, notes, created_at, updated_at\s+\)\s+SELECT u.id, u.shop_id, u.customer_id, c.name as customer_name, \(c.deleted_at IS NOT NULL\) as is_customer_deleted, u.total_price, u.unique_code, u.invoice_number, u.dp_percent, u.dp_due_at, u.dp_overdue_at, u.cancel_reason, u.cancel_notes, u.cancelled_at, u.shortage_notify, u.status, u.payment_status, u.notes, u.created_at, u.updated_at\s+FROM updated u\s+INNER JOIN customers c ON u.customer_id = c.id`).
 					WithArgs(1, "done").
 					WillReturnError(errors.New("database error"))
 			},
@@ -725,6 +890,8 @@ func Test_order_CreateTempOrder(t *testing.T) {
 		customerName  string
 		customerPhone string
 		shopID        int
+		shareLinkID   *int
+		fingerprint   string
 		mockSetup     func(mock sqlmock.Sqlmock)
 		want          *model.TempOrder
 		wantErr       bool
@@ -734,11 +901,13 @@ func Test_order_CreateTempOrder(t *testing.T) {
 			customerName:  "Jane Doe",
 			customerPhone: "+62812345678",
 			shopID:        5,
+			shareLinkID:   intPtr(3),
+			fingerprint:   "fp",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "customer_name", "customer_phone", "shop_id", "total_price", "status", "created_at"}).
-					AddRow(1, "Jane Doe", "+62812345678", 5, 0, "pending", fixedTime)
-				mock.ExpectQuery(`INSERT INTO temp_orders \(customer_name, customer_phone, status, shop_id, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5\)\s+RETURNING id, customer_name, customer_phone, shop_id, total_price, status, created_at`).
-					WithArgs("Jane Doe", "+62812345678", "pending", 5, sqlmock.AnyArg()).
+				rows := sqlmock.NewRows([]string{"id", "customer_name", "customer_phone", "shop_id", "share_link_id", "total_price", "status", "created_at"}).
+					AddRow(1, "Jane Doe", "+62812345678", 5, 3, 0, "pending", fixedTime)
+				mock.ExpectQuery(`INSERT INTO temp_orders \(customer_name, customer_phone, status, shop_id, share_link_id, fingerprint, shipping_address, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, NULLIF\(\$6, ''\), \$7, \$8\)\s+RETURNING id, customer_name, customer_phone, shop_id, share_link_id, total_price, status, created_at`).
+					WithArgs("Jane Doe", "+62812345678", "pending", 5, 3, "fp", nil, sqlmock.AnyArg()).
 					WillReturnRows(rows)
 			},
 			want: &model.TempOrder{
@@ -746,6 +915,7 @@ func Test_order_CreateTempOrder(t *testing.T) {
 				CustomerName:  "Jane Doe",
 				CustomerPhone: "+62812345678",
 				ShopID:        5,
+				ShareLinkID:   sql.NullInt64{Int64: 3, Valid: true},
 				TotalPrice:    0,
 				Status:        "pending",
 				CreatedAt:     fixedTime,
@@ -758,8 +928,8 @@ func Test_order_CreateTempOrder(t *testing.T) {
 			customerPhone: "+62812345678",
 			shopID:        5,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`INSERT INTO temp_orders \(customer_name, customer_phone, shop_id, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4\)\s+RETURNING id, customer_name, customer_phone, shop_id, total_price, status, created_at`).
-					WithArgs("Jane Doe", "+62812345678", 5, sqlmock.AnyArg()).
+				mock.ExpectQuery(`INSERT INTO temp_orders \(customer_name, customer_phone, status, shop_id, share_link_id, fingerprint, shipping_address, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, NULLIF\(\$6, ''\), \$7, \$8\)\s+RETURNING id, customer_name, customer_phone, shop_id, share_link_id, total_price, status, created_at`).
+					WithArgs("Jane Doe", "+62812345678", "pending", 5, nil, "", nil, sqlmock.AnyArg()).
 					WillReturnError(errors.New("database error"))
 			},
 			want:    nil,
@@ -784,7 +954,7 @@ func Test_order_CreateTempOrder(t *testing.T) {
 			}
 			defer tx.Rollback()
 
-			got, gotErr := store.CreateTempOrder(context.Background(), tx, tt.customerName, tt.customerPhone, tt.shopID)
+			got, gotErr := store.CreateTempOrder(context.Background(), tx, tt.customerName, tt.customerPhone, tt.shopID, tt.shareLinkID, tt.fingerprint, nil)
 			if gotErr != nil {
 				if !tt.wantErr {
 					t.Errorf("CreateTempOrder() error = %v, wantErr %v", gotErr, tt.wantErr)
@@ -801,6 +971,96 @@ func Test_order_CreateTempOrder(t *testing.T) {
 	}
 }
 
+func Test_order_HasRecentTempOrder(t *testing.T) {
+	since := time.Date(2024, 1, 15, 10, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name: "returns true when a matching temp order exists",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).
+					WithArgs("fp").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT EXISTS \(\s+SELECT 1 FROM temp_orders\s+WHERE shop_id = \$1 AND fingerprint = \$2 AND created_at >= \$3\s+\)`).
+					WithArgs(5, "fp", since).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			want: true,
+		},
+		{
+			name: "returns false when no matching temp order exists",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).
+					WithArgs("fp").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WithArgs(5, "fp", since).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			want: false,
+		},
+		{
+			name: "returns error when lock fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).
+					WithArgs("fp").
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error on query failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).
+					WithArgs("fp").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WithArgs(5, "fp", since).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewOrderStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			got, gotErr := store.HasRecentTempOrder(context.Background(), tx, 5, "fp", since)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("HasRecentTempOrder() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("HasRecentTempOrder() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("HasRecentTempOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_order_UpdateTempOrderTotalPrice(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -884,10 +1144,10 @@ func Test_order_GetTempOrdersByShopID(t *testing.T) {
 			shopID: 5,
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
-					AddRow(1, 5, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil).
-					AddRow(2, 5, "John Doe", "+62887654321", 1000, "pending", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
+					AddRow(1, 5, nil, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil).
+					AddRow(2, 5, nil, "John Doe", "+62887654321", 1000, "pending", fixedTime, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1`).
 					WithArgs(5).
 					WillReturnRows(rows)
 			},
@@ -902,8 +1162,8 @@ func Test_order_GetTempOrdersByShopID(t *testing.T) {
 			shopID: 99,
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"})
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"})
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1`).
 					WithArgs(99).
 					WillReturnRows(rows)
 			},
@@ -915,7 +1175,7 @@ func Test_order_GetTempOrdersByShopID(t *testing.T) {
 			shopID: 5,
 			opts:   model.OrderFilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1`).
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1`).
 					WithArgs(5).
 					WillReturnError(errors.New("database error"))
 			},
@@ -927,9 +1187,9 @@ func Test_order_GetTempOrdersByShopID(t *testing.T) {
 			shopID: 5,
 			opts:   model.OrderFilterOptions{SearchQuery: strPtr("62812")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
-					AddRow(1, 5, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+AND \(customer_name ILIKE \$2 OR customer_phone ILIKE \$2\)`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
+					AddRow(1, 5, nil, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+AND \(customer_name ILIKE \$2 OR customer_phone ILIKE \$2\)`).
 					WithArgs(5, "%62812%").
 					WillReturnRows(rows)
 			},
@@ -946,9 +1206,9 @@ func Test_order_GetTempOrdersByShopID(t *testing.T) {
 				DateTo:   ptrTime(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
-					AddRow(1, 5, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+AND created_at::date >= \$2\s+AND created_at::date <= \$3`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
+					AddRow(1, 5, nil, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+AND created_at >= \$2\s+AND created_at < \$3`).
 					WithArgs(5, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)).
 					WillReturnRows(rows)
 			},
@@ -962,9 +1222,9 @@ func Test_order_GetTempOrdersByShopID(t *testing.T) {
 			shopID: 5,
 			opts: model.OrderFilterOptions{Status: []string{"pending"}},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
-					AddRow(1, 5, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+AND status = ANY\(\$2\)`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
+					AddRow(1, 5, nil, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+AND status = ANY\(\$2\)`).
 					WithArgs(5, pq.Array([]string{"pending"})).
 					WillReturnRows(rows)
 			},
@@ -978,9 +1238,9 @@ func Test_order_GetTempOrdersByShopID(t *testing.T) {
 			shopID: 5,
 			opts: model.OrderFilterOptions{Sort: strPtr("created_at,desc")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
-					AddRow(1, 5, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+ORDER BY created_at DESC NULLS LAST`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
+					AddRow(1, 5, nil, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE shop_id = \$1\s+ORDER BY created_at DESC NULLS LAST`).
 					WithArgs(5).
 					WillReturnRows(rows)
 			},
@@ -1037,9 +1297,9 @@ func Test_order_GetTempOrderByID(t *testing.T) {
 			id:     1,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
-					AddRow(1, 5, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 5, nil, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at, shipping_address\s+FROM temp_orders\s+WHERE id = \$1`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -1060,9 +1320,9 @@ func Test_order_GetTempOrderByID(t *testing.T) {
 			id:     1,
 			shopID: []int{5},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at"}).
-					AddRow(1, 5, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE id = \$1\s+AND shop_id = \$2`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "share_link_id", "customer_name", "customer_phone", "total_price", "status", "created_at", "updated_at", "shipping_address"}).
+					AddRow(1, 5, nil, "Jane Doe", "+62812345678", 2500, "pending", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at, shipping_address\s+FROM temp_orders\s+WHERE id = \$1\s+AND shop_id = \$2`).
 					WithArgs(1, 5).
 					WillReturnRows(rows)
 			},
@@ -1083,7 +1343,7 @@ func Test_order_GetTempOrderByID(t *testing.T) {
 			id:     999,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE id = \$1`).
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at, shipping_address\s+FROM temp_orders\s+WHERE id = \$1`).
 					WithArgs(999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -1095,7 +1355,7 @@ func Test_order_GetTempOrderByID(t *testing.T) {
 			id:     1,
 			shopID: nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, shop_id, customer_name, customer_phone, total_price, status, created_at, updated_at\s+FROM temp_orders\s+WHERE id = \$1`).
+				mock.ExpectQuery(`SELECT id, shop_id, share_link_id, customer_name, customer_phone, total_price, status, created_at, updated_at, shipping_address\s+FROM temp_orders\s+WHERE id = \$1`).
 					WithArgs(1).
 					WillReturnError(errors.New("database error"))
 			},
@@ -1150,9 +1410,9 @@ func Test_order_GetActiveOrderByCustomerID(t *testing.T) {
 			customerID: 1,
 			shopID:     10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "total_price", "status", "payment_status", "notes", "created_at", "updated_at"}).
-					AddRow(5, 10, "John Doe", 3000, constant.OrderStatusInProgress, "", "notes", fixedTime, nil)
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.customer_id = \$1 AND o.shop_id = \$2 AND o.status IN \(\$3, \$4\)\s+ORDER BY o.created_at DESC\s+LIMIT 1`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "customer_name", "total_price", "unique_code", "status", "payment_status", "notes", "created_at", "updated_at"}).
+					AddRow(5, 10, "John Doe", 3000, 0, constant.OrderStatusInProgress, "", "notes", fixedTime, nil)
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.unique_code, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.customer_id = \$1 AND o.shop_id = \$2 AND o.status IN \(\$3, \$4\)\s+ORDER BY o.created_at DESC\s+LIMIT 1`).
 					WithArgs(1, 10, constant.OrderStatusCreated, constant.OrderStatusInProgress).
 					WillReturnRows(rows)
 			},
@@ -1172,7 +1432,7 @@ func Test_order_GetActiveOrderByCustomerID(t *testing.T) {
 			customerID: 99,
 			shopID:     10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.customer_id = \$1 AND o.shop_id = \$2 AND o.status IN \(\$3, \$4\)\s+ORDER BY o.created_at DESC\s+LIMIT 1`).
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.unique_code, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.customer_id = \$1 AND o.shop_id = \$2 AND o.status IN \(\$3, \$4\)\s+ORDER BY o.created_at DESC\s+LIMIT 1`).
 					WithArgs(99, 10, constant.OrderStatusCreated, constant.OrderStatusInProgress).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -1184,7 +1444,7 @@ func Test_order_GetActiveOrderByCustomerID(t *testing.T) {
 			customerID: 1,
 			shopID:     10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.customer_id = \$1 AND o.shop_id = \$2 AND o.status IN \(\$3, \$4\)\s+ORDER BY o.created_at DESC\s+LIMIT 1`).
+				mock.ExpectQuery(`SELECT o.id, o.shop_id, c.name as customer_name, o.total_price, o.unique_code, o.status, o.payment_status, o.notes, o.created_at, o.updated_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+WHERE o.customer_id = \$1 AND o.shop_id = \$2 AND o.status IN \(\$3, \$4\)\s+ORDER BY o.created_at DESC\s+LIMIT 1`).
 					WithArgs(1, 10, constant.OrderStatusCreated, constant.OrderStatusInProgress).
 					WillReturnError(errors.New("database error"))
 			},
@@ -1318,3 +1578,650 @@ func Test_order_UpdateTempOrderStatus(t *testing.T) {
 		})
 	}
 }
+
+func Test_order_GetOutstandingOrdersByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT o.id, c.name as customer_name, o.total_price, o.unique_code, COALESCE\(p.paid, 0\) as paid_amount, o.created_at\s+FROM orders o\s+INNER JOIN customers c ON o.customer_id = c.id\s+LEFT JOIN \(\s+SELECT order_id, SUM\(amount\) as paid\s+FROM order_payments\s+GROUP BY order_id\s+\) p ON p.order_id = o.id\s+WHERE o.shop_id = \$1 AND o.status != \$2 AND o.payment_status != \$3\s+AND o.total_price \+ o.unique_code > COALESCE\(p.paid, 0\)\s+ORDER BY o.created_at ASC`
+
+	tests := []struct {
+		name       string
+		shopID     int
+		mockSetup  func(mock sqlmock.Sqlmock)
+		wantResult []model.OutstandingOrder
+		wantErr    bool
+	}{
+		{
+			name:   "returns outstanding orders",
+			shopID: 10,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "customer_name", "total_price", "unique_code", "paid_amount", "created_at"}).
+					AddRow(1, "John Doe", 150000, 0, 0, fixedTime).
+					AddRow(2, "Jane Doe", 200000, 123, 50000, fixedTime)
+				mock.ExpectQuery(query).
+					WithArgs(10, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnRows(rows)
+			},
+			wantResult: []model.OutstandingOrder{
+				{ID: 1, CustomerName: "John Doe", TotalPrice: 150000, PaidAmount: 0, CreatedAt: fixedTime},
+				{ID: 2, CustomerName: "Jane Doe", TotalPrice: 200000, UniqueCode: 123, PaidAmount: 50000, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name:   "returns empty slice when nothing is outstanding",
+			shopID: 10,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs(10, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "customer_name", "total_price", "unique_code", "paid_amount", "created_at"}))
+			},
+			wantResult: []model.OutstandingOrder{},
+		},
+		{
+			name:   "returns error on database failure",
+			shopID: 10,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs(10, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			store := NewOrderStoreWithDB(db)
+
+			got, gotErr := store.GetOutstandingOrdersByShopID(context.Background(), tt.shopID)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetOutstandingOrdersByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetOutstandingOrdersByShopID() succeeded unexpectedly")
+			}
+
+			if !reflect.DeepEqual(got, tt.wantResult) {
+				t.Errorf("GetOutstandingOrdersByShopID() = %v, want %v", got, tt.wantResult)
+			}
+		})
+	}
+}
+
+func Test_order_AssignUniqueCode(t *testing.T) {
+	lockQuery := `SELECT pg_advisory_xact_lock\(hashtext\('orders.unique_code'\), \$1\)`
+	updateQuery := `UPDATE orders\s+SET unique_code = COALESCE\(\(\s+SELECT code\s+FROM generate_series\(1, \$3::int\) code\s+WHERE code NOT IN \(\s+SELECT unique_code\s+FROM orders\s+WHERE shop_id = \$2 AND unique_code > 0 AND status != \$4 AND payment_status != \$5\s+\)\s+ORDER BY random\(\)\s+LIMIT 1\s+\), 0\), updated_at = now\(\)\s+WHERE id = \$1 AND shop_id = \$2\s+RETURNING unique_code`
+
+	tests := []struct {
+		name      string
+		id        int
+		shopID    int
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      int
+		wantErr   bool
+	}{
+		{
+			name:   "assigns a free code",
+			id:     1,
+			shopID: 10,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(updateQuery).
+					WithArgs(1, 10, constant.UniqueCodeMax, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnRows(sqlmock.NewRows([]string{"unique_code"}).AddRow(417))
+			},
+			want: 417,
+		},
+		{
+			name:   "returns 0 when every code is taken",
+			id:     1,
+			shopID: 10,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(updateQuery).
+					WithArgs(1, 10, constant.UniqueCodeMax, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnRows(sqlmock.NewRows([]string{"unique_code"}).AddRow(0))
+			},
+			want: 0,
+		},
+		{
+			name:   "returns error when lock fails",
+			id:     1,
+			shopID: 10,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(10).WillReturnError(errors.New("lock error"))
+			},
+			wantErr: true,
+		},
+		{
+			name:   "returns error on update failure",
+			id:     1,
+			shopID: 10,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(updateQuery).
+					WithArgs(1, 10, constant.UniqueCodeMax, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			store := NewOrderStoreWithDB(db)
+			got, gotErr := store.AssignUniqueCode(context.Background(), tx, tt.id, tt.shopID)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("AssignUniqueCode() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("AssignUniqueCode() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("AssignUniqueCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_order_ReclaimUniqueCode(t *testing.T) {
+	lockQuery := `SELECT pg_advisory_xact_lock\(hashtext\('orders.unique_code'\), \$1\)`
+	updateQuery := `WITH taken AS \(\s+SELECT unique_code\s+FROM orders\s+WHERE shop_id = \$2 AND id != \$1 AND unique_code > 0 AND status != \$4 AND payment_status != \$5\s+\)\s+UPDATE orders o\s+SET unique_code = CASE\s+WHEN o.unique_code NOT IN \(SELECT unique_code FROM taken\) THEN o.unique_code\s+ELSE COALESCE\(\(\s+SELECT code\s+FROM generate_series\(1, \$3::int\) code\s+WHERE code NOT IN \(SELECT unique_code FROM taken\)\s+ORDER BY random\(\)\s+LIMIT 1\s+\), 0\)\s+END, updated_at = now\(\)\s+WHERE o.id = \$1 AND o.shop_id = \$2\s+RETURNING o.unique_code`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      int
+		wantErr   bool
+	}{
+		{
+			name: "returns the code the order ends up with",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(updateQuery).
+					WithArgs(1, 10, constant.UniqueCodeMax, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnRows(sqlmock.NewRows([]string{"unique_code"}).AddRow(417))
+			},
+			want: 417,
+		},
+		{
+			name: "returns error when lock fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(10).WillReturnError(errors.New("lock error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns error on update failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(lockQuery).WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(updateQuery).
+					WithArgs(1, 10, constant.UniqueCodeMax, constant.OrderStatusCancelled, constant.OrderPaymentStatusPaid).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			store := NewOrderStoreWithDB(db)
+			got, gotErr := store.ReclaimUniqueCode(context.Background(), tx, 1, 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("ReclaimUniqueCode() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("ReclaimUniqueCode() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("ReclaimUniqueCode() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_order_GetInvoiceNumberForUpdate(t *testing.T) {
+	query := `SELECT invoice_number\s+FROM orders\s+WHERE id = \$1\s+FOR UPDATE`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "returns assigned invoice number",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"invoice_number"}).AddRow("INV/2024/01/12"))
+			},
+			want: "INV/2024/01/12",
+		},
+		{
+			name: "returns empty when the order has no invoice number yet",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"invoice_number"}).AddRow(nil))
+			},
+			want: "",
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			store := NewOrderStoreWithDB(db)
+			got, gotErr := store.GetInvoiceNumberForUpdate(context.Background(), tx, 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetInvoiceNumberForUpdate() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetInvoiceNumberForUpdate() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("GetInvoiceNumberForUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_order_SetInvoiceNumber(t *testing.T) {
+	query := `UPDATE orders\s+SET invoice_seq = \$2, invoice_number = \$3, updated_at = now\(\)\s+WHERE id = \$1`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "stores invoice number",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WithArgs(1, 13, "INV/2024/01/13").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WithArgs(1, 13, "INV/2024/01/13").WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			store := NewOrderStoreWithDB(db)
+			gotErr := store.SetInvoiceNumber(context.Background(), tx, 1, 13, "INV/2024/01/13")
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("SetInvoiceNumber() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_order_SetShippingAddress(t *testing.T) {
+	query := `UPDATE orders\s+SET shipping_address = \$2, updated_at = now\(\)\s+WHERE id = \$1`
+	addressID := 4
+
+	tests := []struct {
+		name      string
+		address   *model.ShippingAddress
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name:    "stores address snapshot",
+			address: &model.ShippingAddress{AddressID: &addressID, Recipient: "Budi", Address: "Jl. Merdeka 1", PostalCode: "12345"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).
+					WithArgs(1, `{"address_id":4,"label":"","recipient":"Budi","phone":"","address":"Jl. Merdeka 1","province":"","city":"","district":"","postal_code":"12345"}`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "nil address clears the snapshot",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WithArgs(1, nil).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:    "returns error on database failure",
+			address: &model.ShippingAddress{Recipient: "Budi"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin transaction: %v", err)
+			}
+
+			store := NewOrderStoreWithDB(db)
+			gotErr := store.SetShippingAddress(context.Background(), tx, 1, tt.address)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("SetShippingAddress() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_order_SetDownPayment(t *testing.T) {
+	query := `UPDATE orders\s+SET dp_rule_id = \$2, dp_percent = \$3, dp_due_at = \$4, dp_auto_cancel = \$5, dp_overdue_at = NULL, payment_status = \$6, updated_at = now\(\)\s+WHERE id = \$1`
+	ruleID := 3
+	dueAt := time.Date(2024, 1, 18, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     SetDownPaymentInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name:  "sets DP requirement",
+			input: SetDownPaymentInput{RuleID: &ruleID, Percent: 50, DueAt: &dueAt, AutoCancel: true, PaymentStatus: constant.OrderPaymentStatusAwaitingDP},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(1, &ruleID, 50, &dueAt, true, constant.OrderPaymentStatusAwaitingDP).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:  "clears DP requirement",
+			input: SetDownPaymentInput{PaymentStatus: constant.OrderPaymentStatusOutstanding},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(1, nil, 0, nil, false, constant.OrderPaymentStatusOutstanding).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:  "returns error on database failure",
+			input: SetDownPaymentInput{PaymentStatus: constant.OrderPaymentStatusOutstanding},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			store := NewOrderStoreWithDB(db)
+			gotErr := store.SetDownPayment(context.Background(), nil, 1, tt.input)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("SetDownPayment() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_order_GetDPOverdueOrders(t *testing.T) {
+	now := time.Date(2024, 1, 20, 10, 0, 0, 0, time.UTC)
+	query := `SELECT o.id, o.shop_id, o.dp_auto_cancel\s+FROM orders o\s+JOIN shops s ON s.id = o.shop_id AND s.closed_at IS NULL\s+LEFT JOIN \(.+\) p ON p.order_id = o.id\s+WHERE o.dp_percent > 0 AND o.dp_overdue_at IS NULL AND o.dp_due_at < \$1\s+AND o.status NOT IN \(\$2, \$3\)\s+AND COALESCE\(p.paid, 0\) < CEIL\(o.total_price \* o.dp_percent / 100.0\)\s+ORDER BY o.dp_due_at ASC`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.DPOverdueOrder
+		wantErr   bool
+	}{
+		{
+			name: "returns overdue orders",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "dp_auto_cancel"}).
+					AddRow(1, 10, false).
+					AddRow(2, 11, true)
+				mock.ExpectQuery(query).
+					WithArgs(now, constant.OrderStatusCancelled, constant.OrderStatusDone).
+					WillReturnRows(rows)
+			},
+			want: []model.DPOverdueOrder{
+				{ID: 1, ShopID: 10},
+				{ID: 2, ShopID: 11, AutoCancel: true},
+			},
+		},
+		{
+			name: "returns empty slice when nothing is overdue",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs(now, constant.OrderStatusCancelled, constant.OrderStatusDone).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "shop_id", "dp_auto_cancel"}))
+			},
+			want: []model.DPOverdueOrder{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			store := NewOrderStoreWithDB(db)
+			got, gotErr := store.GetDPOverdueOrders(context.Background(), now)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetDPOverdueOrders() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetDPOverdueOrders() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDPOverdueOrders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_order_MarkDPOverdue(t *testing.T) {
+	query := `UPDATE orders\s+SET dp_overdue_at = now\(\), updated_at = now\(\)\s+WHERE id = \$1`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "flags order",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			store := NewOrderStoreWithDB(db)
+			gotErr := store.MarkDPOverdue(context.Background(), nil, 1)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("MarkDPOverdue() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_order_CancelOrder(t *testing.T) {
+	query := `UPDATE orders\s+SET status = \$2, cancel_reason = \$3, cancel_notes = \$4, cancelled_at = now\(\), updated_at = now\(\)\s+WHERE id = \$1`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "cancels order with reason",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(1, constant.OrderStatusCancelled, constant.OrderReasonOutOfStock, "supplier sold out").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			store := NewOrderStoreWithDB(db)
+			gotErr := store.CancelOrder(context.Background(), nil, 1, constant.OrderReasonOutOfStock, "supplier sold out")
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("CancelOrder() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
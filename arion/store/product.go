@@ -26,6 +26,10 @@ type (
 		DeleteProductByID(ctx context.Context, productID int) error
 		GetProductsListByActiveOrders(ctx context.Context, shopID int) ([]model.PurchaseProduct, error)
 		SetAllProductsStatusByShopID(ctx context.Context, shopID int, isActive bool) error
+		GetProductsByNames(ctx context.Context, shopID int, names []string) ([]model.Product, error)
+		BulkCreateProducts(ctx context.Context, tx database.Tx, inputs []BulkCreateProductInput) error
+		BulkUpdateProducts(ctx context.Context, tx database.Tx, inputs []BulkUpdateProductInput) error
+		ReorderProducts(ctx context.Context, shopID int, productIDs []int) error
 	}
 
 	product struct {
@@ -39,6 +43,25 @@ type (
 		OriginalPrice *int
 		ImageURL      *string
 		IsActive      *bool
+		CategoryID    *int // 0 removes the product from its category
+		Tags          *[]string
+	}
+
+	BulkCreateProductInput struct {
+		ShopID        int
+		Name          string
+		Description   string
+		Price         int
+		OriginalPrice int
+	}
+
+	// BulkUpdateProductInput overwrites the price of a product; nil fields keep
+	// the current value.
+	BulkUpdateProductInput struct {
+		ID            int
+		Price         int
+		OriginalPrice *int
+		Description   *string
 	}
 )
 
@@ -55,7 +78,7 @@ func (p *product) GetProductByID(ctx context.Context, productID int, shopID ...i
 	criteria := []interface{}{productID}
 
 	q := `
-		SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at
+		SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at
 		FROM products
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -66,7 +89,7 @@ func (p *product) GetProductByID(ctx context.Context, productID int, shopID ...i
 	}
 
 	var product model.Product
-	err := p.db.QueryRowContext(ctx, q, criteria...).Scan(&product.ID, &product.ShopID, &product.Name, &product.Description, &product.Price, &product.OriginalPrice, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
+	err := p.db.QueryRowContext(ctx, q, criteria...).Scan(&product.ID, &product.ShopID, &product.Name, &product.Description, &product.Price, &product.OriginalPrice, &product.ImageURL, &product.IsActive, &product.CategoryID, &product.Position, pq.Array(&product.Tags), &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -79,7 +102,7 @@ func (p *product) GetProductByID(ctx context.Context, productID int, shopID ...i
 
 func (p *product) GetProductsByShopID(ctx context.Context, shopID int, filter model.FilterOptions) ([]model.Product, error) {
 	q := `
-		SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at
+		SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at
 		FROM products
 		WHERE shop_id = $1 AND deleted_at IS NULL
 	`
@@ -96,6 +119,20 @@ func (p *product) GetProductsByShopID(ctx context.Context, shopID int, filter mo
 		args = append(args, *filter.IsActive)
 		argNum++
 	}
+	if filter.CategoryID != nil {
+		q += fmt.Sprintf(` AND category_id IN (SELECT id FROM product_categories WHERE shop_id = $1 AND (id = $%d OR parent_id = $%d))`, argNum, argNum)
+		args = append(args, *filter.CategoryID)
+		argNum++
+	}
+	if len(filter.Tags) > 0 {
+		tags := make([]string, 0, len(filter.Tags))
+		for _, tag := range filter.Tags {
+			tags = append(tags, strings.ToLower(tag))
+		}
+		q += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM unnest(tags) t WHERE LOWER(t) = ANY($%d))`, argNum)
+		args = append(args, pq.Array(tags))
+		argNum++
+	}
 	if filter.Sort != nil {
 		sort := strings.Split(*filter.Sort, ",")
 		if len(sort) == 2 {
@@ -104,7 +141,9 @@ func (p *product) GetProductsByShopID(ctx context.Context, shopID int, filter mo
 			if dir != "ASC" && dir != "DESC" {
 				dir = "ASC"
 			}
-			if allowedCols[col] {
+			if col == "position" {
+				q += " ORDER BY " + productPositionOrder
+			} else if allowedCols[col] {
 				nullsOrder := "NULLS LAST"
 				if dir == "ASC" {
 					nullsOrder = "NULLS FIRST"
@@ -133,7 +172,7 @@ func (p *product) GetProductsByShopID(ctx context.Context, shopID int, filter mo
 	products := []model.Product{}
 	for rows.Next() {
 		var product model.Product
-		err := rows.Scan(&product.ID, &product.ShopID, &product.Name, &product.Description, &product.Price, &product.OriginalPrice, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
+		err := rows.Scan(&product.ID, &product.ShopID, &product.Name, &product.Description, &product.Price, &product.OriginalPrice, &product.ImageURL, &product.IsActive, &product.CategoryID, &product.Position, pq.Array(&product.Tags), &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -154,14 +193,17 @@ func (p *product) CreateProduct(ctx context.Context, name string, description *s
 	var desc string
 	var imgURL string
 	var isActive bool
+	var position int
 
+	// New products go to the end of the uncategorized products.
 	q := `
-		INSERT INTO products (name, description, price, original_price, shop_id, image_url, created_at)
-		VALUES ($1, COALESCE($2, ''), $3, $4, $5, COALESCE($6, ''), $7)
-		RETURNING id, description, image_url, is_active
+		INSERT INTO products (name, description, price, original_price, shop_id, image_url, position, created_at)
+		VALUES ($1, COALESCE($2, ''), $3, $4, $5, COALESCE($6, ''),
+			(SELECT COALESCE(MAX(position) + 1, 0) FROM products WHERE shop_id = $5 AND category_id IS NULL), $7)
+		RETURNING id, description, image_url, is_active, position
 	`
 
-	err := p.db.QueryRowContext(ctx, q, name, description, price, origPrice, shopID, imageURL, now).Scan(&id, &desc, &imgURL, &isActive)
+	err := p.db.QueryRowContext(ctx, q, name, description, price, origPrice, shopID, imageURL, now).Scan(&id, &desc, &imgURL, &isActive, &position)
 	if err != nil {
 		if isProductUniqueViolation(err) {
 			return nil, ErrDuplicateProductName
@@ -177,6 +219,7 @@ func (p *product) CreateProduct(ctx context.Context, name string, description *s
 		OriginalPrice: origPrice,
 		ImageURL:      imgURL,
 		IsActive:      isActive,
+		Position:      position,
 		ShopID:        shopID,
 		CreatedAt:     now,
 	}, nil
@@ -219,6 +262,21 @@ func (p *product) UpdateProduct(ctx context.Context, productID int, input Update
 		args = append(args, *input.IsActive)
 		argNum++
 	}
+	if input.CategoryID != nil {
+		// A product moved into a category goes to its end.
+		set = append(set,
+			fmt.Sprintf(`position = CASE WHEN category_id IS NOT DISTINCT FROM NULLIF($%d, 0) THEN position
+				ELSE (SELECT COALESCE(MAX(c.position) + 1, 0) FROM products c WHERE c.shop_id = products.shop_id AND c.category_id IS NOT DISTINCT FROM NULLIF($%d, 0)) END`, argNum, argNum),
+			fmt.Sprintf("category_id = NULLIF($%d, 0)", argNum),
+		)
+		args = append(args, *input.CategoryID)
+		argNum++
+	}
+	if input.Tags != nil {
+		set = append(set, fmt.Sprintf("tags = $%d", argNum))
+		args = append(args, pq.Array(*input.Tags))
+		argNum++
+	}
 
 	set = append(set, "updated_at = now()")
 
@@ -226,10 +284,10 @@ func (p *product) UpdateProduct(ctx context.Context, productID int, input Update
 		UPDATE products
 		SET %s
 		WHERE id = $1
-		RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at
+		RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at
 	`, strings.Join(set, ","))
 
-	err := p.db.QueryRowContext(ctx, q, args...).Scan(&product.ID, &product.ShopID, &product.Name, &product.Description, &product.Price, &product.OriginalPrice, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt)
+	err := p.db.QueryRowContext(ctx, q, args...).Scan(&product.ID, &product.ShopID, &product.Name, &product.Description, &product.Price, &product.OriginalPrice, &product.ImageURL, &product.IsActive, &product.CategoryID, &product.Position, pq.Array(&product.Tags), &product.CreatedAt, &product.UpdatedAt)
 	if err != nil {
 		if isProductUniqueViolation(err) {
 			return nil, ErrDuplicateProductName
@@ -257,14 +315,17 @@ func (p *product) DeleteProductByID(ctx context.Context, productID int) error {
 
 func (p *product) GetProductsListByActiveOrders(ctx context.Context, shopID int) ([]model.PurchaseProduct, error) {
 	q := `
-		SELECT p.name, p.price, COALESCE(SUM(oi.qty), 0)::int AS qty
+		SELECT p.id, p.name, p.price, COALESCE(SUM(oi.qty), 0)::int AS qty,
+			COALESCE(pli.status, $4), COALESCE(pli.bought_qty, 0), COALESCE(pli.actual_cost, 0), COALESCE(pli.notes, '')
 		FROM products p
 		INNER JOIN order_items oi ON p.id = oi.product_id
 		INNER JOIN orders o ON oi.order_id = o.id
+		LEFT JOIN purchase_list_items pli ON pli.product_id = p.id AND pli.shop_id = o.shop_id
 		WHERE o.shop_id = $1 AND o.status IN ($2, $3)
-		GROUP BY p.id, p.name, p.price
+		GROUP BY p.id, p.name, p.price, pli.status, pli.bought_qty, pli.actual_cost, pli.notes
+		ORDER BY p.name ASC
 	`
-	rows, err := p.db.QueryContext(ctx, q, shopID, constant.OrderStatusCreated, constant.OrderStatusInProgress)
+	rows, err := p.db.QueryContext(ctx, q, shopID, constant.OrderStatusCreated, constant.OrderStatusInProgress, constant.PurchaseStatusPending)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -276,7 +337,7 @@ func (p *product) GetProductsListByActiveOrders(ctx context.Context, shopID int)
 	var list []model.PurchaseProduct
 	for rows.Next() {
 		var pp model.PurchaseProduct
-		if err := rows.Scan(&pp.ProductName, &pp.Price, &pp.Qty); err != nil {
+		if err := rows.Scan(&pp.ProductID, &pp.ProductName, &pp.Price, &pp.Qty, &pp.Status, &pp.BoughtQty, &pp.ActualCost, &pp.Notes); err != nil {
 			return nil, err
 		}
 		list = append(list, pp)
@@ -295,6 +356,110 @@ func (p *product) SetAllProductsStatusByShopID(ctx context.Context, shopID int,
 	return err
 }
 
+// GetProductsByNames returns the live products of the shop whose name is one
+// of names.
+func (p *product) GetProductsByNames(ctx context.Context, shopID int, names []string) ([]model.Product, error) {
+	q := `
+		SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at
+		FROM products
+		WHERE shop_id = $1 AND name = ANY($2) AND deleted_at IS NULL
+	`
+
+	rows, err := p.db.QueryContext(ctx, q, shopID, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []model.Product{}
+	for rows.Next() {
+		var product model.Product
+		if err := rows.Scan(&product.ID, &product.ShopID, &product.Name, &product.Description, &product.Price, &product.OriginalPrice, &product.ImageURL, &product.IsActive, &product.CategoryID, &product.Position, pq.Array(&product.Tags), &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+// BulkCreateProducts inserts the products with a single statement.
+func (p *product) BulkCreateProducts(ctx context.Context, tx database.Tx, inputs []BulkCreateProductInput) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	args := make([]interface{}, 0, len(inputs)*6)
+	for _, input := range inputs {
+		args = append(args, input.Name, input.Description, input.Price, input.OriginalPrice, input.ShopID, now)
+	}
+
+	q := `
+		INSERT INTO products (name, description, price, original_price, shop_id, created_at)
+		VALUES ` + bulkValues(len(inputs), "", "", "", "", "", "")
+
+	_, err := tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		if isProductUniqueViolation(err) {
+			return ErrDuplicateProductName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// BulkUpdateProducts updates the products with a single statement.
+func (p *product) BulkUpdateProducts(ctx context.Context, tx database.Tx, inputs []BulkUpdateProductInput) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(inputs)*4)
+	for _, input := range inputs {
+		args = append(args, input.ID, input.Price, input.OriginalPrice, input.Description)
+	}
+
+	q := `
+		UPDATE products p
+		SET price = v.price,
+			original_price = COALESCE(v.original_price, p.original_price),
+			description = COALESCE(v.description, p.description),
+			updated_at = now()
+		FROM (VALUES ` + bulkValues(len(inputs), "int", "int", "int", "text") + `) AS v(id, price, original_price, description)
+		WHERE p.id = v.id
+	`
+
+	_, err := tx.ExecContext(ctx, q, args...)
+	return err
+}
+
+// ReorderProducts sets the position of the shop's products to their index in
+// productIDs. Products left out keep their position.
+func (p *product) ReorderProducts(ctx context.Context, shopID int, productIDs []int) error {
+	q := `
+		UPDATE products p
+		SET position = v.ord - 1, updated_at = now()
+		FROM unnest($2::int[]) WITH ORDINALITY AS v(id, ord)
+		WHERE p.id = v.id AND p.shop_id = $1
+	`
+
+	_, err := p.db.ExecContext(ctx, q, shopID, pq.Array(productIDs))
+	return err
+}
+
+// productPositionOrder lists products the way the shop arranged them: by
+// category, with a top-level category's own products before those of its
+// subcategories, then by position within the category. Uncategorized
+// products come last.
+const productPositionOrder = `(
+		SELECT ARRAY[COALESCE(pc.position, c.position), COALESCE(pc.id, c.id), CASE WHEN pc.id IS NULL THEN -1 ELSE c.position END, c.id]
+		FROM product_categories c
+		LEFT JOIN product_categories pc ON pc.id = c.parent_id
+		WHERE c.id = products.category_id
+	) ASC NULLS LAST, position ASC, id ASC`
+
 // isProductUniqueViolation checks if the error is a PostgreSQL unique constraint violation
 func isProductUniqueViolation(err error) bool {
 	if pqErr, ok := err.(*pq.Error); ok {
@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/common/apierr"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+var ErrDuplicateProductCategoryName = errors.New(apierr.ErrProductCategoryNameExists)
+
+type (
+	ProductCategoryStore interface {
+		CreateProductCategory(ctx context.Context, input CreateProductCategoryInput) (*model.ProductCategory, error)
+		GetProductCategoryByID(ctx context.Context, id, shopID int) (*model.ProductCategory, error)
+		GetProductCategoriesByShopID(ctx context.Context, shopID int) ([]model.ProductCategory, error)
+		UpdateProductCategory(ctx context.Context, id int, input UpdateProductCategoryInput) (*model.ProductCategory, error)
+		DeleteProductCategoryByID(ctx context.Context, tx database.Tx, id, shopID int) error
+		ReorderProductCategories(ctx context.Context, shopID int, categoryIDs []int) error
+	}
+
+	productCategory struct {
+		db *sql.DB
+	}
+
+	CreateProductCategoryInput struct {
+		ShopID   int
+		ParentID *int
+		Name     string
+	}
+
+	UpdateProductCategoryInput struct {
+		Name     *string
+		ParentID *int // 0 makes the category top-level
+	}
+)
+
+func NewProductCategoryStore() ProductCategoryStore {
+	return &productCategory{db: database.GetDB()}
+}
+
+// NewProductCategoryStoreWithDB creates a ProductCategoryStore with a custom db connection (for testing)
+func NewProductCategoryStoreWithDB(db *sql.DB) ProductCategoryStore {
+	return &productCategory{db: db}
+}
+
+// CreateProductCategory adds the category after its siblings.
+func (pc *productCategory) CreateProductCategory(ctx context.Context, input CreateProductCategoryInput) (*model.ProductCategory, error) {
+	q := `
+		INSERT INTO product_categories (shop_id, parent_id, name, position, created_at)
+		VALUES ($1, $2, $3,
+			(SELECT COALESCE(MAX(position) + 1, 0) FROM product_categories WHERE shop_id = $1 AND parent_id IS NOT DISTINCT FROM $2), $4)
+		RETURNING id, shop_id, parent_id, name, position, created_at, updated_at
+	`
+
+	var category model.ProductCategory
+	err := pc.db.QueryRowContext(ctx, q, input.ShopID, input.ParentID, input.Name, time.Now()).Scan(&category.ID, &category.ShopID, &category.ParentID, &category.Name, &category.Position, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		if isProductUniqueViolation(err) {
+			return nil, ErrDuplicateProductCategoryName
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (pc *productCategory) GetProductCategoryByID(ctx context.Context, id, shopID int) (*model.ProductCategory, error) {
+	q := `
+		SELECT id, shop_id, parent_id, name, position, created_at, updated_at
+		FROM product_categories
+		WHERE id = $1 AND shop_id = $2
+	`
+
+	var category model.ProductCategory
+	err := pc.db.QueryRowContext(ctx, q, id, shopID).Scan(&category.ID, &category.ShopID, &category.ParentID, &category.Name, &category.Position, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// GetProductCategoriesByShopID returns the shop's categories in display
+// order: each top-level category followed by its subcategories.
+func (pc *productCategory) GetProductCategoriesByShopID(ctx context.Context, shopID int) ([]model.ProductCategory, error) {
+	q := `
+		SELECT c.id, c.shop_id, c.parent_id, c.name, c.position, c.created_at, c.updated_at
+		FROM product_categories c
+		LEFT JOIN product_categories p ON p.id = c.parent_id
+		WHERE c.shop_id = $1
+		ORDER BY COALESCE(p.position, c.position), COALESCE(p.id, c.id), c.parent_id NULLS FIRST, c.position, c.id
+	`
+
+	rows, err := pc.db.QueryContext(ctx, q, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []model.ProductCategory{}
+	for rows.Next() {
+		var category model.ProductCategory
+		if err := rows.Scan(&category.ID, &category.ShopID, &category.ParentID, &category.Name, &category.Position, &category.CreatedAt, &category.UpdatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+func (pc *productCategory) UpdateProductCategory(ctx context.Context, id int, input UpdateProductCategoryInput) (*model.ProductCategory, error) {
+	set := []string{}
+	args := []interface{}{id}
+	argNum := 2
+
+	// build query
+	if input.Name != nil {
+		set = append(set, fmt.Sprintf("name = $%d", argNum))
+		args = append(args, *input.Name)
+		argNum++
+	}
+	if input.ParentID != nil {
+		// A category moved under another parent goes after its new siblings.
+		set = append(set,
+			fmt.Sprintf(`position = CASE WHEN parent_id IS NOT DISTINCT FROM NULLIF($%d, 0) THEN position
+				ELSE (SELECT COALESCE(MAX(s.position) + 1, 0) FROM product_categories s WHERE s.shop_id = product_categories.shop_id AND s.parent_id IS NOT DISTINCT FROM NULLIF($%d, 0)) END`, argNum, argNum),
+			fmt.Sprintf("parent_id = NULLIF($%d, 0)", argNum),
+		)
+		args = append(args, *input.ParentID)
+		argNum++
+	}
+
+	set = append(set, "updated_at = now()")
+
+	q := fmt.Sprintf(`
+		UPDATE product_categories
+		SET %s
+		WHERE id = $1
+		RETURNING id, shop_id, parent_id, name, position, created_at, updated_at
+	`, strings.Join(set, ","))
+
+	var category model.ProductCategory
+	err := pc.db.QueryRowContext(ctx, q, args...).Scan(&category.ID, &category.ShopID, &category.ParentID, &category.Name, &category.Position, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		if isProductUniqueViolation(err) {
+			return nil, ErrDuplicateProductCategoryName
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// DeleteProductCategoryByID deletes the category. Its subcategories move up
+// to its parent and its products become uncategorized.
+func (pc *productCategory) DeleteProductCategoryByID(ctx context.Context, tx database.Tx, id, shopID int) error {
+	queries := []string{
+		`UPDATE product_categories
+		SET parent_id = (SELECT parent_id FROM product_categories WHERE id = $1 AND shop_id = $2), updated_at = now()
+		WHERE parent_id = $1 AND shop_id = $2`,
+		`UPDATE products SET category_id = NULL, updated_at = now() WHERE category_id = $1 AND shop_id = $2`,
+		`DELETE FROM product_categories WHERE id = $1 AND shop_id = $2`,
+	}
+
+	for _, q := range queries {
+		var err error
+		if tx != nil {
+			_, err = tx.ExecContext(ctx, q, id, shopID)
+		} else {
+			_, err = pc.db.ExecContext(ctx, q, id, shopID)
+		}
+		if err != nil {
+			if isProductUniqueViolation(err) {
+				return ErrDuplicateProductCategoryName
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReorderProductCategories sets the position of the shop's categories to
+// their index in categoryIDs. Categories left out keep their position.
+func (pc *productCategory) ReorderProductCategories(ctx context.Context, shopID int, categoryIDs []int) error {
+	q := `
+		UPDATE product_categories c
+		SET position = v.ord - 1, updated_at = now()
+		FROM unnest($2::int[]) WITH ORDINALITY AS v(id, ord)
+		WHERE c.id = v.id AND c.shop_id = $1
+	`
+
+	_, err := pc.db.ExecContext(ctx, q, shopID, pq.Array(categoryIDs))
+	return err
+}
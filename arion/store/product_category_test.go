@@ -0,0 +1,353 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+var productCategoryColumns = []string{"id", "shop_id", "parent_id", "name", "position", "created_at", "updated_at"}
+
+func Test_productCategory_CreateProductCategory(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	parentID := 1
+
+	tests := []struct {
+		name      string
+		input     CreateProductCategoryInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ProductCategory
+		wantErr   error
+	}{
+		{
+			name:  "creates subcategory after its siblings",
+			input: CreateProductCategoryInput{ShopID: 10, ParentID: &parentID, Name: "Chips"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product_categories \(shop_id, parent_id, name, position, created_at\)\s+VALUES \(\$1, \$2, \$3,\s+\(SELECT COALESCE\(MAX\(position\) \+ 1, 0\) FROM product_categories WHERE shop_id = \$1 AND parent_id IS NOT DISTINCT FROM \$2\), \$4\)\s+RETURNING id, shop_id, parent_id, name, position, created_at, updated_at`).
+					WithArgs(10, &parentID, "Chips", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(productCategoryColumns).AddRow(2, 10, 1, "Chips", 3, fixedTime, nil))
+			},
+			want: &model.ProductCategory{ID: 2, ShopID: 10, ParentID: sql.NullInt64{Int64: 1, Valid: true}, Name: "Chips", Position: 3, CreatedAt: fixedTime},
+		},
+		{
+			name:  "returns ErrDuplicateProductCategoryName on unique violation",
+			input: CreateProductCategoryInput{ShopID: 10, Name: "Snacks"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product_categories`).WillReturnError(&pq.Error{Code: "23505"})
+			},
+			wantErr: ErrDuplicateProductCategoryName,
+		},
+		{
+			name:  "returns error on database failure",
+			input: CreateProductCategoryInput{ShopID: 10, Name: "Snacks"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product_categories`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: errors.New("database error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductCategoryStoreWithDB(db)
+			got, gotErr := s.CreateProductCategory(context.Background(), tt.input)
+			if gotErr != nil {
+				if tt.wantErr == nil || gotErr.Error() != tt.wantErr.Error() {
+					t.Errorf("CreateProductCategory() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				t.Fatal("CreateProductCategory() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateProductCategory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_productCategory_GetProductCategoryByID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, parent_id, name, position, created_at, updated_at\s+FROM product_categories\s+WHERE id = \$1 AND shop_id = \$2`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ProductCategory
+		wantErr   bool
+	}{
+		{
+			name: "returns category",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 10).
+					WillReturnRows(sqlmock.NewRows(productCategoryColumns).AddRow(2, 10, nil, "Snacks", 0, fixedTime, fixedTime))
+			},
+			want: &model.ProductCategory{ID: 2, ShopID: 10, Name: "Snacks", CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+		},
+		{
+			name: "returns nil when not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 10).WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 10).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductCategoryStoreWithDB(db)
+			got, gotErr := s.GetProductCategoryByID(context.Background(), 2, 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetProductCategoryByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetProductCategoryByID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProductCategoryByID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_productCategory_GetProductCategoriesByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT c.id, c.shop_id, c.parent_id, c.name, c.position, c.created_at, c.updated_at\s+FROM product_categories c\s+LEFT JOIN product_categories p ON p.id = c.parent_id\s+WHERE c.shop_id = \$1\s+ORDER BY COALESCE\(p.position, c.position\), COALESCE\(p.id, c.id\), c.parent_id NULLS FIRST, c.position, c.id`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.ProductCategory
+		wantErr   bool
+	}{
+		{
+			name: "returns categories with subcategories after their parent",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10).
+					WillReturnRows(sqlmock.NewRows(productCategoryColumns).
+						AddRow(1, 10, nil, "Snacks", 0, fixedTime, nil).
+						AddRow(3, 10, 1, "Chips", 0, fixedTime, nil).
+						AddRow(2, 10, nil, "Drinks", 1, fixedTime, nil))
+			},
+			want: []model.ProductCategory{
+				{ID: 1, ShopID: 10, Name: "Snacks", CreatedAt: fixedTime},
+				{ID: 3, ShopID: 10, ParentID: sql.NullInt64{Int64: 1, Valid: true}, Name: "Chips", CreatedAt: fixedTime},
+				{ID: 2, ShopID: 10, Name: "Drinks", Position: 1, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when shop has no categories",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10).WillReturnRows(sqlmock.NewRows(productCategoryColumns))
+			},
+			want: []model.ProductCategory{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductCategoryStoreWithDB(db)
+			got, gotErr := s.GetProductCategoriesByShopID(context.Background(), 10)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetProductCategoriesByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetProductCategoriesByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProductCategoriesByShopID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_productCategory_UpdateProductCategory(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	name := "Sweets"
+	topLevel := 0
+
+	tests := []struct {
+		name      string
+		input     UpdateProductCategoryInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ProductCategory
+		wantErr   error
+	}{
+		{
+			name:  "renames category",
+			input: UpdateProductCategoryInput{Name: &name},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE product_categories\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, parent_id, name, position, created_at, updated_at`).
+					WithArgs(2, "Sweets").
+					WillReturnRows(sqlmock.NewRows(productCategoryColumns).AddRow(2, 10, nil, "Sweets", 0, fixedTime, fixedTime))
+			},
+			want: &model.ProductCategory{ID: 2, ShopID: 10, Name: "Sweets", CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+		},
+		{
+			name:  "moves category to top level after its new siblings",
+			input: UpdateProductCategoryInput{ParentID: &topLevel},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE product_categories\s+SET position = CASE WHEN parent_id IS NOT DISTINCT FROM NULLIF\(\$2, 0\) THEN position\s+ELSE \(SELECT COALESCE\(MAX\(s.position\) \+ 1, 0\) FROM product_categories s WHERE s.shop_id = product_categories.shop_id AND s.parent_id IS NOT DISTINCT FROM NULLIF\(\$2, 0\)\) END,parent_id = NULLIF\(\$2, 0\),updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(2, 0).
+					WillReturnRows(sqlmock.NewRows(productCategoryColumns).AddRow(2, 10, nil, "Chips", 4, fixedTime, fixedTime))
+			},
+			want: &model.ProductCategory{ID: 2, ShopID: 10, Name: "Chips", Position: 4, CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+		},
+		{
+			name:  "returns ErrDuplicateProductCategoryName on unique violation",
+			input: UpdateProductCategoryInput{Name: &name},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE product_categories`).WillReturnError(&pq.Error{Code: "23505"})
+			},
+			wantErr: ErrDuplicateProductCategoryName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductCategoryStoreWithDB(db)
+			got, gotErr := s.UpdateProductCategory(context.Background(), 2, tt.input)
+			if gotErr != nil {
+				if gotErr != tt.wantErr {
+					t.Errorf("UpdateProductCategory() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				t.Fatal("UpdateProductCategory() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateProductCategory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_productCategory_DeleteProductCategoryByID(t *testing.T) {
+	moveChildren := `UPDATE product_categories\s+SET parent_id = \(SELECT parent_id FROM product_categories WHERE id = \$1 AND shop_id = \$2\), updated_at = now\(\)\s+WHERE parent_id = \$1 AND shop_id = \$2`
+	uncategorize := `UPDATE products SET category_id = NULL, updated_at = now\(\) WHERE category_id = \$1 AND shop_id = \$2`
+	deleteCategory := `DELETE FROM product_categories WHERE id = \$1 AND shop_id = \$2`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "moves subcategories up, uncategorizes products and deletes",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(moveChildren).WithArgs(2, 10).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(uncategorize).WithArgs(2, 10).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(deleteCategory).WithArgs(2, 10).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns ErrDuplicateProductCategoryName when a subcategory name clashes",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(moveChildren).WithArgs(2, 10).WillReturnError(&pq.Error{Code: "23505"})
+			},
+			wantErr: ErrDuplicateProductCategoryName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductCategoryStoreWithDB(db)
+			gotErr := s.DeleteProductCategoryByID(context.Background(), nil, 2, 10)
+			if gotErr != tt.wantErr {
+				t.Errorf("DeleteProductCategoryByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_productCategory_ReorderProductCategories(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE product_categories c\s+SET position = v.ord - 1, updated_at = now\(\)\s+FROM unnest\(\$2::int\[\]\) WITH ORDINALITY AS v\(id, ord\)\s+WHERE c.id = v.id AND c.shop_id = \$1`).
+		WithArgs(10, pq.Array([]int{2, 1})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	s := NewProductCategoryStoreWithDB(db)
+	if err := s.ReorderProductCategories(context.Background(), 10, []int{2, 1}); err != nil {
+		t.Errorf("ReorderProductCategories() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	ProductImageStore interface {
+		CreateProductImage(ctx context.Context, tx database.Tx, input CreateProductImageInput) (*model.ProductImage, error)
+		GetProductImageByID(ctx context.Context, id, productID int) (*model.ProductImage, error)
+		GetProductImagesByProductID(ctx context.Context, productID int) ([]model.ProductImage, error)
+		GetProductImagesByProductIDs(ctx context.Context, productIDs []int) ([]model.ProductImage, error)
+		SetPrimaryProductImage(ctx context.Context, tx database.Tx, id, productID int) error
+		ReorderProductImages(ctx context.Context, productID int, imageIDs []int) error
+		DeleteProductImageByID(ctx context.Context, tx database.Tx, id, productID int) error
+		DeleteProductImagesByProductID(ctx context.Context, tx database.Tx, productID int) error
+	}
+
+	productImage struct {
+		db *sql.DB
+	}
+
+	CreateProductImageInput struct {
+		ProductID    int
+		ShopID       int
+		ThumbnailURL string
+		MediumURL    string
+		FullURL      string
+		IsPrimary    bool // the first image of a product is always primary
+	}
+)
+
+const (
+	productImageColumns = "id, product_id, shop_id, thumbnail_url, medium_url, full_url, position, is_primary, created_at"
+
+	// syncProductImageURLQuery copies the full-size URL of the primary image
+	// of product $1 to products.image_url, which the rest of the app reads.
+	syncProductImageURLQuery = `
+		UPDATE products
+		SET image_url = COALESCE((SELECT full_url FROM product_images WHERE product_id = $1 AND is_primary), ''), updated_at = now()
+		WHERE id = $1
+	`
+
+	// promoteProductImageQuery makes the first image of product $1 primary
+	// when it has none.
+	promoteProductImageQuery = `
+		UPDATE product_images
+		SET is_primary = TRUE
+		WHERE id = (SELECT id FROM product_images WHERE product_id = $1 ORDER BY position, id LIMIT 1)
+			AND NOT EXISTS (SELECT 1 FROM product_images WHERE product_id = $1 AND is_primary)
+	`
+)
+
+func NewProductImageStore() ProductImageStore {
+	return &productImage{db: database.GetDB()}
+}
+
+// NewProductImageStoreWithDB creates a ProductImageStore with a custom db connection (for testing)
+func NewProductImageStoreWithDB(db *sql.DB) ProductImageStore {
+	return &productImage{db: db}
+}
+
+func scanProductImage(row interface{ Scan(...interface{}) error }, image *model.ProductImage) error {
+	return row.Scan(&image.ID, &image.ProductID, &image.ShopID, &image.ThumbnailURL, &image.MediumURL, &image.FullURL, &image.Position, &image.IsPrimary, &image.CreatedAt)
+}
+
+func (pi *productImage) exec(ctx context.Context, tx database.Tx, q string, args ...interface{}) error {
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, q, args...)
+	} else {
+		_, err = pi.db.ExecContext(ctx, q, args...)
+	}
+	return err
+}
+
+// CreateProductImage adds the image after the product's other images. It
+// becomes primary when asked to or when the product has no primary image.
+func (pi *productImage) CreateProductImage(ctx context.Context, tx database.Tx, input CreateProductImageInput) (*model.ProductImage, error) {
+	if input.IsPrimary {
+		q := `UPDATE product_images SET is_primary = FALSE WHERE product_id = $1 AND is_primary`
+		if err := pi.exec(ctx, tx, q, input.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	q := `
+		INSERT INTO product_images (product_id, shop_id, thumbnail_url, medium_url, full_url, position, is_primary)
+		VALUES ($1, $2, $3, $4, $5,
+			(SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1),
+			$6 OR NOT EXISTS (SELECT 1 FROM product_images WHERE product_id = $1 AND is_primary))
+		RETURNING ` + productImageColumns
+
+	args := []interface{}{input.ProductID, input.ShopID, input.ThumbnailURL, input.MediumURL, input.FullURL, input.IsPrimary}
+	var image model.ProductImage
+	var err error
+	if tx != nil {
+		err = scanProductImage(tx.QueryRowContext(ctx, q, args...), &image)
+	} else {
+		err = scanProductImage(pi.db.QueryRowContext(ctx, q, args...), &image)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if image.IsPrimary {
+		if err := pi.exec(ctx, tx, syncProductImageURLQuery, input.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &image, nil
+}
+
+func (pi *productImage) GetProductImageByID(ctx context.Context, id, productID int) (*model.ProductImage, error) {
+	q := `SELECT ` + productImageColumns + ` FROM product_images WHERE id = $1 AND product_id = $2`
+
+	var image model.ProductImage
+	if err := scanProductImage(pi.db.QueryRowContext(ctx, q, id, productID), &image); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// GetProductImagesByProductID returns the product's images in display order.
+func (pi *productImage) GetProductImagesByProductID(ctx context.Context, productID int) ([]model.ProductImage, error) {
+	return pi.GetProductImagesByProductIDs(ctx, []int{productID})
+}
+
+// GetProductImagesByProductIDs returns the images of all the products,
+// ordered by product and then in display order.
+func (pi *productImage) GetProductImagesByProductIDs(ctx context.Context, productIDs []int) ([]model.ProductImage, error) {
+	q := `
+		SELECT ` + productImageColumns + `
+		FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position, id
+	`
+
+	rows, err := pi.db.QueryContext(ctx, q, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []model.ProductImage{}
+	for rows.Next() {
+		var image model.ProductImage
+		if err := scanProductImage(rows, &image); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// SetPrimaryProductImage makes the image the product's primary image.
+func (pi *productImage) SetPrimaryProductImage(ctx context.Context, tx database.Tx, id, productID int) error {
+	q := `
+		UPDATE product_images
+		SET is_primary = (id = $1)
+		WHERE product_id = $2 AND (is_primary OR id = $1)
+	`
+	if err := pi.exec(ctx, tx, q, id, productID); err != nil {
+		return err
+	}
+
+	return pi.exec(ctx, tx, syncProductImageURLQuery, productID)
+}
+
+// ReorderProductImages sets the position of the product's images to their
+// index in imageIDs. Images left out keep their position.
+func (pi *productImage) ReorderProductImages(ctx context.Context, productID int, imageIDs []int) error {
+	q := `
+		UPDATE product_images i
+		SET position = v.ord - 1
+		FROM unnest($2::int[]) WITH ORDINALITY AS v(id, ord)
+		WHERE i.id = v.id AND i.product_id = $1
+	`
+
+	_, err := pi.db.ExecContext(ctx, q, productID, pq.Array(imageIDs))
+	return err
+}
+
+// DeleteProductImageByID deletes the image. When it was primary, the first
+// remaining image of the product takes its place.
+func (pi *productImage) DeleteProductImageByID(ctx context.Context, tx database.Tx, id, productID int) error {
+	q := `DELETE FROM product_images WHERE id = $1 AND product_id = $2`
+	if err := pi.exec(ctx, tx, q, id, productID); err != nil {
+		return err
+	}
+
+	for _, q := range []string{promoteProductImageQuery, syncProductImageURLQuery} {
+		if err := pi.exec(ctx, tx, q, productID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteProductImagesByProductID deletes all the product's images.
+func (pi *productImage) DeleteProductImagesByProductID(ctx context.Context, tx database.Tx, productID int) error {
+	queries := []string{
+		`DELETE FROM product_images WHERE product_id = $1`,
+		syncProductImageURLQuery,
+	}
+
+	for _, q := range queries {
+		if err := pi.exec(ctx, tx, q, productID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,377 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+var productImageRowColumns = []string{"id", "product_id", "shop_id", "thumbnail_url", "medium_url", "full_url", "position", "is_primary", "created_at"}
+
+func Test_productImage_CreateProductImage(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     CreateProductImageInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ProductImage
+		wantErr   bool
+	}{
+		{
+			name:  "first image becomes primary and syncs image_url",
+			input: CreateProductImageInput{ProductID: 1, ShopID: 10, ThumbnailURL: "t", MediumURL: "m", FullURL: "f"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product_images \(product_id, shop_id, thumbnail_url, medium_url, full_url, position, is_primary\)`).
+					WithArgs(1, 10, "t", "m", "f", false).
+					WillReturnRows(sqlmock.NewRows(productImageRowColumns).AddRow(2, 1, 10, "t", "m", "f", 0, true, fixedTime))
+				mock.ExpectExec(`UPDATE products\s+SET image_url = COALESCE`).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: &model.ProductImage{ID: 2, ProductID: 1, ShopID: 10, ThumbnailURL: "t", MediumURL: "m", FullURL: "f", Position: 0, IsPrimary: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "later image is added after the others",
+			input: CreateProductImageInput{ProductID: 1, ShopID: 10, ThumbnailURL: "t", MediumURL: "m", FullURL: "f"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product_images`).
+					WithArgs(1, 10, "t", "m", "f", false).
+					WillReturnRows(sqlmock.NewRows(productImageRowColumns).AddRow(3, 1, 10, "t", "m", "f", 1, false, fixedTime))
+			},
+			want: &model.ProductImage{ID: 3, ProductID: 1, ShopID: 10, ThumbnailURL: "t", MediumURL: "m", FullURL: "f", Position: 1, CreatedAt: fixedTime},
+		},
+		{
+			name:  "primary image replaces the current primary",
+			input: CreateProductImageInput{ProductID: 1, ShopID: 10, ThumbnailURL: "t", MediumURL: "m", FullURL: "f", IsPrimary: true},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE product_images SET is_primary = FALSE WHERE product_id = \$1 AND is_primary`).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`INSERT INTO product_images`).
+					WithArgs(1, 10, "t", "m", "f", true).
+					WillReturnRows(sqlmock.NewRows(productImageRowColumns).AddRow(4, 1, 10, "t", "m", "f", 2, true, fixedTime))
+				mock.ExpectExec(`UPDATE products\s+SET image_url = COALESCE`).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: &model.ProductImage{ID: 4, ProductID: 1, ShopID: 10, ThumbnailURL: "t", MediumURL: "m", FullURL: "f", Position: 2, IsPrimary: true, CreatedAt: fixedTime},
+		},
+		{
+			name:  "returns error on database failure",
+			input: CreateProductImageInput{ProductID: 1, ShopID: 10},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO product_images`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductImageStoreWithDB(db)
+			got, gotErr := s.CreateProductImage(context.Background(), nil, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateProductImage() error = %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateProductImage() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateProductImage() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_productImage_GetProductImageByID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ProductImage
+		wantErr   bool
+	}{
+		{
+			name: "returns the image",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, product_id, shop_id, thumbnail_url, medium_url, full_url, position, is_primary, created_at FROM product_images WHERE id = \$1 AND product_id = \$2`).
+					WithArgs(2, 1).
+					WillReturnRows(sqlmock.NewRows(productImageRowColumns).AddRow(2, 1, 10, "t", "m", "f", 0, true, fixedTime))
+			},
+			want: &model.ProductImage{ID: 2, ProductID: 1, ShopID: 10, ThumbnailURL: "t", MediumURL: "m", FullURL: "f", IsPrimary: true, CreatedAt: fixedTime},
+		},
+		{
+			name: "returns nil when not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT .+ FROM product_images WHERE id = \$1`).
+					WithArgs(2, 1).
+					WillReturnRows(sqlmock.NewRows(productImageRowColumns))
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT .+ FROM product_images`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductImageStoreWithDB(db)
+			got, gotErr := s.GetProductImageByID(context.Background(), 2, 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetProductImageByID() error = %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetProductImageByID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProductImageByID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_productImage_GetProductImagesByProductIDs(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.ProductImage
+		wantErr   bool
+	}{
+		{
+			name: "returns the images in display order",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM product_images\s+WHERE product_id = ANY\(\$1\)\s+ORDER BY product_id, position, id`).
+					WithArgs(pq.Array([]int{1, 2})).
+					WillReturnRows(sqlmock.NewRows(productImageRowColumns).
+						AddRow(2, 1, 10, "t2", "m2", "f2", 0, true, fixedTime).
+						AddRow(3, 2, 10, "t3", "m3", "f3", 0, true, fixedTime))
+			},
+			want: []model.ProductImage{
+				{ID: 2, ProductID: 1, ShopID: 10, ThumbnailURL: "t2", MediumURL: "m2", FullURL: "f2", IsPrimary: true, CreatedAt: fixedTime},
+				{ID: 3, ProductID: 2, ShopID: 10, ThumbnailURL: "t3", MediumURL: "m3", FullURL: "f3", IsPrimary: true, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when there are none",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM product_images`).
+					WithArgs(pq.Array([]int{1, 2})).
+					WillReturnRows(sqlmock.NewRows(productImageRowColumns))
+			},
+			want: []model.ProductImage{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM product_images`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductImageStoreWithDB(db)
+			got, gotErr := s.GetProductImagesByProductIDs(context.Background(), []int{1, 2})
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetProductImagesByProductIDs() error = %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetProductImagesByProductIDs() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProductImagesByProductIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_productImage_SetPrimaryProductImage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE product_images\s+SET is_primary = \(id = \$1\)\s+WHERE product_id = \$2 AND \(is_primary OR id = \$1\)`).
+		WithArgs(3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE products\s+SET image_url = COALESCE`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewProductImageStoreWithDB(db)
+	if err := s.SetPrimaryProductImage(context.Background(), nil, 3, 1); err != nil {
+		t.Fatalf("SetPrimaryProductImage() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_productImage_ReorderProductImages(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "sets positions from the order of the IDs",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE product_images i\s+SET position = v.ord - 1\s+FROM unnest\(\$2::int\[\]\) WITH ORDINALITY AS v\(id, ord\)\s+WHERE i.id = v.id AND i.product_id = \$1`).
+					WithArgs(1, pq.Array([]int{3, 2})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE product_images i`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductImageStoreWithDB(db)
+			gotErr := s.ReorderProductImages(context.Background(), 1, []int{3, 2})
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("ReorderProductImages() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_productImage_DeleteProductImageByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "deletes the image and promotes the next one",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM product_images WHERE id = \$1 AND product_id = \$2`).
+					WithArgs(3, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE product_images\s+SET is_primary = TRUE`).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE products\s+SET image_url = COALESCE`).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM product_images`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewProductImageStoreWithDB(db)
+			gotErr := s.DeleteProductImageByID(context.Background(), nil, 3, 1)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("DeleteProductImageByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_productImage_DeleteProductImagesByProductID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM product_images WHERE product_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE products\s+SET image_url = COALESCE`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewProductImageStoreWithDB(db)
+	if err := s.DeleteProductImagesByProductID(context.Background(), nil, 1); err != nil {
+		t.Fatalf("DeleteProductImagesByProductID() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -30,9 +30,9 @@ func Test_product_GetProductByID(t *testing.T) {
 			productID: 1,
 			shopID:    nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, 10, "Product A", "A great product", 1000, 800, "", true, fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, 10, "Product A", "A great product", 1000, 800, "", true, nil, 0, "{}", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -44,6 +44,7 @@ func Test_product_GetProductByID(t *testing.T) {
 				Price:         1000,
 				OriginalPrice: 800,
 				IsActive:      true,
+				Tags:          []string{},
 				CreatedAt:     fixedTime,
 			},
 			wantErr: false,
@@ -53,9 +54,9 @@ func Test_product_GetProductByID(t *testing.T) {
 			productID: 1,
 			shopID:    []int{10},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, 10, "Product A", "A great product", 1000, 800, "", true, fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL\s+AND shop_id = \$2`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, 10, "Product A", "A great product", 1000, 800, "", true, nil, 0, "{}", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL\s+AND shop_id = \$2`).
 					WithArgs(1, 10).
 					WillReturnRows(rows)
 			},
@@ -67,6 +68,7 @@ func Test_product_GetProductByID(t *testing.T) {
 				Price:         1000,
 				OriginalPrice: 800,
 				IsActive:      true,
+				Tags:          []string{},
 				CreatedAt:     fixedTime,
 			},
 			wantErr: false,
@@ -76,7 +78,7 @@ func Test_product_GetProductByID(t *testing.T) {
 			productID: 9999,
 			shopID:    nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL`).
 					WithArgs(9999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -88,7 +90,7 @@ func Test_product_GetProductByID(t *testing.T) {
 			productID: 1,
 			shopID:    nil,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE id = \$1 AND deleted_at IS NULL`).
 					WithArgs(1).
 					WillReturnError(errors.New("database error"))
 			},
@@ -145,16 +147,16 @@ func Test_product_GetProductsByShopID(t *testing.T) {
 			shopID: 10,
 			filter: model.FilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, 10, "Product A", "Description A", 1000, 800, "", true, fixedTime, nil, nil).
-					AddRow(2, 10, "Product B", "Description B", 2000, 1500, "", true, fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, 10, "Product A", "Description A", 1000, 800, "", true, nil, 0, "{}", fixedTime, nil, nil).
+					AddRow(2, 10, "Product B", "Description B", 2000, 1500, "", true, nil, 0, "{}", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
 					WithArgs(10).
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Product{
-				{ID: 1, ShopID: 10, Name: "Product A", Description: "Description A", Price: 1000, OriginalPrice: 800, IsActive: true, CreatedAt: fixedTime},
-				{ID: 2, ShopID: 10, Name: "Product B", Description: "Description B", Price: 2000, OriginalPrice: 1500, IsActive: true, CreatedAt: fixedTime},
+				{ID: 1, ShopID: 10, Name: "Product A", Description: "Description A", Price: 1000, OriginalPrice: 800, IsActive: true, Tags: []string{}, CreatedAt: fixedTime},
+				{ID: 2, ShopID: 10, Name: "Product B", Description: "Description B", Price: 2000, OriginalPrice: 1500, IsActive: true, Tags: []string{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
@@ -163,8 +165,8 @@ func Test_product_GetProductsByShopID(t *testing.T) {
 			shopID: 9999,
 			filter: model.FilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at", "deleted_at"})
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"})
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
 					WithArgs(9999).
 					WillReturnRows(rows)
 			},
@@ -176,7 +178,7 @@ func Test_product_GetProductsByShopID(t *testing.T) {
 			shopID: 9999,
 			filter: model.FilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
 					WithArgs(9999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -188,7 +190,7 @@ func Test_product_GetProductsByShopID(t *testing.T) {
 			shopID: 10,
 			filter: model.FilterOptions{},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL`).
 					WithArgs(10).
 					WillReturnError(errors.New("database error"))
 			},
@@ -200,14 +202,14 @@ func Test_product_GetProductsByShopID(t *testing.T) {
 			shopID: 10,
 			filter: model.FilterOptions{SearchQuery: strPtr("widget")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, 10, "Widget A", "A useful widget", 1000, 800, "", true, fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+AND name ILIKE \$2`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, 10, "Widget A", "A useful widget", 1000, 800, "", true, nil, 0, "{}", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+AND name ILIKE \$2`).
 					WithArgs(10, "%widget%").
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Product{
-				{ID: 1, ShopID: 10, Name: "Widget A", Description: "A useful widget", Price: 1000, OriginalPrice: 800, IsActive: true, CreatedAt: fixedTime},
+				{ID: 1, ShopID: 10, Name: "Widget A", Description: "A useful widget", Price: 1000, OriginalPrice: 800, IsActive: true, Tags: []string{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
@@ -216,14 +218,14 @@ func Test_product_GetProductsByShopID(t *testing.T) {
 			shopID: 10,
 			filter: model.FilterOptions{IsActive: func() *bool { v := true; return &v }()},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, 10, "Active Product", "Desc", 1000, 800, "", true, fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+AND is_active = \$2`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, 10, "Active Product", "Desc", 1000, 800, "", true, nil, 0, "{}", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+AND is_active = \$2`).
 					WithArgs(10, true).
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Product{
-				{ID: 1, ShopID: 10, Name: "Active Product", Description: "Desc", Price: 1000, OriginalPrice: 800, IsActive: true, CreatedAt: fixedTime},
+				{ID: 1, ShopID: 10, Name: "Active Product", Description: "Desc", Price: 1000, OriginalPrice: 800, IsActive: true, Tags: []string{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
@@ -232,19 +234,48 @@ func Test_product_GetProductsByShopID(t *testing.T) {
 			shopID: 10,
 			filter: model.FilterOptions{Sort: strPtr("name,asc")},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at", "deleted_at"}).
-					AddRow(1, 10, "Alpha", "Desc", 500, 400, "", true, fixedTime, nil, nil).
-					AddRow(2, 10, "Beta", "Desc", 1000, 800, "", true, fixedTime, nil, nil)
-				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+ORDER BY LOWER\(name\) ASC NULLS FIRST`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, 10, "Alpha", "Desc", 500, 400, "", true, nil, 0, "{}", fixedTime, nil, nil).
+					AddRow(2, 10, "Beta", "Desc", 1000, 800, "", true, nil, 0, "{}", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+ORDER BY LOWER\(name\) ASC NULLS FIRST`).
 					WithArgs(10).
 					WillReturnRows(rows)
 			},
 			wantResult: []model.Product{
-				{ID: 1, ShopID: 10, Name: "Alpha", Description: "Desc", Price: 500, OriginalPrice: 400, IsActive: true, CreatedAt: fixedTime},
-				{ID: 2, ShopID: 10, Name: "Beta", Description: "Desc", Price: 1000, OriginalPrice: 800, IsActive: true, CreatedAt: fixedTime},
+				{ID: 1, ShopID: 10, Name: "Alpha", Description: "Desc", Price: 500, OriginalPrice: 400, IsActive: true, Tags: []string{}, CreatedAt: fixedTime},
+				{ID: 2, ShopID: 10, Name: "Beta", Description: "Desc", Price: 1000, OriginalPrice: 800, IsActive: true, Tags: []string{}, CreatedAt: fixedTime},
 			},
 			wantErr: false,
 		},
+		{
+			name:   "get products by shop ID with category and tags filters",
+			shopID: 10,
+			filter: model.FilterOptions{CategoryID: intPtr(3), Tags: []string{"Sale", "new"}},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(1, 10, "Chips", "Desc", 500, 400, "", true, 4, 2, "{sale,spicy}", fixedTime, nil, nil)
+				mock.ExpectQuery(`SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND deleted_at IS NULL\s+AND category_id IN \(SELECT id FROM product_categories WHERE shop_id = \$1 AND \(id = \$2 OR parent_id = \$2\)\) AND EXISTS \(SELECT 1 FROM unnest\(tags\) t WHERE LOWER\(t\) = ANY\(\$3\)\)`).
+					WithArgs(10, 3, pq.Array([]string{"sale", "new"})).
+					WillReturnRows(rows)
+			},
+			wantResult: []model.Product{
+				{ID: 1, ShopID: 10, Name: "Chips", Description: "Desc", Price: 500, OriginalPrice: 400, IsActive: true, CategoryID: sql.NullInt64{Int64: 4, Valid: true}, Position: 2, Tags: []string{"sale", "spicy"}, CreatedAt: fixedTime},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "get products by shop ID sorted by position orders by category then position",
+			shopID: 10,
+			filter: model.FilterOptions{Sort: strPtr("position,asc")},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"})
+				mock.ExpectQuery(`WHERE shop_id = \$1 AND deleted_at IS NULL\s+ORDER BY \(\s+SELECT ARRAY\[.+\]\s+FROM product_categories c\s+LEFT JOIN product_categories pc ON pc.id = c.parent_id\s+WHERE c.id = products.category_id\s+\) ASC NULLS LAST, position ASC, id ASC`).
+					WithArgs(10).
+					WillReturnRows(rows)
+			},
+			wantResult: []model.Product{},
+			wantErr:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -308,8 +339,8 @@ func Test_product_CreateProduct(t *testing.T) {
 				originalPrice: nil,
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "description", "image_url", "is_active"}).AddRow(1, "Product description", "", true)
-				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\), \$7\)\s+RETURNING id, description, image_url, is_active`).
+				rows := sqlmock.NewRows([]string{"id", "description", "image_url", "is_active", "position"}).AddRow(1, "Product description", "", true, 3)
+				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, position, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\),\s+\(SELECT COALESCE\(MAX\(position\) \+ 1, 0\) FROM products WHERE shop_id = \$5 AND category_id IS NULL\), \$7\)\s+RETURNING id, description, image_url, is_active, position`).
 					WithArgs("New Product", strPtr("Product description"), 1500, 1500, 10, nil, sqlmock.AnyArg()).
 					WillReturnRows(rows)
 			},
@@ -320,6 +351,7 @@ func Test_product_CreateProduct(t *testing.T) {
 				Price:         1500,
 				OriginalPrice: 1500,
 				IsActive:      true,
+				Position:      3,
 				ShopID:        10,
 			},
 			wantErr: false,
@@ -334,8 +366,8 @@ func Test_product_CreateProduct(t *testing.T) {
 				originalPrice: intPtr(1200),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "description", "image_url", "is_active"}).AddRow(1, "", "", true)
-				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\), \$7\)\s+RETURNING id, description, image_url, is_active`).
+				rows := sqlmock.NewRows([]string{"id", "description", "image_url", "is_active", "position"}).AddRow(1, "", "", true, 0)
+				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, position, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\),\s+\(SELECT COALESCE\(MAX\(position\) \+ 1, 0\) FROM products WHERE shop_id = \$5 AND category_id IS NULL\), \$7\)\s+RETURNING id, description, image_url, is_active, position`).
 					WithArgs("New Product", nil, 1500, 1200, 10, nil, sqlmock.AnyArg()).
 					WillReturnRows(rows)
 			},
@@ -360,7 +392,7 @@ func Test_product_CreateProduct(t *testing.T) {
 				originalPrice: nil,
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\), \$7\)\s+RETURNING id, description, image_url, is_active`).
+				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, position, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\),\s+\(SELECT COALESCE\(MAX\(position\) \+ 1, 0\) FROM products WHERE shop_id = \$5 AND category_id IS NULL\), \$7\)\s+RETURNING id, description, image_url, is_active, position`).
 					WithArgs("Existing Product", nil, 1000, 1000, 10, nil, sqlmock.AnyArg()).
 					WillReturnError(&pq.Error{Code: "23505"})
 			},
@@ -377,7 +409,7 @@ func Test_product_CreateProduct(t *testing.T) {
 				originalPrice: nil,
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\), \$7\)\s+RETURNING id, description, image_url, is_active`).
+				mock.ExpectQuery(`INSERT INTO products \(name, description, price, original_price, shop_id, image_url, position, created_at\)\s+VALUES \(\$1, COALESCE\(\$2, ''\), \$3, \$4, \$5, COALESCE\(\$6, ''\),\s+\(SELECT COALESCE\(MAX\(position\) \+ 1, 0\) FROM products WHERE shop_id = \$5 AND category_id IS NULL\), \$7\)\s+RETURNING id, description, image_url, is_active, position`).
 					WithArgs("New Product", nil, 1000, 1000, 10, nil, sqlmock.AnyArg()).
 					WillReturnError(errors.New("database error"))
 			},
@@ -441,9 +473,9 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Name: strPtr("Updated Product"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at"}).
-					AddRow(1, 10, "Updated Product", "Description", 1000, 800, "", true, fixedTime, updatedTime)
-				mock.ExpectQuery(`UPDATE products\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at"}).
+					AddRow(1, 10, "Updated Product", "Description", 1000, 800, "", true, nil, 0, "{}", fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE products\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at`).
 					WithArgs(1, "Updated Product").
 					WillReturnRows(rows)
 			},
@@ -455,6 +487,7 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Price:         1000,
 				OriginalPrice: 800,
 				IsActive:      true,
+				Tags:          []string{},
 				CreatedAt:     fixedTime,
 				UpdatedAt:     sql.NullTime{Time: updatedTime, Valid: true},
 			},
@@ -467,9 +500,9 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Price: intPtr(2000),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at"}).
-					AddRow(1, 10, "Product A", "Description", 2000, 800, "", true, fixedTime, updatedTime)
-				mock.ExpectQuery(`UPDATE products\s+SET price = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at"}).
+					AddRow(1, 10, "Product A", "Description", 2000, 800, "", true, nil, 0, "{}", fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE products\s+SET price = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at`).
 					WithArgs(1, 2000).
 					WillReturnRows(rows)
 			},
@@ -481,6 +514,7 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Price:         2000,
 				OriginalPrice: 800,
 				IsActive:      true,
+				Tags:          []string{},
 				CreatedAt:     fixedTime,
 				UpdatedAt:     sql.NullTime{Time: updatedTime, Valid: true},
 			},
@@ -493,9 +527,9 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Description: strPtr("Updated description"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at"}).
-					AddRow(1, 10, "Product A", "Updated description", 1000, 800, "", true, fixedTime, updatedTime)
-				mock.ExpectQuery(`UPDATE products\s+SET description = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at"}).
+					AddRow(1, 10, "Product A", "Updated description", 1000, 800, "", true, nil, 0, "{}", fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE products\s+SET description = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at`).
 					WithArgs(1, "Updated description").
 					WillReturnRows(rows)
 			},
@@ -507,6 +541,7 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Price:         1000,
 				OriginalPrice: 800,
 				IsActive:      true,
+				Tags:          []string{},
 				CreatedAt:     fixedTime,
 				UpdatedAt:     sql.NullTime{Time: updatedTime, Valid: true},
 			},
@@ -519,9 +554,9 @@ func Test_product_UpdateProduct(t *testing.T) {
 				OriginalPrice: intPtr(1200),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "created_at", "updated_at"}).
-					AddRow(1, 10, "Product A", "Description", 1000, 1200, "", true, fixedTime, updatedTime)
-				mock.ExpectQuery(`UPDATE products\s+SET original_price = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at`).
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at"}).
+					AddRow(1, 10, "Product A", "Description", 1000, 1200, "", true, nil, 0, "{}", fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE products\s+SET original_price = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at`).
 					WithArgs(1, 1200).
 					WillReturnRows(rows)
 			},
@@ -533,6 +568,37 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Price:         1000,
 				OriginalPrice: 1200,
 				IsActive:      true,
+				Tags:          []string{},
+				CreatedAt:     fixedTime,
+				UpdatedAt:     sql.NullTime{Time: updatedTime, Valid: true},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "update product category and tags",
+			productID: 1,
+			input: UpdateProductInput{
+				CategoryID: intPtr(3),
+				Tags:       &[]string{"sale", "new"},
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at"}).
+					AddRow(1, 10, "Product A", "Description", 1000, 800, "", true, 3, 5, "{sale,new}", fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE products\s+SET position = CASE WHEN category_id IS NOT DISTINCT FROM NULLIF\(\$2, 0\) THEN position\s+ELSE \(SELECT COALESCE\(MAX\(c.position\) \+ 1, 0\) FROM products c WHERE c.shop_id = products.shop_id AND c.category_id IS NOT DISTINCT FROM NULLIF\(\$2, 0\)\) END,category_id = NULLIF\(\$2, 0\),tags = \$3,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(1, 3, pq.Array([]string{"sale", "new"})).
+					WillReturnRows(rows)
+			},
+			wantResult: &model.Product{
+				ID:            1,
+				ShopID:        10,
+				Name:          "Product A",
+				Description:   "Description",
+				Price:         1000,
+				OriginalPrice: 800,
+				IsActive:      true,
+				CategoryID:    sql.NullInt64{Int64: 3, Valid: true},
+				Position:      5,
+				Tags:          []string{"sale", "new"},
 				CreatedAt:     fixedTime,
 				UpdatedAt:     sql.NullTime{Time: updatedTime, Valid: true},
 			},
@@ -545,7 +611,7 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Name: strPtr("Existing Name"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`UPDATE products\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at`).
+				mock.ExpectQuery(`UPDATE products\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at`).
 					WithArgs(1, "Existing Name").
 					WillReturnError(&pq.Error{Code: "23505"})
 			},
@@ -559,7 +625,7 @@ func Test_product_UpdateProduct(t *testing.T) {
 				Name: strPtr("New Name"),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`UPDATE products\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, created_at, updated_at`).
+				mock.ExpectQuery(`UPDATE products\s+SET name = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at`).
 					WithArgs(9999, "New Name").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -598,6 +664,55 @@ func Test_product_UpdateProduct(t *testing.T) {
 	}
 }
 
+func Test_product_ReorderProducts(t *testing.T) {
+	query := `UPDATE products p\s+SET position = v.ord - 1, updated_at = now\(\)\s+FROM unnest\(\$2::int\[\]\) WITH ORDINALITY AS v\(id, ord\)\s+WHERE p.id = v.id AND p.shop_id = \$1`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "sets positions in list order",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(10, pq.Array([]int{3, 1, 2})).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(10, pq.Array([]int{3, 1, 2})).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+			store := NewProductStoreWithDB(db)
+
+			gotErr := store.ReorderProducts(context.Background(), 10, []int{3, 1, 2})
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("ReorderProducts() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
 func Test_product_DeleteProductByID(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -664,6 +779,9 @@ func Test_product_DeleteProductByID(t *testing.T) {
 }
 
 func Test_product_GetProductsListByActiveOrders(t *testing.T) {
+	query := `SELECT p\.id, p\.name, p\.price, COALESCE\(SUM\(oi\.qty\), 0\)::int AS qty,\s+COALESCE\(pli\.status, \$4\), COALESCE\(pli\.bought_qty, 0\), COALESCE\(pli\.actual_cost, 0\), COALESCE\(pli\.notes, ''\)\s+FROM products p\s+INNER JOIN order_items oi ON p\.id = oi\.product_id\s+INNER JOIN orders o ON oi\.order_id = o\.id\s+LEFT JOIN purchase_list_items pli ON pli\.product_id = p\.id AND pli\.shop_id = o\.shop_id\s+WHERE o\.shop_id = \$1 AND o\.status IN \(\$2, \$3\)\s+GROUP BY p\.id, p\.name, p\.price, pli\.status, pli\.bought_qty, pli\.actual_cost, pli\.notes\s+ORDER BY p\.name ASC`
+	columns := []string{"id", "name", "price", "qty", "status", "bought_qty", "actual_cost", "notes"}
+
 	tests := []struct {
 		name      string
 		shopID    int
@@ -675,16 +793,16 @@ func Test_product_GetProductsListByActiveOrders(t *testing.T) {
 			name:   "returns aggregated products from active orders",
 			shopID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"name", "price", "qty"}).
-					AddRow("Product A", 1000, 5).
-					AddRow("Product B", 2000, 3)
-				mock.ExpectQuery(`SELECT p\.name, p\.price, COALESCE\(SUM\(oi\.qty\), 0\)::int AS qty\s+FROM products p\s+INNER JOIN order_items oi ON p\.id = oi\.product_id\s+INNER JOIN orders o ON oi\.order_id = o\.id\s+WHERE o\.shop_id = \$1 AND o\.status IN \(\$2, \$3\)\s+GROUP BY p\.id, p\.name, p\.price`).
-					WithArgs(10, constant.OrderStatusCreated, constant.OrderStatusInProgress).
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "Product A", 1000, 5, constant.PurchaseStatusBought, 4, 3800, "only 4 left").
+					AddRow(2, "Product B", 2000, 3, constant.PurchaseStatusPending, 0, 0, "")
+				mock.ExpectQuery(query).
+					WithArgs(10, constant.OrderStatusCreated, constant.OrderStatusInProgress, constant.PurchaseStatusPending).
 					WillReturnRows(rows)
 			},
 			want: []model.PurchaseProduct{
-				{ProductName: "Product A", Price: 1000, Qty: 5},
-				{ProductName: "Product B", Price: 2000, Qty: 3},
+				{ProductID: 1, ProductName: "Product A", Price: 1000, Qty: 5, Status: constant.PurchaseStatusBought, BoughtQty: 4, ActualCost: 3800, Notes: "only 4 left"},
+				{ProductID: 2, ProductName: "Product B", Price: 2000, Qty: 3, Status: constant.PurchaseStatusPending},
 			},
 			wantErr: false,
 		},
@@ -692,9 +810,9 @@ func Test_product_GetProductsListByActiveOrders(t *testing.T) {
 			name:   "returns empty list when no active order products",
 			shopID: 20,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"name", "price", "qty"})
-				mock.ExpectQuery(`SELECT p\.name, p\.price, COALESCE\(SUM\(oi\.qty\), 0\)::int AS qty\s+FROM products p\s+INNER JOIN order_items oi ON p\.id = oi\.product_id\s+INNER JOIN orders o ON oi\.order_id = o\.id\s+WHERE o\.shop_id = \$1 AND o\.status IN \(\$2, \$3\)\s+GROUP BY p\.id, p\.name, p\.price`).
-					WithArgs(20, constant.OrderStatusCreated, constant.OrderStatusInProgress).
+				rows := sqlmock.NewRows(columns)
+				mock.ExpectQuery(query).
+					WithArgs(20, constant.OrderStatusCreated, constant.OrderStatusInProgress, constant.PurchaseStatusPending).
 					WillReturnRows(rows)
 			},
 			want:    nil,
@@ -704,8 +822,8 @@ func Test_product_GetProductsListByActiveOrders(t *testing.T) {
 			name:   "returns error on database failure",
 			shopID: 10,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT p\.name, p\.price, COALESCE\(SUM\(oi\.qty\), 0\)::int AS qty\s+FROM products p\s+INNER JOIN order_items oi ON p\.id = oi\.product_id\s+INNER JOIN orders o ON oi\.order_id = o\.id\s+WHERE o\.shop_id = \$1 AND o\.status IN \(\$2, \$3\)\s+GROUP BY p\.id, p\.name, p\.price`).
-					WithArgs(10, constant.OrderStatusCreated, constant.OrderStatusInProgress).
+				mock.ExpectQuery(query).
+					WithArgs(10, constant.OrderStatusCreated, constant.OrderStatusInProgress, constant.PurchaseStatusPending).
 					WillReturnError(errors.New("database error"))
 			},
 			want:    nil,
@@ -806,3 +924,172 @@ func Test_product_SetAllProductsStatusByShopID(t *testing.T) {
 		})
 	}
 }
+
+func Test_product_GetProductsByNames(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, name, description, price, original_price, image_url, is_active, category_id, position, tags, created_at, updated_at, deleted_at\s+FROM products\s+WHERE shop_id = \$1 AND name = ANY\(\$2\) AND deleted_at IS NULL`
+	names := []string{"Kaos Polos", "Topi"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.Product
+		wantErr   bool
+	}{
+		{
+			name: "returns matching products",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "shop_id", "name", "description", "price", "original_price", "image_url", "is_active", "category_id", "position", "tags", "created_at", "updated_at", "deleted_at"}).
+					AddRow(4, 10, "Topi", "", 45000, 30000, "", true, nil, 0, "{}", fixedTime, nil, nil)
+				mock.ExpectQuery(query).WithArgs(10, pq.Array(names)).WillReturnRows(rows)
+			},
+			want: []model.Product{
+				{ID: 4, ShopID: 10, Name: "Topi", Price: 45000, OriginalPrice: 30000, IsActive: true, Tags: []string{}, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10, pq.Array(names)).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			store := NewProductStoreWithDB(db)
+			got, gotErr := store.GetProductsByNames(context.Background(), 10, names)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetProductsByNames() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetProductsByNames() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProductsByNames() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_product_BulkCreateProducts(t *testing.T) {
+	query := `INSERT INTO products \(name, description, price, original_price, shop_id, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\), \(\$7, \$8, \$9, \$10, \$11, \$12\)`
+	inputs := []BulkCreateProductInput{
+		{ShopID: 10, Name: "Kaos Polos", Description: "Katun combed", Price: 85000, OriginalPrice: 60000},
+		{ShopID: 10, Name: "Topi", Price: 45000, OriginalPrice: 45000},
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "inserts all rows in one statement",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("Kaos Polos", "Katun combed", 85000, 60000, 10, sqlmock.AnyArg(), "Topi", "", 45000, 45000, 10, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "maps unique violation to duplicate name",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(&pq.Error{Code: "23505"})
+			},
+			wantErr: ErrDuplicateProductName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewProductStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := store.BulkCreateProducts(context.Background(), tx, inputs)
+			if gotErr != tt.wantErr {
+				t.Errorf("BulkCreateProducts() error = %v, want %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_product_BulkUpdateProducts(t *testing.T) {
+	originalPrice := 60000
+	query := `UPDATE products p\s+SET price = v.price,\s+original_price = COALESCE\(v.original_price, p.original_price\),\s+description = COALESCE\(v.description, p.description\),\s+updated_at = now\(\)\s+FROM \(VALUES \(\$1::int, \$2::int, \$3::int, \$4::text\), \(\$5::int, \$6::int, \$7::int, \$8::text\)\) AS v\(id, price, original_price, description\)\s+WHERE p.id = v.id`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "updates all rows in one statement",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs(3, 85000, 60000, nil, 4, 45000, nil, nil).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewProductStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := store.BulkUpdateProducts(context.Background(), tx, []BulkUpdateProductInput{
+				{ID: 3, Price: 85000, OriginalPrice: &originalPrice},
+				{ID: 4, Price: 45000},
+			})
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("BulkUpdateProducts() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
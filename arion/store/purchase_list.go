@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	PurchaseListStore interface {
+		GetPurchaseListItem(ctx context.Context, tx database.Tx, shopID, productID int) (*model.PurchaseListItem, error)
+		GetPurchaseListItemsByShopID(ctx context.Context, tx database.Tx, shopID int) ([]model.PurchaseListItem, error)
+		UpsertPurchaseListItem(ctx context.Context, tx database.Tx, input UpsertPurchaseListItemInput) (*model.PurchaseListItem, error)
+		ResetPurchaseList(ctx context.Context, shopID int) error
+	}
+
+	purchaselist struct {
+		db *sql.DB
+	}
+
+	UpsertPurchaseListItemInput struct {
+		ShopID     int
+		ProductID  int
+		Status     string
+		BoughtQty  int
+		ActualCost int
+		Notes      string
+	}
+)
+
+func NewPurchaseListStore() PurchaseListStore {
+	return &purchaselist{db: database.GetDB()}
+}
+
+// NewPurchaseListStoreWithDB creates a PurchaseListStore with a custom db connection (for testing)
+func NewPurchaseListStoreWithDB(db *sql.DB) PurchaseListStore {
+	return &purchaselist{db: db}
+}
+
+// GetPurchaseListItem returns the shop's purchase list row of a product.
+// Inside a transaction the row is locked until tx ends, so the bought
+// quantity can't change while it is being allocated.
+func (p *purchaselist) GetPurchaseListItem(ctx context.Context, tx database.Tx, shopID, productID int) (*model.PurchaseListItem, error) {
+	q := `
+		SELECT id, shop_id, product_id, status, bought_qty, actual_cost, notes, created_at, updated_at
+		FROM purchase_list_items
+		WHERE shop_id = $1 AND product_id = $2
+	`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRowContext(ctx, q+" FOR UPDATE", shopID, productID)
+	} else {
+		row = p.db.QueryRowContext(ctx, q, shopID, productID)
+	}
+
+	var item model.PurchaseListItem
+	err := row.Scan(&item.ID, &item.ShopID, &item.ProductID, &item.Status, &item.BoughtQty, &item.ActualCost, &item.Notes, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// GetPurchaseListItemsByShopID returns every product the shop has ticked off
+// its purchase list so far, in product order. Inside a transaction the rows
+// are locked until tx ends, in that same order.
+func (p *purchaselist) GetPurchaseListItemsByShopID(ctx context.Context, tx database.Tx, shopID int) ([]model.PurchaseListItem, error) {
+	q := `
+		SELECT id, shop_id, product_id, status, bought_qty, actual_cost, notes, created_at, updated_at
+		FROM purchase_list_items
+		WHERE shop_id = $1
+		ORDER BY product_id ASC
+	`
+
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, q+" FOR UPDATE", shopID)
+	} else {
+		rows, err = p.db.QueryContext(ctx, q, shopID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.PurchaseListItem{}
+	for rows.Next() {
+		var item model.PurchaseListItem
+		err := rows.Scan(&item.ID, &item.ShopID, &item.ProductID, &item.Status, &item.BoughtQty, &item.ActualCost, &item.Notes, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// UpsertPurchaseListItem records the shopping progress of a product. A shop
+// has at most one row per product, created the first time it is ticked off.
+func (p *purchaselist) UpsertPurchaseListItem(ctx context.Context, tx database.Tx, input UpsertPurchaseListItemInput) (*model.PurchaseListItem, error) {
+	q := `
+		INSERT INTO purchase_list_items (shop_id, product_id, status, bought_qty, actual_cost, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (shop_id, product_id) DO UPDATE
+		SET status = EXCLUDED.status, bought_qty = EXCLUDED.bought_qty, actual_cost = EXCLUDED.actual_cost, notes = EXCLUDED.notes, updated_at = now()
+		RETURNING id, shop_id, product_id, status, bought_qty, actual_cost, notes, created_at, updated_at
+	`
+
+	var item model.PurchaseListItem
+	args := []interface{}{input.ShopID, input.ProductID, input.Status, input.BoughtQty, input.ActualCost, input.Notes}
+	var err error
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, q, args...).Scan(&item.ID, &item.ShopID, &item.ProductID, &item.Status, &item.BoughtQty, &item.ActualCost, &item.Notes, &item.CreatedAt, &item.UpdatedAt)
+	} else {
+		err = p.db.QueryRowContext(ctx, q, args...).Scan(&item.ID, &item.ShopID, &item.ProductID, &item.Status, &item.BoughtQty, &item.ActualCost, &item.Notes, &item.CreatedAt, &item.UpdatedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// ResetPurchaseList clears the shop's shopping progress, e.g. before the next trip.
+func (p *purchaselist) ResetPurchaseList(ctx context.Context, shopID int) error {
+	q := `
+		DELETE FROM purchase_list_items
+		WHERE shop_id = $1
+	`
+
+	_, err := p.db.ExecContext(ctx, q, shopID)
+	return err
+}
@@ -0,0 +1,296 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_purchaselist_GetPurchaseListItem(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, product_id, status, bought_qty, actual_cost, notes, created_at, updated_at\s+FROM purchase_list_items\s+WHERE shop_id = \$1 AND product_id = \$2`
+	columns := []string{"id", "shop_id", "product_id", "status", "bought_qty", "actual_cost", "notes", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		useTx     bool
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.PurchaseListItem
+		wantErr   bool
+	}{
+		{
+			name: "returns purchase list item",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, 10, 5, constant.PurchaseStatusBought, 8, 76000, "", fixedTime, nil)
+				mock.ExpectQuery(query).WithArgs(10, 5).WillReturnRows(rows)
+			},
+			want: &model.PurchaseListItem{ID: 1, ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000, CreatedAt: fixedTime},
+		},
+		{
+			name:  "locks the row inside transaction",
+			useTx: true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, 10, 5, constant.PurchaseStatusBought, 8, 76000, "", fixedTime, nil)
+				mock.ExpectQuery(query+`\s+FOR UPDATE`).WithArgs(10, 5).WillReturnRows(rows)
+			},
+			want: &model.PurchaseListItem{ID: 1, ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000, CreatedAt: fixedTime},
+		},
+		{
+			name: "returns nil when product was not ticked off yet",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10, 5).WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10, 5).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			var got *model.PurchaseListItem
+			var gotErr error
+			s := NewPurchaseListStoreWithDB(db)
+			if tt.useTx {
+				tx, err := db.Begin()
+				if err != nil {
+					t.Fatalf("failed to begin transaction: %v", err)
+				}
+				got, gotErr = s.GetPurchaseListItem(context.Background(), tx, 10, 5)
+			} else {
+				got, gotErr = s.GetPurchaseListItem(context.Background(), nil, 10, 5)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetPurchaseListItem() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetPurchaseListItem() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPurchaseListItem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_purchaselist_GetPurchaseListItemsByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT id, shop_id, product_id, status, bought_qty, actual_cost, notes, created_at, updated_at\s+FROM purchase_list_items\s+WHERE shop_id = \$1\s+ORDER BY product_id ASC`
+	columns := []string{"id", "shop_id", "product_id", "status", "bought_qty", "actual_cost", "notes", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		useTx     bool
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.PurchaseListItem
+		wantErr   bool
+	}{
+		{
+			name: "returns ticked off products",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, 10, 5, constant.PurchaseStatusBought, 8, 76000, "", fixedTime, nil).
+					AddRow(2, 10, 6, constant.PurchaseStatusUnavailable, 0, 0, "sold out", fixedTime, nil)
+				mock.ExpectQuery(query).WithArgs(10).WillReturnRows(rows)
+			},
+			want: []model.PurchaseListItem{
+				{ID: 1, ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000, CreatedAt: fixedTime},
+				{ID: 2, ShopID: 10, ProductID: 6, Status: constant.PurchaseStatusUnavailable, Notes: "sold out", CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when nothing was ticked off",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.PurchaseListItem{},
+		},
+		{
+			name:  "locks the rows inside transaction",
+			useTx: true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query + `\s+FOR UPDATE`).WithArgs(10).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.PurchaseListItem{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(10).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			var got []model.PurchaseListItem
+			var gotErr error
+			s := NewPurchaseListStoreWithDB(db)
+			if tt.useTx {
+				tx, err := db.Begin()
+				if err != nil {
+					t.Fatalf("failed to begin transaction: %v", err)
+				}
+				got, gotErr = s.GetPurchaseListItemsByShopID(context.Background(), tx, 10)
+			} else {
+				got, gotErr = s.GetPurchaseListItemsByShopID(context.Background(), nil, 10)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetPurchaseListItemsByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetPurchaseListItemsByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPurchaseListItemsByShopID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_purchaselist_UpsertPurchaseListItem(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `INSERT INTO purchase_list_items \(shop_id, product_id, status, bought_qty, actual_cost, notes, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, now\(\)\)\s+ON CONFLICT \(shop_id, product_id\) DO UPDATE\s+SET status = EXCLUDED.status, bought_qty = EXCLUDED.bought_qty, actual_cost = EXCLUDED.actual_cost, notes = EXCLUDED.notes, updated_at = now\(\)\s+RETURNING id, shop_id, product_id, status, bought_qty, actual_cost, notes, created_at, updated_at`
+	columns := []string{"id", "shop_id", "product_id", "status", "bought_qty", "actual_cost", "notes", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		input     UpsertPurchaseListItemInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.PurchaseListItem
+		wantErr   bool
+	}{
+		{
+			name:  "records bought product",
+			input: UpsertPurchaseListItemInput{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000, Notes: "last 8 on the shelf"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, 10, 5, constant.PurchaseStatusBought, 8, 76000, "last 8 on the shelf", fixedTime, fixedTime)
+				mock.ExpectQuery(query).
+					WithArgs(10, 5, constant.PurchaseStatusBought, 8, 76000, "last 8 on the shelf").
+					WillReturnRows(rows)
+			},
+			want: &model.PurchaseListItem{ID: 1, ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusBought, BoughtQty: 8, ActualCost: 76000, Notes: "last 8 on the shelf", CreatedAt: fixedTime, UpdatedAt: sql.NullTime{Time: fixedTime, Valid: true}},
+		},
+		{
+			name:  "returns error on database failure",
+			input: UpsertPurchaseListItemInput{ShopID: 10, ProductID: 5, Status: constant.PurchaseStatusUnavailable},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs(10, 5, constant.PurchaseStatusUnavailable, 0, 0, "").
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewPurchaseListStoreWithDB(db)
+			got, gotErr := s.UpsertPurchaseListItem(context.Background(), nil, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("UpsertPurchaseListItem() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("UpsertPurchaseListItem() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpsertPurchaseListItem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_purchaselist_ResetPurchaseList(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "clears shop purchase list",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM purchase_list_items\s+WHERE shop_id = \$1`).
+					WithArgs(10).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM purchase_list_items`).
+					WithArgs(10).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewPurchaseListStoreWithDB(db)
+			gotErr := s.ResetPurchaseList(context.Background(), 10)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("ResetPurchaseList() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	// ReportStore runs the report queries. Rows are handed to fn one at a
+	// time as they are read, so a report is never held in memory; an error
+	// from fn stops the query and is returned.
+	ReportStore interface {
+		StreamSalesByProduct(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.ProductSalesRow) error) error
+		StreamSalesByCustomer(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.CustomerSalesRow) error) error
+		StreamDailyRevenue(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.DailyRevenueRow) error) error
+		StreamReceivables(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.ReceivableRow) error) error
+		StreamPayments(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.PaymentReportRow) error) error
+	}
+
+	report struct {
+		db *sql.DB
+	}
+)
+
+func NewReportStore() ReportStore {
+	return &report{db: database.GetDB()}
+}
+
+// NewReportStoreWithDB creates a ReportStore with a custom db connection (for testing)
+func NewReportStoreWithDB(db *sql.DB) ReportStore {
+	return &report{db: db}
+}
+
+// StreamSalesByProduct groups ordered items by product, best selling first.
+// Dates filter on the order date.
+func (r *report) StreamSalesByProduct(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.ProductSalesRow) error) error {
+	where, args := reportOrderFilter(shopID, "o.created_at", opts, true)
+	q := `
+		SELECT p.id, p.name, COUNT(DISTINCT o.id), COALESCE(SUM(oi.qty), 0), COALESCE(SUM(p.price * oi.qty), 0), COALESCE(SUM(p.original_price * oi.qty), 0)
+		FROM order_items oi
+		INNER JOIN orders o ON o.id = oi.order_id
+		INNER JOIN products p ON p.id = oi.product_id
+		WHERE ` + where + `
+		GROUP BY p.id, p.name
+		ORDER BY 5 DESC, p.name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.ProductSalesRow
+		if err := rows.Scan(&row.ProductID, &row.ProductName, &row.Orders, &row.Qty, &row.Sales, &row.Cost); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamSalesByCustomer groups orders by customer, biggest spender first.
+// Dates filter on the order date.
+func (r *report) StreamSalesByCustomer(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.CustomerSalesRow) error) error {
+	where, args := reportOrderFilter(shopID, "o.created_at", opts, true)
+	q := `
+		SELECT c.id, c.name, c.phone, COUNT(o.id), COALESCE(SUM(o.total_price), 0), COALESCE(SUM(pay.paid), 0)
+		FROM orders o
+		INNER JOIN customers c ON c.id = o.customer_id
+		LEFT JOIN LATERAL (SELECT SUM(op.amount) AS paid FROM order_payments op WHERE op.order_id = o.id) pay ON true
+		WHERE ` + where + `
+		GROUP BY c.id, c.name, c.phone
+		ORDER BY 5 DESC, c.name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.CustomerSalesRow
+		if err := rows.Scan(&row.CustomerID, &row.CustomerName, &row.Phone, &row.Orders, &row.Total, &row.Paid); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamDailyRevenue sums payments per day in the shop's timezone. Dates
+// filter on the payment date, so a payment for an old order counts on the day
+// it came in.
+func (r *report) StreamDailyRevenue(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.DailyRevenueRow) error) error {
+	where, args := reportOrderFilter(shopID, "op.created_at", opts, false)
+	q := `
+		SELECT (op.created_at AT TIME ZONE s.timezone)::date AS day, COUNT(*) FILTER (WHERE op.amount > 0), COALESCE(SUM(op.amount) FILTER (WHERE op.amount > 0), 0), COALESCE(-SUM(op.amount) FILTER (WHERE op.amount < 0), 0)
+		FROM order_payments op
+		INNER JOIN orders o ON o.id = op.order_id
+		INNER JOIN shops s ON s.id = o.shop_id
+		WHERE ` + where + `
+		GROUP BY day
+		ORDER BY day ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.DailyRevenueRow
+		if err := rows.Scan(&row.Date, &row.Payments, &row.Received, &row.Refunded); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamReceivables lists non-cancelled orders whose payments do not cover
+// the amount due (total plus unique code), oldest first. Dates filter on the
+// order date.
+func (r *report) StreamReceivables(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.ReceivableRow) error) error {
+	where, args := reportOrderFilter(shopID, "o.created_at", opts, true)
+	q := `
+		SELECT o.id, o.invoice_number, c.name, c.phone, o.payment_status, o.total_price + o.unique_code, COALESCE(pay.paid, 0), o.dp_due_at, o.created_at
+		FROM orders o
+		INNER JOIN customers c ON c.id = o.customer_id
+		LEFT JOIN LATERAL (SELECT SUM(op.amount) AS paid FROM order_payments op WHERE op.order_id = o.id) pay ON true
+		WHERE ` + where + ` AND o.total_price + o.unique_code > COALESCE(pay.paid, 0)
+		ORDER BY o.created_at ASC, o.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.ReceivableRow
+		if err := rows.Scan(&row.OrderID, &row.InvoiceNumber, &row.CustomerName, &row.Phone, &row.PaymentStatus, &row.AmountDue, &row.Paid, &row.DPDueAt, &row.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamPayments lists the payment ledger, refunds included, in the order the
+// money moved. Dates filter on the payment date.
+func (r *report) StreamPayments(ctx context.Context, shopID int, opts model.OrderFilterOptions, fn func(model.PaymentReportRow) error) error {
+	where, args := reportOrderFilter(shopID, "op.created_at", opts, false)
+	q := `
+		SELECT op.id, op.order_id, o.invoice_number, c.name, op.amount, op.created_at
+		FROM order_payments op
+		INNER JOIN orders o ON o.id = op.order_id
+		INNER JOIN customers c ON c.id = o.customer_id
+		WHERE ` + where + `
+		ORDER BY op.created_at ASC, op.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.PaymentReportRow
+		if err := rows.Scan(&row.ID, &row.OrderID, &row.InvoiceNumber, &row.CustomerName, &row.Amount, &row.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// reportOrderFilter builds the WHERE clause shared by the reports: the shop,
+// the date range on dateCol and the order status and payment status filters.
+// Without a status filter, cancelled orders are left out when
+// excludeCancelled is set; money reports keep them since refunds are booked
+// on cancelled orders.
+func reportOrderFilter(shopID int, dateCol string, opts model.OrderFilterOptions, excludeCancelled bool) (string, []interface{}) {
+	where := "o.shop_id = $1"
+	args := []interface{}{shopID}
+	argNum := 2
+
+	if opts.DateFrom != nil {
+		where += fmt.Sprintf(" AND %s >= $%d", dateCol, argNum)
+		args = append(args, *opts.DateFrom)
+		argNum++
+	}
+	if opts.DateTo != nil {
+		where += fmt.Sprintf(" AND %s < $%d", dateCol, argNum)
+		args = append(args, *opts.DateTo)
+		argNum++
+	}
+	if len(opts.Status) > 0 {
+		where += fmt.Sprintf(" AND o.status = ANY($%d)", argNum)
+		args = append(args, pq.Array(opts.Status))
+		argNum++
+	} else if excludeCancelled {
+		where += fmt.Sprintf(" AND o.status != $%d", argNum)
+		args = append(args, constant.OrderStatusCancelled)
+		argNum++
+	}
+	if opts.PaymentStatus != nil {
+		where += fmt.Sprintf(" AND o.payment_status = $%d", argNum)
+		args = append(args, *opts.PaymentStatus)
+	}
+
+	return where, args
+}
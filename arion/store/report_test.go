@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_reportOrderFilter(t *testing.T) {
+	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	paid := constant.OrderPaymentStatusPaid
+
+	tests := []struct {
+		name             string
+		dateCol          string
+		opts             model.OrderFilterOptions
+		excludeCancelled bool
+		wantWhere        string
+		wantArgs         []interface{}
+	}{
+		{
+			name:             "shop only, cancelled orders left out",
+			dateCol:          "o.created_at",
+			excludeCancelled: true,
+			wantWhere:        "o.shop_id = $1 AND o.status != $2",
+			wantArgs:         []interface{}{1, constant.OrderStatusCancelled},
+		},
+		{
+			name:      "shop only, cancelled orders kept",
+			dateCol:   "op.created_at",
+			wantWhere: "o.shop_id = $1",
+			wantArgs:  []interface{}{1},
+		},
+		{
+			name:             "all filters, status filter replaces the cancelled exclusion",
+			dateCol:          "op.created_at",
+			opts:             model.OrderFilterOptions{DateFrom: &dateFrom, DateTo: &dateTo, Status: []string{"done"}, PaymentStatus: &paid},
+			excludeCancelled: true,
+			wantWhere:        "o.shop_id = $1 AND op.created_at >= $2 AND op.created_at < $3 AND o.status = ANY($4) AND o.payment_status = $5",
+			wantArgs:         []interface{}{1, dateFrom, dateTo, pq.Array([]string{"done"}), paid},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := reportOrderFilter(1, tt.dateCol, tt.opts, tt.excludeCancelled)
+			if where != tt.wantWhere {
+				t.Errorf("reportOrderFilter() where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("reportOrderFilter() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_report_StreamSalesByProduct(t *testing.T) {
+	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		opts      model.OrderFilterOptions
+		fnErr     error
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.ProductSalesRow
+		wantErr   bool
+	}{
+		{
+			name: "streams product rows",
+			opts: model.OrderFilterOptions{DateFrom: &dateFrom},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "orders", "qty", "sales", "cost"}).
+					AddRow(1, "Product A", 3, 5, 50000, 40000).
+					AddRow(2, "Product B", 1, 1, 5000, 4500)
+				mock.ExpectQuery(`SELECT p\.id, p\.name, COUNT\(DISTINCT o\.id\), COALESCE\(SUM\(oi\.qty\), 0\), COALESCE\(SUM\(p\.price \* oi\.qty\), 0\), COALESCE\(SUM\(p\.original_price \* oi\.qty\), 0\)\s+FROM order_items oi\s+INNER JOIN orders o ON o\.id = oi\.order_id\s+INNER JOIN products p ON p\.id = oi\.product_id\s+WHERE o\.shop_id = \$1 AND o\.created_at >= \$2 AND o\.status != \$3\s+GROUP BY p\.id, p\.name\s+ORDER BY 5 DESC, p\.name ASC`).
+					WithArgs(1, dateFrom, constant.OrderStatusCancelled).
+					WillReturnRows(rows)
+			},
+			want: []model.ProductSalesRow{
+				{ProductID: 1, ProductName: "Product A", Orders: 3, Qty: 5, Sales: 50000, Cost: 40000},
+				{ProductID: 2, ProductName: "Product B", Orders: 1, Qty: 1, Sales: 5000, Cost: 4500},
+			},
+		},
+		{
+			name:  "stops at the first callback error",
+			fnErr: errors.New("client gone"),
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "orders", "qty", "sales", "cost"}).
+					AddRow(1, "Product A", 3, 5, 50000, 40000).
+					AddRow(2, "Product B", 1, 1, 5000, 4500)
+				mock.ExpectQuery(`SELECT p\.id, p\.name`).
+					WithArgs(1, constant.OrderStatusCancelled).
+					WillReturnRows(rows)
+			},
+			want:    []model.ProductSalesRow{{ProductID: 1, ProductName: "Product A", Orders: 3, Qty: 5, Sales: 50000, Cost: 40000}},
+			wantErr: true,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT p\.id, p\.name`).
+					WithArgs(1, constant.OrderStatusCancelled).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewReportStoreWithDB(db)
+			var got []model.ProductSalesRow
+			gotErr := s.StreamSalesByProduct(context.Background(), 1, tt.opts, func(row model.ProductSalesRow) error {
+				got = append(got, row)
+				return tt.fnErr
+			})
+
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("StreamSalesByProduct() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StreamSalesByProduct() rows = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_report_StreamSalesByCustomer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "phone", "orders", "total", "paid"}).
+		AddRow(10, "John Doe", "08123", 2, 30000, 25000)
+	mock.ExpectQuery(`SELECT c\.id, c\.name, c\.phone, COUNT\(o\.id\), COALESCE\(SUM\(o\.total_price\), 0\), COALESCE\(SUM\(pay\.paid\), 0\)\s+FROM orders o\s+INNER JOIN customers c ON c\.id = o\.customer_id\s+LEFT JOIN LATERAL \(SELECT SUM\(op\.amount\) AS paid FROM order_payments op WHERE op\.order_id = o\.id\) pay ON true\s+WHERE o\.shop_id = \$1 AND o\.status = ANY\(\$2\)\s+GROUP BY c\.id, c\.name, c\.phone`).
+		WithArgs(1, pq.Array([]string{"done"})).
+		WillReturnRows(rows)
+
+	var got []model.CustomerSalesRow
+	err = NewReportStoreWithDB(db).StreamSalesByCustomer(context.Background(), 1, model.OrderFilterOptions{Status: []string{"done"}}, func(row model.CustomerSalesRow) error {
+		got = append(got, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamSalesByCustomer() error = %v", err)
+	}
+	want := []model.CustomerSalesRow{{CustomerID: 10, CustomerName: "John Doe", Phone: "08123", Orders: 2, Total: 30000, Paid: 25000}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamSalesByCustomer() rows = %v, want %v", got, want)
+	}
+}
+
+func Test_report_StreamDailyRevenue(t *testing.T) {
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"date", "payments", "received", "refunded"}).
+		AddRow(day, 3, 45000, 5000)
+	mock.ExpectQuery(`SELECT \(op\.created_at AT TIME ZONE s\.timezone\)::date AS day, COUNT\(\*\) FILTER \(WHERE op\.amount > 0\), COALESCE\(SUM\(op\.amount\) FILTER \(WHERE op\.amount > 0\), 0\), COALESCE\(-SUM\(op\.amount\) FILTER \(WHERE op\.amount < 0\), 0\)\s+FROM order_payments op\s+INNER JOIN orders o ON o\.id = op\.order_id\s+INNER JOIN shops s ON s\.id = o\.shop_id\s+WHERE o\.shop_id = \$1 AND op\.created_at < \$2\s+GROUP BY day`).
+		WithArgs(1, dateTo).
+		WillReturnRows(rows)
+
+	var got []model.DailyRevenueRow
+	err = NewReportStoreWithDB(db).StreamDailyRevenue(context.Background(), 1, model.OrderFilterOptions{DateTo: &dateTo}, func(row model.DailyRevenueRow) error {
+		got = append(got, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamDailyRevenue() error = %v", err)
+	}
+	want := []model.DailyRevenueRow{{Date: day, Payments: 3, Received: 45000, Refunded: 5000}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamDailyRevenue() rows = %v, want %v", got, want)
+	}
+}
+
+func Test_report_StreamReceivables(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	dueAt := fixedTime.AddDate(0, 0, 3)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "invoice_number", "name", "phone", "payment_status", "amount_due", "paid", "dp_due_at", "created_at"}).
+		AddRow(1, "INV/2024/01/1", "John Doe", "08123", "awaiting_dp", 15123, 0, dueAt, fixedTime).
+		AddRow(2, nil, "Jane Doe", "", "outstanding", 5000, 2000, nil, fixedTime)
+	mock.ExpectQuery(`SELECT o\.id, o\.invoice_number, c\.name, c\.phone, o\.payment_status, o\.total_price \+ o\.unique_code, COALESCE\(pay\.paid, 0\), o\.dp_due_at, o\.created_at\s+FROM orders o\s+INNER JOIN customers c ON c\.id = o\.customer_id\s+LEFT JOIN LATERAL \(SELECT SUM\(op\.amount\) AS paid FROM order_payments op WHERE op\.order_id = o\.id\) pay ON true\s+WHERE o\.shop_id = \$1 AND o\.status != \$2 AND o\.total_price \+ o\.unique_code > COALESCE\(pay\.paid, 0\)\s+ORDER BY o\.created_at ASC, o\.id ASC`).
+		WithArgs(1, constant.OrderStatusCancelled).
+		WillReturnRows(rows)
+
+	var got []model.ReceivableRow
+	err = NewReportStoreWithDB(db).StreamReceivables(context.Background(), 1, model.OrderFilterOptions{}, func(row model.ReceivableRow) error {
+		got = append(got, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamReceivables() error = %v", err)
+	}
+	want := []model.ReceivableRow{
+		{OrderID: 1, InvoiceNumber: sql.NullString{String: "INV/2024/01/1", Valid: true}, CustomerName: "John Doe", Phone: "08123", PaymentStatus: "awaiting_dp", AmountDue: 15123, DPDueAt: sql.NullTime{Time: dueAt, Valid: true}, CreatedAt: fixedTime},
+		{OrderID: 2, CustomerName: "Jane Doe", PaymentStatus: "outstanding", AmountDue: 5000, Paid: 2000, CreatedAt: fixedTime},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamReceivables() rows = %v, want %v", got, want)
+	}
+}
+
+func Test_report_StreamPayments(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	paid := constant.OrderPaymentStatusPaid
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "order_id", "invoice_number", "name", "amount", "created_at"}).
+		AddRow(5, 1, "INV/2024/01/1", "John Doe", 15000, fixedTime).
+		AddRow(6, 1, "INV/2024/01/1", "John Doe", -5000, fixedTime)
+	mock.ExpectQuery(`SELECT op\.id, op\.order_id, o\.invoice_number, c\.name, op\.amount, op\.created_at\s+FROM order_payments op\s+INNER JOIN orders o ON o\.id = op\.order_id\s+INNER JOIN customers c ON c\.id = o\.customer_id\s+WHERE o\.shop_id = \$1 AND o\.payment_status = \$2\s+ORDER BY op\.created_at ASC, op\.id ASC`).
+		WithArgs(1, paid).
+		WillReturnRows(rows)
+
+	var got []model.PaymentReportRow
+	err = NewReportStoreWithDB(db).StreamPayments(context.Background(), 1, model.OrderFilterOptions{PaymentStatus: &paid}, func(row model.PaymentReportRow) error {
+		got = append(got, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamPayments() error = %v", err)
+	}
+	number := sql.NullString{String: "INV/2024/01/1", Valid: true}
+	want := []model.PaymentReportRow{
+		{ID: 5, OrderID: 1, InvoiceNumber: number, CustomerName: "John Doe", Amount: 15000, CreatedAt: fixedTime},
+		{ID: 6, OrderID: 1, InvoiceNumber: number, CustomerName: "John Doe", Amount: -5000, CreatedAt: fixedTime},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamPayments() rows = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+const shareTokenCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+const shareTokenLength = 12
+
+type (
+	ShareLinkStore interface {
+		CreateShareLink(ctx context.Context, tx database.Tx, input CreateShareLinkInput) (*model.ShareLink, error)
+		GetShareLinkByID(ctx context.Context, id, shopID int) (*model.ShareLink, error)
+		GetShareLinkByToken(ctx context.Context, token string) (*model.ShareLink, error)
+		GetDefaultShareLink(ctx context.Context, shopID int) (*model.ShareLink, error)
+		GetShareLinksByShopID(ctx context.Context, shopID int) ([]model.ShareLink, error)
+		RevokeShareLink(ctx context.Context, id, shopID int) error
+		RegenerateShareLinkToken(ctx context.Context, id, shopID int) (string, error)
+	}
+
+	sharelink struct {
+		db *sql.DB
+	}
+
+	// CreateShareLinkInput holds a new link. An empty Token is generated.
+	// ProductIDs that are not the shop's are skipped.
+	CreateShareLinkInput struct {
+		ShopID     int
+		Token      string
+		Label      string
+		ExpiresAt  *time.Time
+		ProductIDs []int
+	}
+)
+
+func NewShareLinkStore() ShareLinkStore {
+	return &sharelink{db: database.GetDB()}
+}
+
+// NewShareLinkStoreWithDB creates a ShareLinkStore with a custom db connection (for testing)
+func NewShareLinkStoreWithDB(db *sql.DB) ShareLinkStore {
+	return &sharelink{db: db}
+}
+
+func generateShareToken() (string, error) {
+	b := make([]byte, shareTokenLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = shareTokenCharset[int(b[i])%len(shareTokenCharset)]
+	}
+	return string(b), nil
+}
+
+func (s *sharelink) CreateShareLink(ctx context.Context, tx database.Tx, input CreateShareLinkInput) (*model.ShareLink, error) {
+	token := input.Token
+	if token == "" {
+		var err error
+		if token, err = generateShareToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	q := `
+		INSERT INTO share_links (shop_id, token, label, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, shop_id, token, label, expires_at, revoked_at, created_at, updated_at
+	`
+
+	var link model.ShareLink
+	err := tx.QueryRowContext(ctx, q, input.ShopID, token, input.Label, input.ExpiresAt, time.Now()).Scan(&link.ID, &link.ShopID, &link.Token, &link.Label, &link.ExpiresAt, &link.RevokedAt, &link.CreatedAt, &link.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	link.ProductIDs = []int{}
+	if len(input.ProductIDs) == 0 {
+		return &link, nil
+	}
+
+	q = `
+		INSERT INTO share_link_products (share_link_id, product_id)
+		SELECT $1, p.id
+		FROM products p
+		WHERE p.id = ANY($2) AND p.shop_id = $3
+		ON CONFLICT DO NOTHING
+		RETURNING product_id
+	`
+
+	rows, err := tx.QueryContext(ctx, q, link.ID, pq.Array(input.ProductIDs), input.ShopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int
+		if err := rows.Scan(&productID); err != nil {
+			return nil, err
+		}
+		link.ProductIDs = append(link.ProductIDs, productID)
+	}
+
+	return &link, rows.Err()
+}
+
+func (s *sharelink) GetShareLinkByID(ctx context.Context, id, shopID int) (*model.ShareLink, error) {
+	q := `
+		SELECT l.id, l.shop_id, l.token, l.label, l.expires_at, l.revoked_at,
+			ARRAY(SELECT product_id FROM share_link_products WHERE share_link_id = l.id ORDER BY product_id),
+			l.created_at, l.updated_at
+		FROM share_links l
+		WHERE l.id = $1 AND l.shop_id = $2
+	`
+
+	return s.getShareLink(ctx, q, id, shopID)
+}
+
+// GetShareLinkByToken returns the link with the token, also when it is revoked
+// or expired.
+func (s *sharelink) GetShareLinkByToken(ctx context.Context, token string) (*model.ShareLink, error) {
+	q := `
+		SELECT l.id, l.shop_id, l.token, l.label, l.expires_at, l.revoked_at,
+			ARRAY(SELECT product_id FROM share_link_products WHERE share_link_id = l.id ORDER BY product_id),
+			l.created_at, l.updated_at
+		FROM share_links l
+		WHERE l.token = $1
+	`
+
+	return s.getShareLink(ctx, q, token)
+}
+
+// GetDefaultShareLink returns the shop's oldest link that shows every product
+// and neither expires nor is revoked.
+func (s *sharelink) GetDefaultShareLink(ctx context.Context, shopID int) (*model.ShareLink, error) {
+	q := `
+		SELECT l.id, l.shop_id, l.token, l.label, l.expires_at, l.revoked_at,
+			ARRAY[]::INT[],
+			l.created_at, l.updated_at
+		FROM share_links l
+		WHERE l.shop_id = $1
+			AND l.expires_at IS NULL
+			AND l.revoked_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM share_link_products WHERE share_link_id = l.id)
+		ORDER BY l.created_at, l.id
+		LIMIT 1
+	`
+
+	return s.getShareLink(ctx, q, shopID)
+}
+
+func (s *sharelink) getShareLink(ctx context.Context, q string, args ...interface{}) (*model.ShareLink, error) {
+	var link model.ShareLink
+	var productIDs pq.Int64Array
+	err := s.db.QueryRowContext(ctx, q, args...).Scan(&link.ID, &link.ShopID, &link.Token, &link.Label, &link.ExpiresAt, &link.RevokedAt, &productIDs, &link.CreatedAt, &link.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	link.ProductIDs = toInts(productIDs)
+	return &link, nil
+}
+
+// GetShareLinksByShopID returns the shop's links, newest first, with the
+// number of temp orders placed through each.
+func (s *sharelink) GetShareLinksByShopID(ctx context.Context, shopID int) ([]model.ShareLink, error) {
+	q := `
+		SELECT l.id, l.shop_id, l.token, l.label, l.expires_at, l.revoked_at,
+			ARRAY(SELECT product_id FROM share_link_products WHERE share_link_id = l.id ORDER BY product_id),
+			COUNT(t.id), COUNT(t.id) FILTER (WHERE t.status = $2),
+			l.created_at, l.updated_at
+		FROM share_links l
+		LEFT JOIN temp_orders t ON t.share_link_id = l.id
+		WHERE l.shop_id = $1
+		GROUP BY l.id
+		ORDER BY l.created_at DESC, l.id DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, q, shopID, constant.TempOrderStatusAccepted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []model.ShareLink{}
+	for rows.Next() {
+		var link model.ShareLink
+		var productIDs pq.Int64Array
+		if err := rows.Scan(&link.ID, &link.ShopID, &link.Token, &link.Label, &link.ExpiresAt, &link.RevokedAt, &productIDs, &link.TempOrders, &link.Accepted, &link.CreatedAt, &link.UpdatedAt); err != nil {
+			return nil, err
+		}
+		link.ProductIDs = toInts(productIDs)
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// RevokeShareLink stops the link from working. Revoking a revoked link keeps
+// its first revocation time.
+func (s *sharelink) RevokeShareLink(ctx context.Context, id, shopID int) error {
+	q := `
+		UPDATE share_links
+		SET revoked_at = COALESCE(revoked_at, now()), updated_at = now()
+		WHERE id = $1 AND shop_id = $2
+	`
+
+	_, err := s.db.ExecContext(ctx, q, id, shopID)
+	return err
+}
+
+// RegenerateShareLinkToken gives the link a new token, so the old one stops
+// working, and reactivates a revoked link. It returns "" if the shop has no
+// such link.
+func (s *sharelink) RegenerateShareLinkToken(ctx context.Context, id, shopID int) (string, error) {
+	token, err := generateShareToken()
+	if err != nil {
+		return "", err
+	}
+
+	q := `
+		UPDATE share_links
+		SET token = $3, revoked_at = NULL, updated_at = now()
+		WHERE id = $1 AND shop_id = $2
+		RETURNING token
+	`
+
+	err = s.db.QueryRowContext(ctx, q, id, shopID, token).Scan(&token)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return token, nil
+}
+
+func toInts(values pq.Int64Array) []int {
+	ints := make([]int, len(values))
+	for i, v := range values {
+		ints[i] = int(v)
+	}
+	return ints
+}
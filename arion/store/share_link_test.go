@@ -0,0 +1,376 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_sharelink_CreateShareLink(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	expiresAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "shop_id", "token", "label", "expires_at", "revoked_at", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		input     CreateShareLinkInput
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ShareLink
+		wantErr   bool
+	}{
+		{
+			name:  "creates link with the given token",
+			input: CreateShareLinkInput{ShopID: 1, Token: "abc123"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO share_links \(shop_id, token, label, expires_at, created_at\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5\)\s+RETURNING id, shop_id, token, label, expires_at, revoked_at, created_at, updated_at`).
+					WithArgs(1, "abc123", "", nil, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "abc123", "", nil, nil, fixedTime, nil))
+			},
+			want: &model.ShareLink{ID: 1, ShopID: 1, Token: "abc123", ProductIDs: []int{}, CreatedAt: fixedTime},
+		},
+		{
+			name:  "creates link with expiry and the shop's products only",
+			input: CreateShareLinkInput{ShopID: 1, Token: "abc123", Label: "Trip Jepang", ExpiresAt: &expiresAt, ProductIDs: []int{3, 4, 99}},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO share_links`).
+					WithArgs(1, "abc123", "Trip Jepang", &expiresAt, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(2, 1, "abc123", "Trip Jepang", expiresAt, nil, fixedTime, nil))
+				mock.ExpectQuery(`INSERT INTO share_link_products \(share_link_id, product_id\)\s+SELECT \$1, p.id\s+FROM products p\s+WHERE p.id = ANY\(\$2\) AND p.shop_id = \$3`).
+					WithArgs(2, sqlmock.AnyArg(), 1).
+					WillReturnRows(sqlmock.NewRows([]string{"product_id"}).AddRow(3).AddRow(4))
+			},
+			want: &model.ShareLink{
+				ID:         2,
+				ShopID:     1,
+				Token:      "abc123",
+				Label:      "Trip Jepang",
+				ExpiresAt:  sql.NullTime{Time: expiresAt, Valid: true},
+				ProductIDs: []int{3, 4},
+				CreatedAt:  fixedTime,
+			},
+		},
+		{
+			name:  "returns error on database failure",
+			input: CreateShareLinkInput{ShopID: 1, Token: "abc123"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO share_links`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			s := NewShareLinkStoreWithDB(db)
+			got, gotErr := s.CreateShareLink(context.Background(), tx, tt.input)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("CreateShareLink() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("CreateShareLink() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateShareLink() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sharelink_GetShareLinkByToken(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `SELECT l.id, l.shop_id, l.token, l.label, l.expires_at, l.revoked_at,\s+ARRAY\(SELECT product_id FROM share_link_products WHERE share_link_id = l.id ORDER BY product_id\),\s+l.created_at, l.updated_at\s+FROM share_links l\s+WHERE l.token = \$1`
+	columns := []string{"id", "shop_id", "token", "label", "expires_at", "revoked_at", "product_ids", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ShareLink
+		wantErr   bool
+	}{
+		{
+			name: "returns revoked link with its products",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("abc123").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(2, 1, "abc123", "IG post", nil, fixedTime, "{3,4}", fixedTime, nil))
+			},
+			want: &model.ShareLink{
+				ID:         2,
+				ShopID:     1,
+				Token:      "abc123",
+				Label:      "IG post",
+				RevokedAt:  sql.NullTime{Time: fixedTime, Valid: true},
+				ProductIDs: []int{3, 4},
+				CreatedAt:  fixedTime,
+			},
+		},
+		{
+			name: "returns nil when token does not exist",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("abc123").WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("abc123").WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewShareLinkStoreWithDB(db)
+			got, gotErr := s.GetShareLinkByToken(context.Background(), "abc123")
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetShareLinkByToken() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetShareLinkByToken() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetShareLinkByToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sharelink_GetDefaultShareLink(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `FROM share_links l\s+WHERE l.shop_id = \$1\s+AND l.expires_at IS NULL\s+AND l.revoked_at IS NULL\s+AND NOT EXISTS \(SELECT 1 FROM share_link_products WHERE share_link_id = l.id\)\s+ORDER BY l.created_at, l.id\s+LIMIT 1`
+	columns := []string{"id", "shop_id", "token", "label", "expires_at", "revoked_at", "product_ids", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      *model.ShareLink
+		wantErr   bool
+	}{
+		{
+			name: "returns oldest unrestricted link",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "abc123", "", nil, nil, "{}", fixedTime, nil))
+			},
+			want: &model.ShareLink{ID: 1, ShopID: 1, Token: "abc123", ProductIDs: []int{}, CreatedAt: fixedTime},
+		},
+		{
+			name: "returns nil when shop has no such link",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(sql.ErrNoRows)
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewShareLinkStoreWithDB(db)
+			got, gotErr := s.GetDefaultShareLink(context.Background(), 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetDefaultShareLink() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetDefaultShareLink() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDefaultShareLink() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sharelink_GetShareLinksByShopID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	query := `COUNT\(t.id\), COUNT\(t.id\) FILTER \(WHERE t.status = \$2\),\s+l.created_at, l.updated_at\s+FROM share_links l\s+LEFT JOIN temp_orders t ON t.share_link_id = l.id\s+WHERE l.shop_id = \$1\s+GROUP BY l.id\s+ORDER BY l.created_at DESC, l.id DESC`
+	columns := []string{"id", "shop_id", "token", "label", "expires_at", "revoked_at", "product_ids", "temp_orders", "accepted", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.ShareLink
+		wantErr   bool
+	}{
+		{
+			name: "returns links with temp order counts",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1, "accepted").
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(2, 1, "def456", "IG post", nil, nil, "{3}", 5, 2, fixedTime, nil).
+						AddRow(1, 1, "abc123", "", nil, nil, "{}", 0, 0, fixedTime, nil))
+			},
+			want: []model.ShareLink{
+				{ID: 2, ShopID: 1, Token: "def456", Label: "IG post", ProductIDs: []int{3}, TempOrders: 5, Accepted: 2, CreatedAt: fixedTime},
+				{ID: 1, ShopID: 1, Token: "abc123", ProductIDs: []int{}, CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty list when shop has no links",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1, "accepted").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.ShareLink{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1, "accepted").WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewShareLinkStoreWithDB(db)
+			got, gotErr := s.GetShareLinksByShopID(context.Background(), 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetShareLinksByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetShareLinksByShopID() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetShareLinksByShopID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sharelink_RevokeShareLink(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE share_links\s+SET revoked_at = COALESCE\(revoked_at, now\(\)\), updated_at = now\(\)\s+WHERE id = \$1 AND shop_id = \$2`).
+		WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewShareLinkStoreWithDB(db)
+	if err := s.RevokeShareLink(context.Background(), 2, 1); err != nil {
+		t.Errorf("RevokeShareLink() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func Test_sharelink_RegenerateShareLinkToken(t *testing.T) {
+	query := `UPDATE share_links\s+SET token = \$3, revoked_at = NULL, updated_at = now\(\)\s+WHERE id = \$1 AND shop_id = \$2\s+RETURNING token`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantToken bool
+		wantErr   bool
+	}{
+		{
+			name: "returns the new token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 1, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow("newtoken"))
+			},
+			wantToken: true,
+		},
+		{
+			name: "returns empty token when link does not exist",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 1, sqlmock.AnyArg()).WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2, 1, sqlmock.AnyArg()).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewShareLinkStoreWithDB(db)
+			got, gotErr := s.RegenerateShareLinkToken(context.Background(), 2, 1)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("RegenerateShareLinkToken() error = %v, wantErr %v", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("RegenerateShareLinkToken() succeeded unexpectedly")
+			}
+			if (got != "") != tt.wantToken {
+				t.Errorf("RegenerateShareLinkToken() = %q, wantToken %v", got, tt.wantToken)
+			}
+		})
+	}
+}
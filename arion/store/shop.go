@@ -2,28 +2,49 @@ package store
 
 import (
 	"context"
-	"crypto/rand"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/zeirash/recapo/arion/common/constant"
 	"github.com/zeirash/recapo/arion/common/database"
 	"github.com/zeirash/recapo/arion/model"
 )
 
-const shareTokenCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
-const shareTokenLength = 12
-
 type (
 	ShopStore interface {
 		CreateShop(ctx context.Context, tx database.Tx, name string) (*model.Shop, error)
-		GetShareTokenByID(ctx context.Context, shopID int) (string, error)
-		GetShopByShareToken(ctx context.Context, shareToken string) (*model.Shop, error)
 		GetShopByID(ctx context.Context, shopID int) (*model.Shop, error)
+		UpdateShop(ctx context.Context, shopID int, input UpdateShopInput) (*model.Shop, error)
+		CloseShop(ctx context.Context, tx database.Tx, shopID int) error
+		ReopenShop(ctx context.Context, shopID int) (bool, error)
+		GetShopsToPurge(ctx context.Context, closedBefore time.Time) ([]int, error)
+		GetShopImageURLs(ctx context.Context, shopID int) ([]string, error)
+		PurgeShop(ctx context.Context, tx database.Tx, shopID int) error
 	}
 
 	shop struct {
 		db *sql.DB
 	}
+
+	UpdateShopInput struct {
+		Name                 *string
+		UniqueCodeEnabled    *bool
+		UniqueCodeAsFee      *bool
+		Address              *string
+		LogoURL              *string
+		BankAccounts         *string
+		InvoiceFooter        *string
+		InvoiceNumberPattern *string
+		Timezone             *string
+		Locale               *string
+		Description          *string
+		WhatsApp             *string
+		Instagram            *string
+		InvoiceMessage       *string
+		Currency             *string
+	}
 )
 
 func NewShopStore() ShopStore {
@@ -35,70 +56,145 @@ func NewShopStoreWithDB(db *sql.DB) ShopStore {
 	return &shop{db: db}
 }
 
-func generateShareToken() (string, error) {
-	b := make([]byte, shareTokenLength)
-	_, err := rand.Read(b)
-	if err != nil {
-		return "", err
-	}
-	for i := range b {
-		b[i] = shareTokenCharset[int(b[i])%len(shareTokenCharset)]
-	}
-	return string(b), nil
-}
-
+// CreateShop adds the shop row. Its storefront link is a share link of its
+// own.
 func (s *shop) CreateShop(ctx context.Context, tx database.Tx, name string) (*model.Shop, error) {
-	shareToken, err := generateShareToken()
-	if err != nil {
-		return nil, err
-	}
-
 	now := time.Now()
 	var id int
 
 	q := `
-		INSERT INTO shops (name, share_token, created_at)
-		VALUES ($1, $2, $3)
+		INSERT INTO shops (name, created_at)
+		VALUES ($1, $2)
 		RETURNING id
 	`
 
-	err = tx.QueryRowContext(ctx, q, name, shareToken, now).Scan(&id)
+	err := tx.QueryRowContext(ctx, q, name, now).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
 
 	return &model.Shop{
-		ID:         id,
-		Name:       name,
-		ShareToken: shareToken,
-		CreatedAt:  now,
+		ID:        id,
+		Name:      name,
+		Timezone:  constant.DefaultShopTimezone,
+		Locale:    constant.DefaultShopLocale,
+		Currency:  constant.DefaultShopCurrency,
+		CreatedAt: now,
 	}, nil
 }
 
-func (s *shop) GetShareTokenByID(ctx context.Context, shopID int) (string, error) {
-	q := `SELECT share_token FROM shops WHERE id = $1`
+func (s *shop) GetShopByID(ctx context.Context, shopID int) (*model.Shop, error) {
+	q := `
+		SELECT id, name, unique_code_enabled, unique_code_as_fee, address, logo_url, bank_accounts, invoice_footer, invoice_number_pattern, timezone, locale, description, whatsapp, instagram, invoice_message, currency, closed_at, created_at, updated_at
+		FROM shops
+		WHERE id = $1
+	`
 
-	var token string
-	err := s.db.QueryRowContext(ctx, q, shopID).Scan(&token)
+	var sh model.Shop
+	err := s.db.QueryRowContext(ctx, q, shopID).Scan(&sh.ID, &sh.Name, &sh.UniqueCodeEnabled, &sh.UniqueCodeAsFee, &sh.Address, &sh.LogoURL, &sh.BankAccounts, &sh.InvoiceFooter, &sh.InvoiceNumberPattern, &sh.Timezone, &sh.Locale, &sh.Description, &sh.WhatsApp, &sh.Instagram, &sh.InvoiceMessage, &sh.Currency, &sh.ClosedAt, &sh.CreatedAt, &sh.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", nil
+			return nil, nil
 		}
-		return "", err
+		return nil, err
 	}
 
-	return token, nil
+	return &sh, nil
 }
 
-func (s *shop) GetShopByID(ctx context.Context, shopID int) (*model.Shop, error) {
-	q := `
-		SELECT id, name, share_token, created_at, updated_at
-		FROM shops
+func (s *shop) UpdateShop(ctx context.Context, shopID int, input UpdateShopInput) (*model.Shop, error) {
+	set := []string{}
+	args := []interface{}{shopID}
+	argNum := 2
+
+	// build query
+	if input.Name != nil {
+		set = append(set, fmt.Sprintf("name = $%d", argNum))
+		args = append(args, *input.Name)
+		argNum++
+	}
+	if input.UniqueCodeEnabled != nil {
+		set = append(set, fmt.Sprintf("unique_code_enabled = $%d", argNum))
+		args = append(args, *input.UniqueCodeEnabled)
+		argNum++
+	}
+	if input.UniqueCodeAsFee != nil {
+		set = append(set, fmt.Sprintf("unique_code_as_fee = $%d", argNum))
+		args = append(args, *input.UniqueCodeAsFee)
+		argNum++
+	}
+	if input.Address != nil {
+		set = append(set, fmt.Sprintf("address = $%d", argNum))
+		args = append(args, *input.Address)
+		argNum++
+	}
+	if input.LogoURL != nil {
+		set = append(set, fmt.Sprintf("logo_url = $%d", argNum))
+		args = append(args, *input.LogoURL)
+		argNum++
+	}
+	if input.BankAccounts != nil {
+		set = append(set, fmt.Sprintf("bank_accounts = $%d", argNum))
+		args = append(args, *input.BankAccounts)
+		argNum++
+	}
+	if input.InvoiceFooter != nil {
+		set = append(set, fmt.Sprintf("invoice_footer = $%d", argNum))
+		args = append(args, *input.InvoiceFooter)
+		argNum++
+	}
+	if input.InvoiceNumberPattern != nil {
+		set = append(set, fmt.Sprintf("invoice_number_pattern = $%d", argNum))
+		args = append(args, *input.InvoiceNumberPattern)
+		argNum++
+	}
+	if input.Timezone != nil {
+		set = append(set, fmt.Sprintf("timezone = $%d", argNum))
+		args = append(args, *input.Timezone)
+		argNum++
+	}
+	if input.Locale != nil {
+		set = append(set, fmt.Sprintf("locale = $%d", argNum))
+		args = append(args, *input.Locale)
+		argNum++
+	}
+	if input.Description != nil {
+		set = append(set, fmt.Sprintf("description = $%d", argNum))
+		args = append(args, *input.Description)
+		argNum++
+	}
+	if input.WhatsApp != nil {
+		set = append(set, fmt.Sprintf("whatsapp = $%d", argNum))
+		args = append(args, *input.WhatsApp)
+		argNum++
+	}
+	if input.Instagram != nil {
+		set = append(set, fmt.Sprintf("instagram = $%d", argNum))
+		args = append(args, *input.Instagram)
+		argNum++
+	}
+	if input.InvoiceMessage != nil {
+		set = append(set, fmt.Sprintf("invoice_message = $%d", argNum))
+		args = append(args, *input.InvoiceMessage)
+		argNum++
+	}
+	if input.Currency != nil {
+		set = append(set, fmt.Sprintf("currency = $%d", argNum))
+		args = append(args, *input.Currency)
+		argNum++
+	}
+
+	set = append(set, "updated_at = now()")
+
+	q := fmt.Sprintf(`
+		UPDATE shops
+		SET %s
 		WHERE id = $1
-	`
+		RETURNING id, name, unique_code_enabled, unique_code_as_fee, address, logo_url, bank_accounts, invoice_footer, invoice_number_pattern, timezone, locale, description, whatsapp, instagram, invoice_message, currency, closed_at, created_at, updated_at
+	`, strings.Join(set, ","))
 
 	var sh model.Shop
-	err := s.db.QueryRowContext(ctx, q, shopID).Scan(&sh.ID, &sh.Name, &sh.ShareToken, &sh.CreatedAt, &sh.UpdatedAt)
+	err := s.db.QueryRowContext(ctx, q, args...).Scan(&sh.ID, &sh.Name, &sh.UniqueCodeEnabled, &sh.UniqueCodeAsFee, &sh.Address, &sh.LogoURL, &sh.BankAccounts, &sh.InvoiceFooter, &sh.InvoiceNumberPattern, &sh.Timezone, &sh.Locale, &sh.Description, &sh.WhatsApp, &sh.Instagram, &sh.InvoiceMessage, &sh.Currency, &sh.ClosedAt, &sh.CreatedAt, &sh.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -109,21 +205,139 @@ func (s *shop) GetShopByID(ctx context.Context, shopID int) (*model.Shop, error)
 	return &sh, nil
 }
 
-func (s *shop) GetShopByShareToken(ctx context.Context, shareToken string) (*model.Shop, error) {
+// CloseShop marks the shop as closed. The shop keeps its data until it is
+// purged.
+func (s *shop) CloseShop(ctx context.Context, tx database.Tx, shopID int) error {
+	q := `
+		UPDATE shops
+		SET closed_at = now(), updated_at = now()
+		WHERE id = $1 AND closed_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, q, shopID)
+	return err
+}
+
+// ReopenShop clears the closure of a shop that has not been purged yet. It
+// reports whether the shop was reopened.
+func (s *shop) ReopenShop(ctx context.Context, shopID int) (bool, error) {
 	q := `
-		SELECT id, name, share_token, created_at, updated_at
+		UPDATE shops
+		SET closed_at = NULL, updated_at = now()
+		WHERE id = $1 AND closed_at IS NOT NULL AND purged_at IS NULL
+	`
+
+	res, err := s.db.ExecContext(ctx, q, shopID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// GetShopsToPurge returns the shops closed before closedBefore whose data is
+// still there.
+func (s *shop) GetShopsToPurge(ctx context.Context, closedBefore time.Time) ([]int, error) {
+	q := `
+		SELECT id
 		FROM shops
-		WHERE share_token = $1
+		WHERE closed_at < $1 AND purged_at IS NULL
+		ORDER BY id
 	`
 
-	var sh model.Shop
-	err := s.db.QueryRowContext(ctx, q, shareToken).Scan(&sh.ID, &sh.Name, &sh.ShareToken, &sh.CreatedAt, &sh.UpdatedAt)
+	rows, err := s.db.QueryContext(ctx, q, closedBefore)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetShopImageURLs returns the uploaded images of the shop: the logo,
+// product images and their variants, soft-deleted products included, and
+// refund proofs.
+func (s *shop) GetShopImageURLs(ctx context.Context, shopID int) ([]string, error) {
+	q := `
+		SELECT logo_url FROM shops WHERE id = $1 AND logo_url <> ''
+		UNION
+		SELECT image_url FROM products WHERE shop_id = $1 AND image_url <> ''
+		UNION
+		SELECT unnest(ARRAY[thumbnail_url, medium_url, full_url]) FROM product_images WHERE shop_id = $1
+		UNION
+		SELECT r.proof_url FROM order_refunds r
+		INNER JOIN orders o ON o.id = r.order_id
+		WHERE o.shop_id = $1 AND r.proof_url <> ''
+	`
+
+	rows, err := s.db.QueryContext(ctx, q, shopID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &sh, nil
+	urls := []string{}
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// PurgeShop deletes the data and users of the shop, child rows first, and
+// empties the shop row. The row itself stays with purged_at set, so the
+// subscription and its payments are kept for billing records.
+func (s *shop) PurgeShop(ctx context.Context, tx database.Tx, shopID int) error {
+	queries := []string{
+		`DELETE FROM temp_order_items WHERE temp_order_id IN (SELECT id FROM temp_orders WHERE shop_id = $1)`,
+		`DELETE FROM temp_orders WHERE shop_id = $1`,
+		`DELETE FROM order_refunds WHERE order_id IN (SELECT id FROM orders WHERE shop_id = $1)`,
+		`DELETE FROM customer_credits WHERE shop_id = $1`,
+		`DELETE FROM bank_statement_lines WHERE shop_id = $1`,
+		`DELETE FROM order_payments WHERE order_id IN (SELECT id FROM orders WHERE shop_id = $1)`,
+		`DELETE FROM order_items WHERE order_id IN (SELECT id FROM orders WHERE shop_id = $1)`,
+		`DELETE FROM orders WHERE shop_id = $1`,
+		`DELETE FROM customer_addresses WHERE shop_id = $1`,
+		`DELETE FROM customers WHERE shop_id = $1`,
+		`DELETE FROM share_link_products WHERE share_link_id IN (SELECT id FROM share_links WHERE shop_id = $1)`,
+		`DELETE FROM share_links WHERE shop_id = $1`,
+		`DELETE FROM purchase_list_items WHERE shop_id = $1`,
+		`DELETE FROM product_images WHERE shop_id = $1`,
+		`DELETE FROM products WHERE shop_id = $1`,
+		`DELETE FROM product_categories WHERE shop_id = $1`,
+		`DELETE FROM dp_rules WHERE shop_id = $1`,
+		`DELETE FROM document_sequences WHERE shop_id = $1`,
+		`DELETE FROM invitations WHERE shop_id = $1`,
+		`DELETE FROM users WHERE shop_id = $1`,
+		`UPDATE shops
+		SET address = '', logo_url = '', bank_accounts = '', invoice_footer = '', description = '',
+			whatsapp = '', instagram = '', invoice_message = '', purged_at = now(), updated_at = now()
+		WHERE id = $1`,
+	}
+
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q, shopID); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -12,77 +12,6 @@ import (
 	"github.com/zeirash/recapo/arion/model"
 )
 
-func Test_shop_GetShopByShareToken(t *testing.T) {
-	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
-
-	tests := []struct {
-		name       string
-		shareToken string
-		mockSetup  func(mock sqlmock.Sqlmock)
-		wantResult *model.Shop
-		wantErr    bool
-	}{
-		{
-			name:       "successfully get shop by share token",
-			shareToken: "abc123xyz789",
-			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "share_token", "created_at", "updated_at"}).
-					AddRow(1, "My Shop", "abc123xyz789", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, name, share_token, created_at, updated_at\s+FROM shops\s+WHERE share_token = \$1`).
-					WithArgs("abc123xyz789").
-					WillReturnRows(rows)
-			},
-			wantResult: &model.Shop{
-				ID:         1,
-				Name:       "My Shop",
-				ShareToken: "abc123xyz789",
-				CreatedAt:  fixedTime,
-			},
-			wantErr: false,
-		},
-		{
-			name:       "returns nil when shop not found",
-			shareToken: "nonexistent",
-			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, share_token, created_at, updated_at\s+FROM shops\s+WHERE share_token = \$1`).
-					WithArgs("nonexistent").
-					WillReturnError(sql.ErrNoRows)
-			},
-			wantResult: nil,
-			wantErr:    false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			db, mock, err := sqlmock.New()
-			if err != nil {
-				t.Fatalf("failed to create sqlmock: %v", err)
-			}
-			defer db.Close()
-
-			tt.mockSetup(mock)
-
-			s := &shop{db: db}
-			got, gotErr := s.GetShopByShareToken(context.Background(), tt.shareToken)
-
-			if gotErr != nil {
-				if !tt.wantErr {
-					t.Errorf("GetShopByShareToken() error = %v, wantErr %v", gotErr, tt.wantErr)
-				}
-				return
-			}
-			if tt.wantErr {
-				t.Fatal("GetShopByShareToken() succeeded unexpectedly")
-			}
-
-			if !reflect.DeepEqual(got, tt.wantResult) {
-				t.Errorf("GetShopByShareToken() = %v, want %v", got, tt.wantResult)
-			}
-		})
-	}
-}
-
 func Test_shop_CreateShop(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -97,14 +26,16 @@ func Test_shop_CreateShop(t *testing.T) {
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
-				mock.ExpectQuery(`INSERT INTO shops \(name, share_token, created_at\)\s+VALUES \(\$1, \$2, \$3\)\s+RETURNING id`).
-					WithArgs("My Shop", sqlmock.AnyArg(), sqlmock.AnyArg()).
+				mock.ExpectQuery(`INSERT INTO shops \(name, created_at\)\s+VALUES \(\$1, \$2\)\s+RETURNING id`).
+					WithArgs("My Shop", sqlmock.AnyArg()).
 					WillReturnRows(rows)
 			},
 			wantResult: &model.Shop{
-				ID:         1,
-				Name:       "My Shop",
-				ShareToken: "abc123def456", // Will be overwritten in assertion
+				ID:       1,
+				Name:     "My Shop",
+				Timezone: "Asia/Jakarta",
+				Locale:   "id",
+				Currency: "IDR",
 			},
 			wantErr: false,
 		},
@@ -113,8 +44,8 @@ func Test_shop_CreateShop(t *testing.T) {
 			shopName: "My Shop",
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery(`INSERT INTO shops \(name, share_token, created_at\)\s+VALUES \(\$1, \$2, \$3\)\s+RETURNING id`).
-					WithArgs("My Shop", sqlmock.AnyArg(), sqlmock.AnyArg()).
+				mock.ExpectQuery(`INSERT INTO shops \(name, created_at\)\s+VALUES \(\$1, \$2\)\s+RETURNING id`).
+					WithArgs("My Shop", sqlmock.AnyArg()).
 					WillReturnError(errors.New("database error"))
 			},
 			wantResult: nil,
@@ -150,9 +81,8 @@ func Test_shop_CreateShop(t *testing.T) {
 				t.Fatal("CreateShop() succeeded unexpectedly")
 			}
 
-			// Set expected CreatedAt and ShareToken to match for DeepEqual comparison
+			// Set expected CreatedAt to match for DeepEqual comparison
 			tt.wantResult.CreatedAt = got.CreatedAt
-			tt.wantResult.ShareToken = got.ShareToken
 
 			if !reflect.DeepEqual(got, tt.wantResult) {
 				t.Errorf("CreateShop() = %v, want %v", got, tt.wantResult)
@@ -161,46 +91,53 @@ func Test_shop_CreateShop(t *testing.T) {
 	}
 }
 
-func Test_shop_GetShareTokenByID(t *testing.T) {
+func Test_shop_GetShopByID(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name      string
 		shopID    int
 		mockSetup func(mock sqlmock.Sqlmock)
-		want      string
+		want      *model.Shop
 		wantErr   bool
 	}{
 		{
-			name:   "successfully get share token by shop id",
+			name:   "successfully get shop by id",
 			shopID: 1,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"share_token"}).AddRow("abc123xyz789")
-				mock.ExpectQuery(`SELECT share_token FROM shops WHERE id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "name", "unique_code_enabled", "unique_code_as_fee", "address", "logo_url", "bank_accounts", "invoice_footer", "invoice_number_pattern", "timezone", "locale", "description", "whatsapp", "instagram", "invoice_message", "currency", "closed_at", "created_at", "updated_at"}).
+					AddRow(1, "My Shop", false, false, "", "", "", "", "", "Asia/Jakarta", "id", "", "", "", "", "IDR", nil, fixedTime, nil)
+				mock.ExpectQuery(`SELECT id, name, unique_code_enabled, unique_code_as_fee, address, logo_url, bank_accounts, invoice_footer, invoice_number_pattern, timezone, locale, description, whatsapp, instagram, invoice_message, currency, closed_at, created_at, updated_at\s+FROM shops\s+WHERE id = \$1`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
-			want:    "abc123xyz789",
-			wantErr: false,
+			want: &model.Shop{
+				ID:        1,
+				Name:      "My Shop",
+				Timezone:  "Asia/Jakarta",
+				Locale:    "id",
+				Currency:  "IDR",
+				CreatedAt: fixedTime,
+			},
 		},
 		{
-			name:   "returns empty string when shop not found",
+			name:   "returns nil when shop not found",
 			shopID: 999,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT share_token FROM shops WHERE id = \$1`).
+				mock.ExpectQuery(`SELECT id, name, unique_code_enabled, unique_code_as_fee, address, logo_url, bank_accounts, invoice_footer, invoice_number_pattern, timezone, locale, description, whatsapp, instagram, invoice_message, currency, closed_at, created_at, updated_at\s+FROM shops\s+WHERE id = \$1`).
 					WithArgs(999).
 					WillReturnError(sql.ErrNoRows)
 			},
-			want:    "",
-			wantErr: false,
+			want: nil,
 		},
 		{
 			name:   "returns error on database failure",
 			shopID: 1,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT share_token FROM shops WHERE id = \$1`).
+				mock.ExpectQuery(`SELECT id, name, unique_code_enabled, unique_code_as_fee, address, logo_url, bank_accounts, invoice_footer, invoice_number_pattern, timezone, locale, description, whatsapp, instagram, invoice_message, currency, closed_at, created_at, updated_at\s+FROM shops\s+WHERE id = \$1`).
 					WithArgs(1).
 					WillReturnError(errors.New("database error"))
 			},
-			want:    "",
 			wantErr: true,
 		},
 	}
@@ -215,56 +152,167 @@ func Test_shop_GetShareTokenByID(t *testing.T) {
 			tt.mockSetup(mock)
 
 			s := &shop{db: db}
-			got, gotErr := s.GetShareTokenByID(context.Background(), tt.shopID)
+			got, gotErr := s.GetShopByID(context.Background(), tt.shopID)
 			if gotErr != nil {
 				if !tt.wantErr {
-					t.Errorf("GetShareTokenByID() error = %v, wantErr %v", gotErr, tt.wantErr)
+					t.Errorf("GetShopByID() failed: %v", gotErr)
 				}
 				return
 			}
 			if tt.wantErr {
-				t.Fatal("GetShareTokenByID() succeeded unexpectedly")
+				t.Fatal("GetShopByID() succeeded unexpectedly")
 			}
-			if got != tt.want {
-				t.Errorf("GetShareTokenByID() = %v, want %v", got, tt.want)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetShopByID() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_shop_GetShopByID(t *testing.T) {
+func Test_shop_UpdateShop(t *testing.T) {
 	fixedTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	updatedTime := time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC)
+	enabled := true
 
 	tests := []struct {
 		name      string
 		shopID    int
+		input     UpdateShopInput
 		mockSetup func(mock sqlmock.Sqlmock)
 		want      *model.Shop
 		wantErr   bool
 	}{
 		{
-			name:   "successfully get shop by id",
+			name:   "successfully enable unique code",
 			shopID: 1,
+			input:  UpdateShopInput{UniqueCodeEnabled: &enabled},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "share_token", "created_at", "updated_at"}).
-					AddRow(1, "My Shop", "abc123xyz789", fixedTime, nil)
-				mock.ExpectQuery(`SELECT id, name, share_token, created_at, updated_at\s+FROM shops\s+WHERE id = \$1`).
-					WithArgs(1).
+				rows := sqlmock.NewRows([]string{"id", "name", "unique_code_enabled", "unique_code_as_fee", "address", "logo_url", "bank_accounts", "invoice_footer", "invoice_number_pattern", "timezone", "locale", "description", "whatsapp", "instagram", "invoice_message", "currency", "closed_at", "created_at", "updated_at"}).
+					AddRow(1, "My Shop", true, false, "", "", "", "", "", "Asia/Jakarta", "id", "", "", "", "", "IDR", nil, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE shops\s+SET unique_code_enabled = \$2,updated_at = now\(\)\s+WHERE id = \$1\s+RETURNING id, name, unique_code_enabled, unique_code_as_fee, address, logo_url, bank_accounts, invoice_footer, invoice_number_pattern, timezone, locale, description, whatsapp, instagram, invoice_message, currency, closed_at, created_at, updated_at`).
+					WithArgs(1, true).
 					WillReturnRows(rows)
 			},
 			want: &model.Shop{
-				ID:         1,
-				Name:       "My Shop",
-				ShareToken: "abc123xyz789",
-				CreatedAt:  fixedTime,
+				ID:                1,
+				Name:              "My Shop",
+				UniqueCodeEnabled: true,
+				Timezone:          "Asia/Jakarta",
+				Locale:            "id",
+				Currency:          "IDR",
+				CreatedAt:         fixedTime,
+				UpdatedAt:         sql.NullTime{Time: updatedTime, Valid: true},
+			},
+		},
+		{
+			name:   "successfully update invoice branding",
+			shopID: 1,
+			input: UpdateShopInput{
+				Address:       func() *string { s := "Jl. Melati 5, Bandung"; return &s }(),
+				BankAccounts:  func() *string { s := "BCA 1234567890 a.n. My Shop"; return &s }(),
+				InvoiceFooter: func() *string { s := "Terima kasih!"; return &s }(),
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "unique_code_enabled", "unique_code_as_fee", "address", "logo_url", "bank_accounts", "invoice_footer", "invoice_number_pattern", "timezone", "locale", "description", "whatsapp", "instagram", "invoice_message", "currency", "closed_at", "created_at", "updated_at"}).
+					AddRow(1, "My Shop", false, false, "Jl. Melati 5, Bandung", "", "BCA 1234567890 a.n. My Shop", "Terima kasih!", "", "Asia/Jakarta", "id", "", "", "", "", "IDR", nil, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE shops\s+SET address = \$2,bank_accounts = \$3,invoice_footer = \$4,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(1, "Jl. Melati 5, Bandung", "BCA 1234567890 a.n. My Shop", "Terima kasih!").
+					WillReturnRows(rows)
+			},
+			want: &model.Shop{
+				ID:            1,
+				Name:          "My Shop",
+				Address:       "Jl. Melati 5, Bandung",
+				BankAccounts:  "BCA 1234567890 a.n. My Shop",
+				InvoiceFooter: "Terima kasih!",
+				Timezone:      "Asia/Jakarta",
+				Locale:        "id",
+				Currency:      "IDR",
+				CreatedAt:     fixedTime,
+				UpdatedAt:     sql.NullTime{Time: updatedTime, Valid: true},
+			},
+		},
+		{
+			name:   "successfully update invoice number pattern",
+			shopID: 1,
+			input:  UpdateShopInput{InvoiceNumberPattern: func() *string { s := "INV/{YYYY}/{seq:4}"; return &s }()},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "unique_code_enabled", "unique_code_as_fee", "address", "logo_url", "bank_accounts", "invoice_footer", "invoice_number_pattern", "timezone", "locale", "description", "whatsapp", "instagram", "invoice_message", "currency", "closed_at", "created_at", "updated_at"}).
+					AddRow(1, "My Shop", false, false, "", "", "", "", "INV/{YYYY}/{seq:4}", "Asia/Jakarta", "id", "", "", "", "", "IDR", nil, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE shops\s+SET invoice_number_pattern = \$2,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(1, "INV/{YYYY}/{seq:4}").
+					WillReturnRows(rows)
+			},
+			want: &model.Shop{
+				ID:                   1,
+				Name:                 "My Shop",
+				InvoiceNumberPattern: "INV/{YYYY}/{seq:4}",
+				Timezone:             "Asia/Jakarta",
+				Locale:               "id",
+				Currency:             "IDR",
+				CreatedAt:            fixedTime,
+				UpdatedAt:            sql.NullTime{Time: updatedTime, Valid: true},
+			},
+		},
+		{
+			name:   "successfully update timezone and locale",
+			shopID: 1,
+			input: UpdateShopInput{
+				Timezone: func() *string { s := "Asia/Makassar"; return &s }(),
+				Locale:   func() *string { s := "en"; return &s }(),
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "unique_code_enabled", "unique_code_as_fee", "address", "logo_url", "bank_accounts", "invoice_footer", "invoice_number_pattern", "timezone", "locale", "description", "whatsapp", "instagram", "invoice_message", "currency", "closed_at", "created_at", "updated_at"}).
+					AddRow(1, "My Shop", false, false, "", "", "", "", "", "Asia/Makassar", "en", "", "", "", "", "IDR", nil, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE shops\s+SET timezone = \$2,locale = \$3,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(1, "Asia/Makassar", "en").
+					WillReturnRows(rows)
+			},
+			want: &model.Shop{
+				ID:        1,
+				Name:      "My Shop",
+				Timezone:  "Asia/Makassar",
+				Locale:    "en",
+				Currency:  "IDR",
+				CreatedAt: fixedTime,
+				UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
+			},
+		},
+		{
+			name:   "successfully update profile",
+			shopID: 1,
+			input: UpdateShopInput{
+				Name:      func() *string { s := "Toko Jastip"; return &s }(),
+				WhatsApp:  func() *string { s := "6281234567890"; return &s }(),
+				Instagram: func() *string { s := "tokojastip"; return &s }(),
+				Currency:  func() *string { s := "SGD"; return &s }(),
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "unique_code_enabled", "unique_code_as_fee", "address", "logo_url", "bank_accounts", "invoice_footer", "invoice_number_pattern", "timezone", "locale", "description", "whatsapp", "instagram", "invoice_message", "currency", "closed_at", "created_at", "updated_at"}).
+					AddRow(1, "Toko Jastip", false, false, "", "", "", "", "", "Asia/Jakarta", "id", "", "6281234567890", "tokojastip", "", "SGD", nil, fixedTime, updatedTime)
+				mock.ExpectQuery(`UPDATE shops\s+SET name = \$2,whatsapp = \$3,instagram = \$4,currency = \$5,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(1, "Toko Jastip", "6281234567890", "tokojastip", "SGD").
+					WillReturnRows(rows)
+			},
+			want: &model.Shop{
+				ID:        1,
+				Name:      "Toko Jastip",
+				Timezone:  "Asia/Jakarta",
+				Locale:    "id",
+				WhatsApp:  "6281234567890",
+				Instagram: "tokojastip",
+				Currency:  "SGD",
+				CreatedAt: fixedTime,
+				UpdatedAt: sql.NullTime{Time: updatedTime, Valid: true},
 			},
 		},
 		{
 			name:   "returns nil when shop not found",
 			shopID: 999,
+			input:  UpdateShopInput{UniqueCodeAsFee: &enabled},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, share_token, created_at, updated_at\s+FROM shops\s+WHERE id = \$1`).
-					WithArgs(999).
+				mock.ExpectQuery(`UPDATE shops\s+SET unique_code_as_fee = \$2,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(999, true).
 					WillReturnError(sql.ErrNoRows)
 			},
 			want: nil,
@@ -272,9 +320,10 @@ func Test_shop_GetShopByID(t *testing.T) {
 		{
 			name:   "returns error on database failure",
 			shopID: 1,
+			input:  UpdateShopInput{UniqueCodeEnabled: &enabled},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, name, share_token, created_at, updated_at\s+FROM shops\s+WHERE id = \$1`).
-					WithArgs(1).
+				mock.ExpectQuery(`UPDATE shops\s+SET unique_code_enabled = \$2,updated_at = now\(\)\s+WHERE id = \$1`).
+					WithArgs(1, true).
 					WillReturnError(errors.New("database error"))
 			},
 			wantErr: true,
@@ -291,18 +340,237 @@ func Test_shop_GetShopByID(t *testing.T) {
 			tt.mockSetup(mock)
 
 			s := &shop{db: db}
-			got, gotErr := s.GetShopByID(context.Background(), tt.shopID)
+			got, gotErr := s.UpdateShop(context.Background(), tt.shopID, tt.input)
 			if gotErr != nil {
 				if !tt.wantErr {
-					t.Errorf("GetShopByID() failed: %v", gotErr)
+					t.Errorf("UpdateShop() failed: %v", gotErr)
 				}
 				return
 			}
 			if tt.wantErr {
-				t.Fatal("GetShopByID() succeeded unexpectedly")
+				t.Fatal("UpdateShop() succeeded unexpectedly")
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("GetShopByID() = %v, want %v", got, tt.want)
+				t.Errorf("UpdateShop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shop_CloseShop(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE shops\s+SET closed_at = now\(\), updated_at = now\(\)\s+WHERE id = \$1 AND closed_at IS NULL`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := NewShopStoreWithDB(db).CloseShop(context.Background(), tx, 1); err != nil {
+		t.Errorf("CloseShop() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_shop_ReopenShop(t *testing.T) {
+	query := `UPDATE shops\s+SET closed_at = NULL, updated_at = now\(\)\s+WHERE id = \$1 AND closed_at IS NOT NULL AND purged_at IS NULL`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name: "reopens a closed shop",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: true,
+		},
+		{
+			name: "shop not closed or already purged",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			want: false,
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			got, err := NewShopStoreWithDB(db).ReopenShop(context.Background(), 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReopenShop() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ReopenShop() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_shop_GetShopsToPurge(t *testing.T) {
+	closedBefore := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	query := `SELECT id\s+FROM shops\s+WHERE closed_at < \$1 AND purged_at IS NULL\s+ORDER BY id`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []int
+		wantErr   bool
+	}{
+		{
+			name: "returns the shops past their grace period",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(closedBefore).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(7))
+			},
+			want: []int{3, 7},
+		},
+		{
+			name: "no shops to purge",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(closedBefore).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			want: []int{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(closedBefore).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			got, err := NewShopStoreWithDB(db).GetShopsToPurge(context.Background(), closedBefore)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetShopsToPurge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetShopsToPurge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shop_GetShopImageURLs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT logo_url FROM shops WHERE id = \$1 AND logo_url <> ''\s+UNION\s+SELECT image_url FROM products WHERE shop_id = \$1 AND image_url <> ''\s+UNION\s+SELECT unnest\(ARRAY\[thumbnail_url, medium_url, full_url\]\) FROM product_images WHERE shop_id = \$1\s+UNION\s+SELECT r\.proof_url FROM order_refunds r\s+INNER JOIN orders o ON o\.id = r\.order_id\s+WHERE o\.shop_id = \$1 AND r\.proof_url <> ''`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"logo_url"}).AddRow("/uploads/logos/a.png").AddRow("/uploads/products/b.jpg"))
+
+	got, err := NewShopStoreWithDB(db).GetShopImageURLs(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetShopImageURLs() error = %v", err)
+	}
+	if want := []string{"/uploads/logos/a.png", "/uploads/products/b.jpg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetShopImageURLs() = %v, want %v", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_shop_PurgeShop(t *testing.T) {
+	tables := []string{
+		"temp_order_items", "temp_orders", "order_refunds", "customer_credits", "bank_statement_lines", "order_payments", "order_items", "orders",
+		"customer_addresses", "customers", "share_link_products", "share_links", "purchase_list_items", "product_images", "products",
+		"product_categories", "dp_rules", "document_sequences", "invitations", "users",
+	}
+	emptyShop := `UPDATE shops\s+SET address = '', logo_url = '', bank_accounts = '', invoice_footer = '', description = '',\s+whatsapp = '', instagram = '', invoice_message = '', purged_at = now\(\), updated_at = now\(\)\s+WHERE id = \$1`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "deletes the shop's rows child tables first and empties the shop",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				for _, table := range tables {
+					mock.ExpectExec(`DELETE FROM ` + table + ` WHERE `).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectExec(emptyShop).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "stops at the first failing delete",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM temp_order_items`).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := NewShopStoreWithDB(db).PurgeShop(context.Background(), tx, 1)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("PurgeShop() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
 			}
 		})
 	}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	// StatsStore aggregates orders and payments for the dashboard. Buckets are
+	// cut in the period's timezone.
+	StatsStore interface {
+		GetTimeseries(ctx context.Context, shopID int, period model.StatsPeriod) ([]model.StatsBucket, error)
+		GetTopProducts(ctx context.Context, shopID int, period model.StatsPeriod, limit int) ([]model.TopProduct, error)
+		GetTopCustomers(ctx context.Context, shopID int, period model.StatsPeriod, limit int) ([]model.TopCustomer, error)
+	}
+
+	stats struct {
+		db *sql.DB
+	}
+)
+
+func NewStatsStore() StatsStore {
+	return &stats{db: database.GetDB()}
+}
+
+// NewStatsStoreWithDB creates a StatsStore with a custom db connection (for testing)
+func NewStatsStoreWithDB(db *sql.DB) StatsStore {
+	return &stats{db: db}
+}
+
+// GetTimeseries returns the buckets of the period that have any activity, in
+// order. Orders, sales, net sales and new customers count non-cancelled
+// orders by order date; revenue sums payments net of refunds by payment date.
+// A customer is new in the bucket of their first non-cancelled order.
+func (s *stats) GetTimeseries(ctx context.Context, shopID int, period model.StatsPeriod) ([]model.StatsBucket, error) {
+	q := `
+		WITH ord AS (
+			SELECT date_trunc($2, o.created_at AT TIME ZONE $3) AS bucket, COUNT(*) AS orders, SUM(o.total_price) AS sales
+			FROM orders o
+			WHERE o.shop_id = $1 AND o.status != $6 AND o.created_at >= $4 AND o.created_at < $5
+			GROUP BY 1
+		), pay AS (
+			SELECT date_trunc($2, op.created_at AT TIME ZONE $3) AS bucket, SUM(op.amount) AS revenue
+			FROM order_payments op
+			INNER JOIN orders o ON o.id = op.order_id
+			WHERE o.shop_id = $1 AND op.created_at >= $4 AND op.created_at < $5
+			GROUP BY 1
+		), net AS (
+			SELECT date_trunc($2, o.created_at AT TIME ZONE $3) AS bucket, SUM((p.price - p.original_price) * oi.qty) AS net_sales
+			FROM order_items oi
+			INNER JOIN orders o ON o.id = oi.order_id
+			INNER JOIN products p ON p.id = oi.product_id
+			WHERE o.shop_id = $1 AND o.status != $6 AND o.created_at >= $4 AND o.created_at < $5
+			GROUP BY 1
+		), cust AS (
+			SELECT date_trunc($2, f.first_at AT TIME ZONE $3) AS bucket, COUNT(*) AS new_customers
+			FROM (
+				SELECT MIN(o.created_at) AS first_at
+				FROM orders o
+				WHERE o.shop_id = $1 AND o.status != $6
+				GROUP BY o.customer_id
+			) f
+			WHERE f.first_at >= $4 AND f.first_at < $5
+			GROUP BY 1
+		)
+		SELECT b.bucket, COALESCE(ord.orders, 0), COALESCE(ord.sales, 0), COALESCE(pay.revenue, 0), COALESCE(net.net_sales, 0), COALESCE(cust.new_customers, 0)
+		FROM (SELECT bucket FROM ord UNION SELECT bucket FROM pay UNION SELECT bucket FROM net UNION SELECT bucket FROM cust) b
+		LEFT JOIN ord ON ord.bucket = b.bucket
+		LEFT JOIN pay ON pay.bucket = b.bucket
+		LEFT JOIN net ON net.bucket = b.bucket
+		LEFT JOIN cust ON cust.bucket = b.bucket
+		ORDER BY b.bucket ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, q, shopID, period.Bucket, period.Timezone, period.From, period.To, constant.OrderStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []model.StatsBucket{}
+	for rows.Next() {
+		var b model.StatsBucket
+		if err := rows.Scan(&b.Start, &b.Orders, &b.Sales, &b.Revenue, &b.NetSales, &b.NewCustomers); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, rows.Err()
+}
+
+// GetTopProducts returns the best selling products of the period by sales.
+func (s *stats) GetTopProducts(ctx context.Context, shopID int, period model.StatsPeriod, limit int) ([]model.TopProduct, error) {
+	q := `
+		SELECT p.id, p.name, SUM(oi.qty), SUM(p.price * oi.qty)
+		FROM order_items oi
+		INNER JOIN orders o ON o.id = oi.order_id
+		INNER JOIN products p ON p.id = oi.product_id
+		WHERE o.shop_id = $1 AND o.status != $2 AND o.created_at >= $3 AND o.created_at < $4
+		GROUP BY p.id, p.name
+		ORDER BY 4 DESC, p.name ASC
+		LIMIT $5
+	`
+
+	rows, err := s.db.QueryContext(ctx, q, shopID, constant.OrderStatusCancelled, period.From, period.To, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []model.TopProduct{}
+	for rows.Next() {
+		var p model.TopProduct
+		if err := rows.Scan(&p.ProductID, &p.ProductName, &p.Qty, &p.Sales); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+
+	return products, rows.Err()
+}
+
+// GetTopCustomers returns the customers that ordered the most in the period.
+func (s *stats) GetTopCustomers(ctx context.Context, shopID int, period model.StatsPeriod, limit int) ([]model.TopCustomer, error) {
+	q := `
+		SELECT c.id, c.name, COUNT(o.id), SUM(o.total_price)
+		FROM orders o
+		INNER JOIN customers c ON c.id = o.customer_id
+		WHERE o.shop_id = $1 AND o.status != $2 AND o.created_at >= $3 AND o.created_at < $4
+		GROUP BY c.id, c.name
+		ORDER BY 4 DESC, c.name ASC
+		LIMIT $5
+	`
+
+	rows, err := s.db.QueryContext(ctx, q, shopID, constant.OrderStatusCancelled, period.From, period.To, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []model.TopCustomer{}
+	for rows.Next() {
+		var c model.TopCustomer
+		if err := rows.Scan(&c.CustomerID, &c.CustomerName, &c.Orders, &c.Sales); err != nil {
+			return nil, err
+		}
+		customers = append(customers, c)
+	}
+
+	return customers, rows.Err()
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/common/constant"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+var testStatsPeriod = model.StatsPeriod{
+	From:     time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC),
+	To:       time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC),
+	Bucket:   constant.StatsBucketWeek,
+	Timezone: "Asia/Jakarta",
+}
+
+func Test_stats_GetTimeseries(t *testing.T) {
+	week1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	week2 := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.StatsBucket
+		wantErr   bool
+	}{
+		{
+			name: "returns buckets with activity",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"bucket", "orders", "sales", "revenue", "net_sales", "new_customers"}).
+					AddRow(week1, 3, 60000, 45000, 12000, 2).
+					AddRow(week2, 0, 0, 15000, 0, 0)
+				mock.ExpectQuery(`WITH ord AS \(\s+SELECT date_trunc\(\$2, o\.created_at AT TIME ZONE \$3\) AS bucket, COUNT\(\*\) AS orders, SUM\(o\.total_price\) AS sales\s+FROM orders o\s+WHERE o\.shop_id = \$1 AND o\.status != \$6 AND o\.created_at >= \$4 AND o\.created_at < \$5.+ORDER BY b\.bucket ASC`).
+					WithArgs(1, constant.StatsBucketWeek, "Asia/Jakarta", testStatsPeriod.From, testStatsPeriod.To, constant.OrderStatusCancelled).
+					WillReturnRows(rows)
+			},
+			want: []model.StatsBucket{
+				{Start: week1, Orders: 3, Sales: 60000, Revenue: 45000, NetSales: 12000, NewCustomers: 2},
+				{Start: week2, Revenue: 15000},
+			},
+		},
+		{
+			name: "returns empty slice when nothing happened",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`WITH ord AS`).
+					WillReturnRows(sqlmock.NewRows([]string{"bucket", "orders", "sales", "revenue", "net_sales", "new_customers"}))
+			},
+			want: []model.StatsBucket{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`WITH ord AS`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewStatsStoreWithDB(db)
+			got, err := s.GetTimeseries(context.Background(), 1, testStatsPeriod)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetTimeseries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTimeseries() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func Test_stats_GetTopProducts(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.TopProduct
+		wantErr   bool
+	}{
+		{
+			name: "returns products by sales",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "qty", "sales"}).
+					AddRow(2, "Product B", 4, 80000).
+					AddRow(1, "Product A", 10, 50000)
+				mock.ExpectQuery(`SELECT p\.id, p\.name, SUM\(oi\.qty\), SUM\(p\.price \* oi\.qty\)\s+FROM order_items oi\s+INNER JOIN orders o ON o\.id = oi\.order_id\s+INNER JOIN products p ON p\.id = oi\.product_id\s+WHERE o\.shop_id = \$1 AND o\.status != \$2 AND o\.created_at >= \$3 AND o\.created_at < \$4\s+GROUP BY p\.id, p\.name\s+ORDER BY 4 DESC, p\.name ASC\s+LIMIT \$5`).
+					WithArgs(1, constant.OrderStatusCancelled, testStatsPeriod.From, testStatsPeriod.To, 5).
+					WillReturnRows(rows)
+			},
+			want: []model.TopProduct{
+				{ProductID: 2, ProductName: "Product B", Qty: 4, Sales: 80000},
+				{ProductID: 1, ProductName: "Product A", Qty: 10, Sales: 50000},
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT p\.id, p\.name`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewStatsStoreWithDB(db)
+			got, err := s.GetTopProducts(context.Background(), 1, testStatsPeriod, 5)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetTopProducts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTopProducts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_stats_GetTopCustomers(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.TopCustomer
+		wantErr   bool
+	}{
+		{
+			name: "returns customers by sales",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "orders", "sales"}).
+					AddRow(10, "John Doe", 3, 90000)
+				mock.ExpectQuery(`SELECT c\.id, c\.name, COUNT\(o\.id\), SUM\(o\.total_price\)\s+FROM orders o\s+INNER JOIN customers c ON c\.id = o\.customer_id\s+WHERE o\.shop_id = \$1 AND o\.status != \$2 AND o\.created_at >= \$3 AND o\.created_at < \$4\s+GROUP BY c\.id, c\.name\s+ORDER BY 4 DESC, c\.name ASC\s+LIMIT \$5`).
+					WithArgs(1, constant.OrderStatusCancelled, testStatsPeriod.From, testStatsPeriod.To, 5).
+					WillReturnRows(rows)
+			},
+			want: []model.TopCustomer{{CustomerID: 10, CustomerName: "John Doe", Orders: 3, Sales: 90000}},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT c\.id, c\.name`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewStatsStoreWithDB(db)
+			got, err := s.GetTopCustomers(context.Background(), 1, testStatsPeriod, 5)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetTopCustomers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTopCustomers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/zeirash/recapo/arion/common/database"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+type (
+	UploadedObjectStore interface {
+		CreateUploadedObject(ctx context.Context, key string, size int64, contentType string) error
+		GetUnreferencedUploadedObjects(ctx context.Context, createdBefore time.Time, limit int) ([]model.UploadedObject, error)
+		DeleteUploadedObject(ctx context.Context, key string) error
+	}
+
+	uploadedObject struct {
+		db *sql.DB
+	}
+)
+
+func NewUploadedObjectStore() UploadedObjectStore {
+	return &uploadedObject{db: database.GetDB()}
+}
+
+// NewUploadedObjectStoreWithDB creates an UploadedObjectStore with a custom db connection (for testing)
+func NewUploadedObjectStoreWithDB(db *sql.DB) UploadedObjectStore {
+	return &uploadedObject{db: db}
+}
+
+// CreateUploadedObject records a stored object. Storing a key again
+// records it anew.
+func (u *uploadedObject) CreateUploadedObject(ctx context.Context, key string, size int64, contentType string) error {
+	q := `
+		INSERT INTO uploaded_objects (key, size, content_type, created_at)
+		VALUES ($1, $2, $3, now())
+		ON CONFLICT (key) DO UPDATE SET size = EXCLUDED.size, content_type = EXCLUDED.content_type, created_at = EXCLUDED.created_at
+	`
+
+	_, err := u.db.ExecContext(ctx, q, key, size, contentType)
+	return err
+}
+
+// GetUnreferencedUploadedObjects returns up to limit objects, oldest first,
+// stored before createdBefore that no row points to. Keys are "dir/file",
+// so a URL points to the object whose key is its last two path segments,
+// whatever host it is served from.
+func (u *uploadedObject) GetUnreferencedUploadedObjects(ctx context.Context, createdBefore time.Time, limit int) ([]model.UploadedObject, error) {
+	q := `
+		WITH refs AS (
+			SELECT logo_url AS url FROM shops
+			UNION ALL
+			SELECT image_url FROM products
+			UNION ALL
+			SELECT unnest(ARRAY[thumbnail_url, medium_url, full_url]) FROM product_images
+			UNION ALL
+			SELECT proof_url FROM order_refunds
+		)
+		SELECT o.key, o.size, o.content_type, o.created_at
+		FROM uploaded_objects o
+		WHERE o.created_at < $1
+			AND NOT EXISTS (SELECT 1 FROM refs WHERE substring(refs.url from '[^/]+/[^/]+$') = o.key)
+		ORDER BY o.created_at, o.key
+		LIMIT $2
+	`
+
+	rows, err := u.db.QueryContext(ctx, q, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := []model.UploadedObject{}
+	for rows.Next() {
+		var object model.UploadedObject
+		if err := rows.Scan(&object.Key, &object.Size, &object.ContentType, &object.CreatedAt); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, rows.Err()
+}
+
+func (u *uploadedObject) DeleteUploadedObject(ctx context.Context, key string) error {
+	q := `DELETE FROM uploaded_objects WHERE key = $1`
+
+	_, err := u.db.ExecContext(ctx, q, key)
+	return err
+}
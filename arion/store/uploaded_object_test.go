@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeirash/recapo/arion/model"
+)
+
+func Test_uploadedObject_CreateUploadedObject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO uploaded_objects \(key, size, content_type, created_at\)\s+VALUES \(\$1, \$2, \$3, now\(\)\)\s+ON CONFLICT \(key\) DO UPDATE`).
+		WithArgs("products/a_full.webp", int64(1024), "image/webp").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewUploadedObjectStoreWithDB(db)
+	if err := s.CreateUploadedObject(context.Background(), "products/a_full.webp", 1024, "image/webp"); err != nil {
+		t.Fatalf("CreateUploadedObject() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func Test_uploadedObject_GetUnreferencedUploadedObjects(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	createdBefore := fixedTime.Add(24 * time.Hour)
+	columns := []string{"key", "size", "content_type", "created_at"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      []model.UploadedObject
+		wantErr   bool
+	}{
+		{
+			name: "returns the objects no row points to, oldest first",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`WITH refs AS \(\s+SELECT logo_url AS url FROM shops\s+UNION ALL\s+SELECT image_url FROM products\s+UNION ALL\s+SELECT unnest\(ARRAY\[thumbnail_url, medium_url, full_url\]\) FROM product_images\s+UNION ALL\s+SELECT proof_url FROM order_refunds\s+\)\s+SELECT o.key, o.size, o.content_type, o.created_at\s+FROM uploaded_objects o\s+WHERE o.created_at < \$1\s+AND NOT EXISTS \(SELECT 1 FROM refs WHERE substring\(refs.url from '\[\^/\]\+/\[\^/\]\+\$'\) = o.key\)\s+ORDER BY o.created_at, o.key\s+LIMIT \$2`).
+					WithArgs(createdBefore, 100).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow("products/a_full.webp", int64(2048), "image/webp", fixedTime).
+						AddRow("refunds/b.jpg", int64(512), "image/jpeg", fixedTime))
+			},
+			want: []model.UploadedObject{
+				{Key: "products/a_full.webp", Size: 2048, ContentType: "image/webp", CreatedAt: fixedTime},
+				{Key: "refunds/b.jpg", Size: 512, ContentType: "image/jpeg", CreatedAt: fixedTime},
+			},
+		},
+		{
+			name: "returns empty slice when every object is in use",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM uploaded_objects o`).
+					WithArgs(createdBefore, 100).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.UploadedObject{},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM uploaded_objects o`).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			s := NewUploadedObjectStoreWithDB(db)
+			got, gotErr := s.GetUnreferencedUploadedObjects(context.Background(), createdBefore, 100)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("GetUnreferencedUploadedObjects() error = %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("GetUnreferencedUploadedObjects() succeeded unexpectedly")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUnreferencedUploadedObjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_uploadedObject_DeleteUploadedObject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM uploaded_objects WHERE key = \$1`).
+		WithArgs("products/a_full.webp").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewUploadedObjectStoreWithDB(db)
+	if err := s.DeleteUploadedObject(context.Background(), "products/a_full.webp"); err != nil {
+		t.Fatalf("DeleteUploadedObject() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
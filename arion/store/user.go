@@ -24,6 +24,7 @@ type (
 		UpdateUser(ctx context.Context, id int, input UpdateUserInput) (*model.User, error)
 		SetSessionToken(ctx context.Context, userID int, sessionToken string) error
 		ClearSessionToken(ctx context.Context, userID int) error
+		ResetSessionTokensByShopID(ctx context.Context, tx database.Tx, shopID int) error
 		Roles() []string
 		IsValidRole(role string) bool
 	}
@@ -252,6 +253,14 @@ func (u *user) ClearSessionToken(ctx context.Context, userID int) error {
 	return err
 }
 
+// ResetSessionTokensByShopID gives every user of the shop a new random session
+// token, which signs out all of their sessions.
+func (u *user) ResetSessionTokensByShopID(ctx context.Context, tx database.Tx, shopID int) error {
+	q := `UPDATE users SET session_token = md5(random()::text || id::text), updated_at = now() WHERE shop_id = $1`
+	_, err := tx.ExecContext(ctx, q, shopID)
+	return err
+}
+
 func (u *user) Roles() []string {
 	return []string{
 		constant.RoleSystem,
@@ -889,3 +889,55 @@ func Test_user_GetUsersByShopID(t *testing.T) {
 		})
 	}
 }
+
+func Test_user_ResetSessionTokensByShopID(t *testing.T) {
+	query := `UPDATE users SET session_token = md5\(random\(\)::text \|\| id::text\), updated_at = now\(\) WHERE shop_id = \$1`
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "gives every user of the shop a new session token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+		},
+		{
+			name: "returns error on database failure",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(10).WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.mockSetup(mock)
+			store := NewUserStoreWithDB(db)
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin tx: %v", err)
+			}
+			defer tx.Rollback()
+
+			gotErr := store.ResetSessionTokensByShopID(context.Background(), tx, 10)
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("ResetSessionTokensByShopID() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
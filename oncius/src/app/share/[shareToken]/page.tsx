@@ -10,6 +10,7 @@ import { createAppTheme } from '@/theme'
 import { Plus, Minus, ImageIcon, ChevronUp, ChevronDown, ShoppingCart } from 'lucide-react'
 import { useTranslations, useLocale } from 'next-intl'
 import { api, resolveImageURL } from '@/utils/api'
+import { solveChallenge } from '@/utils/pow'
 import RecapoLogoText from '@/components/ui/RecapoLogoText'
 import { useChangeLocale } from '@/hooks/useLocale'
 
@@ -101,7 +102,7 @@ export default function SharePage() {
       if (!shareToken) return null
       const res = await api.getPublicProducts(shareToken)
       if (!res.success) throw new Error(res.message || tShare('fetchFailed'))
-      return res.data as Product[]
+      return (res.data?.products ?? []) as Product[]
     },
     { enabled: !!shareToken }
   )
@@ -120,6 +121,7 @@ export default function SharePage() {
   const [quantities, setQuantities] = useState<Record<number, number>>({})
   const [customerName, setCustomerName] = useState('')
   const [customerPhone, setCustomerPhone] = useState('')
+  const [website, setWebsite] = useState('')
   const [orderSubmitting, setOrderSubmitting] = useState(false)
   const [orderError, setOrderError] = useState<string | null>(null)
   const [orderSuccess, setOrderSuccess] = useState(false)
@@ -137,10 +139,20 @@ export default function SharePage() {
     setOrderError(null)
     setOrderSubmitting(true)
     try {
+      const challengeRes = await api.getPublicOrderChallenge(shareToken)
+      if (!challengeRes.success) {
+        setOrderError(challengeRes.message || tShare('orderFailed'))
+        return
+      }
+      const { challenge, difficulty } = challengeRes.data
+      const solution = await solveChallenge(challenge, difficulty)
       const res = await api.createPublicOrderTemp(shareToken, {
         customer_name: customerName.trim(),
         customer_phone: customerPhone.trim(),
         order_items: summaryItems.map((p) => ({ product_id: p.id, qty: quantities[p.id] ?? 0 })),
+        pow_challenge: challenge,
+        pow_solution: solution,
+        website,
       })
       if (res.success) {
         setOrderSuccess(true)
@@ -210,6 +222,10 @@ export default function SharePage() {
           size="small"
         />
       </Box>
+      {/* Honeypot: hidden from people, filled in by bots */}
+      <Box aria-hidden sx={{ position: 'absolute', left: '-10000px', width: 1, height: 1, overflow: 'hidden' }}>
+        <input type="text" name="website" tabIndex={-1} autoComplete="off" value={website} onChange={(e) => setWebsite(e.target.value)} />
+      </Box>
       {orderSuccess && (
         <Typography sx={{ color: 'success.main', fontSize: '0.875rem', mb: 2 }}>{tShare('orderSuccess')}</Typography>
       )}
@@ -339,10 +339,29 @@ export const api = {
   },
 
   // Customers
-  getCustomers: (search?: string, sort?: string) => {
+  getCustomers: (
+    search?: string,
+    sort?: string,
+    filters?: {
+      tags?: string[]
+      blocked?: boolean
+      min_orders?: number
+      min_spent?: number
+      has_outstanding?: boolean
+      last_order_from?: string
+      last_order_to?: string
+    }
+  ) => {
     const params = new URLSearchParams()
     if (search) params.set('search', search)
     if (sort) params.set('sort', sort)
+    if (filters?.tags?.length) params.set('tags', filters.tags.join(','))
+    if (filters?.blocked !== undefined) params.set('blocked', String(filters.blocked))
+    if (filters?.min_orders !== undefined) params.set('min_orders', String(filters.min_orders))
+    if (filters?.min_spent !== undefined) params.set('min_spent', String(filters.min_spent))
+    if (filters?.has_outstanding !== undefined) params.set('has_outstanding', String(filters.has_outstanding))
+    if (filters?.last_order_from) params.set('last_order_from', filters.last_order_from)
+    if (filters?.last_order_to) params.set('last_order_to', filters.last_order_to)
     const qs = params.toString()
     return apiRequest<ApiResponse<any[]>>(`/customers${qs ? `?${qs}` : ''}`)
   },
@@ -360,7 +379,7 @@ export const api = {
 
   updateCustomer: (
     id: number | string,
-    data: Partial<{ name: string; phone: string; address: string }>
+    data: Partial<{ name: string; phone: string; address: string; tags: string[]; blocked: boolean }>
   ) => {
     return apiRequest<ApiResponse>(`/customers/${id}`, {
       method: 'PATCH',
@@ -384,6 +403,13 @@ export const api = {
     )
   },
 
+  mergeCustomers: (data: { customer_id: number; duplicate_ids: number[] }) => {
+    return apiRequest<ApiResponse>('/customers/merge', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  },
+
   // Orders
   getOrders: (opts?: { search?: string; status?: string; payment_status?: string; date_from?: string; date_to?: string }) => {
     const params = new URLSearchParams()
@@ -645,12 +671,27 @@ export const api = {
 
   // Public (no auth)
   getPublicProducts: (shareToken: string) => {
-    return apiRequest<ApiResponse<any[]>>(`/public/shops/${encodeURIComponent(shareToken)}/products`, {}, true)
+    return apiRequest<ApiResponse<{ shop: any; products: any[] }>>(`/public/shops/${encodeURIComponent(shareToken)}/products`, {}, true)
+  },
+
+  getPublicOrderChallenge: (shareToken: string) => {
+    return apiRequest<ApiResponse<{ challenge: string; difficulty: number; expires_at: string }>>(
+      `/public/shops/${encodeURIComponent(shareToken)}/challenge`,
+      {},
+      true
+    )
   },
 
   createPublicOrderTemp: (
     shareToken: string,
-    data: { customer_name: string; customer_phone: string; order_items: Array<{ product_id: number; qty: number }> }
+    data: {
+      customer_name: string
+      customer_phone: string
+      order_items: Array<{ product_id: number; qty: number }>
+      pow_challenge?: string
+      pow_solution?: string
+      website?: string
+    }
   ) => {
     return apiRequest<ApiResponse<any>>(
       `/public/shops/${encodeURIComponent(shareToken)}/order`,
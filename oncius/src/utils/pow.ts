@@ -0,0 +1,20 @@
+// Solves the proof-of-work challenge the share page sends with an order:
+// find the first number, counting up from 0, for which
+// sha256(challenge + ":" + solution) starts with `difficulty` zero bits.
+export async function solveChallenge(challenge: string, difficulty: number): Promise<string> {
+  const encoder = new TextEncoder()
+  for (let i = 0; ; i++) {
+    const solution = String(i)
+    const digest = new Uint8Array(await crypto.subtle.digest('SHA-256', encoder.encode(`${challenge}:${solution}`)))
+    if (leadingZeroBits(digest) >= difficulty) return solution
+  }
+}
+
+function leadingZeroBits(bytes: Uint8Array): number {
+  let zeros = 0
+  for (const b of bytes) {
+    if (b !== 0) return zeros + Math.clz32(b) - 24
+    zeros += 8
+  }
+  return zeros
+}